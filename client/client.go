@@ -1,16 +1,26 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"net"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/golang/protobuf/proto"
 
 	upd "github.com/intel-go/nff-go-nat/updatecfg"
 )
@@ -19,10 +29,109 @@ type dumpRequestArray []*upd.DumpControlRequest
 type addresChangeRequestArray []*upd.InterfaceAddressChangeRequest
 type portForwardRequestArray []*upd.PortForwardingChangeRequest
 
+// Neighbor table request, only one of fields is set.
+type neighborRequest struct {
+	get    *upd.NeighborsRequest
+	add    *upd.NeighborChangeRequest
+	delete *upd.NeighborChangeRequest
+	flush  *upd.NeighborsFlushRequest
+}
+type neighborRequestArray []neighborRequest
+
+// DHCP client request, control is nil for lease query.
+type dhcpRequest struct {
+	lease   *upd.DHCPLeaseRequest
+	control *upd.DHCPControlRequest
+}
+type dhcpRequestArray []dhcpRequest
+
+// Remote dump sink request, disconnect is set when connect is nil.
+type dumpSinkRequest struct {
+	connect    *upd.DumpSinkConnectRequest
+	disconnect *upd.DumpSinkDisconnectRequest
+}
+type dumpSinkRequestArray []dumpSinkRequest
+type wakeOnLANRequestArray []*upd.WakeOnLANRequest
+type statisticsRequestArray []*upd.PortStatisticsRequest
+type linkStatusRequestArray []*upd.LinkStatusRequest
+type egressPolicerRequestArray []*upd.EgressPolicerChangeRequest
+type subscribersRequestArray []*upd.SubscribersRequest
+type topTalkersRequestArray []*upd.TopTalkersRequest
+type applicationsRequestArray []*upd.ApplicationsRequest
+type countriesRequestArray []*upd.CountriesRequest
+type announcementRequestArray []*upd.RouteAnnouncementRequest
+type sessionDeleteRequestArray []*upd.SessionDeleteRequest
+type sessionsFindRequestArray []*upd.SessionsFindRequest
+type logSamplingRequestArray []*upd.SessionLogSamplingRequest
+type externalAddressRequestArray []*upd.ExternalAddressRequest
+type packetTraceRequestArray []*upd.PacketTraceRequest
+type handshakesRequestArray []*upd.ForwardedHandshakesRequest
+type identitiesRequestArray []*upd.HostIdentitiesRequest
+
+// Blackhole rules request, change is nil for rules query.
+type blackholeRequest struct {
+	get    *upd.BlackholeRulesRequest
+	change *upd.BlackholeRuleChangeRequest
+}
+type blackholeRequestArray []blackholeRequest
+
+// Port triggers request, set is nil for triggers query.
+type portTriggersRequest struct {
+	get *upd.PortTriggersRequest
+	set *upd.PortTriggersChangeRequest
+}
+type portTriggersRequestArray []portTriggersRequest
+
+// Maintenance request, set is nil for status query.
+type maintenanceRequest struct {
+	get *upd.MaintenanceStatusRequest
+	set *upd.MaintenanceRequest
+}
+type maintenanceRequestArray []maintenanceRequest
+
+// Captive portal request, set is nil for hosts query.
+type portalRequest struct {
+	get *upd.CaptivePortalHostsRequest
+	set *upd.CaptivePortalHostRequest
+}
+type portalRequestArray []portalRequest
+
+// Port trigger rule in config file format, ports are numbers or
+// strings with ranges.
+type portTriggerRule struct {
+	Protocol     string        `json:"protocol"`
+	Ports        interface{}   `json:"ports"`
+	OpenProtocol string        `json:"open-protocol"`
+	OpenPorts    []interface{} `json:"open-ports"`
+}
+
 var (
-	dumpRequests         dumpRequestArray
-	addresChangeRequests addresChangeRequestArray
-	portForwardRequests  portForwardRequestArray
+	dumpRequests          dumpRequestArray
+	addresChangeRequests  addresChangeRequestArray
+	portForwardRequests   portForwardRequestArray
+	neighborRequests      neighborRequestArray
+	dhcpRequests          dhcpRequestArray
+	dumpSinkRequests      dumpSinkRequestArray
+	wakeOnLANRequests     wakeOnLANRequestArray
+	statisticsRequests    statisticsRequestArray
+	linkStatusRequests    linkStatusRequestArray
+	egressPolicerRequests egressPolicerRequestArray
+	subscribersRequests   subscribersRequestArray
+	topTalkersRequests    topTalkersRequestArray
+	applicationsRequests  applicationsRequestArray
+	countriesRequests     countriesRequestArray
+	announcementRequests  announcementRequestArray
+	sessionDeleteRequests sessionDeleteRequestArray
+	sessionsFindRequests  sessionsFindRequestArray
+	blackholeRequests     blackholeRequestArray
+	portTriggersRequests  portTriggersRequestArray
+	logSamplingRequests   logSamplingRequestArray
+	externalRequests      externalAddressRequestArray
+	maintenanceRequests   maintenanceRequestArray
+	packetTraceRequests   packetTraceRequestArray
+	handshakesRequests    handshakesRequestArray
+	portalRequests        portalRequestArray
+	identitiesRequests    identitiesRequestArray
 )
 
 func (dra *dumpRequestArray) String() string {
@@ -78,7 +187,7 @@ func (acra *addresChangeRequestArray) String() string {
 
 func (acra *addresChangeRequestArray) Set(value string) error {
 	parts := strings.Split(value, ",")
-	if len(parts) != 2 {
+	if (len(parts) != 2 && len(parts) != 3) || (len(parts) == 3 && parts[2] != "keep") {
 		return fmt.Errorf("Bad port index and subnet address specified \"%s\"", value)
 	}
 	index, err := strconv.ParseUint(parts[0], 10, 32)
@@ -104,6 +213,7 @@ func (acra *addresChangeRequestArray) Set(value string) error {
 			},
 			MaskBitsNumber: uint32(ones),
 		},
+		KeepSessions: len(parts) == 3,
 	})
 	return nil
 }
@@ -116,9 +226,28 @@ func (pfra *portForwardRequestArray) String() string {
 	return res
 }
 
+// parseForwardTarget parses target address of forwarded port. Link
+// local IPv6 address may have zone, e.g. fe80::7%1.
+func parseForwardTarget(value string) (net.IP, string, error) {
+	host := value
+	zone := ""
+	if i := strings.IndexByte(host, '%'); i >= 0 {
+		host, zone = host[:i], host[i+1:]
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, "", fmt.Errorf("Bad IP address specified \"%s\"", value)
+	}
+	ip4 := ip.To4()
+	if ip4 != nil {
+		ip = ip4
+	}
+	return ip, zone, nil
+}
+
 func (pfra *portForwardRequestArray) Set(value string) error {
 	parts := strings.Split(value, ",")
-	if len(parts) != 6 {
+	if len(parts) < 6 {
 		return fmt.Errorf("Bad port forwarding specification \"%s\"", value)
 	}
 
@@ -145,13 +274,15 @@ func (pfra *portForwardRequestArray) Set(value string) error {
 		return err
 	}
 
-	ip := net.ParseIP(parts[4])
-	if ip == nil {
-		return fmt.Errorf("Bad IP address specified \"%s\"", parts[4])
+	// Target on KNI interface of port in a form of kni=address
+	target := parts[4]
+	kni := strings.HasPrefix(target, "kni=")
+	if kni {
+		target = strings.TrimPrefix(target, "kni=")
 	}
-	ip4 := ip.To4()
-	if ip4 != nil {
-		ip = ip4
+	ip, zone, err := parseForwardTarget(target)
+	if err != nil {
+		return err
 	}
 
 	tport, err := strconv.ParseUint(parts[5], 10, 16)
@@ -159,6 +290,35 @@ func (pfra *portForwardRequestArray) Set(value string) error {
 		return err
 	}
 
+	// Source specific targets in a form of prefix=address:port
+	sources := []*upd.ForwardedSource{}
+	for _, s := range parts[6:] {
+		sourceParts := strings.SplitN(s, "=", 2)
+		if len(sourceParts) != 2 {
+			return fmt.Errorf("Bad port forwarding source specification \"%s\"", s)
+		}
+		host, port, err := net.SplitHostPort(sourceParts[1])
+		if err != nil {
+			return err
+		}
+		sip, szone, err := parseForwardTarget(host)
+		if err != nil {
+			return err
+		}
+		stport, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, &upd.ForwardedSource{
+			Prefix: sourceParts[0],
+			TargetAddress: &upd.IPAddress{
+				Address: sip,
+			},
+			TargetPortNumber: uint32(stport),
+			TargetZone:       szone,
+		})
+	}
+
 	*pfra = append(*pfra, &upd.PortForwardingChangeRequest{
 		EnableForwarding: enable,
 		InterfaceId:      uint32(index),
@@ -169,75 +329,1921 @@ func (pfra *portForwardRequestArray) Set(value string) error {
 			},
 			TargetPortNumber: uint32(tport),
 			Protocol:         upd.Protocol(proto),
+			TargetZone:       zone,
+			Sources:          sources,
+			Kni:              kni,
 		},
 	})
 	return nil
 }
 
-func main() {
-	flag.Usage = func() {
-		fmt.Printf(`Usage: client [-a server:port] [-d {+|-}{d|t|k}] [-s index:subnet] [-p {+|-},{TCP|UDP|TCP6|UDP6},port number,target IP address,target port]
+func (nra *neighborRequestArray) String() string {
+	return ""
+}
 
-Client sends GRPS requests to NAT server controlling packets trace dump,
-ports subnet adresses and forwarded ports. Multiple requests of the same
-type are allowed and are processed in the following order: all dump, all
-subnet, all port forwarding requests.
+func (nra *neighborRequestArray) Set(value string) error {
+	parts := strings.Split(value, ",")
+	if len(parts) < 2 {
+		return fmt.Errorf("Bad neighbor table request specification \"%s\"", value)
+	}
 
-`)
-		flag.PrintDefaults()
+	index, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return err
 	}
-	address := flag.String("a", "localhost:60602", "Specifies server address")
-	flag.Var(&dumpRequests, "d", `Control dump trace output in a form of +/- and letter,
-e.g. +d or -t or +k:
-    + and - mean to enable or disable corresponding trace,
-    d means to trace dropped packets,
-    t means to trace translated (normally sent) packets,
-    k means to trace packets that were sent to KNI interface.`)
-	flag.Var(&addresChangeRequests, "s", `Control network interface subnet in a form of index:subnet,
-e.g. 1,192.168.5.1/24 or 1,fd16::1/128. Port index is DPDK port
-number. Subnet is given in form of port IP address and prefix bits.`)
-	flag.Var(&portForwardRequests, "p", `Control TCP and UDP port forwarding in a form of
-+/-,index,protocol,source port,target IP address,target port, e.g.
-+,1,TCP,2222,192.168.5.7,22 or -,0,TCP,22,0.0.0.0,0 or
-+,1,TCP6,2222,fd14::3,22 or -,0,TCP6,22,::,0. If target
-address is zero, it means that port is forwarded to corresponding
-network port KNI interface. Port forwarding to a non-zero
-target address (not to a KNI interface) is possible only for
-public network port.`)
-	flag.Parse()
 
-	// Set up a connection to the server.
-	conn, err := grpc.Dial(*address, grpc.WithInsecure())
+	var ip net.IP
+	if parts[0] == "+" || parts[0] == "-" {
+		if len(parts) < 3 {
+			return fmt.Errorf("IP address is required in neighbor table request \"%s\"", value)
+		}
+		ip = net.ParseIP(parts[2])
+		if ip == nil {
+			return fmt.Errorf("Bad IP address specified \"%s\"", parts[2])
+		}
+		ip4 := ip.To4()
+		if ip4 != nil {
+			ip = ip4
+		}
+	}
+
+	var req neighborRequest
+	switch {
+	case parts[0] == "l" && len(parts) == 2:
+		req.get = &upd.NeighborsRequest{
+			InterfaceId: uint32(index),
+		}
+	case parts[0] == "+" && len(parts) == 4:
+		mac, err := net.ParseMAC(parts[3])
+		if err != nil {
+			return err
+		}
+		req.add = &upd.NeighborChangeRequest{
+			InterfaceId: uint32(index),
+			Address: &upd.IPAddress{
+				Address: ip,
+			},
+			MacAddress: mac,
+		}
+	case parts[0] == "-" && len(parts) == 3:
+		req.delete = &upd.NeighborChangeRequest{
+			InterfaceId: uint32(index),
+			Address: &upd.IPAddress{
+				Address: ip,
+			},
+		}
+	case (parts[0] == "f" || parts[0] == "F") && len(parts) == 2:
+		req.flush = &upd.NeighborsFlushRequest{
+			InterfaceId: uint32(index),
+			FlushStatic: parts[0] == "F",
+		}
+	default:
+		return fmt.Errorf("Bad neighbor table request specification \"%s\"", value)
+	}
+	*nra = append(*nra, req)
+	return nil
+}
+
+func (dra *dhcpRequestArray) String() string {
+	return ""
+}
+
+func (dra *dhcpRequestArray) Set(value string) error {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 && (len(parts) != 3 || parts[2] != "ipv6") {
+		return fmt.Errorf("Bad DHCP request specification \"%s\"", value)
+	}
+
+	index, err := strconv.ParseUint(parts[1], 10, 32)
 	if err != nil {
-		log.Fatalf("did not connect: %v", err)
+		return err
 	}
-	defer conn.Close()
-	c := upd.NewUpdaterClient(conn)
+	ipv6 := len(parts) == 3
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	if parts[0] == "lease" {
+		*dra = append(*dra, dhcpRequest{
+			lease: &upd.DHCPLeaseRequest{
+				InterfaceId: uint32(index),
+				Ipv6:        ipv6,
+			},
+		})
+		return nil
+	}
 
-	for _, r := range dumpRequests {
-		reply, err := c.ControlDump(ctx, r)
+	action, ok := map[string]upd.DHCPAction{
+		"renew":   upd.DHCPAction_DHCP_RENEW,
+		"release": upd.DHCPAction_DHCP_RELEASE,
+		"restart": upd.DHCPAction_DHCP_RESTART,
+	}[parts[0]]
+	if !ok {
+		return fmt.Errorf("Bad DHCP request type \"%s\"", parts[0])
+	}
+	*dra = append(*dra, dhcpRequest{
+		control: &upd.DHCPControlRequest{
+			InterfaceId: uint32(index),
+			Ipv6:        ipv6,
+			Action:      action,
+		},
+	})
+	return nil
+}
+
+func (wra *wakeOnLANRequestArray) String() string {
+	return ""
+}
+
+func (wra *wakeOnLANRequestArray) Set(value string) error {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 && len(parts) != 3 {
+		return fmt.Errorf("Bad Wake-on-LAN request specification \"%s\"", value)
+	}
+
+	index, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return err
+	}
+	mac, err := net.ParseMAC(parts[1])
+	if err != nil {
+		return err
+	}
+	if len(mac) != 6 {
+		return fmt.Errorf("Bad MAC address specified \"%s\"", parts[1])
+	}
+
+	// SecureOn password is given either as MAC address or as IPv4
+	// address like in ether-wake
+	var password []byte
+	if len(parts) == 3 {
+		if ip := net.ParseIP(parts[2]).To4(); ip != nil {
+			password = ip
+		} else if pw, err := net.ParseMAC(parts[2]); err == nil && len(pw) == 6 {
+			password = pw
+		} else {
+			return fmt.Errorf("Bad Wake-on-LAN password specified \"%s\"", parts[2])
+		}
+	}
+
+	*wra = append(*wra, &upd.WakeOnLANRequest{
+		InterfaceId: uint32(index),
+		MacAddress:  mac,
+		Password:    password,
+	})
+	return nil
+}
+
+func (sra *statisticsRequestArray) String() string {
+	return ""
+}
+
+func (sra *statisticsRequestArray) Set(value string) error {
+	index, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return err
+	}
+	*sra = append(*sra, &upd.PortStatisticsRequest{
+		InterfaceId: uint32(index),
+	})
+	return nil
+}
+
+func (lsra *linkStatusRequestArray) String() string {
+	return ""
+}
+
+func (lsra *linkStatusRequestArray) Set(value string) error {
+	index, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return err
+	}
+	*lsra = append(*lsra, &upd.LinkStatusRequest{
+		InterfaceId: uint32(index),
+	})
+	return nil
+}
+
+func (sra *egressPolicerRequestArray) String() string {
+	return ""
+}
+
+// parsePolicerClass parses rate in kilobits per second optionally
+// followed by burst in bytes, e.g. 10000 or 10000/150000.
+func parsePolicerClass(value string) (uint64, uint64, error) {
+	parts := strings.Split(value, "/")
+	if len(parts) > 2 {
+		return 0, 0, fmt.Errorf("Bad policer rate specification \"%s\"", value)
+	}
+	rate, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	var burst uint64
+	if len(parts) == 2 {
+		burst, err = strconv.ParseUint(parts[1], 10, 64)
 		if err != nil {
-			log.Fatalf("could not update: %v", err)
+			return 0, 0, err
 		}
-		log.Printf("update successful: \"%s\"", reply.String())
 	}
+	return rate, burst, nil
+}
 
-	for _, r := range addresChangeRequests {
-		reply, err := c.ChangeInterfaceAddress(ctx, r)
+func (sra *egressPolicerRequestArray) Set(value string) error {
+	parts := strings.Split(value, ",")
+	if len(parts) < 3 {
+		return fmt.Errorf("Bad egress policer specification \"%s\"", value)
+	}
+
+	index, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return err
+	}
+	req := &upd.EgressPolicerChangeRequest{
+		InterfaceId: uint32(index),
+	}
+	if req.Rate, req.Burst, err = parsePolicerClass(parts[1]); err != nil {
+		return err
+	}
+	if req.HostRate, req.HostBurst, err = parsePolicerClass(parts[2]); err != nil {
+		return err
+	}
+
+	for _, h := range parts[3:] {
+		hostParts := strings.Split(h, "=")
+		if len(hostParts) != 2 {
+			return fmt.Errorf("Bad egress policer host specification \"%s\"", h)
+		}
+		ip := net.ParseIP(hostParts[0])
+		if ip == nil {
+			return fmt.Errorf("Bad IP address specified \"%s\"", hostParts[0])
+		}
+		ip4 := ip.To4()
+		if ip4 != nil {
+			ip = ip4
+		}
+		rate, burst, err := parsePolicerClass(hostParts[1])
 		if err != nil {
-			log.Fatalf("could not update: %v", err)
+			return err
 		}
-		log.Printf("update successful: \"%s\"", reply.String())
+		req.Hosts = append(req.Hosts, &upd.EgressPolicerHost{
+			Address: &upd.IPAddress{
+				Address: ip,
+			},
+			Rate:  rate,
+			Burst: burst,
+		})
 	}
 
-	for _, r := range portForwardRequests {
-		reply, err := c.ChangePortForwarding(ctx, r)
+	*sra = append(*sra, req)
+	return nil
+}
+
+func (sra *subscribersRequestArray) String() string {
+	return ""
+}
+
+func (sra *subscribersRequestArray) Set(value string) error {
+	index, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return err
+	}
+	*sra = append(*sra, &upd.SubscribersRequest{
+		InterfaceId: uint32(index),
+	})
+	return nil
+}
+
+func (tra *topTalkersRequestArray) String() string {
+	return ""
+}
+
+func (tra *topTalkersRequestArray) Set(value string) error {
+	parts := strings.Split(value, ",")
+	if len(parts) > 2 {
+		return fmt.Errorf("Expected index[,count], got %s", value)
+	}
+	index, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return err
+	}
+	var count uint64
+	if len(parts) == 2 {
+		count, err = strconv.ParseUint(parts[1], 10, 32)
 		if err != nil {
-			log.Fatalf("could not update: %v", err)
+			return err
+		}
+	}
+	*tra = append(*tra, &upd.TopTalkersRequest{
+		InterfaceId: uint32(index),
+		Count:       uint32(count),
+	})
+	return nil
+}
+
+func (ara *announcementRequestArray) String() string {
+	return ""
+}
+
+func (ara *announcementRequestArray) Set(value string) error {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 || (parts[1] != "announce" && parts[1] != "withdraw") {
+		return fmt.Errorf("Expected index,announce or index,withdraw, got %s", value)
+	}
+	index, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return err
+	}
+	*ara = append(*ara, &upd.RouteAnnouncementRequest{
+		InterfaceId: uint32(index),
+		Announce:    parts[1] == "announce",
+	})
+	return nil
+}
+
+func (mra *maintenanceRequestArray) String() string {
+	return ""
+}
+
+func (mra *maintenanceRequestArray) Set(value string) error {
+	parts := strings.Split(value, ",")
+	index, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return err
+	}
+	if len(parts) == 1 {
+		*mra = append(*mra, maintenanceRequest{
+			get: &upd.MaintenanceStatusRequest{
+				InterfaceId: uint32(index),
+			},
+		})
+		return nil
+	}
+	req := &upd.MaintenanceRequest{
+		InterfaceId: uint32(index),
+	}
+	switch parts[1] {
+	case "on":
+		req.Enable = true
+	case "off":
+	default:
+		return fmt.Errorf("Expected index, index,on[,flush][,reject] or index,off, got %s", value)
+	}
+	for _, opt := range parts[2:] {
+		switch {
+		case opt == "flush" && req.Enable:
+			req.FlushSessions = true
+		case opt == "reject" && req.Enable:
+			req.Reject = true
+		default:
+			return fmt.Errorf("Bad maintenance option %s in %s", opt, value)
+		}
+	}
+	*mra = append(*mra, maintenanceRequest{set: req})
+	return nil
+}
+
+func (pra *portalRequestArray) String() string {
+	return ""
+}
+
+func (pra *portalRequestArray) Set(value string) error {
+	parts := strings.Split(value, ",")
+	index, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return err
+	}
+	if len(parts) == 1 {
+		*pra = append(*pra, portalRequest{
+			get: &upd.CaptivePortalHostsRequest{
+				InterfaceId: uint32(index),
+			},
+		})
+		return nil
+	}
+	if len(parts) != 3 {
+		return fmt.Errorf("Expected index or index,host,auth|unauth|forget, got %s", value)
+	}
+	host := &upd.CaptivePortalHost{}
+	if ip := net.ParseIP(parts[1]); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		host.Address = &upd.IPAddress{
+			Address: ip,
+		}
+	} else if mac, err := net.ParseMAC(parts[1]); err == nil && len(mac) == 6 {
+		host.MacAddress = mac
+	} else {
+		return fmt.Errorf("Bad captive portal host %s, expected IP or MAC address", parts[1])
+	}
+	switch parts[2] {
+	case "auth":
+		host.State = upd.CaptivePortalState_PORTAL_AUTHENTICATED
+	case "unauth":
+		host.State = upd.CaptivePortalState_PORTAL_UNAUTHENTICATED
+	case "forget":
+		host.State = upd.CaptivePortalState_PORTAL_DEFAULT
+	default:
+		return fmt.Errorf("Bad captive portal host state %s", parts[2])
+	}
+	*pra = append(*pra, portalRequest{
+		set: &upd.CaptivePortalHostRequest{
+			InterfaceId: uint32(index),
+			Host:        host,
+		},
+	})
+	return nil
+}
+
+func (hra *handshakesRequestArray) String() string {
+	return ""
+}
+
+func (hra *handshakesRequestArray) Set(value string) error {
+	index, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return err
+	}
+	*hra = append(*hra, &upd.ForwardedHandshakesRequest{
+		InterfaceId: uint32(index),
+	})
+	return nil
+}
+
+func (ira *identitiesRequestArray) String() string {
+	return ""
+}
+
+func (ira *identitiesRequestArray) Set(value string) error {
+	index, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return err
+	}
+	*ira = append(*ira, &upd.HostIdentitiesRequest{
+		InterfaceId: uint32(index),
+	})
+	return nil
+}
+
+func (ptra *packetTraceRequestArray) String() string {
+	return ""
+}
+
+func (ptra *packetTraceRequestArray) Set(value string) error {
+	parts := strings.Split(value, ",")
+	if len(parts) < 6 {
+		return fmt.Errorf("Bad packet specification \"%s\"", value)
+	}
+	index, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return err
+	}
+	protocol, ok := map[string]uint32{
+		"TCP":   6,
+		"UDP":   17,
+		"ICMP":  1,
+		"ICMP6": 58,
+	}[parts[1]]
+	if !ok {
+		number, err := strconv.ParseUint(parts[1], 10, 8)
+		if err != nil {
+			return fmt.Errorf("Bad protocol specified \"%s\"", parts[1])
+		}
+		protocol = uint32(number)
+	}
+	var addrs [2]net.IP
+	var ports [2]uint64
+	for i := range addrs {
+		addrs[i] = net.ParseIP(parts[2+i*2])
+		if addrs[i] == nil {
+			return fmt.Errorf("Bad IP address specified \"%s\"", parts[2+i*2])
+		}
+		if ip4 := addrs[i].To4(); ip4 != nil {
+			addrs[i] = ip4
+		}
+		ports[i], err = strconv.ParseUint(parts[3+i*2], 10, 16)
+		if err != nil {
+			return err
+		}
+	}
+	request := &upd.PacketTraceRequest{
+		InterfaceId: uint32(index),
+		Protocol:    protocol,
+		Source: &upd.IPAddress{
+			Address: addrs[0],
+		},
+		SourcePort: uint32(ports[0]),
+		Destination: &upd.IPAddress{
+			Address: addrs[1],
+		},
+		DestinationPort: uint32(ports[1]),
+	}
+	for _, option := range parts[6:] {
+		switch {
+		case option == "syn":
+			request.NewConnection = true
+		case option == "options":
+			request.IpOptions = true
+		case strings.HasPrefix(option, "ttl="):
+			ttl, err := strconv.ParseUint(strings.TrimPrefix(option, "ttl="), 10, 8)
+			if err != nil || ttl == 0 {
+				return fmt.Errorf("Bad TTL specified \"%s\"", option)
+			}
+			request.HopLimit = uint32(ttl)
+		default:
+			return fmt.Errorf("Bad packet specification \"%s\"", value)
+		}
+	}
+	*ptra = append(*ptra, request)
+	return nil
+}
+
+func (sdra *sessionDeleteRequestArray) String() string {
+	return ""
+}
+
+func (sdra *sessionDeleteRequestArray) Set(value string) error {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return fmt.Errorf("Bad session specification \"%s\"", value)
+	}
+	index, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return err
+	}
+	protocol, ok := map[string]uint32{
+		"TCP":   6,
+		"UDP":   17,
+		"ICMP":  1,
+		"ICMP6": 58,
+	}[parts[1]]
+	if !ok {
+		return fmt.Errorf("Bad protocol specified \"%s\"", parts[1])
+	}
+	ip := net.ParseIP(parts[2])
+	if ip == nil {
+		return fmt.Errorf("Bad IP address specified \"%s\"", parts[2])
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	port, err := strconv.ParseUint(parts[3], 10, 16)
+	if err != nil {
+		return err
+	}
+	*sdra = append(*sdra, &upd.SessionDeleteRequest{
+		InterfaceId: uint32(index),
+		Protocol:    protocol,
+		Address: &upd.IPAddress{
+			Address: ip,
+		},
+		Port: uint32(port),
+	})
+	return nil
+}
+
+func (sfra *sessionsFindRequestArray) String() string {
+	return ""
+}
+
+func (sfra *sessionsFindRequestArray) Set(value string) error {
+	parts := strings.Split(value, ",")
+	index, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return err
+	}
+	req := &upd.SessionsFindRequest{
+		InterfaceId: uint32(index),
+		Filter:      &upd.SessionFilter{},
+	}
+	f := req.Filter
+	for _, cond := range parts[1:] {
+		kv := strings.SplitN(cond, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("Bad session condition \"%s\"", cond)
+		}
+		key, v := kv[0], kv[1]
+		switch key {
+		case "protocol":
+			protocol, ok := map[string]uint32{
+				"TCP":   6,
+				"UDP":   17,
+				"ICMP":  1,
+				"ICMP6": 58,
+			}[v]
+			if !ok {
+				return fmt.Errorf("Bad protocol specified \"%s\"", v)
+			}
+			f.Protocol = protocol
+		case "public", "private", "remote":
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return fmt.Errorf("Bad IP address specified \"%s\"", v)
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+			}
+			addr := &upd.IPAddress{
+				Address: ip,
+			}
+			switch key {
+			case "public":
+				f.PublicAddress = addr
+			case "private":
+				f.PrivateAddress = addr
+			default:
+				f.RemoteAddress = addr
+			}
+		case "public-port", "private-port", "remote-port", "min-age", "max-age", "limit":
+			n, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				return err
+			}
+			if strings.HasSuffix(key, "-port") && n > 65535 {
+				return fmt.Errorf("Bad port specified \"%s\"", v)
+			}
+			*map[string]*uint32{
+				"public-port":  &f.PublicPort,
+				"private-port": &f.PrivatePort,
+				"remote-port":  &f.RemotePort,
+				"min-age":      &f.MinAge,
+				"max-age":      &f.MaxAge,
+				"limit":        &req.Limit,
+			}[key] = uint32(n)
+		case "min-bytes", "max-bytes", "cursor":
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return err
+			}
+			*map[string]*uint64{
+				"min-bytes": &f.MinBytes,
+				"max-bytes": &f.MaxBytes,
+				"cursor":    &req.Cursor,
+			}[key] = n
+		case "kind":
+			kind, ok := map[string]upd.SessionKind{
+				"dynamic": upd.SessionKind_SESSIONS_DYNAMIC,
+				"static":  upd.SessionKind_SESSIONS_STATIC,
+			}[v]
+			if !ok {
+				return fmt.Errorf("Bad session kind specified \"%s\"", v)
+			}
+			f.Kind = kind
+		case "mac":
+			mac, err := net.ParseMAC(v)
+			if err != nil {
+				return err
+			}
+			f.PrivateMacAddress = mac
+		default:
+			return fmt.Errorf("Bad session condition \"%s\"", cond)
+		}
+	}
+	*sfra = append(*sfra, req)
+	return nil
+}
+
+// macString formats MAC address, empty address is printed as -.
+func macString(mac []byte) string {
+	if len(mac) == 0 {
+		return "-"
+	}
+	return net.HardwareAddr(mac).String()
+}
+
+// sessionEndpoint formats address and port of session, empty address
+// is printed as KNI.
+func sessionEndpoint(a *upd.IPAddress, port uint32) string {
+	if a == nil {
+		if port == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("KNI:%d", port)
+	}
+	return net.JoinHostPort(net.IP(a.GetAddress()).String(), strconv.Itoa(int(port)))
+}
+
+func (ara *applicationsRequestArray) String() string {
+	return ""
+}
+
+func (ara *applicationsRequestArray) Set(value string) error {
+	index, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return err
+	}
+	*ara = append(*ara, &upd.ApplicationsRequest{
+		InterfaceId: uint32(index),
+	})
+	return nil
+}
+
+func (cra *countriesRequestArray) String() string {
+	return ""
+}
+
+func (cra *countriesRequestArray) Set(value string) error {
+	index, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return err
+	}
+	*cra = append(*cra, &upd.CountriesRequest{
+		InterfaceId: uint32(index),
+	})
+	return nil
+}
+
+func (bra *blackholeRequestArray) String() string {
+	return ""
+}
+
+func (bra *blackholeRequestArray) Set(value string) error {
+	parts := strings.Split(value, ",")
+	if len(parts) < 2 {
+		return fmt.Errorf("Bad blackhole request specification \"%s\"", value)
+	}
+
+	index, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return err
+	}
+
+	var req blackholeRequest
+	switch {
+	case parts[0] == "l" && len(parts) == 2:
+		req.get = &upd.BlackholeRulesRequest{
+			InterfaceId: uint32(index),
+		}
+	case parts[0] == "+" && (len(parts) == 3 || len(parts) == 4):
+		rule := &upd.BlackholeRule{
+			Destination: parts[2],
+		}
+		if len(parts) == 4 {
+			rule.Action = parts[3]
+		}
+		req.change = &upd.BlackholeRuleChangeRequest{
+			Enable:      true,
+			InterfaceId: uint32(index),
+			Rule:        rule,
+		}
+	case parts[0] == "-" && len(parts) == 3:
+		req.change = &upd.BlackholeRuleChangeRequest{
+			InterfaceId: uint32(index),
+			Rule: &upd.BlackholeRule{
+				Destination: parts[2],
+			},
+		}
+	default:
+		return fmt.Errorf("Bad blackhole request specification \"%s\"", value)
+	}
+	*bra = append(*bra, req)
+	return nil
+}
+
+func (pta *portTriggersRequestArray) String() string {
+	return ""
+}
+
+func (pta *portTriggersRequestArray) Set(value string) error {
+	parts := strings.SplitN(value, ",", 3)
+	if len(parts) < 2 {
+		return fmt.Errorf("Bad port triggers request specification \"%s\"", value)
+	}
+
+	index, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return err
+	}
+
+	var req portTriggersRequest
+	switch {
+	case parts[0] == "l" && len(parts) == 2:
+		req.get = &upd.PortTriggersRequest{
+			InterfaceId: uint32(index),
+		}
+	case parts[0] == "s" && len(parts) == 3:
+		data, err := ioutil.ReadFile(parts[2])
+		if err != nil {
+			return err
+		}
+		rules := []portTriggerRule{}
+		if err := json.Unmarshal(data, &rules); err != nil {
+			return fmt.Errorf("Bad port triggers file %s: %v", parts[2], err)
+		}
+		req.set = &upd.PortTriggersChangeRequest{
+			InterfaceId: uint32(index),
+			Triggers:    []*upd.PortTrigger{},
+		}
+		for _, r := range rules {
+			t := &upd.PortTrigger{
+				Protocol:     r.Protocol,
+				Ports:        fmt.Sprint(r.Ports),
+				OpenProtocol: r.OpenProtocol,
+			}
+			for _, p := range r.OpenPorts {
+				t.OpenPorts = append(t.OpenPorts, fmt.Sprint(p))
+			}
+			req.set.Triggers = append(req.set.Triggers, t)
+		}
+	default:
+		return fmt.Errorf("Bad port triggers request specification \"%s\"", value)
+	}
+	*pta = append(*pta, req)
+	return nil
+}
+
+func (eara *externalAddressRequestArray) String() string {
+	return ""
+}
+
+func (eara *externalAddressRequestArray) Set(value string) error {
+	index, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return err
+	}
+	*eara = append(*eara, &upd.ExternalAddressRequest{
+		InterfaceId: uint32(index),
+	})
+	return nil
+}
+
+func (lsra *logSamplingRequestArray) String() string {
+	return ""
+}
+
+func (lsra *logSamplingRequestArray) Set(value string) error {
+	parts := strings.Split(value, ",")
+	if len(parts) < 2 {
+		return fmt.Errorf("Bad session log sampling specification \"%s\"", value)
+	}
+
+	index, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return err
+	}
+	rate, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return err
+	}
+	req := &upd.SessionLogSamplingRequest{
+		InterfaceId: uint32(index),
+		Rate:        uint32(rate),
+	}
+	for _, h := range parts[2:] {
+		if mac, err := net.ParseMAC(h); err == nil {
+			req.SubscriberMacAddresses = append(req.SubscriberMacAddresses, mac)
+			continue
+		}
+		ip := net.ParseIP(h)
+		if ip == nil {
+			return fmt.Errorf("Bad IP address specified \"%s\"", h)
+		}
+		ip4 := ip.To4()
+		if ip4 != nil {
+			ip = ip4
+		}
+		req.Subscribers = append(req.Subscribers, &upd.IPAddress{
+			Address: ip,
+		})
+	}
+	*lsra = append(*lsra, req)
+	return nil
+}
+
+func parseTraceTypes(value string) ([]upd.TraceType, error) {
+	traceTypes := []upd.TraceType{}
+	for _, c := range value {
+		t, ok := map[rune]upd.TraceType{
+			'd': upd.TraceType_DUMP_DROP,
+			't': upd.TraceType_DUMP_TRANSLATE,
+			'k': upd.TraceType_DUMP_KNI,
+		}[c]
+		if !ok {
+			return nil, fmt.Errorf("Bad dump type character \"%c\"", c)
+		}
+		traceTypes = append(traceTypes, t)
+	}
+	return traceTypes, nil
+}
+
+func parseInterfaceIDs(values []string) ([]uint32, error) {
+	ids := []uint32{}
+	for _, v := range values {
+		index, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, uint32(index))
+	}
+	return ids, nil
+}
+
+func (dsra *dumpSinkRequestArray) String() string {
+	return ""
+}
+
+func (dsra *dumpSinkRequestArray) Set(value string) error {
+	parts := strings.Split(value, ",")
+	if len(parts) == 2 && parts[0] == "-" {
+		*dsra = append(*dsra, dumpSinkRequest{
+			disconnect: &upd.DumpSinkDisconnectRequest{
+				Address: parts[1],
+			},
+		})
+		return nil
+	}
+	if len(parts) < 3 || parts[0] != "+" {
+		return fmt.Errorf("Bad dump sink specification \"%s\"", value)
+	}
+
+	traceTypes, err := parseTraceTypes(parts[2])
+	if err != nil {
+		return err
+	}
+	req := &upd.DumpSinkConnectRequest{
+		Address:    parts[1],
+		TraceTypes: traceTypes,
+	}
+	rest := parts[3:]
+	if len(rest) > 0 && strings.HasPrefix(rest[0], "tls") {
+		req.UseTls = true
+		switch {
+		case rest[0] == "tls":
+		case rest[0] == "tls-insecure":
+			req.InsecureSkipVerify = true
+		case strings.HasPrefix(rest[0], "tls="):
+			req.CaCertificate, err = ioutil.ReadFile(strings.TrimPrefix(rest[0], "tls="))
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("Bad TLS specification \"%s\"", rest[0])
+		}
+		rest = rest[1:]
+	}
+	req.InterfaceIds, err = parseInterfaceIDs(rest)
+	if err != nil {
+		return err
+	}
+	*dsra = append(*dsra, dumpSinkRequest{
+		connect: req,
+	})
+	return nil
+}
+
+// streamDump writes packets streamed by server into pcap file until
+// stream is finished.
+// Size of session snapshots which client is able to send and receive
+const maxSnapshotSize = 256 << 20
+
+// exportSessions saves session snapshot of NAT to a file.
+func exportSessions(ctx context.Context, c upd.UpdaterClient, fileName string) error {
+	snapshot, err := c.ExportSessions(ctx, &upd.SessionsExportRequest{}, grpc.MaxCallRecvMsgSize(maxSnapshotSize))
+	if err != nil {
+		return err
+	}
+	data, err := proto.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(fileName, data, 0600); err != nil {
+		return err
+	}
+	log.Printf("exported %d sessions to %s", len(snapshot.GetSessions()), fileName)
+	return nil
+}
+
+// importSessions sends session snapshot from a file to NAT.
+func importSessions(ctx context.Context, c upd.UpdaterClient, fileName string) error {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	snapshot := &upd.SessionSnapshot{}
+	if err := proto.Unmarshal(data, snapshot); err != nil {
+		return fmt.Errorf("Bad session snapshot file %s: %v", fileName, err)
+	}
+	reply, err := c.ImportSessions(ctx, snapshot, grpc.MaxCallSendMsgSize(maxSnapshotSize))
+	if err != nil {
+		return err
+	}
+	log.Printf("update successful: \"%s\"", reply.String())
+	return nil
+}
+
+// saveRunningConfig saves configuration which is in effect in NAT to a
+// file in config file format.
+func saveRunningConfig(ctx context.Context, c upd.UpdaterClient, fileName string) error {
+	reply, err := c.GetRunningConfig(ctx, &upd.RunningConfigRequest{})
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(fileName, []byte(reply.GetConfig()), 0600); err != nil {
+		return err
+	}
+	log.Printf("saved running config to %s", fileName)
+	return nil
+}
+
+// commitConfig sends candidate config from a file to NAT. Argument is
+// file[,timeout] where timeout is time in seconds to confirm commit.
+func commitConfig(ctx context.Context, c upd.UpdaterClient, arg string) error {
+	parts := strings.Split(arg, ",")
+	if len(parts) > 2 {
+		return fmt.Errorf("Bad commit specification \"%s\", should be file[,timeout]", arg)
+	}
+	req := &upd.CommitConfigRequest{}
+	if len(parts) == 2 {
+		timeout, err := strconv.ParseUint(parts[1], 10, 32)
+		if err != nil {
+			return fmt.Errorf("Bad confirm timeout \"%s\"", parts[1])
+		}
+		req.ConfirmTimeout = uint32(timeout)
+	}
+	data, err := ioutil.ReadFile(parts[0])
+	if err != nil {
+		return err
+	}
+	req.Config = string(data)
+	reply, err := c.CommitConfig(ctx, req)
+	if err != nil {
+		return err
+	}
+	log.Printf("update successful: \"%s\"", reply.String())
+	return nil
+}
+
+func streamDump(c upd.UpdaterClient, value string) error {
+	parts := strings.Split(value, ",")
+	if len(parts) < 2 {
+		return fmt.Errorf("Bad dump stream specification \"%s\"", value)
+	}
+	traceTypes, err := parseTraceTypes(parts[0])
+	if err != nil {
+		return err
+	}
+	ids, err := parseInterfaceIDs(parts[2:])
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(parts[1])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	// Nanosecond resolution pcap global header
+	err = binary.Write(file, binary.LittleEndian, []uint32{0xa1b23c4d, 2 | 4<<16, 0, 0, 65535, 1})
+	if err != nil {
+		return err
+	}
+
+	stream, err := c.StreamDump(context.Background(), &upd.DumpStreamRequest{
+		TraceTypes:   traceTypes,
+		InterfaceIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("writing dump to %s, press Ctrl-C to stop", parts[1])
+	for {
+		p, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = binary.Write(file, binary.LittleEndian, []uint32{
+			uint32(p.GetTimestamp() / 1e9),
+			uint32(p.GetTimestamp() % 1e9),
+			uint32(len(p.GetData())),
+			uint32(len(p.GetData())),
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(p.GetData()); err != nil {
+			return err
+		}
+	}
+}
+
+// portName formats port index and tenant of its port pair.
+func portName(id uint32, tenant string) string {
+	if tenant == "" {
+		return fmt.Sprintf("port %d", id)
+	}
+	return fmt.Sprintf("port %d (tenant %s)", id, tenant)
+}
+
+// printFlowGraph prints flow graph in Graphviz DOT format with a
+// cluster for every port pair.
+func printFlowGraph(graph *upd.FlowGraphReply) {
+	fmt.Println("digraph nat {")
+	fmt.Println("\trankdir=LR;")
+	pair := -1
+	for _, n := range graph.GetNodes() {
+		if int(n.GetPair()) != pair {
+			if pair >= 0 {
+				fmt.Println("\t}")
+			}
+			pair = int(n.GetPair())
+			fmt.Printf("\tsubgraph cluster_%d {\n\t\tlabel=\"port pair %d\";\n", pair, pair)
+		}
+		label := n.GetKind()
+		if n.GetName() != "" {
+			label += "\\n" + n.GetName()
+		}
+		switch n.GetKind() {
+		case "receiver", "sender", "kni":
+			label += fmt.Sprintf("\\nport %d", n.GetInterfaceId())
+		}
+		fmt.Printf("\t\t%q [label=\"%s\"];\n", n.GetId(), label)
+	}
+	if pair >= 0 {
+		fmt.Println("\t}")
+	}
+	for _, e := range graph.GetEdges() {
+		if e.GetCounted() {
+			fmt.Printf("\t%q -> %q [label=\"%.0f pps\\n%d packets\"];\n", e.GetFrom(), e.GetTo(),
+				e.GetPacketsPerSecond(), e.GetPackets())
+		} else {
+			fmt.Printf("\t%q -> %q;\n", e.GetFrom(), e.GetTo())
+		}
+	}
+	fmt.Println("}")
+}
+
+func printDHCPLease(lease *upd.DHCPLeaseReply) {
+	fmt.Printf("%s, IPv6 %v: state %s", portName(lease.GetInterfaceId(), lease.GetTenant()), lease.GetIpv6(), lease.GetState().String())
+	if lease.GetSubnet() != nil {
+		fmt.Printf(", address %s/%d", net.IP(lease.GetSubnet().GetAddress().GetAddress()).String(),
+			lease.GetSubnet().GetMaskBitsNumber())
+	}
+	if lease.GetServer() != nil {
+		fmt.Printf(", server %s", net.IP(lease.GetServer().GetAddress()).String())
+	}
+	if lease.GetLeaseObtained() != 0 {
+		fmt.Printf(", lease %ds, T1 %ds, T2 %ds, expires %s", lease.GetLeaseSeconds(),
+			lease.GetRenewSeconds(), lease.GetRebindSeconds(),
+			time.Unix(lease.GetLeaseExpires(), 0).String())
+	}
+	fmt.Println()
+}
+
+// Bearer token sent with every request.
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{
+		"authorization": "Bearer " + string(t),
+	}, nil
+}
+
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+func dialOptions(useTLS bool, caFile, certFile, keyFile, token string) ([]grpc.DialOption, error) {
+	opts := []grpc.DialOption{}
+	if useTLS || caFile != "" || certFile != "" {
+		config := &tls.Config{}
+		if caFile != "" {
+			pem, err := ioutil.ReadFile(caFile)
+			if err != nil {
+				return nil, err
+			}
+			config.RootCAs = x509.NewCertPool()
+			if !config.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("failed to parse CA file %s", caFile)
+			}
+		}
+		if certFile != "" {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				return nil, err
+			}
+			config.Certificates = []tls.Certificate{cert}
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(config)))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+	if token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials(token)))
+	}
+	return opts, nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Printf(`Usage: client [-a server:port] [-d {+|-}{d|t|k}] [-s index:subnet] [-p {+|-},{TCP|UDP|TCP6|UDP6},port number,target IP address,target port] [-n {l|+|-|f|F},index[,IP address[,MAC address]]] [-c {lease|renew|release|restart},index[,ipv6]]
+       [-wol index,MAC address[,password]] [-stats index] [-link index]
+       [-police-egress index,rate[/burst],host rate[/burst][,address=rate[/burst]...]] [-subscribers index] [-top-talkers index[,count]]
+       [-applications index] [-countries index] [-export-sessions file] [-import-sessions file] [-r {+,address,types[,tls|tls-insecure|tls=CA file][,index...]|-,address}] [-w types,file[,index...]]
+       [-tls] [-ca file] [-cert file -key file] [-token token]
+
+Client sends GRPS requests to NAT server controlling packets trace dump,
+ports subnet adresses, forwarded ports, neighbor tables and DHCP
+clients. Multiple requests of the same type are allowed and are processed
+in the following order: all dump, all subnet, all port forwarding, all
+neighbor table, all DHCP, all Wake-on-LAN, all statistics, all remote
+dump sink requests. Dump stream requested with -w is received after all
+other requests are processed.
+
+`)
+		flag.PrintDefaults()
+	}
+	address := flag.String("a", "localhost:60602", "Specifies server address")
+	useTLS := flag.Bool("tls", false, "Connect to server using TLS")
+	caFile := flag.String("ca", "", "CA certificates PEM file used to verify server certificate, implies -tls")
+	certFile := flag.String("cert", "", "Client certificate PEM file, implies -tls")
+	keyFile := flag.String("key", "", "Client certificate key PEM file")
+	token := flag.String("token", "", "Authorization token sent to server")
+	flag.Var(&dumpRequests, "d", `Control dump trace output in a form of +/- and letter,
+e.g. +d or -t or +k:
+    + and - mean to enable or disable corresponding trace,
+    d means to trace dropped packets,
+    t means to trace translated (normally sent) packets,
+    k means to trace packets that were sent to KNI interface.`)
+	flag.Var(&addresChangeRequests, "s", `Control network interface subnet in a form of index:subnet,
+e.g. 1,192.168.5.1/24 or 1,fd16::1/128. Port index is DPDK port
+number. Subnet is given in form of port IP address and prefix bits.
+Optional keep suffix, e.g. 1,192.168.5.1/24,keep, keeps sessions of
+old public port address on it until they expire.`)
+	flag.Var(&portForwardRequests, "p", `Control TCP and UDP port forwarding in a form of
++/-,index,protocol,source port,target IP address,target port, e.g.
++,1,TCP,2222,192.168.5.7,22 or -,0,TCP,22,0.0.0.0,0 or
++,1,TCP6,2222,fd14::3,22 or -,0,TCP6,22,::,0. If target
+address is zero, it means that port is forwarded to corresponding
+network port KNI interface. Port forwarding to a non-zero
+target address (not to a KNI interface) is possible only for
+public network port. Link local IPv6 target address should have
+zone with name or index of private port, e.g.
++,1,TCP6,2222,fe80::7%0,22. Remote hosts within prefixes may be
+forwarded to other targets listed after target port in a form of
+prefix=target IP address:target port, e.g.
++,1,TCP,2222,192.168.5.7,22,198.51.100.0/24=192.168.5.8:22.
+Public port may be forwarded to an address of its KNI interface
+with another target port by kni= prefix of target address, e.g.
++,0,TCP6,2222,kni=fd00::1,8022.`)
+	flag.Var(&neighborRequests, "n", `Inspect and change port ARP/ND neighbor table in a form of
+operation,index[,IP address[,MAC address]], e.g. l,0 or
++,1,192.168.5.7,52:54:00:12:34:56 or -,1,fd14::3 or f,1:
+    l means to list neighbor table entries,
+    + means to add a static entry which is never overwritten by
+      learned addresses,
+    - means to delete an entry,
+    f means to flush all learned entries,
+    F means to flush all entries including static ones.`)
+	flag.Var(&dhcpRequests, "c", `Control port DHCP client in a form of request,index[,ipv6], e.g.
+lease,0 or renew,1 or restart,1,ipv6. Optional ipv6 means to
+control DHCPv6 client instead of DHCPv4:
+    lease means to print current lease details,
+    renew means to ask DHCP server to extend current lease,
+    release means to release current lease, no new address is
+      requested until client is restarted,
+    restart means to forget current lease and start acquiring
+      address from scratch.`)
+	flag.Var(&wakeOnLANRequests, "wol", `Send Wake-on-LAN magic packet to private port network in a form of
+index,MAC address[,password], e.g. 0,52:54:00:12:34:56 or
+0,52:54:00:12:34:56,01:02:03:04:05:06. Packet is broadcast to
+port subnet. Optional SecureOn password is given either in
+MAC address form or in IPv4 address form.`)
+	flag.Var(&statisticsRequests, "stats", `Print NAT packet counters and network card extended statistics
+of port with specified index, e.g. 0.`)
+	flag.Var(&linkStatusRequests, "link", `Print link state of network card port with specified index, e.g. 0.`)
+	flag.Var(&egressPolicerRequests, "police-egress", `Change egress policer of port pair in a form of
+index,rate[/burst],host rate[/burst][,address=rate[/burst]...], e.g.
+1,100000,10000 or 1,100000/1500000,0,192.168.14.20=50000. Rates
+are in kilobits per second, zero rate means no limit, bursts are in
+bytes. Global rate applies to all packets sent by public port, host
+rate applies to every private host which is not listed separately.
+Policer has to be enabled in config.`)
+	flag.Var(&subscribersRequests, "subscribers", `Print policing counters of subscribers of port pair with specified port
+index, e.g. 0. Every line contains private address and conforming,
+exceeding and dropped bytes of egress and then ingress traffic and
+MAC address of private host.`)
+	flag.Var(&topTalkersRequests, "top-talkers", `Print translated traffic by IP protocol and private hosts and public
+destinations with most bytes of port pair with specified port index,
+e.g. 0,10. Optional count limits number of printed hosts and
+destinations. Every protocol line contains protocol number and egress
+and then ingress packets and bytes. Every host and destination line
+contains address, packets, bytes and maximum overestimation of packets
+and bytes. Top talkers have to be enabled in config.`)
+	flag.Var(&applicationsRequests, "applications", `Print translated traffic by application of port pair with specified
+port index, e.g. 0. Every line contains application name, tagged and
+active sessions and egress and then ingress packets and bytes.
+Application identification has to be enabled in config.`)
+	flag.Var(&countriesRequests, "countries", `Print translated traffic by country of remote hosts of port pair
+with specified port index, e.g. 0. Every line contains country code,
+tagged sessions, egress and then ingress packets and bytes and packets
+denied by country policy. Country accounting has to be enabled in
+config.`)
+	flag.Var(&announcementRequests, "announce", `Withdraw public addresses of port pair with specified port index
+from routing daemon or announce them again, e.g. 1,withdraw or
+1,announce. Route announcement has to be enabled in config.`)
+	flag.Var(&sessionDeleteRequests, "delete-session", `Delete dynamic session and free its public port immediately in a
+form of index,protocol,address,port, e.g. 1,TCP,203.0.113.5,1025 or
+0,UDP,192.168.14.7,5060 or 0,ICMP6,fd14::3,17. Protocol is one of
+TCP, UDP, ICMP or ICMP6. Session is identified by public address
+and port when index is public port or by private address and port
+when index is private port of port pair, ICMP sessions by query
+identifier instead of port.`)
+	flag.Var(&sessionsFindRequests, "find-sessions", `Print sessions of port pair with specified port index which match
+all conditions in a form of index[,condition=value...], e.g.
+0,protocol=TCP,private=192.168.14.7,min-bytes=1000000 or
+1,kind=static,limit=100,cursor=65537. Conditions are protocol (TCP,
+UDP, ICMP or ICMP6), public, private and remote addresses, public-port,
+private-port and remote-port, min-age and max-age in seconds since
+session creation, min-bytes and max-bytes, kind (dynamic or static)
+and mac of private host. limit is maximum number of printed sessions,
+1000 by default, cursor continues search where previous one stopped.
+Every line contains protocol, public, private and remote address and
+port, kind, age, idle time and remaining lifetime in seconds, packets,
+bytes and MAC address of private host.`)
+	flag.Var(&blackholeRequests, "blackhole", `Inspect and change blackhole rules of port pair with specified port
+index in a form of operation,index[,destination[,action]], e.g. l,0
+or +,0,198.51.100.0/24 or +,0,c2.example.com,kni or
+-,0,198.51.100.0/24:
+    l means to list rules with matched packets and resolved
+      addresses of domain names,
+    + means to add a rule which drops traffic of private hosts to
+      destination prefix, address or domain name, or sends it to
+      private port KNI interface with kni action,
+    - means to remove a rule.`)
+	flag.Var(&portTriggersRequests, "port-triggers", `Inspect or replace port trigger rules of port pair with specified
+port index in a form of operation,index[,file], e.g. l,0 or
+s,0,triggers.json:
+    l means to list rules and ports which are opened to private hosts,
+    s means to replace all rules with rules from JSON file, which has
+      the same format as port-triggers option of config, empty list
+      removes all rules.`)
+	flag.Var(&logSamplingRequests, "log-sampling", `Change session log sampling of port pair in a form of
+index,rate[,host...], e.g. 0,100 or 0,100,192.168.14.20. One of
+every rate sessions is logged, zero or one means all sessions.
+Sessions of listed private hosts are always logged, host is an IP
+address or a MAC address when host identity is tracked. Sampling
+applies to sessions created after change.`)
+	flag.Var(&externalRequests, "external-address", `Print external address and port of public port with specified index,
+e.g. 1, which STUN server reported. STUN discovery has to be enabled
+in config.`)
+	flowGraph := flag.Bool("flow-graph", false, `Print flow graphs of port pairs with packet rates of their edges
+measured during a second in Graphviz DOT format, e.g. for
+client -flow-graph | dot -Tsvg > flows.svg`)
+	nftables := flag.Bool("nftables", false, `Print forwarded ports, netmap, DMZ hosts, source NAT, blackhole and
+KNI steering rules of port pairs as nftables ruleset, e.g. to compare
+with ruleset of Linux NAT. Server of NAT instance prints only its
+port pairs.`)
+	flag.Var(&packetTraceRequests, "trace-packet", `Print what NAT would do with packet received by port with specified
+index without sending it, in a form of index,protocol,source,source-port,
+destination,destination-port[,syn][,ttl=N][,options], e.g. 1,TCP,
+198.51.100.7,40000,203.0.113.5,443,syn or 0,UDP,192.168.14.7,5060,
+198.51.100.7,5060. Protocol is one of TCP, UDP, ICMP, ICMP6 or
+protocol number, ports of other protocols are ignored. syn marks TCP
+packet which starts new connection, ICMP echo request has query
+identifier as source port. ttl sets IPv4 TTL or IPv6 hop limit which
+is 64 by default, options marks IPv4 packet with options or IPv6
+jumbogram.`)
+	flag.Var(&handshakesRequests, "handshakes", `Print TCP handshakes of forwarded ports of port pair with specified
+port index, e.g. 0. Every forwarded port line contains port, attempts,
+completed, pending, no-reply, refused, incomplete and untracked
+handshakes, it is followed by recent failed handshakes with time,
+remote address and port and reason. Handshake tracking has to be
+enabled in config.`)
+	flag.Var(&identitiesRequests, "host-identities", `Print private hosts of port pair with specified port index by their
+MAC addresses, e.g. 0. Every line contains MAC address, time when
+host was first and last seen, IPv4 address changes, egress and then
+ingress packets and bytes and addresses of host. Host identity has to
+be enabled in config.`)
+	flag.Var(&maintenanceRequests, "maintenance", `Put port pair with specified port index into maintenance, take it out
+or print its state in a form of index,on[,flush][,reject] or
+index,off or index, e.g. 1,on,flush or 1. Port pair in maintenance
+creates no new sessions, flush removes existing dynamic sessions and
+reject answers new sessions with TCP reset or ICMP port unreachable.`)
+	flag.Var(&portalRequests, "portal", `Authenticate, unauthenticate or forget private host of captive portal
+of port pair with specified port index, or print states of hosts, in
+a form of index,host,auth|unauth|forget or index, e.g.
+1,192.168.14.20,auth or 1,02:00:00:00:00:01,unauth or 1. Host is an IP
+or MAC address, forgotten hosts get default state of config. HTTP
+flows of unauthenticated hosts are redirected to portal.`)
+	exportFile := flag.String("export-sessions", "", `Save dynamic sessions of all port pairs to a session snapshot file
+in protobuf format, e.g. sessions.pb.`)
+	importFile := flag.String("import-sessions", "", `Add sessions from a session snapshot file to NAT. Sessions which
+don't match public port address or private subnet of their port
+pair, conflict with existing sessions or are expired are skipped.`)
+	runningConfigFile := flag.String("running-config", "", `Save configuration which is in effect in NAT to a file in config
+file format, e.g. running.json. It includes forwarded ports, static
+neighbors and rules changed with control API and addresses acquired
+with DHCP. Server of NAT instance saves only its instance.`)
+	commitArg := flag.String("commit", "", `Change settings of running config to settings of candidate config
+file in a form of file[,timeout], e.g. running.json or
+running.json,60. Candidate is usually running config saved with
+-running-config and edited. Only addresses, forwarded ports, static
+neighbors, egress policers, port triggers, blackhole rules and
+session log sampling may differ from running config. With timeout
+commit is rolled back unless it is confirmed in this many seconds.`)
+	confirmCommit := flag.Bool("confirm-commit", false, "Confirm commit made with -commit and timeout")
+	rollbackCommit := flag.Bool("rollback-commit", false, "Roll back commit made with -commit and timeout without waiting for it to expire")
+	flag.Var(&dumpSinkRequests, "r", `Connect NAT dump output to remote collector or disconnect it in a form of
++,address,types[,tls][,index...] or -,address, e.g.
++,collector:5000,dk or +,collector:5000,t,tls=ca.pem,0,1 or
+-,collector:5000. NAT connects to collector TCP address and sends
+all dumped packets in a single pcap stream. Types are letters
+as in -d option. Optional tls means to use TLS with system CA
+certificates, tls-insecure disables certificate verification
+and tls=file uses CA certificate from PEM file. Optional port
+indices limit dump to specified ports.`)
+	streamFile := flag.String("w", "", `Stream NAT dump output to a local pcap file in a form of
+types,file[,index...], e.g. dk,nat.pcap or t,nat.pcap,1. Types
+are letters as in -d option. Optional port indices limit dump
+to specified ports. Client runs until interrupted.`)
+	flag.Parse()
+
+	// Set up a connection to the server.
+	opts, err := dialOptions(*useTLS, *caFile, *certFile, *keyFile, *token)
+	if err != nil {
+		log.Fatalf("bad connection options: %v", err)
+	}
+	conn, err := grpc.Dial(*address, opts...)
+	if err != nil {
+		log.Fatalf("did not connect: %v", err)
+	}
+	defer conn.Close()
+	c := upd.NewUpdaterClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, r := range dumpRequests {
+		reply, err := c.ControlDump(ctx, r)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("update successful: \"%s\"", reply.String())
+	}
+
+	for _, r := range addresChangeRequests {
+		reply, err := c.ChangeInterfaceAddress(ctx, r)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("update successful: \"%s\"", reply.String())
+	}
+
+	for _, r := range portForwardRequests {
+		reply, err := c.ChangePortForwarding(ctx, r)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("update successful: \"%s\"", reply.String())
+	}
+
+	for _, r := range neighborRequests {
+		var reply *upd.Reply
+		var err error
+		switch {
+		case r.get != nil:
+			var neighbors *upd.NeighborsReply
+			neighbors, err = c.GetNeighbors(ctx, r.get)
+			if err == nil {
+				log.Printf("%s neighbors:", portName(r.get.GetInterfaceId(), neighbors.GetTenant()))
+				for _, n := range neighbors.GetNeighbors() {
+					kind := "learned"
+					if n.GetStatic() {
+						kind = "static"
+					}
+					fmt.Printf("%s\t%s\t%s\n", net.IP(n.GetAddress().GetAddress()).String(),
+						net.HardwareAddr(n.GetMacAddress()).String(), kind)
+				}
+				continue
+			}
+		case r.add != nil:
+			reply, err = c.AddStaticNeighbor(ctx, r.add)
+		case r.delete != nil:
+			reply, err = c.DeleteNeighbor(ctx, r.delete)
+		case r.flush != nil:
+			reply, err = c.FlushNeighbors(ctx, r.flush)
+		}
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("update successful: \"%s\"", reply.String())
+	}
+
+	for _, r := range dhcpRequests {
+		var lease *upd.DHCPLeaseReply
+		var err error
+		if r.control != nil {
+			lease, err = c.ControlDHCP(ctx, r.control)
+		} else {
+			lease, err = c.GetDHCPLease(ctx, r.lease)
+		}
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		printDHCPLease(lease)
+	}
+
+	for _, r := range wakeOnLANRequests {
+		reply, err := c.SendWakeOnLAN(ctx, r)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("update successful: \"%s\"", reply.String())
+	}
+
+	for _, r := range statisticsRequests {
+		stats, err := c.GetPortStatistics(ctx, r)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("%s statistics:", portName(stats.GetInterfaceId(), stats.GetTenant()))
+		for _, counter := range stats.GetNatCounters() {
+			fmt.Printf("nat\t%s\t%d\n", counter.GetName(), counter.GetValue())
+		}
+		for _, counter := range stats.GetNicCounters() {
+			fmt.Printf("nic\t%s\t%d\n", counter.GetName(), counter.GetValue())
+		}
+	}
+
+	for _, r := range linkStatusRequests {
+		link, err := c.GetLinkStatus(ctx, r)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		if !link.GetUp() {
+			log.Printf("%s link is down", portName(link.GetInterfaceId(), link.GetTenant()))
+			continue
+		}
+		duplex := "half duplex"
+		if link.GetFullDuplex() {
+			duplex = "full duplex"
+		}
+		autoneg := ""
+		if link.GetAutonegotiation() {
+			autoneg = ", autonegotiated"
+		}
+		log.Printf("%s link is up, %d Mbps %s%s", portName(link.GetInterfaceId(), link.GetTenant()), link.GetSpeedMbps(), duplex, autoneg)
+	}
+
+	for _, r := range egressPolicerRequests {
+		reply, err := c.ChangeEgressPolicer(ctx, r)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("update successful: \"%s\"", reply.String())
+	}
+
+	for _, r := range subscribersRequests {
+		subscribers, err := c.GetSubscribers(ctx, r)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("%s subscribers:", portName(r.GetInterfaceId(), subscribers.GetTenant()))
+		for _, sub := range subscribers.GetSubscribers() {
+			e, i := sub.GetEgress(), sub.GetIngress()
+			address := "-"
+			if sub.GetAddress() != nil {
+				address = net.IP(sub.GetAddress().GetAddress()).String()
+			}
+			fmt.Printf("%s\t%d\t%d\t%d\t%d\t%d\t%d\t%s\n", address,
+				e.GetConformBytes(), e.GetExceedBytes(), e.GetDropBytes(),
+				i.GetConformBytes(), i.GetExceedBytes(), i.GetDropBytes(), macString(sub.GetMacAddress()))
+		}
+	}
+
+	for _, r := range topTalkersRequests {
+		talkers, err := c.GetTopTalkers(ctx, r)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		port := portName(r.GetInterfaceId(), talkers.GetTenant())
+		log.Printf("%s protocols:", port)
+		for _, p := range talkers.GetProtocols() {
+			fmt.Printf("%d\t%d\t%d\t%d\t%d\n", p.GetProtocol(),
+				p.GetEgressPackets(), p.GetEgressBytes(), p.GetIngressPackets(), p.GetIngressBytes())
+		}
+		printTalkers := func(list []*upd.Talker) {
+			for _, t := range list {
+				fmt.Printf("%s\t%d\t%d\t%d\t%d\n", net.IP(t.GetAddress().GetAddress()).String(),
+					t.GetPackets(), t.GetBytes(), t.GetErrorPackets(), t.GetErrorBytes())
+			}
+		}
+		log.Printf("%s top hosts:", port)
+		printTalkers(talkers.GetHosts())
+		log.Printf("%s top destinations:", port)
+		printTalkers(talkers.GetDestinations())
+	}
+
+	for _, r := range applicationsRequests {
+		apps, err := c.GetApplications(ctx, r)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("%s applications:", portName(r.GetInterfaceId(), apps.GetTenant()))
+		for _, a := range apps.GetApplications() {
+			fmt.Printf("%s\t%d\t%d\t%d\t%d\t%d\t%d\n", a.GetName(), a.GetSessions(), a.GetActiveSessions(),
+				a.GetEgressPackets(), a.GetEgressBytes(), a.GetIngressPackets(), a.GetIngressBytes())
+		}
+	}
+
+	for _, r := range countriesRequests {
+		countries, err := c.GetCountries(ctx, r)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("%s countries:", portName(r.GetInterfaceId(), countries.GetTenant()))
+		for _, cc := range countries.GetCountries() {
+			fmt.Printf("%s\t%d\t%d\t%d\t%d\t%d\t%d\n", cc.GetCountry(), cc.GetSessions(),
+				cc.GetEgressPackets(), cc.GetEgressBytes(), cc.GetIngressPackets(), cc.GetIngressBytes(), cc.GetDeniedPackets())
+		}
+	}
+
+	for _, r := range announcementRequests {
+		reply, err := c.ControlRouteAnnouncement(ctx, r)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("update successful: \"%s\"", reply.String())
+	}
+
+	for _, r := range blackholeRequests {
+		if r.get != nil {
+			rules, err := c.GetBlackholeRules(ctx, r.get)
+			if err != nil {
+				log.Fatalf("could not update: %v", err)
+			}
+			log.Printf("%s blackhole rules:", portName(r.get.GetInterfaceId(), rules.GetTenant()))
+			for _, rule := range rules.GetRules() {
+				addrs := []string{}
+				for _, a := range rule.GetAddresses() {
+					addrs = append(addrs, net.IP(a.GetAddress()).String())
+				}
+				fmt.Printf("%s\t%s\t%d\t%s\n", rule.GetDestination(), rule.GetAction(), rule.GetPackets(), strings.Join(addrs, " "))
+			}
+			continue
+		}
+		reply, err := c.ChangeBlackholeRule(ctx, r.change)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("update successful: \"%s\"", reply.String())
+	}
+
+	for _, r := range portTriggersRequests {
+		if r.get != nil {
+			triggers, err := c.GetPortTriggers(ctx, r.get)
+			if err != nil {
+				log.Fatalf("could not update: %v", err)
+			}
+			port := portName(r.get.GetInterfaceId(), triggers.GetTenant())
+			log.Printf("%s port triggers:", port)
+			for _, t := range triggers.GetTriggers() {
+				fmt.Printf("%s\t%s\t%s\t%s\n", t.GetProtocol(), t.GetPorts(), t.GetOpenProtocol(), strings.Join(t.GetOpenPorts(), " "))
+			}
+			log.Printf("%s opened ports:", port)
+			for _, o := range triggers.GetOpened() {
+				fmt.Printf("%d\t%d\t%s\n", o.GetProtocol(), o.GetPort(), net.IP(o.GetHost().GetAddress()).String())
+			}
+			continue
+		}
+		reply, err := c.SetPortTriggers(ctx, r.set)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("update successful: \"%s\"", reply.String())
+	}
+
+	for _, r := range logSamplingRequests {
+		reply, err := c.ChangeSessionLogSampling(ctx, r)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("update successful: \"%s\"", reply.String())
+	}
+
+	for _, r := range externalRequests {
+		external, err := c.GetExternalAddress(ctx, r)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		name := portName(external.GetInterfaceId(), external.GetTenant())
+		server := net.IP(external.GetServer().GetAddress()).String()
+		if external.GetAddress() == nil {
+			log.Printf("%s external address is not known, STUN server %s didn't answer", name, server)
+			continue
+		}
+		log.Printf("%s external address is %s port %d, reported by STUN server %s %s ago", name,
+			net.IP(external.GetAddress().GetAddress()).String(), external.GetPort(), server,
+			time.Since(time.Unix(external.GetLearned(), 0)).Round(time.Second))
+	}
+
+	for _, r := range maintenanceRequests {
+		var reply *upd.MaintenanceReply
+		var err error
+		if r.set != nil {
+			reply, err = c.SetMaintenance(ctx, r.set)
+		} else {
+			reply, err = c.GetMaintenance(ctx, r.get)
+		}
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		name := portName(reply.GetInterfaceId(), reply.GetTenant())
+		if !reply.GetMaintenance() {
+			log.Printf("Port pair of %s is not in maintenance, %d active sessions", name, reply.GetActiveSessions())
+			continue
+		}
+		answer := "dropped"
+		if reply.GetReject() {
+			answer = "rejected"
+		}
+		log.Printf("Port pair of %s is in maintenance for %s, %d new sessions %s, %d sessions flushed, %d active sessions",
+			name, time.Since(time.Unix(reply.GetSince(), 0)).Round(time.Second), reply.GetRefusedSessions(), answer,
+			reply.GetFlushedSessions(), reply.GetActiveSessions())
+	}
+
+	for _, r := range portalRequests {
+		var reply *upd.CaptivePortalHostsReply
+		var err error
+		if r.set != nil {
+			reply, err = c.SetCaptivePortalHost(ctx, r.set)
+		} else {
+			reply, err = c.GetCaptivePortalHosts(ctx, r.get)
+		}
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("%s captive portal hosts, default state %s, %d redirected flows:",
+			portName(reply.GetInterfaceId(), reply.GetTenant()), reply.GetDefaultState(), reply.GetRedirectedFlows())
+		for _, h := range reply.GetHosts() {
+			host := net.HardwareAddr(h.GetMacAddress()).String()
+			if h.GetAddress() != nil {
+				host = net.IP(h.GetAddress().GetAddress()).String()
+			}
+			fmt.Printf("%s\t%s\n", host, h.GetState())
+		}
+	}
+
+	if *flowGraph {
+		graph, err := c.GetFlowGraph(ctx, &upd.FlowGraphRequest{})
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		printFlowGraph(graph)
+	}
+
+	if *nftables {
+		reply, err := c.GetNftablesRuleset(ctx, &upd.NftablesRulesetRequest{})
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		fmt.Print(reply.GetRuleset())
+	}
+
+	for _, r := range packetTraceRequests {
+		reply, err := c.TracePacket(ctx, r)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("Packet received by %s:", portName(r.GetInterfaceId(), reply.GetTenant()))
+		for _, step := range reply.GetSteps() {
+			fmt.Printf("%s\t%s\n", step.GetStage(), step.GetResult())
+		}
+		fmt.Printf("verdict\t%s by port %d, packet %s:%d -> %s:%d\n", reply.GetVerdict(), reply.GetOutputInterfaceId(),
+			net.IP(reply.GetSource().GetAddress()).String(), reply.GetSourcePort(),
+			net.IP(reply.GetDestination().GetAddress()).String(), reply.GetDestinationPort())
+	}
+
+	for _, r := range handshakesRequests {
+		reply, err := c.GetForwardedHandshakes(ctx, r)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("%s forwarded port handshakes:", portName(r.GetInterfaceId(), reply.GetTenant()))
+		for _, f := range reply.GetForwards() {
+			protocol := "TCP"
+			if f.GetIpv6() {
+				protocol = "TCP6"
+			}
+			fmt.Printf("%s/%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\n", protocol, f.GetPort(), f.GetAttempts(), f.GetCompleted(), f.GetPending(),
+				f.GetNoReply(), f.GetRefused(), f.GetIncomplete(), f.GetOverflow())
+			for _, h := range f.GetFailed() {
+				fmt.Printf("\t%s\t%s\t%d\t%s\n", time.Unix(0, h.GetTime()).Format(time.RFC3339),
+					net.IP(h.GetAddress().GetAddress()).String(), h.GetPort(), h.GetReason())
+			}
+		}
+	}
+
+	for _, r := range identitiesRequests {
+		reply, err := c.GetHostIdentities(ctx, r)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("%s private hosts, %d untracked:", portName(r.GetInterfaceId(), reply.GetTenant()), reply.GetOverflow())
+		for _, h := range reply.GetHosts() {
+			addresses := []string{}
+			for _, a := range h.GetAddresses() {
+				addresses = append(addresses, net.IP(a.GetAddress()).String())
+			}
+			fmt.Printf("%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%s\n", macString(h.GetMacAddress()),
+				time.Unix(0, h.GetFirstSeen()).Format(time.RFC3339), time.Unix(0, h.GetLastSeen()).Format(time.RFC3339),
+				h.GetAddressChanges(), h.GetEgressPackets(), h.GetEgressBytes(), h.GetIngressPackets(), h.GetIngressBytes(),
+				strings.Join(addresses, ","))
+		}
+	}
+
+	for _, r := range sessionDeleteRequests {
+		reply, err := c.DeleteSession(ctx, r)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("update successful: \"%s\"", reply.String())
+	}
+
+	for _, r := range sessionsFindRequests {
+		found, err := c.FindSessions(ctx, r)
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("%s sessions:", portName(r.GetInterfaceId(), found.GetTenant()))
+		now := time.Now()
+		for _, session := range found.GetSessions() {
+			kind := "dynamic"
+			if session.GetStatic() {
+				kind = "static"
+			}
+			fmt.Printf("%d\t%s\t%s\t%s\t%s\t%d\t%d\t%.3f\t%d\t%d\t%s\n", session.GetProtocol(),
+				sessionEndpoint(session.GetPublicAddress(), session.GetPublicPort()),
+				sessionEndpoint(session.GetPrivateAddress(), session.GetPrivatePort()),
+				sessionEndpoint(session.GetRemoteAddress(), session.GetRemotePort()), kind,
+				int64(now.Sub(time.Unix(0, session.GetCreated())).Seconds()),
+				int64(now.Sub(time.Unix(0, session.GetLastUsed())).Seconds()),
+				float64(session.GetRemainingLifetimeMs())/1000,
+				session.GetPackets(), session.GetBytes(), macString(session.GetPrivateMacAddress()))
+		}
+		if next := found.GetNextCursor(); next != 0 {
+			log.Printf("more sessions match, continue with cursor=%d", next)
+		}
+	}
+
+	if *exportFile != "" {
+		if err := exportSessions(ctx, c, *exportFile); err != nil {
+			log.Fatalf("could not export sessions: %v", err)
+		}
+	}
+
+	if *importFile != "" {
+		if err := importSessions(ctx, c, *importFile); err != nil {
+			log.Fatalf("could not import sessions: %v", err)
+		}
+	}
+
+	if *runningConfigFile != "" {
+		if err := saveRunningConfig(ctx, c, *runningConfigFile); err != nil {
+			log.Fatalf("could not save running config: %v", err)
+		}
+	}
+
+	if *commitArg != "" {
+		if err := commitConfig(ctx, c, *commitArg); err != nil {
+			log.Fatalf("could not commit: %v", err)
+		}
+	}
+
+	if *confirmCommit {
+		reply, err := c.ConfirmCommit(ctx, &upd.ConfirmCommitRequest{})
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("update successful: \"%s\"", reply.String())
+	}
+
+	if *rollbackCommit {
+		reply, err := c.RollbackCommit(ctx, &upd.RollbackCommitRequest{})
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("update successful: \"%s\"", reply.String())
+	}
+
+	for _, r := range dumpSinkRequests {
+		var reply *upd.Reply
+		var err error
+		if r.connect != nil {
+			reply, err = c.ConnectDumpSink(ctx, r.connect)
+		} else {
+			reply, err = c.DisconnectDumpSink(ctx, r.disconnect)
+		}
+		if err != nil {
+			log.Fatalf("could not update: %v", err)
+		}
+		log.Printf("update successful: \"%s\"", reply.String())
+	}
+
+	if *streamFile != "" {
+		if err := streamDump(c, *streamFile); err != nil {
+			log.Fatalf("dump stream failed: %v", err)
 		}
-		log.Printf("update successful: \"%s\"", reply.String())
 	}
 }
@@ -22,6 +22,7 @@ require (
 	github.com/intel-go/nff-go v0.9.1
 	github.com/microcosm-cc/bluemonday v1.0.2 // indirect
 	github.com/nsf/gocode v0.0.0-20181120081338-6cac7c69a41e // indirect
+	github.com/openconfig/gnmi v0.0.0-20190823184014-89b2bf29312c
 	github.com/openzipkin/zipkin-go v0.1.5 // indirect
 	github.com/prometheus/client_model v0.0.0-20190129233127-fd36f4220a90 // indirect
 	github.com/prometheus/common v0.2.0 // indirect
@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/signal"
 	"runtime/pprof"
+	"syscall"
 
 	"github.com/intel-go/nff-go/flow"
 
@@ -45,6 +46,7 @@ func main() {
 	configFile := flag.String("config", "config.json", "Specify config file name.")
 	flag.BoolVar(&nat.NoCalculateChecksum, "nocsum", false, "Specify whether to calculate checksums in modified packets.")
 	flag.BoolVar(&nat.NoHWTXChecksum, "nohwcsum", false, "Specify whether to use hardware offloading for checksums calculation (requires -csum).")
+	flag.BoolVar(&nat.SetKNIRoutes, "set-kni-routes", false, "Install routes of port subnets and default gateways of public ports via KNI interfaces into host routing table and remove them on exit.")
 	noscheduler := flag.Bool("no-scheduler", false, "Disable scheduler.")
 	setKniIP := flag.Bool("set-kni-IP", false, "Set IP addresses specified in config file to created KNI interfaces. Do not use if your system uses Network Manager! Use Network Manager configurations instead.")
 	bringUpKniInterfaces := flag.Bool("bring-up-kni", false, "Set IP addresses specified in config file to created KNI interfaces. Do not use if your system uses Network Manager! Use Network Manager configurations instead.")
@@ -58,6 +60,10 @@ e.g. "-dump d" or "-dump dtk":
 	schedulerInterval := flag.Uint("scheduler-interval", 500, "Set scheduler interval in ms. Lower values allow faster reaction to changing traffic but increase scheduling overhead.")
 	sendCPUCoresPerPort := flag.Int("send-threads", 1, "Number of CPU cores to be occupied by Send routines.")
 	tXQueuesNumberPerPort := flag.Int("tx-queues", 4, "Number of transmit queues to use on network card.")
+	snmpAddress := flag.String("snmp", "", "Start SNMP agent on specified UDP address, e.g. \":161\". Agent is disabled by default.")
+	snmpCommunity := flag.String("snmp-community", "public", "SNMP community accepted by SNMP agent.")
+	noHuge := flag.Bool("no-huge", false, "Run DPDK without hugepages with virtual devices from flow-graph config only, e.g. for functional testing in containers. KNI interfaces cannot be used.")
+	selfTest := flag.Bool("selftest", false, "Bring up ports, check MAC addresses, links, checksum offloading and neighbors, print report and exit. Control API, SNMP agent, event notifications, conntrack synchronization and route announcements are not started.")
 	flag.Parse()
 
 	if *cpuprofile != "" {
@@ -69,9 +75,9 @@ e.g. "-dump d" or "-dump dtk":
 
 	nat.DumpEnabled = dumpControl
 
-	// Set up reaction to SIGINT (Ctrl-C)
+	// Set up reaction to SIGINT (Ctrl-C) and SIGTERM
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
 	// Read config
 	flow.CheckFatal(nat.ReadConfig(*configFile, *setKniIP, *bringUpKniInterfaces))
@@ -88,11 +94,45 @@ e.g. "-dump d" or "-dump dtk":
 		TXQueuesNumberPerPort: *tXQueuesNumberPerPort,
 	}
 
+	// Flow graph options from config file are used unless
+	// corresponding command line options are specified
+	explicitFlags := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		explicitFlags[f.Name] = true
+	})
+	fg := &nat.Natconfig.FlowGraph
+	if !explicitFlags["cores"] && fg.CPUList != "" {
+		nffgoconfig.CPUList = fg.CPUList
+	}
+	if !explicitFlags["no-scheduler"] && fg.DisableScheduler {
+		nffgoconfig.DisableScheduler = true
+	}
+	if !explicitFlags["scheduler-interval"] && fg.SchedulerInterval != 0 {
+		nffgoconfig.SchedulerInterval = fg.SchedulerInterval
+	}
+	nffgoconfig.RingSize = fg.RingSize
+	nffgoconfig.MbufNumber = fg.MbufNumber
+	nffgoconfig.MbufCacheSize = fg.MbufCacheSize
+	nffgoconfig.PersistentClones = fg.PersistentClones
+	nffgoconfig.RestrictedCloning = fg.RestrictedCloning
+	nffgoconfig.MaxInIndex = fg.ReceiveInstances
+
+	// Virtual devices and running without hugepages
+	ealArgs, err := nat.EALArgs(*noHuge || fg.NoHuge)
+	flow.CheckFatal(err)
+	nffgoconfig.DPDKArgs = append(nffgoconfig.DPDKArgs, ealArgs...)
+
+	// Put cores of KNI interfaces first so that NFF-Go assigns them
+	// to KNI devices
+	kniCPUList, err := nat.KNICPUList(nffgoconfig.CPUList)
+	flow.CheckFatal(err)
+	nffgoconfig.CPUList = kniCPUList
+
 	flow.CheckFatal(flow.SystemInit(&nffgoconfig))
 
 	offloadingAvailable := nat.CheckHWOffloading()
 	if !nat.NoHWTXChecksum && !offloadingAvailable {
-		println("Warning! Requested hardware offloading is not available on all ports. Falling back to software checksum calculation.")
+		println("Warning! Requested hardware offloading is not available on any port. Falling back to software checksum calculation.")
 		nat.NoHWTXChecksum = true
 		flow.SetUseHWCapability(flow.HWTXChecksumCapability, false)
 	}
@@ -100,25 +140,147 @@ e.g. "-dump d" or "-dump dtk":
 	// Initialize flows and necessary state
 	nat.InitFlows()
 
-	// Start GRPC server
-	flow.CheckFatal(nat.StartGRPCServer())
+	if !*selfTest {
+		// Start GRPC server
+		flow.CheckFatal(nat.StartGRPCServer())
+
+		// Start SNMP agent
+		if *snmpAddress != "" {
+			flow.CheckFatal(nat.StartSNMPAgent(*snmpAddress, *snmpCommunity))
+		}
+
+		// Start IGMP querier for port pairs which forward multicast
+		nat.StartMulticast()
+
+		// Start webhook notifications about operational events
+		nat.StartEventNotifications()
+
+		// Start exporting sessions to conntrackd
+		flow.CheckFatal(nat.StartConntrackSync())
+
+		// Start logging sessions to syslog collector
+		flow.CheckFatal(nat.StartSessionLog())
+
+		// Start asking external server to authorize new sessions
+		flow.CheckFatal(nat.StartSessionAuthorization())
+
+		// Start exporting spans and counters to OpenTelemetry
+		nat.StartOpenTelemetry()
+
+		// Start collecting lifetime histograms of ended sessions
+		nat.StartSessionAging()
+
+		// Start saving counters which survive restarts
+		nat.StartStatisticsPersistence()
+	}
 
 	// Perform all network initialization so that DHCP client could
 	// start sending packets
 	flow.CheckFatal(flow.SystemInitPortsAndMemory())
 
+	// Configure network ports as specified in config
+	flow.CheckFatal(nat.ApplyEthernetSettings())
+
+	// Start watching link state of network ports
+	nat.StartLinkMonitor()
+
+	// Check that IPv6 addresses of ports are not used by other hosts
+	nat.StartDuplicateAddressDetection()
+
+	// Start rotation of temporary IPv6 addresses of public ports
+	nat.StartTemporaryAddresses()
+
+	// Start learning IPv6 default routers of public ports
+	nat.StartRouterDiscovery()
+
 	// Start DHCP client
 	if nat.NeedDHCP || *setKniIP {
 		nat.StartDHCPClient()
 	}
 
+	// Start installing routes via KNI interfaces
+	nat.StartKNIRoutes()
+
+	// Start resolving blackholed domain names
+	nat.StartBlackholeResolver()
+
+	// Start reloading changed GeoIP database
+	nat.StartGeoIPReloader()
+
+	// Start closing ports opened by port triggers
+	nat.StartPortTriggers()
+
+	// Start forgetting idle flows redirected to captive portal
+	nat.StartCaptivePortal()
+
+	// Start forgetting private hosts which are not seen
+	nat.StartHostIdentity()
+
+	// Start releasing DHCP addresses of idle public ports
+	nat.StartIdleAddressRelease()
+
+	// Start removing retired public addresses without sessions
+	nat.StartRetiredAddresses()
+
+	// Start probing private hosts of forwarded ports
+	nat.StartForwardHealth()
+
+	// Start resetting TCP sessions which time out
+	nat.StartTCPTimeoutResets()
+
+	// Start removing expired sessions of forwarded ports
+	nat.StartForwardedSessionExpiry()
+
+	// Start probing path MTU toward next hops of public ports
+	nat.StartPathMTUDiscovery()
+
+	// Start discovering external addresses of public ports
+	nat.StartExternalAddressDiscovery()
+
+	// Start announcing public addresses to routing daemons
+	if !*selfTest {
+		nat.StartRouteAnnouncements()
+	}
+
 	// Start flow scheduler
 	go func() {
 		flow.CheckFatal(flow.SystemStartScheduler())
 	}()
 
+	// Self test doesn't wait for signals and doesn't save sessions
+	if *selfTest {
+		passed := nat.RunSelfTest()
+		nat.RemoveKNIRoutes()
+		nat.CloseAllDumpFiles()
+		if !passed {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	// Wait for interrupt
 	sig := <-c
 	fmt.Printf("Received signal %v\n", sig)
+	if sig == syscall.SIGTERM {
+		// Established sessions are drained unless another signal
+		// interrupts draining
+		drained := make(chan struct{})
+		go func() {
+			nat.DrainSessions()
+			close(drained)
+		}()
+		select {
+		case <-drained:
+		case sig = <-c:
+			fmt.Printf("Received signal %v, stopping without draining\n", sig)
+		}
+	}
+	if err := nat.SaveSessions(); err != nil {
+		fmt.Printf("Failed to save sessions: %v\n", err)
+	}
+	if err := nat.SaveStatistics(); err != nil {
+		fmt.Printf("Failed to save statistics: %v\n", err)
+	}
+	nat.RemoveKNIRoutes()
 	nat.CloseAllDumpFiles()
 }
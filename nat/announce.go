@@ -0,0 +1,202 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/vishvananda/netlink"
+)
+
+// Reasons of withdrawn announcements which are reported in events.
+const (
+	announceReasonControl     = "control"
+	announceReasonLinkDown    = "link-down"
+	announceReasonDraining    = "draining"
+	announceReasonMaintenance = "maintenance"
+)
+
+// Announcement of public addresses of port pair to routing daemon
+// running on public KNI interface. Addresses are installed as routes
+// via KNI interface into host routing table, so that daemon like FRR
+// picks them up from kernel and advertises them, e.g. with
+// "redistribute kernel" in BGP.
+type routeAnnouncementConfig struct {
+	Enable bool `json:"enable"`
+	// Routing table of announced routes, zero means main table
+	Table int `json:"table"`
+}
+
+// Announcement state of port pair.
+type announcementState struct {
+	// Set to 1 when routes are withdrawn with GRPC request
+	withdrawn int32
+	// Whether routes were announced by last update, set after first
+	// update
+	announced bool
+	updated   bool
+}
+
+var announcedRoutes = newKNIRouteTable()
+
+func (cfg *routeAnnouncementConfig) check(pp *portPair) error {
+	if !cfg.Enable {
+		return nil
+	}
+	if pp.PublicPort.KNIName == "" {
+		return fmt.Errorf("Route announcement of port pair with public port %d requires public KNI interface", pp.PublicPort.Index)
+	}
+	if cfg.Table < 0 {
+		return errors.New("Route announcement table should not be negative")
+	}
+	return nil
+}
+
+// announcedPrefixes returns public addresses of port pair. Addresses
+// of public port, its temporary addresses and addresses of VLAN
+// subinterfaces are host routes, public prefixes of netmap rules are
+// announced as they are.
+func (pp *portPair) announcedPrefixes() []*net.IPNet {
+	port := &pp.PublicPort
+	prefixes := []*net.IPNet{}
+	if port.Subnet.addressAcquired {
+		prefixes = append(prefixes, ipv4Net(port.Subnet.Addr, 0xffffffff))
+	}
+	if port.Subnet6.addressAcquired {
+		prefixes = append(prefixes, &net.IPNet{
+			IP:   append(net.IP{}, port.Subnet6.Addr[:]...),
+			Mask: net.CIDRMask(128, 128),
+		})
+	}
+	for _, t := range port.temporaryAddressList() {
+		prefixes = append(prefixes, &net.IPNet{
+			IP:   append(net.IP{}, t.addr[:]...),
+			Mask: net.CIDRMask(128, 128),
+		})
+	}
+	for i := range port.VLANs {
+		if port.VLANs[i].Subnet.addressAcquired {
+			prefixes = append(prefixes, ipv4Net(port.VLANs[i].Subnet.Addr, 0xffffffff))
+		}
+	}
+	for i := range pp.Netmap {
+		rule := &pp.Netmap[i]
+		prefixes = append(prefixes, ipv4Net(rule.Public.Addr&rule.Public.Mask, rule.Public.Mask))
+	}
+	return prefixes
+}
+
+// withdrawReason returns why routes of port pair shouldn't be
+// announced now or empty string.
+func (pp *portPair) withdrawReason() string {
+	switch {
+	case atomic.LoadInt32(&pp.announcement.withdrawn) != 0:
+		return announceReasonControl
+	case pp.inMaintenance():
+		return announceReasonMaintenance
+	case isDraining():
+		return announceReasonDraining
+	case !pp.PublicPort.getLinkStatus().up:
+		return announceReasonLinkDown
+	}
+	return ""
+}
+
+// StartRouteAnnouncements starts announcing public addresses of port
+// pairs which have route announcement enabled.
+func StartRouteAnnouncements() {
+	enabled := false
+	for i := range Natconfig.PortPairs {
+		enabled = enabled || Natconfig.PortPairs[i].RouteAnnouncement.Enable
+	}
+	if !enabled {
+		return
+	}
+	go func() {
+		for {
+			if !updateAnnouncements() {
+				return
+			}
+			time.Sleep(kniRoutesInterval)
+		}
+	}()
+}
+
+// updateAnnouncements installs routes of public addresses of port
+// pairs or withdraws them. It returns false when routes were removed
+// because NAT stops.
+func updateAnnouncements() bool {
+	announcedRoutes.mutex.Lock()
+	defer announcedRoutes.mutex.Unlock()
+	if announcedRoutes.stopped {
+		return false
+	}
+	for i := range Natconfig.PortPairs {
+		pp := &Natconfig.PortPairs[i]
+		if pp.RouteAnnouncement.Enable {
+			pp.updateAnnouncement()
+		}
+	}
+	return true
+}
+
+// updateAnnouncement updates routes of one port pair and reports
+// changes of announcement state. Mutex should be locked.
+func (pp *portPair) updateAnnouncement() {
+	port := &pp.PublicPort
+	link, err := netlink.LinkByName(port.KNIName)
+	if err != nil {
+		return
+	}
+	reason := pp.withdrawReason()
+	wanted := map[string]netlink.Route{}
+	if reason == "" {
+		for _, prefix := range pp.announcedPrefixes() {
+			wanted[prefix.String()] = netlink.Route{
+				LinkIndex: link.Attrs().Index,
+				Dst:       prefix,
+				Scope:     netlink.SCOPE_LINK,
+				Table:     pp.RouteAnnouncement.Table,
+			}
+		}
+	}
+	announcedRoutes.update(port, wanted)
+
+	state := &pp.announcement
+	announced := reason == ""
+	if state.updated && state.announced == announced {
+		return
+	}
+	// Announcement which starts normally is not reported
+	if state.updated || !announced {
+		details := map[string]interface{}{
+			"announced": announced,
+		}
+		if announced {
+			println("Port", port.logName(), "announces public addresses")
+		} else {
+			details["reason"] = reason
+			println("Port", port.logName(), "withdraws public addresses,", reason)
+		}
+		raiseEvent(EventFailover, port, details)
+	}
+	state.updated = true
+	state.announced = announced
+}
+
+// setAnnouncement withdraws routes of port pair or announces them
+// again and updates routes immediately.
+func (pp *portPair) setAnnouncement(announce bool) {
+	withdrawn := int32(1)
+	if announce {
+		withdrawn = 0
+	}
+	atomic.StoreInt32(&pp.announcement.withdrawn, withdrawn)
+	updateAnnouncements()
+}
@@ -0,0 +1,307 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// Application tags of sessions. Zero tag means that session was not
+// identified yet, other tags are indexes of application table.
+const (
+	appUntagged = iota
+	appOther
+	appICMP
+)
+
+// Applications identified by well-known remote ports of TCP and UDP
+// sessions
+var wellKnownApplications = []struct {
+	name string
+	tcp  []uint16
+	udp  []uint16
+}{
+	{"ftp", []uint16{20, 21}, nil},
+	{"ssh", []uint16{22}, nil},
+	{"telnet", []uint16{23}, nil},
+	{"smtp", []uint16{25, 465, 587}, nil},
+	{"dns", []uint16{53, 853}, []uint16{53}},
+	{"http", []uint16{80, 8080}, nil},
+	{"pop3", []uint16{110, 995}, nil},
+	{"ntp", nil, []uint16{123}},
+	{"imap", []uint16{143, 993}, nil},
+	{"https", []uint16{443, 8443}, nil},
+	{"quic", nil, []uint16{443}},
+	{"ipsec", nil, []uint16{500, 4500}},
+	{"openvpn", []uint16{1194}, []uint16{1194}},
+	{"rdp", []uint16{3389}, []uint16{3389}},
+	{"sip", []uint16{5060, 5061}, []uint16{5060}},
+}
+
+// Application identification of port pair. Sessions are tagged by
+// remote port, or by public port for forwarded ports, when their first
+// packet is translated. Egress TCP sessions are tagged again by TLS
+// server name if their first payload is a TLS ClientHello with server
+// name of custom application.
+type applicationsConfig struct {
+	Enable bool `json:"enable"`
+	// Applications which are checked before well-known ports
+	Custom []applicationRule `json:"custom"`
+}
+
+type applicationRule struct {
+	Name string `json:"name"`
+	// Remote TCP and UDP ports
+	Ports []uint16 `json:"ports"`
+	// Server names and their subdomains
+	Domains []string `json:"domains"`
+}
+
+// Traffic of application. Counters are updated from translation
+// handlers, so they should be accessed only atomically.
+type applicationCounters struct {
+	// Tagged sessions
+	sessions       uint64
+	egressPackets  uint64
+	egressBytes    uint64
+	ingressPackets uint64
+	ingressBytes   uint64
+}
+
+type applicationDomain struct {
+	name        string
+	application uint16
+}
+
+// Application table is built from config and is not changed
+// afterwards, so packet handlers use it without locking.
+type applicationTable struct {
+	// Application names indexed by tag, name of untagged is empty
+	names []string
+	// Key is IP protocol number shifted by 16 bits and port
+	ports    map[uint32]uint16
+	domains  []applicationDomain
+	counters []applicationCounters
+}
+
+func applicationPortKey(protocol uint8, port uint16) uint32 {
+	return uint32(protocol)<<16 | uint32(port)
+}
+
+// checkApplications builds application table of port pair if
+// application identification is enabled.
+func (pp *portPair) checkApplications() error {
+	if !pp.Applications.Enable {
+		if len(pp.Applications.Custom) != 0 {
+			return errors.New("Custom applications require application identification to be enabled")
+		}
+		return nil
+	}
+
+	table := &applicationTable{
+		names: []string{"", "other", "icmp"},
+		ports: map[uint32]uint16{},
+	}
+	lookup := func(name string) uint16 {
+		for i := range table.names {
+			if table.names[i] == name {
+				return uint16(i)
+			}
+		}
+		table.names = append(table.names, name)
+		return uint16(len(table.names) - 1)
+	}
+
+	custom := map[string]bool{}
+	for i := range pp.Applications.Custom {
+		rule := &pp.Applications.Custom[i]
+		if rule.Name == "" {
+			return errors.New("Custom application should have a name")
+		}
+		if custom[rule.Name] {
+			return errors.New("Duplicate custom application " + rule.Name)
+		}
+		custom[rule.Name] = true
+		if len(rule.Ports) == 0 && len(rule.Domains) == 0 {
+			return fmt.Errorf("Custom application %s should have ports or domains", rule.Name)
+		}
+		app := lookup(rule.Name)
+		for _, p := range rule.Ports {
+			if p == 0 {
+				return fmt.Errorf("Custom application %s has zero port", rule.Name)
+			}
+			table.ports[applicationPortKey(types.TCPNumber, p)] = app
+			table.ports[applicationPortKey(types.UDPNumber, p)] = app
+		}
+		for _, d := range rule.Domains {
+			d = strings.ToLower(strings.TrimSuffix(d, "."))
+			if !isDomainName(d) {
+				return fmt.Errorf("Custom application %s has bad domain %s", rule.Name, d)
+			}
+			table.domains = append(table.domains, applicationDomain{
+				name:        d,
+				application: app,
+			})
+		}
+	}
+	for _, wk := range wellKnownApplications {
+		app := lookup(wk.name)
+		add := func(protocol uint8, ports []uint16) {
+			for _, p := range ports {
+				key := applicationPortKey(protocol, p)
+				if _, ok := table.ports[key]; !ok {
+					table.ports[key] = app
+				}
+			}
+		}
+		add(types.TCPNumber, wk.tcp)
+		add(types.UDPNumber, wk.udp)
+	}
+	table.counters = make([]applicationCounters, len(table.names))
+	pp.apps = table
+	return nil
+}
+
+// byPort returns application of TCP or UDP port.
+func (table *applicationTable) byPort(protocol uint8, port uint16) uint16 {
+	switch protocol {
+	case types.ICMPNumber, types.ICMPv6Number:
+		return appICMP
+	}
+	if app, ok := table.ports[applicationPortKey(protocol, port)]; ok {
+		return app
+	}
+	return appOther
+}
+
+// byServerName returns custom application of TLS server name.
+func (table *applicationTable) byServerName(name string) (uint16, bool) {
+	name = strings.ToLower(name)
+	for i := range table.domains {
+		d := table.domains[i].name
+		if name == d || strings.HasSuffix(name, "."+d) {
+			return table.domains[i].application, true
+		}
+	}
+	return 0, false
+}
+
+// parseTLSServerName returns server name of TLS ClientHello if whole
+// server name extension is in data.
+func parseTLSServerName(data []byte) string {
+	// Record header, handshake header, version and random
+	const helloStart = 5 + 4
+	if len(data) < helloStart+2+32 || data[0] != 22 || data[5] != 1 {
+		return ""
+	}
+	pos := helloStart + 2 + 32
+	skip := func(lenBytes int) bool {
+		if len(data) < pos+lenBytes {
+			return false
+		}
+		n := 0
+		for i := 0; i < lenBytes; i++ {
+			n = n<<8 | int(data[pos+i])
+		}
+		pos += lenBytes + n
+		return len(data) >= pos
+	}
+	// Session ID, cipher suites and compression methods
+	if !skip(1) || !skip(2) || !skip(1) || len(data) < pos+2 {
+		return ""
+	}
+	end := pos + 2 + int(binary.BigEndian.Uint16(data[pos:]))
+	pos += 2
+	if end > len(data) {
+		end = len(data)
+	}
+	for pos+4 <= end {
+		extType := binary.BigEndian.Uint16(data[pos:])
+		extLen := int(binary.BigEndian.Uint16(data[pos+2:]))
+		pos += 4
+		if pos+extLen > end {
+			return ""
+		}
+		// Server name extension holds list of names, only host name
+		// type is defined
+		if extType == 0 && extLen >= 5 && data[pos+2] == 0 {
+			nameLen := int(binary.BigEndian.Uint16(data[pos+3:]))
+			if 5+nameLen > extLen {
+				return ""
+			}
+			return string(data[pos+5 : pos+5+nameLen])
+		}
+		pos += extLen
+	}
+	return ""
+}
+
+// countApplication tags session of translated packet and accounts
+// packet to its application. Dynamic sessions are tagged by remote
+// port and forwarded ports by public port. First payload of egress TCP
+// session is checked for TLS server name.
+func (pp *portPair) countApplication(pme *portMapEntry, pkt *packet.Packet, protocol uint8, egress bool, remotePort, publicPort uint16) {
+	table := pp.apps
+	if table == nil {
+		return
+	}
+	if pme.application == appUntagged {
+		port := remotePort
+		if pme.static {
+			port = publicPort
+		}
+		pme.application = table.byPort(protocol, port)
+		atomic.AddUint64(&table.counters[pme.application].sessions, 1)
+	}
+	if egress && protocol == types.TCPNumber && !pme.static && !pme.sniChecked && len(table.domains) != 0 {
+		if payload, ok := pkt.GetPacketPayload(); ok && len(payload) != 0 {
+			pme.sniChecked = true
+			if app, ok := table.byServerName(parseTLSServerName(payload)); ok && app != pme.application {
+				atomic.AddUint64(&table.counters[pme.application].sessions, ^uint64(0))
+				atomic.AddUint64(&table.counters[app].sessions, 1)
+				pme.application = app
+			}
+		}
+	}
+
+	ac := &table.counters[pme.application]
+	length := uint64(pkt.GetPacketLen())
+	if egress {
+		atomic.AddUint64(&ac.egressPackets, 1)
+		atomic.AddUint64(&ac.egressBytes, length)
+	} else {
+		atomic.AddUint64(&ac.ingressPackets, 1)
+		atomic.AddUint64(&ac.ingressBytes, length)
+	}
+}
+
+// applicationActiveSessions returns number of active dynamic sessions
+// of every application. Port maps are read without taking a lock so
+// values are approximate.
+func (pp *portPair) applicationActiveSessions() []int {
+	active := make([]int, len(pp.apps.names))
+	for _, ipv6 := range pp.ipFamilies() {
+		for _, protocol := range pp.sessionProtocols(ipv6) {
+			pm := pp.getPublicPortPortmap(ipv6, protocol)
+			if pm == nil {
+				continue
+			}
+			for p := portStart; p < portEnd; p++ {
+				if !pm[p].static && pm[p].application != appUntagged && time.Since(pm[p].lastused) <= connectionTimeout {
+					active[pm[p].application]++
+				}
+			}
+		}
+	}
+	return active
+}
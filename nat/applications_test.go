@@ -0,0 +1,140 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"testing"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+func tlsLength(n, bytes int) []byte {
+	b := make([]byte, bytes)
+	for i := bytes - 1; i >= 0; i-- {
+		b[i] = byte(n)
+		n >>= 8
+	}
+	return b
+}
+
+func tlsExtension(extType uint16, data []byte) []byte {
+	return append(append([]byte{byte(extType >> 8), byte(extType)}, tlsLength(len(data), 2)...), data...)
+}
+
+func tlsServerNameExtension(nameType byte, name string) []byte {
+	entry := append(append([]byte{nameType}, tlsLength(len(name), 2)...), name...)
+	return tlsExtension(0, append(tlsLength(len(entry), 2), entry...))
+}
+
+// tlsClientHello returns TLS record with ClientHello which has session
+// ID and extensions.
+func tlsClientHello(sessionID []byte, extensions ...[]byte) []byte {
+	body := []byte{0x03, 0x03}
+	body = append(body, make([]byte, 32)...)
+	body = append(body, byte(len(sessionID)))
+	body = append(body, sessionID...)
+	// Two cipher suites and null compression
+	body = append(body, 0x00, 0x04, 0x13, 0x01, 0x13, 0x02, 0x01, 0x00)
+	var ext []byte
+	for _, e := range extensions {
+		ext = append(ext, e...)
+	}
+	body = append(body, tlsLength(len(ext), 2)...)
+	body = append(body, ext...)
+	handshake := append(append([]byte{1}, tlsLength(len(body), 3)...), body...)
+	return append(append([]byte{22, 0x03, 0x01}, tlsLength(len(handshake), 2)...), handshake...)
+}
+
+func TestParseTLSServerName(t *testing.T) {
+	hello := tlsClientHello(make([]byte, 32), tlsServerNameExtension(0, "example.com"))
+	notHandshake := append([]byte{}, hello...)
+	notHandshake[0] = 23
+	serverHello := append([]byte{}, hello...)
+	serverHello[5] = 2
+	longName := tlsClientHello(nil, tlsExtension(0, []byte{0x00, 0x08, 0x00, 0x00, 0x10, 'a', 'b', 'c'}))
+	badSessionID := tlsClientHello(nil, tlsServerNameExtension(0, "example.com"))
+	badSessionID[43] = 200
+	badExtension := tlsClientHello(nil, tlsExtension(10, []byte{0, 2, 0, 29}), tlsServerNameExtension(0, "example.com"))
+	// Length of first extension overruns extensions
+	badExtension[len(badExtension)-len(tlsServerNameExtension(0, "example.com"))-5] = 0xff
+
+	tests := []struct {
+		name string
+		data []byte
+		sni  string
+	}{
+		{"server name", hello, "example.com"},
+		{"without session ID", tlsClientHello(nil, tlsServerNameExtension(0, "www.example.org")), "www.example.org"},
+		{"after other extensions", tlsClientHello(nil, tlsExtension(10, []byte{0, 2, 0, 29}), tlsExtension(13, []byte{0, 2, 4, 3}),
+			tlsServerNameExtension(0, "example.net")), "example.net"},
+		{"before other extensions", tlsClientHello(nil, tlsServerNameExtension(0, "example.net"), tlsExtension(10, []byte{0, 2, 0, 29})), "example.net"},
+		{"without extensions", tlsClientHello(nil), ""},
+		{"without server name", tlsClientHello(nil, tlsExtension(10, []byte{0, 2, 0, 29})), ""},
+		{"other name type", tlsClientHello(nil, tlsServerNameExtension(1, "example.com")), ""},
+		{"not handshake", notHandshake, ""},
+		{"not ClientHello", serverHello, ""},
+		{"truncated server name", hello[:len(hello)-3], ""},
+		{"truncated random", hello[:20], ""},
+		{"empty", nil, ""},
+		{"name overruns extension", longName, ""},
+		{"session ID overruns data", badSessionID, ""},
+		{"extension overruns extensions", badExtension, ""},
+	}
+	for _, tt := range tests {
+		if got := parseTLSServerName(tt.data); got != tt.sni {
+			t.Errorf("%s: server name is %q, expected %q", tt.name, got, tt.sni)
+		}
+	}
+}
+
+func TestApplicationLookup(t *testing.T) {
+	pp := &portPair{}
+	pp.Applications.Enable = true
+	pp.Applications.Custom = []applicationRule{
+		{Name: "video", Ports: []uint16{443}, Domains: []string{"Video.Example.COM."}},
+		{Name: "game", Ports: []uint16{27015}},
+	}
+	if err := pp.checkApplications(); err != nil {
+		t.Fatal(err)
+	}
+	table := pp.apps
+	name := func(app uint16) string {
+		return table.names[app]
+	}
+
+	ports := []struct {
+		protocol uint8
+		port     uint16
+		app      string
+	}{
+		{types.TCPNumber, 443, "video"},
+		{types.UDPNumber, 27015, "game"},
+		{types.TCPNumber, 1, "other"},
+		{types.ICMPNumber, 0, "icmp"},
+		{types.ICMPv6Number, 443, "icmp"},
+	}
+	for _, tt := range ports {
+		if got := name(table.byPort(tt.protocol, tt.port)); got != tt.app {
+			t.Errorf("Port %d of protocol %d is application %q, expected %q", tt.port, tt.protocol, got, tt.app)
+		}
+	}
+
+	names := []struct {
+		name  string
+		app   string
+		found bool
+	}{
+		{"video.example.com", "video", true},
+		{"CDN.Video.Example.com", "video", true},
+		{"badvideo.example.com", "", false},
+		{"example.com", "", false},
+	}
+	for _, tt := range names {
+		app, found := table.byServerName(tt.name)
+		if found != tt.found || (found && name(app) != tt.app) {
+			t.Errorf("Server name %s is application %q found %v, expected %q found %v", tt.name, name(app), found, tt.app, tt.found)
+		}
+	}
+}
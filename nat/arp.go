@@ -5,18 +5,163 @@
 package nat
 
 import (
+	"bytes"
+	"errors"
+	"net"
+	"sort"
+
 	"github.com/intel-go/nff-go/common"
 	"github.com/intel-go/nff-go/packet"
 	"github.com/intel-go/nff-go/types"
 )
 
+// Value type of ARP/ND table. Static entries are added with GRPC
+// requests and are never overwritten by learned addresses.
+type neighborEntry struct {
+	mac    types.MACAddress
+	static bool
+}
+
+// Static neighbor of config file, IPv4 or IPv6 address and its MAC
+// address.
+type staticNeighbor struct {
+	Address string `json:"address"`
+	MAC     string `json:"mac"`
+}
+
+// checkStaticNeighbors parses static neighbors of port and adds them
+// to its neighbor table.
+func (port *ipPort) checkStaticNeighbors() error {
+	if len(port.StaticNeighbors) != 0 && port.staticArpMode {
+		return errors.New("Port " + port.logName() + " uses static destination MAC address, it cannot have static neighbors")
+	}
+	for _, n := range port.StaticNeighbors {
+		ip := net.ParseIP(n.Address)
+		if ip == nil {
+			return errors.New("Bad static neighbor address " + n.Address)
+		}
+		var key interface{}
+		if ip4 := ip.To4(); ip4 != nil {
+			addr, err := convertIPv4(ip4)
+			if err != nil {
+				return err
+			}
+			key = addr
+		} else {
+			var addr6 types.IPv6Address
+			copy(addr6[:], ip.To16())
+			key = addr6
+		}
+		hw, err := net.ParseMAC(n.MAC)
+		if err != nil || len(hw) != types.EtherAddrLen {
+			return errors.New("Bad MAC address " + n.MAC + " of static neighbor " + n.Address)
+		}
+		var mac types.MACAddress
+		copy(mac[:], hw)
+		port.arpTable.Store(key, neighborEntry{
+			mac:    mac,
+			static: true,
+		})
+	}
+	return nil
+}
+
+// currentStaticNeighbors returns static entries of neighbor table
+// including ones added with GRPC requests, IPv4 neighbors first.
+func (port *ipPort) currentStaticNeighbors() []staticNeighbor {
+	type neighbor struct {
+		addr net.IP
+		mac  types.MACAddress
+	}
+	list := []neighbor{}
+	port.arpTable.Range(func(k, v interface{}) bool {
+		entry := v.(neighborEntry)
+		if !entry.static {
+			return true
+		}
+		switch ip := k.(type) {
+		case types.IPv4Address:
+			a := types.IPv4ToBytes(ip)
+			list = append(list, neighbor{net.IP{a[3], a[2], a[1], a[0]}, entry.mac})
+		case types.IPv6Address:
+			list = append(list, neighbor{append(net.IP{}, ip[:]...), entry.mac})
+		}
+		return true
+	})
+	sort.Slice(list, func(i, j int) bool {
+		if len(list[i].addr) != len(list[j].addr) {
+			return len(list[i].addr) < len(list[j].addr)
+		}
+		return bytes.Compare(list[i].addr, list[j].addr) < 0
+	})
+	result := make([]staticNeighbor, len(list))
+	for i := range list {
+		result[i] = staticNeighbor{
+			Address: list[i].addr.String(),
+			MAC:     list[i].mac.String(),
+		}
+	}
+	return result
+}
+
+// storeNeighbor saves learned MAC address for IP address which is
+// either types.IPv4Address in host byte order or types.IPv6Address.
+func (port *ipPort) storeNeighbor(ip interface{}, mac types.MACAddress) {
+	if port.health != nil {
+		port.health.markAnswered(ip)
+	}
+	if port.identities != nil {
+		port.identities.learn(ip, mac)
+	}
+	port.completeNeighbor(ip)
+	port.completeResolution(ip)
+	v, found := port.arpTable.Load(ip)
+	if found {
+		entry := v.(neighborEntry)
+		if entry.static || entry.mac == mac {
+			return
+		}
+	}
+	port.arpTable.Store(ip, neighborEntry{
+		mac: mac,
+	})
+	port.finishResolutionSpan(ip, mac)
+}
+
+// flushNeighbors deletes learned neighbors and optionally static
+// ones too. It returns number of deleted entries.
+func (port *ipPort) flushNeighbors(flushStatic bool) int {
+	count := 0
+	port.arpTable.Range(func(k, v interface{}) bool {
+		if flushStatic || !v.(neighborEntry).static {
+			port.arpTable.Delete(k)
+			count++
+		}
+		return true
+	})
+	port.flushResolutions()
+	return count
+}
+
+func (port *ipPort) loadNeighbor(ip interface{}) (types.MACAddress, bool) {
+	v, found := port.arpTable.Load(ip)
+	if found {
+		return v.(neighborEntry).mac, true
+	}
+	return types.MACAddress{}, false
+}
+
 func (port *ipPort) handleARP(pkt *packet.Packet) uint {
 	arp := pkt.GetARPNoCheck()
 
+	if !port.allowControlPlane(controlPlaneARP, packet.SwapBytesIPv4Addr(types.ArrayToIPv4(arp.SPA))) {
+		return DirDROP
+	}
+
 	if packet.SwapBytesUint16(arp.Operation) != packet.ARPRequest {
 		if packet.SwapBytesUint16(arp.Operation) == packet.ARPReply {
 			ipv4 := packet.SwapBytesIPv4Addr(types.ArrayToIPv4(arp.SPA))
-			port.arpTable.Store(ipv4, arp.SHA)
+			port.storeNeighbor(ipv4, arp.SHA)
 		}
 		if port.KNIName != "" {
 			return DirKNI
@@ -31,7 +176,9 @@ func (port *ipPort) handleARP(pkt *packet.Packet) uint {
 
 	// Check that someone is asking about MAC of my IP address and HW
 	// address is blank in request
-	if types.BytesToIPv4(arp.TPA[0], arp.TPA[1], arp.TPA[2], arp.TPA[3]) != packet.SwapBytesIPv4Addr(port.Subnet.Addr) {
+	target := packet.SwapBytesIPv4Addr(types.ArrayToIPv4(arp.TPA))
+	if target != port.Subnet.Addr && !port.isVLANAddress(pkt, target) && !port.isRetiredIPv4Address(target) &&
+		port.findNetmapRule(target, true) == nil {
 		println("Warning! Got an ARP packet with target IPv4 address", types.IPv4ArrayToString(arp.TPA),
 			"different from IPv4 address on interface. Should be", port.Subnet.Addr.String(),
 			". ARP request ignored.")
@@ -43,6 +190,10 @@ func (port *ipPort) handleARP(pkt *packet.Packet) uint {
 		return DirDROP
 	}
 
+	if !neighborLimiter.allow(port, packet.SwapBytesIPv4Addr(types.ArrayToIPv4(arp.SPA))) {
+		return DirDROP
+	}
+
 	// Prepare an answer to this request
 	answerPacket, err := packet.NewPacket()
 	if err != nil {
@@ -56,7 +207,7 @@ func (port *ipPort) handleARP(pkt *packet.Packet) uint {
 	}
 
 	port.dumpPacket(answerPacket, DirSEND)
-	answerPacket.SendPacket(port.Index)
+	port.sendPacket(answerPacket)
 
 	return DirDROP
 }
@@ -65,27 +216,39 @@ func (port *ipPort) getMACForIPv4(ip types.IPv4Address) (types.MACAddress, bool)
 	if port.staticArpMode {
 		return port.DstMACAddress, true
 	} else {
-		v, found := port.arpTable.Load(ip)
+		mac, found := port.loadNeighbor(ip)
 		if found {
-			return v.(types.MACAddress), true
+			return mac, true
+		}
+		if port.allowResolution(ip) {
+			port.sendARPRequest(ip)
 		}
-		port.sendARPRequest(ip)
 		return types.MACAddress{}, false
 	}
 }
 
 func (port *ipPort) sendARPRequest(ip types.IPv4Address) {
+	port.sendVLANARPRequest(ip, port.Subnet.Addr, port.Vlan)
+}
+
+// sendVLANARPRequest sends ARP request from specified address with
+// specified vlan tag.
+func (port *ipPort) sendVLANARPRequest(ip, src types.IPv4Address, vlan uint16) {
+	if !neighborLimiter.allow(port, ip) {
+		return
+	}
+	port.startResolutionSpan(ip)
 	requestPacket, err := packet.NewPacket()
 	if err != nil {
 		common.LogFatal(common.Debug, err)
 	}
 
 	packet.InitARPRequestPacket(requestPacket, port.SrcMACAddress,
-		packet.SwapBytesIPv4Addr(port.Subnet.Addr), packet.SwapBytesIPv4Addr(ip))
-	if port.Vlan != 0 {
-		requestPacket.AddVLANTag(port.Vlan)
+		packet.SwapBytesIPv4Addr(src), packet.SwapBytesIPv4Addr(ip))
+	if vlan != 0 {
+		requestPacket.AddVLANTag(vlan)
 	}
 
 	port.dumpPacket(requestPacket, DirSEND)
-	requestPacket.SendPacket(port.Index)
+	port.sendPacket(requestPacket)
 }
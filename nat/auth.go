@@ -0,0 +1,439 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Roles of control API clients. Every role includes capabilities of
+// previous roles.
+type apiRole int
+
+const (
+	roleNone apiRole = iota
+	// Monitoring, may only read state
+	roleReadOnly
+	// May dump traffic, flush tables and restart DHCP clients but
+	// doesn't change configuration
+	roleOperator
+	// May change configuration
+	roleAdmin
+)
+
+var apiRoleLookup = map[string]apiRole{
+	"none":      roleNone,
+	"read-only": roleReadOnly,
+	"operator":  roleOperator,
+	"admin":     roleAdmin,
+}
+
+// Roles required to call control API methods. Methods which are not
+// listed here require admin role.
+var methodRoles = map[string]apiRole{
+	"/updatecfg.Updater/GetNeighbors":           roleReadOnly,
+	"/updatecfg.Updater/GetDHCPLease":           roleReadOnly,
+	"/updatecfg.Updater/GetPortStatistics":      roleReadOnly,
+	"/updatecfg.Updater/GetLinkStatus":          roleReadOnly,
+	"/updatecfg.Updater/GetSubscribers":         roleReadOnly,
+	"/updatecfg.Updater/GetTopTalkers":          roleReadOnly,
+	"/updatecfg.Updater/GetBlackholeRules":      roleReadOnly,
+	"/updatecfg.Updater/GetApplications":        roleReadOnly,
+	"/updatecfg.Updater/GetCountries":           roleReadOnly,
+	"/updatecfg.Updater/GetPortTriggers":        roleReadOnly,
+	"/updatecfg.Updater/GetExternalAddress":     roleReadOnly,
+	"/updatecfg.Updater/GetFlowGraph":           roleReadOnly,
+	"/updatecfg.Updater/GetMaintenance":         roleReadOnly,
+	"/updatecfg.Updater/GetNftablesRuleset":     roleReadOnly,
+	"/updatecfg.Updater/GetForwardedHandshakes": roleReadOnly,
+	"/updatecfg.Updater/GetCaptivePortalHosts":  roleReadOnly,
+	"/updatecfg.Updater/GetHostIdentities":      roleReadOnly,
+	"/updatecfg.Updater/ControlDump":            roleOperator,
+	"/updatecfg.Updater/StreamDump":             roleOperator,
+	"/updatecfg.Updater/ConnectDumpSink":        roleOperator,
+	"/updatecfg.Updater/DisconnectDumpSink":     roleOperator,
+	"/updatecfg.Updater/ExportSessions":         roleOperator,
+	"/updatecfg.Updater/FindSessions":           roleOperator,
+	"/updatecfg.Updater/TracePacket":            roleOperator,
+	"/updatecfg.Updater/AddStaticNeighbor":      roleOperator,
+	"/updatecfg.Updater/DeleteNeighbor":         roleOperator,
+	"/updatecfg.Updater/FlushNeighbors":         roleOperator,
+	"/updatecfg.Updater/ControlDHCP":            roleOperator,
+	"/updatecfg.Updater/SendWakeOnLAN":          roleOperator,
+	"/updatecfg.Updater/SetMaintenance":         roleOperator,
+	"/updatecfg.Updater/SetCaptivePortalHost":   roleOperator,
+	"/updatecfg.Updater/ChangeInterfaceAddress": roleAdmin,
+	"/updatecfg.Updater/ChangePortForwarding":   roleAdmin,
+	"/gnmi.gNMI/Capabilities":                   roleReadOnly,
+	"/gnmi.gNMI/Get":                            roleReadOnly,
+	"/gnmi.gNMI/Subscribe":                      roleReadOnly,
+	"/gnmi.gNMI/Set":                            roleAdmin,
+
+	// Reflection service only describes API
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo": roleReadOnly,
+}
+
+// Control API access configuration. When no tokens or certificates
+// are specified, all clients have admin role.
+type controlAPIConfig struct {
+	// Server certificate and key PEM files which enable TLS
+	TLSCertificate string `json:"tls-certificate"`
+	TLSKey         string `json:"tls-key"`
+	// CA certificates PEM file used to verify client certificates
+	ClientCA     string           `json:"client-ca"`
+	Tokens       []apiToken       `json:"tokens"`
+	Certificates []apiCertificate `json:"certificates"`
+	// Role of clients which present neither token nor known
+	// certificate
+	DefaultRole apiRole `json:"default-role"`
+}
+
+// Bearer token sent by client in authorization metadata.
+type apiToken struct {
+	Token string  `json:"token"`
+	Role  apiRole `json:"role"`
+	// Indexes of port pairs which client may access, empty list
+	// means all port pairs
+	PortPairs []int `json:"port-pairs"`
+}
+
+// Client certificate attributes. Empty attributes match any value.
+type apiCertificate struct {
+	CommonName         string  `json:"common-name"`
+	OrganizationalUnit string  `json:"organizational-unit"`
+	Role               apiRole `json:"role"`
+	PortPairs          []int   `json:"port-pairs"`
+}
+
+// Role of client and port pairs which it may access, nil pairs means
+// all port pairs.
+type apiAccess struct {
+	role  apiRole
+	pairs map[int]bool
+}
+
+// Methods which clients limited to port pairs may call although
+// their requests don't specify ports
+var unscopedMethods = map[string]bool{
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo": true,
+}
+
+// Requests of a single port and of a list of ports
+type interfaceRequest interface {
+	GetInterfaceId() uint32
+}
+
+type interfacesRequest interface {
+	GetInterfaceIds() []uint32
+}
+
+// UnmarshalJSON parses control API role name.
+func (out *apiRole) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	result, ok := apiRoleLookup[s]
+	if !ok {
+		return errors.New("Bad control API role name: " + s)
+	}
+
+	*out = result
+	return nil
+}
+
+// MarshalJSON writes role name as it is used in config file.
+func (role apiRole) MarshalJSON() ([]byte, error) {
+	return json.Marshal(role.String())
+}
+
+// String returns role name as it is used in config file.
+func (role apiRole) String() string {
+	for name, r := range apiRoleLookup {
+		if r == role {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+func (cfg *controlAPIConfig) check() error {
+	if (cfg.TLSCertificate == "") != (cfg.TLSKey == "") {
+		return errors.New("Both tls-certificate and tls-key should be specified for control API")
+	}
+	if cfg.ClientCA != "" && cfg.TLSCertificate == "" {
+		return errors.New("Control API client-ca requires tls-certificate and tls-key")
+	}
+	if len(cfg.Certificates) != 0 && cfg.ClientCA == "" {
+		return errors.New("Control API certificates roles require client-ca")
+	}
+	for i := range cfg.Tokens {
+		if cfg.Tokens[i].Token == "" {
+			return errors.New("Control API token should not be empty")
+		}
+		if err := checkAPIPortPairs(cfg.Tokens[i].PortPairs); err != nil {
+			return err
+		}
+	}
+	for i := range cfg.Certificates {
+		if err := checkAPIPortPairs(cfg.Certificates[i].PortPairs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkAPIPortPairs(pairs []int) error {
+	for _, index := range pairs {
+		if index < 0 {
+			return fmt.Errorf("Bad control API port pair index %d", index)
+		}
+	}
+	return nil
+}
+
+// grant adds role and port pairs of matching token or certificate to
+// client access. Client gets the highest role, port pairs of
+// credentials which grant lower roles are ignored.
+func (access *apiAccess) grant(role apiRole, pairs []int) {
+	if role < access.role {
+		return
+	}
+	if role > access.role {
+		access.role = role
+		access.pairs = map[int]bool{}
+	} else if access.pairs == nil {
+		return
+	}
+	if len(pairs) == 0 {
+		access.pairs = nil
+		return
+	}
+	for _, index := range pairs {
+		access.pairs[index] = true
+	}
+}
+
+// authorizationEnabled returns true when roles are assigned to
+// clients. Otherwise API is open as before roles were introduced.
+func (cfg *controlAPIConfig) authorizationEnabled() bool {
+	return len(cfg.Tokens) != 0 || len(cfg.Certificates) != 0
+}
+
+// serverOptions returns GRPC server options which enable TLS and
+// roles checks according to configuration.
+func (cfg *controlAPIConfig) serverOptions() ([]grpc.ServerOption, error) {
+	opts := []grpc.ServerOption{}
+	if cfg.TLSCertificate != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertificate, cfg.TLSKey)
+		if err != nil {
+			return nil, err
+		}
+		config := &tls.Config{
+			Certificates: []tls.Certificate{cert},
+		}
+		if cfg.ClientCA != "" {
+			pem, err := ioutil.ReadFile(cfg.ClientCA)
+			if err != nil {
+				return nil, err
+			}
+			config.ClientCAs = x509.NewCertPool()
+			if !config.ClientCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("Failed to parse control API client CA file %s", cfg.ClientCA)
+			}
+			// Clients without certificate may still use tokens
+			config.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+	}
+	// GRPC allows only one interceptor of a kind, so it both checks
+	// roles and traces configuration changes
+	if cfg.authorizationEnabled() || Natconfig.OpenTelemetry.enabled() {
+		opts = append(opts, grpc.UnaryInterceptor(cfg.unaryInterceptor))
+	}
+	if cfg.authorizationEnabled() {
+		opts = append(opts, grpc.StreamInterceptor(cfg.streamInterceptor))
+	}
+	return opts, nil
+}
+
+// clientAccess returns the highest role granted to client by its
+// token and certificate and port pairs which it may access with this
+// role.
+func (cfg *controlAPIConfig) clientAccess(ctx context.Context) apiAccess {
+	access := apiAccess{
+		role: cfg.DefaultRole,
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, v := range md["authorization"] {
+			if !strings.HasPrefix(v, "Bearer ") {
+				continue
+			}
+			token := []byte(strings.TrimPrefix(v, "Bearer "))
+			for i := range cfg.Tokens {
+				t := &cfg.Tokens[i]
+				if subtle.ConstantTimeCompare(token, []byte(t.Token)) == 1 {
+					access.grant(t.Role, t.PortPairs)
+				}
+			}
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			// Only verified certificates are taken into account
+			for _, chain := range tlsInfo.State.VerifiedChains {
+				if len(chain) == 0 {
+					continue
+				}
+				subject := chain[0].Subject
+				for i := range cfg.Certificates {
+					c := &cfg.Certificates[i]
+					if c.matches(subject.CommonName, subject.OrganizationalUnit) {
+						access.grant(c.Role, c.PortPairs)
+					}
+				}
+			}
+		}
+	}
+	return access
+}
+
+func (c *apiCertificate) matches(commonName string, units []string) bool {
+	if c.CommonName != "" && c.CommonName != commonName {
+		return false
+	}
+	if c.OrganizationalUnit == "" {
+		return true
+	}
+	for _, u := range units {
+		if u == c.OrganizationalUnit {
+			return true
+		}
+	}
+	return false
+}
+
+func (cfg *controlAPIConfig) authorize(ctx context.Context, method string) (apiAccess, error) {
+	required, ok := methodRoles[method]
+	if !ok {
+		required = roleAdmin
+	}
+	access := cfg.clientAccess(ctx)
+	if access.role == roleNone {
+		return access, status.Errorf(codes.Unauthenticated, "Control API requires token or client certificate")
+	}
+	if access.role < required {
+		return access, status.Errorf(codes.PermissionDenied, "Method %s requires %s role, client has %s role", method, required, access.role)
+	}
+	return access, nil
+}
+
+// allowsPort returns true if port with index belongs to port pair
+// which client may access.
+func (access *apiAccess) allowsPort(portId uint32) bool {
+	for i := range Natconfig.PortPairs {
+		pp := &Natconfig.PortPairs[i]
+		if uint32(pp.PublicPort.Index) == portId || uint32(pp.PrivatePort.Index) == portId {
+			return access.pairs[i]
+		}
+	}
+	return false
+}
+
+// checkScope checks that request of client limited to port pairs
+// refers only to ports of these pairs. Requests which don't specify
+// ports, or which mean all ports with empty list, may affect other
+// port pairs or global settings, so they are refused.
+func (access *apiAccess) checkScope(method string, req interface{}) error {
+	if access.pairs == nil || unscopedMethods[method] {
+		return nil
+	}
+	switch r := req.(type) {
+	case interfaceRequest:
+		if !access.allowsPort(r.GetInterfaceId()) {
+			return status.Errorf(codes.PermissionDenied, "Client may not access interface with ID %d", r.GetInterfaceId())
+		}
+		return nil
+	case interfacesRequest:
+		if len(r.GetInterfaceIds()) == 0 {
+			return status.Errorf(codes.PermissionDenied, "Client limited to port pairs should list interfaces in %s requests", method)
+		}
+		for _, id := range r.GetInterfaceIds() {
+			if !access.allowsPort(id) {
+				return status.Errorf(codes.PermissionDenied, "Client may not access interface with ID %d", id)
+			}
+		}
+		return nil
+	}
+	return status.Errorf(codes.PermissionDenied, "Method %s is not available to clients limited to port pairs", method)
+}
+
+// Server stream which checks scope of every request of client.
+type scopedServerStream struct {
+	grpc.ServerStream
+	access apiAccess
+	method string
+}
+
+func (ss *scopedServerStream) RecvMsg(m interface{}) error {
+	if err := ss.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return ss.access.checkScope(ss.method, m)
+}
+
+func (cfg *controlAPIConfig) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	span := startSpan(info.FullMethod, otelSpanKindServer, nil)
+	if span != nil {
+		span.attributes["rpc.system"] = "grpc"
+		span.attributes["rpc.method"] = info.FullMethod
+	}
+	if cfg.authorizationEnabled() {
+		access, err := cfg.authorize(ctx, info.FullMethod)
+		if err == nil {
+			err = access.checkScope(info.FullMethod, req)
+		}
+		if err != nil {
+			span.finish(err)
+			return nil, err
+		}
+	}
+	resp, err := handler(ctx, req)
+	span.finish(err)
+	return resp, err
+}
+
+func (cfg *controlAPIConfig) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	access, err := cfg.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	if access.pairs != nil {
+		ss = &scopedServerStream{
+			ServerStream: ss,
+			access:       access,
+			method:       info.FullMethod,
+		}
+	}
+	return handler(srv, ss)
+}
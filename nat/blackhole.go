@@ -0,0 +1,344 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// Addresses of blackholed domain names are resolved this often
+const blackholeResolveInterval = time.Minute
+
+type blackholeAction int
+
+const (
+	blackholeDrop blackholeAction = iota
+	blackholeKNI
+)
+
+var blackholeActionLookup = map[string]blackholeAction{
+	"drop": blackholeDrop,
+	"kni":  blackholeKNI,
+}
+
+// Destination which traffic from private hosts is contained, e.g.
+// command and control server of malware. Packets are silently dropped
+// or sent to KNI interface of private port where a honeypot may
+// answer them.
+type blackholeRule struct {
+	// Prefix, address or domain name
+	Destination string          `json:"destination"`
+	Action      blackholeAction `json:"action"`
+	domain      bool
+	// Matched prefixes, *blackholePrefixes value. Addresses of domain
+	// name are replaced every time it is resolved.
+	prefixes atomic.Value
+	// Matched packets
+	packets uint64
+}
+
+type blackholePrefixes struct {
+	v4 []ipv4Subnet
+	v6 []ipv6Subnet
+}
+
+// Serializes changes of blackhole rules of all port pairs
+var blackholeMutex sync.Mutex
+
+// UnmarshalJSON parses blackhole action.
+func (out *blackholeAction) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	result, ok := blackholeActionLookup[s]
+	if !ok {
+		return errors.New("Bad blackhole action: " + s)
+	}
+
+	*out = result
+	return nil
+}
+
+// MarshalJSON writes action name as it is used in config file.
+func (action blackholeAction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(action.String())
+}
+
+// String returns action name as it is used in config file.
+func (action blackholeAction) String() string {
+	for name, a := range blackholeActionLookup {
+		if a == action {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// isDomainName returns true if name consists of labels of letters,
+// digits and hyphens.
+func isDomainName(name string) bool {
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func newBlackholePrefixes(nets []*net.IPNet) *blackholePrefixes {
+	prefixes := &blackholePrefixes{}
+	for _, n := range nets {
+		if ip4 := n.IP.To4(); ip4 != nil {
+			var subnet ipv4Subnet
+			subnet.Addr, _ = convertIPv4(ip4)
+			subnet.Mask, _ = convertIPv4(n.Mask[len(n.Mask)-net.IPv4len:])
+			prefixes.v4 = append(prefixes.v4, subnet)
+		} else {
+			var subnet ipv6Subnet
+			copy(subnet.Addr[:], n.IP.To16())
+			copy(subnet.Mask[:], n.Mask)
+			prefixes.v6 = append(prefixes.v6, subnet)
+		}
+	}
+	return prefixes
+}
+
+// parse checks action and finds prefix of destination. Destination
+// which is neither prefix nor address is a domain name, it has no
+// addresses until it is resolved.
+func (rule *blackholeRule) parse(pp *portPair) error {
+	if rule.Action == blackholeKNI && pp.PrivatePort.KNIName == "" {
+		return fmt.Errorf("Blackhole rule %s with kni action requires KNI interface of private port %d", rule.Destination, pp.PrivatePort.Index)
+	}
+	dst := rule.Destination
+	if ip := net.ParseIP(dst); ip != nil {
+		if ip.To4() != nil {
+			dst += "/32"
+		} else {
+			dst += "/128"
+		}
+	}
+	_, ipnet, err := net.ParseCIDR(dst)
+	if err == nil {
+		rule.prefixes.Store(newBlackholePrefixes([]*net.IPNet{ipnet}))
+		return nil
+	}
+	if !isDomainName(rule.Destination) {
+		return errors.New("Bad blackhole destination " + rule.Destination)
+	}
+	rule.domain = true
+	rule.prefixes.Store(&blackholePrefixes{})
+	return nil
+}
+
+// resolve looks up addresses of domain name. Old addresses are kept
+// if lookup fails.
+func (rule *blackholeRule) resolve() {
+	ips, err := net.LookupIP(rule.Destination)
+	if err != nil {
+		println("Warning! Failed to resolve blackholed domain", rule.Destination, ":", err.Error())
+		return
+	}
+	nets := []*net.IPNet{}
+	for _, ip := range ips {
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+			bits = 8 * net.IPv4len
+		}
+		nets = append(nets, &net.IPNet{
+			IP:   ip,
+			Mask: net.CIDRMask(bits, bits),
+		})
+	}
+	rule.prefixes.Store(newBlackholePrefixes(nets))
+}
+
+// addresses returns resolved addresses of domain name.
+func (rule *blackholeRule) addresses() []net.IP {
+	if !rule.domain {
+		return nil
+	}
+	prefixes := rule.prefixes.Load().(*blackholePrefixes)
+	ips := []net.IP{}
+	for i := range prefixes.v4 {
+		ips = append(ips, ipv4Net(prefixes.v4[i].Addr, prefixes.v4[i].Mask).IP.To4())
+	}
+	for i := range prefixes.v6 {
+		ips = append(ips, append(net.IP{}, prefixes.v6[i].Addr[:]...))
+	}
+	return ips
+}
+
+func (rule *blackholeRule) matches(dst4 types.IPv4Address, dst6 types.IPv6Address, ipv6 bool) bool {
+	prefixes := rule.prefixes.Load().(*blackholePrefixes)
+	if ipv6 {
+		for i := range prefixes.v6 {
+			if prefixes.v6[i].checkAddrWithingSubnet(dst6) {
+				return true
+			}
+		}
+		return false
+	}
+	for i := range prefixes.v4 {
+		if prefixes.v4[i].checkAddrWithingSubnet(dst4) {
+			return true
+		}
+	}
+	return false
+}
+
+// blackholeRules returns current rules of port pair. List is replaced
+// when rules change, so it may be used by packet handlers without
+// locking.
+func (pp *portPair) blackholeRules() []*blackholeRule {
+	list := pp.blackholes.Load()
+	if list == nil {
+		return nil
+	}
+	return list.([]*blackholeRule)
+}
+
+// checkBlackhole parses configured blackhole rules.
+func (pp *portPair) checkBlackhole() error {
+	for _, rule := range pp.Blackhole {
+		if rule == nil {
+			return errors.New("Blackhole rule should not be empty")
+		}
+		if err := rule.parse(pp); err != nil {
+			return err
+		}
+	}
+	pp.blackholes.Store(append([]*blackholeRule{}, pp.Blackhole...))
+	return nil
+}
+
+// handleBlackhole drops packet of private host or sends it to KNI if
+// its destination matches blackhole rule.
+func (pp *portPair) handleBlackhole(port *ipPort, pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) (uint, bool) {
+	rules := pp.blackholeRules()
+	if len(rules) == 0 {
+		return 0, false
+	}
+	var dst4 types.IPv4Address
+	var dst6 types.IPv6Address
+	if pktIPv4 != nil {
+		dst4 = packet.SwapBytesIPv4Addr(pktIPv4.DstAddr)
+	} else {
+		dst6 = pktIPv6.DstAddr
+	}
+	for _, rule := range rules {
+		if !rule.matches(dst4, dst6, pktIPv6 != nil) {
+			continue
+		}
+		atomic.AddUint64(&rule.packets, 1)
+		dir := DirDROP
+		if rule.Action == blackholeKNI {
+			dir = DirKNI
+		}
+		port.dumpPacket(pkt, dir)
+		return dir, true
+	}
+	return 0, false
+}
+
+// changeBlackholeRule adds rule, replacing rule with the same
+// destination, or removes rule with destination of rule.
+func (pp *portPair) changeBlackholeRule(rule *blackholeRule, enable bool) error {
+	if enable {
+		if err := rule.parse(pp); err != nil {
+			return err
+		}
+		if rule.domain {
+			rule.resolve()
+		}
+	}
+
+	blackholeMutex.Lock()
+	defer blackholeMutex.Unlock()
+	list := []*blackholeRule{}
+	found := false
+	for _, r := range pp.blackholeRules() {
+		if r.Destination == rule.Destination {
+			found = true
+			continue
+		}
+		list = append(list, r)
+	}
+	if !enable && !found {
+		return errors.New("Blackhole rule " + rule.Destination + " is not found")
+	}
+	if enable {
+		list = append(list, rule)
+	}
+	pp.blackholes.Store(list)
+	return nil
+}
+
+// mergeBlackholeRules returns parsed rules which replace all rules of
+// port pair. Rules which are not changed keep their counters and
+// addresses, domain names of new rules are resolved.
+func (pp *portPair) mergeBlackholeRules(rules []*blackholeRule) []*blackholeRule {
+	old := map[string]*blackholeRule{}
+	for _, r := range pp.blackholeRules() {
+		old[r.Destination] = r
+	}
+	list := []*blackholeRule{}
+	for _, rule := range rules {
+		if r, ok := old[rule.Destination]; ok && r.Action == rule.Action {
+			rule = r
+		} else if rule.domain {
+			rule.resolve()
+		}
+		list = append(list, rule)
+	}
+	return list
+}
+
+// setBlackholeRules replaces all rules of port pair with rules merged
+// by mergeBlackholeRules.
+func (pp *portPair) setBlackholeRules(list []*blackholeRule) {
+	blackholeMutex.Lock()
+	defer blackholeMutex.Unlock()
+	pp.blackholes.Store(list)
+}
+
+// StartBlackholeResolver starts periodic resolution of blackholed
+// domain names. Rules with domain names may be added at runtime, so
+// it runs even if there are none in config.
+func StartBlackholeResolver() {
+	go func() {
+		for {
+			for i := range Natconfig.PortPairs {
+				for _, rule := range Natconfig.PortPairs[i].blackholeRules() {
+					if rule.domain {
+						rule.resolve()
+					}
+				}
+			}
+			time.Sleep(blackholeResolveInterval)
+		}
+	}()
+}
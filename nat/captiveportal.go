@@ -0,0 +1,441 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+
+	upd "github.com/intel-go/nff-go-nat/updatecfg"
+)
+
+const (
+	// Portal port and redirected port when they are not configured
+	defaultPortalPort = 80
+	// Maximum number of redirected flows of one port pair. New
+	// flows are dropped until idle flows are forgotten.
+	maxPortalFlows = 65536
+)
+
+// Captive portal of private hosts, e.g. of guest network. HTTP flows
+// of unauthenticated hosts are redirected to portal address and all
+// their other traffic is dropped, except traffic to allowed
+// destinations. Portal is reached through public port.
+type captivePortalConfig struct {
+	// IPv4 and IPv6 portal addresses, at least one of them enables
+	// captive portal. Hosts of family without portal address have all
+	// their HTTP flows dropped.
+	Address  string `json:"address"`
+	Address6 string `json:"address6"`
+	// TCP port of portal
+	Port uint16 `json:"port"`
+	// Destination TCP ports of redirected HTTP flows
+	HTTPPorts []uint16 `json:"http-ports"`
+	// Addresses and prefixes which unauthenticated hosts may reach,
+	// e.g. DNS servers
+	Allowed []string `json:"allowed"`
+	// Hosts which are not listed are authenticated, otherwise they
+	// are unauthenticated until they are authenticated with GRPC
+	AuthenticatedByDefault bool `json:"authenticated-by-default"`
+	portal4                types.IPv4Address
+	portal6                types.IPv6Address
+	allowed4               []ipv4Subnet
+	allowed6               []ipv6Subnet
+}
+
+// Redirected flow is identified by private host address and source
+// port.
+type portalFlow struct {
+	host interface{}
+	port uint16
+}
+
+// Destination which private host asked for before its flow was
+// redirected to portal.
+type portalRedirect struct {
+	dst  interface{}
+	port uint16
+	// Time of last packet in nanoseconds since Unix epoch
+	lastused int64
+}
+
+// Captive portal state of port pair. Counters are updated from
+// translation handlers, so they should be accessed only atomically.
+type captivePortalState struct {
+	// Host states by types.IPv4Address, types.IPv6Address or
+	// types.MACAddress, true for authenticated hosts
+	hosts sync.Map
+	// Redirected flows by portalFlow, *portalRedirect values
+	flows      sync.Map
+	flowsCount int32
+	// Packets of unauthenticated hosts redirected to portal and
+	// dropped
+	redirected, dropped uint64
+}
+
+func (cfg *captivePortalConfig) enabled() bool {
+	return cfg.Address != "" || cfg.Address6 != ""
+}
+
+func (cfg *captivePortalConfig) check(pp *portPair) error {
+	if !cfg.enabled() {
+		return nil
+	}
+	if cfg.Address != "" {
+		ip := net.ParseIP(cfg.Address).To4()
+		if ip == nil {
+			return fmt.Errorf("Bad captive portal IPv4 address %s", cfg.Address)
+		}
+		cfg.portal4, _ = convertIPv4(ip)
+		if pp.PrivatePort.Subnet.Mask != 0 && pp.PrivatePort.Subnet.checkAddrWithingSubnet(cfg.portal4) {
+			return fmt.Errorf("Captive portal address %s of port %s should be reached through public port", cfg.Address, pp.PrivatePort.logName())
+		}
+	}
+	if cfg.Address6 != "" {
+		ip := net.ParseIP(cfg.Address6)
+		if ip == nil || ip.To4() != nil {
+			return fmt.Errorf("Bad captive portal IPv6 address %s", cfg.Address6)
+		}
+		if pp.DisableIPv6 {
+			return fmt.Errorf("Captive portal IPv6 address of port %s requires IPv6", pp.PrivatePort.logName())
+		}
+		copy(cfg.portal6[:], ip)
+		if pp.PrivatePort.Subnet6.Mask != zeroIPv6Addr && pp.PrivatePort.Subnet6.checkAddrWithingSubnet(cfg.portal6) {
+			return fmt.Errorf("Captive portal address %s of port %s should be reached through public port", cfg.Address6, pp.PrivatePort.logName())
+		}
+	}
+	if cfg.Port == 0 {
+		cfg.Port = defaultPortalPort
+	}
+	if len(cfg.HTTPPorts) == 0 {
+		cfg.HTTPPorts = []uint16{defaultPortalPort}
+	}
+	cfg.allowed4, cfg.allowed6 = nil, nil
+	for _, a := range cfg.Allowed {
+		prefix := a
+		if ip := net.ParseIP(a); ip != nil {
+			if ip.To4() != nil {
+				prefix += "/32"
+			} else {
+				prefix += "/128"
+			}
+		}
+		_, ipnet, err := net.ParseCIDR(prefix)
+		if err != nil {
+			return fmt.Errorf("Bad captive portal allowed destination %s", a)
+		}
+		if ip4 := ipnet.IP.To4(); ip4 != nil {
+			var subnet ipv4Subnet
+			subnet.Addr, _ = convertIPv4(ip4)
+			subnet.Mask, _ = convertIPv4(ipnet.Mask[len(ipnet.Mask)-net.IPv4len:])
+			cfg.allowed4 = append(cfg.allowed4, subnet)
+		} else {
+			var subnet ipv6Subnet
+			copy(subnet.Addr[:], ipnet.IP.To16())
+			copy(subnet.Mask[:], ipnet.Mask)
+			cfg.allowed6 = append(cfg.allowed6, subnet)
+		}
+	}
+	return nil
+}
+
+func (cfg *captivePortalConfig) isHTTPPort(port uint16) bool {
+	for _, p := range cfg.HTTPPorts {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+// portal returns portal address of IP family, nil if there is none.
+func (cfg *captivePortalConfig) portal(ipv6 bool) interface{} {
+	if ipv6 {
+		if cfg.Address6 == "" {
+			return nil
+		}
+		return cfg.portal6
+	}
+	if cfg.Address == "" {
+		return nil
+	}
+	return cfg.portal4
+}
+
+// allows returns true for packets which unauthenticated hosts may
+// send: to allowed destinations, to addresses of private port, to
+// multicast, broadcast and IPv6 link local addresses.
+func (cfg *captivePortalConfig) allows(port *ipPort, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) bool {
+	if pktIPv4 != nil {
+		dst := packet.SwapBytesIPv4Addr(pktIPv4.DstAddr)
+		if dst == port.Subnet.Addr || dst>>28 == 0xe || dst == types.IPv4Address(0xffffffff) {
+			return true
+		}
+		for i := range cfg.allowed4 {
+			if cfg.allowed4[i].checkAddrWithingSubnet(dst) {
+				return true
+			}
+		}
+		return false
+	}
+	dst := pktIPv6.DstAddr
+	if dst == port.Subnet6.Addr || dst[0] == 0xff || (dst[0] == 0xfe && dst[1]&0xc0 == 0x80) {
+		return true
+	}
+	for i := range cfg.allowed6 {
+		if cfg.allowed6[i].checkAddrWithingSubnet(dst) {
+			return true
+		}
+	}
+	return false
+}
+
+// portalAuthenticated returns state of private host. State of MAC
+// address takes precedence over state of IP address.
+func (pp *portPair) portalAuthenticated(mac types.MACAddress, addr interface{}) bool {
+	if v, ok := pp.portal.hosts.Load(mac); ok {
+		return v.(bool)
+	}
+	if v, ok := pp.portal.hosts.Load(addr); ok {
+		return v.(bool)
+	}
+	return pp.CaptivePortal.AuthenticatedByDefault
+}
+
+// portalAllows returns true if packet of private host passes captive
+// portal without redirection: host is authenticated or destination
+// is allowed.
+func (pp *portPair) portalAllows(port *ipPort, mac types.MACAddress, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) bool {
+	src, _ := packetAddresses(pktIPv4, pktIPv6)
+	return pp.portalAuthenticated(mac, src) || pp.CaptivePortal.allows(port, pktIPv4, pktIPv6)
+}
+
+// portalRedirects returns portal address which flow of unauthenticated
+// private host is redirected to, nil if flow is dropped.
+func (cfg *captivePortalConfig) portalRedirects(ipv6 bool, protocol uint8, dstPort uint16) interface{} {
+	if protocol != types.TCPNumber || !cfg.isHTTPPort(dstPort) {
+		return nil
+	}
+	return cfg.portal(ipv6)
+}
+
+// handleCaptivePortal redirects HTTP flows of unauthenticated private
+// host to portal and drops its other packets. Redirected packets have
+// portal as destination and are translated as usual, so it returns
+// true only if packet is dropped.
+func (pp *portPair) handleCaptivePortal(port *ipPort, pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) (uint, bool) {
+	cfg := &pp.CaptivePortal
+	if !cfg.enabled() || pp.portalAllows(port, pkt.Ether.SAddr, pktIPv4, pktIPv6) {
+		return 0, false
+	}
+	// Fragments without transport header cannot be redirected
+	if pktIPv4 == nil || !isIPv4LaterFragment(pktIPv4) {
+		src, dst := packetAddresses(pktIPv4, pktIPv6)
+		protocol, pktTCP, _, _, srcPort, dstPort := ParseAllKnownL4(pkt, pktIPv4, pktIPv6)
+		if portal := cfg.portalRedirects(pktIPv6 != nil, protocol, dstPort); portal != nil &&
+			pp.redirectToPortal(pktIPv4, pktIPv6, pktTCP, portalFlow{host: src, port: srcPort}, dst, dstPort, portal) {
+			atomic.AddUint64(&pp.portal.redirected, 1)
+			return 0, false
+		}
+	}
+	atomic.AddUint64(&pp.portal.dropped, 1)
+	port.dumpPacket(pkt, DirDROP)
+	return DirDROP, true
+}
+
+// redirectToPortal remembers original destination of flow and sets
+// portal as its destination. It returns false if there are too many
+// redirected flows.
+func (pp *portPair) redirectToPortal(pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr, pktTCP *packet.TCPHdr,
+	key portalFlow, dst interface{}, dstPort uint16, portal interface{}) bool {
+	now := time.Now().UnixNano()
+	v, found := pp.portal.flows.Load(key)
+	if !found || v.(*portalRedirect).dst != dst || v.(*portalRedirect).port != dstPort {
+		// Source port of host may be reused for another destination
+		if !found && atomic.LoadInt32(&pp.portal.flowsCount) >= maxPortalFlows {
+			return false
+		}
+		v = &portalRedirect{dst: dst, port: dstPort}
+		if _, loaded := pp.portal.flows.LoadOrStore(key, v); loaded {
+			pp.portal.flows.Store(key, v)
+		} else {
+			atomic.AddInt32(&pp.portal.flowsCount, 1)
+		}
+	}
+	atomic.StoreInt64(&v.(*portalRedirect).lastused, now)
+	rewriteTCPEndpoint(pktIPv4, pktIPv6, pktTCP, false, portal, pp.CaptivePortal.Port)
+	return true
+}
+
+// restorePortalSource sets destination which private host asked for
+// as source of portal reply which is translated to private host with
+// address and port.
+func (pp *portPair) restorePortalSource(pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr, pktTCP *packet.TCPHdr,
+	v4addr types.IPv4Address, v6addr types.IPv6Address, port uint16) {
+	if atomic.LoadInt32(&pp.portal.flowsCount) == 0 || packet.SwapBytesUint16(pktTCP.SrcPort) != pp.CaptivePortal.Port {
+		return
+	}
+	var host interface{}
+	if pktIPv6 != nil {
+		if pp.CaptivePortal.Address6 == "" || pktIPv6.SrcAddr != pp.CaptivePortal.portal6 {
+			return
+		}
+		host = v6addr
+	} else {
+		if pp.CaptivePortal.Address == "" || packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr) != pp.CaptivePortal.portal4 {
+			return
+		}
+		host = v4addr
+	}
+	v, found := pp.portal.flows.Load(portalFlow{host: host, port: port})
+	if !found {
+		return
+	}
+	r := v.(*portalRedirect)
+	atomic.StoreInt64(&r.lastused, time.Now().UnixNano())
+	rewriteTCPEndpoint(pktIPv4, pktIPv6, pktTCP, true, r.dst, r.port)
+}
+
+// rewriteTCPEndpoint replaces source or destination address and port
+// of TCP packet and updates its checksums. Address is
+// types.IPv4Address or types.IPv6Address.
+func rewriteTCPEndpoint(pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr, pktTCP *packet.TCPHdr, source bool, addr interface{}, port uint16) {
+	old := saveTranslatedHeader(pktIPv4, pktIPv6)
+	var oldPort uint16
+	if source {
+		oldPort = packet.SwapBytesUint16(pktTCP.SrcPort)
+		pktTCP.SrcPort = packet.SwapBytesUint16(port)
+		if pktIPv6 != nil {
+			pktIPv6.SrcAddr = addr.(types.IPv6Address)
+		} else {
+			pktIPv4.SrcAddr = packet.SwapBytesIPv4Addr(addr.(types.IPv4Address))
+		}
+	} else {
+		oldPort = packet.SwapBytesUint16(pktTCP.DstPort)
+		pktTCP.DstPort = packet.SwapBytesUint16(port)
+		if pktIPv6 != nil {
+			pktIPv6.DstAddr = addr.(types.IPv6Address)
+		} else {
+			pktIPv4.DstAddr = packet.SwapBytesIPv4Addr(addr.(types.IPv4Address))
+		}
+	}
+	if !NoCalculateChecksum {
+		old.updateChecksums(oldPort, port, pktTCP, nil, nil)
+	}
+}
+
+// forgetIdlePortalFlows removes redirected flows without packets for
+// connection timeout.
+func (pp *portPair) forgetIdlePortalFlows(now time.Time) {
+	pp.portal.flows.Range(func(k, v interface{}) bool {
+		if now.Sub(time.Unix(0, atomic.LoadInt64(&v.(*portalRedirect).lastused))) > connectionTimeout {
+			pp.portal.flows.Delete(k)
+			atomic.AddInt32(&pp.portal.flowsCount, -1)
+		}
+		return true
+	})
+}
+
+// StartCaptivePortal starts forgetting idle redirected flows of port
+// pairs with captive portal.
+func StartCaptivePortal() {
+	enabled := false
+	for i := range Natconfig.PortPairs {
+		enabled = enabled || Natconfig.PortPairs[i].CaptivePortal.enabled()
+	}
+	if !enabled {
+		return
+	}
+	go func() {
+		for {
+			time.Sleep(connectionTimeout)
+			now := time.Now()
+			for i := range Natconfig.PortPairs {
+				if Natconfig.PortPairs[i].CaptivePortal.enabled() {
+					Natconfig.PortPairs[i].forgetIdlePortalFlows(now)
+				}
+			}
+		}
+	}()
+}
+
+// setPortalHost changes state of private host identified by address
+// or MAC address. Default state forgets host.
+func (pp *portPair) setPortalHost(host *upd.CaptivePortalHost) error {
+	var key interface{}
+	mac := host.GetMacAddress()
+	switch {
+	case host.GetAddress() != nil && len(mac) != 0:
+		return fmt.Errorf("Captive portal host should have either address or MAC address")
+	case host.GetAddress() != nil:
+		addr, err := convertNeighborAddress(host.GetAddress())
+		if err != nil {
+			return err
+		}
+		key = addr
+	case len(mac) == types.EtherAddrLen:
+		var addr types.MACAddress
+		copy(addr[:], mac)
+		key = addr
+	default:
+		return fmt.Errorf("Bad MAC address length %d", len(mac))
+	}
+	switch host.GetState() {
+	case upd.CaptivePortalState_PORTAL_DEFAULT:
+		pp.portal.hosts.Delete(key)
+	case upd.CaptivePortalState_PORTAL_AUTHENTICATED:
+		pp.portal.hosts.Store(key, true)
+	case upd.CaptivePortalState_PORTAL_UNAUTHENTICATED:
+		pp.portal.hosts.Store(key, false)
+	default:
+		return fmt.Errorf("Bad captive portal state %d", host.GetState())
+	}
+	return nil
+}
+
+// portalHostsReply returns states of listed hosts of port pair.
+func (pp *portPair) portalHostsReply(portId uint32) *upd.CaptivePortalHostsReply {
+	reply := &upd.CaptivePortalHostsReply{
+		InterfaceId:     portId,
+		DefaultState:    upd.CaptivePortalState_PORTAL_UNAUTHENTICATED,
+		Hosts:           []*upd.CaptivePortalHost{},
+		RedirectedFlows: uint64(atomic.LoadInt32(&pp.portal.flowsCount)),
+		Tenant:          pp.Tenant,
+	}
+	if pp.CaptivePortal.AuthenticatedByDefault {
+		reply.DefaultState = upd.CaptivePortalState_PORTAL_AUTHENTICATED
+	}
+	pp.portal.hosts.Range(func(k, v interface{}) bool {
+		h := &upd.CaptivePortalHost{
+			State: upd.CaptivePortalState_PORTAL_UNAUTHENTICATED,
+		}
+		if v.(bool) {
+			h.State = upd.CaptivePortalState_PORTAL_AUTHENTICATED
+		}
+		if mac, ok := k.(types.MACAddress); ok {
+			h.MacAddress = append([]byte{}, mac[:]...)
+		} else {
+			h.Address = hostAddress(k)
+		}
+		reply.Hosts = append(reply.Hosts, h)
+		return true
+	})
+	return reply
+}
+
+// counters returns captive portal counters of port pair.
+func (s *captivePortalState) counters() []*upd.Counter {
+	return []*upd.Counter{
+		&upd.Counter{Name: "portal-redirected-packets", Value: atomic.LoadUint64(&s.redirected)},
+		&upd.Counter{Name: "portal-dropped-packets", Value: atomic.LoadUint64(&s.dropped)},
+		&upd.Counter{Name: "portal-redirected-flows", Value: uint64(atomic.LoadInt32(&s.flowsCount))},
+	}
+}
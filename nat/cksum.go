@@ -10,9 +10,23 @@ import (
 	"unsafe"
 )
 
+// Offsets of checksums in transport headers
+const (
+	tcpChecksumOffset  = 16
+	udpChecksumOffset  = 6
+	icmpChecksumOffset = 2
+)
+
+// Checksums of IPv4 packets with options are always calculated in
+// software because NFF-Go functions and offload flags assume fixed
+// header length.
 func setIPv4UDPChecksum(pkt *packet.Packet, calculateChecksum, hWTXChecksum bool) {
 	if calculateChecksum {
 		l3 := pkt.GetIPv4NoCheck()
+		if hasIPv4Options(l3) {
+			ipv4OptionsChecksums(l3, pkt.L4, udpChecksumOffset, true)
+			return
+		}
 		l4 := pkt.GetUDPNoCheck()
 		if hWTXChecksum {
 			l3.HdrChecksum = 0
@@ -33,6 +47,10 @@ func setIPv4UDPChecksum(pkt *packet.Packet, calculateChecksum, hWTXChecksum bool
 func setIPv4TCPChecksum(pkt *packet.Packet, calculateChecksum, hWTXChecksum bool) {
 	if calculateChecksum {
 		l3 := pkt.GetIPv4NoCheck()
+		if hasIPv4Options(l3) {
+			ipv4OptionsChecksums(l3, pkt.L4, tcpChecksumOffset, true)
+			return
+		}
 		l4 := pkt.GetTCPNoCheck()
 		if hWTXChecksum {
 			l3.HdrChecksum = 0
@@ -53,6 +71,10 @@ func setIPv4TCPChecksum(pkt *packet.Packet, calculateChecksum, hWTXChecksum bool
 func setIPv4ICMPChecksum(pkt *packet.Packet, calculateChecksum, hWTXChecksum bool) {
 	if calculateChecksum {
 		l3 := pkt.GetIPv4NoCheck()
+		if hasIPv4Options(l3) {
+			ipv4OptionsChecksums(l3, pkt.L4, icmpChecksumOffset, false)
+			return
+		}
 		if hWTXChecksum {
 			l3.HdrChecksum = 0
 			l2len := uint32(types.EtherLen)
@@ -69,6 +91,28 @@ func setIPv4ICMPChecksum(pkt *packet.Packet, calculateChecksum, hWTXChecksum boo
 	}
 }
 
+// setIPv4HdrChecksum calculates only IPv4 header checksum. It is used
+// for fragments because transport checksum covers whole datagram.
+func setIPv4HdrChecksum(pkt *packet.Packet, calculateChecksum, hWTXChecksum bool) {
+	if calculateChecksum {
+		l3 := pkt.GetIPv4NoCheck()
+		if hasIPv4Options(l3) {
+			ipv4OptionsChecksums(l3, nil, -1, false)
+			return
+		}
+		if hWTXChecksum {
+			l3.HdrChecksum = 0
+			l2len := uint32(types.EtherLen)
+			if pkt.Ether.EtherType == types.SwapVLANNumber {
+				l2len += types.VLANLen
+			}
+			pkt.SetTXIPv4OLFlags(l2len, types.IPv4MinLen)
+		} else {
+			l3.HdrChecksum = packet.SwapBytesUint16(packet.CalculateIPv4Checksum(l3))
+		}
+	}
+}
+
 func setIPv6UDPChecksum(pkt *packet.Packet, calculateChecksum, hWTXChecksum bool) {
 	if calculateChecksum {
 		l3 := pkt.GetIPv6NoCheck()
@@ -114,3 +158,153 @@ func setIPv6ICMPChecksum(pkt *packet.Packet, calculateChecksum, hWTXChecksum boo
 			unsafe.Pointer(uintptr(unsafe.Pointer(l4))+types.ICMPLen)))
 	}
 }
+
+// Header fields which translation changes, remembered before packet
+// is translated, so that checksums are updated only for changed 16
+// bit words as described in RFC 1624 instead of being calculated over
+// whole packet.
+type translatedHeader struct {
+	pktIPv4 *packet.IPv4Hdr
+	pktIPv6 *packet.IPv6Hdr
+	// Version, header length and type of service of IPv4 header
+	ipv4Word0 uint16
+	src4      types.IPv4Address
+	dst4      types.IPv4Address
+	src6      types.IPv6Address
+	dst6      types.IPv6Address
+}
+
+func saveTranslatedHeader(pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) translatedHeader {
+	if pktIPv4 != nil {
+		return translatedHeader{
+			pktIPv4:   pktIPv4,
+			ipv4Word0: uint16(pktIPv4.VersionIhl)<<8 | uint16(pktIPv4.TypeOfService),
+			src4:      packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr),
+			dst4:      packet.SwapBytesIPv4Addr(pktIPv4.DstAddr),
+		}
+	}
+	return translatedHeader{
+		pktIPv6: pktIPv6,
+		src6:    pktIPv6.SrcAddr,
+		dst6:    pktIPv6.DstAddr,
+	}
+}
+
+// protocol returns transport protocol of translated packet.
+func (old *translatedHeader) protocol() uint8 {
+	if old.pktIPv4 != nil {
+		return old.pktIPv4.NextProtoID
+	}
+	return old.pktIPv6.Proto
+}
+
+func updateChecksumIPv4Addr(cksum uint16, old, new types.IPv4Address) uint16 {
+	if old != new {
+		cksum = updateChecksum(cksum, uint16(old>>16), uint16(new>>16))
+		cksum = updateChecksum(cksum, uint16(old), uint16(new))
+	}
+	return cksum
+}
+
+func updateChecksumIPv6Addr(cksum uint16, old, new types.IPv6Address) uint16 {
+	if old != new {
+		for i := 0; i < types.IPv6AddrLen; i += 2 {
+			cksum = updateChecksum(cksum, uint16(old[i])<<8|uint16(old[i+1]), uint16(new[i])<<8|uint16(new[i+1]))
+		}
+	}
+	return cksum
+}
+
+// updateChecksums adjusts IPv4 header checksum and transport checksum
+// of translated packet. It returns false if transport checksum cannot
+// be updated and should be calculated.
+func (old *translatedHeader) updateChecksums(oldPort, newPort uint16, pktTCP *packet.TCPHdr, pktUDP *packet.UDPHdr, pktICMP *packet.ICMPHdr) bool {
+	pktIPv4, pktIPv6 := old.pktIPv4, old.pktIPv6
+	var cksum *uint16
+	switch {
+	case pktTCP != nil:
+		cksum = &pktTCP.Cksum
+	case pktUDP != nil:
+		// Zero UDP checksum means that IPv4 datagram has no checksum,
+		// it is not allowed for IPv6. UDP-Lite and DCCP always have
+		// checksum.
+		if pktUDP.DgramCksum == 0 && old.protocol() == types.UDPNumber {
+			if pktIPv6 != nil {
+				return false
+			}
+		} else {
+			cksum = &pktUDP.DgramCksum
+		}
+	default:
+		cksum = &pktICMP.Cksum
+	}
+
+	// ICMP checksum doesn't cover IPv4 pseudo header
+	pseudoHdr := pktICMP == nil || pktIPv6 != nil
+	var c uint16
+	if cksum != nil {
+		c = packet.SwapBytesUint16(*cksum)
+		c = updateChecksum(c, oldPort, newPort)
+	}
+	if pktIPv4 != nil {
+		src := packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr)
+		dst := packet.SwapBytesIPv4Addr(pktIPv4.DstAddr)
+		hc := packet.SwapBytesUint16(pktIPv4.HdrChecksum)
+		hc = updateChecksum(hc, old.ipv4Word0, uint16(pktIPv4.VersionIhl)<<8|uint16(pktIPv4.TypeOfService))
+		hc = updateChecksumIPv4Addr(hc, old.src4, src)
+		hc = updateChecksumIPv4Addr(hc, old.dst4, dst)
+		pktIPv4.HdrChecksum = packet.SwapBytesUint16(hc)
+		if cksum != nil && pseudoHdr {
+			c = updateChecksumIPv4Addr(c, old.src4, src)
+			c = updateChecksumIPv4Addr(c, old.dst4, dst)
+		}
+	} else if cksum != nil {
+		c = updateChecksumIPv6Addr(c, old.src6, pktIPv6.SrcAddr)
+		c = updateChecksumIPv6Addr(c, old.dst6, pktIPv6.DstAddr)
+	}
+	if cksum != nil {
+		if pktUDP != nil && old.protocol() != dccpNumber && c == 0 {
+			c = 0xffff
+		}
+		*cksum = packet.SwapBytesUint16(c)
+	}
+	return true
+}
+
+// setTranslatedChecksums sets checksums of packet which got new
+// addresses and port and is sent by out port, nil for KNI interface.
+// Checksum strategy of port chooses whether they are updated
+// incrementally, and checksums which cannot be updated are offloaded
+// or calculated. UDP-Lite and DCCP checksums may cover only part of
+// data, network cards cannot offload them and they are always
+// updated.
+func setTranslatedChecksums(pkt *packet.Packet, ipv6 bool, oldPort, newPort uint16, pktTCP *packet.TCPHdr, pktUDP *packet.UDPHdr, pktICMP *packet.ICMPHdr,
+	out *ipPort, old *translatedHeader) {
+	if NoCalculateChecksum {
+		return
+	}
+	if (out.prefersIncremental() || hasUDPLayout(old.protocol())) && old.updateChecksums(oldPort, newPort, pktTCP, pktUDP, pktICMP) {
+		out.countChecksum(checksumIncrementalUpdate)
+		return
+	}
+	hWTXChecksum := out != nil && out.hwTXChecksum
+	if hWTXChecksum && (ipv6 || !hasIPv4Options(old.pktIPv4)) {
+		out.countChecksum(checksumOffloaded)
+	} else {
+		out.countChecksum(checksumCalculated)
+	}
+	switch {
+	case pktTCP != nil && ipv6:
+		setIPv6TCPChecksum(pkt, true, hWTXChecksum)
+	case pktTCP != nil:
+		setIPv4TCPChecksum(pkt, true, hWTXChecksum)
+	case pktUDP != nil && ipv6:
+		setIPv6UDPChecksum(pkt, true, hWTXChecksum)
+	case pktUDP != nil:
+		setIPv4UDPChecksum(pkt, true, hWTXChecksum)
+	case ipv6:
+		setIPv6ICMPChecksum(pkt, true, hWTXChecksum)
+	default:
+		setIPv4ICMPChecksum(pkt, true, hWTXChecksum)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+)
+
+// How port sets checksums of packets which it sends. Translation
+// changes only a few header words, so their checksums may be updated
+// incrementally in software, offloaded to network card or calculated
+// over whole packet in software. Global NoCalculateChecksum and
+// NoHWTXChecksum flags override port strategy.
+type checksumStrategy int
+
+const (
+	// Translated packets are updated incrementally, packets which
+	// checksums cannot be updated and packets generated by NAT are
+	// offloaded if network card supports it and calculated in
+	// software otherwise
+	checksumAuto checksumStrategy = iota
+	// Checksums are offloaded if network card supports it, otherwise
+	// translated packets are updated incrementally
+	checksumOffload
+	// Checksums are never offloaded, translated packets are updated
+	// incrementally
+	checksumIncremental
+	// Checksums are always calculated in software over whole packet
+	checksumSoftware
+)
+
+var checksumStrategyLookup = map[string]checksumStrategy{
+	"auto":        checksumAuto,
+	"offload":     checksumOffload,
+	"incremental": checksumIncremental,
+	"software":    checksumSoftware,
+}
+
+// Ways in which checksums of sent packet are set.
+const (
+	checksumOffloaded = iota
+	checksumIncrementalUpdate
+	checksumCalculated
+	checksumPaths
+)
+
+// Names of checksum paths in port statistics.
+var checksumPathNames = [checksumPaths]string{"checksum-offloaded", "checksum-incremental", "checksum-software"}
+
+// UnmarshalJSON parses checksum strategy.
+func (out *checksumStrategy) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	result, ok := checksumStrategyLookup[s]
+	if !ok {
+		return errors.New("Bad checksum strategy: " + s)
+	}
+
+	*out = result
+	return nil
+}
+
+// String returns strategy name as it is used in config file.
+func (strategy checksumStrategy) String() string {
+	for name, s := range checksumStrategyLookup {
+		if s == strategy {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// MarshalJSON writes strategy name as it is used in config file.
+func (strategy checksumStrategy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strategy.String())
+}
+
+// allowsOffload returns true if strategy lets network card calculate
+// checksums.
+func (strategy checksumStrategy) allowsOffload() bool {
+	return strategy == checksumAuto || strategy == checksumOffload
+}
+
+// prefersIncremental returns true if checksums of translated packets
+// sent by port should be updated incrementally. Port is nil for
+// packets sent to KNI interface.
+func (port *ipPort) prefersIncremental() bool {
+	if port == nil {
+		return true
+	}
+	switch port.ChecksumStrategy {
+	case checksumOffload:
+		return !port.hwTXChecksum
+	case checksumSoftware:
+		return false
+	}
+	return true
+}
+
+// countChecksum counts packet which checksums port set in specified
+// way.
+func (port *ipPort) countChecksum(path int) {
+	if port != nil {
+		atomic.AddUint64(&port.checksums[path], 1)
+	}
+}
@@ -0,0 +1,598 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/types"
+
+	upd "github.com/intel-go/nff-go-nat/updatecfg"
+)
+
+// Commit replaces settings of running config which may be changed at
+// runtime with settings of candidate config in running config format.
+// Candidate is checked as a whole and staged before anything is
+// changed. Address changes are applied first with the same functions
+// which control API uses, because they configure KNI interfaces and
+// may fail. Then all other staged settings of every port pair are
+// swapped in at once with mutex of port pair locked, so that packet
+// handlers never see half of a commit. If address change fails,
+// addresses which are already changed are rolled back. Commit which
+// requires confirmation is rolled back unless it is confirmed in time,
+// so that a change which cuts off control API connection undoes
+// itself.
+
+// Keys of port and port pair settings which commit changes. All other
+// settings of candidate should be equal to running config.
+var (
+	commitPortKeys = []string{"subnet", "subnet6", "forward-ports", "static-neighbors"}
+	commitPairKeys = []string{"egress-policer", "port-triggers", "blackhole", "session-log-sampling"}
+)
+
+// Time to confirm commit is limited, so that forgotten commit doesn't
+// roll back long after it was made
+const maxCommitConfirmTimeout = 1 * time.Hour
+
+// Change of one staged setting of running port pair. It is applied with
+// mutex of port pair locked and cannot fail, everything it sets is
+// checked while it is staged.
+type commitStep func()
+
+// Staged changes of running config.
+type stagedCommit struct {
+	// Address changes, they are applied before other changes
+	addresses []*upd.InterfaceAddressChangeRequest
+	pairs     []*portPair
+	// Changes of port pairs in the order of pairs
+	steps [][]commitStep
+}
+
+// Commit which is rolled back unless it is confirmed in time.
+type pendingCommit struct {
+	updater *server
+	// Running config before commit
+	previous []byte
+	timer    *time.Timer
+}
+
+var (
+	// Serializes commits of all control API servers
+	commitMutex sync.Mutex
+	// Commits waiting for confirmation by NAT instance of server which
+	// made them, main control API server has nil instance
+	pendingCommits = map[*natInstance]*pendingCommit{}
+)
+
+// prepareCandidate sets state of candidate port pair which is
+// computed at start to state of running port pair, so that candidate
+// settings are checked the same way as running ones.
+func (pp *portPair) prepareCandidate(running *portPair) {
+	pp.PrivatePort.prepareCandidate(&running.PrivatePort, &pp.PublicPort)
+	pp.PublicPort.prepareCandidate(&running.PublicPort, &pp.PrivatePort)
+}
+
+func (port *ipPort) prepareCandidate(running, opposite *ipPort) {
+	port.Type = running.Type
+	port.opposite = opposite
+	port.tenant = running.tenant
+	port.staticArpMode = running.staticArpMode
+	port.ipv4Disabled = running.ipv4Disabled
+	port.ipv6Disabled = running.ipv6Disabled
+	// Subnets with dhcp keep their current addresses
+	if !port.Subnet.addressAcquired {
+		port.Subnet.Addr = running.Subnet.Addr
+		port.Subnet.Mask = running.Subnet.Mask
+		port.Subnet.addressAcquired = running.Subnet.addressAcquired
+	}
+	if !port.Subnet6.addressAcquired {
+		port.Subnet6.Addr = running.Subnet6.Addr
+		port.Subnet6.Mask = running.Subnet6.Mask
+		port.Subnet6.addressAcquired = running.Subnet6.addressAcquired
+	}
+}
+
+// parseCandidate parses candidate config and returns its port pairs
+// in the order of running port pairs.
+func (s *server) parseCandidate(data []byte, running []*portPair) ([]*portPair, error) {
+	cfg := &Config{}
+	var inst *natInstance
+	pairs := []*portPair{}
+	if s.instance != nil {
+		inst = &natInstance{}
+		if err := json.Unmarshal(data, inst); err != nil {
+			return nil, err
+		}
+		for i := range inst.PortPairs {
+			pp := &inst.PortPairs[i]
+			if pp.Tenant == "" {
+				pp.Tenant = inst.Name
+			}
+			pp.instance = inst
+			pairs = append(pairs, pp)
+		}
+	} else {
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, err
+		}
+		if err := cfg.mergeInstances(); err != nil {
+			return nil, err
+		}
+		for i := range cfg.PortPairs {
+			pairs = append(pairs, &cfg.PortPairs[i])
+		}
+	}
+	if len(pairs) != len(running) {
+		return nil, fmt.Errorf("Candidate config has %d port pairs while running config has %d", len(pairs), len(running))
+	}
+	for i, pp := range pairs {
+		pp.prepareCandidate(running[i])
+	}
+
+	runningDoc, err := configDocument(Natconfig, s.instance, running)
+	if err != nil {
+		return nil, err
+	}
+	candidateDoc, err := configDocument(cfg, inst, pairs)
+	if err != nil {
+		return nil, err
+	}
+	path, different, err := configDifference(runningDoc, candidateDoc)
+	if err != nil {
+		return nil, err
+	}
+	if different {
+		return nil, errors.New("Setting " + path + " of candidate config differs from running config, it cannot be changed without restart")
+	}
+	return pairs, nil
+}
+
+// configDifference compares config documents without settings which
+// commit changes. It returns path of first setting which differs.
+func configDifference(running, candidate []byte) (string, bool, error) {
+	var a, b interface{}
+	if err := json.Unmarshal(running, &a); err != nil {
+		return "", false, err
+	}
+	if err := json.Unmarshal(candidate, &b); err != nil {
+		return "", false, err
+	}
+	removeCommitSettings(a)
+	removeCommitSettings(b)
+	path, different := jsonDifference(a, b, "")
+	return path, different, nil
+}
+
+// removeCommitSettings deletes settings which commit changes from
+// port pairs of config document and of its NAT instances.
+func removeCommitSettings(doc interface{}) {
+	root, _ := doc.(map[string]interface{})
+	lists := []interface{}{root["port-pairs"]}
+	instances, _ := root["instances"].([]interface{})
+	for _, inst := range instances {
+		if m, ok := inst.(map[string]interface{}); ok {
+			lists = append(lists, m["port-pairs"])
+		}
+	}
+	for _, list := range lists {
+		pairs, _ := list.([]interface{})
+		for _, p := range pairs {
+			pair, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, key := range commitPairKeys {
+				delete(pair, key)
+			}
+			for _, name := range []string{"private-port", "public-port"} {
+				if port, ok := pair[name].(map[string]interface{}); ok {
+					for _, key := range commitPortKeys {
+						delete(port, key)
+					}
+				}
+			}
+		}
+	}
+}
+
+// jsonDifference returns path of first value which differs in decoded
+// JSON documents.
+func jsonDifference(a, b interface{}, path string) (string, bool) {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			return path + "/", true
+		}
+		keys := []string{}
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, ok := av[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if p, different := jsonDifference(av[k], bv[k], path+"/"+k); different {
+				return p, true
+			}
+		}
+		return "", false
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return path, true
+		}
+		for i := range av {
+			if p, different := jsonDifference(av[i], bv[i], path+"/"+strconv.Itoa(i)); different {
+				return p, true
+			}
+		}
+		return "", false
+	}
+	if !reflect.DeepEqual(a, b) {
+		return path, true
+	}
+	return "", false
+}
+
+func jsonEqual(a, b interface{}) bool {
+	da, erra := json.Marshal(a)
+	db, errb := json.Marshal(b)
+	return erra == nil && errb == nil && bytes.Equal(da, db)
+}
+
+// subnetRequest returns GRPC request which sets address of port.
+func subnetRequest(port *ipPort, addr net.IP, mask net.IPMask) *upd.InterfaceAddressChangeRequest {
+	ones, _ := mask.Size()
+	return &upd.InterfaceAddressChangeRequest{
+		InterfaceId: uint32(port.Index),
+		PortSubnet: &upd.Subnet{
+			Address:        gnmiIPAddress(addr),
+			MaskBitsNumber: uint32(ones),
+		},
+	}
+}
+
+// neighborRequest returns GRPC request which adds or deletes static
+// neighbor of port.
+func neighborRequest(port *ipPort, n *staticNeighbor) *upd.NeighborChangeRequest {
+	mac, _ := net.ParseMAC(n.MAC)
+	return &upd.NeighborChangeRequest{
+		InterfaceId: uint32(port.Index),
+		Address:     gnmiIPAddress(net.ParseIP(n.Address)),
+		MacAddress:  mac,
+	}
+}
+
+// stagePort checks candidate settings of port and returns requests
+// which change its addresses and steps which change its other
+// settings.
+func (s *server) stagePort(pp *portPair, running, candidate *ipPort) ([]*upd.InterfaceAddressChangeRequest, []commitStep, error) {
+	var requests []*upd.InterfaceAddressChangeRequest
+	steps := []commitStep{}
+
+	if c := &candidate.Subnet; !running.familyDisabled(false) && c.addressAcquired &&
+		(c.Addr != running.Subnet.Addr || c.Mask != running.Subnet.Mask || !running.Subnet.addressAcquired) {
+		a := types.IPv4ToBytes(c.Addr)
+		m := types.IPv4ToBytes(c.Mask)
+		requests = append(requests, subnetRequest(running, net.IPv4(a[3], a[2], a[1], a[0]), net.IPv4Mask(m[3], m[2], m[1], m[0])))
+	}
+	if c := &candidate.Subnet6; !running.familyDisabled(true) && c.addressAcquired &&
+		(c.Addr != running.Subnet6.Addr || c.Mask != running.Subnet6.Mask || !running.Subnet6.addressAcquired) {
+		requests = append(requests, subnetRequest(running, net.IP(c.Addr[:]), net.IPMask(c.Mask[:])))
+	}
+
+	// Neighbors are compared in normalized form of neighbor table
+	if err := candidate.checkStaticNeighbors(); err != nil {
+		return nil, nil, err
+	}
+	wanted := candidate.currentStaticNeighbors()
+	current := map[string]staticNeighbor{}
+	for _, n := range running.currentStaticNeighbors() {
+		current[n.Address] = n
+	}
+	for i := range wanted {
+		n := &wanted[i]
+		if c, ok := current[n.Address]; ok && c.MAC == n.MAC {
+			delete(current, n.Address)
+			continue
+		}
+		delete(current, n.Address)
+		ip, err := convertNeighborAddress(neighborRequest(running, n).GetAddress())
+		if err != nil {
+			return nil, nil, err
+		}
+		mac, _ := net.ParseMAC(n.MAC)
+		var entry neighborEntry
+		copy(entry.mac[:], mac)
+		entry.static = true
+		steps = append(steps, func() {
+			running.arpTable.Store(ip, entry)
+		})
+	}
+	for _, n := range current {
+		ip, err := convertNeighborAddress(neighborRequest(running, &n).GetAddress())
+		if err != nil {
+			return nil, nil, err
+		}
+		steps = append(steps, func() {
+			running.arpTable.Delete(ip)
+		})
+	}
+
+	type forwardKey struct {
+		protocol protocolId
+		port     uint16
+	}
+	forwards := map[forwardKey]bool{}
+	changed := false
+	for i := range candidate.ForwardPorts {
+		fp := &candidate.ForwardPorts[i]
+		if err := candidate.checkPortForwarding(fp); err != nil {
+			return nil, nil, err
+		}
+		if running.Type == iPUBLIC && pp.isLocalPort(fp.Port) &&
+			(fp.Protocol.id == types.TCPNumber || fp.Protocol.id == types.UDPNumber) {
+			return nil, nil, fmt.Errorf("Port %d is reserved in local-ports of interface %d and cannot be forwarded", fp.Port, running.Index)
+		}
+		key := forwardKey{fp.Protocol, fp.Port}
+		if forwards[key] {
+			return nil, nil, fmt.Errorf("Port %d of protocol %s is forwarded twice on port %s", fp.Port, fp.Protocol.String(), running.logName())
+		}
+		forwards[key] = true
+		unchanged := false
+		for j := range running.ForwardPorts {
+			if running.ForwardPorts[j].Port == fp.Port && running.ForwardPorts[j].Protocol == fp.Protocol {
+				unchanged = jsonEqual(&running.ForwardPorts[j], fp)
+			}
+		}
+		if unchanged {
+			continue
+		}
+		changed = true
+		steps = append(steps, func() {
+			running.deleteForwardedPort(pp, fp)
+			running.enableStaticPortForward(fp)
+		})
+	}
+	for i := range running.ForwardPorts {
+		fp := running.ForwardPorts[i]
+		if forwards[forwardKey{fp.Protocol, fp.Port}] {
+			continue
+		}
+		changed = true
+		steps = append(steps, func() {
+			running.deleteForwardedPort(pp, &fp)
+		})
+	}
+	if changed {
+		// List is replaced instead of changed in place, readers which
+		// copied it keep consistent list
+		list := append([]forwardedPort{}, candidate.ForwardPorts...)
+		steps = append(steps, func() {
+			running.ForwardPorts = list
+		})
+	}
+	return requests, steps, nil
+}
+
+// deleteForwardedPort removes sessions and entries of forwarded port.
+// Mutex of port pair should be locked.
+func (port *ipPort) deleteForwardedPort(pp *portPair, fp *forwardedPort) {
+	if port.Type == iPUBLIC {
+		pp.deleteOldConnection(fp.Protocol.ipv6, fp.Protocol.id, int(fp.Port))
+	} else {
+		port.deletePortForwardingEntry(fp.Protocol.ipv6, fp.Protocol.id, int(fp.Port))
+	}
+}
+
+// stagePair checks candidate settings of port pair and returns steps
+// which change them.
+func stagePair(running, candidate *portPair) ([]commitStep, error) {
+	steps := []commitStep{}
+
+	policer := running.EgressPolicer
+	if running.egressPolicer != nil {
+		policer = running.egressPolicer.getConfig()
+	}
+	if !jsonEqual(&policer, &candidate.EgressPolicer) {
+		if running.egressPolicer == nil {
+			return nil, fmt.Errorf("Egress policer of interface %d is not enabled in config", running.PublicPort.Index)
+		}
+		cfg := candidate.EgressPolicer
+		if err := cfg.check(); err != nil {
+			return nil, err
+		}
+		steps = append(steps, func() {
+			running.egressPolicer.setConfig(cfg)
+		})
+	}
+
+	if !jsonEqual(running.portTriggers(), candidate.PortTriggers) {
+		rules := candidate.PortTriggers
+		for i := range rules {
+			if err := rules[i].check(); err != nil {
+				return nil, err
+			}
+		}
+		steps = append(steps, func() {
+			running.triggers.Store(rules)
+		})
+	}
+
+	if !jsonEqual(running.blackholeRules(), candidate.Blackhole) {
+		for _, rule := range candidate.Blackhole {
+			if rule == nil {
+				return nil, errors.New("Blackhole rule should not be empty")
+			}
+			if err := rule.parse(running); err != nil {
+				return nil, err
+			}
+		}
+		// Domain names are resolved before mutex is locked
+		rules := running.mergeBlackholeRules(candidate.Blackhole)
+		steps = append(steps, func() {
+			running.setBlackholeRules(rules)
+		})
+	}
+
+	if sampling := candidate.SessionLogSampling; !jsonEqual(running.logSampling(), &sampling) {
+		if err := sampling.check(); err != nil {
+			return nil, err
+		}
+		steps = append(steps, func() {
+			running.sessionSampling.Store(&sampling)
+		})
+	}
+	return steps, nil
+}
+
+// stagePorts checks candidate settings of both ports of port pair.
+func (s *server) stagePorts(running, candidate *portPair) ([]*upd.InterfaceAddressChangeRequest, []commitStep, error) {
+	running.mutex.Lock()
+	defer running.mutex.Unlock()
+
+	addresses, steps, err := s.stagePort(running, &running.PrivatePort, &candidate.PrivatePort)
+	if err != nil {
+		return nil, nil, err
+	}
+	publicAddresses, publicSteps, err := s.stagePort(running, &running.PublicPort, &candidate.PublicPort)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(addresses, publicAddresses...), append(steps, publicSteps...), nil
+}
+
+// stageCommit checks candidate config as a whole and stages changes
+// of running config to it.
+func (s *server) stageCommit(data []byte) (*stagedCommit, error) {
+	running := s.managedPairs()
+	candidates, err := s.parseCandidate(data, running)
+	if err != nil {
+		return nil, err
+	}
+
+	staged := &stagedCommit{
+		pairs: running,
+	}
+	for i, pp := range running {
+		addresses, portSteps, err := s.stagePorts(pp, candidates[i])
+		if err != nil {
+			return nil, err
+		}
+		pairSteps, err := stagePair(pp, candidates[i])
+		if err != nil {
+			return nil, err
+		}
+		staged.addresses = append(staged.addresses, addresses...)
+		staged.steps = append(staged.steps, append(portSteps, pairSteps...))
+	}
+	return staged, nil
+}
+
+// commit changes running config to candidate config. It returns
+// number of applied changes and error of address change which failed.
+func (s *server) commit(ctx context.Context, data []byte) (int, error) {
+	staged, err := s.stageCommit(data)
+	if err != nil {
+		return 0, err
+	}
+	for i, req := range staged.addresses {
+		if _, err := s.ChangeInterfaceAddress(ctx, req); err != nil {
+			return i, err
+		}
+	}
+	count := len(staged.addresses)
+	for i, pp := range staged.pairs {
+		steps := staged.steps[i]
+		if len(steps) == 0 {
+			continue
+		}
+		pp.mutex.Lock()
+		for _, step := range steps {
+			step()
+		}
+		pp.mutex.Unlock()
+		count += len(steps)
+	}
+	return count, nil
+}
+
+// commitOrRestore commits candidate config and restores previous
+// addresses if one of address changes fails.
+func (s *server) commitOrRestore(ctx context.Context, data, previous []byte) (int, error) {
+	count, err := s.commit(ctx, data)
+	if err == nil || count == 0 {
+		return count, err
+	}
+	if _, restoreErr := s.commit(ctx, previous); restoreErr != nil {
+		return 0, fmt.Errorf("Commit failed: %v, failed to restore previous config: %v", err, restoreErr)
+	}
+	return 0, fmt.Errorf("Commit failed, previous config is restored: %v", err)
+}
+
+// conflictingCommit returns commit waiting for confirmation whose
+// rollback may change port pairs of server. Main control API server
+// manages port pairs of all instances.
+func (s *server) conflictingCommit() *pendingCommit {
+	if s.instance != nil {
+		if c := pendingCommits[s.instance]; c != nil {
+			return c
+		}
+		return pendingCommits[nil]
+	}
+	for _, c := range pendingCommits {
+		return c
+	}
+	return nil
+}
+
+// raiseConfigReloadEvent reports that commit, its rollback or its
+// expiration changed running config. Reason is commit, rollback or
+// expired.
+func (s *server) raiseConfigReloadEvent(reason string, changes int) {
+	if changes == 0 {
+		return
+	}
+	details := map[string]interface{}{
+		"reason":  reason,
+		"changes": changes,
+	}
+	if s.instance != nil {
+		details["instance"] = s.instance.Name
+	}
+	raiseEvent(EventConfigReload, nil, details)
+}
+
+// expire rolls back commit which is not confirmed in time.
+func (c *pendingCommit) expire() {
+	commitMutex.Lock()
+	defer commitMutex.Unlock()
+	if pendingCommits[c.updater.instance] != c {
+		return
+	}
+	delete(pendingCommits, c.updater.instance)
+	common.LogWarning(common.No, "Commit is not confirmed in time, rolling back to previous config")
+	count, err := c.updater.commit(context.Background(), c.previous)
+	if err != nil {
+		common.LogWarning(common.No, "Failed to roll back commit:", err)
+	}
+	c.updater.raiseConfigReloadEvent("expired", count)
+}
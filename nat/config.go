@@ -11,7 +11,9 @@ import (
 	"net"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/intel-go/nff-go/flow"
@@ -49,6 +51,8 @@ type hostPort struct {
 	Addr6 types.IPv6Address
 	Port  uint16
 	ipv6  bool
+	// Zone of link local IPv6 address, name or index of port
+	zone string
 }
 
 type protocolId struct {
@@ -60,6 +64,15 @@ type forwardedPort struct {
 	Port        uint16     `json:"port"`
 	Destination hostPort   `json:"destination"`
 	Protocol    protocolId `json:"protocol"`
+	// Other destinations for sources within prefixes, first
+	// matching prefix wins
+	Sources []forwardedSource `json:"sources"`
+	// Destination is an address of KNI interface of port, packets
+	// are sent to KNI with this address and port
+	KNI bool `json:"kni"`
+	// Seconds without packets after which session of remote host
+	// with forwarded port expires, zero means no sessions
+	IdleTimeout int `json:"idle-timeout"`
 }
 
 var protocolIdLookup map[string]protocolId = map[string]protocolId{
@@ -96,6 +109,21 @@ func (out *protocolId) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// MarshalJSON writes protocol name as it is used in config file.
+func (pid protocolId) MarshalJSON() ([]byte, error) {
+	return json.Marshal(pid.String())
+}
+
+// String returns protocol name as it is used in config file.
+func (pid protocolId) String() string {
+	for name, id := range protocolIdLookup {
+		if id == pid {
+			return name
+		}
+	}
+	return "unknown(" + strconv.Itoa(int(pid.id)) + ")"
+}
+
 type ipv4Subnet struct {
 	Addr            types.IPv4Address
 	Mask            types.IPv4Address
@@ -123,11 +151,19 @@ func (subnet *ipv4Subnet) String() string {
 			}
 			mask >>= 1
 		}
-		return subnet.Addr.String() + "/" + strconv.Itoa(i)
+		return StringIPv4Int(uint32(subnet.Addr)) + "/" + strconv.Itoa(i)
 	}
 	return "DHCP address not acquired"
 }
 
+// configString returns subnet in a form of config file setting.
+func (subnet *ipv4Subnet) configString() string {
+	if !subnet.addressAcquired {
+		return "dhcp"
+	}
+	return subnet.String()
+}
+
 func (subnet *ipv4Subnet) checkAddrWithingSubnet(addr types.IPv4Address) bool {
 	return addr&subnet.Mask == subnet.Addr&subnet.Mask
 }
@@ -140,7 +176,9 @@ type ipv6Subnet struct {
 	llMulticastAddr types.IPv6Address
 	addressAcquired bool
 	kniAddressSet   bool
-	ds              dhcpv6State
+	// Static address is used by another host
+	duplicate bool
+	ds        dhcpv6State
 }
 
 func (subnet *ipv6Subnet) String() string {
@@ -158,6 +196,20 @@ func (subnet *ipv6Subnet) String() string {
 	return "DHCP address not acquired"
 }
 
+// needDHCPv6 returns true if DHCPv6 client should request address.
+func (subnet *ipv6Subnet) needDHCPv6() bool {
+	return !subnet.ds.released && !subnet.duplicate
+}
+
+// configString returns subnet in a form of config file setting.
+func (subnet *ipv6Subnet) configString() string {
+	if !subnet.addressAcquired {
+		return "dhcp"
+	}
+	ones, _ := net.IPMask(subnet.Mask[:]).Size()
+	return net.IP(subnet.Addr[:]).String() + "/" + strconv.Itoa(ones)
+}
+
 func (subnet *ipv6Subnet) andMask(addr types.IPv6Address) types.IPv6Address {
 	var result types.IPv6Address
 	for i := range addr {
@@ -171,10 +223,48 @@ func (subnet *ipv6Subnet) checkAddrWithingSubnet(addr types.IPv6Address) bool {
 }
 
 type portMapEntry struct {
+	// Translated packets and bytes of session in both directions,
+	// updated atomically by packet handlers
+	packets              uint64
+	bytes                uint64
+	created              time.Time
 	lastused             time.Time
 	finCount             uint8
 	terminationDirection terminationDirection
 	static               bool
+	// Public address of dynamic IPv4 session which uses VLAN
+	// subinterface, zero for address of port
+	addr types.IPv4Address
+	// Tuple or Tuple6 of remote host which session was created for,
+	// nil if it is not known
+	remote interface{}
+	// Temporary address of dynamic IPv6 session, nil for address of
+	// port
+	temporary *temporaryAddress
+	// Application of session, zero until first packet is translated
+	application uint16
+	// First TCP payload of session was checked for TLS server name
+	sniChecked bool
+	// Country of remote host, zero until first packet is translated
+	country uint16
+	// Inbound port opened by port trigger, nil for other sessions
+	trigger *triggerOpening
+	// Source specific destinations of forwarded port
+	sources []forwardedSource
+	// Last acknowledgement numbers of TCP session by direction and
+	// bits of directions which were seen, kept when idle sessions
+	// are reset
+	tcpAcks  [2]uint32
+	tcpAcked uint8
+	// MAC address of private host which created dynamic session, zero
+	// if hosts are not tracked
+	mac types.MACAddress
+	// Private host which port is counted by port sharing, nil if it
+	// is not counted
+	share *hostPorts
+	// Sessions of remote hosts of forwarded port, nil if forwarded
+	// port has no idle timeout
+	forwarded *forwardedSessions
 }
 
 // Type describing a network port
@@ -186,9 +276,45 @@ type ipPort struct {
 	KNIName       string           `json:"kni-name"`
 	ForwardPorts  []forwardedPort  `json:"forward-ports"`
 	DstMACAddress types.MACAddress `json:"dst-mac"`
+	// Core of KNI kernel thread and KNI send and receive functions,
+	// nil lets NFF-Go choose it
+	KNICore *int `json:"kni-core"`
+	// Forget learned neighbors when link goes down
+	FlushNeighborsOnLinkDown bool `json:"flush-neighbors-on-link-down"`
+	// Segmentation offloads of KNI interface
+	KNIOffload kniOffloadConfig `json:"kni-offload"`
+	// Link speed, flow control and promiscuous mode of network card
+	Ethernet ethernetConfig `json:"ethernet"`
+	// Link encryption of public port
+	MACsec macsecConfig `json:"macsec"`
+	// IPv6 duplicate address detection
+	DAD dadConfig `json:"dad"`
+	// VLAN subinterfaces of public port with their own subnets
+	VLANs []publicVLAN `json:"vlans"`
+	// Rotated IPv6 addresses of public port for new sessions
+	TemporaryAddresses temporaryAddressConfig `json:"temporary-addresses"`
+	// IPv6 default routers learning of public port
+	RouterDiscovery routerDiscoveryConfig `json:"router-discovery"`
+	// Neighbors probed by -selftest mode
+	SelfTest selfTestConfig `json:"self-test"`
+	// Which traffic without translation goes to KNI interface
+	KNISteering []kniSteeringRule `json:"kni-steering"`
+	// Neighbors which are never overwritten by learned addresses
+	StaticNeighbors []staticNeighbor `json:"static-neighbors"`
+	// How checksums of packets sent from port are set
+	ChecksumStrategy checksumStrategy `json:"checksum-strategy"`
+	// Netmap rules of port pair, set for public port
+	netmap []netmapRule
+	// Tenant of port pair
+	tenant string
+	// NAT instance of port pair, nil for port pairs outside instances
+	instance      *natInstance
 	staticArpMode bool
-	SrcMACAddress types.MACAddress
-	Type          interfaceType
+	// Checksums of packets sent from port are offloaded to network
+	// card
+	hwTXChecksum  bool
+	SrcMACAddress types.MACAddress `json:"-"`
+	Type          interfaceType    `json:"-"`
 	// Pointer to an opposite port in a pair
 	opposite *ipPort
 	// Map of allocated IP ports on public interface
@@ -201,22 +327,251 @@ type ipPort struct {
 	// Debug dump stuff
 	fdump    [DirKNI + 1]*os.File
 	dumpsync [DirKNI + 1]sync.Mutex
+	// Packet counters
+	stats portStats
+	// Counters of translation handler instances which count packets
+	// received by this port
+	instanceStats      []*portStats
+	instanceStatsMutex sync.Mutex
+	// Counters of previous runs loaded from statistics file
+	savedStats portStats
+	// Received packets of unsupported IP protocols by protocol
+	// number
+	protocolPackets [256]uint64
+	// Received packets with IPv4 options and IPv6 jumbograms
+	ipOptions ipOptionsCounters
+	// Received packets dropped because their TTL or hop limit expired
+	ttlExpired uint64
+	// Packets routed to private ports of other port pairs
+	privateRouted privateRouteCounters
+	// Sent packets by the way their checksums were set
+	checksums [checksumPaths]uint64
+	// Packets segmented and coalesced by KNI offloads
+	kniOffload kniOffloadCounters
+	// Scheduler of flows merged before port sends them
+	scheduler *egressScheduler
+	// Link state of network card port, linkStatus value
+	link atomic.Value
+	// Tentative IPv6 addresses
+	dad dadState
+	// Translations of fragmented IPv4 datagrams received by port
+	fragments fragmentTable
+	// Temporary IPv6 addresses, []*temporaryAddress value
+	temporary atomic.Value
+	// Public addresses replaced by renumbering which still have
+	// sessions, []*retiredAddress value
+	retired atomic.Value
+	// Learned IPv6 default routers and redirects
+	routers routerState
+	// Forwarded ports sent to explicit addresses of KNI interface
+	kniForwards kniForwarding
+	// Reachability of private hosts of forwarded ports, set for
+	// private port when it is probed
+	health *forwardHealth
+	// MAC addresses of private hosts, set for private port when they
+	// are tracked
+	identities *hostIdentityTable
+	// Incomplete IPv6 neighbor entries, set when ND cache is
+	// protected
+	incomplete *incompleteNeighbors
+	// Outstanding ARP and ND resolutions, set when they are paced
+	resolver *neighborResolver
+	// External address learned from STUN server, set for public
+	// port when it is discovered
+	external *externalAddress
+	// Path MTU toward next hops, set for public port when MSS is
+	// clamped
+	pathMTU *pathMTU
+	// IP families which are disabled for port pair
+	ipv4Disabled bool
+	ipv6Disabled bool
 }
 
 // Config for one port pair.
 type portPair struct {
 	PrivatePort ipPort `json:"private-port"`
 	PublicPort  ipPort `json:"public-port"`
+	// VRF or tenant which private network belongs to. It is
+	// reported in events, logs and control API replies, so port
+	// pairs with the same private subnet can be told apart.
+	Tenant string `json:"tenant"`
+	// NAT instance which port pair belongs to
+	instance *natInstance
+	// Single family port pairs don't handle packets of other IP
+	// family and don't allocate its tables
+	DisableIPv4 bool `json:"disable-ipv4"`
+	DisableIPv6 bool `json:"disable-ipv6"`
+	// Reaction to inbound packets which don't belong to any
+	// translation
+	UnsolicitedInbound unsolicitedPolicy `json:"unsolicited-inbound"`
+	// Send reset to both hosts of TCP session which times out
+	TCPTimeoutReset bool `json:"tcp-timeout-reset"`
+	timeoutResets   uint64
+	// Decrement TTL and hop limit of forwarded packets like a router
+	// and answer expired ones with time exceeded errors
+	DecrementTTL bool `json:"decrement-ttl"`
+	// Overrides vector-translation of flow graph for this port pair
+	VectorTranslation *bool `json:"vector-translation"`
+	// Indexes of private ports of other port pairs which subnets are
+	// routed from private port without translation
+	PrivateRoutes []uint16 `json:"private-routes"`
+	privateRoutes []*ipPort
+	// Transports translated in addition to TCP, UDP and ICMP
+	TranslateProtocols []translatedProtocol `json:"translate-protocols"`
+	// Fate of packets of IP protocols which are not translated
+	UnsupportedProtocols unsupportedProtocolsPolicy `json:"unsupported-protocols"`
+	// Fate of IPv4 packets with options and IPv6 jumbograms
+	IPOptions ipOptionsPolicy `json:"ip-options"`
+	// Multicast groups forwarded from public to private port
+	Multicast multicastConfig `json:"multicast"`
+	// UDP ports relayed from public port to private network
+	BroadcastRelay []broadcastRelay `json:"broadcast-relay"`
+	// DHCP relay agent for private clients
+	DHCPRelay dhcpRelayConfig `json:"dhcp-relay"`
+	// DSCP rewriting of translated packets
+	DSCP dscpPolicy `json:"dscp"`
+	// IPv6 flow label of egress translated packets
+	FlowLabel    flowLabelAction `json:"ipv6-flow-label"`
+	flowLabelKey [16]byte
+	// Rate limits of packets sent by public port
+	EgressPolicer egressPolicerConfig `json:"egress-policer"`
+	egressPolicer *egressPolicer
+	// Weighted scheduling of flows merged before ports send them
+	EgressScheduling egressSchedulingConfig `json:"egress-scheduling"`
+	// Rate plans of private hosts
+	Policing policingConfig `json:"policing"`
+	// Subscriber table, private address or MAC address to *subscriber
+	subscribers      sync.Map
+	subscribersCount int32
+	// Nonzero while idle subscribers are being forgotten
+	subscribersForgetting int32
+	// Sharing of public ports between private hosts
+	PortSharing  portSharingConfig `json:"port-sharing"`
+	portPools    map[portPoolKey]*portPoolUsage
+	sharingStats portSharingStats
+	// Range and allocation of public ICMP query identifiers
+	ICMPIdentifiers icmpIdentifiersConfig `json:"icmp-identifiers"`
+	// Top talkers and traffic by IP protocol number
+	TopTalkers talkersConfig `json:"top-talkers"`
+	talkers    *topTalkers
+	protocols  [256]protocolCounters
+	// Tagging of sessions by application
+	Applications applicationsConfig `json:"applications"`
+	apps         *applicationTable
+	// Policy and accounting by country of remote hosts
+	Countries countryPolicy `json:"countries"`
+	countries *[countriesCount]countryCounters
+	// Static 1:1 translation of private prefixes to public prefixes
+	Netmap []netmapRule `json:"netmap"`
+	// Blocking of public sources which flood forwarded ports
+	FloodMitigation floodMitigationConfig `json:"flood-mitigation"`
+	blocklist       blocklist
+	// Probing of private hosts of forwarded ports
+	ForwardHealth forwardHealthConfig `json:"forward-health"`
+	// MSS clamping to path MTU toward next hops of public port
+	PathMTU pathMTUConfig `json:"path-mtu"`
+	// Failed TCP handshakes of forwarded ports
+	HandshakeTracking handshakeTrackingConfig `json:"handshake-tracking"`
+	handshakes        sync.Map
+	// Decisions of external session authorization server
+	authorizations sessionAuthorizations
+	// Redirection of unauthenticated private hosts to captive portal
+	CaptivePortal captivePortalConfig `json:"captive-portal"`
+	portal        captivePortalState
+	// MAC addresses of private hosts which survive their address
+	// changes
+	HostIdentity hostIdentityConfig `json:"host-identity"`
+	// Release of DHCP address of public port without sessions
+	IdleAddressRelease idleReleaseConfig `json:"idle-address-release"`
+	idleRelease        idleReleaseState
+	// Discovery of external address of public port with STUN
+	STUN stunConfig `json:"stun"`
+	// Routes of public addresses for routing daemon on public KNI
+	RouteAnnouncement routeAnnouncementConfig `json:"route-announcement"`
+	announcement      announcementState
+	// Maintenance mode set with control API
+	maintenance maintenanceState
+	// Private hosts which receive inbound traffic of public address
+	// which matches no session or forwarded port
+	DMZHost  string `json:"dmz-host"`
+	DMZHost6 string `json:"dmz-host6"`
+	dmz      dmzHost
+	// Timeout of RTP and RTCP sessions
+	MediaTimeout mediaTimeoutConfig `json:"media-timeout"`
+	// Inbound ports opened by outbound sessions of private hosts
+	PortTriggers []portTrigger `json:"port-triggers"`
+	triggers     atomic.Value
+	openings     []*triggerOpening
+	// Public ports reserved for traffic which NAT and KNI host
+	// originate, nil if no ports are reserved
+	LocalPorts []portSpan `json:"local-ports"`
+	localPorts *localPortSet
+	// Source NAT of traffic which private KNI host sends to public
+	// network, nil if it is disabled
+	KNISourceNAT kniSourceNATConfig `json:"kni-source-nat"`
+	kniSNAT      *kniSourceNAT
+	// Destinations which traffic of private hosts is contained
+	Blackhole  []*blackholeRule `json:"blackhole"`
+	blackholes atomic.Value
+	// Sessions which are logged to syslog collector
+	SessionLogSampling sessionLogSampling `json:"session-log-sampling"`
+	sessionSampling    atomic.Value
+	// Lifetime histograms of ended sessions, nil if they are disabled
+	aging *sessionAging
 	// Synchronization point for lookup table modifications
 	mutex sync.Mutex
 	// Port that was allocated last
 	lastport int
+	// ICMP identifier that was allocated last
+	lastICMPPort int
 }
 
 // Config for NAT.
 type Config struct {
-	HostName             string     `json:"host-name"`
-	PortPairs            []portPair `json:"port-pairs"`
+	HostName  string          `json:"host-name"`
+	PortPairs []portPair      `json:"port-pairs"`
+	Webhooks  []webhookConfig `json:"webhooks"`
+	// Port pool utilization in percents which triggers webhook
+	// notification
+	PortPoolHighWatermark int              `json:"port-pool-high-watermark"`
+	ControlAPI            controlAPIConfig `json:"control-api"`
+	// Limits for ICMP, ARP and ND packets generated by NAT
+	ICMPRateLimit     rateLimitConfig `json:"icmp-rate-limit"`
+	NeighborRateLimit rateLimitConfig `json:"neighbor-rate-limit"`
+	// Limits for incomplete IPv6 neighbor entries
+	NDProtection ndProtectionConfig `json:"nd-protection"`
+	// Pacing and backoff of ARP and ND requests
+	NeighborResolution neighborResolutionConfig `json:"neighbor-resolution"`
+	// Limits for packets received by NAT addresses and KNI
+	// interfaces
+	ControlPlaneProtection controlPlaneConfig `json:"control-plane-protection"`
+	// NFF-Go scheduler and memory options
+	FlowGraph flowGraphConfig `json:"flow-graph"`
+	// Graceful shutdown options
+	Shutdown shutdownConfig `json:"shutdown"`
+	// File where sessions are saved on exit and restored from on
+	// start
+	SessionStateFile string `json:"session-state-file"`
+	// File where DHCP leases are saved, so that restarted NAT
+	// requests the same addresses
+	DHCPLeaseFile string `json:"dhcp-lease-file"`
+	// Saving of port counters which survive restarts
+	PersistentStatistics statisticsPersistenceConfig `json:"persistent-statistics"`
+	// Export of sessions to Linux conntrackd
+	ConntrackSync conntrackSyncConfig `json:"conntrack-sync"`
+	// Logging of sessions to syslog collector
+	SessionLog sessionLogConfig `json:"session-log"`
+	// Histograms of lifetimes of ended sessions
+	SessionAging sessionAgingConfig `json:"session-aging"`
+	// Export of spans and counters to OpenTelemetry collector
+	OpenTelemetry otelConfig `json:"opentelemetry"`
+	// Database of countries of addresses
+	GeoIP geoipConfig `json:"geoip"`
+	// External authorization of new sessions
+	SessionAuthorization sessionAuthorizationConfig `json:"session-authorization"`
+	// Separate NAT instances with their own port pairs and control
+	// API servers
+	Instances            []natInstance `json:"instances"`
 	setKniIP             bool
 	bringUpKniInterfaces bool
 }
@@ -224,6 +579,9 @@ type Config struct {
 // Type used to pass handler index to translation functions.
 type pairIndex struct {
 	index int
+	// Packet counters of handler instance, nil if context was not
+	// copied by NFF-Go
+	stats *instanceStats
 }
 
 var (
@@ -242,13 +600,19 @@ var (
 	DumpEnabled [DirKNI + 1]bool
 )
 
+// Copy is called by NFF-Go for every instance and clone of flow
+// function, so each of them gets its own packet counters.
 func (pi pairIndex) Copy() interface{} {
 	return pairIndex{
 		index: pi.index,
+		stats: Natconfig.PortPairs[pi.index].newInstanceStats(),
 	}
 }
 
 func (pi pairIndex) Delete() {
+	if pi.stats != nil {
+		Natconfig.PortPairs[pi.index].deleteInstanceStats(pi.stats)
+	}
 }
 
 // Returns IPv4 address in little endian format. Needs swap before
@@ -298,6 +662,12 @@ func (out *ipv4Subnet) UnmarshalJSON(b []byte) error {
 	return errors.New("Failed to parse address " + s)
 }
 
+// MarshalJSON writes subnet as it is used in config file. Acquired
+// DHCP address is written instead of dhcp.
+func (subnet *ipv4Subnet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(subnet.configString())
+}
+
 // UnmarshalJSON parses ipv 4 subnet details.
 func (out *ipv6Subnet) UnmarshalJSON(b []byte) error {
 	var s string
@@ -334,6 +704,12 @@ func (out *ipv6Subnet) UnmarshalJSON(b []byte) error {
 	return errors.New("Failed to parse address " + s)
 }
 
+// MarshalJSON writes subnet as it is used in config file. Acquired
+// DHCP address is written instead of dhcp.
+func (subnet *ipv6Subnet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(subnet.configString())
+}
+
 // UnmarshalJSON parses ipv4 host:port string. Port may be omitted and
 // is set to zero in this case.
 func (out *hostPort) UnmarshalJSON(b []byte) error {
@@ -346,6 +722,10 @@ func (out *hostPort) UnmarshalJSON(b []byte) error {
 	if err != nil {
 		return err
 	}
+	if i := strings.IndexByte(hostStr, '%'); i >= 0 {
+		out.zone = hostStr[i+1:]
+		hostStr = hostStr[:i]
+	}
 
 	ipArray := net.ParseIP(hostStr)
 	if ipArray == nil {
@@ -360,6 +740,9 @@ func (out *hostPort) UnmarshalJSON(b []byte) error {
 		copy(out.Addr6[:], ipv6addr)
 		out.ipv6 = true
 	}
+	if out.zone != "" && (!out.ipv6 || !isIPv6LinkLocal(out.Addr6)) {
+		return errors.New("Zone is allowed only for link local IPv6 addresses: " + s)
+	}
 
 	if portStr != "" {
 		port, err := strconv.ParseInt(portStr, 10, 32)
@@ -374,19 +757,42 @@ func (out *hostPort) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// String returns host:port string in the same form as it is parsed
+// by UnmarshalJSON.
+func (hp *hostPort) String() string {
+	var host string
+	if hp.ipv6 {
+		host = net.IP(hp.Addr6[:]).String()
+		if hp.zone != "" {
+			host += "%" + hp.zone
+		}
+	} else {
+		host = StringIPv4Int(uint32(hp.Addr4))
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(hp.Port)))
+}
+
+// MarshalJSON writes host:port string as it is used in config file.
+func (hp *hostPort) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hp.String())
+}
+
 // ReadConfig function reads and parses config file
 func ReadConfig(fileName string, setKniIP, bringUpKniInterfaces bool) error {
-	file, err := os.Open(fileName)
+	data, err := loadConfigFile(fileName)
 	if err != nil {
 		return err
 	}
-	decoder := json.NewDecoder(file)
 
-	err = decoder.Decode(&Natconfig)
+	err = json.Unmarshal(data, &Natconfig)
 	if err != nil {
 		return err
 	}
 
+	if err := Natconfig.mergeInstances(); err != nil {
+		return err
+	}
+
 	if setKniIP {
 		Natconfig.setKniIP = true
 	}
@@ -394,6 +800,61 @@ func ReadConfig(fileName string, setKniIP, bringUpKniInterfaces bool) error {
 		Natconfig.bringUpKniInterfaces = true
 	}
 
+	for i := range Natconfig.Webhooks {
+		if err := Natconfig.Webhooks[i].check(); err != nil {
+			return err
+		}
+	}
+	if Natconfig.PortPoolHighWatermark < 0 || Natconfig.PortPoolHighWatermark > 100 {
+		return fmt.Errorf("Port pool high watermark should be between 0 and 100 percents")
+	}
+	if err := Natconfig.ControlAPI.check(); err != nil {
+		return err
+	}
+	if err := Natconfig.ICMPRateLimit.check("icmp-rate-limit"); err != nil {
+		return err
+	}
+	if err := Natconfig.NeighborRateLimit.check("neighbor-rate-limit"); err != nil {
+		return err
+	}
+	if err := Natconfig.NDProtection.check(); err != nil {
+		return err
+	}
+	if err := Natconfig.NeighborResolution.check(); err != nil {
+		return err
+	}
+	if err := Natconfig.ControlPlaneProtection.check(); err != nil {
+		return err
+	}
+	icmpLimiter = newRateLimiter(Natconfig.ICMPRateLimit)
+	neighborLimiter = newRateLimiter(Natconfig.NeighborRateLimit)
+	newControlPlaneLimiters(&Natconfig.ControlPlaneProtection)
+
+	if err := Natconfig.FlowGraph.check(); err != nil {
+		return err
+	}
+	if err := Natconfig.ConntrackSync.check(); err != nil {
+		return err
+	}
+	if err := Natconfig.SessionLog.check(); err != nil {
+		return err
+	}
+	if err := Natconfig.SessionAging.check(); err != nil {
+		return err
+	}
+	if err := Natconfig.PersistentStatistics.check(); err != nil {
+		return err
+	}
+	if err := Natconfig.OpenTelemetry.check(); err != nil {
+		return err
+	}
+	if err := Natconfig.GeoIP.check(); err != nil {
+		return err
+	}
+	if err := Natconfig.SessionAuthorization.check(); err != nil {
+		return err
+	}
+
 	for i := range Natconfig.PortPairs {
 		pp := &Natconfig.PortPairs[i]
 
@@ -401,6 +862,15 @@ func ReadConfig(fileName string, setKniIP, bringUpKniInterfaces bool) error {
 		pp.PublicPort.Type = iPUBLIC
 		pp.PublicPort.opposite = &pp.PrivatePort
 		pp.PrivatePort.opposite = &pp.PublicPort
+		pp.PrivatePort.tenant = pp.Tenant
+		pp.PublicPort.tenant = pp.Tenant
+		pp.PrivatePort.instance = pp.instance
+		pp.PublicPort.instance = pp.instance
+
+		if err := pp.UnsolicitedInbound.RateLimit.check("unsolicited-inbound rate-limit"); err != nil {
+			return err
+		}
+		pp.UnsolicitedInbound.limiter = newRateLimiter(pp.UnsolicitedInbound.RateLimit)
 
 		if pp.PrivatePort.Vlan == 0 && pp.PublicPort.Vlan != 0 {
 			return errors.New("Private port with index " +
@@ -419,10 +889,16 @@ func ReadConfig(fileName string, setKniIP, bringUpKniInterfaces bool) error {
 		if (pp.PrivatePort.Vlan != 0 && pp.PrivatePort.KNIName != "") || (pp.PrivatePort.Vlan != 0 && pp.PrivatePort.KNIName != "") {
 			return fmt.Errorf("Using VLANs together with KNI is not supported yet.")
 		}
+		if err := pp.checkPublicVLANs(); err != nil {
+			return err
+		}
+		if err := pp.checkIPFamilies(); err != nil {
+			return err
+		}
 
 		port := &pp.PrivatePort
 		for pi := 0; pi < 2; pi++ {
-			if !port.Subnet.addressAcquired {
+			if !port.Subnet.addressAcquired && !port.ipv4Disabled {
 				if Natconfig.HostName == "" {
 					return fmt.Errorf("DHCP option for port %d requires that you set host-name configuration option", port.Index)
 				}
@@ -433,6 +909,31 @@ func ReadConfig(fileName string, setKniIP, bringUpKniInterfaces bool) error {
 				NeedKNI = true
 			}
 
+			if err := port.Ethernet.check(port.Index); err != nil {
+				return err
+			}
+			if err := port.MACsec.check(port); err != nil {
+				return err
+			}
+			if err := port.DAD.check(port.Index); err != nil {
+				return err
+			}
+			if err := port.TemporaryAddresses.check(port); err != nil {
+				return err
+			}
+			if err := port.RouterDiscovery.check(port); err != nil {
+				return err
+			}
+			if err := port.SelfTest.check(port); err != nil {
+				return err
+			}
+			if err := port.checkKNISteering(); err != nil {
+				return err
+			}
+			if err := port.KNIOffload.check(port); err != nil {
+				return err
+			}
+
 			for fpi := range port.ForwardPorts {
 				fp := &port.ForwardPorts[fpi]
 				err := port.checkPortForwarding(fp)
@@ -442,20 +943,141 @@ func ReadConfig(fileName string, setKniIP, bringUpKniInterfaces bool) error {
 			}
 			if port.DstMACAddress != (types.MACAddress{}) {
 				port.staticArpMode = true
-				fmt.Printf("Activating static ARP mode for port %d, using %s MAC address\n",
-					port.Index, port.DstMACAddress.String())
+				fmt.Printf("Activating static ARP mode for port %s, using %s MAC address\n",
+					port.logName(), port.DstMACAddress.String())
+			}
+			if err := port.checkStaticNeighbors(); err != nil {
+				return err
 			}
 			port = &pp.PublicPort
 		}
+
+		if err := pp.checkTranslatedProtocols(); err != nil {
+			return err
+		}
+		if err := pp.UnsupportedProtocols.check(pp); err != nil {
+			return err
+		}
+		if err := pp.IPOptions.check(); err != nil {
+			return err
+		}
+		if err := pp.Multicast.check(); err != nil {
+			return err
+		}
+		if err := pp.checkBroadcastRelays(); err != nil {
+			return err
+		}
+		if err := pp.DHCPRelay.check(pp); err != nil {
+			return err
+		}
+		if err := pp.DSCP.check(); err != nil {
+			return err
+		}
+		if err := pp.checkFlowLabel(); err != nil {
+			return err
+		}
+		if err := pp.EgressPolicer.check(); err != nil {
+			return err
+		}
+		if err := pp.Policing.check(); err != nil {
+			return err
+		}
+		if err := pp.PortSharing.check(); err != nil {
+			return err
+		}
+		if err := pp.ICMPIdentifiers.check(); err != nil {
+			return err
+		}
+		if err := pp.checkNetmap(); err != nil {
+			return err
+		}
+		if err := pp.TopTalkers.check(); err != nil {
+			return err
+		}
+		if err := pp.checkApplications(); err != nil {
+			return err
+		}
+		if err := pp.checkCountries(); err != nil {
+			return err
+		}
+		if err := pp.FloodMitigation.check(); err != nil {
+			return err
+		}
+		if err := pp.HandshakeTracking.check(); err != nil {
+			return err
+		}
+		if err := pp.checkForwardHealth(); err != nil {
+			return err
+		}
+		if err := pp.checkPathMTU(); err != nil {
+			return err
+		}
+		if err := pp.checkLocalPorts(); err != nil {
+			return err
+		}
+		if err := pp.checkSTUN(); err != nil {
+			return err
+		}
+		if err := pp.checkKNISourceNAT(); err != nil {
+			return err
+		}
+		if err := pp.EgressScheduling.check(pp); err != nil {
+			return err
+		}
+		if err := pp.CaptivePortal.check(pp); err != nil {
+			return err
+		}
+		if err := pp.checkHostIdentity(); err != nil {
+			return err
+		}
+		if err := pp.IdleAddressRelease.check(pp); err != nil {
+			return err
+		}
+		if Natconfig.SessionAging.enabled() {
+			pp.aging = newSessionAging(&Natconfig.SessionAging)
+		}
+		pp.PrivatePort.initNDProtection()
+		pp.PublicPort.initNDProtection()
+		pp.PrivatePort.initNeighborResolver()
+		pp.PublicPort.initNeighborResolver()
+		if err := pp.RouteAnnouncement.check(pp); err != nil {
+			return err
+		}
+		if err := pp.checkBlackhole(); err != nil {
+			return err
+		}
+		if err := pp.setPortTriggers(pp.PortTriggers); err != nil {
+			return err
+		}
+		if err := pp.setLogSampling(pp.SessionLogSampling); err != nil {
+			return err
+		}
+		if err := pp.checkDMZ(); err != nil {
+			return err
+		}
+		if err := pp.MediaTimeout.check(); err != nil {
+			return err
+		}
+	}
+	for i := range Natconfig.PortPairs {
+		if err := Natconfig.PortPairs[i].checkPrivateRoutes(); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return checkKNICores()
 }
 
 func (port *ipPort) checkPortForwarding(fp *forwardedPort) error {
+	if err := fp.checkIdleTimeout(port); err != nil {
+		return err
+	}
 	if fp.Destination.ipv6 != fp.Protocol.ipv6 {
 		return fmt.Errorf("Port forwarding protocol should be TCP or UDP for IPv4 addresses and TCP6 or UDP6 for IPv6 addresses")
 	}
+	if port.familyDisabled(fp.Protocol.ipv6) {
+		return fmt.Errorf("Port forwarding of port %d uses IP family which is disabled on port %d", fp.Port, port.Index)
+	}
 
 	var isAddrZero bool
 	if fp.Destination.ipv6 {
@@ -464,7 +1086,20 @@ func (port *ipPort) checkPortForwarding(fp *forwardedPort) error {
 		isAddrZero = fp.Destination.Addr4 == 0
 	}
 
-	if isAddrZero {
+	if fp.KNI && !isAddrZero {
+		if port.KNIName == "" {
+			return errors.New("Port with index " +
+				strconv.Itoa(int(port.Index)) +
+				" should have \"kni-name\" setting if you want to forward packets to KNI address " + fp.Destination.String())
+		}
+		if port.Type == iPRIVATE {
+			return errors.New("Port forwarding to explicit KNI address is allowed only on public port, traffic of private port address is always sent to KNI interface")
+		}
+		if fp.Destination.Port == 0 {
+			fp.Destination.Port = fp.Port
+		}
+		NeedKNI = true
+	} else if isAddrZero {
 		if port.KNIName == "" {
 			return errors.New("Port with index " +
 				strconv.Itoa(int(port.Index)) +
@@ -481,7 +1116,16 @@ func (port *ipPort) checkPortForwarding(fp *forwardedPort) error {
 			return errors.New("Only KNI port forwarding is allowed on private port. All translated connections from private to public network can be initiated without any forwarding rules.")
 		}
 
-		if fp.Destination.ipv6 {
+		if fp.Destination.ipv6 && isIPv6LinkLocal(fp.Destination.Addr6) {
+			// Link local address is valid only on the link which
+			// zone names
+			if !port.opposite.hasZone(fp.Destination.zone) {
+				return errors.New("Link local destination address " +
+					fp.Destination.Addr6.String() +
+					" should have zone of port " +
+					port.opposite.ifName())
+			}
+		} else if fp.Destination.ipv6 {
 			if !port.opposite.Subnet6.checkAddrWithingSubnet(fp.Destination.Addr6) {
 				return errors.New("Destination address " +
 					fp.Destination.Addr6.String() +
@@ -501,7 +1145,7 @@ func (port *ipPort) checkPortForwarding(fp *forwardedPort) error {
 			fp.Destination.Port = fp.Port
 		}
 	}
-	return nil
+	return port.checkForwardedSources(fp)
 }
 
 // Reads MAC addresses for local interfaces into pair ports.
@@ -511,25 +1155,41 @@ func (pp *portPair) initLocalMACs() {
 }
 
 func (port *ipPort) initIPv6LLAddresses() {
+	if port.ipv6Disabled {
+		return
+	}
 	packet.CalculateIPv6LinkLocalAddrForMAC(&port.Subnet6.llAddr, port.SrcMACAddress)
-	println("Configured link local address", port.Subnet6.llAddr.String(), "for port", port.Index)
+	println("Configured link local address", port.Subnet6.llAddr.String(), "for port", port.logName())
 	packet.CalculateIPv6MulticastAddrForDstIP(&port.Subnet6.llMulticastAddr, port.Subnet6.llAddr)
-	println("Configured link local multicast address", port.Subnet6.llMulticastAddr.String(), "for port", port.Index)
+	println("Configured link local multicast address", port.Subnet6.llMulticastAddr.String(), "for port", port.logName())
 	if port.Subnet6.Addr != zeroIPv6Addr {
 		packet.CalculateIPv6MulticastAddrForDstIP(&port.Subnet6.multicastAddr, port.Subnet6.Addr)
-		println("Configured multicast address", port.Subnet6.multicastAddr.String(), "for port", port.Index)
+		println("Configured multicast address", port.Subnet6.multicastAddr.String(), "for port", port.logName())
 	}
 }
 
-func (port *ipPort) allocatePublicPortPortMap() {
+// allocatePublicPortPortMap allocates port maps of IP families which
+// are not disabled and of additional translated protocols. Port maps
+// of disabled family stay nil.
+func (port *ipPort) allocatePublicPortPortMap(translated []translatedProtocol) {
 	port.portmap = make([][]portMapEntry, 256)
-	port.portmap[types.ICMPNumber] = make([]portMapEntry, portEnd)
-	port.portmap[types.TCPNumber] = make([]portMapEntry, portEnd)
-	port.portmap[types.UDPNumber] = make([]portMapEntry, portEnd)
+	if !port.ipv4Disabled {
+		port.portmap[types.ICMPNumber] = make([]portMapEntry, portEnd)
+		port.portmap[types.TCPNumber] = make([]portMapEntry, portEnd)
+		port.portmap[types.UDPNumber] = make([]portMapEntry, portEnd)
+		for _, p := range translated {
+			port.portmap[p] = make([]portMapEntry, portEnd)
+		}
+	}
 	port.portmap6 = make([][]portMapEntry, 256)
-	port.portmap6[types.TCPNumber] = make([]portMapEntry, portEnd)
-	port.portmap6[types.UDPNumber] = make([]portMapEntry, portEnd)
-	port.portmap6[types.ICMPv6Number] = make([]portMapEntry, portEnd)
+	if !port.ipv6Disabled {
+		port.portmap6[types.TCPNumber] = make([]portMapEntry, portEnd)
+		port.portmap6[types.UDPNumber] = make([]portMapEntry, portEnd)
+		port.portmap6[types.ICMPv6Number] = make([]portMapEntry, portEnd)
+		for _, p := range translated {
+			port.portmap6[p] = make([]portMapEntry, portEnd)
+		}
+	}
 }
 
 func (port *ipPort) allocateLookupMap() {
@@ -556,16 +1216,28 @@ func (port *ipPort) enableStaticPortForward(fp *forwardedPort) {
 			addr: fp.Destination.Addr6,
 			port: fp.Destination.Port,
 		}
+		// Packets for address of KNI interface are found by zero
+		// address like other packets sent to KNI
+		if fp.rewritesKNI() {
+			port.enableKNIForward(fp.Protocol.id, keyEntry, valEntry)
+			valEntry = Tuple6{}
+		}
 		port.translationTable[fp.Protocol.id].Store(keyEntry, valEntry)
-		if fp.Destination.Addr6 != zeroIPv6Addr {
+		if valEntry.addr != zeroIPv6Addr {
 			port.opposite.translationTable[fp.Protocol.id].Store(valEntry, keyEntry)
 		}
+		for _, k := range sourceDestinationKeys(fp.Sources) {
+			port.opposite.translationTable[fp.Protocol.id].Store(k, keyEntry)
+		}
 		if port.Type == iPUBLIC {
 			port.getPortmap(fp.Protocol.ipv6, fp.Protocol.id)[fp.Port] = portMapEntry{
+				created:              time.Now(),
 				lastused:             time.Now(),
 				finCount:             0,
 				terminationDirection: 0,
 				static:               true,
+				sources:              fp.Sources,
+				forwarded:            fp.newForwardedSessions(),
 			}
 		}
 	} else {
@@ -577,21 +1249,74 @@ func (port *ipPort) enableStaticPortForward(fp *forwardedPort) {
 			addr: fp.Destination.Addr4,
 			port: fp.Destination.Port,
 		}
+		if fp.rewritesKNI() {
+			port.enableKNIForward(fp.Protocol.id, keyEntry, valEntry)
+			valEntry = Tuple{}
+		}
 		port.translationTable[fp.Protocol.id].Store(keyEntry, valEntry)
-		if fp.Destination.Addr4 != 0 {
+		if valEntry.addr != 0 {
 			port.opposite.translationTable[fp.Protocol.id].Store(valEntry, keyEntry)
 		}
+		for _, k := range sourceDestinationKeys(fp.Sources) {
+			port.opposite.translationTable[fp.Protocol.id].Store(k, keyEntry)
+		}
 		if port.Type == iPUBLIC {
 			port.getPortmap(fp.Protocol.ipv6, fp.Protocol.id)[fp.Port] = portMapEntry{
+				created:              time.Now(),
 				lastused:             time.Now(),
 				finCount:             0,
 				terminationDirection: 0,
 				static:               true,
+				sources:              fp.Sources,
+				forwarded:            fp.newForwardedSessions(),
 			}
 		}
 	}
 }
 
+// Keeps list of forwarded ports in sync with runtime changes so that
+// it always reflects currently active forwarding rules.
+// List is replaced instead of changed in place, so that readers which
+// copied it with forwardedPorts keep consistent list.
+func (port *ipPort) updateForwardPortsList(fp *forwardedPort, enable bool) {
+	list := make([]forwardedPort, 0, len(port.ForwardPorts)+1)
+	for i := range port.ForwardPorts {
+		if port.ForwardPorts[i].Port == fp.Port && port.ForwardPorts[i].Protocol == fp.Protocol {
+			continue
+		}
+		list = append(list, port.ForwardPorts[i])
+	}
+	if enable {
+		list = append(list, *fp)
+	}
+	port.ForwardPorts = list
+}
+
+// setForwardedPort adds, replaces or removes forwarded port of port of
+// port pair. Translation entries of forwarded port number are removed
+// before new rule is enabled.
+func (pp *portPair) setForwardedPort(port *ipPort, fp *forwardedPort, enable bool) {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+	if port.Type == iPUBLIC {
+		pp.deleteOldConnection(fp.Protocol.ipv6, fp.Protocol.id, int(fp.Port))
+	} else {
+		port.deletePortForwardingEntry(fp.Protocol.ipv6, fp.Protocol.id, int(fp.Port))
+	}
+	if enable {
+		port.enableStaticPortForward(fp)
+	}
+	port.updateForwardPortsList(fp, enable)
+}
+
+// forwardedPorts returns list of forwarded ports of port of port pair.
+// List is changed at runtime with mutex locked.
+func (pp *portPair) forwardedPorts(port *ipPort) []forwardedPort {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+	return port.ForwardPorts
+}
+
 func (port *ipPort) getPortmap(ipv6 bool, protocol uint8) []portMapEntry {
 	if ipv6 {
 		return port.portmap6[protocol]
@@ -600,27 +1325,35 @@ func (port *ipPort) getPortmap(ipv6 bool, protocol uint8) []portMapEntry {
 	}
 }
 
+// initState initializes addresses, translation tables and forwarded
+// ports of port pair before its packets are handled.
+func (pp *portPair) initState() {
+	pp.initLocalMACs()
+	pp.PublicPort.MACsec.initSCI(&pp.PublicPort)
+	pp.PrivatePort.initIPv6LLAddresses()
+	pp.PublicPort.initIPv6LLAddresses()
+	pp.PrivatePort.allocateLookupMap()
+	pp.PublicPort.allocateLookupMap()
+	pp.PublicPort.allocatePublicPortPortMap(pp.TranslateProtocols)
+	pp.lastport = portStart
+	pp.lastICMPPort, _ = pp.dynamicPortRange(types.ICMPNumber)
+	pp.PrivatePort.initPortPortForwardingEntries()
+	pp.PublicPort.initPortPortForwardingEntries()
+}
+
 // InitFlows initializes flow graph for all interface pairs.
 func InitFlows() {
 	for i := range Natconfig.PortPairs {
 		pp := &Natconfig.PortPairs[i]
 
 		// Init port pairs state
-		pp.initLocalMACs()
-		pp.PrivatePort.initIPv6LLAddresses()
-		pp.PublicPort.initIPv6LLAddresses()
-		pp.PrivatePort.allocateLookupMap()
-		pp.PublicPort.allocateLookupMap()
-		pp.PublicPort.allocatePublicPortPortMap()
-		pp.lastport = portStart
-		pp.PrivatePort.initPortPortForwardingEntries()
-		pp.PublicPort.initPortPortForwardingEntries()
+		pp.initState()
 
 		// Handler context with handler index
 		context := new(pairIndex)
 		context.index = i
 
-		var fromPubKNI, fromPrivKNI, toPub, toPriv *flow.Flow
+		var fromPubKNI, fromPrivKNI, kniToPub, toPub, toPriv *flow.Flow
 		var pubKNI, privKNI *flow.Kni
 		var outsPub = uint(2)
 		var outsPriv = uint(2)
@@ -628,19 +1361,49 @@ func InitFlows() {
 		// Initialize public to private flow
 		publicToPrivate, err := flow.SetReceiver(pp.PublicPort.Index)
 		flow.CheckFatal(err)
+		// MACsec frames are decrypted before they are translated
+		if pp.PublicPort.MACsec.enabled() {
+			flow.CheckFatal(flow.SetHandlerDrop(publicToPrivate, macsecInput, context))
+		}
 		if pp.PublicPort.KNIName != "" {
 			outsPub = 3
 		}
-		pubTranslationOut, err := flow.SetSplitter(publicToPrivate, PublicToPrivateTranslation, outsPub, context)
+		// Replies of private KNI host sessions have their own output
+		if pp.kniSNAT != nil {
+			outsPub = 4
+		}
+		var pubTranslationOut []*flow.Flow
+		if pp.vectorTranslation() {
+			pubTranslationOut, err = flow.SetVectorSplitter(publicToPrivate, publicToPrivateVector, outsPub, context)
+		} else {
+			pubTranslationOut, err = flow.SetSplitter(publicToPrivate, publicToPrivateCounted, outsPub, context)
+		}
 		flow.CheckFatal(err)
 		flow.CheckFatal(flow.SetStopper(pubTranslationOut[DirDROP]))
+		if outsPub == 4 && pp.PublicPort.KNIName == "" {
+			flow.CheckFatal(flow.SetStopper(pubTranslationOut[DirKNI]))
+		}
 
 		// Initialize public KNI interface if requested
 		if pp.PublicPort.KNIName != "" {
 			pubKNI, err = flow.CreateKniDevice(pp.PublicPort.Index, pp.PublicPort.KNIName)
 			flow.CheckFatal(err)
-			flow.CheckFatal(flow.SetSenderKNI(pubTranslationOut[DirKNI], pubKNI))
-			fromPubKNI = flow.SetReceiverKNI(pubKNI)
+			if Natconfig.ControlPlaneProtection.enabled() {
+				flow.CheckFatal(flow.SetHandlerDrop(pubTranslationOut[DirKNI], publicToKNIPolicing, context))
+			}
+			// TCP segments are coalesced before KNI interface
+			if pp.PublicPort.KNIOffload.GRO {
+				flow.CheckFatal(flow.SetVectorHandlerDrop(pubTranslationOut[DirKNI], publicKNICoalescing, context))
+			}
+			fromPubKNI = pp.PublicPort.setKNIFlows(pubTranslationOut[DirKNI], pubKNI)
+			// Replies of KNI addresses of forwarded ports get
+			// forwarded port as source
+			flow.CheckFatal(flow.SetHandler(fromPubKNI, publicKNIOutput, context))
+			if pp.PublicPort.KNIOffload.enabled() {
+				flow.CheckFatal(pp.PublicPort.setKNIMTU())
+				fromPubKNI, err = pp.PublicPort.setKNISegmentation(fromPubKNI)
+				flow.CheckFatal(err)
+			}
 		}
 
 		// Initialize private to public flow
@@ -649,7 +1412,12 @@ func InitFlows() {
 		if pp.PrivatePort.KNIName != "" {
 			outsPriv = 3
 		}
-		privTranslationOut, err := flow.SetSplitter(privateToPublic, PrivateToPublicTranslation, outsPriv, context)
+		var privTranslationOut []*flow.Flow
+		if pp.vectorTranslation() {
+			privTranslationOut, err = flow.SetVectorSplitter(privateToPublic, privateToPublicVector, outsPriv, context)
+		} else {
+			privTranslationOut, err = flow.SetSplitter(privateToPublic, privateToPublicCounted, outsPriv, context)
+		}
 		flow.CheckFatal(err)
 		flow.CheckFatal(flow.SetStopper(privTranslationOut[DirDROP]))
 
@@ -657,51 +1425,140 @@ func InitFlows() {
 		if pp.PrivatePort.KNIName != "" {
 			privKNI, err = flow.CreateKniDevice(pp.PrivatePort.Index, pp.PrivatePort.KNIName)
 			flow.CheckFatal(err)
-			flow.CheckFatal(flow.SetSenderKNI(privTranslationOut[DirKNI], privKNI))
-			fromPrivKNI = flow.SetReceiverKNI(privKNI)
+			if Natconfig.ControlPlaneProtection.enabled() {
+				flow.CheckFatal(flow.SetHandlerDrop(privTranslationOut[DirKNI], privateToKNIPolicing, context))
+			}
+			toPrivKNI := privTranslationOut[DirKNI]
+			if pp.kniSNAT != nil {
+				toPrivKNI, err = flow.SetMerger(toPrivKNI, pubTranslationOut[dirPrivateKNI])
+				flow.CheckFatal(err)
+			}
+			if pp.PrivatePort.KNIOffload.GRO {
+				flow.CheckFatal(flow.SetVectorHandlerDrop(toPrivKNI, privateKNICoalescing, context))
+			}
+			fromPrivKNI = pp.PrivatePort.setKNIFlows(toPrivKNI, privKNI)
+			// Traffic of KNI host to public network is translated
+			if pp.kniSNAT != nil {
+				kniOut, err := flow.SetSplitter(fromPrivKNI, privateKNIOutput, 3, context)
+				flow.CheckFatal(err)
+				flow.CheckFatal(flow.SetStopper(kniOut[DirDROP]))
+				fromPrivKNI, kniToPub = kniOut[DirSEND], kniOut[dirKNIToPublic]
+			}
+			// Large TCP segments of KNI host are split after they
+			// are translated
+			if pp.PrivatePort.KNIOffload.enabled() {
+				flow.CheckFatal(pp.PrivatePort.setKNIMTU())
+				fromPrivKNI, err = pp.PrivatePort.setKNISegmentation(fromPrivKNI)
+				flow.CheckFatal(err)
+				if kniToPub != nil {
+					kniToPub, err = pp.PrivatePort.setKNISegmentation(kniToPub)
+					flow.CheckFatal(err)
+				}
+			}
 		}
 
-		// Merge traffic coming from public KNI with translated
-		// traffic from private side
-		if fromPubKNI != nil {
-			toPub, err = flow.SetMerger(fromPubKNI, privTranslationOut[DirSEND])
+		// Merge traffic coming from public KNI and translated traffic
+		// of private KNI host with translated traffic from private
+		// side
+		toPub = privTranslationOut[DirSEND]
+		if fromPubKNI != nil || kniToPub != nil {
+			pp.PublicPort.setEgressScheduling(i, pp.EgressScheduling.PublicRate,
+				[egressClasses]*flow.Flow{toPub, fromPubKNI, kniToPub})
+			merged := []*flow.Flow{}
+			if fromPubKNI != nil {
+				merged = append(merged, fromPubKNI)
+			}
+			merged = append(merged, toPub)
+			if kniToPub != nil {
+				merged = append(merged, kniToPub)
+			}
+			toPub, err = flow.SetMerger(merged...)
 			flow.CheckFatal(err)
-		} else {
-			toPub = privTranslationOut[DirSEND]
 		}
 
 		// Merge traffic coming from private KNI with translated
 		// traffic from public side
 		if fromPrivKNI != nil {
+			pp.PrivatePort.setEgressScheduling(i, pp.EgressScheduling.PrivateRate,
+				[egressClasses]*flow.Flow{pubTranslationOut[DirSEND], fromPrivKNI, nil})
 			toPriv, err = flow.SetMerger(fromPrivKNI, pubTranslationOut[DirSEND])
 			flow.CheckFatal(err)
 		} else {
 			toPriv = pubTranslationOut[DirSEND]
 		}
 
+		if pp.TopTalkers.Entries != 0 {
+			pp.talkers = newTopTalkers(pp.TopTalkers)
+		}
+
+		// Police traffic before it is sent to public port
+		if pp.EgressPolicer.enabled() {
+			pp.egressPolicer = newEgressPolicer(pp.EgressPolicer)
+			flow.CheckFatal(flow.SetHandlerDrop(toPub, egressPolicing, context))
+		}
+
+		// Frames are encrypted after everything else is done to them
+		if pp.PublicPort.MACsec.enabled() {
+			flow.CheckFatal(flow.SetHandlerDrop(toPub, macsecOutput, context))
+		}
+
 		// Set senders to output packets
 		err = flow.SetSender(toPriv, pp.PrivatePort.Index)
 		flow.CheckFatal(err)
 		err = flow.SetSender(toPub, pp.PublicPort.Index)
 		flow.CheckFatal(err)
 	}
+
+	// Continue sessions saved by previously running NAT
+	loadSessions()
+	// Continue counters saved by previously running NAT
+	loadStatistics()
 }
 
+// CheckHWOffloading enables checksum offloading on ports which
+// support it. It returns false if no port supports it.
 func CheckHWOffloading() bool {
-	ports := []uint16{}
+	ports := []*ipPort{}
+	indexes := []uint16{}
 
 	for i := range Natconfig.PortPairs {
 		pp := &Natconfig.PortPairs[i]
-		ports = append(ports, pp.PublicPort.Index, pp.PrivatePort.Index)
+		ports = append(ports, &pp.PublicPort, &pp.PrivatePort)
+		indexes = append(indexes, pp.PublicPort.Index, pp.PrivatePort.Index)
 	}
 
-	capabilities := flow.CheckHWCapability(flow.HWTXChecksumCapability, ports)
-	for _, c := range capabilities {
-		if !c {
-			return false
+	// Ports are configured with offloading which their cards
+	// support, so checksums are calculated in software only for
+	// ports which lack it
+	capabilities := flow.CheckHWCapability(flow.HWTXChecksumCapability, indexes)
+	available := false
+	for i, c := range capabilities {
+		// Checksums of encrypted frames cannot be calculated by
+		// network card
+		ports[i].hwTXChecksum = c && !NoHWTXChecksum && !ports[i].MACsec.enabled() && ports[i].ChecksumStrategy.allowsOffload()
+		if c {
+			available = true
+		} else if !NoHWTXChecksum {
+			println("Warning! Hardware checksum offloading is not available on port", ports[i].logName(),
+				". Checksums of its packets are calculated in software.")
 		}
 	}
-	return true
+	return available
+}
+
+// logName returns port index and tenant of its port pair for log
+// messages.
+// hasZone returns true if zone of link local address is name or
+// index of port.
+func (port *ipPort) hasZone(zone string) bool {
+	return zone == port.ifName() || zone == strconv.Itoa(int(port.Index))
+}
+
+func (port *ipPort) logName() string {
+	if port.tenant == "" {
+		return strconv.Itoa(int(port.Index))
+	}
+	return fmt.Sprintf("%d (tenant %s)", port.Index, port.tenant)
 }
 
 func (c *Config) getPortAndPairByID(portId uint32) (*ipPort, *portPair) {
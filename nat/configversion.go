@@ -0,0 +1,244 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	versionKey = "version"
+	// Current version of config file schema. Files without version
+	// have version 1.
+	configVersion = 2
+)
+
+// Migrations of config file tree, migration with index i converts
+// tree of version i+1 to version i+2. When schema changes, migration
+// is appended and configVersion is incremented, so that files written
+// for older versions keep working.
+var configMigrations = []func(tree map[string]interface{}, fileName string) error{
+	// Version 1 silently ignored unknown settings, e.g. misspelled
+	// ones or settings of newer versions. They are removed with a
+	// warning, version 2 rejects them.
+	func(tree map[string]interface{}, fileName string) error {
+		return checkConfigKeys(tree, func(obj map[string]interface{}, key, path string) error {
+			fmt.Printf("Warning! Unknown setting %s in config file \"%s\" is ignored\n", path, fileName)
+			delete(obj, key)
+			return nil
+		})
+	},
+}
+
+var (
+	configType      = reflect.TypeOf(Config{})
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textType        = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// migrateConfigTree converts tree of one config file to current
+// schema version and checks that it has no unknown settings. Each
+// included file has its own version.
+func migrateConfigTree(tree map[string]interface{}, fileName string) error {
+	version := 1
+	if v, ok := tree[versionKey]; ok {
+		delete(tree, versionKey)
+		// Numbers of config tree are decoded as json.Number
+		n, ok := v.(json.Number)
+		f, err := n.Float64()
+		if !ok || err != nil || f != float64(int(f)) || f < 1 {
+			return fmt.Errorf("Bad \"%s\" value in config file \"%s\", it should be a positive integer", versionKey, fileName)
+		}
+		version = int(f)
+	}
+	if version > configVersion {
+		return fmt.Errorf("Config file \"%s\" has version %d, only versions up to %d are supported", fileName, version, configVersion)
+	}
+	for _, migrate := range configMigrations[version-1:] {
+		if err := migrate(tree, fileName); err != nil {
+			return err
+		}
+	}
+	return checkConfigKeys(tree, func(obj map[string]interface{}, key, path string) error {
+		return fmt.Errorf("Unknown setting %s in config file \"%s\"", path, fileName)
+	})
+}
+
+// checkConfigKeys calls unknown for every setting of config file tree
+// which is not in schema.
+func checkConfigKeys(tree map[string]interface{}, unknown unknownSetting) error {
+	w := configWalker{unknown: unknown}
+	return w.walkRoot(tree, "")
+}
+
+// walkRoot walks top level object of config file. Per-host overrides
+// have the same schema as config file and may set variables too.
+func (w *configWalker) walkRoot(tree map[string]interface{}, path string) error {
+	for _, key := range sortedKeys(tree) {
+		switch key {
+		case includeKey, variablesKey:
+		case hostOverridesKey:
+			hosts, _ := tree[key].(map[string]interface{})
+			for _, host := range sortedKeys(hosts) {
+				if override, ok := hosts[host].(map[string]interface{}); ok {
+					if err := w.walkRoot(override, path+"/"+key+"/"+host); err != nil {
+						return err
+					}
+				}
+			}
+		default:
+			if err := w.walkField(tree, key, configType, path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkConfigValues decodes every setting of complete config tree, so
+// that decoding error is reported with path of setting which caused
+// it.
+func checkConfigValues(tree map[string]interface{}) error {
+	w := configWalker{values: true}
+	return w.walk(tree, configType, "")
+}
+
+// Handler of setting which is not in schema. Path is in the same
+// format as in commit errors, e.g. /port-pairs/0/public-port/vlan.
+type unknownSetting func(obj map[string]interface{}, key, path string) error
+
+// configWalker walks decoded JSON tree along with Go type it is
+// decoded to.
+type configWalker struct {
+	unknown unknownSetting
+	// Decode values which have their own decoding
+	values bool
+}
+
+func (w *configWalker) walk(node interface{}, t reflect.Type, path string) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if node == nil {
+		return nil
+	}
+	if reflect.PtrTo(t).Implements(unmarshalerType) || reflect.PtrTo(t).Implements(textType) {
+		return w.decode(node, t, path)
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return w.decode(node, t, path)
+		}
+		for _, key := range sortedKeys(obj) {
+			if err := w.walkField(obj, key, t, path); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		list, ok := node.([]interface{})
+		if !ok {
+			return w.decode(node, t, path)
+		}
+		for i, n := range list {
+			if err := w.walk(n, t.Elem(), fmt.Sprintf("%s/%d", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return w.decode(node, t, path)
+		}
+		for _, key := range sortedKeys(obj) {
+			if err := w.walk(obj[key], t.Elem(), path+"/"+key); err != nil {
+				return err
+			}
+		}
+	default:
+		return w.decode(node, t, path)
+	}
+	return nil
+}
+
+// walkField walks value of object key which is decoded to field of
+// struct type t.
+func (w *configWalker) walkField(obj map[string]interface{}, key string, t reflect.Type, path string) error {
+	path += "/" + key
+	field, ok := jsonField(t, key)
+	if !ok {
+		if w.unknown == nil {
+			return nil
+		}
+		return w.unknown(obj, key, path)
+	}
+	return w.walk(obj[key], field.Type, path)
+}
+
+func (w *configWalker) decode(node interface{}, t reflect.Type, path string) error {
+	if !w.values {
+		return nil
+	}
+	data, err := json.Marshal(node)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, reflect.New(t).Interface()); err != nil {
+		return fmt.Errorf("Bad value of setting %s: %v", path, err)
+	}
+	return nil
+}
+
+// jsonField finds struct field which JSON key is decoded to. Like
+// encoding/json, it matches keys case-insensitively and looks into
+// embedded structs.
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if f, ok := jsonField(ft, key); ok {
+					return f, true
+				}
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,171 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type walkerEmbedded struct {
+	Inner int `json:"inner"`
+}
+
+type walkerStruct struct {
+	walkerEmbedded
+	Named   int `json:"named-field"`
+	Plain   int
+	Ignored int `json:"-"`
+	hidden  int
+}
+
+func TestJSONField(t *testing.T) {
+	tests := []struct {
+		key   string
+		field string
+	}{
+		{"named-field", "Named"},
+		{"Named-Field", "Named"},
+		{"plain", "Plain"},
+		{"inner", "Inner"},
+		{"Ignored", ""},
+		{"-", ""},
+		{"hidden", ""},
+		{"walkerEmbedded", ""},
+		{"other", ""},
+	}
+	typ := reflect.TypeOf(walkerStruct{})
+	for _, tt := range tests {
+		field, ok := jsonField(typ, tt.key)
+		if ok != (tt.field != "") || (ok && field.Name != tt.field) {
+			t.Errorf("Key %q is field %q found %v, expected %q", tt.key, field.Name, ok, tt.field)
+		}
+	}
+}
+
+func parseConfigTree(t *testing.T, s string) map[string]interface{} {
+	tree, err := decodeConfigTree([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func TestDecodeConfigTree(t *testing.T) {
+	// Integers beyond float64 precision are encoded again unchanged
+	config := `{"counter":18446744073709551615,"id":9007199254740993,"ratio":0.25}`
+	data, err := json.Marshal(parseConfigTree(t, config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != config {
+		t.Errorf("Config tree is encoded as %s, expected %s", data, config)
+	}
+	for _, bad := range []string{`{"a": 1} {}`, `{"a": 1}}`, `{"a": }`} {
+		if _, err := decodeConfigTree([]byte(bad)); err == nil {
+			t.Errorf("Config %s is decoded without error", bad)
+		}
+	}
+}
+
+func TestCheckConfigKeys(t *testing.T) {
+	tree := parseConfigTree(t, `{
+		"include": ["other.json"],
+		"variables": {"addr": "192.168.1.1/24"},
+		"typo": 1,
+		"host-overrides": {"nat1": {"host-name": "nat1", "bogus": true}},
+		"port-pairs": [
+			{"public-port": {"index": 0, "vlann": 5, "subnet": {"own": "decoding"}}},
+			{"private-port": {"index": 1}, "Public-Port": {"Index": 2}}
+		]
+	}`)
+	var paths []string
+	err := checkConfigKeys(tree, func(obj map[string]interface{}, key, path string) error {
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"/host-overrides/nat1/bogus",
+		"/port-pairs/0/public-port/vlann",
+		"/typo",
+	}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Unknown settings are %v, expected %v", paths, expected)
+	}
+}
+
+func TestMigrateConfigTree(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		err    string
+		keys   []string
+	}{
+		{"version 1 without version", `{"host-name": "nat", "typo": 1}`, "", []string{"host-name"}},
+		{"version 1", `{"version": 1, "port-pairs": [{"publicport": {}}]}`, "", []string{"port-pairs"}},
+		{"current version", `{"version": 2, "host-name": "nat"}`, "", []string{"host-name"}},
+		{"unknown setting of current version", `{"version": 2, "port-pairs": [{"publicport": {}}]}`,
+			"Unknown setting /port-pairs/0/publicport", nil},
+		{"newer version", `{"version": 3}`, "only versions up to 2", nil},
+		{"zero version", `{"version": 0}`, "Bad \"version\" value", nil},
+		{"fractional version", `{"version": 1.5}`, "Bad \"version\" value", nil},
+		{"string version", `{"version": "2"}`, "Bad \"version\" value", nil},
+	}
+	for _, tt := range tests {
+		tree := parseConfigTree(t, tt.config)
+		err := migrateConfigTree(tree, "test.json")
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error is %v, expected %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if keys := sortedKeys(tree); !reflect.DeepEqual(keys, tt.keys) {
+			t.Errorf("%s: migrated settings are %v, expected %v", tt.name, keys, tt.keys)
+		}
+	}
+
+	// Unknown setting of version 1 is removed in nested object too
+	tree := parseConfigTree(t, `{"port-pairs": [{"public-port": {"index": 0, "vlann": 5}}]}`)
+	if err := migrateConfigTree(tree, "test.json"); err != nil {
+		t.Fatal(err)
+	}
+	port := tree["port-pairs"].([]interface{})[0].(map[string]interface{})["public-port"].(map[string]interface{})
+	if _, ok := port["vlann"]; ok {
+		t.Errorf("Unknown setting of version 1 is not removed")
+	}
+}
+
+func TestCheckConfigValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{"valid", `{"port-pairs": [{"public-port": {"index": 1, "subnet": "192.168.1.1/24"}}]}`, ""},
+		{"bad number", `{"port-pairs": [{}, {"public-port": {"index": "one"}}]}`, "/port-pairs/1/public-port/index"},
+		{"bad own decoding", `{"port-pairs": [{"public-port": {"subnet": "bad"}}]}`, "/port-pairs/0/public-port/subnet"},
+		{"object instead of list", `{"port-pairs": {"public-port": {}}}`, "/port-pairs"},
+	}
+	for _, tt := range tests {
+		err := checkConfigValues(parseConfigTree(t, tt.config))
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), "Bad value of setting "+tt.err+":") {
+			t.Errorf("%s: error is %v, expected error of setting %s", tt.name, err, tt.err)
+		}
+	}
+}
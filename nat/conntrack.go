@@ -0,0 +1,361 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/types"
+)
+
+const (
+	// Session tables are scanned this often when interval is not
+	// configured, in seconds
+	defaultConntrackSyncInterval = 1
+	// All active sessions are sent again this often when resync
+	// interval is not configured, in seconds
+	defaultConntrackResyncInterval = 30
+	// Messages are packed into datagrams of at most this size, so
+	// they fit into Ethernet frames
+	conntrackMaxDatagram = 1400
+)
+
+// Message header, message types and attributes of conntrackd sync
+// protocol version 1.
+const (
+	conntrackProtocolVersion = 1
+	conntrackHeaderSize      = 8
+
+	conntrackMsgNew = 0
+	conntrackMsgUpd = 1
+	conntrackMsgDel = 2
+
+	ntaIPv4      = 0
+	ntaIPv6      = 1
+	ntaPort      = 2
+	ntaL4Proto   = 3
+	ntaTCPState  = 4
+	ntaStatus    = 5
+	ntaSNATIPv4  = 12
+	ntaSPATPort  = 14
+	ntaSNATIPv6  = 29
+	ntaAlignment = 4
+)
+
+// Linux conntrack status bits and TCP states of exported sessions.
+const (
+	ipsSeenReply    = 1 << 1
+	ipsAssured      = 1 << 2
+	ipsConfirmed    = 1 << 3
+	ipsSrcNAT       = 1 << 4
+	ipsSrcNATDone   = 1 << 7
+	ipsDstNATDone   = 1 << 8
+	conntrackStatus = ipsSeenReply | ipsAssured | ipsConfirmed | ipsSrcNAT | ipsSrcNATDone | ipsDstNATDone
+
+	tcpConntrackEstablished = 3
+	tcpConntrackFinWait     = 4
+	tcpConntrackTimeWait    = 7
+)
+
+// Export of sessions to Linux conntrackd which keeps them as warm
+// standby. Messages use conntrackd sync protocol, so conntrackd
+// should be configured in NOTRACK mode with UDP or multicast channel.
+type conntrackSyncConfig struct {
+	// UDP address of conntrackd channel, host:port of unicast peer
+	// or multicast group
+	Address string `json:"address"`
+	// Interface which sends messages to multicast group
+	Interface string `json:"interface"`
+	// Seconds between scans of session tables, zero means default
+	Interval int `json:"interval"`
+	// Seconds between sending all active sessions again, zero means
+	// default
+	ResyncInterval int `json:"resync-interval"`
+	// Local UDP port of messages, zero lets Linux choose it
+	SourcePort uint16 `json:"source-port"`
+	addr       *net.UDPAddr
+}
+
+// Dynamic session as it was seen by last scan of session tables.
+type conntrackSession struct {
+	// Tuple or Tuple6 of public, private and remote side
+	public   interface{}
+	private  interface{}
+	remote   interface{}
+	finCount uint8
+	// MAC address of private host when hosts are tracked
+	mac    types.MACAddress
+	hasMAC bool
+}
+
+// Session is identified by its public port in port pool of protocol.
+type conntrackSessionKey struct {
+	pair     int
+	ipv6     bool
+	protocol uint8
+	port     uint16
+}
+
+// Packs sync messages into datagrams sent to conntrackd.
+type conntrackSender struct {
+	conn     *net.UDPConn
+	datagram []byte
+	seq      uint32
+}
+
+func (cfg *conntrackSyncConfig) enabled() bool {
+	return cfg.Address != ""
+}
+
+func (cfg *conntrackSyncConfig) check() error {
+	if !cfg.enabled() {
+		return nil
+	}
+	addr, err := net.ResolveUDPAddr("udp", cfg.Address)
+	if err != nil {
+		return fmt.Errorf("Bad conntrack-sync address \"%s\": %+v", cfg.Address, err)
+	}
+	if cfg.Interface != "" && !addr.IP.IsMulticast() {
+		return errors.New("Conntrack-sync interface can be used only with multicast address")
+	}
+	if cfg.Interval < 0 || cfg.ResyncInterval < 0 {
+		return errors.New("Conntrack-sync intervals should not be negative")
+	}
+	cfg.addr = addr
+	return nil
+}
+
+func (cfg *conntrackSyncConfig) interval() time.Duration {
+	if cfg.Interval == 0 {
+		return defaultConntrackSyncInterval * time.Second
+	}
+	return time.Duration(cfg.Interval) * time.Second
+}
+
+func (cfg *conntrackSyncConfig) resyncInterval() time.Duration {
+	if cfg.ResyncInterval == 0 {
+		return defaultConntrackResyncInterval * time.Second
+	}
+	return time.Duration(cfg.ResyncInterval) * time.Second
+}
+
+// StartConntrackSync starts exporting sessions to conntrackd if it is
+// configured.
+func StartConntrackSync() error {
+	cfg := &Natconfig.ConntrackSync
+	if !cfg.enabled() {
+		return nil
+	}
+	conn, err := net.DialUDP("udp", localUDPAddr(cfg.SourcePort), cfg.addr)
+	if err != nil {
+		return fmt.Errorf("Failed to open conntrack-sync socket: %+v", err)
+	}
+	if cfg.Interface != "" {
+		ifi, err := net.InterfaceByName(cfg.Interface)
+		if err != nil {
+			conn.Close()
+			return fmt.Errorf("Bad conntrack-sync interface \"%s\": %+v", cfg.Interface, err)
+		}
+		if cfg.addr.IP.To4() != nil {
+			err = ipv4.NewPacketConn(conn).SetMulticastInterface(ifi)
+		} else {
+			err = ipv6.NewPacketConn(conn).SetMulticastInterface(ifi)
+		}
+		if err != nil {
+			conn.Close()
+			return fmt.Errorf("Failed to set conntrack-sync multicast interface: %+v", err)
+		}
+	}
+	sender := &conntrackSender{
+		conn:     conn,
+		datagram: make([]byte, 0, conntrackMaxDatagram),
+	}
+	go sender.run(cfg)
+	return nil
+}
+
+// run periodically compares session tables with their previous scan
+// and sends new, updated and deleted sessions. Sessions which are idle
+// longer than session timeout are reported as deleted. Sessions are
+// updated when TCP connection is closing and on every resync, so
+// standby which started later learns all of them.
+func (s *conntrackSender) run(cfg *conntrackSyncConfig) {
+	known := map[conntrackSessionKey]conntrackSession{}
+	resync := time.Now()
+	for {
+		full := time.Since(resync) >= cfg.resyncInterval()
+		if full {
+			resync = time.Now()
+		}
+		current := map[conntrackSessionKey]conntrackSession{}
+		for i := range Natconfig.PortPairs {
+			Natconfig.PortPairs[i].scanConntrackSessions(i, current)
+		}
+		for key, cur := range current {
+			old, ok := known[key]
+			switch {
+			case !ok:
+				s.send(conntrackMsgNew, key, &cur)
+			case old.private != cur.private || old.remote != cur.remote || old.public != cur.public:
+				// Public port was reused by another session
+				s.send(conntrackMsgDel, key, &old)
+				s.send(conntrackMsgNew, key, &cur)
+			case full || old.finCount != cur.finCount:
+				s.send(conntrackMsgUpd, key, &cur)
+			}
+		}
+		for key, old := range known {
+			if _, ok := current[key]; !ok {
+				s.send(conntrackMsgDel, key, &old)
+			}
+		}
+		s.flush()
+		known = current
+		time.Sleep(cfg.interval())
+	}
+}
+
+// scanConntrackSessions adds active dynamic TCP and UDP sessions of
+// port pair to sessions map. Sessions which remote side is not known,
+// like restored ones, cannot be represented by conntrack and are
+// skipped. ICMP identifiers are not exported because conntrackd
+// doesn't carry their translation.
+func (pp *portPair) scanConntrackSessions(index int, sessions map[conntrackSessionKey]conntrackSession) {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	now := time.Now()
+	for _, ipv6 := range pp.ipFamilies() {
+		for _, protocol := range []uint8{types.TCPNumber, types.UDPNumber} {
+			pm := pp.getPublicPortPortmap(ipv6, protocol)
+			for p := portStart; p < portEnd; p++ {
+				pme := &pm[p]
+				if pme.static || pme.remote == nil || now.Sub(pme.lastused) > connectionTimeout {
+					continue
+				}
+				pubKey := pp.sessionPublicKey(ipv6, *pme, uint16(p))
+				v, found := pp.PublicPort.translationTable[protocol].Load(pubKey)
+				if !found {
+					continue
+				}
+				session := conntrackSession{
+					public:   pubKey,
+					private:  v,
+					remote:   pme.remote,
+					finCount: pme.finCount,
+				}
+				session.mac, session.hasMAC = pp.sessionMAC(pme, v)
+				sessions[conntrackSessionKey{index, ipv6, protocol, uint16(p)}] = session
+			}
+		}
+	}
+}
+
+// send adds message about session to current datagram.
+func (s *conntrackSender) send(msgType uint8, key conntrackSessionKey, session *conntrackSession) {
+	msg := buildConntrackMessage(msgType, key, session)
+	if len(s.datagram)+len(msg) > conntrackMaxDatagram {
+		s.flush()
+	}
+	s.seq++
+	binary.BigEndian.PutUint32(msg[4:], s.seq)
+	s.datagram = append(s.datagram, msg...)
+}
+
+func (s *conntrackSender) flush() {
+	if len(s.datagram) == 0 {
+		return
+	}
+	if _, err := s.conn.Write(s.datagram); err != nil {
+		common.LogWarning(common.No, "Failed to send sessions to conntrackd", Natconfig.ConntrackSync.Address, ":", err)
+	}
+	s.datagram = s.datagram[:0]
+}
+
+// buildConntrackMessage encodes session as conntrackd message. Original
+// direction of conntrack entry goes from private host to remote host
+// and source NAT replaces private side by public side. Sequence number
+// is set by sender.
+func buildConntrackMessage(msgType uint8, key conntrackSessionKey, session *conntrackSession) []byte {
+	msg := make([]byte, conntrackHeaderSize, 128)
+	msg[0] = conntrackProtocolVersion<<4 | msgType
+
+	var privPort, pubPort, remotePort uint16
+	if key.ipv6 {
+		pub, priv, remote := session.public.(Tuple6), session.private.(Tuple6), session.remote.(Tuple6)
+		msg = addConntrackAttr(msg, ntaIPv6, append(append([]byte{}, priv.addr[:]...), remote.addr[:]...))
+		msg = addConntrackAttr(msg, ntaSNATIPv6, pub.addr[:])
+		privPort, pubPort, remotePort = priv.port, pub.port, remote.port
+	} else {
+		pub, priv, remote := session.public.(Tuple), session.private.(Tuple), session.remote.(Tuple)
+		addrs := make([]byte, 8)
+		binary.BigEndian.PutUint32(addrs, uint32(priv.addr))
+		binary.BigEndian.PutUint32(addrs[4:], uint32(remote.addr))
+		msg = addConntrackAttr(msg, ntaIPv4, addrs)
+		msg = addConntrackAttr(msg, ntaSNATIPv4, conntrackSwappedUint32(uint32(pub.addr)))
+		privPort, pubPort, remotePort = priv.port, pub.port, remote.port
+	}
+	msg = addConntrackAttr(msg, ntaL4Proto, []byte{key.protocol})
+	status := make([]byte, 4)
+	binary.BigEndian.PutUint32(status, conntrackStatus)
+	msg = addConntrackAttr(msg, ntaStatus, status)
+
+	ports := make([]byte, 4)
+	binary.BigEndian.PutUint16(ports, privPort)
+	binary.BigEndian.PutUint16(ports[2:], remotePort)
+	msg = addConntrackAttr(msg, ntaPort, ports)
+	msg = addConntrackAttr(msg, ntaSPATPort, conntrackSwappedUint16(pubPort))
+	if key.protocol == types.TCPNumber {
+		msg = addConntrackAttr(msg, ntaTCPState, []byte{conntrackTCPState(session.finCount)})
+	}
+	binary.BigEndian.PutUint16(msg[2:], uint16(len(msg)))
+	return msg
+}
+
+// addConntrackAttr appends attribute padded to 4 bytes.
+func addConntrackAttr(msg []byte, attr uint16, data []byte) []byte {
+	hdr := make([]byte, 4)
+	binary.BigEndian.PutUint16(hdr, uint16(len(hdr)+len(data)))
+	binary.BigEndian.PutUint16(hdr[2:], attr)
+	msg = append(append(msg, hdr...), data...)
+	for len(msg)%ntaAlignment != 0 {
+		msg = append(msg, 0)
+	}
+	return msg
+}
+
+// NAT address and port attributes hold values which conntrackd on
+// little-endian hosts converts from network byte order once more, so
+// they go in reversed byte order.
+func conntrackSwappedUint32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
+
+func conntrackSwappedUint16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.LittleEndian.PutUint16(b, v)
+	return b
+}
+
+func conntrackTCPState(finCount uint8) uint8 {
+	switch finCount {
+	case 0:
+		return tcpConntrackEstablished
+	case 1:
+		return tcpConntrackFinWait
+	default:
+		return tcpConntrackTimeWait
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"github.com/intel-go/nff-go/flow"
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// Classes of received packets which are handled by NAT itself or are
+// sent to KNI interface. Every class has its own buckets, so a flood
+// of one class doesn't starve others.
+const (
+	controlPlaneARP = iota
+	controlPlaneND
+	controlPlaneDHCP
+	controlPlaneICMP
+	// All other packets sent to KNI interface, e.g. SSH or BGP
+	controlPlaneManagement
+	controlPlaneClasses
+)
+
+// Limits for packets received by NAT addresses and KNI interfaces.
+// Rates are in packets per second, zero rate means no limit.
+// Per-source buckets are kept by source IP address, and by sender
+// protocol address for ARP.
+type controlPlaneConfig struct {
+	ARP        rateLimitConfig `json:"arp"`
+	ND         rateLimitConfig `json:"nd"`
+	DHCP       rateLimitConfig `json:"dhcp"`
+	ICMP       rateLimitConfig `json:"icmp"`
+	Management rateLimitConfig `json:"management"`
+}
+
+// Limiters of control plane classes, nil when protection is disabled
+var controlPlaneLimiters [controlPlaneClasses]*rateLimiter
+
+func (cfg *controlPlaneConfig) classes() [controlPlaneClasses]*rateLimitConfig {
+	return [controlPlaneClasses]*rateLimitConfig{
+		&cfg.ARP,
+		&cfg.ND,
+		&cfg.DHCP,
+		&cfg.ICMP,
+		&cfg.Management,
+	}
+}
+
+func (cfg *controlPlaneConfig) check() error {
+	names := [controlPlaneClasses]string{"arp", "nd", "dhcp", "icmp", "management"}
+	for i, class := range cfg.classes() {
+		if err := class.check("control-plane-protection " + names[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enabled returns true if any class is limited.
+func (cfg *controlPlaneConfig) enabled() bool {
+	for _, class := range cfg.classes() {
+		if class.Rate != 0 || class.PerSourceRate != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// newControlPlaneLimiters creates limiters of limited classes.
+func newControlPlaneLimiters(cfg *controlPlaneConfig) {
+	for i, class := range cfg.classes() {
+		if class.Rate != 0 || class.PerSourceRate != 0 {
+			controlPlaneLimiters[i] = newRateLimiter(*class)
+		} else {
+			controlPlaneLimiters[i] = nil
+		}
+	}
+}
+
+// allowControlPlane returns true if one more packet of class from
+// source may be handled by NAT or sent to KNI interface of port.
+func (port *ipPort) allowControlPlane(class int, source interface{}) bool {
+	return controlPlaneLimiters[class].allow(port, source)
+}
+
+// isNeighborDiscovery returns true for ICMPv6 messages which NAT
+// handles as neighbor discovery.
+func isNeighborDiscovery(icmpType uint8) bool {
+	return icmpType == types.ICMPv6NeighborSolicitation ||
+		icmpType == types.ICMPv6NeighborAdvertisement ||
+		icmpType == icmpv6RouterAdvertisement ||
+		icmpType == icmpv6Redirect
+}
+
+// allowToKNI polices packet which is sent to KNI interface of port.
+// ARP and neighbor discovery are already limited when they are
+// received, ICMP and all other packets are limited here.
+func (port *ipPort) allowToKNI(pkt *packet.Packet) bool {
+	pktIPv4, pktIPv6, _ := pkt.ParseAllKnownL3CheckVLAN()
+	var source interface{}
+	var protocol uint8
+	if pktIPv4 != nil {
+		source = packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr)
+		protocol = pktIPv4.NextProtoID
+	} else if pktIPv6 != nil {
+		source = pktIPv6.SrcAddr
+		protocol = pktIPv6.Proto
+	} else {
+		return true
+	}
+	class := controlPlaneManagement
+	switch protocol {
+	case types.ICMPNumber:
+		class = controlPlaneICMP
+	case types.ICMPv6Number:
+		pkt.ParseL4ForIPv6()
+		if icmp := pkt.GetICMPForIPv6(); icmp != nil && isNeighborDiscovery(icmp.Type) {
+			return true
+		}
+		class = controlPlaneICMP
+	}
+	return port.allowControlPlane(class, source)
+}
+
+func publicToKNIPolicing(pkt *packet.Packet, ctx flow.UserContext) bool {
+	return Natconfig.PortPairs[ctx.(pairIndex).index].PublicPort.allowToKNI(pkt)
+}
+
+func privateToKNIPolicing(pkt *packet.Packet, ctx flow.UserContext) bool {
+	return Natconfig.PortPairs[ctx.(pairIndex).index].PrivatePort.allowToKNI(pkt)
+}
@@ -0,0 +1,321 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket/layers"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// Duplicate address detection constants from RFC 4861 and RFC 4862.
+const (
+	dadDefaultTransmits = 1
+	dadMaxTransmits     = 10
+	// Time to wait for answers after each neighbor solicitation
+	dadRetransTimer = time.Second
+	// First solicitation is delayed randomly up to this time
+	dadMaxDelay = time.Second
+)
+
+var allNodesMulticastAddr = types.IPv6Address{0xff, 0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x01}
+
+// Duplicate address detection settings of port. IPv6 addresses are
+// not used until detection finishes.
+type dadConfig struct {
+	Disable bool `json:"disable"`
+	// Number of neighbor solicitations sent for every address, zero
+	// means default
+	Transmits int `json:"transmits"`
+	// Duplicate address acquired from DHCPv6 server is declined and
+	// another address is requested. Without it DHCPv6 client stops
+	// after declining address.
+	Retry bool `json:"retry"`
+}
+
+// Tentative address which is checked.
+type dadProbe struct {
+	duplicate bool
+	// Address of host which uses the same address
+	mac types.MACAddress
+}
+
+// Tentative addresses of port. Probes are started by control code and
+// marked as duplicate by packet handlers, so they are protected by a
+// mutex.
+type dadState struct {
+	mutex  sync.Mutex
+	probes map[types.IPv6Address]*dadProbe
+}
+
+func (cfg *dadConfig) check(port uint16) error {
+	if cfg.Transmits < 0 || cfg.Transmits > dadMaxTransmits {
+		return fmt.Errorf("Port %d DAD transmits should be between 0 and %d", port, dadMaxTransmits)
+	}
+	return nil
+}
+
+func (cfg *dadConfig) transmits() int {
+	if cfg.Transmits == 0 {
+		return dadDefaultTransmits
+	}
+	return cfg.Transmits
+}
+
+// StartDuplicateAddressDetection checks link local and static IPv6
+// addresses of all ports in background. It should be called after
+// ports are started and before DHCP client is started.
+func StartDuplicateAddressDetection() {
+	for i := range Natconfig.PortPairs {
+		pp := &Natconfig.PortPairs[i]
+		pp.PrivatePort.startDuplicateAddressDetection()
+		pp.PublicPort.startDuplicateAddressDetection()
+	}
+}
+
+func (port *ipPort) startDuplicateAddressDetection() {
+	if port.DAD.Disable || port.ipv6Disabled {
+		return
+	}
+	// Link local address is derived from MAC address, so it cannot
+	// be replaced when it is duplicate
+	llProbe := port.startProbe(port.Subnet6.llAddr)
+	go port.runProbe(port.Subnet6.llAddr, llProbe)
+
+	if !port.Subnet6.addressAcquired {
+		return
+	}
+	port.Subnet6.addressAcquired = false
+	addr := port.Subnet6.Addr
+	probe := port.startProbe(addr)
+	go func() {
+		if _, duplicate := port.runProbe(addr, probe); duplicate {
+			port.Subnet6.duplicate = true
+			return
+		}
+		port.Subnet6.addressAcquired = true
+	}()
+}
+
+// startProbe marks address as tentative. Address should be checked
+// with runProbe after that.
+func (port *ipPort) startProbe(addr types.IPv6Address) *dadProbe {
+	probe := &dadProbe{}
+	port.dad.mutex.Lock()
+	defer port.dad.mutex.Unlock()
+	if port.dad.probes == nil {
+		port.dad.probes = map[types.IPv6Address]*dadProbe{}
+	}
+	port.dad.probes[addr] = probe
+	return probe
+}
+
+// runProbe performs duplicate address detection of tentative address
+// and blocks until it finishes. It returns MAC address of host which
+// uses the same address and true if address is duplicate.
+func (port *ipPort) runProbe(addr types.IPv6Address, probe *dadProbe) (types.MACAddress, bool) {
+	defer func() {
+		port.dad.mutex.Lock()
+		if port.dad.probes[addr] == probe {
+			delete(port.dad.probes, addr)
+		}
+		port.dad.mutex.Unlock()
+	}()
+
+	time.Sleep(time.Duration(rand.Int63n(int64(dadMaxDelay))))
+	for i := 0; i < port.DAD.transmits(); i++ {
+		port.sendDADNeighborSolicitation(addr)
+		time.Sleep(dadRetransTimer)
+		port.dad.mutex.Lock()
+		duplicate, mac := probe.duplicate, probe.mac
+		port.dad.mutex.Unlock()
+		if duplicate {
+			port.addressConflict(addr, mac, true)
+			return mac, true
+		}
+	}
+	println("Address", addr.String(), "is unique on port", port.logName())
+	return types.MACAddress{}, false
+}
+
+// detectDuplicateAddress checks address and blocks until check
+// finishes.
+func (port *ipPort) detectDuplicateAddress(addr types.IPv6Address) (types.MACAddress, bool) {
+	return port.runProbe(addr, port.startProbe(addr))
+}
+
+func (port *ipPort) isTentative(addr types.IPv6Address) bool {
+	port.dad.mutex.Lock()
+	defer port.dad.mutex.Unlock()
+	_, ok := port.dad.probes[addr]
+	return ok
+}
+
+// markDuplicate records that another host uses tentative address. It
+// returns false if address is not tentative.
+func (port *ipPort) markDuplicate(addr types.IPv6Address, mac types.MACAddress) bool {
+	port.dad.mutex.Lock()
+	defer port.dad.mutex.Unlock()
+	probe, ok := port.dad.probes[addr]
+	if !ok {
+		return false
+	}
+	if !probe.duplicate {
+		probe.duplicate = true
+		probe.mac = mac
+	}
+	return true
+}
+
+// ownsIPv6Address returns true if address is assigned to port and is
+// not tentative.
+func (port *ipPort) ownsIPv6Address(addr types.IPv6Address) bool {
+	if addr == port.Subnet6.llAddr {
+		return !port.isTentative(addr)
+	}
+	return addr == port.Subnet6.Addr && port.Subnet6.addressAcquired || port.isTemporaryAddress(addr) ||
+		port.isRetiredIPv6Address(addr)
+}
+
+// addressConflict reports that another host uses address of port.
+func (port *ipPort) addressConflict(addr types.IPv6Address, mac types.MACAddress, tentative bool) {
+	println("Warning! Address", addr.String(), "of port", port.logName(), "is used by host", mac.String())
+	state := "assigned"
+	if tentative {
+		state = "tentative"
+	}
+	raiseEvent(EventAddressConflict, port, map[string]interface{}{
+		"address": addr.String(),
+		"mac":     mac.String(),
+		"state":   state,
+	})
+}
+
+// handleDADSolicitation processes neighbor solicitation sent from
+// unspecified address by another host which checks target address.
+func (port *ipPort) handleDADSolicitation(pkt *packet.Packet, target types.IPv6Address) {
+	// Switch may reflect our own solicitations
+	if pkt.Ether.SAddr == port.SrcMACAddress {
+		return
+	}
+	// Both hosts check the same address at the same time
+	if port.markDuplicate(target, pkt.Ether.SAddr) {
+		return
+	}
+	// Assigned address is defended, host which checks it finds it
+	// duplicate. Kernel answers for KNI interface.
+	if port.KNIName != "" || !port.ownsIPv6Address(target) || !neighborLimiter.allow(port, target) {
+		return
+	}
+	answerPacket, err := packet.NewPacket()
+	if err != nil {
+		common.LogFatal(common.Debug, err)
+	}
+	var dstMAC types.MACAddress
+	packet.CalculateIPv6BroadcastMACForDstMulticastIP(&dstMAC, allNodesMulticastAddr)
+	packet.InitICMPv6NeighborAdvertisementPacket(answerPacket, port.SrcMACAddress, dstMAC, target, allNodesMulticastAddr)
+	// Advertisement is not solicited because it is sent to all nodes
+	answerPacket.GetICMPNoCheck().Identifier = packet.SwapBytesUint16(packet.ICMPv6NDOverrideFlag)
+
+	vlan := pkt.GetVLAN()
+	if vlan != nil {
+		answerPacket.AddVLANTag(packet.SwapBytesUint16(vlan.TCI))
+	}
+
+	setIPv6ICMPChecksum(answerPacket, !NoCalculateChecksum, port.hwTXChecksum)
+	port.dumpPacket(answerPacket, DirSEND)
+	port.sendPacket(answerPacket)
+}
+
+// handleAddressAdvertisement checks that neighbor advertisement of
+// another host doesn't claim address of port. It returns true if
+// address belongs to port.
+func (port *ipPort) handleAddressAdvertisement(target types.IPv6Address, mac types.MACAddress) bool {
+	if mac == port.SrcMACAddress {
+		return true
+	}
+	if port.markDuplicate(target, mac) {
+		return true
+	}
+	if !port.ownsIPv6Address(target) {
+		return false
+	}
+	if neighborLimiter.allow(port, mac) {
+		port.addressConflict(target, mac, false)
+	}
+	return true
+}
+
+// sendDADNeighborSolicitation sends neighbor solicitation for
+// tentative address. It is sent from unspecified address without
+// source link layer address option as RFC 4862 requires.
+func (port *ipPort) sendDADNeighborSolicitation(addr types.IPv6Address) {
+	requestPacket, err := packet.NewPacket()
+	if err != nil {
+		common.LogFatal(common.Debug, err)
+	}
+	packet.InitEmptyIPv6ICMPPacket(requestPacket, packet.ICMPv6NeighborSolicitationMessageSize)
+
+	var multicastAddr types.IPv6Address
+	packet.CalculateIPv6MulticastAddrForDstIP(&multicastAddr, addr)
+	packet.CalculateIPv6BroadcastMACForDstMulticastIP(&requestPacket.Ether.DAddr, multicastAddr)
+	requestPacket.Ether.SAddr = port.SrcMACAddress
+
+	ipv6 := requestPacket.GetIPv6NoCheck()
+	ipv6.SrcAddr = zeroIPv6Addr
+	ipv6.DstAddr = multicastAddr
+
+	icmp := requestPacket.GetICMPNoCheck()
+	icmp.Type = types.ICMPv6NeighborSolicitation
+	icmp.Identifier = 0
+	icmp.SeqNum = 0
+	requestPacket.ParseL7(types.ICMPv6Number)
+	requestPacket.GetICMPv6NeighborSolicitationMessage().TargetAddr = addr
+
+	if port.Vlan != 0 {
+		requestPacket.AddVLANTag(port.Vlan)
+	}
+
+	setIPv6ICMPChecksum(requestPacket, !NoCalculateChecksum, port.hwTXChecksum)
+	port.dumpPacket(requestPacket, DirSEND)
+	port.sendPacket(requestPacket)
+}
+
+// checkDHCPv6Address checks address acquired from DHCPv6 server in
+// background. Address is used if it is unique and is declined
+// otherwise.
+func (port *ipPort) checkDHCPv6Address(oldaddr types.IPv6Address, oldsubnet string) {
+	addr := port.Subnet6.Addr
+	probe := port.startProbe(addr)
+	go func() {
+		if _, duplicate := port.runProbe(addr, probe); duplicate {
+			if oldsubnet != "" {
+				port.raiseDHCPAddressEvent(true, oldsubnet, "")
+			}
+			port.sendDHCPv6DeclineRequest()
+			return
+		}
+		port.dhcpv6AddressConfirmed(oldaddr, oldsubnet)
+	}()
+}
+
+// sendDHCPv6DeclineRequest tells server that acquired address is used
+// by another host.
+func (port *ipPort) sendDHCPv6DeclineRequest() {
+	port.newDHCPv6TransactionId()
+	port.composeAndSendDHCPv6Packet(layers.DHCPv6MsgTypeDecline, port.leaseDHCPv6Options())
+	port.Subnet6.addressAcquired = false
+	port.Subnet6.ds = dhcpv6State{
+		lastDHCPv6PacketTypeSent: layers.DHCPv6MsgTypeDecline,
+		released:                 !port.DAD.Retry,
+	}
+}
@@ -5,6 +5,8 @@
 package nat
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net"
@@ -21,6 +23,28 @@ import (
 type dhcpState struct {
 	lastDHCPPacketTypeSent layers.DHCPMsgType
 	dhcpTransactionId      uint32
+	// Set when lease renewal is requested while address is acquired
+	renewing bool
+	// Set when lease is released, no new leases are requested until
+	// client is restarted
+	released bool
+	// Current lease details
+	lease dhcpLease
+	// Lease from lease file which is requested instead of
+	// discovering a new one
+	cached         *savedDHCPLease
+	rebootAttempts int
+}
+
+// Lease details reported to GRPC clients.
+type dhcpLease struct {
+	server   net.IP
+	obtained time.Time
+	duration time.Duration
+	t1       time.Duration
+	t2       time.Duration
+	// First router from router option, nil if there is none
+	router net.IP
 }
 
 const (
@@ -67,6 +91,7 @@ var (
 )
 
 func StartDHCPClient() {
+	loadDHCPLeases()
 	go func() {
 		sendDHCPRequests()
 	}()
@@ -77,41 +102,44 @@ func sendDHCPRequests() {
 	for {
 		for i := range Natconfig.PortPairs {
 			pp := &Natconfig.PortPairs[i]
-
-			port := &pp.PublicPort
-			var err error
-			if !port.Subnet.addressAcquired {
-				port.sendDHCPDiscoverRequest()
-			} else if Natconfig.setKniIP && !port.Subnet.kniAddressSet {
-				err = port.setLinkIPv4KNIAddress(port.Subnet.Addr, port.Subnet.Mask, 0, 0, Natconfig.bringUpKniInterfaces)
-				port.Subnet.kniAddressSet = err == nil
-			}
-
-			if !port.Subnet6.addressAcquired {
-				err = port.setLinkIPv6KNIAddress(port.Subnet6.llAddr, SingleIPMask, zeroIPv6Addr, zeroIPv6Addr, Natconfig.bringUpKniInterfaces)
-				port.sendDHCPv6SolicitRequest()
-			} else if Natconfig.setKniIP && !port.Subnet6.kniAddressSet {
-				err = port.setLinkIPv6KNIAddress(port.Subnet6.Addr, port.Subnet6.Mask, zeroIPv6Addr, zeroIPv6Addr, Natconfig.bringUpKniInterfaces)
-				port.Subnet6.kniAddressSet = err == nil
-			}
-
-			port = &pp.PrivatePort
-			if !port.Subnet.addressAcquired {
-				port.sendDHCPDiscoverRequest()
-			} else if Natconfig.setKniIP && !port.Subnet.kniAddressSet {
-				err = port.setLinkIPv4KNIAddress(port.Subnet.Addr, port.Subnet.Mask, 0, 0, Natconfig.bringUpKniInterfaces)
-				port.Subnet.kniAddressSet = err == nil
+			if pp.takeReacquireRequest() {
+				pp.PublicPort.Subnet.ds.released = false
 			}
-
-			if !port.Subnet6.addressAcquired {
-				err = port.setLinkIPv6KNIAddress(port.Subnet6.llAddr, SingleIPMask, zeroIPv6Addr, zeroIPv6Addr, Natconfig.bringUpKniInterfaces)
-				port.sendDHCPv6SolicitRequest()
-			} else if Natconfig.setKniIP && !port.Subnet6.kniAddressSet {
-				err = port.setLinkIPv6KNIAddress(port.Subnet6.Addr, port.Subnet6.Mask, zeroIPv6Addr, zeroIPv6Addr, Natconfig.bringUpKniInterfaces)
-				port.Subnet6.kniAddressSet = err == nil
-			}
-			if err != nil {
-				fmt.Println(err)
+			for _, port := range []*ipPort{&pp.PublicPort, &pp.PrivatePort} {
+				var err error
+				switch {
+				case port.ipv4Disabled:
+					// Disabled family gets no address
+				case !port.Subnet.addressAcquired:
+					if port.Subnet.ds.cached != nil {
+						port.sendDHCPRebootRequest()
+					} else if !port.Subnet.ds.released {
+						port.sendDHCPDiscoverRequest()
+					}
+				case Natconfig.setKniIP && !port.Subnet.kniAddressSet:
+					err = port.setLinkIPv4KNIAddress(port.Subnet.Addr, port.Subnet.Mask, 0, 0, Natconfig.bringUpKniInterfaces)
+					port.Subnet.kniAddressSet = err == nil
+				}
+
+				switch {
+				case port.ipv6Disabled:
+					// Disabled family gets no address
+				case !port.Subnet6.addressAcquired:
+					if port.Subnet6.needDHCPv6() && !port.isTentative(port.Subnet6.Addr) {
+						err = port.setLinkIPv6KNIAddress(port.Subnet6.llAddr, SingleIPMask, zeroIPv6Addr, zeroIPv6Addr, Natconfig.bringUpKniInterfaces)
+						if port.Subnet6.ds.cached != nil {
+							port.sendDHCPv6RebootRequest()
+						} else {
+							port.sendDHCPv6SolicitRequest()
+						}
+					}
+				case Natconfig.setKniIP && !port.Subnet6.kniAddressSet:
+					err = port.setLinkIPv6KNIAddress(port.Subnet6.Addr, port.Subnet6.Mask, zeroIPv6Addr, zeroIPv6Addr, Natconfig.bringUpKniInterfaces)
+					port.Subnet6.kniAddressSet = err == nil
+				}
+				if err != nil {
+					fmt.Println(err)
+				}
 			}
 		}
 		time.Sleep(requestInterval)
@@ -141,6 +169,11 @@ func (port *ipPort) composeAndSendDHCPPacket(packetType layers.DHCPMsgType, opti
 	dhcp := dhcpRequestPacket
 	dhcp.Xid = port.Subnet.ds.dhcpTransactionId
 	dhcp.ClientHWAddr = hwa
+	// Address being released is specified in client address field
+	if packetType == layers.DHCPMsgTypeRelease {
+		a := types.IPv4ToBytes(port.Subnet.Addr)
+		dhcp.ClientIP = net.IP{a[3], a[2], a[1], a[0]}
+	}
 	options = append(options,
 		layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(packetType)}),
 		layers.NewDHCPOption(layers.DHCPOptHostname, []byte(Natconfig.HostName)))
@@ -177,14 +210,15 @@ func (port *ipPort) composeAndSendDHCPPacket(packetType layers.DHCPMsgType, opti
 		pkt.AddVLANTag(port.Vlan)
 	}
 
-	setIPv4UDPChecksum(pkt, !NoCalculateChecksum, !NoHWTXChecksum)
+	setIPv4UDPChecksum(pkt, !NoCalculateChecksum, port.hwTXChecksum)
 	port.dumpPacket(pkt, DirSEND)
-	pkt.SendPacket(port.Index)
+	port.sendPacket(pkt)
 
 	port.Subnet.ds.lastDHCPPacketTypeSent = packetType
 }
 
 func (port *ipPort) sendDHCPDiscoverRequest() {
+	port.startDHCPSpan(false, "discover")
 	port.Subnet.ds.dhcpTransactionId = rnd.Uint32()
 	port.composeAndSendDHCPPacket(layers.DHCPMsgTypeDiscover, dhcpOptions)
 }
@@ -195,8 +229,31 @@ func (port *ipPort) sendDHCPRequestRequest(serverIP, clientIP []byte) {
 		layers.NewDHCPOption(layers.DHCPOptRequestIP, clientIP)))
 }
 
+// sendDHCPRenewRequest asks server to extend current lease.
+func (port *ipPort) sendDHCPRenewRequest() {
+	a := types.IPv4ToBytes(port.Subnet.Addr)
+	port.Subnet.ds.dhcpTransactionId = rnd.Uint32()
+	port.Subnet.ds.renewing = true
+	port.startDHCPSpan(false, "renew")
+	port.sendDHCPRequestRequest(port.Subnet.ds.lease.server.To4(), []byte{a[3], a[2], a[1], a[0]})
+}
+
+// sendDHCPReleaseRequest releases current lease. Port has no address
+// after that until DHCP client is restarted.
+func (port *ipPort) sendDHCPReleaseRequest() {
+	port.composeAndSendDHCPPacket(layers.DHCPMsgTypeRelease, []layers.DHCPOption{
+		layers.NewDHCPOption(layers.DHCPOptServerID, port.Subnet.ds.lease.server.To4()),
+	})
+	port.raiseDHCPAddressEvent(false, port.Subnet.String(), "")
+	port.Subnet.addressAcquired = false
+	port.Subnet.ds = dhcpState{
+		released: true,
+	}
+	saveDHCPLeases()
+}
+
 func (port *ipPort) handleDHCP(pkt *packet.Packet) bool {
-	if port.Subnet.addressAcquired {
+	if port.Subnet.addressAcquired && !port.Subnet.ds.renewing {
 		// Port already has address, ignore this traffic
 		return false
 	}
@@ -206,6 +263,9 @@ func (port *ipPort) handleDHCP(pkt *packet.Packet) bool {
 		pkt.GetUDPNoCheck().SrcPort != packet.SwapBytesUint16(DHCPServerPort) {
 		return false
 	}
+	if !port.allowControlPlane(controlPlaneDHCP, packet.SwapBytesIPv4Addr(pkt.GetIPv4NoCheck().SrcAddr)) {
+		return true
+	}
 
 	var dhcp layers.DHCPv4
 	parser := gopacket.NewDecodingLayerParser(layers.LayerTypeDHCPv4, &dhcp)
@@ -232,6 +292,10 @@ func (port *ipPort) handleDHCP(pkt *packet.Packet) bool {
 		port.handleDHCPAck(pkt, &dhcp)
 	} else {
 		println("Warning! Received some bad response from DHCP server. Trying again with discover request.")
+		port.finishDHCPSpan(false, "", errors.New("Bad response from DHCP server"))
+		if port.Subnet.ds.renewing {
+			port.raiseDHCPAddressEvent(false, port.Subnet.String(), "")
+		}
 		port.Subnet.addressAcquired = false
 		port.Subnet.ds = dhcpState{}
 	}
@@ -246,15 +310,61 @@ func (port *ipPort) handleDHCPAck(pkt *packet.Packet, dhcp *layers.DHCPv4) {
 	maskOption := getDHCPOption(dhcp, layers.DHCPOptSubnetMask)
 	if maskOption == nil {
 		println("Warning! Received a DHCP response without subnet mask! Trying again with discover request.")
+		port.finishDHCPSpan(false, "", errors.New("DHCP response without subnet mask"))
 		port.Subnet.addressAcquired = false
 		port.Subnet.ds = dhcpState{}
 		return
 	}
+	var oldaddr, oldmask types.IPv4Address
+	oldsubnet := ""
+	if port.Subnet.ds.renewing {
+		oldaddr = port.Subnet.Addr
+		oldmask = port.Subnet.Mask
+		oldsubnet = port.Subnet.String()
+	}
 	port.Subnet.Addr, _ = convertIPv4(dhcp.YourClientIP.To4())
 	port.Subnet.Mask, _ = convertIPv4(maskOption.Data)
 	port.Subnet.addressAcquired = true
-	println("Successfully acquired IP address:", port.Subnet.String(), "on port", port.Index)
+	port.Subnet.ds.renewing = false
+	port.Subnet.ds.lease = getDHCPLease(dhcp)
+	port.Subnet.ds.cached = nil
+	println("Successfully acquired IP address:", port.Subnet.String(), "on port", port.logName())
+	port.finishDHCPSpan(false, port.Subnet.String(), nil)
+	saveDHCPLeases()
 
 	// Set address on KNI interface if present
-	port.setLinkIPv4KNIAddress(port.Subnet.Addr, port.Subnet.Mask, 0, 0, Natconfig.bringUpKniInterfaces)
+	if oldaddr != port.Subnet.Addr || oldmask != port.Subnet.Mask {
+		port.setLinkIPv4KNIAddress(port.Subnet.Addr, port.Subnet.Mask, oldaddr, oldmask, Natconfig.bringUpKniInterfaces)
+		port.raiseDHCPAddressEvent(false, oldsubnet, port.Subnet.String())
+	}
+}
+
+func getDHCPLease(dhcp *layers.DHCPv4) dhcpLease {
+	lease := dhcpLease{
+		server:   dhcp.NextServerIP,
+		obtained: time.Now(),
+	}
+	if o := getDHCPOption(dhcp, layers.DHCPOptServerID); o != nil && len(o.Data) == 4 {
+		lease.server = net.IP(append([]byte{}, o.Data...))
+	}
+	if o := getDHCPOption(dhcp, layers.DHCPOptRouter); o != nil && len(o.Data) >= 4 {
+		lease.router = net.IP(append([]byte{}, o.Data[:4]...))
+	}
+	seconds := func(opt layers.DHCPOpt) time.Duration {
+		if o := getDHCPOption(dhcp, opt); o != nil && len(o.Data) == 4 {
+			return time.Duration(binary.BigEndian.Uint32(o.Data)) * time.Second
+		}
+		return 0
+	}
+	lease.duration = seconds(layers.DHCPOptLeaseTime)
+	lease.t1 = seconds(layers.DHCPOptT1)
+	lease.t2 = seconds(layers.DHCPOptT2)
+	// Default values according to RFC 2131 4.4.5
+	if lease.t1 == 0 {
+		lease.t1 = lease.duration / 2
+	}
+	if lease.t2 == 0 {
+		lease.t2 = lease.duration * 7 / 8
+	}
+	return lease
 }
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"net"
 	"strings"
+	"time"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
@@ -21,6 +22,20 @@ import (
 type dhcpv6State struct {
 	lastDHCPv6PacketTypeSent layers.DHCPv6MsgType
 	dhcpv6TransactionId      [3]byte
+	// Set when lease renewal is requested while address is acquired
+	renewing bool
+	// Set when lease is released, no new leases are requested until
+	// client is restarted
+	released bool
+	// Current lease details, IANA and server ID options are saved
+	// to be used in renew and release requests
+	lease    dhcpLease
+	iana     DHCPv6IANA
+	serverID *layers.DHCPv6Option
+	// Lease from lease file which is requested instead of
+	// soliciting a new one
+	cached         *savedDHCPLease
+	rebootAttempts int
 }
 
 const (
@@ -116,9 +131,9 @@ func (port *ipPort) composeAndSendDHCPv6Packet(packetType layers.DHCPv6MsgType,
 		pkt.AddVLANTag(port.Vlan)
 	}
 
-	setIPv6UDPChecksum(pkt, !NoCalculateChecksum, !NoHWTXChecksum)
+	setIPv6UDPChecksum(pkt, !NoCalculateChecksum, port.hwTXChecksum)
 	port.dumpPacket(pkt, DirSEND)
-	pkt.SendPacket(port.Index)
+	port.sendPacket(pkt)
 
 	port.Subnet6.ds.lastDHCPv6PacketTypeSent = packetType
 }
@@ -159,12 +174,9 @@ func (port *ipPort) checkDHCPv6ServerAnswerAndGetIANA(dhcpv6 *layers.DHCPv6) *la
 }
 
 func (port *ipPort) sendDHCPv6SolicitRequest() {
+	port.startDHCPSpan(true, "solicit")
 	// Create new transaction ID
-	port.Subnet6.ds.dhcpv6TransactionId = [3]byte{
-		uint8(rnd.Uint32()),
-		uint8(rnd.Uint32()),
-		uint8(rnd.Uint32()),
-	}
+	port.newDHCPv6TransactionId()
 	iana := DHCPv6IANA{
 		IAID:    rnd.Uint32(),
 		Options: layers.DHCPv6Options{},
@@ -190,8 +202,48 @@ func (port *ipPort) handleDHCPv6Advertise(pkt *packet.Packet, dhcpv6 *layers.DHC
 	port.composeAndSendDHCPv6Packet(layers.DHCPv6MsgTypeRequest, options)
 }
 
+func (port *ipPort) newDHCPv6TransactionId() {
+	port.Subnet6.ds.dhcpv6TransactionId = [3]byte{
+		uint8(rnd.Uint32()),
+		uint8(rnd.Uint32()),
+		uint8(rnd.Uint32()),
+	}
+}
+
+func (port *ipPort) leaseDHCPv6Options() []layers.DHCPv6Option {
+	options := []layers.DHCPv6Option{
+		layers.NewDHCPv6Option(layers.DHCPv6OptIANA, port.Subnet6.ds.iana.Encode()),
+	}
+	if port.Subnet6.ds.serverID != nil {
+		options = append(options, *port.Subnet6.ds.serverID)
+	}
+	return options
+}
+
+// sendDHCPv6RenewRequest asks server to extend current lease.
+func (port *ipPort) sendDHCPv6RenewRequest() {
+	port.newDHCPv6TransactionId()
+	port.Subnet6.ds.renewing = true
+	port.startDHCPSpan(true, "renew")
+	port.composeAndSendDHCPv6Packet(layers.DHCPv6MsgTypeRenew, port.leaseDHCPv6Options())
+}
+
+// sendDHCPv6ReleaseRequest releases current lease. Port has no
+// address after that until DHCPv6 client is restarted.
+func (port *ipPort) sendDHCPv6ReleaseRequest() {
+	port.newDHCPv6TransactionId()
+	port.composeAndSendDHCPv6Packet(layers.DHCPv6MsgTypeRelease, port.leaseDHCPv6Options())
+	port.raiseDHCPAddressEvent(true, port.Subnet6.String(), "")
+	port.Subnet6.addressAcquired = false
+	port.Subnet6.ds = dhcpv6State{
+		lastDHCPv6PacketTypeSent: layers.DHCPv6MsgTypeRelease,
+		released:                 true,
+	}
+	saveDHCPLeases()
+}
+
 func (port *ipPort) handleDHCPv6(pkt *packet.Packet) bool {
-	if port.Subnet6.addressAcquired {
+	if port.Subnet6.addressAcquired && !port.Subnet6.ds.renewing {
 		// Port already has address, ignore this traffic
 		return false
 	}
@@ -201,6 +253,9 @@ func (port *ipPort) handleDHCPv6(pkt *packet.Packet) bool {
 		pkt.GetUDPNoCheck().SrcPort != packet.SwapBytesUint16(DHCPv6ServerPort) {
 		return false
 	}
+	if !port.allowControlPlane(controlPlaneDHCP, pkt.GetIPv6NoCheck().SrcAddr) {
+		return true
+	}
 
 	var dhcpv6 layers.DHCPv6
 	parser := gopacket.NewDecodingLayerParser(layers.LayerTypeDHCPv6, &dhcpv6)
@@ -213,12 +268,25 @@ func (port *ipPort) handleDHCPv6(pkt *packet.Packet) bool {
 		return false
 	}
 
+	// Address from previous reply is being checked for duplicates
+	if port.isTentative(port.Subnet6.Addr) {
+		return true
+	}
+
 	if port.Subnet6.ds.lastDHCPv6PacketTypeSent == layers.DHCPv6MsgTypeSolicit && dhcpv6.MsgType == layers.DHCPv6MsgTypeAdverstise {
 		port.handleDHCPv6Advertise(pkt, &dhcpv6)
-	} else if port.Subnet6.ds.lastDHCPv6PacketTypeSent == layers.DHCPv6MsgTypeRequest && dhcpv6.MsgType == layers.DHCPv6MsgTypeReply {
+	} else if (port.Subnet6.ds.lastDHCPv6PacketTypeSent == layers.DHCPv6MsgTypeRequest ||
+		port.Subnet6.ds.lastDHCPv6PacketTypeSent == layers.DHCPv6MsgTypeRenew) && dhcpv6.MsgType == layers.DHCPv6MsgTypeReply {
 		port.handleDHCPv6Reply(pkt, &dhcpv6)
+	} else if (port.Subnet6.ds.lastDHCPv6PacketTypeSent == layers.DHCPv6MsgTypeRelease ||
+		port.Subnet6.ds.lastDHCPv6PacketTypeSent == layers.DHCPv6MsgTypeDecline) && dhcpv6.MsgType == layers.DHCPv6MsgTypeReply {
+		// Server confirmed address release or decline, nothing to do
 	} else {
 		println("Warning! Received some bad response from DHCPv6 server", dhcpv6.MsgType.String())
+		port.finishDHCPSpan(true, "", errors.New("Bad response from DHCPv6 server"))
+		if port.Subnet6.ds.renewing {
+			port.raiseDHCPAddressEvent(true, port.Subnet6.String(), "")
+		}
 		port.Subnet6.addressAcquired = false
 		port.Subnet6.ds = dhcpv6State{}
 	}
@@ -261,14 +329,61 @@ func (port *ipPort) handleDHCPv6Reply(pkt *packet.Packet, dhcpv6 *layers.DHCPv6)
 		return
 	}
 
+	oldaddr := zeroIPv6Addr
+	oldsubnet := ""
+	if port.Subnet6.ds.renewing {
+		oldaddr = port.Subnet6.Addr
+		oldsubnet = port.Subnet6.String()
+	}
 	copy(port.Subnet6.Addr[:], ia.Address.To16())
 	packet.CalculateIPv6MulticastAddrForDstIP(&port.Subnet6.multicastAddr, port.Subnet6.Addr)
 	port.Subnet6.Mask = SingleIPMask
+	port.Subnet6.ds.renewing = false
+	port.Subnet6.ds.cached = nil
+
+	// Save lease details. IANA options are decoded from packet
+	// buffer so they are copied.
+	srcAddr := pkt.GetIPv6NoCheck().SrcAddr
+	port.Subnet6.ds.lease = dhcpLease{
+		server:   net.IP(append([]byte{}, srcAddr[:]...)),
+		obtained: time.Now(),
+		duration: time.Duration(ia.ValidLifetime) * time.Second,
+		t1:       time.Duration(iana.T1) * time.Second,
+		t2:       time.Duration(iana.T2) * time.Second,
+	}
+	port.Subnet6.ds.iana = DHCPv6IANA{
+		IAID: iana.IAID,
+		Options: layers.DHCPv6Options{
+			layers.NewDHCPv6Option(layers.DHCPv6OptIAAddr, append([]byte{}, addressOption.Data...)),
+		},
+	}
+	if serverID := getDHCPv6Option(dhcpv6.Options, layers.DHCPv6OptServerID); serverID != nil {
+		id := layers.NewDHCPv6Option(layers.DHCPv6OptServerID, append([]byte{}, serverID.Data...))
+		port.Subnet6.ds.serverID = &id
+	}
+
+	// New address is not used until it is found unique
+	if oldaddr != port.Subnet6.Addr && !port.DAD.Disable {
+		port.Subnet6.addressAcquired = false
+		port.checkDHCPv6Address(oldaddr, oldsubnet)
+		return
+	}
+	port.dhcpv6AddressConfirmed(oldaddr, oldsubnet)
+}
+
+// dhcpv6AddressConfirmed starts using address acquired from DHCPv6
+// server.
+func (port *ipPort) dhcpv6AddressConfirmed(oldaddr types.IPv6Address, oldsubnet string) {
 	port.Subnet6.addressAcquired = true
-	println("Successfully acquired IP address:", port.Subnet6.String(), "on port", port.Index)
+	println("Successfully acquired IP address:", port.Subnet6.String(), "on port", port.logName())
+	port.finishDHCPSpan(true, port.Subnet6.String(), nil)
+	saveDHCPLeases()
 
 	// Set address on KNI interface if present
-	port.setLinkIPv6KNIAddress(port.Subnet6.Addr, port.Subnet6.Mask, zeroIPv6Addr, zeroIPv6Addr, Natconfig.bringUpKniInterfaces)
+	if oldaddr != port.Subnet6.Addr {
+		port.setLinkIPv6KNIAddress(port.Subnet6.Addr, port.Subnet6.Mask, oldaddr, SingleIPMask, Natconfig.bringUpKniInterfaces)
+		port.raiseDHCPAddressEvent(true, oldsubnet, port.Subnet6.String())
+	}
 }
 
 type DHCPv6FQDNFlags byte
@@ -0,0 +1,208 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket/layers"
+)
+
+// Cached lease is requested this many times before client falls back
+// to discovering a new lease
+const dhcpRebootAttempts = 2
+
+// DHCP lease saved to lease file so that restarted NAT requests the
+// same address again instead of discovering a new one.
+type savedDHCPLease struct {
+	Port    uint16    `json:"port"`
+	MAC     string    `json:"mac"`
+	IPv6    bool      `json:"ipv6"`
+	Address string    `json:"address"`
+	Server  string    `json:"server"`
+	Expires time.Time `json:"expires"`
+	// Identity association and server DUID of DHCPv6 lease
+	IAID     uint32 `json:"iaid,omitempty"`
+	ServerID []byte `json:"server-id,omitempty"`
+}
+
+// Lease file is written by DHCP handlers of different ports
+var dhcpLeaseFileMutex sync.Mutex
+
+// saveDHCPLeases writes current leases of all ports to lease file if it
+// is configured. Cached leases which are not confirmed yet are kept.
+// It is called when lease is acquired or released.
+func saveDHCPLeases() {
+	if Natconfig.DHCPLeaseFile == "" {
+		return
+	}
+	dhcpLeaseFileMutex.Lock()
+	defer dhcpLeaseFileMutex.Unlock()
+
+	leases := []savedDHCPLease{}
+	for i := range Natconfig.PortPairs {
+		pp := &Natconfig.PortPairs[i]
+		for _, port := range []*ipPort{&pp.PublicPort, &pp.PrivatePort} {
+			if l := port.Subnet.savedLease(port); l != nil {
+				leases = append(leases, *l)
+			}
+			if l := port.Subnet6.savedLease(port); l != nil {
+				leases = append(leases, *l)
+			}
+		}
+	}
+	data, err := json.Marshal(leases)
+	if err != nil {
+		println("Warning! Failed to encode DHCP leases:", err.Error())
+		return
+	}
+	if err := writeFileAtomically(Natconfig.DHCPLeaseFile, data); err != nil {
+		println("Warning! Failed to write DHCP lease file", Natconfig.DHCPLeaseFile, ":", err.Error())
+	}
+}
+
+// savedLease returns lease of subnet or cached lease which it
+// requests, nil if address is static.
+func (subnet *ipv4Subnet) savedLease(port *ipPort) *savedDHCPLease {
+	if !subnet.addressAcquired {
+		return subnet.ds.cached
+	}
+	lease := &subnet.ds.lease
+	// Lease time is mandatory in acknowledgements, so there is no
+	// lease when it is not known
+	if lease.duration == 0 {
+		return nil
+	}
+	return &savedDHCPLease{
+		Port:    port.Index,
+		MAC:     port.SrcMACAddress.String(),
+		Address: StringIPv4Int(uint32(subnet.Addr)),
+		Server:  lease.server.String(),
+		Expires: lease.obtained.Add(lease.duration),
+	}
+}
+
+func (subnet *ipv6Subnet) savedLease(port *ipPort) *savedDHCPLease {
+	if !subnet.addressAcquired {
+		return subnet.ds.cached
+	}
+	lease := &subnet.ds.lease
+	if lease.duration == 0 {
+		return nil
+	}
+	l := &savedDHCPLease{
+		Port:    port.Index,
+		MAC:     port.SrcMACAddress.String(),
+		IPv6:    true,
+		Address: net.IP(subnet.Addr[:]).String(),
+		Server:  lease.server.String(),
+		Expires: lease.obtained.Add(lease.duration),
+		IAID:    subnet.ds.iana.IAID,
+	}
+	if subnet.ds.serverID != nil {
+		l.ServerID = subnet.ds.serverID.Data
+	}
+	return l
+}
+
+// loadDHCPLeases reads lease file and makes DHCP clients of ports
+// request cached addresses. Leases of other network cards and expired
+// leases are ignored. Missing file is not an error.
+func loadDHCPLeases() {
+	if Natconfig.DHCPLeaseFile == "" {
+		return
+	}
+	data, err := ioutil.ReadFile(Natconfig.DHCPLeaseFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			println("Warning! Failed to read DHCP lease file", Natconfig.DHCPLeaseFile, ":", err.Error())
+		}
+		return
+	}
+	var leases []savedDHCPLease
+	if err := json.Unmarshal(data, &leases); err != nil {
+		println("Warning! Failed to parse DHCP lease file", Natconfig.DHCPLeaseFile, ":", err.Error())
+		return
+	}
+
+	for i := range leases {
+		l := &leases[i]
+		if time.Now().After(l.Expires) {
+			continue
+		}
+		ip := net.ParseIP(l.Address)
+		if ip == nil {
+			continue
+		}
+		for j := range Natconfig.PortPairs {
+			pp := &Natconfig.PortPairs[j]
+			for _, port := range []*ipPort{&pp.PublicPort, &pp.PrivatePort} {
+				if port.Index != l.Port || port.SrcMACAddress.String() != l.MAC {
+					continue
+				}
+				if !l.IPv6 && !port.ipv4Disabled && !port.Subnet.addressAcquired && ip.To4() != nil {
+					port.Subnet.ds.cached = l
+					println("Requesting cached DHCP address", l.Address, "on port", port.logName())
+				}
+				if l.IPv6 && !port.ipv6Disabled && !port.Subnet6.addressAcquired && ip.To4() == nil {
+					port.Subnet6.ds.cached = l
+					port.Subnet6.ds.iana = DHCPv6IANA{
+						IAID: l.IAID,
+						Options: layers.DHCPv6Options{
+							layers.NewDHCPv6Option(layers.DHCPv6OptIAAddr, (&DHCPv6IAAddress{Address: ip.To16()}).Encode()),
+						},
+					}
+					if l.ServerID != nil {
+						id := layers.NewDHCPv6Option(layers.DHCPv6OptServerID, l.ServerID)
+						port.Subnet6.ds.serverID = &id
+					}
+					println("Requesting cached DHCPv6 address", l.Address, "on port", port.logName())
+				}
+			}
+		}
+	}
+}
+
+// sendDHCPRebootRequest requests cached address without server
+// identifier like client in INIT-REBOOT state of RFC 2131 does. Server
+// either acknowledges address or rejects it, and client falls back to
+// discovery when cached lease is rejected, expires or gets no answer.
+func (port *ipPort) sendDHCPRebootRequest() {
+	ds := &port.Subnet.ds
+	if ds.rebootAttempts >= dhcpRebootAttempts || time.Now().After(ds.cached.Expires) {
+		println("Cached DHCP address", ds.cached.Address, "was not confirmed on port", port.logName())
+		ds.cached = nil
+		ds.rebootAttempts = 0
+		port.sendDHCPDiscoverRequest()
+		return
+	}
+	ds.rebootAttempts++
+	port.startDHCPSpan(false, "reboot")
+	ds.dhcpTransactionId = rnd.Uint32()
+	port.composeAndSendDHCPPacket(layers.DHCPMsgTypeRequest, append(dhcpOptions,
+		layers.NewDHCPOption(layers.DHCPOptRequestIP, net.ParseIP(ds.cached.Address).To4())))
+}
+
+// sendDHCPv6RebootRequest requests cached address from server which
+// leased it. Reply of server is handled like reply to request after
+// advertise, so address is checked for duplicates before it is used.
+func (port *ipPort) sendDHCPv6RebootRequest() {
+	ds := &port.Subnet6.ds
+	if ds.rebootAttempts >= dhcpRebootAttempts || time.Now().After(ds.cached.Expires) {
+		println("Cached DHCPv6 address", ds.cached.Address, "was not confirmed on port", port.logName())
+		port.Subnet6.ds = dhcpv6State{}
+		port.sendDHCPv6SolicitRequest()
+		return
+	}
+	ds.rebootAttempts++
+	port.startDHCPSpan(true, "reboot")
+	port.newDHCPv6TransactionId()
+	port.composeAndSendDHCPv6Packet(layers.DHCPv6MsgTypeRequest, port.leaseDHCPv6Options())
+}
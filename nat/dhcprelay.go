@@ -0,0 +1,256 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"bytes"
+	"errors"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+const (
+	// Relay agent information option of RFC 3046 and its sub-options
+	dhcpOptRelayAgentInfo layers.DHCPOpt = 82
+	relayAgentCircuitID                  = 1
+	relayAgentRemoteID                   = 2
+	// Link selection sub-option of RFC 3527 tells server subnet of
+	// clients because gateway address is public port address
+	relayAgentLinkSelection = 5
+	// Relayed requests which passed more relays are dropped, as
+	// recommended by RFC 1542
+	dhcpRelayMaxHops = 16
+	// Broadcast flag of DHCP message
+	dhcpBroadcastFlag = 0x8000
+)
+
+// DHCP relay agent of private port. Requests of private clients are
+// sent from public port address to DHCP server with relay agent
+// information, replies of server are sent back to clients.
+type dhcpRelayConfig struct {
+	// IPv4 address of DHCP server reachable through public port,
+	// empty value disables relay agent
+	Server string `json:"server"`
+	// Circuit ID sent to server, name of private port by default
+	CircuitID string `json:"circuit-id"`
+	// Remote ID sent to server, host name by default
+	RemoteID  string `json:"remote-id"`
+	server    types.IPv4Address
+	agentInfo []byte
+}
+
+func (cfg *dhcpRelayConfig) enabled() bool {
+	return cfg.Server != ""
+}
+
+// check parses server address and prepares relay agent information
+// option which is added to requests.
+func (cfg *dhcpRelayConfig) check(pp *portPair) error {
+	if !cfg.enabled() {
+		return nil
+	}
+	ip := net.ParseIP(cfg.Server).To4()
+	if ip == nil {
+		return errors.New("Bad DHCP relay server address: " + cfg.Server)
+	}
+	cfg.server, _ = convertIPv4(ip)
+	private := &pp.PrivatePort
+	if !private.Subnet.addressAcquired {
+		return errors.New("DHCP relay requires static address of private port " + private.ifName())
+	}
+	if private.Subnet.checkAddrWithingSubnet(cfg.server) {
+		return errors.New("DHCP relay server " + cfg.Server + " should be reachable through public port, not within private subnet " +
+			private.Subnet.String())
+	}
+
+	circuit := cfg.CircuitID
+	if circuit == "" {
+		circuit = private.ifName()
+	}
+	remote := cfg.RemoteID
+	if remote == "" {
+		remote = Natconfig.HostName
+	}
+	if len(circuit) > 255 || len(remote) > 255 {
+		return errors.New("DHCP relay circuit ID and remote ID should not be longer than 255 bytes")
+	}
+	subnet := types.IPv4ToBytes(private.Subnet.Addr & private.Subnet.Mask)
+	info := []byte{relayAgentCircuitID, byte(len(circuit))}
+	info = append(info, circuit...)
+	if remote != "" {
+		info = append(info, relayAgentRemoteID, byte(len(remote)))
+		info = append(info, remote...)
+	}
+	info = append(info, relayAgentLinkSelection, 4, subnet[3], subnet[2], subnet[1], subnet[0])
+	if len(info) > 255 {
+		return errors.New("DHCP relay agent information is longer than 255 bytes")
+	}
+	cfg.agentInfo = info
+	return nil
+}
+
+// decodeDHCP decodes DHCP message of UDP packet. Fields of message
+// point into packet buffer.
+func decodeDHCP(pkt *packet.Packet) (*layers.DHCPv4, bool) {
+	var dhcp layers.DHCPv4
+	parser := gopacket.NewDecodingLayerParser(layers.LayerTypeDHCPv4, &dhcp)
+	payload, ok := pkt.GetPacketPayload()
+	if !ok || len(payload) < 240 {
+		return nil, false
+	}
+	decoded := []gopacket.LayerType{}
+	err := parser.DecodeLayers(payload, &decoded)
+	if err != nil || len(decoded) != 1 {
+		return nil, false
+	}
+	return &dhcp, true
+}
+
+// relayDHCPRequest sends DHCP request of private client to relay
+// server. It returns false if packet is not a request which should be
+// relayed. Requests which cannot be relayed are dropped, clients
+// retransmit them.
+func (pp *portPair) relayDHCPRequest(pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, pktUDP *packet.UDPHdr) bool {
+	cfg := &pp.DHCPRelay
+	if !cfg.enabled() || pktUDP.DstPort != packet.SwapBytesUint16(DHCPServerPort) ||
+		pktUDP.SrcPort != packet.SwapBytesUint16(DHCPClientPort) {
+		return false
+	}
+	dst := packet.SwapBytesIPv4Addr(pktIPv4.DstAddr)
+	private := &pp.PrivatePort
+	if dst != BroadcastIPv4 && dst != private.Subnet.Addr && dst != private.Subnet.broadcastAddr() {
+		return false
+	}
+	public := &pp.PublicPort
+	if !public.Subnet.addressAcquired {
+		return true
+	}
+
+	dhcp, ok := decodeDHCP(pkt)
+	if !ok || dhcp.Operation != layers.DHCPOpRequest || dhcp.HardwareOpts >= dhcpRelayMaxHops {
+		return true
+	}
+	// Messages of other relays and messages which already have agent
+	// information, e.g. forged by client, are not relayed
+	if !dhcp.RelayAgentIP.Equal(net.IPv4zero) || getDHCPOption(dhcp, dhcpOptRelayAgentInfo) != nil {
+		return true
+	}
+
+	mac, found := public.getMACForIPv4(cfg.server)
+	if !found {
+		return true
+	}
+	a := types.IPv4ToBytes(public.Subnet.Addr)
+	dhcp.RelayAgentIP = net.IP{a[3], a[2], a[1], a[0]}
+	// Hops field is called HardwareOpts by gopacket
+	dhcp.HardwareOpts++
+	dhcp.Options = append(append(layers.DHCPOptions{}, dhcp.Options...),
+		layers.NewDHCPOption(dhcpOptRelayAgentInfo, cfg.agentInfo))
+	public.sendDHCPRelayPacket(dhcp, mac, cfg.server, DHCPServerPort)
+	return true
+}
+
+// relayDHCPReply sends reply of relay server to private client. It
+// returns false if packet is not a reply of server.
+func (pp *portPair) relayDHCPReply(pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, pktUDP *packet.UDPHdr) bool {
+	cfg := &pp.DHCPRelay
+	public := &pp.PublicPort
+	if !cfg.enabled() || pktUDP.DstPort != packet.SwapBytesUint16(DHCPServerPort) ||
+		packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr) != cfg.server ||
+		packet.SwapBytesIPv4Addr(pktIPv4.DstAddr) != public.Subnet.Addr {
+		return false
+	}
+
+	dhcp, ok := decodeDHCP(pkt)
+	if !ok || dhcp.Operation != layers.DHCPOpReply || len(dhcp.ClientHWAddr) != types.EtherAddrLen {
+		return true
+	}
+	// Server echoes agent information, replies without it or with
+	// information of another agent are not for this port pair
+	info := getDHCPOption(dhcp, dhcpOptRelayAgentInfo)
+	if info == nil || !bytes.Equal(info.Data, cfg.agentInfo) {
+		return true
+	}
+	options := layers.DHCPOptions{}
+	for _, o := range dhcp.Options {
+		if o.Type != dhcpOptRelayAgentInfo {
+			options = append(options, o)
+		}
+	}
+	dhcp.Options = options
+
+	// Clients which have address get unicast replies, other clients
+	// get them to offered address and hardware address unless they
+	// asked for broadcast
+	private := &pp.PrivatePort
+	var mac types.MACAddress
+	copy(mac[:], dhcp.ClientHWAddr)
+	// Acknowledged address identifies client
+	if msgType := getDHCPOption(dhcp, layers.DHCPOptMessageType); private.identities != nil && msgType != nil &&
+		len(msgType.Data) == 1 && msgType.Data[0] == byte(layers.DHCPMsgTypeAck) {
+		if ip := dhcp.YourClientIP.To4(); ip != nil {
+			addr, _ := convertIPv4(ip)
+			private.identities.learn(addr, mac)
+		}
+	}
+	var dst types.IPv4Address
+	if ip := dhcp.ClientIP.To4(); ip != nil && !ip.Equal(net.IPv4zero) {
+		dst, _ = convertIPv4(ip)
+	} else if ip := dhcp.YourClientIP.To4(); dhcp.Flags&dhcpBroadcastFlag == 0 && ip != nil && !ip.Equal(net.IPv4zero) {
+		dst, _ = convertIPv4(ip)
+	} else {
+		mac = BroadcastMAC
+		dst = BroadcastIPv4
+	}
+	private.sendDHCPRelayPacket(dhcp, mac, dst, DHCPClientPort)
+	return true
+}
+
+// sendDHCPRelayPacket sends relayed DHCP message from DHCP server
+// port of port address.
+func (port *ipPort) sendDHCPRelayPacket(dhcp *layers.DHCPv4, mac types.MACAddress, dst types.IPv4Address, dstPort uint16) {
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{
+		FixLengths: true,
+	}
+	if err := gopacket.SerializeLayers(buf, opts, dhcp); err != nil {
+		return
+	}
+
+	pkt, err := packet.NewPacket()
+	if err != nil {
+		common.LogWarning(common.Debug, "Failed to allocate DHCP relay packet:", err)
+		return
+	}
+	payloadBuffer := buf.Bytes()
+	packet.InitEmptyIPv4UDPPacket(pkt, uint(len(payloadBuffer)))
+	pkt.Ether.SAddr = port.SrcMACAddress
+	pkt.Ether.DAddr = mac
+
+	ipv4 := pkt.GetIPv4NoCheck()
+	ipv4.SrcAddr = packet.SwapBytesIPv4Addr(port.Subnet.Addr)
+	ipv4.DstAddr = packet.SwapBytesIPv4Addr(dst)
+
+	udp := pkt.GetUDPNoCheck()
+	udp.SrcPort = packet.SwapBytesUint16(DHCPServerPort)
+	udp.DstPort = packet.SwapBytesUint16(dstPort)
+
+	payload, _ := pkt.GetPacketPayload()
+	copy(payload, payloadBuffer)
+
+	if port.Vlan != 0 {
+		pkt.AddVLANTag(port.Vlan)
+	}
+
+	setIPv4UDPChecksum(pkt, !NoCalculateChecksum, port.hwTXChecksum)
+	port.dumpPacket(pkt, DirSEND)
+	port.sendPacket(pkt)
+}
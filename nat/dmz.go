@@ -0,0 +1,111 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+// Private hosts which receive inbound traffic of public port
+// addresses which matches no session or forwarded port.
+type dmzHost struct {
+	addr4    types.IPv4Address
+	addr6    types.IPv6Address
+	hasAddr4 bool
+	hasAddr6 bool
+}
+
+// checkDMZ parses DMZ host addresses and checks that they belong to
+// private port subnets.
+func (pp *portPair) checkDMZ() error {
+	if pp.DMZHost != "" {
+		ip := net.ParseIP(pp.DMZHost).To4()
+		if ip == nil {
+			return errors.New("Bad DMZ host IPv4 address: " + pp.DMZHost)
+		}
+		if pp.DisableIPv4 {
+			return errors.New("DMZ host " + pp.DMZHost + " requires IPv4 which is disabled")
+		}
+		pp.dmz.addr4, _ = convertIPv4(ip)
+		if !pp.PrivatePort.Subnet.checkAddrWithingSubnet(pp.dmz.addr4) {
+			return errors.New("DMZ host " + pp.DMZHost +
+				" should be within subnet " + pp.PrivatePort.Subnet.String())
+		}
+		pp.dmz.hasAddr4 = true
+	}
+	if pp.DMZHost6 != "" {
+		ip := net.ParseIP(pp.DMZHost6)
+		if ip == nil || ip.To4() != nil {
+			return errors.New("Bad DMZ host IPv6 address: " + pp.DMZHost6)
+		}
+		if pp.DisableIPv6 {
+			return errors.New("DMZ host " + pp.DMZHost6 + " requires IPv6 which is disabled")
+		}
+		copy(pp.dmz.addr6[:], ip.To16())
+		if !pp.PrivatePort.Subnet6.checkAddrWithingSubnet(pp.dmz.addr6) {
+			return errors.New("DMZ host " + pp.DMZHost6 +
+				" should be within subnet " + pp.PrivatePort.Subnet6.String())
+		}
+		pp.dmz.hasAddr6 = true
+	}
+	return nil
+}
+
+// openDMZSession creates session between public port address and DMZ
+// host for TCP or UDP packet which matches no session. Session uses
+// the same port on both sides, so DMZ host serves any port which is
+// not forwarded and not used by other sessions. It returns private
+// entry of new session.
+func (pp *portPair) openDMZSession(ipv6 bool, protocol uint8, pubKey interface{}) (interface{}, bool) {
+	if (protocol != types.TCPNumber && protocol != types.UDPNumber) || pp.refusesNewSessions() {
+		return nil, false
+	}
+	var privEntry interface{}
+	var portNumber uint16
+	if ipv6 {
+		key := pubKey.(Tuple6)
+		if !pp.dmz.hasAddr6 || !pp.PublicPort.Subnet6.addressAcquired || key.addr != pp.PublicPort.Subnet6.Addr {
+			return nil, false
+		}
+		privEntry = Tuple6{addr: pp.dmz.addr6, port: key.port}
+		portNumber = key.port
+	} else {
+		key := pubKey.(Tuple)
+		if !pp.dmz.hasAddr4 || !pp.PublicPort.Subnet.addressAcquired || key.addr != pp.PublicPort.Subnet.Addr {
+			return nil, false
+		}
+		privEntry = Tuple{addr: pp.dmz.addr4, port: key.port}
+		portNumber = key.port
+	}
+	if portNumber >= portEnd || pp.isLocalPort(portNumber) {
+		return nil, false
+	}
+
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+	pm := pp.getPublicPortPortmap(ipv6, protocol)
+	// Public port may be used by session of VLAN or temporary address
+	// and private port by session which DMZ host started itself
+	if pm[portNumber].static || time.Since(pm[portNumber].lastused) <= connectionTimeout {
+		return nil, false
+	}
+	if _, found := pp.PrivatePort.translationTable[protocol].Load(privEntry); found {
+		return nil, false
+	}
+	pp.deleteOldConnection(ipv6, protocol, int(portNumber))
+	pm[portNumber] = portMapEntry{
+		created:  time.Now(),
+		lastused: time.Now(),
+	}
+	pp.PublicPort.translationTable[protocol].Store(pubKey, privEntry)
+	pp.PrivatePort.translationTable[protocol].Store(privEntry, pubKey)
+	atomic.AddUint64(&pp.PublicPort.stats.sessions, 1)
+	return privEntry, true
+}
@@ -0,0 +1,140 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/intel-go/nff-go/packet"
+)
+
+// Number of DSCP code points, DSCP is 6 upper bits of IPv4 type of
+// service and IPv6 traffic class, ECN bits are never changed
+const dscpValues = 64
+
+// What happens to DSCP field of translated packets.
+type dscpAction int
+
+const (
+	dscpPreserve dscpAction = iota
+	dscpClear
+	// Values listed in remap table are replaced, others are preserved
+	dscpRemap
+)
+
+var dscpActionLookup = map[string]dscpAction{
+	"preserve": dscpPreserve,
+	"clear":    dscpClear,
+	"remap":    dscpRemap,
+}
+
+// DSCP policy for one direction of translation.
+type dscpRule struct {
+	Action dscpAction `json:"action"`
+	// Original DSCP value to new DSCP value, e.g. {"46": 34}
+	Remap map[string]uint8 `json:"remap"`
+	table [dscpValues]uint8
+}
+
+// Port pair DSCP policies of egress (private to public) and ingress
+// (public to private) translation.
+type dscpPolicy struct {
+	Egress  dscpRule `json:"egress"`
+	Ingress dscpRule `json:"ingress"`
+}
+
+// UnmarshalJSON parses DSCP action.
+func (out *dscpAction) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	result, ok := dscpActionLookup[s]
+	if !ok {
+		return errors.New("Bad DSCP action: " + s)
+	}
+
+	*out = result
+	return nil
+}
+
+// String returns action name as it is used in config file.
+func (action dscpAction) String() string {
+	for name, a := range dscpActionLookup {
+		if a == action {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// MarshalJSON writes action name as it is used in config file.
+func (action dscpAction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(action.String())
+}
+
+func (policy *dscpPolicy) check() error {
+	if err := policy.Egress.check("egress"); err != nil {
+		return err
+	}
+	return policy.Ingress.check("ingress")
+}
+
+// check builds translation table of DSCP values.
+func (rule *dscpRule) check(direction string) error {
+	if rule.Action != dscpRemap {
+		if len(rule.Remap) != 0 {
+			return fmt.Errorf("DSCP %s remap table requires remap action", direction)
+		}
+		return nil
+	}
+	if len(rule.Remap) == 0 {
+		return fmt.Errorf("DSCP %s remap action requires remap table", direction)
+	}
+
+	for i := range rule.table {
+		rule.table[i] = uint8(i)
+	}
+	for from, to := range rule.Remap {
+		v, err := strconv.ParseUint(from, 10, 8)
+		if err != nil || v >= dscpValues {
+			return fmt.Errorf("Bad DSCP %s remap value %s, should be between 0 and 63", direction, from)
+		}
+		if to >= dscpValues {
+			return fmt.Errorf("Bad DSCP %s remap value %d, should be between 0 and 63", direction, to)
+		}
+		rule.table[v] = to
+	}
+	return nil
+}
+
+func (rule *dscpRule) translate(dscp uint8) uint8 {
+	if rule.Action == dscpClear {
+		return 0
+	}
+	return rule.table[dscp]
+}
+
+// apply changes DSCP of IPv4 or IPv6 header. IPv4 header checksum
+// has to be updated after that.
+func (rule *dscpRule) apply(pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) {
+	if rule.Action == dscpPreserve {
+		return
+	}
+	if pktIPv4 != nil {
+		tos := pktIPv4.TypeOfService
+		pktIPv4.TypeOfService = rule.translate(tos>>2)<<2 | tos&0x3
+	} else {
+		// Traffic class is between version and flow label
+		vtcFlow := packet.SwapBytesUint32(pktIPv6.VtcFlow)
+		dscp := uint8(vtcFlow >> 22 & 0x3f)
+		vtcFlow = vtcFlow&^(0x3f<<22) | uint32(rule.translate(dscp))<<22
+		pktIPv6.VtcFlow = packet.SwapBytesUint32(vtcFlow)
+	}
+}
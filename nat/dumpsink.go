@@ -0,0 +1,256 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/packet"
+)
+
+const (
+	dumpSinkQueueSize   = 4096
+	dumpSinkDialTimeout = 10 * time.Second
+)
+
+// Packet captured for remote dump sinks.
+type dumpedPacket struct {
+	port      uint16
+	dir       uint
+	timestamp time.Time
+	data      []byte
+}
+
+// dumpSink receives copies of dumped packets. Packets are queued
+// without blocking packet processing, they are dropped when the
+// queue is full.
+type dumpSink struct {
+	name    string
+	dirs    [DirKNI + 1]bool
+	ports   map[uint16]bool
+	packets chan dumpedPacket
+	dropped uint64
+	done    chan struct{}
+	once    sync.Once
+	// Closed when connected sink finished writing
+	stopped chan struct{}
+	// NAT instance whose control API connected sink
+	instance *natInstance
+}
+
+var (
+	// Active remote dump sinks, a []*dumpSink slice which is
+	// replaced every time sinks are added or removed
+	remoteDumpSinks atomic.Value
+	// Serializes modifications of remote dump sinks list
+	remoteDumpSinksMutex sync.Mutex
+	// Remote dump sinks which are connected by NAT to collectors
+	remoteDumpConnections = map[string]*dumpSink{}
+)
+
+func init() {
+	remoteDumpSinks.Store([]*dumpSink{})
+}
+
+func newDumpSink(name string, dirs [DirKNI + 1]bool, ports []uint16) *dumpSink {
+	sink := &dumpSink{
+		name:    name,
+		dirs:    dirs,
+		packets: make(chan dumpedPacket, dumpSinkQueueSize),
+		done:    make(chan struct{}),
+	}
+	if len(ports) != 0 {
+		sink.ports = map[uint16]bool{}
+		for _, p := range ports {
+			sink.ports[p] = true
+		}
+	}
+	return sink
+}
+
+func addDumpSink(sink *dumpSink) {
+	remoteDumpSinksMutex.Lock()
+	defer remoteDumpSinksMutex.Unlock()
+	sinks := remoteDumpSinks.Load().([]*dumpSink)
+	newSinks := append(append([]*dumpSink{}, sinks...), sink)
+	remoteDumpSinks.Store(newSinks)
+}
+
+// removeDumpSink stops sending packets to a sink. It is safe to call
+// it several times.
+func removeDumpSink(sink *dumpSink) {
+	remoteDumpSinksMutex.Lock()
+	defer remoteDumpSinksMutex.Unlock()
+	sinks := remoteDumpSinks.Load().([]*dumpSink)
+	newSinks := []*dumpSink{}
+	for _, s := range sinks {
+		if s != sink {
+			newSinks = append(newSinks, s)
+		}
+	}
+	remoteDumpSinks.Store(newSinks)
+	if remoteDumpConnections[sink.name] == sink {
+		delete(remoteDumpConnections, sink.name)
+	}
+	sink.once.Do(func() {
+		close(sink.done)
+	})
+}
+
+// dumpPacketRemote queues packet copy to all sinks interested in it.
+func (port *ipPort) dumpPacketRemote(pkt *packet.Packet, dir uint) {
+	sinks := remoteDumpSinks.Load().([]*dumpSink)
+	if len(sinks) == 0 {
+		return
+	}
+	var dp *dumpedPacket
+	for _, s := range sinks {
+		if !s.dirs[dir] || (s.ports != nil && !s.ports[port.Index]) {
+			continue
+		}
+		if dp == nil {
+			// Raw bytes point into packet buffer which is reused
+			// after packet is sent, so they are copied
+			dp = &dumpedPacket{
+				port:      port.Index,
+				dir:       dir,
+				timestamp: time.Now(),
+				data:      append([]byte{}, pkt.GetRawPacketBytes()...),
+			}
+		}
+		select {
+		case s.packets <- *dp:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+}
+
+// connectDumpSink connects to remote collector and starts sending
+// packets to it in pcap format. All dumped packets are written
+// into a single pcap stream.
+func connectDumpSink(address string, useTLS bool, caCert []byte, insecure bool, dirs [DirKNI + 1]bool, ports []uint16, instance *natInstance) error {
+	remoteDumpSinksMutex.Lock()
+	_, exists := remoteDumpConnections[address]
+	remoteDumpSinksMutex.Unlock()
+	if exists {
+		return fmt.Errorf("Dump sink %s is already connected", address)
+	}
+
+	var conn net.Conn
+	var err error
+	if useTLS {
+		config := &tls.Config{
+			InsecureSkipVerify: insecure,
+		}
+		if len(caCert) != 0 {
+			config.RootCAs = x509.NewCertPool()
+			if !config.RootCAs.AppendCertsFromPEM(caCert) {
+				return errors.New("Failed to parse CA certificate")
+			}
+		}
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: dumpSinkDialTimeout}, "tcp", address, config)
+	} else {
+		conn, err = net.DialTimeout("tcp", address, dumpSinkDialTimeout)
+	}
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(conn)
+	if err := packet.WritePcapGlobalHdr(w); err != nil {
+		conn.Close()
+		return err
+	}
+
+	sink := newDumpSink(address, dirs, ports)
+	sink.stopped = make(chan struct{})
+	sink.instance = instance
+	remoteDumpSinksMutex.Lock()
+	if _, exists := remoteDumpConnections[address]; exists {
+		remoteDumpSinksMutex.Unlock()
+		conn.Close()
+		return fmt.Errorf("Dump sink %s is already connected", address)
+	}
+	remoteDumpConnections[address] = sink
+	remoteDumpSinksMutex.Unlock()
+	addDumpSink(sink)
+
+	go func() {
+		defer close(sink.stopped)
+		defer conn.Close()
+		defer removeDumpSink(sink)
+		for {
+			select {
+			case <-sink.done:
+				w.Flush()
+				return
+			case dp := <-sink.packets:
+				if err := writePcapRecord(w, &dp); err != nil {
+					common.LogWarning(common.No, "Failed to write dump to", address, ":", err)
+					return
+				}
+				// Flush when there are no more packets to send
+				if len(sink.packets) == 0 {
+					if err := w.Flush(); err != nil {
+						common.LogWarning(common.No, "Failed to write dump to", address, ":", err)
+						return
+					}
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// disconnectDumpSink disconnects remote collector. Control API of
+// NAT instance may disconnect only sinks which it connected.
+func disconnectDumpSink(address string, instance *natInstance) (uint64, error) {
+	remoteDumpSinksMutex.Lock()
+	sink, exists := remoteDumpConnections[address]
+	remoteDumpSinksMutex.Unlock()
+	if !exists || (instance != nil && sink.instance != instance) {
+		return 0, fmt.Errorf("Dump sink %s is not connected", address)
+	}
+	removeDumpSink(sink)
+	return atomic.LoadUint64(&sink.dropped), nil
+}
+
+func writePcapRecord(w *bufio.Writer, dp *dumpedPacket) error {
+	hdr := packet.PcapRecHdr{
+		TsSec:   uint32(dp.timestamp.Unix()),
+		TsUsec:  uint32(dp.timestamp.Nanosecond() / 1000),
+		InclLen: uint32(len(dp.data)),
+		OrigLen: uint32(len(dp.data)),
+	}
+	if err := binary.Write(w, binary.LittleEndian, &hdr); err != nil {
+		return err
+	}
+	_, err := w.Write(dp.data)
+	return err
+}
+
+// closeAllDumpSinks flushes and closes all remote dump sinks.
+func closeAllDumpSinks() {
+	for _, s := range remoteDumpSinks.Load().([]*dumpSink) {
+		removeDumpSink(s)
+		if s.stopped != nil {
+			select {
+			case <-s.stopped:
+			case <-time.After(time.Second):
+			}
+		}
+	}
+}
@@ -0,0 +1,328 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/flow"
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+const (
+	// Maximum number of private hosts tracked by one egress policer.
+	// When it is reached, idle hosts are forgotten.
+	maxEgressPolicerHosts = 65536
+	// Burst which is used when it is not configured, in time of
+	// transmission at configured rate
+	policerDefaultBurstTime = 100 * time.Millisecond
+	// Burst should fit at least one full size frame
+	policerMinBurst = 1518
+)
+
+// Rate class of egress policer. Rate is in kilobits per second, burst
+// is in bytes, zero rate means no limit.
+type egressPolicerClass struct {
+	Rate  uint64 `json:"rate"`
+	Burst uint64 `json:"burst"`
+}
+
+// Private host with its own rate class.
+type egressPolicerHost struct {
+	Address string `json:"address"`
+	Rate    uint64 `json:"rate"`
+	Burst   uint64 `json:"burst"`
+}
+
+// Egress policer of port pair which is configured by egress-policer
+// option. Packets sent by public port have to fit into global class
+// and packets of private hosts have to fit into class of host when
+// they are translated. Classes are policed independently, so packet
+// dropped by global class is still counted by class of its host.
+type egressPolicerConfig struct {
+	Rate  uint64 `json:"rate"`
+	Burst uint64 `json:"burst"`
+	// Class of every private host which is not listed in hosts
+	HostRate  uint64              `json:"host-rate"`
+	HostBurst uint64              `json:"host-burst"`
+	Hosts     []egressPolicerHost `json:"hosts"`
+	// Host classes by types.IPv4Address or types.IPv6Address
+	hostClasses map[interface{}]egressPolicerClass
+}
+
+// egressPolicer keeps rate policers of global and host classes. It is
+// called from packet handlers running on several cores, so policers
+// are updated atomically and current classes are replaced as a whole.
+type egressPolicer struct {
+	// Packets and bytes which exceeded rate and were dropped
+	droppedPackets uint64
+	droppedBytes   uint64
+	// Current *egressPolicerState
+	state atomic.Value
+	// Nonzero while idle hosts are being forgotten
+	forgetting int32
+}
+
+// Classes of egress policer and policers of private hosts which sent
+// packets since classes were set.
+type egressPolicerState struct {
+	config egressPolicerConfig
+	// Nil if global rate is not limited
+	global *ratePolicer
+	// *ratePolicer by types.IPv4Address or types.IPv6Address
+	hosts      sync.Map
+	hostsCount int32
+}
+
+// Lock-free single rate policer. Instead of number of tokens it keeps
+// theoretical arrival time of the next packet in nanoseconds as GCRA
+// of ITU-T I.371 does. This is equivalent to token bucket, but state
+// fits into one word which is updated atomically. Time of zero means
+// full bucket.
+type ratePolicer struct {
+	tat int64
+	// Nanoseconds of transmission of one byte and of whole burst at
+	// policed rate
+	byteTime  float64
+	burstTime int64
+}
+
+func (cfg *egressPolicerConfig) enabled() bool {
+	return cfg.Rate != 0 || cfg.HostRate != 0 || len(cfg.Hosts) != 0
+}
+
+// check parses host addresses and checks bursts.
+func (cfg *egressPolicerConfig) check() error {
+	if err := (&egressPolicerClass{cfg.Rate, cfg.Burst}).check("egress policer"); err != nil {
+		return err
+	}
+	if err := (&egressPolicerClass{cfg.HostRate, cfg.HostBurst}).check("egress policer host"); err != nil {
+		return err
+	}
+	cfg.hostClasses = map[interface{}]egressPolicerClass{}
+	for i := range cfg.Hosts {
+		h := &cfg.Hosts[i]
+		ip := net.ParseIP(h.Address)
+		if ip == nil {
+			return fmt.Errorf("Bad egress policer host address %s", h.Address)
+		}
+		class := egressPolicerClass{h.Rate, h.Burst}
+		if err := class.check("egress policer host " + h.Address); err != nil {
+			return err
+		}
+		var key interface{}
+		if ip4 := ip.To4(); ip4 != nil {
+			key, _ = convertIPv4(ip4)
+		} else {
+			var addr types.IPv6Address
+			copy(addr[:], ip.To16())
+			key = addr
+		}
+		if _, ok := cfg.hostClasses[key]; ok {
+			return fmt.Errorf("Egress policer host %s is listed more than once", h.Address)
+		}
+		cfg.hostClasses[key] = class
+	}
+	return nil
+}
+
+func (class *egressPolicerClass) check(name string) error {
+	if class.Burst != 0 && class.Burst < policerMinBurst {
+		return fmt.Errorf("Burst of %s should be at least %d bytes", name, policerMinBurst)
+	}
+	return nil
+}
+
+// bytesRate converts rate in kilobits per second to bytes per
+// second.
+func bytesRate(rate uint64) float64 {
+	return float64(rate) * 1000 / 8
+}
+
+// burstBytes returns configured burst or default burst for rate.
+func burstBytes(rate, burst uint64) float64 {
+	if burst != 0 {
+		return float64(burst)
+	}
+	b := bytesRate(rate) * policerDefaultBurstTime.Seconds()
+	if b < policerMinBurst {
+		return policerMinBurst
+	}
+	return b
+}
+
+func (class egressPolicerClass) bytesRate() float64 {
+	return bytesRate(class.Rate)
+}
+
+func (class egressPolicerClass) burstBytes() float64 {
+	return burstBytes(class.Rate, class.Burst)
+}
+
+func newRatePolicer(rate, burst uint64) *ratePolicer {
+	byteTime := float64(time.Second) / bytesRate(rate)
+	return &ratePolicer{
+		byteTime:  byteTime,
+		burstTime: int64(burstBytes(rate, burst) * byteTime),
+	}
+}
+
+// conform takes tokens for packet of specified length at now
+// nanoseconds and returns false if bucket doesn't have enough of them.
+func (rp *ratePolicer) conform(now int64, length uint) bool {
+	cost := int64(float64(length) * rp.byteTime)
+	for {
+		tat := atomic.LoadInt64(&rp.tat)
+		next := tat
+		if next < now {
+			next = now
+		}
+		next += cost
+		if next-now > rp.burstTime {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&rp.tat, tat, next) {
+			return true
+		}
+	}
+}
+
+// idle returns true if bucket of policer is full at now nanoseconds.
+func (rp *ratePolicer) idle(now int64) bool {
+	return atomic.LoadInt64(&rp.tat) <= now
+}
+
+func newEgressPolicer(cfg egressPolicerConfig) *egressPolicer {
+	policer := &egressPolicer{}
+	policer.setConfig(cfg)
+	return policer
+}
+
+// setConfig replaces policer classes. All buckets start full.
+func (policer *egressPolicer) setConfig(cfg egressPolicerConfig) {
+	state := &egressPolicerState{config: cfg}
+	if cfg.Rate != 0 {
+		state.global = newRatePolicer(cfg.Rate, cfg.Burst)
+	}
+	policer.state.Store(state)
+}
+
+// getConfig returns current policer classes.
+func (policer *egressPolicer) getConfig() egressPolicerConfig {
+	return policer.state.Load().(*egressPolicerState).config
+}
+
+// hostClass returns rate class of a private host.
+func (state *egressPolicerState) hostClass(host interface{}) egressPolicerClass {
+	if class, ok := state.config.hostClasses[host]; ok {
+		return class
+	}
+	return egressPolicerClass{state.config.HostRate, state.config.HostBurst}
+}
+
+// allowGlobal returns true if packet of specified length sent by
+// public port fits into global class.
+func (policer *egressPolicer) allowGlobal(length uint) bool {
+	state := policer.state.Load().(*egressPolicerState)
+	if state.global == nil || state.global.conform(time.Now().UnixNano(), length) {
+		return true
+	}
+	return policer.drop(length)
+}
+
+// allowHost returns true if packet of specified length sent by private
+// host fits into class of the host. Host is either types.IPv4Address
+// or types.IPv6Address.
+func (policer *egressPolicer) allowHost(host interface{}, length uint) bool {
+	state := policer.state.Load().(*egressPolicerState)
+	now := time.Now().UnixNano()
+	var rp *ratePolicer
+	if v, ok := state.hosts.Load(host); ok {
+		rp = v.(*ratePolicer)
+	} else {
+		class := state.hostClass(host)
+		if class.Rate == 0 {
+			return true
+		}
+		if atomic.LoadInt32(&state.hostsCount) >= maxEgressPolicerHosts {
+			policer.forgetIdleHosts(state, now)
+		}
+		v, loaded := state.hosts.LoadOrStore(host, newRatePolicer(class.Rate, class.Burst))
+		if !loaded {
+			atomic.AddInt32(&state.hostsCount, 1)
+		}
+		rp = v.(*ratePolicer)
+	}
+	if rp.conform(now, length) {
+		return true
+	}
+	return policer.drop(length)
+}
+
+func (policer *egressPolicer) drop(length uint) bool {
+	atomic.AddUint64(&policer.droppedPackets, 1)
+	atomic.AddUint64(&policer.droppedBytes, uint64(length))
+	return false
+}
+
+// forgetIdleHosts removes hosts which buckets are already full, so
+// forgetting them doesn't change policing. If there are no such hosts,
+// all hosts are forgotten. Only one handler forgets hosts at a time,
+// others don't wait for it.
+func (policer *egressPolicer) forgetIdleHosts(state *egressPolicerState, now int64) {
+	if !atomic.CompareAndSwapInt32(&policer.forgetting, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&policer.forgetting, 0)
+
+	count := int32(0)
+	state.hosts.Range(func(k, v interface{}) bool {
+		if v.(*ratePolicer).idle(now) {
+			state.hosts.Delete(k)
+		} else {
+			count++
+		}
+		return true
+	})
+	if count >= maxEgressPolicerHosts {
+		state.hosts.Range(func(k, v interface{}) bool {
+			state.hosts.Delete(k)
+			return true
+		})
+		count = 0
+	}
+	atomic.StoreInt32(&state.hostsCount, count)
+}
+
+// droppedCounters returns numbers of packets and bytes dropped by
+// policer.
+func (policer *egressPolicer) droppedCounters() (uint64, uint64) {
+	if policer == nil {
+		return 0, 0
+	}
+	return atomic.LoadUint64(&policer.droppedPackets), atomic.LoadUint64(&policer.droppedBytes)
+}
+
+// egressPolicing is a handler which drops packets sent to public port
+// when they exceed global rate. Host classes are applied by
+// translation which knows private host of packet.
+func egressPolicing(pkt *packet.Packet, ctx flow.UserContext) bool {
+	pp := &Natconfig.PortPairs[ctx.(pairIndex).index]
+	// ARP and other non IP packets are never policed
+	pktIPv4, pktIPv6, _ := pkt.ParseAllKnownL3CheckVLAN()
+	if pktIPv4 == nil && pktIPv6 == nil {
+		return true
+	}
+	if pp.egressPolicer.allowGlobal(pkt.GetPacketLen()) {
+		return true
+	}
+	pp.PublicPort.dumpPacket(pkt, DirDROP)
+	return false
+}
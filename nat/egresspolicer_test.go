@@ -0,0 +1,148 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+func TestRatePolicer(t *testing.T) {
+	// 1000 bytes per second with burst of 2000 bytes
+	rp := newRatePolicer(8, 2000)
+	now := int64(time.Hour)
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		length  uint
+		conform bool
+	}{
+		{"full bucket", 0, 1500, true},
+		{"rest of burst", 0, 500, true},
+		{"empty bucket", 0, 1, false},
+		{"partly refilled bucket", 500 * time.Millisecond, 500, true},
+		{"packet larger than tokens", 500 * time.Millisecond, 600, false},
+		{"dropped packet takes no tokens", 0, 500, true},
+		{"bucket is refilled up to burst", time.Minute, 2000, true},
+		{"over burst", time.Minute, 2001, false},
+	}
+	for _, tt := range tests {
+		now += int64(tt.elapsed)
+		if got := rp.conform(now, tt.length); got != tt.conform {
+			t.Errorf("%s: packet of %d bytes conforms %v, expected %v", tt.name, tt.length, got, tt.conform)
+		}
+	}
+	rp.conform(now, 1000)
+	if rp.idle(now) {
+		t.Errorf("Policer is idle after packet")
+	}
+	if !rp.idle(now + int64(2*time.Second)) {
+		t.Errorf("Policer is not idle after burst time")
+	}
+}
+
+func TestRatePolicerConcurrent(t *testing.T) {
+	rp := newRatePolicer(8, 100000)
+	now := int64(time.Hour)
+	var passed uint64
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if rp.conform(now, 100) {
+					atomic.AddUint64(&passed, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if passed != 1000 {
+		t.Errorf("%d packets of burst passed, expected 1000", passed)
+	}
+}
+
+func TestEgressPolicerClasses(t *testing.T) {
+	cfg := egressPolicerConfig{
+		HostRate:  8,
+		HostBurst: 2000,
+		Hosts: []egressPolicerHost{
+			{Address: "192.168.1.10", Rate: 16, Burst: 4000},
+			{Address: "fd00::a", Rate: 8, Burst: 3000},
+		},
+	}
+	if err := cfg.check(); err != nil {
+		t.Fatal(err)
+	}
+	policer := newEgressPolicer(cfg)
+	listed := hostIPv4(192, 168, 1, 10)
+	other := hostIPv4(192, 168, 1, 11)
+	var listed6 types.IPv6Address
+	copy(listed6[:], testPrivate6)
+
+	tests := []struct {
+		name    string
+		host    interface{}
+		length  uint
+		allowed bool
+	}{
+		{"host of default class", other, 2000, true},
+		{"default class is exceeded", other, 1500, false},
+		{"listed host", listed, 4000, true},
+		{"class of listed host is exceeded", listed, 1500, false},
+		{"listed IPv6 host", listed6, 3000, true},
+		{"class of listed IPv6 host is exceeded", listed6, 1500, false},
+	}
+	for _, tt := range tests {
+		if got := policer.allowHost(tt.host, tt.length); got != tt.allowed {
+			t.Errorf("%s: packet is allowed %v, expected %v", tt.name, got, tt.allowed)
+		}
+	}
+	if !policer.allowGlobal(100000) {
+		t.Errorf("Packet is dropped without global rate")
+	}
+	if packets, bytes := policer.droppedCounters(); packets != 3 || bytes != 4500 {
+		t.Errorf("%d packets and %d bytes are dropped, expected 3 and 4500", packets, bytes)
+	}
+
+	// New classes start with full buckets
+	cfg.Rate, cfg.Burst = 8, 2000
+	cfg.HostRate = 0
+	policer.setConfig(cfg)
+	if !policer.allowHost(listed, 4000) || !policer.allowHost(other, 100000) {
+		t.Errorf("Host classes are not replaced")
+	}
+	if !policer.allowGlobal(2000) || policer.allowGlobal(1) {
+		t.Errorf("Global class is not applied")
+	}
+}
+
+func TestEgressPolicerForgetIdleHosts(t *testing.T) {
+	policer := newEgressPolicer(egressPolicerConfig{HostRate: 8})
+	state := policer.state.Load().(*egressPolicerState)
+	now := time.Now().UnixNano()
+	busy := newRatePolicer(8, 0)
+	busy.conform(now, 1000)
+	state.hosts.Store(hostIPv4(192, 168, 1, 1), busy)
+	state.hosts.Store(hostIPv4(192, 168, 1, 2), newRatePolicer(8, 0))
+	state.hostsCount = 2
+
+	policer.forgetIdleHosts(state, now)
+	if _, ok := state.hosts.Load(hostIPv4(192, 168, 1, 1)); !ok {
+		t.Errorf("Busy host is forgotten")
+	}
+	if _, ok := state.hosts.Load(hostIPv4(192, 168, 1, 2)); ok {
+		t.Errorf("Idle host is not forgotten")
+	}
+	if state.hostsCount != 1 {
+		t.Errorf("%d hosts are counted, expected 1", state.hostsCount)
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/flow"
+	"github.com/intel-go/nff-go/packet"
+
+	upd "github.com/intel-go/nff-go-nat/updatecfg"
+)
+
+// Sources of flows which are merged before they are sent by port.
+const (
+	// Packets translated by port pair
+	egressTranslated = iota
+	// Packets sent by KNI interface of port
+	egressKNI
+	// Packets of private KNI host translated to public port
+	egressKNITranslated
+	egressClasses
+)
+
+var egressClassNames = [egressClasses]string{"translated", "kni", "kni-translated"}
+
+// Weighted scheduling of flows which are merged before port pair
+// ports send them, so that heavy traffic of KNI interfaces doesn't
+// starve translated traffic. Rates are link rates of ports in kilobits
+// per second, zero rate disables scheduling of port. Burst is in
+// bytes.
+type egressSchedulingConfig struct {
+	PublicRate  uint64        `json:"public-rate"`
+	PrivateRate uint64        `json:"private-rate"`
+	Burst       uint64        `json:"burst"`
+	Weights     egressWeights `json:"weights"`
+}
+
+// Relative weights of sources, zero weight means 1.
+type egressWeights struct {
+	Translated    uint64 `json:"translated"`
+	KNI           uint64 `json:"kni"`
+	KNITranslated uint64 `json:"kni-translated"`
+}
+
+// Guaranteed share of one source and its counters.
+type egressClass struct {
+	rate, burst float64
+	bucket      tokenBucket
+	// Packets sent within share, packets sent with rate which other
+	// sources didn't use and dropped packets
+	guaranteed, borrowed, droppedPackets, droppedBytes uint64
+}
+
+// egressScheduler gives every source of port a share of link rate
+// proportional to its weight. Source may exceed its share while link is
+// not busy with other sources. Scheduler is called from handlers of
+// merged flows running on several cores, so it is protected by a
+// mutex.
+type egressScheduler struct {
+	mutex       sync.Mutex
+	rate, burst float64
+	link        tokenBucket
+	sources     [egressClasses]bool
+	classes     [egressClasses]egressClass
+}
+
+// Handler context of merged flow.
+type egressSource struct {
+	index  int
+	public bool
+	class  int
+}
+
+func (es egressSource) Copy() interface{} {
+	return egressSource{
+		index:  es.index,
+		public: es.public,
+		class:  es.class,
+	}
+}
+
+func (es egressSource) Delete() {
+}
+
+func (w *egressWeights) weights() [egressClasses]uint64 {
+	return [egressClasses]uint64{w.Translated, w.KNI, w.KNITranslated}
+}
+
+// check sets default weights and checks that scheduled ports have
+// more than one source.
+func (cfg *egressSchedulingConfig) check(pp *portPair) error {
+	if err := (&egressPolicerClass{cfg.PublicRate, cfg.Burst}).check("egress scheduling"); err != nil {
+		return err
+	}
+	if cfg.PublicRate != 0 && pp.PublicPort.KNIName == "" && (pp.PrivatePort.KNIName == "" || pp.kniSNAT == nil) {
+		return fmt.Errorf("Egress scheduling of port %s requires KNI interface or KNI source NAT", pp.PublicPort.logName())
+	}
+	if cfg.PrivateRate != 0 && pp.PrivatePort.KNIName == "" {
+		return fmt.Errorf("Egress scheduling of port %s requires KNI interface", pp.PrivatePort.logName())
+	}
+	for _, w := range []*uint64{&cfg.Weights.Translated, &cfg.Weights.KNI, &cfg.Weights.KNITranslated} {
+		if *w == 0 {
+			*w = 1
+		}
+	}
+	return nil
+}
+
+// newEgressScheduler divides rate between sources which port has. All
+// buckets start full.
+func newEgressScheduler(cfg *egressSchedulingConfig, rate uint64, sources [egressClasses]bool) *egressScheduler {
+	now := time.Now()
+	s := &egressScheduler{
+		rate:    bytesRate(rate),
+		burst:   burstBytes(rate, cfg.Burst),
+		sources: sources,
+	}
+	s.link = tokenBucket{tokens: s.burst, last: now}
+	weights := cfg.Weights.weights()
+	total := uint64(0)
+	for c := range sources {
+		if sources[c] {
+			total += weights[c]
+		}
+	}
+	for c := range sources {
+		if !sources[c] {
+			continue
+		}
+		share := float64(weights[c]) / float64(total)
+		class := &s.classes[c]
+		class.rate = s.rate * share
+		class.burst = s.burst * share
+		if class.burst < policerMinBurst {
+			class.burst = policerMinBurst
+		}
+		class.bucket = tokenBucket{tokens: class.burst, last: now}
+	}
+	return s
+}
+
+// allow returns true if packet of specified length from source class
+// may be sent. Packet within share of class is always sent and takes
+// tokens of link too, which may become negative, so that other classes
+// cannot borrow rate which is already used. Packet over share is sent
+// only if link has enough tokens.
+func (s *egressScheduler) allow(class int, length uint) bool {
+	now := time.Now()
+	amount := float64(length)
+	c := &s.classes[class]
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.link.refill(now, s.rate, s.burst)
+	c.bucket.refill(now, c.rate, c.burst)
+	if c.bucket.tokens >= amount {
+		c.bucket.tokens -= amount
+		s.link.tokens -= amount
+		if s.link.tokens < -s.burst {
+			s.link.tokens = -s.burst
+		}
+		atomic.AddUint64(&c.guaranteed, 1)
+		return true
+	}
+	if s.link.tokens >= amount {
+		s.link.tokens -= amount
+		atomic.AddUint64(&c.borrowed, 1)
+		return true
+	}
+	atomic.AddUint64(&c.droppedPackets, 1)
+	atomic.AddUint64(&c.droppedBytes, uint64(length))
+	return false
+}
+
+// counters returns counters of every source of port.
+func (s *egressScheduler) counters() []*upd.Counter {
+	counters := []*upd.Counter{}
+	for i := range s.classes {
+		if !s.sources[i] {
+			continue
+		}
+		c := &s.classes[i]
+		name := "scheduler-" + egressClassNames[i]
+		counters = append(counters,
+			&upd.Counter{Name: name + "-guaranteed-packets", Value: atomic.LoadUint64(&c.guaranteed)},
+			&upd.Counter{Name: name + "-borrowed-packets", Value: atomic.LoadUint64(&c.borrowed)},
+			&upd.Counter{Name: name + "-drop-packets", Value: atomic.LoadUint64(&c.droppedPackets)},
+			&upd.Counter{Name: name + "-drop-bytes", Value: atomic.LoadUint64(&c.droppedBytes)})
+	}
+	return counters
+}
+
+// setEgressScheduling creates scheduler of port if its rate is
+// configured and installs scheduling handlers on flows which are
+// merged before port sends them. Flows are indexed by source class,
+// nil flow means that port has no such source.
+func (port *ipPort) setEgressScheduling(pair int, rate uint64, flows [egressClasses]*flow.Flow) {
+	if rate == 0 {
+		return
+	}
+	var sources [egressClasses]bool
+	for c := range flows {
+		sources[c] = flows[c] != nil
+	}
+	port.scheduler = newEgressScheduler(&Natconfig.PortPairs[pair].EgressScheduling, rate, sources)
+	for c := range flows {
+		if flows[c] == nil {
+			continue
+		}
+		context := egressSource{
+			index:  pair,
+			public: port.Type == iPUBLIC,
+			class:  c,
+		}
+		flow.CheckFatal(flow.SetHandlerDrop(flows[c], egressScheduling, context))
+	}
+}
+
+// egressScheduling is a handler which drops packets of merged flow
+// when its source exceeds its share of busy link.
+func egressScheduling(pkt *packet.Packet, ctx flow.UserContext) bool {
+	source := ctx.(egressSource)
+	pp := &Natconfig.PortPairs[source.index]
+	port := &pp.PrivatePort
+	if source.public {
+		port = &pp.PublicPort
+	}
+	if port.scheduler.allow(source.class, pkt.GetPacketLen()) {
+		return true
+	}
+	port.dumpPacket(pkt, DirDROP)
+	return false
+}
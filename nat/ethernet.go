@@ -0,0 +1,224 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+/*
+#include <string.h>
+#include <rte_ethdev.h>
+
+// Link speeds are taken from device configuration when port is
+// started. NFF-Go configures ports without them, so configuration is
+// updated in place and port is restarted. Queues set up by NFF-Go and
+// promiscuous mode are kept across restart.
+static int nat_set_link_speeds(uint16_t port, uint32_t speeds) {
+	rte_eth_dev_stop(port);
+	rte_eth_devices[port].data->dev_conf.link_speeds = speeds;
+	return rte_eth_dev_start(port);
+}
+
+static int nat_set_flow_ctrl(uint16_t port, int mode) {
+	struct rte_eth_fc_conf fc;
+	memset(&fc, 0, sizeof(fc));
+	int rc = rte_eth_dev_flow_ctrl_get(port, &fc);
+	if (rc != 0)
+		return rc;
+	fc.mode = mode;
+	return rte_eth_dev_flow_ctrl_set(port, &fc);
+}
+
+static uint32_t nat_speed_capa(uint16_t port) {
+	struct rte_eth_dev_info dev_info;
+	memset(&dev_info, 0, sizeof(dev_info));
+	rte_eth_dev_info_get(port, &dev_info);
+	return dev_info.speed_capa;
+}
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Pause frames which network card port sends and honors.
+type flowControlMode int
+
+const (
+	// Flow control is left as driver configures it
+	flowControlDefault flowControlMode = iota
+	flowControlNone
+	// Port honors received pause frames
+	flowControlRX
+	// Port sends pause frames
+	flowControlTX
+	flowControlFull
+)
+
+var flowControlModeLookup = map[string]flowControlMode{
+	"default": flowControlDefault,
+	"none":    flowControlNone,
+	"rx":      flowControlRX,
+	"tx":      flowControlTX,
+	"full":    flowControlFull,
+}
+
+// Ethernet settings of network card port. Zero values keep driver
+// defaults.
+type ethernetConfig struct {
+	// Link speed in Mbps, zero means to autonegotiate any speed
+	Speed uint32 `json:"speed"`
+	// Half duplex is possible only at 10 and 100 Mbps
+	HalfDuplex bool `json:"half-duplex"`
+	// Force speed instead of advertising only it
+	DisableAutonegotiation bool            `json:"disable-autonegotiation"`
+	FlowControl            flowControlMode `json:"flow-control"`
+	// NFF-Go enables promiscuous mode on all ports. With it disabled
+	// port receives only unicast packets for its own MAC address,
+	// broadcast and multicast packets.
+	DisablePromiscuous bool `json:"disable-promiscuous"`
+}
+
+// UnmarshalJSON parses flow control mode.
+func (out *flowControlMode) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	result, ok := flowControlModeLookup[s]
+	if !ok {
+		return errors.New("Bad flow control mode: " + s)
+	}
+
+	*out = result
+	return nil
+}
+
+// String returns flow control mode as it is used in config file.
+func (mode flowControlMode) String() string {
+	for name, a := range flowControlModeLookup {
+		if a == mode {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// MarshalJSON writes flow control mode as it is used in config file.
+func (mode flowControlMode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(mode.String())
+}
+
+// linkSpeeds returns DPDK link speeds flags for configured speed.
+func (cfg *ethernetConfig) linkSpeeds() (uint32, bool) {
+	var speed uint32
+	switch cfg.Speed {
+	case 10:
+		speed = C.ETH_LINK_SPEED_10M
+		if cfg.HalfDuplex {
+			speed = C.ETH_LINK_SPEED_10M_HD
+		}
+	case 100:
+		speed = C.ETH_LINK_SPEED_100M
+		if cfg.HalfDuplex {
+			speed = C.ETH_LINK_SPEED_100M_HD
+		}
+	case 1000:
+		speed = C.ETH_LINK_SPEED_1G
+	case 2500:
+		speed = C.ETH_LINK_SPEED_2_5G
+	case 5000:
+		speed = C.ETH_LINK_SPEED_5G
+	case 10000:
+		speed = C.ETH_LINK_SPEED_10G
+	case 20000:
+		speed = C.ETH_LINK_SPEED_20G
+	case 25000:
+		speed = C.ETH_LINK_SPEED_25G
+	case 40000:
+		speed = C.ETH_LINK_SPEED_40G
+	case 50000:
+		speed = C.ETH_LINK_SPEED_50G
+	case 56000:
+		speed = C.ETH_LINK_SPEED_56G
+	case 100000:
+		speed = C.ETH_LINK_SPEED_100G
+	default:
+		return 0, false
+	}
+	if cfg.DisableAutonegotiation {
+		speed |= C.ETH_LINK_SPEED_FIXED
+	}
+	return speed, true
+}
+
+func (cfg *ethernetConfig) check(port uint16) error {
+	if cfg.Speed == 0 {
+		if cfg.HalfDuplex || cfg.DisableAutonegotiation {
+			return fmt.Errorf("Port %d ethernet half-duplex and disable-autonegotiation options require speed", port)
+		}
+		return nil
+	}
+	if _, ok := cfg.linkSpeeds(); !ok {
+		return fmt.Errorf("Port %d ethernet speed %d Mbps is not supported", port, cfg.Speed)
+	}
+	if cfg.HalfDuplex && cfg.Speed != 10 && cfg.Speed != 100 {
+		return fmt.Errorf("Port %d half duplex is possible only at 10 and 100 Mbps", port)
+	}
+	return nil
+}
+
+// ApplyEthernetSettings configures link speed, flow control and
+// promiscuous mode of ports as specified in config. It should be
+// called after ports are started and before packets processing
+// starts because port may be restarted.
+func ApplyEthernetSettings() error {
+	for i := range Natconfig.PortPairs {
+		pp := &Natconfig.PortPairs[i]
+		if err := pp.PrivatePort.applyEthernetSettings(); err != nil {
+			return err
+		}
+		if err := pp.PublicPort.applyEthernetSettings(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (port *ipPort) applyEthernetSettings() error {
+	cfg := &port.Ethernet
+	index := C.uint16_t(port.Index)
+
+	if speeds, ok := cfg.linkSpeeds(); ok {
+		capa := uint32(C.nat_speed_capa(index))
+		if capa != 0 && capa&speeds&^C.ETH_LINK_SPEED_FIXED == 0 {
+			return fmt.Errorf("Port %d doesn't support %d Mbps link speed", port.Index, cfg.Speed)
+		}
+		if rc := C.nat_set_link_speeds(index, C.uint32_t(speeds)); rc != 0 {
+			return fmt.Errorf("Failed to set link speed of port %d, error %d", port.Index, int(rc))
+		}
+	}
+
+	if cfg.FlowControl != flowControlDefault {
+		mode := map[flowControlMode]C.int{
+			flowControlNone: C.RTE_FC_NONE,
+			flowControlRX:   C.RTE_FC_RX_PAUSE,
+			flowControlTX:   C.RTE_FC_TX_PAUSE,
+			flowControlFull: C.RTE_FC_FULL,
+		}[cfg.FlowControl]
+		if rc := C.nat_set_flow_ctrl(index, mode); rc != 0 {
+			return fmt.Errorf("Failed to set flow control of port %d, error %d", port.Index, int(rc))
+		}
+	}
+
+	if cfg.DisablePromiscuous {
+		C.rte_eth_promiscuous_disable(index)
+		// Multicast packets are needed for IPv6 neighbor discovery
+		// and multicast forwarding
+		C.rte_eth_allmulticast_enable(index)
+	}
+	return nil
+}
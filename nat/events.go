@@ -0,0 +1,272 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/intel-go/nff-go/common"
+)
+
+// Operational event types which can be sent to webhooks.
+const (
+	EventDHCPAddressChanged     = "dhcp-address-changed"
+	EventLinkUp                 = "link-up"
+	EventLinkDown               = "link-down"
+	EventFailover               = "failover"
+	EventPortPoolHighWatermark  = "port-pool-high-watermark"
+	EventConfigReload           = "config-reload"
+	EventAddressConflict        = "address-conflict"
+	EventSourceBlocked          = "source-blocked"
+	EventForwardHostDown        = "forward-host-down"
+	EventForwardHostUp          = "forward-host-up"
+	EventExternalAddressChanged = "external-address-changed"
+	EventMaintenance            = "maintenance"
+	EventHostAddressChanged     = "host-address-changed"
+	EventPathMTUChanged         = "path-mtu-changed"
+)
+
+const (
+	eventQueueSize           = 256
+	webhookTimeout           = 5 * time.Second
+	portPoolCheckInterval    = 10 * time.Second
+	defaultPortPoolWatermark = 90
+	// Port pool alarm is cleared when utilization drops below
+	// watermark by this number of percents
+	portPoolWatermarkHysteresis = 10
+)
+
+var (
+	knownEvents = map[string]bool{
+		EventDHCPAddressChanged:     true,
+		EventLinkUp:                 true,
+		EventLinkDown:               true,
+		EventFailover:               true,
+		EventPortPoolHighWatermark:  true,
+		EventConfigReload:           true,
+		EventAddressConflict:        true,
+		EventSourceBlocked:          true,
+		EventForwardHostDown:        true,
+		EventForwardHostUp:          true,
+		EventExternalAddressChanged: true,
+		EventMaintenance:            true,
+		EventHostAddressChanged:     true,
+		EventPathMTUChanged:         true,
+	}
+	eventQueue = make(chan *event, eventQueueSize)
+)
+
+// Webhook target.
+type webhookConfig struct {
+	URL string `json:"url"`
+	// Types of events sent to this webhook, empty list means all
+	// events
+	Events  []string          `json:"events"`
+	Headers map[string]string `json:"headers"`
+}
+
+// Event notification which is sent to webhooks as JSON document.
+type event struct {
+	Event    string                 `json:"event"`
+	Time     time.Time              `json:"time"`
+	HostName string                 `json:"host-name"`
+	Port     *uint16                `json:"port,omitempty"`
+	Tenant   string                 `json:"tenant,omitempty"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+}
+
+func (wh *webhookConfig) check() error {
+	u, err := url.Parse(wh.URL)
+	if err != nil {
+		return fmt.Errorf("Bad webhook URL \"%s\": %+v", wh.URL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("Webhook URL \"%s\" should use http or https scheme", wh.URL)
+	}
+	for _, e := range wh.Events {
+		if !knownEvents[e] {
+			return fmt.Errorf("Unknown event type \"%s\" for webhook \"%s\"", e, wh.URL)
+		}
+	}
+	return nil
+}
+
+func (wh *webhookConfig) wants(e string) bool {
+	if len(wh.Events) == 0 {
+		return true
+	}
+	for _, w := range wh.Events {
+		if w == e {
+			return true
+		}
+	}
+	return false
+}
+
+// raiseEvent queues event notification for all webhooks which
+// subscribed for it. Port is optional. Function never blocks, events
+// are dropped if queue is full.
+func raiseEvent(eventType string, port *ipPort, details map[string]interface{}) {
+	if len(Natconfig.Webhooks) == 0 {
+		return
+	}
+	e := &event{
+		Event:    eventType,
+		Time:     time.Now(),
+		HostName: Natconfig.HostName,
+		Details:  details,
+	}
+	if port != nil {
+		index := port.Index
+		e.Port = &index
+		e.Tenant = port.tenant
+	}
+	select {
+	case eventQueue <- e:
+	default:
+		common.LogWarning(common.No, "Event queue is full, dropping event", eventType)
+	}
+}
+
+// raiseDHCPAddressEvent reports change of port address acquired by
+// DHCP or DHCPv6 client. Empty new address means that address was
+// lost.
+func (port *ipPort) raiseDHCPAddressEvent(ipv6 bool, oldAddress, newAddress string) {
+	raiseEvent(EventDHCPAddressChanged, port, map[string]interface{}{
+		"ipv6":        ipv6,
+		"old-address": oldAddress,
+		"new-address": newAddress,
+	})
+}
+
+// StartEventNotifications starts sending events to configured
+// webhooks and monitoring port pools and KNI interfaces links.
+func StartEventNotifications() {
+	if len(Natconfig.Webhooks) == 0 {
+		return
+	}
+	go sendEvents()
+	go monitorPortPools()
+	if NeedKNI {
+		go monitorKNILinks()
+	}
+}
+
+func sendEvents() {
+	client := &http.Client{
+		Timeout: webhookTimeout,
+	}
+	for e := range eventQueue {
+		data, err := json.Marshal(e)
+		if err != nil {
+			common.LogWarning(common.No, "Failed to encode event", e.Event, ":", err)
+			continue
+		}
+		for i := range Natconfig.Webhooks {
+			wh := &Natconfig.Webhooks[i]
+			if !wh.wants(e.Event) {
+				continue
+			}
+			req, err := http.NewRequest(http.MethodPost, wh.URL, bytes.NewReader(data))
+			if err != nil {
+				common.LogWarning(common.No, "Failed to create webhook request", wh.URL, ":", err)
+				continue
+			}
+			req.Header.Set("Content-Type", "application/json")
+			for k, v := range wh.Headers {
+				req.Header.Set(k, v)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				common.LogWarning(common.No, "Failed to send event to webhook", wh.URL, ":", err)
+				continue
+			}
+			resp.Body.Close()
+			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+				common.LogWarning(common.No, "Webhook", wh.URL, "returned status", resp.Status)
+			}
+		}
+	}
+}
+
+func monitorPortPools() {
+	watermark := Natconfig.PortPoolHighWatermark
+	if watermark == 0 {
+		watermark = defaultPortPoolWatermark
+	}
+	alarms := make([]bool, len(Natconfig.PortPairs))
+	for {
+		time.Sleep(portPoolCheckInterval)
+		for i := range Natconfig.PortPairs {
+			pp := &Natconfig.PortPairs[i]
+			utilization := pp.portPoolUtilization()
+			if !alarms[i] && utilization >= watermark {
+				alarms[i] = true
+				raiseEvent(EventPortPoolHighWatermark, &pp.PublicPort, map[string]interface{}{
+					"state":       "exceeded",
+					"utilization": utilization,
+					"watermark":   watermark,
+				})
+			} else if alarms[i] && utilization < watermark-portPoolWatermarkHysteresis {
+				alarms[i] = false
+				raiseEvent(EventPortPoolHighWatermark, &pp.PublicPort, map[string]interface{}{
+					"state":       "cleared",
+					"utilization": utilization,
+					"watermark":   watermark,
+				})
+			}
+		}
+	}
+}
+
+// monitorKNILinks reports link state changes of KNI interfaces. NFF-Go
+// doesn't report physical link state so state of KNI interfaces in
+// Linux is used.
+func monitorKNILinks() {
+	ports := map[string]*ipPort{}
+	for i := range Natconfig.PortPairs {
+		pp := &Natconfig.PortPairs[i]
+		for _, port := range []*ipPort{&pp.PrivatePort, &pp.PublicPort} {
+			if port.KNIName != "" {
+				ports[port.KNIName] = port
+			}
+		}
+	}
+
+	updates := make(chan netlink.LinkUpdate)
+	if err := netlink.LinkSubscribe(updates, nil); err != nil {
+		common.LogWarning(common.No, "Failed to subscribe to KNI link updates:", err)
+		return
+	}
+	states := map[string]bool{}
+	for u := range updates {
+		name := u.Link.Attrs().Name
+		port, ok := ports[name]
+		if !ok {
+			continue
+		}
+		up := u.Link.Attrs().Flags&net.FlagUp != 0 && u.Link.Attrs().OperState != netlink.OperDown
+		if old, ok := states[name]; ok && old == up {
+			continue
+		}
+		states[name] = up
+		eventType := EventLinkDown
+		if up {
+			eventType = EventLinkUp
+		}
+		raiseEvent(eventType, port, map[string]interface{}{
+			"source":    "kni",
+			"interface": name,
+		})
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+	"fmt"
+)
+
+// checkIPFamilies checks that features of port pair don't need IP
+// family which is disabled and marks ports of pair. Ports of disabled
+// family don't get addresses, don't answer ARP or ND and drop all
+// packets of that family.
+func (pp *portPair) checkIPFamilies() error {
+	if pp.DisableIPv4 && pp.DisableIPv6 {
+		return fmt.Errorf("Port pair with public port %d should not have both IPv4 and IPv6 disabled", pp.PublicPort.Index)
+	}
+	if pp.DisableIPv4 {
+		switch {
+		case len(pp.PublicPort.VLANs) != 0:
+			return errors.New("VLAN subinterfaces require IPv4 which is disabled")
+		case len(pp.Netmap) != 0:
+			return errors.New("Netmap rules require IPv4 which is disabled")
+		case len(pp.Multicast.Groups) != 0:
+			return errors.New("Multicast forwarding requires IPv4 which is disabled")
+		case len(pp.BroadcastRelay) != 0:
+			return errors.New("Broadcast relay requires IPv4 which is disabled")
+		case pp.DHCPRelay.enabled():
+			return errors.New("DHCP relay requires IPv4 which is disabled")
+		}
+	}
+	if pp.DisableIPv6 {
+		switch {
+		case pp.PublicPort.TemporaryAddresses.enabled():
+			return errors.New("Temporary addresses require IPv6 which is disabled")
+		case pp.PublicPort.RouterDiscovery.Enable:
+			return errors.New("Router discovery requires IPv6 which is disabled")
+		case pp.Multicast.MLDQuerier:
+			return errors.New("MLD querier requires IPv6 which is disabled")
+		}
+	}
+	for _, port := range []*ipPort{&pp.PublicPort, &pp.PrivatePort} {
+		port.ipv4Disabled = pp.DisableIPv4
+		port.ipv6Disabled = pp.DisableIPv6
+	}
+	return nil
+}
+
+// familyDisabled returns true if IPv6 or IPv4 is not translated by
+// port.
+func (port *ipPort) familyDisabled(ipv6 bool) bool {
+	if ipv6 {
+		return port.ipv6Disabled
+	}
+	return port.ipv4Disabled
+}
+
+// ipFamilies returns IP families which are translated by port pair,
+// true stands for IPv6.
+func (pp *portPair) ipFamilies() []bool {
+	families := []bool{}
+	if !pp.DisableIPv4 {
+		families = append(families, false)
+	}
+	if !pp.DisableIPv6 {
+		families = append(families, true)
+	}
+	return families
+}
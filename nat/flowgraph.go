@@ -0,0 +1,195 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/flow"
+)
+
+// NFF-Go scheduler and memory options. NFF-Go doesn't allow to pin
+// individual flow functions to cores or to size their rings
+// separately, and burst size is a compile time constant, so these
+// options apply to flow graphs of all port pairs. Only vector
+// translation, which NAT chooses itself, can be overridden by port
+// pair.
+type flowGraphConfig struct {
+	// Cores available to scheduler, e.g. "2-9,12"
+	CPUList string `json:"cpu-list"`
+	// Number of burst size groups in every ring, power of 2
+	RingSize uint `json:"ring-size"`
+	// Number of mbufs in mempool per port and in per core cache
+	MbufNumber    uint `json:"mbuf-number"`
+	MbufCacheSize uint `json:"mbuf-cache-size"`
+	// Scheduler interval in milliseconds
+	SchedulerInterval uint `json:"scheduler-interval"`
+	// No flow function is cloned when scheduler is disabled
+	DisableScheduler bool `json:"disable-scheduler"`
+	// Cloned flow functions are never stopped
+	PersistentClones bool `json:"persistent-clones"`
+	// Don't clone flow functions when it can reorder packets
+	RestrictedCloning bool `json:"restricted-cloning"`
+	// Maximum number of receive queues, and parallel instances of
+	// handlers, per port, 1 or even number
+	ReceiveInstances int32 `json:"receive-instances"`
+	// Translate bursts of packets with vector handlers
+	VectorTranslation bool `json:"vector-translation"`
+	// DPDK virtual devices which are used as ports, e.g.
+	// "net_af_packet0,iface=eth0"
+	VirtualDevices []string `json:"virtual-devices"`
+	// Run DPDK without hugepages, with virtual devices only
+	NoHuge bool `json:"no-huge"`
+	// Megabytes of memory of DPDK without hugepages, zero means
+	// default
+	Memory uint `json:"memory"`
+}
+
+// vectorTranslation returns true if packets of port pair are
+// translated with vector handlers. Option of port pair takes
+// precedence over option of flow graph.
+func (pp *portPair) vectorTranslation() bool {
+	if pp.VectorTranslation != nil {
+		return *pp.VectorTranslation
+	}
+	return Natconfig.FlowGraph.VectorTranslation
+}
+
+// Memory of DPDK without hugepages when it is not configured, in
+// megabytes
+const defaultNoHugeMemory = 512
+
+func (cfg *flowGraphConfig) check() error {
+	if cfg.RingSize&(cfg.RingSize-1) != 0 {
+		return errors.New("Flow graph ring-size should be power of 2")
+	}
+	if cfg.ReceiveInstances < 0 || (cfg.ReceiveInstances > 1 && cfg.ReceiveInstances%2 != 0) {
+		return errors.New("Flow graph receive-instances should be 1 or even number")
+	}
+	return nil
+}
+
+// EALArgs returns DPDK EAL arguments which add virtual devices of
+// flow graph and run DPDK without hugepages if noHuge is set. Without
+// hugepages physical network cards are not probed and NFF-Go shared
+// config files are not created, so that NAT can run in unprivileged
+// containers with virtual devices only.
+func EALArgs(noHuge bool) ([]string, error) {
+	cfg := &Natconfig.FlowGraph
+	args := []string{}
+	for _, dev := range cfg.VirtualDevices {
+		args = append(args, "--vdev="+dev)
+	}
+	if !noHuge {
+		return args, nil
+	}
+	// KNI needs physically contiguous memory of hugepages
+	if NeedKNI {
+		return nil, errors.New("KNI interfaces cannot be used without hugepages")
+	}
+	if len(cfg.VirtualDevices) == 0 {
+		return nil, errors.New("Running without hugepages requires virtual-devices in flow graph")
+	}
+	memory := cfg.Memory
+	if memory == 0 {
+		memory = defaultNoHugeMemory
+	}
+	return append(args, "--no-huge", "--no-pci", "--no-shconf", "-m", strconv.FormatUint(uint64(memory), 10)), nil
+}
+
+// kniPorts returns ports with KNI interfaces in the order their KNI
+// devices are created by InitFlows.
+func kniPorts() []*ipPort {
+	ports := []*ipPort{}
+	for i := range Natconfig.PortPairs {
+		pp := &Natconfig.PortPairs[i]
+		for _, port := range []*ipPort{&pp.PublicPort, &pp.PrivatePort} {
+			if port.KNIName != "" {
+				ports = append(ports, port)
+			}
+		}
+	}
+	return ports
+}
+
+// checkKNICores checks that either all KNI interfaces or none of them
+// have cores and that cores are different.
+func checkKNICores() error {
+	ports := kniPorts()
+	cores := map[int]uint16{}
+	for _, port := range ports {
+		if port.KNICore == nil {
+			continue
+		}
+		if *port.KNICore < 0 {
+			return fmt.Errorf("KNI core of port %d should not be negative", port.Index)
+		}
+		if other, ok := cores[*port.KNICore]; ok {
+			return fmt.Errorf("Ports %d and %d have the same KNI core %d", other, port.Index, *port.KNICore)
+		}
+		cores[*port.KNICore] = port.Index
+	}
+	if len(cores) != 0 && len(cores) != len(ports) {
+		return errors.New("Either all ports with KNI interfaces or none of them should have kni-core")
+	}
+	return nil
+}
+
+// KNICPUList returns list of cores for NFF-Go scheduler where cores
+// of KNI interfaces are put first. NFF-Go gives every KNI device the
+// first free core from its list, so this way each interface gets its
+// core. KNI cores are removed from the rest of the list and are not
+// used for other flow functions. List is returned unchanged when KNI
+// cores are not configured.
+func KNICPUList(cpuList string) (string, error) {
+	ports := kniPorts()
+	if len(ports) == 0 || ports[0].KNICore == nil {
+		return cpuList, nil
+	}
+	var cpus []int
+	if cpuList != "" {
+		var err error
+		if cpus, err = common.HandleCPUList(cpuList, runtime.NumCPU()); err != nil {
+			return "", err
+		}
+	} else {
+		cpus = common.GetDefaultCPUs(runtime.NumCPU())
+	}
+
+	list := []string{}
+	kniCores := map[int]bool{}
+	for _, port := range ports {
+		if *port.KNICore >= runtime.NumCPU() {
+			return "", fmt.Errorf("KNI core %d of port %d exceeds number of cores", *port.KNICore, port.Index)
+		}
+		list = append(list, strconv.Itoa(*port.KNICore))
+		kniCores[*port.KNICore] = true
+	}
+	for _, cpu := range cpus {
+		if !kniCores[cpu] {
+			list = append(list, strconv.Itoa(cpu))
+		}
+	}
+	return strings.Join(list, ","), nil
+}
+
+// setKNIFlows sends packets to KNI device and returns flow of packets
+// received from it. When KNI core is configured, one function sends
+// and receives packets on the core of KNI kernel thread, otherwise
+// scheduler places send and receive functions on its cores.
+func (port *ipPort) setKNIFlows(toKNI *flow.Flow, kni *flow.Kni) *flow.Flow {
+	if port.KNICore != nil {
+		fromKNI, err := flow.SetSenderReceiverKNI(toKNI, kni, true)
+		flow.CheckFatal(err)
+		return fromKNI
+	}
+	flow.CheckFatal(flow.SetSenderKNI(toKNI, kni))
+	return flow.SetReceiverKNI(kni)
+}
@@ -0,0 +1,123 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/intel-go/nff-go/packet"
+)
+
+// Flow label is 20 lower bits of IPv6 version, traffic class and flow
+// label word
+const flowLabelMask = 0xfffff
+
+// What happens to IPv6 flow label of packets which are translated
+// from private to public port. Upstream routers may balance ECMP
+// paths by flow label, so packets of one session should have the same
+// label and different sessions should have different labels.
+type flowLabelAction int
+
+const (
+	// Flow label set by private host is sent unchanged
+	flowLabelPreserve flowLabelAction = iota
+	// Packets without flow label get label generated from session,
+	// labels set by private hosts are preserved
+	flowLabelGenerate
+	// All packets get label generated from session
+	flowLabelReplace
+)
+
+var flowLabelActionLookup = map[string]flowLabelAction{
+	"preserve": flowLabelPreserve,
+	"generate": flowLabelGenerate,
+	"replace":  flowLabelReplace,
+}
+
+// UnmarshalJSON parses IPv6 flow label action.
+func (out *flowLabelAction) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	result, ok := flowLabelActionLookup[s]
+	if !ok {
+		return errors.New("Bad IPv6 flow label action: " + s)
+	}
+
+	*out = result
+	return nil
+}
+
+// String returns action name as it is used in config file.
+func (action flowLabelAction) String() string {
+	for name, a := range flowLabelActionLookup {
+		if a == action {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// MarshalJSON writes action name as it is used in config file.
+func (action flowLabelAction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(action.String())
+}
+
+// checkFlowLabel generates secret key of flow label hash, so labels
+// of sessions cannot be predicted outside of NAT. Key is not saved,
+// labels of sessions change when NAT restarts.
+func (pp *portPair) checkFlowLabel() error {
+	if pp.FlowLabel == flowLabelPreserve {
+		return nil
+	}
+	if pp.DisableIPv6 {
+		return fmt.Errorf("IPv6 flow label action %s of port %s requires IPv6", pp.FlowLabel, pp.PublicPort.logName())
+	}
+	if _, err := rand.Read(pp.flowLabelKey[:]); err != nil {
+		return fmt.Errorf("Failed to generate flow label key of port %s: %v", pp.PublicPort.logName(), err)
+	}
+	return nil
+}
+
+// applyFlowLabel sets flow label of translated IPv6 packet. Label is
+// a keyed FNV-1a hash of translated addresses, protocol and ports, so
+// it is the same for all packets of session without keeping any state.
+// Flow label is not covered by checksums.
+func (pp *portPair) applyFlowLabel(pktIPv6 *packet.IPv6Hdr, protocol uint8, srcPort, dstPort uint16) {
+	if pp.FlowLabel == flowLabelPreserve || pktIPv6 == nil {
+		return
+	}
+	vtcFlow := packet.SwapBytesUint32(pktIPv6.VtcFlow)
+	if pp.FlowLabel == flowLabelGenerate && vtcFlow&flowLabelMask != 0 {
+		return
+	}
+
+	const prime = 16777619
+	h := uint32(2166136261)
+	for _, b := range pp.flowLabelKey {
+		h = (h ^ uint32(b)) * prime
+	}
+	for _, b := range pktIPv6.SrcAddr {
+		h = (h ^ uint32(b)) * prime
+	}
+	for _, b := range pktIPv6.DstAddr {
+		h = (h ^ uint32(b)) * prime
+	}
+	for _, b := range [...]byte{protocol, byte(srcPort >> 8), byte(srcPort), byte(dstPort >> 8), byte(dstPort)} {
+		h = (h ^ uint32(b)) * prime
+	}
+	// Upper bits are folded into label, zero label means that packet
+	// is not labeled
+	label := (h ^ h>>20) & flowLabelMask
+	if label == 0 {
+		label = 1
+	}
+	pktIPv6.VtcFlow = packet.SwapBytesUint32(vtcFlow&^flowLabelMask | label)
+}
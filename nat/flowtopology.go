@@ -0,0 +1,230 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import "fmt"
+
+// Kinds of flow functions in flow graph topology
+const (
+	flowNodeReceiver  = "receiver"
+	flowNodeSplitter  = "splitter"
+	flowNodeHandler   = "handler"
+	flowNodeStopper   = "stopper"
+	flowNodeKNI       = "kni"
+	flowNodeMerger    = "merger"
+	flowNodeSender    = "sender"
+	flowNodeGenerator = "generator"
+)
+
+// Flow function which InitFlows creates for port pair. NFF-Go doesn't
+// expose its flow graph, so topology is built from the same config
+// which InitFlows uses.
+type flowNode struct {
+	id   string
+	kind string
+	// Name of handler function or KNI interface
+	name string
+	pair int
+	// Port of receivers, senders and KNI devices
+	port *ipPort
+}
+
+// Flow between two flow functions. Counter returns number of packets
+// which went through edge, it is nil for edges which NAT doesn't
+// count.
+type flowEdge struct {
+	from, to string
+	counter  func() uint64
+}
+
+// Topology of flow graph of port pairs which is built while flows are
+// added.
+type flowTopology struct {
+	nodes []flowNode
+	edges []flowEdge
+}
+
+// Open end of flow which is connected to the next flow function.
+type flowEnd struct {
+	node    string
+	counter func() uint64
+}
+
+func (t *flowTopology) addNode(pair int, kind, name string, port *ipPort, role string) string {
+	id := fmt.Sprintf("pair%d/%s", pair, role)
+	t.nodes = append(t.nodes, flowNode{
+		id:   id,
+		kind: kind,
+		name: name,
+		pair: pair,
+		port: port,
+	})
+	return id
+}
+
+// connect adds node after open end of flow and returns new end of
+// flow, edges after new node are not counted.
+func (t *flowTopology) connect(end flowEnd, pair int, kind, name string, port *ipPort, role string) flowEnd {
+	id := t.addNode(pair, kind, name, port, role)
+	t.edges = append(t.edges, flowEdge{from: end.node, to: id, counter: end.counter})
+	return flowEnd{node: id}
+}
+
+// counterOf returns function which reads one of port counters.
+func counterOf(port *ipPort, counter func(*portStats) uint64) func() uint64 {
+	return func() uint64 {
+		stats := port.getStats()
+		return counter(&stats)
+	}
+}
+
+func rxPacketsOf(s *portStats) uint64   { return s.rxPackets }
+func txPacketsOf(s *portStats) uint64   { return s.txPackets }
+func kniPacketsOf(s *portStats) uint64  { return s.kniPackets }
+func dropPacketsOf(s *portStats) uint64 { return s.dropPackets }
+
+// addTranslation adds receiver, optional MACsec decryption and
+// translation splitter of port and returns ends of its send and KNI
+// outputs. KNI end is empty if port has no KNI interface.
+func (t *flowTopology) addTranslation(pair int, port *ipPort, side, translation string) (flowEnd, flowEnd) {
+	var kni flowEnd
+	end := flowEnd{node: t.addNode(pair, flowNodeReceiver, "", port, side+"-receiver")}
+	if port.MACsec.enabled() {
+		end = t.connect(end, pair, flowNodeHandler, "macsecInput", nil, side+"-macsec-input")
+	}
+	end.counter = counterOf(port, rxPacketsOf)
+	if Natconfig.PortPairs[pair].vectorTranslation() {
+		translation += "Vector"
+	} else {
+		translation += "Counted"
+	}
+	splitter := t.connect(end, pair, flowNodeSplitter, translation, nil, side+"-translation")
+	t.connect(flowEnd{node: splitter.node, counter: counterOf(port, dropPacketsOf)},
+		pair, flowNodeStopper, "", nil, side+"-drop")
+	// Public translation without KNI interface stops its unused KNI
+	// output
+	if port.Type == iPUBLIC && port.KNIName == "" && Natconfig.PortPairs[pair].kniSNAT != nil {
+		t.connect(flowEnd{node: splitter.node}, pair, flowNodeStopper, "", nil, side+"-kni-drop")
+	}
+
+	if port.KNIName != "" {
+		kni = flowEnd{node: splitter.node, counter: counterOf(port, kniPacketsOf)}
+		if Natconfig.ControlPlaneProtection.enabled() {
+			kni = t.connect(kni, pair, flowNodeHandler, side+"ToKNIPolicing", nil, side+"-kni-policing")
+		}
+		// Replies of private KNI host sessions come from public
+		// translation
+		if port.Type == iPRIVATE && Natconfig.PortPairs[pair].kniSNAT != nil {
+			kni = t.addOutput(pair, flowEnd{node: fmt.Sprintf("pair%d/public-translation", pair)}, kni, side+"-kni")
+		}
+		if port.KNIOffload.GRO {
+			kni = t.connect(kni, pair, flowNodeHandler, side+"KNICoalescing", nil, side+"-kni-coalescing")
+		}
+		kni = t.connect(kni, pair, flowNodeKNI, port.KNIName, port, side+"-kni")
+	}
+	return flowEnd{node: splitter.node, counter: counterOf(port.opposite, txPacketsOf)}, kni
+}
+
+// addOutput merges translated packets with packets of KNI interface of
+// destination port if it has one and with other flows and returns end
+// of merged flow.
+func (t *flowTopology) addOutput(pair int, translated, kni flowEnd, side string, other ...flowEnd) flowEnd {
+	inputs := []flowEnd{}
+	if kni.node != "" {
+		inputs = append(inputs, kni)
+	}
+	inputs = append(inputs, translated)
+	for _, end := range other {
+		if end.node != "" {
+			inputs = append(inputs, end)
+		}
+	}
+	if len(inputs) == 1 {
+		return translated
+	}
+	id := t.addNode(pair, flowNodeMerger, "", nil, side+"-merger")
+	for _, end := range inputs {
+		t.edges = append(t.edges, flowEdge{from: end.node, to: id, counter: end.counter})
+	}
+	return flowEnd{node: id}
+}
+
+// addSegmentation adds segmentation handler of packets from KNI
+// interface and merger with generator of their segments to flow end.
+func (t *flowTopology) addSegmentation(pair int, end flowEnd, role string) flowEnd {
+	end = t.connect(end, pair, flowNodeHandler, "kniSegmentation", nil, role+"-segmentation")
+	generator := t.addNode(pair, flowNodeGenerator, "kniSegmentGenerator", nil, role+"-segments")
+	id := t.addNode(pair, flowNodeMerger, "", nil, role+"-segments-merger")
+	t.edges = append(t.edges, flowEdge{from: end.node, to: id}, flowEdge{from: generator, to: id})
+	return flowEnd{node: id}
+}
+
+// addScheduling adds egress scheduling handler of source class to flow
+// end which is merged before port sends it.
+func (t *flowTopology) addScheduling(pair int, port *ipPort, end flowEnd, side string, class int) flowEnd {
+	if port.scheduler == nil || end.node == "" {
+		return end
+	}
+	return t.connect(end, pair, flowNodeHandler, "egressScheduling", nil, side+"-"+egressClassNames[class]+"-scheduling")
+}
+
+// addFlowTopology adds flow functions which InitFlows creates for port
+// pair with index in config.
+func (t *flowTopology) addFlowTopology(pair int) {
+	pp := &Natconfig.PortPairs[pair]
+	toPriv, pubKNI := t.addTranslation(pair, &pp.PublicPort, "public", "publicToPrivate")
+	if pubKNI.node != "" {
+		pubKNI = t.connect(pubKNI, pair, flowNodeHandler, "publicKNIOutput", nil, "public-kni-output")
+		if pp.PublicPort.KNIOffload.enabled() {
+			pubKNI = t.addSegmentation(pair, pubKNI, "public-kni")
+		}
+	}
+	toPub, privKNI := t.addTranslation(pair, &pp.PrivatePort, "private", "privateToPublic")
+	// Traffic of private KNI host to public network is translated
+	var kniToPub flowEnd
+	if pp.kniSNAT != nil {
+		privKNI = t.connect(privKNI, pair, flowNodeSplitter, "privateKNIOutput", nil, "private-kni-output")
+		t.connect(privKNI, pair, flowNodeStopper, "", nil, "private-kni-drop")
+		kniToPub = privKNI
+		if pp.PrivatePort.KNIOffload.enabled() {
+			kniToPub = t.addSegmentation(pair, kniToPub, "private-kni-to-public")
+		}
+	}
+	if privKNI.node != "" && pp.PrivatePort.KNIOffload.enabled() {
+		privKNI = t.addSegmentation(pair, privKNI, "private-kni")
+	}
+
+	if pubKNI.node != "" || kniToPub.node != "" {
+		toPub = t.addScheduling(pair, &pp.PublicPort, toPub, "public", egressTranslated)
+		pubKNI = t.addScheduling(pair, &pp.PublicPort, pubKNI, "public", egressKNI)
+		kniToPub = t.addScheduling(pair, &pp.PublicPort, kniToPub, "public", egressKNITranslated)
+	}
+	if privKNI.node != "" {
+		toPriv = t.addScheduling(pair, &pp.PrivatePort, toPriv, "private", egressTranslated)
+		privKNI = t.addScheduling(pair, &pp.PrivatePort, privKNI, "private", egressKNI)
+	}
+	toPub = t.addOutput(pair, toPub, pubKNI, "public", kniToPub)
+	toPriv = t.addOutput(pair, toPriv, privKNI, "private")
+	if pp.EgressPolicer.enabled() {
+		toPub = t.connect(toPub, pair, flowNodeHandler, "egressPolicing", nil, "public-egress-policing")
+	}
+	if pp.PublicPort.MACsec.enabled() {
+		toPub = t.connect(toPub, pair, flowNodeHandler, "macsecOutput", nil, "public-macsec-output")
+	}
+	t.connect(toPriv, pair, flowNodeSender, "", &pp.PrivatePort, "private-sender")
+	t.connect(toPub, pair, flowNodeSender, "", &pp.PublicPort, "public-sender")
+}
+
+// sampleEdges returns current values of edge counters, zero for edges
+// which are not counted.
+func (t *flowTopology) sampleEdges() []uint64 {
+	packets := make([]uint64, len(t.edges))
+	for i := range t.edges {
+		if t.edges[i].counter != nil {
+			packets[i] = t.edges[i].counter()
+		}
+	}
+	return packets
+}
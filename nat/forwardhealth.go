@@ -0,0 +1,284 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+const (
+	// Probes without answer after which host is unreachable when it
+	// is not configured
+	defaultForwardHealthFailures = 3
+	// Port pairs are checked for due probes this often
+	forwardHealthCheckInterval = time.Second
+	// Echo identifier of probes of forwarded port destinations
+	forwardHealthEchoID = 0x5e20
+)
+
+// Probing of private hosts which are destinations of forwarded
+// ports. Hosts are probed with ARP or neighbor solicitation and
+// optionally with ICMP echo every interval seconds, zero interval
+// disables probing.
+type forwardHealthConfig struct {
+	Interval int `json:"interval"`
+	// Probes without answer after which host is unreachable, zero
+	// means default
+	Failures int `json:"failures"`
+	// Probe hosts with ICMP echo too, it is required in static ARP
+	// mode
+	Echo bool `json:"echo"`
+	// Answer new TCP connections to unreachable hosts with reset
+	Reset bool `json:"reset"`
+}
+
+// Probed destination of forwarded ports.
+type forwardHost struct {
+	// Set when host answers probe or sends packet which updates
+	// neighbor table
+	answered int32
+	// Set while host is unreachable
+	down int32
+	// Probes without answer so far, used only by prober
+	failures int
+}
+
+// Reachability of destinations of forwarded ports of private port.
+// Hosts are updated by packet handlers running on several cores.
+type forwardHealth struct {
+	// Inbound packets dropped because host is unreachable
+	refused uint64
+	// *forwardHost by types.IPv4Address or types.IPv6Address of host
+	hosts sync.Map
+	// Number of unreachable hosts, checked by packet handlers
+	// before host lookup
+	down int32
+	// Time of next probes, used only by prober
+	next time.Time
+	seq  uint16
+}
+
+func (cfg *forwardHealthConfig) failures() int {
+	if cfg.Failures == 0 {
+		return defaultForwardHealthFailures
+	}
+	return cfg.Failures
+}
+
+// checkForwardHealth checks probing options and enables reachability
+// tracking on private port.
+func (pp *portPair) checkForwardHealth() error {
+	cfg := &pp.ForwardHealth
+	if cfg.Interval < 0 || cfg.Failures < 0 {
+		return fmt.Errorf("Values of forward-health should not be negative")
+	}
+	if cfg.Interval == 0 {
+		return nil
+	}
+	if pp.PrivatePort.staticArpMode && !cfg.Echo {
+		return fmt.Errorf("Forward health of private port %d requires echo in static ARP mode", pp.PrivatePort.Index)
+	}
+	pp.PrivatePort.health = &forwardHealth{}
+	return nil
+}
+
+// markAnswered notes that host answered probe. Hosts which are not
+// destinations of forwarded ports are ignored.
+func (health *forwardHealth) markAnswered(ip interface{}) {
+	v, ok := health.hosts.Load(ip)
+	if ok {
+		atomic.StoreInt32(&v.(*forwardHost).answered, 1)
+	}
+}
+
+// handleForwardHealthReply returns true if ICMP packet sent to port
+// address is echo reply to probe of forwarded port destination.
+func (port *ipPort) handleForwardHealthReply(protocol uint8, pkt *packet.Packet, icmp *packet.ICMPHdr) bool {
+	if port.health == nil || packet.SwapBytesUint16(icmp.Identifier) != forwardHealthEchoID || icmp.Code != 0 {
+		return false
+	}
+	if protocol == types.ICMPNumber {
+		if icmp.Type != types.ICMPTypeEchoResponse {
+			return false
+		}
+		port.health.markAnswered(packet.SwapBytesIPv4Addr(pkt.GetIPv4NoCheck().SrcAddr))
+	} else {
+		if icmp.Type != types.ICMPv6TypeEchoResponse {
+			return false
+		}
+		port.health.markAnswered(pkt.GetIPv6NoCheck().SrcAddr)
+	}
+	return true
+}
+
+// isHostUnreachable returns true if private host of forwarded port
+// doesn't answer probes.
+func (port *ipPort) isHostUnreachable(ipv6 bool, v4addr types.IPv4Address, v6addr types.IPv6Address) bool {
+	health := port.health
+	if health == nil || atomic.LoadInt32(&health.down) == 0 {
+		return false
+	}
+	var v interface{}
+	var ok bool
+	if ipv6 {
+		v, ok = health.hosts.Load(v6addr)
+	} else {
+		v, ok = health.hosts.Load(v4addr)
+	}
+	return ok && atomic.LoadInt32(&v.(*forwardHost).down) != 0
+}
+
+// refuseUnreachableHost drops inbound packet of forwarded port which
+// private host is unreachable. New TCP connections are answered with
+// reset if forward health options require it.
+func (pp *portPair) refuseUnreachableHost(pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr, pktTCP *packet.TCPHdr) uint {
+	atomic.AddUint64(&pp.PrivatePort.health.refused, 1)
+	if pktTCP != nil && pp.ForwardHealth.Reset && isNewTCPConnection(pktTCP) {
+		pp.PublicPort.sendTCPReset(pkt, pktIPv4, pktIPv6, pktTCP)
+	}
+	pp.PublicPort.dumpPacket(pkt, DirDROP)
+	return DirDROP
+}
+
+// forwardHealthCounters returns number of unreachable hosts and
+// inbound packets dropped because their host is unreachable.
+func (pp *portPair) forwardHealthCounters() (uint64, uint64) {
+	health := pp.PrivatePort.health
+	return uint64(atomic.LoadInt32(&health.down)), atomic.LoadUint64(&health.refused)
+}
+
+// forwardHosts returns private hosts of forwarded ports of public
+// port. Destinations in KNI interface are not probed.
+func (pp *portPair) forwardHosts() map[interface{}]bool {
+	hosts := map[interface{}]bool{}
+	add := func(hp *hostPort) {
+		if hp.ipv6 {
+			if hp.Addr6 != zeroIPv6Addr {
+				hosts[hp.Addr6] = true
+			}
+		} else if hp.Addr4 != 0 {
+			hosts[hp.Addr4] = true
+		}
+	}
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+	for i := range pp.PublicPort.ForwardPorts {
+		fp := &pp.PublicPort.ForwardPorts[i]
+		if !fp.rewritesKNI() {
+			add(&fp.Destination)
+		}
+		for j := range fp.Sources {
+			add(&fp.Sources[j].Destination)
+		}
+	}
+	return hosts
+}
+
+// probeForwardHosts updates reachability of private hosts of
+// forwarded ports with answers to previous probes and probes them
+// again. Changes are reported with events.
+func (pp *portPair) probeForwardHosts() {
+	port := &pp.PrivatePort
+	health := port.health
+	now := time.Now()
+	if now.Before(health.next) {
+		return
+	}
+	health.next = now.Add(time.Duration(pp.ForwardHealth.Interval) * time.Second)
+
+	hosts := pp.forwardHosts()
+	health.hosts.Range(func(k, v interface{}) bool {
+		if !hosts[k] {
+			health.hosts.Delete(k)
+			if atomic.LoadInt32(&v.(*forwardHost).down) != 0 {
+				atomic.AddInt32(&health.down, -1)
+			}
+		}
+		return true
+	})
+
+	for addr := range hosts {
+		// New hosts are reachable until they miss probes
+		v, _ := health.hosts.LoadOrStore(addr, &forwardHost{
+			answered: 1,
+		})
+		host := v.(*forwardHost)
+		if atomic.SwapInt32(&host.answered, 0) != 0 {
+			host.failures = 0
+			if atomic.CompareAndSwapInt32(&host.down, 1, 0) {
+				atomic.AddInt32(&health.down, -1)
+				port.reportForwardHost(addr, EventForwardHostUp)
+			}
+		} else {
+			host.failures++
+			if host.failures >= pp.ForwardHealth.failures() && atomic.CompareAndSwapInt32(&host.down, 0, 1) {
+				atomic.AddInt32(&health.down, 1)
+				port.reportForwardHost(addr, EventForwardHostDown)
+			}
+		}
+		health.seq++
+		port.probeForwardHost(addr, pp.ForwardHealth.Echo, health.seq)
+	}
+}
+
+// probeForwardHost sends ARP request or neighbor solicitation to host
+// and echo request if it is enabled and host MAC address is known.
+// Only echo is sent in static ARP mode.
+func (port *ipPort) probeForwardHost(addr interface{}, echo bool, seq uint16) {
+	mac, found := port.DstMACAddress, port.staticArpMode
+	if !port.staticArpMode {
+		switch ip := addr.(type) {
+		case types.IPv4Address:
+			port.sendARPRequest(ip)
+		case types.IPv6Address:
+			port.sendNDNeighborSolicitationRequest(ip)
+		}
+		mac, found = port.loadNeighbor(addr)
+	}
+	if echo && found {
+		port.sendEchoRequest(addr, mac, forwardHealthEchoID, seq)
+	}
+}
+
+func (port *ipPort) reportForwardHost(addr interface{}, eventType string) {
+	host := fmt.Sprint(addr)
+	if eventType == EventForwardHostDown {
+		common.LogWarning(common.No, "Private host", host, "of forwarded ports of port", port.logName(), "is unreachable")
+	} else {
+		common.LogWarning(common.No, "Private host", host, "of forwarded ports of port", port.logName(), "is reachable again")
+	}
+	raiseEvent(eventType, port, map[string]interface{}{
+		"host": host,
+	})
+}
+
+// StartForwardHealth starts probing private hosts of forwarded ports
+// for port pairs which enable it.
+func StartForwardHealth() {
+	pairs := []*portPair{}
+	for i := range Natconfig.PortPairs {
+		if Natconfig.PortPairs[i].PrivatePort.health != nil {
+			pairs = append(pairs, &Natconfig.PortPairs[i])
+		}
+	}
+	if len(pairs) == 0 {
+		return
+	}
+	go func() {
+		for {
+			for _, pp := range pairs {
+				pp.probeForwardHosts()
+			}
+			time.Sleep(forwardHealthCheckInterval)
+		}
+	}()
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+	"net"
+
+	"github.com/intel-go/nff-go/packet"
+)
+
+// Private destination of forwarded port for public sources within
+// prefix, e.g. management networks may reach another host than
+// everyone else. Sources which match no prefix go to destination of
+// forwarded port.
+type forwardedSource struct {
+	Prefix      string   `json:"prefix"`
+	Destination hostPort `json:"destination"`
+	subnet      ipv4Subnet
+	subnet6     ipv6Subnet
+}
+
+// checkForwardedSources parses source prefixes of forwarded port and
+// checks their destinations like destination of forwarded port.
+func (port *ipPort) checkForwardedSources(fp *forwardedPort) error {
+	if len(fp.Sources) != 0 && port.Type != iPUBLIC {
+		return errors.New("Source specific destinations of forwarded ports are allowed only on public port")
+	}
+	for i := range fp.Sources {
+		s := &fp.Sources[i]
+		_, n, err := net.ParseCIDR(s.Prefix)
+		if err != nil {
+			return errors.New("Bad forwarded port source prefix " + s.Prefix)
+		}
+		if ip4 := n.IP.To4(); ip4 != nil {
+			if fp.Protocol.ipv6 {
+				return errors.New("Source prefix " + s.Prefix + " of IPv6 forwarded port should be IPv6 prefix")
+			}
+			s.subnet.Addr, _ = convertIPv4(ip4)
+			s.subnet.Mask, _ = convertIPv4(n.Mask[len(n.Mask)-net.IPv4len:])
+			s.subnet.addressAcquired = true
+		} else {
+			if !fp.Protocol.ipv6 {
+				return errors.New("Source prefix " + s.Prefix + " of IPv4 forwarded port should be IPv4 prefix")
+			}
+			copy(s.subnet6.Addr[:], n.IP.To16())
+			copy(s.subnet6.Mask[:], n.Mask)
+			s.subnet6.addressAcquired = true
+		}
+		dst := forwardedPort{
+			Port:        fp.Port,
+			Destination: s.Destination,
+			Protocol:    fp.Protocol,
+		}
+		if err := port.checkPortForwarding(&dst); err != nil {
+			return err
+		}
+		s.Destination = dst.Destination
+	}
+	return nil
+}
+
+// sourceDestination returns destination of forwarded port for source
+// of packet, nil if source matches no prefix. Port map entries of
+// forwarded ports hold their source rules, so forwarded ports without
+// them don't pay for lookup.
+func sourceDestination(sources []forwardedSource, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) *hostPort {
+	if pktIPv4 != nil {
+		src := packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr)
+		for i := range sources {
+			if sources[i].subnet.checkAddrWithingSubnet(src) {
+				return &sources[i].Destination
+			}
+		}
+		return nil
+	}
+	for i := range sources {
+		if sources[i].subnet6.checkAddrWithingSubnet(pktIPv6.SrcAddr) {
+			return &sources[i].Destination
+		}
+	}
+	return nil
+}
+
+// sourceDestinationKeys returns private side keys of source specific
+// destinations, so that replies of their hosts are translated back to
+// forwarded port. Destinations on KNI interface have no private side.
+func sourceDestinationKeys(sources []forwardedSource) []interface{} {
+	keys := []interface{}{}
+	for i := range sources {
+		d := &sources[i].Destination
+		if d.ipv6 && d.Addr6 != zeroIPv6Addr {
+			keys = append(keys, Tuple6{addr: d.Addr6, port: d.Port})
+		} else if !d.ipv6 && d.Addr4 != 0 {
+			keys = append(keys, Tuple{addr: d.Addr4, port: d.Port})
+		}
+	}
+	return keys
+}
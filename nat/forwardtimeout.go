@@ -0,0 +1,145 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/packet"
+)
+
+const (
+	// Maximum idle timeout of forwarded port in seconds
+	maxForwardedIdleTimeout = 7 * 24 * 3600
+	// Sessions of forwarded ports are checked this often
+	forwardedSessionsInterval = time.Second
+)
+
+// Sessions of remote hosts with forwarded port which has idle timeout.
+// Packets of all remote hosts share the static mapping of forwarded
+// port, so sessions are kept separately and only for ports with idle
+// timeout.
+type forwardedSessions struct {
+	timeout time.Duration
+	// Time of last packet in Unix nanoseconds by Tuple or Tuple6 of
+	// remote host, values are *int64
+	remotes sync.Map
+	// Time when expired sessions are removed next, used only by
+	// expiry goroutine
+	nextExpiry time.Time
+}
+
+func (fp *forwardedPort) checkIdleTimeout(port *ipPort) error {
+	if fp.IdleTimeout < 0 || fp.IdleTimeout > maxForwardedIdleTimeout {
+		return errors.New("Idle timeout of forwarded port should be between 0 and 604800 seconds")
+	}
+	if fp.IdleTimeout != 0 && port.Type != iPUBLIC {
+		return errors.New("Idle timeout is supported only by forwarded ports of public port")
+	}
+	return nil
+}
+
+// newForwardedSessions returns session table of forwarded port or nil
+// if port has no idle timeout.
+func (fp *forwardedPort) newForwardedSessions() *forwardedSessions {
+	if fp.IdleTimeout == 0 {
+		return nil
+	}
+	return &forwardedSessions{
+		timeout: time.Duration(fp.IdleTimeout) * time.Second,
+	}
+}
+
+// lookup returns time of last packet of session of remote host, it
+// returns false if remote host had no packets within idle timeout.
+func (fs *forwardedSessions) lookup(remote interface{}, now int64) (*int64, bool) {
+	v, ok := fs.remotes.Load(remote)
+	if !ok {
+		return nil, false
+	}
+	last := v.(*int64)
+	if now-atomic.LoadInt64(last) > int64(fs.timeout) {
+		return nil, false
+	}
+	return last, true
+}
+
+// active returns true and updates session of remote host if it had
+// packets within idle timeout.
+func (fs *forwardedSessions) active(remote interface{}, now int64) bool {
+	last, ok := fs.lookup(remote, now)
+	if ok {
+		atomic.StoreInt64(last, now)
+	}
+	return ok
+}
+
+// inbound checks packet of remote host sent to forwarded port. Every
+// UDP datagram and TCP segment which starts connection starts session
+// if remote host has none. It returns false if packet doesn't belong to
+// a session.
+func (fs *forwardedSessions) inbound(remote interface{}, pktTCP *packet.TCPHdr, now int64) bool {
+	if fs.active(remote, now) {
+		return true
+	}
+	if pktTCP != nil && !isNewTCPConnection(pktTCP) {
+		return false
+	}
+	last := now
+	if v, loaded := fs.remotes.LoadOrStore(remote, &last); loaded {
+		atomic.StoreInt64(v.(*int64), now)
+	}
+	return true
+}
+
+// expire removes sessions which were idle for timeout. Sessions are
+// checked once per timeout, so they are removed at most one timeout
+// after they expire.
+func (fs *forwardedSessions) expire(now time.Time) {
+	if now.Before(fs.nextExpiry) {
+		return
+	}
+	fs.nextExpiry = now.Add(fs.timeout)
+	deadline := now.Add(-fs.timeout).UnixNano()
+	fs.remotes.Range(func(k, v interface{}) bool {
+		if atomic.LoadInt64(v.(*int64)) < deadline {
+			fs.remotes.Delete(k)
+		}
+		return true
+	})
+}
+
+// expireForwardedSessions removes sessions of forwarded ports of port
+// pair which expired.
+func (pp *portPair) expireForwardedSessions(now time.Time) {
+	for _, fp := range pp.forwardedPorts(&pp.PublicPort) {
+		if fp.IdleTimeout == 0 {
+			continue
+		}
+		pp.mutex.Lock()
+		fs := pp.PublicPort.getPortmap(fp.Protocol.ipv6, fp.Protocol.id)[fp.Port].forwarded
+		pp.mutex.Unlock()
+		if fs != nil {
+			fs.expire(now)
+		}
+	}
+}
+
+// StartForwardedSessionExpiry starts removing expired sessions of
+// forwarded ports which have idle timeout.
+func StartForwardedSessionExpiry() {
+	go func() {
+		for {
+			now := time.Now()
+			for i := range Natconfig.PortPairs {
+				Natconfig.PortPairs[i].expireForwardedSessions(now)
+			}
+			time.Sleep(forwardedSessionsInterval)
+		}
+	}()
+}
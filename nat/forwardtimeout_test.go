@@ -0,0 +1,101 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+func TestForwardedIdleTimeoutCheck(t *testing.T) {
+	public := &ipPort{Type: iPUBLIC}
+	private := &ipPort{Type: iPRIVATE}
+	tests := []struct {
+		name    string
+		port    *ipPort
+		timeout int
+		valid   bool
+	}{
+		{"no timeout", private, 0, true},
+		{"timeout of public port", public, 30, true},
+		{"maximum timeout", public, maxForwardedIdleTimeout, true},
+		{"negative timeout", public, -1, false},
+		{"too long timeout", public, maxForwardedIdleTimeout + 1, false},
+		{"timeout of private port", private, 30, false},
+	}
+	for _, tt := range tests {
+		fp := &forwardedPort{IdleTimeout: tt.timeout}
+		if err := fp.checkIdleTimeout(tt.port); (err == nil) != tt.valid {
+			t.Errorf("%s: error is %v, expected valid %v", tt.name, err, tt.valid)
+		}
+	}
+	if (&forwardedPort{}).newForwardedSessions() != nil {
+		t.Errorf("Forwarded port without idle timeout has sessions")
+	}
+}
+
+func TestForwardedSessions(t *testing.T) {
+	fs := (&forwardedPort{IdleTimeout: 10}).newForwardedSessions()
+	syn := &packet.TCPHdr{TCPFlags: types.TCPFlagSyn}
+	ack := &packet.TCPHdr{TCPFlags: types.TCPFlagAck}
+	tcp := Tuple{addr: hostIPv4(198, 51, 100, 1), port: 40000}
+	udp := Tuple{addr: hostIPv4(198, 51, 100, 2), port: 40000}
+	start := time.Now()
+	second := func(s int) int64 {
+		return start.Add(time.Duration(s) * time.Second).UnixNano()
+	}
+
+	tests := []struct {
+		name    string
+		outward bool
+		remote  interface{}
+		tcp     *packet.TCPHdr
+		at      int
+		allowed bool
+	}{
+		{"reply without session", true, udp, nil, 0, false},
+		{"TCP segment without session", false, tcp, ack, 0, false},
+		{"SYN starts session", false, tcp, syn, 0, true},
+		{"reply within session", true, tcp, nil, 5, true},
+		{"reply refreshes session", false, tcp, ack, 14, true},
+		{"TCP session expired", false, tcp, ack, 25, false},
+		{"reply after session expired", true, tcp, nil, 25, false},
+		{"SYN starts session again", false, tcp, syn, 26, true},
+		{"datagram starts session", false, udp, nil, 0, true},
+		{"datagram after session expired starts session", false, udp, nil, 20, true},
+		{"reply to new session", true, udp, nil, 21, true},
+	}
+	for _, tt := range tests {
+		var allowed bool
+		if tt.outward {
+			allowed = fs.active(tt.remote, second(tt.at))
+		} else {
+			allowed = fs.inbound(tt.remote, tt.tcp, second(tt.at))
+		}
+		if allowed != tt.allowed {
+			t.Errorf("%s: packet is allowed %v, expected %v", tt.name, allowed, tt.allowed)
+		}
+	}
+
+	// Sessions are removed once per timeout
+	fs.expire(start.Add(32 * time.Second))
+	if _, ok := fs.remotes.Load(udp); ok {
+		t.Errorf("Expired session is not removed")
+	}
+	if _, ok := fs.remotes.Load(tcp); !ok {
+		t.Errorf("Active session is removed")
+	}
+	fs.expire(start.Add(41 * time.Second))
+	if _, ok := fs.remotes.Load(tcp); !ok {
+		t.Errorf("Sessions are removed before next check")
+	}
+	fs.expire(start.Add(42 * time.Second))
+	if _, ok := fs.remotes.Load(tcp); ok {
+		t.Errorf("Expired session is not removed by next check")
+	}
+}
@@ -0,0 +1,371 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+const (
+	// Fragmented datagrams tracked by one port. Only first fragment
+	// has transport header, so translation of first fragment is
+	// remembered and applied to all other fragments of the datagram.
+	maxFragmentFlows = 4096
+	// Part of fragmented datagrams table which only DNS datagrams may
+	// use, so that large DNS answers are translated when table is
+	// filled with other traffic
+	dnsReservedFragmentFlows = 1024
+	// Fragments which arrived before first fragment of their
+	// datagram and are held until it arrives
+	maxHeldFragments = 256
+	// Fragments of a datagram normally arrive within milliseconds
+	fragmentTimeout = 5 * time.Second
+
+	dnsPort = 53
+
+	ipv4DontFragment   = 0x4000
+	ipv4MoreFragments  = 0x2000
+	ipv4FragmentOffset = 0x1fff
+)
+
+// Fragments of datagram are identified by addresses, protocol and
+// identification field of IPv4 header before translation.
+type fragmentKey struct {
+	src      types.IPv4Address
+	dst      types.IPv4Address
+	id       uint16
+	protocol uint8
+}
+
+// Translation of fragmented datagram.
+type fragmentFlow struct {
+	// Translated source address for egress or destination address
+	// for ingress datagram
+	addr types.IPv4Address
+	mac  types.MACAddress
+	// Vlan tag of translated fragments
+	vlan uint16
+	// Private host which sends or receives datagram
+	host    types.IPv4Address
+	dns     bool
+	created time.Time
+}
+
+type heldFragment struct {
+	key      fragmentKey
+	data     []byte
+	received time.Time
+}
+
+// Fragmented datagrams received by port. Table is used by packet
+// handlers running on several cores, so it is protected by a mutex.
+type fragmentTable struct {
+	mutex sync.Mutex
+	flows map[fragmentKey]*fragmentFlow
+	held  []heldFragment
+	// Fragments without transport header translated, dropped
+	// because their datagram is unknown and held until first
+	// fragment arrives
+	translated uint64
+	dropped    uint64
+	heldCount  uint64
+}
+
+func isIPv4Fragment(pktIPv4 *packet.IPv4Hdr) bool {
+	return packet.SwapBytesUint16(pktIPv4.FragmentOffset)&(ipv4MoreFragments|ipv4FragmentOffset) != 0
+}
+
+// isIPv4LaterFragment returns true if packet is a fragment other than
+// first, so it has no transport header.
+func isIPv4LaterFragment(pktIPv4 *packet.IPv4Hdr) bool {
+	return packet.SwapBytesUint16(pktIPv4.FragmentOffset)&ipv4FragmentOffset != 0
+}
+
+func newFragmentKey(pktIPv4 *packet.IPv4Hdr) fragmentKey {
+	return fragmentKey{
+		src:      pktIPv4.SrcAddr,
+		dst:      pktIPv4.DstAddr,
+		id:       pktIPv4.PacketID,
+		protocol: pktIPv4.NextProtoID,
+	}
+}
+
+func isDNS(pktUDP *packet.UDPHdr) bool {
+	return pktUDP != nil && (packet.SwapBytesUint16(pktUDP.SrcPort) == dnsPort ||
+		packet.SwapBytesUint16(pktUDP.DstPort) == dnsPort)
+}
+
+func (ft *fragmentTable) lookup(key fragmentKey, now time.Time) *fragmentFlow {
+	ft.mutex.Lock()
+	defer ft.mutex.Unlock()
+	flow, ok := ft.flows[key]
+	if !ok || now.Sub(flow.created) > fragmentTimeout {
+		return nil
+	}
+	return flow
+}
+
+// store remembers translation of first fragment. Other datagrams
+// cannot use reserved part of table, DNS datagrams replace other ones
+// when table is full. It returns fragments of this datagram which
+// arrived before first fragment.
+func (ft *fragmentTable) store(key fragmentKey, flow *fragmentFlow) [][]byte {
+	ft.mutex.Lock()
+	defer ft.mutex.Unlock()
+	if ft.flows == nil {
+		ft.flows = map[fragmentKey]*fragmentFlow{}
+	}
+	delete(ft.flows, key)
+
+	limit := maxFragmentFlows - dnsReservedFragmentFlows
+	if flow.dns {
+		limit = maxFragmentFlows
+	}
+	if len(ft.flows) >= limit {
+		ft.forgetExpired(flow.created)
+	}
+	if len(ft.flows) >= limit && flow.dns {
+		for k, f := range ft.flows {
+			if !f.dns {
+				delete(ft.flows, k)
+				break
+			}
+		}
+	}
+	if len(ft.flows) < limit {
+		ft.flows[key] = flow
+	}
+	return ft.release(key)
+}
+
+func (ft *fragmentTable) forgetExpired(now time.Time) {
+	for k, f := range ft.flows {
+		if now.Sub(f.created) > fragmentTimeout {
+			delete(ft.flows, k)
+		}
+	}
+}
+
+// hold keeps copy of fragment which arrived before first fragment of
+// its datagram. It returns false if there is no room for it.
+func (ft *fragmentTable) hold(key fragmentKey, pkt *packet.Packet, now time.Time) bool {
+	ft.mutex.Lock()
+	defer ft.mutex.Unlock()
+	if len(ft.held) >= maxHeldFragments {
+		kept := ft.held[:0]
+		for _, h := range ft.held {
+			if now.Sub(h.received) <= fragmentTimeout {
+				kept = append(kept, h)
+			}
+		}
+		ft.held = kept
+	}
+	if len(ft.held) >= maxHeldFragments {
+		return false
+	}
+	// Packet buffer is reused after packet is dropped, so it is
+	// copied
+	ft.held = append(ft.held, heldFragment{
+		key:      key,
+		data:     append([]byte{}, pkt.GetRawPacketBytes()...),
+		received: now,
+	})
+	atomic.AddUint64(&ft.heldCount, 1)
+	return true
+}
+
+// release removes and returns held fragments of datagram. Mutex
+// should be locked.
+func (ft *fragmentTable) release(key fragmentKey) [][]byte {
+	var result [][]byte
+	kept := ft.held[:0]
+	for _, h := range ft.held {
+		if h.key == key {
+			result = append(result, h.data)
+		} else {
+			kept = append(kept, h)
+		}
+	}
+	ft.held = kept
+	return result
+}
+
+// counters returns numbers of translated, dropped and held fragments.
+func (ft *fragmentTable) counters() (uint64, uint64, uint64) {
+	return atomic.LoadUint64(&ft.translated), atomic.LoadUint64(&ft.dropped), atomic.LoadUint64(&ft.heldCount)
+}
+
+// storeFragmentFlow remembers translation of first fragment of a
+// datagram received by port and sends fragments of this datagram
+// which arrived before it. Header should not be translated yet.
+func (pp *portPair) storeFragmentFlow(port *ipPort, pktIPv4 *packet.IPv4Hdr, pktUDP *packet.UDPHdr, addr, host types.IPv4Address, mac types.MACAddress, vlan uint16) {
+	flow := &fragmentFlow{
+		addr:    addr,
+		mac:     mac,
+		vlan:    vlan,
+		host:    host,
+		dns:     isDNS(pktUDP),
+		created: time.Now(),
+	}
+	for _, data := range port.fragments.store(newFragmentKey(pktIPv4), flow) {
+		pp.sendHeldFragment(port, data, flow)
+	}
+}
+
+func (pp *portPair) sendHeldFragment(port *ipPort, data []byte, flow *fragmentFlow) {
+	if !pp.policeFragment(port, flow, uint(len(data))) {
+		atomic.AddUint64(&port.fragments.dropped, 1)
+		return
+	}
+	pkt, err := packet.NewPacket()
+	if err != nil {
+		atomic.AddUint64(&port.fragments.dropped, 1)
+		return
+	}
+	packet.GeneratePacketFromByte(pkt, data)
+	pktVLAN := pkt.ParseL3CheckVLAN()
+	pp.translateFragment(port, pkt, pktVLAN, pkt.GetIPv4CheckVLAN(), flow)
+	atomic.AddUint64(&port.opposite.stats.txPackets, 1)
+	atomic.AddUint64(&port.opposite.stats.txBytes, uint64(len(data)))
+	port.opposite.sendPacket(pkt)
+}
+
+// handleLaterFragment translates fragment which has no transport
+// header in the same way as first fragment of its datagram was
+// translated.
+func (pp *portPair) handleLaterFragment(port *ipPort, pkt *packet.Packet, pktVLAN *packet.VLANHdr, pktIPv4 *packet.IPv4Hdr) uint {
+	now := time.Now()
+	key := newFragmentKey(pktIPv4)
+	if flow := port.fragments.lookup(key, now); flow != nil {
+		if !pp.policeFragment(port, flow, pkt.GetPacketLen()) {
+			atomic.AddUint64(&port.fragments.dropped, 1)
+			port.dumpPacket(pkt, DirDROP)
+			return DirDROP
+		}
+		if !pp.forwardHopLimit(port, pkt, pktIPv4, nil, false) {
+			return DirDROP
+		}
+		pp.translateFragment(port, pkt, pktVLAN, pktIPv4, flow)
+		return DirSEND
+	}
+
+	// Unknown fragments are passed to KNI interface in the same way
+	// as packets which don't belong to any session
+	if port.KNIName != "" {
+		port.dumpPacket(pkt, DirKNI)
+		return DirKNI
+	}
+	// Held fragments are sent later, so their TTL is checked now
+	if pktIPv4.NextProtoID == types.UDPNumber && !pp.forwardHopLimit(port, pkt, pktIPv4, nil, false) {
+		return DirDROP
+	}
+	// Only UDP datagrams are held, TCP normally avoids fragmentation
+	if pktIPv4.NextProtoID != types.UDPNumber || !port.fragments.hold(key, pkt, now) {
+		atomic.AddUint64(&port.fragments.dropped, 1)
+		port.dumpPacket(pkt, DirDROP)
+	}
+	return DirDROP
+}
+
+func (pp *portPair) policeFragment(port *ipPort, flow *fragmentFlow, length uint) bool {
+	egress := port.Type == iPRIVATE
+	if !pp.policingActive(egress) {
+		return true
+	}
+	return pp.policeHost(flow.host, egress, length)
+}
+
+// translateFragment changes layer 2 and layer 3 headers of fragment
+// without transport header.
+func (pp *portPair) translateFragment(port *ipPort, pkt *packet.Packet, pktVLAN *packet.VLANHdr, pktIPv4 *packet.IPv4Hdr, flow *fragmentFlow) {
+	pp.countTraffic(pktIPv4.NextProtoID, port.Type == iPRIVATE, pkt.GetPacketLen(), pktIPv4, nil, flow.host, types.IPv6Address{})
+	pkt.Ether.DAddr = flow.mac
+	pkt.Ether.SAddr = port.opposite.SrcMACAddress
+	if pktVLAN != nil {
+		pktVLAN.SetVLANTagIdentifier(flow.vlan)
+	}
+	if port.Type == iPUBLIC {
+		pktIPv4.DstAddr = packet.SwapBytesIPv4Addr(flow.addr)
+		pp.DSCP.Ingress.apply(pktIPv4, nil)
+	} else {
+		pktIPv4.SrcAddr = packet.SwapBytesIPv4Addr(flow.addr)
+		pp.DSCP.Egress.apply(pktIPv4, nil)
+	}
+	setIPv4HdrChecksum(pkt, !NoCalculateChecksum, port.opposite.hwTXChecksum)
+	atomic.AddUint64(&port.fragments.translated, 1)
+	port.opposite.dumpPacket(pkt, DirSEND)
+}
+
+// updateChecksum adjusts internet checksum after 16 bit word of data
+// changes as described in RFC 1624.
+func updateChecksum(cksum, old, new uint16) uint16 {
+	sum := uint32(^cksum) + uint32(^old) + uint32(new)
+	sum = sum&0xffff + sum>>16
+	sum = sum&0xffff + sum>>16
+	return ^uint16(sum)
+}
+
+// translateFirstFragment sets source or destination address and port
+// of first fragment of a datagram which is sent by out port. Transport
+// checksum covers all fragments, so it is updated instead of being
+// calculated whatever checksum strategy of port is.
+func translateFirstFragment(pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, src bool, addr types.IPv4Address, port uint16,
+	pktTCP *packet.TCPHdr, pktUDP *packet.UDPHdr, pktICMP *packet.ICMPHdr, out *ipPort) {
+	var oldAddr types.IPv4Address
+	if src {
+		oldAddr = packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr)
+		pktIPv4.SrcAddr = packet.SwapBytesIPv4Addr(addr)
+	} else {
+		oldAddr = packet.SwapBytesIPv4Addr(pktIPv4.DstAddr)
+		pktIPv4.DstAddr = packet.SwapBytesIPv4Addr(addr)
+	}
+
+	var cksum, l4port *uint16
+	// ICMP checksum doesn't include pseudo header
+	pseudoHdr := true
+	if pktTCP != nil {
+		cksum = &pktTCP.Cksum
+		l4port = &pktTCP.DstPort
+		if src {
+			l4port = &pktTCP.SrcPort
+		}
+	} else if pktUDP != nil {
+		// Zero UDP checksum means that datagram has no checksum,
+		// UDP-Lite and DCCP always have checksum
+		if pktUDP.DgramCksum != 0 || pktIPv4.NextProtoID != types.UDPNumber {
+			cksum = &pktUDP.DgramCksum
+		}
+		l4port = &pktUDP.DstPort
+		if src {
+			l4port = &pktUDP.SrcPort
+		}
+	} else {
+		cksum = &pktICMP.Cksum
+		l4port = &pktICMP.Identifier
+		pseudoHdr = false
+	}
+	oldPort := packet.SwapBytesUint16(*l4port)
+	*l4port = packet.SwapBytesUint16(port)
+
+	if cksum != nil && !NoCalculateChecksum {
+		c := packet.SwapBytesUint16(*cksum)
+		if pseudoHdr {
+			c = updateChecksum(c, uint16(oldAddr>>16), uint16(addr>>16))
+			c = updateChecksum(c, uint16(oldAddr), uint16(addr))
+		}
+		c = updateChecksum(c, oldPort, port)
+		if pktUDP != nil && c == 0 {
+			c = 0xffff
+		}
+		*cksum = packet.SwapBytesUint16(c)
+		out.countChecksum(checksumIncrementalUpdate)
+	}
+	setIPv4HdrChecksum(pkt, !NoCalculateChecksum, out.hwTXChecksum)
+}
@@ -0,0 +1,239 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/packet"
+)
+
+// GeoIP database file is checked for changes this often
+const geoipReloadInterval = time.Minute
+
+// Country indexes of sessions. Zero index means that session was not
+// tagged yet, countries with ISO 3166 codes are indexed by their two
+// letters.
+const (
+	countryUntagged = iota
+	countryUnknown
+	countryFirst
+	countriesCount = countryFirst + 26*26
+)
+
+// Name of countryUnknown in config and statistics
+const unknownCountryName = "unknown"
+
+type geoipConfig struct {
+	// MaxMind DB file with countries of addresses, e.g. GeoLite2
+	// Country database
+	Database string `json:"database"`
+}
+
+// Current GeoIP database, *mmdbReader value. Database is replaced when
+// its file changes.
+var geoipDB atomic.Value
+
+// Country based policy and accounting of port pair. Remote hosts are
+// destinations of sessions started by private hosts and sources of
+// packets sent to forwarded ports.
+type countryPolicy struct {
+	// Count traffic of sessions by country of remote host
+	Accounting bool `json:"accounting"`
+	// Only remote hosts of these countries are allowed when list is
+	// not empty
+	Allow []string `json:"allow"`
+	// Remote hosts of these countries are denied
+	Deny       []string `json:"deny"`
+	restricted bool
+	denied     [countriesCount]bool
+}
+
+// Traffic of remote hosts of country. Counters are updated from
+// translation handlers, so they should be accessed only atomically.
+type countryCounters struct {
+	// Tagged dynamic sessions
+	sessions       uint64
+	egressPackets  uint64
+	egressBytes    uint64
+	ingressPackets uint64
+	ingressBytes   uint64
+	// Packets denied by country policy
+	deniedPackets uint64
+}
+
+// countryIndex returns index of two letter country code.
+func countryIndex(code string) uint16 {
+	if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+		return countryUnknown
+	}
+	return countryFirst + uint16(code[0]-'A')*26 + uint16(code[1]-'A')
+}
+
+// countryName returns country code of country index.
+func countryName(c uint16) string {
+	if c < countryFirst {
+		return unknownCountryName
+	}
+	c -= countryFirst
+	return string([]byte{byte('A' + c/26), byte('A' + c%26)})
+}
+
+// parseCountry parses country code of config or "unknown" for
+// addresses without country.
+func parseCountry(name string) (uint16, error) {
+	if name == unknownCountryName {
+		return countryUnknown, nil
+	}
+	c := countryIndex(strings.ToUpper(name))
+	if c == countryUnknown {
+		return 0, errors.New("Bad country code " + name)
+	}
+	return c, nil
+}
+
+// check loads GeoIP database if it is configured.
+func (cfg *geoipConfig) check() error {
+	if cfg.Database == "" {
+		return nil
+	}
+	db, err := openMMDB(cfg.Database)
+	if err != nil {
+		return err
+	}
+	geoipDB.Store(db)
+	return nil
+}
+
+// checkCountries parses country policy of port pair and allocates its
+// country counters.
+func (pp *portPair) checkCountries() error {
+	policy := &pp.Countries
+	if len(policy.Allow) != 0 && len(policy.Deny) != 0 {
+		return errors.New("Only one of allowed and denied countries may be specified")
+	}
+	policy.restricted = len(policy.Allow) != 0 || len(policy.Deny) != 0
+	if (policy.restricted || policy.Accounting) && Natconfig.GeoIP.Database == "" {
+		return errors.New("Country policy and accounting require GeoIP database")
+	}
+	if len(policy.Allow) != 0 {
+		for c := range policy.denied {
+			policy.denied[c] = true
+		}
+	}
+	for _, list := range [][]string{policy.Allow, policy.Deny} {
+		for _, name := range list {
+			c, err := parseCountry(name)
+			if err != nil {
+				return err
+			}
+			policy.denied[c] = len(policy.Deny) != 0
+		}
+	}
+	if policy.Accounting {
+		pp.countries = &[countriesCount]countryCounters{}
+	}
+	return nil
+}
+
+// remoteCountry returns country of destination of egress packet or of
+// source of ingress packet. Packet should not be translated yet.
+func remoteCountry(pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr, egress bool) uint16 {
+	v := geoipDB.Load()
+	if v == nil {
+		return countryUnknown
+	}
+	db := v.(*mmdbReader)
+	if pktIPv6 != nil {
+		if egress {
+			return db.country(pktIPv6.DstAddr[:])
+		}
+		return db.country(pktIPv6.SrcAddr[:])
+	}
+	addr := pktIPv4.SrcAddr
+	if egress {
+		addr = pktIPv4.DstAddr
+	}
+	a := packet.SwapBytesIPv4Addr(addr)
+	return db.country([]byte{byte(a >> 24), byte(a >> 16), byte(a >> 8), byte(a)})
+}
+
+// checkCountry returns false if remote host of packet is denied by
+// country policy of port pair.
+func (pp *portPair) checkCountry(pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr, egress bool) bool {
+	if !pp.Countries.restricted {
+		return true
+	}
+	c := remoteCountry(pktIPv4, pktIPv6, egress)
+	if !pp.Countries.denied[c] {
+		return true
+	}
+	if pp.countries != nil {
+		atomic.AddUint64(&pp.countries[c].deniedPackets, 1)
+	}
+	return false
+}
+
+// countCountry tags dynamic session of translated packet with country
+// of its remote host when its first packet is translated and accounts
+// packet to this country. Packets of forwarded ports are accounted to
+// country of their remote host.
+func (pp *portPair) countCountry(pme *portMapEntry, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr, egress bool, length uint) {
+	counters := pp.countries
+	if counters == nil {
+		return
+	}
+	c := pme.country
+	if pme.static || c == countryUntagged {
+		c = remoteCountry(pktIPv4, pktIPv6, egress)
+		if !pme.static {
+			pme.country = c
+			atomic.AddUint64(&counters[c].sessions, 1)
+		}
+	}
+	cc := &counters[c]
+	if egress {
+		atomic.AddUint64(&cc.egressPackets, 1)
+		atomic.AddUint64(&cc.egressBytes, uint64(length))
+	} else {
+		atomic.AddUint64(&cc.ingressPackets, 1)
+		atomic.AddUint64(&cc.ingressBytes, uint64(length))
+	}
+}
+
+// StartGeoIPReloader starts checking GeoIP database file for changes.
+// Changed file is loaded without restart, sessions keep countries they
+// were tagged with.
+func StartGeoIPReloader() {
+	name := Natconfig.GeoIP.Database
+	if name == "" {
+		return
+	}
+	var modTime time.Time
+	if fi, err := os.Stat(name); err == nil {
+		modTime = fi.ModTime()
+	}
+	go func() {
+		for {
+			time.Sleep(geoipReloadInterval)
+			fi, err := os.Stat(name)
+			if err != nil || fi.ModTime().Equal(modTime) {
+				continue
+			}
+			db, err := openMMDB(name)
+			if err != nil {
+				println("Warning! Failed to reload GeoIP database:", err.Error())
+				continue
+			}
+			modTime = fi.ModTime()
+			geoipDB.Store(db)
+			println("Reloaded GeoIP database", name)
+		}
+	}()
+}
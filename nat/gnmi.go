@@ -0,0 +1,785 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/intel-go/nff-go/types"
+
+	upd "github.com/intel-go/nff-go-nat/updatecfg"
+)
+
+const (
+	gnmiVersion      = "0.7.0"
+	gnmiModelName    = "nff-go-nat"
+	gnmiModelVersion = "2019-11-05"
+	gnmiRootName     = "nat"
+
+	gnmiDefaultSampleInterval = 10 * time.Second
+	gnmiOnChangeInterval      = 1 * time.Second
+)
+
+// Keys of YANG lists in the order they are specified in the model.
+var gnmiListKeys = map[string][]string{
+	"port-pair":            {"id"},
+	"forward-port":         {"protocol", "port"},
+	"unsupported-protocol": {"protocol"},
+	"nic-counter":          {"name"},
+}
+
+// gnmiServer implements gNMI service on top of nff-go-nat YANG model
+// (see yang/nff-go-nat.yang). Configuration changes are translated
+// into updatecfg requests so that they go through the same checks.
+type gnmiServer struct {
+	updater *server
+}
+
+type gnmiLeaf struct {
+	path  []*gnmi.PathElem
+	value interface{}
+}
+
+func (port *ipPort) gnmiTree(pp *portPair, dataType gnmi.GetRequest_DataType) map[string]interface{} {
+	result := map[string]interface{}{}
+	if dataType != gnmi.GetRequest_STATE && dataType != gnmi.GetRequest_OPERATIONAL {
+		result["index"] = uint64(port.Index)
+		result["subnet"] = port.Subnet.configString()
+		result["subnet6"] = port.Subnet6.configString()
+		result["vlan-tag"] = uint64(port.Vlan)
+		result["kni-name"] = port.KNIName
+		if port.staticArpMode {
+			result["dst-mac"] = port.DstMACAddress.String()
+		}
+		forwards := []interface{}{}
+		list := pp.forwardedPorts(port)
+		for i := range list {
+			fp := &list[i]
+			forwards = append(forwards, map[string]interface{}{
+				"protocol":    fp.Protocol.String(),
+				"port":        uint64(fp.Port),
+				"destination": fp.Destination.String(),
+			})
+		}
+		result["forward-port"] = forwards
+	}
+	if dataType != gnmi.GetRequest_CONFIG {
+		counters := port.getProtocolCounters()
+		protocols := make([]int, 0, len(counters))
+		for p := range counters {
+			protocols = append(protocols, int(p))
+		}
+		sort.Ints(protocols)
+		unsupported := []interface{}{}
+		for _, p := range protocols {
+			unsupported = append(unsupported, map[string]interface{}{
+				"protocol": uint64(p),
+				"packets":  counters[uint8(p)],
+			})
+		}
+		stats := port.getStats()
+		packetCounters := map[string]interface{}{
+			"rx-packets":   stats.rxPackets,
+			"rx-bytes":     stats.rxBytes,
+			"tx-packets":   stats.txPackets,
+			"tx-bytes":     stats.txBytes,
+			"kni-packets":  stats.kniPackets,
+			"drop-packets": stats.dropPackets,
+		}
+		// Network card counters are omitted when driver doesn't
+		// report them
+		if nic, err := getNICStats(port.Index); err == nil {
+			packetCounters["nic-rx-missed"] = nic.rxMissed
+			packetCounters["nic-rx-errors"] = nic.rxErrors
+			packetCounters["nic-tx-errors"] = nic.txErrors
+			packetCounters["nic-rx-no-mbuf"] = nic.rxNoMbuf
+		}
+		nicCounters := []interface{}{}
+		if xstats, err := getNICXStats(port.Index); err == nil {
+			for _, c := range xstats {
+				nicCounters = append(nicCounters, map[string]interface{}{
+					"name":  c.name,
+					"value": c.value,
+				})
+			}
+		}
+		link := port.getLinkStatus()
+		result["state"] = map[string]interface{}{
+			"mac-address":          port.SrcMACAddress.String(),
+			"address-acquired":     port.Subnet.addressAcquired,
+			"address6-acquired":    port.Subnet6.addressAcquired,
+			"link-local-address":   net.IP(port.Subnet6.llAddr[:]).String(),
+			"link-up":              link.up,
+			"link-speed":           uint64(link.speed),
+			"link-full-duplex":     link.fullDuplex,
+			"maintenance":          portInMaintenance(port),
+			"unsupported-protocol": unsupported,
+			"counters":             packetCounters,
+			"nic-counter":          nicCounters,
+		}
+	}
+	return result
+}
+
+func (c *Config) gnmiTree(dataType gnmi.GetRequest_DataType, updater *server) map[string]interface{} {
+	nat := map[string]interface{}{}
+	if dataType != gnmi.GetRequest_STATE && dataType != gnmi.GetRequest_OPERATIONAL {
+		nat["host-name"] = c.HostName
+	}
+	pairs := []interface{}{}
+	for i := range c.PortPairs {
+		pp := &c.PortPairs[i]
+		// Port pairs of other NAT instances are not visible, ids
+		// of visible port pairs stay the same
+		if !updater.managesPair(pp) {
+			continue
+		}
+		pair := map[string]interface{}{
+			"id":           uint64(i),
+			"private-port": pp.PrivatePort.gnmiTree(pp, dataType),
+			"public-port":  pp.PublicPort.gnmiTree(pp, dataType),
+		}
+		if dataType != gnmi.GetRequest_STATE && dataType != gnmi.GetRequest_OPERATIONAL {
+			pair["tenant"] = pp.Tenant
+		}
+		pairs = append(pairs, pair)
+	}
+	nat["port-pair"] = pairs
+	return map[string]interface{}{
+		gnmiRootName: nat,
+	}
+}
+
+func gnmiFullPath(prefix, path *gnmi.Path) []*gnmi.PathElem {
+	elems := []*gnmi.PathElem{}
+	elems = append(elems, prefix.GetElem()...)
+	elems = append(elems, path.GetElem()...)
+	return elems
+}
+
+func gnmiPathString(elems []*gnmi.PathElem) string {
+	str := ""
+	for _, e := range elems {
+		str += "/" + e.GetName()
+		if keys, ok := gnmiListKeys[e.GetName()]; ok {
+			for _, k := range keys {
+				if v, ok := e.GetKey()[k]; ok {
+					str += "[" + k + "=" + v + "]"
+				}
+			}
+		}
+	}
+	if str == "" {
+		return "/"
+	}
+	return str
+}
+
+// gnmiWalk finds all nodes of data tree which match path. Lists are
+// expanded into individual entries, missing or "*" keys match any
+// entry.
+func gnmiWalk(node interface{}, elems, current []*gnmi.PathElem, result []gnmiLeaf) []gnmiLeaf {
+	if len(elems) == 0 {
+		return append(result, gnmiLeaf{
+			path:  current,
+			value: node,
+		})
+	}
+
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		return result
+	}
+	e := elems[0]
+	child, ok := m[e.GetName()]
+	if !ok {
+		return result
+	}
+
+	list, ok := child.([]interface{})
+	if !ok {
+		next := append(append([]*gnmi.PathElem{}, current...), &gnmi.PathElem{Name: e.GetName()})
+		return gnmiWalk(child, elems[1:], next, result)
+	}
+
+	for _, item := range list {
+		entry := item.(map[string]interface{})
+		keys := map[string]string{}
+		matches := true
+		for _, k := range gnmiListKeys[e.GetName()] {
+			value := fmt.Sprint(entry[k])
+			if want, ok := e.GetKey()[k]; ok && want != "*" && want != value {
+				matches = false
+				break
+			}
+			keys[k] = value
+		}
+		if matches {
+			next := append(append([]*gnmi.PathElem{}, current...), &gnmi.PathElem{Name: e.GetName(), Key: keys})
+			result = gnmiWalk(entry, elems[1:], next, result)
+		}
+	}
+	return result
+}
+
+func gnmiEncodeValue(value interface{}, encoding gnmi.Encoding) (*gnmi.TypedValue, error) {
+	switch v := value.(type) {
+	case string:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: v}}, nil
+	case uint64:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: v}}, nil
+	case bool:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_BoolVal{BoolVal: v}}, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if encoding == gnmi.Encoding_JSON {
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_JsonVal{JsonVal: data}}, nil
+	}
+	return &gnmi.TypedValue{Value: &gnmi.TypedValue_JsonIetfVal{JsonIetfVal: data}}, nil
+}
+
+func checkGNMIEncoding(encoding gnmi.Encoding) error {
+	if encoding != gnmi.Encoding_JSON && encoding != gnmi.Encoding_JSON_IETF {
+		return status.Errorf(codes.Unimplemented, "Encoding %s is not supported", encoding.String())
+	}
+	return nil
+}
+
+// Collects updates for one path. Paths of updates are relative to
+// prefix.
+func gnmiCollectUpdates(updater *server, prefix, path *gnmi.Path, dataType gnmi.GetRequest_DataType, encoding gnmi.Encoding) ([]*gnmi.Update, error) {
+	leafs := gnmiWalk(Natconfig.gnmiTree(dataType, updater), gnmiFullPath(prefix, path), nil, nil)
+	if len(leafs) == 0 {
+		return nil, status.Errorf(codes.NotFound, "Path %s not found", gnmiPathString(gnmiFullPath(prefix, path)))
+	}
+
+	prefixLen := len(prefix.GetElem())
+	updates := []*gnmi.Update{}
+	for _, l := range leafs {
+		val, err := gnmiEncodeValue(l.value, encoding)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to encode value: %+v", err)
+		}
+		updates = append(updates, &gnmi.Update{
+			Path: &gnmi.Path{Elem: l.path[prefixLen:]},
+			Val:  val,
+		})
+	}
+	return updates, nil
+}
+
+func (g *gnmiServer) Capabilities(ctx context.Context, in *gnmi.CapabilityRequest) (*gnmi.CapabilityResponse, error) {
+	return &gnmi.CapabilityResponse{
+		SupportedModels: []*gnmi.ModelData{
+			&gnmi.ModelData{
+				Name:         gnmiModelName,
+				Organization: "Intel Corporation",
+				Version:      gnmiModelVersion,
+			},
+		},
+		SupportedEncodings: []gnmi.Encoding{gnmi.Encoding_JSON, gnmi.Encoding_JSON_IETF},
+		GNMIVersion:        gnmiVersion,
+	}, nil
+}
+
+func (g *gnmiServer) Get(ctx context.Context, in *gnmi.GetRequest) (*gnmi.GetResponse, error) {
+	if err := checkGNMIEncoding(in.GetEncoding()); err != nil {
+		return nil, err
+	}
+
+	paths := in.GetPath()
+	if len(paths) == 0 {
+		paths = []*gnmi.Path{&gnmi.Path{}}
+	}
+	notifications := []*gnmi.Notification{}
+	for _, p := range paths {
+		updates, err := gnmiCollectUpdates(g.updater, in.GetPrefix(), p, in.GetType(), in.GetEncoding())
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, &gnmi.Notification{
+			Timestamp: time.Now().UnixNano(),
+			Prefix:    in.GetPrefix(),
+			Update:    updates,
+		})
+	}
+	return &gnmi.GetResponse{
+		Notification: notifications,
+	}, nil
+}
+
+func gnmiDecodeValue(val *gnmi.TypedValue) (interface{}, error) {
+	switch v := val.GetValue().(type) {
+	case *gnmi.TypedValue_StringVal:
+		return v.StringVal, nil
+	case *gnmi.TypedValue_UintVal:
+		return v.UintVal, nil
+	case *gnmi.TypedValue_JsonVal:
+		var result interface{}
+		err := json.Unmarshal(v.JsonVal, &result)
+		return result, err
+	case *gnmi.TypedValue_JsonIetfVal:
+		var result interface{}
+		err := json.Unmarshal(v.JsonIetfVal, &result)
+		return result, err
+	}
+	return nil, fmt.Errorf("Unsupported value type %T", val.GetValue())
+}
+
+// Finds port referenced by path in a form of
+// /nat/port-pair[id=N]/{private|public}-port and returns remaining
+// path elements. Port pair should be managed by updater.
+func gnmiPortFromPath(updater *server, elems []*gnmi.PathElem) (*ipPort, []*gnmi.PathElem, error) {
+	if len(elems) < 3 || elems[0].GetName() != gnmiRootName || elems[1].GetName() != "port-pair" {
+		return nil, nil, fmt.Errorf("Path should start with /%s/port-pair[id=N]/", gnmiRootName)
+	}
+	id, err := strconv.ParseUint(elems[1].GetKey()["id"], 10, 32)
+	if err != nil || !updater.managesPairIndex(int(id)) {
+		return nil, nil, fmt.Errorf("Bad port pair id \"%s\"", elems[1].GetKey()["id"])
+	}
+	pp := &Natconfig.PortPairs[id]
+	switch elems[2].GetName() {
+	case "private-port":
+		return &pp.PrivatePort, elems[3:], nil
+	case "public-port":
+		return &pp.PublicPort, elems[3:], nil
+	}
+	return nil, nil, fmt.Errorf("Unknown port \"%s\", should be private-port or public-port", elems[2].GetName())
+}
+
+func gnmiIPAddress(ip net.IP) *upd.IPAddress {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return &upd.IPAddress{
+		Address: ip,
+	}
+}
+
+// gnmiChange is a change of Set request which paths and values are
+// already checked. It returns function which reverts the change after
+// it was made.
+type gnmiChange func(ctx context.Context) (func(ctx context.Context) error, error)
+
+func (g *gnmiServer) changeSubnet(ctx context.Context, port *ipPort, subnet string) error {
+	ip, ipnet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return err
+	}
+	ones, _ := ipnet.Mask.Size()
+	_, err = g.updater.ChangeInterfaceAddress(ctx, &upd.InterfaceAddressChangeRequest{
+		InterfaceId: uint32(port.Index),
+		PortSubnet: &upd.Subnet{
+			Address:        gnmiIPAddress(ip),
+			MaskBitsNumber: uint32(ones),
+		},
+	})
+	return err
+}
+
+// prepareSubnet checks subnet value and returns change which sets it.
+// Previous subnet of port is restored when change fails or is
+// reverted.
+func (g *gnmiServer) prepareSubnet(port *ipPort, value interface{}) (gnmiChange, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("Subnet value should be a string")
+	}
+	ip, _, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, err
+	}
+	ipv6 := ip.To4() == nil
+	current := func() string {
+		if ipv6 {
+			return port.Subnet6.String()
+		}
+		return port.Subnet.String()
+	}
+	return func(ctx context.Context) (func(ctx context.Context) error, error) {
+		previous := current()
+		restore := func(ctx context.Context) error {
+			if err := g.changeSubnet(ctx, port, previous); err != nil {
+				return fmt.Errorf("Failed to restore subnet %s of port %d: %+v", previous, port.Index, err)
+			}
+			return nil
+		}
+		if err := g.changeSubnet(ctx, port, s); err != nil {
+			// Failed change may have already set new address
+			if current() != previous {
+				if rerr := restore(ctx); rerr != nil {
+					return nil, fmt.Errorf("%+v, %+v", err, rerr)
+				}
+			}
+			return nil, err
+		}
+		return restore, nil
+	}, nil
+}
+
+// prepareForwardPort checks forwarded port entry and returns change
+// which adds, replaces or deletes it. Previous rule of the same port
+// number is restored when change is reverted.
+func (g *gnmiServer) prepareForwardPort(port *ipPort, e *gnmi.PathElem, destination string, enable bool) (gnmiChange, error) {
+	proto, ok := protocolIdLookup[e.GetKey()["protocol"]]
+	if !ok {
+		return nil, fmt.Errorf("Bad protocol \"%s\"", e.GetKey()["protocol"])
+	}
+	number, err := strconv.ParseUint(e.GetKey()["port"], 10, 16)
+	if err != nil {
+		return nil, err
+	}
+	if enable {
+		var dst hostPort
+		data, _ := json.Marshal(destination)
+		if err := dst.UnmarshalJSON(data); err != nil {
+			return nil, err
+		}
+	}
+
+	var protocol upd.Protocol = upd.Protocol(proto.id)
+	if proto.ipv6 {
+		protocol |= upd.Protocol_IPv6_Flag
+	}
+
+	return func(ctx context.Context) (func(ctx context.Context) error, error) {
+		_, pp := g.updater.getPortAndPairByID(uint32(port.Index))
+		var previous *forwardedPort
+		list := pp.forwardedPorts(port)
+		for i := range list {
+			if list[i].Port == uint16(number) && list[i].Protocol == proto {
+				fp := list[i]
+				previous = &fp
+			}
+		}
+		// Deleted rule is looked up in current forwarding list
+		// because its destination is necessary to pass forwarding
+		// checks
+		target := destination
+		if !enable {
+			if previous == nil {
+				return nil, fmt.Errorf("Forwarded port %s is not found", gnmiPathString([]*gnmi.PathElem{e}))
+			}
+			target = previous.Destination.String()
+		}
+
+		var dst hostPort
+		data, _ := json.Marshal(target)
+		if err := dst.UnmarshalJSON(data); err != nil {
+			return nil, err
+		}
+		var addr net.IP
+		if dst.ipv6 {
+			addr = net.IP(dst.Addr6[:])
+		} else {
+			a := types.IPv4ToBytes(dst.Addr4)
+			addr = net.IPv4(a[3], a[2], a[1], a[0])
+		}
+
+		_, err := g.updater.ChangePortForwarding(ctx, &upd.PortForwardingChangeRequest{
+			EnableForwarding: enable,
+			InterfaceId:      uint32(port.Index),
+			Port: &upd.ForwardedPort{
+				SourcePortNumber: uint32(number),
+				TargetAddress:    gnmiIPAddress(addr),
+				TargetPortNumber: uint32(dst.Port),
+				Protocol:         protocol,
+				TargetZone:       dst.zone,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		// Previous rule is restored as it was, with settings which
+		// gNMI doesn't change
+		return func(ctx context.Context) error {
+			if previous != nil {
+				pp.setForwardedPort(port, previous, true)
+			} else {
+				pp.setForwardedPort(port, &forwardedPort{Port: uint16(number), Protocol: proto}, false)
+			}
+			return nil
+		}, nil
+	}, nil
+}
+
+// prepareSet checks path and value of Set operation and returns change
+// which makes it.
+func (g *gnmiServer) prepareSet(elems []*gnmi.PathElem, value interface{}, enable bool) (gnmiChange, error) {
+	port, rest, err := gnmiPortFromPath(g.updater, elems)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) == 1 && enable && (rest[0].GetName() == "subnet" || rest[0].GetName() == "subnet6") {
+		return g.prepareSubnet(port, value)
+	}
+	if len(rest) >= 1 && rest[0].GetName() == "forward-port" {
+		var destination string
+		if enable {
+			if len(rest) == 2 && rest[1].GetName() == "destination" {
+				destination, _ = value.(string)
+			} else if len(rest) == 1 {
+				obj, _ := value.(map[string]interface{})
+				destination, _ = obj["destination"].(string)
+			}
+			if destination == "" {
+				return nil, fmt.Errorf("Forwarded port requires string destination value")
+			}
+		} else if len(rest) != 1 {
+			return nil, fmt.Errorf("Only whole forwarded port entries may be deleted")
+		}
+		return g.prepareForwardPort(port, rest[0], destination, enable)
+	}
+	return nil, fmt.Errorf("Path %s cannot be changed", gnmiPathString(elems))
+}
+
+// Set applies all changes of request or none of them. All paths and
+// values are checked before any change is made, and when a change
+// fails, changes which were already made are reverted in reverse
+// order.
+func (g *gnmiServer) Set(ctx context.Context, in *gnmi.SetRequest) (*gnmi.SetResponse, error) {
+	results := []*gnmi.UpdateResult{}
+	changes := []gnmiChange{}
+	names := []string{}
+
+	for _, p := range in.GetDelete() {
+		change, err := g.prepareSet(gnmiFullPath(in.GetPrefix(), p), nil, false)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Delete %s failed: %+v", gnmiPathString(p.GetElem()), err)
+		}
+		changes = append(changes, change)
+		names = append(names, "Delete "+gnmiPathString(p.GetElem()))
+		results = append(results, &gnmi.UpdateResult{Path: p, Op: gnmi.UpdateResult_DELETE})
+	}
+
+	for _, op := range []struct {
+		updates []*gnmi.Update
+		op      gnmi.UpdateResult_Operation
+	}{
+		{in.GetReplace(), gnmi.UpdateResult_REPLACE},
+		{in.GetUpdate(), gnmi.UpdateResult_UPDATE},
+	} {
+		for _, u := range op.updates {
+			value, err := gnmiDecodeValue(u.GetVal())
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "Bad value for %s: %+v", gnmiPathString(u.GetPath().GetElem()), err)
+			}
+			change, err := g.prepareSet(gnmiFullPath(in.GetPrefix(), u.GetPath()), value, true)
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "%s %s failed: %+v", op.op.String(), gnmiPathString(u.GetPath().GetElem()), err)
+			}
+			changes = append(changes, change)
+			names = append(names, op.op.String()+" "+gnmiPathString(u.GetPath().GetElem()))
+			results = append(results, &gnmi.UpdateResult{Path: u.GetPath(), Op: op.op})
+		}
+	}
+
+	reverts := []func(ctx context.Context) error{}
+	for i, change := range changes {
+		revert, err := change(ctx)
+		if err == nil {
+			reverts = append(reverts, revert)
+			continue
+		}
+		msg := fmt.Sprintf("%s failed: %+v", names[i], err)
+		for j := len(reverts) - 1; j >= 0; j-- {
+			if rerr := reverts[j](ctx); rerr != nil {
+				msg += fmt.Sprintf(", revert of %s failed: %+v", names[j], rerr)
+			}
+		}
+		return nil, status.Errorf(codes.InvalidArgument, "%s", msg)
+	}
+
+	return &gnmi.SetResponse{
+		Prefix:    in.GetPrefix(),
+		Response:  results,
+		Timestamp: time.Now().UnixNano(),
+	}, nil
+}
+
+type gnmiSubscribeStream struct {
+	stream  gnmi.GNMI_SubscribeServer
+	updater *server
+	mutex   sync.Mutex
+}
+
+func (s *gnmiSubscribeStream) send(response *gnmi.SubscribeResponse) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.stream.Send(response)
+}
+
+func (s *gnmiSubscribeStream) sendUpdates(list *gnmi.SubscriptionList, subscriptions []*gnmi.Subscription) error {
+	for _, sub := range subscriptions {
+		updates, err := gnmiCollectUpdates(s.updater, list.GetPrefix(), sub.GetPath(), gnmi.GetRequest_ALL, list.GetEncoding())
+		if err != nil {
+			return err
+		}
+		err = s.send(&gnmi.SubscribeResponse{
+			Response: &gnmi.SubscribeResponse_Update{
+				Update: &gnmi.Notification{
+					Timestamp: time.Now().UnixNano(),
+					Prefix:    list.GetPrefix(),
+					Update:    updates,
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *gnmiSubscribeStream) sendSync() error {
+	return s.send(&gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_SyncResponse{
+			SyncResponse: true,
+		},
+	})
+}
+
+// Periodically sends updates for one subscription of STREAM mode
+// subscription list. ON_CHANGE and TARGET_DEFINED subscriptions are
+// sampled frequently and only changed values are sent.
+func (s *gnmiSubscribeStream) streamSubscription(list *gnmi.SubscriptionList, sub *gnmi.Subscription, errs chan error) {
+	interval := time.Duration(sub.GetSampleInterval())
+	onChange := sub.GetMode() != gnmi.SubscriptionMode_SAMPLE
+	if onChange {
+		interval = gnmiOnChangeInterval
+	} else if interval == 0 {
+		interval = gnmiDefaultSampleInterval
+	}
+
+	last := map[string]string{}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for first := true; ; first = false {
+		if !first {
+			select {
+			case <-s.stream.Context().Done():
+				return
+			case <-ticker.C:
+			}
+		}
+
+		updates, err := gnmiCollectUpdates(s.updater, list.GetPrefix(), sub.GetPath(), gnmi.GetRequest_ALL, list.GetEncoding())
+		if err != nil {
+			errs <- err
+			return
+		}
+		changed := []*gnmi.Update{}
+		for _, u := range updates {
+			key := gnmiPathString(u.GetPath().GetElem())
+			value := u.GetVal().String()
+			if !onChange || last[key] != value {
+				changed = append(changed, u)
+			}
+			last[key] = value
+		}
+		if first && list.GetUpdatesOnly() {
+			continue
+		}
+		if len(changed) == 0 {
+			continue
+		}
+		err = s.send(&gnmi.SubscribeResponse{
+			Response: &gnmi.SubscribeResponse_Update{
+				Update: &gnmi.Notification{
+					Timestamp: time.Now().UnixNano(),
+					Prefix:    list.GetPrefix(),
+					Update:    changed,
+				},
+			},
+		})
+		if err != nil {
+			errs <- err
+			return
+		}
+	}
+}
+
+func (g *gnmiServer) Subscribe(stream gnmi.GNMI_SubscribeServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	list := req.GetSubscribe()
+	if list == nil {
+		return status.Errorf(codes.InvalidArgument, "First subscribe request should contain subscription list")
+	}
+	if err := checkGNMIEncoding(list.GetEncoding()); err != nil {
+		return err
+	}
+
+	s := gnmiSubscribeStream{
+		stream:  stream,
+		updater: g.updater,
+	}
+	switch list.GetMode() {
+	case gnmi.SubscriptionList_ONCE:
+		if err := s.sendUpdates(list, list.GetSubscription()); err != nil {
+			return err
+		}
+		return s.sendSync()
+	case gnmi.SubscriptionList_POLL:
+		for {
+			if err := s.sendUpdates(list, list.GetSubscription()); err != nil {
+				return err
+			}
+			if err := s.sendSync(); err != nil {
+				return err
+			}
+			req, err := stream.Recv()
+			if err != nil {
+				return err
+			}
+			if req.GetPoll() == nil {
+				return status.Errorf(codes.InvalidArgument, "Only poll requests are allowed for POLL subscription")
+			}
+		}
+	}
+
+	// STREAM mode. Initial values are sent by subscription goroutines
+	// so sync response is sent after first round of updates.
+	if !list.GetUpdatesOnly() {
+		if err := s.sendUpdates(list, list.GetSubscription()); err != nil {
+			return err
+		}
+	}
+	if err := s.sendSync(); err != nil {
+		return err
+	}
+	errs := make(chan error, len(list.GetSubscription()))
+	for _, sub := range list.GetSubscription() {
+		go s.streamSubscription(&gnmi.SubscriptionList{
+			Prefix:      list.GetPrefix(),
+			Encoding:    list.GetEncoding(),
+			UpdatesOnly: true,
+		}, sub, errs)
+	}
+	select {
+	case <-stream.Context().Done():
+		return stream.Context().Err()
+	case err := <-errs:
+		return err
+	}
+}
@@ -5,32 +5,78 @@
 package nat
 
 import (
+	"bytes"
 	"fmt"
 	"net"
+	"sort"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 
+	"github.com/openconfig/gnmi/proto/gnmi"
+
 	"github.com/intel-go/nff-go/common"
 	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
 
 	upd "github.com/intel-go/nff-go-nat/updatecfg"
 )
 
 const (
 	GRPCServerPort = ":60602"
+	// Largest request accepted by GRPC server. Session snapshots of
+	// several port pairs are bigger than default 4 MB limit.
+	GRPCMaxMessageSize = 256 << 20
+	// Interval in milliseconds over which packet rates of flow
+	// graph edges are measured by default and at most
+	defaultFlowGraphInterval = 1000
+	maxFlowGraphInterval     = 5000
 )
 
-type server struct{}
+// server implements updatecfg service. Server of NAT instance
+// manages only port pairs of its instance, main server manages all
+// port pairs.
+type server struct {
+	instance *natInstance
+}
 
+// StartGRPCServer starts main control API server and servers of NAT
+// instances.
 func StartGRPCServer() error {
-	lis, err := net.Listen("tcp", GRPCServerPort)
+	if err := startGRPCServer(GRPCServerPort, &Natconfig.ControlAPI, nil); err != nil {
+		return err
+	}
+	for i := range Natconfig.Instances {
+		inst := &Natconfig.Instances[i]
+		if err := startGRPCServer(inst.GRPCAddress, &inst.ControlAPI, inst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startGRPCServer(address string, cfg *controlAPIConfig, instance *natInstance) error {
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	opts, err := cfg.serverOptions()
 	if err != nil {
+		lis.Close()
 		return err
 	}
-	s := grpc.NewServer()
-	upd.RegisterUpdaterServer(s, &server{})
+	opts = append(opts, grpc.MaxRecvMsgSize(GRPCMaxMessageSize))
+	s := grpc.NewServer(opts...)
+	updater := &server{
+		instance: instance,
+	}
+	upd.RegisterUpdaterServer(s, updater)
+	gnmi.RegisterGNMIServer(s, &gnmiServer{
+		updater: updater,
+	})
 	// Register reflection service on gRPC server.
 	reflection.Register(s)
 
@@ -42,13 +88,36 @@ func StartGRPCServer() error {
 	return nil
 }
 
+// getPortAndPairByID finds port which is managed by server.
+func (s *server) getPortAndPairByID(portId uint32) (*ipPort, *portPair) {
+	port, pp := Natconfig.getPortAndPairByID(portId)
+	if pp == nil || !s.managesPair(pp) {
+		return nil, nil
+	}
+	return port, pp
+}
+
+func (s *server) managesPair(pp *portPair) bool {
+	return s.instance == nil || pp.instance == s.instance
+}
+
+// managesPairIndex returns true if port pair with index in config
+// exists and is managed by server.
+func (s *server) managesPairIndex(index int) bool {
+	return index >= 0 && index < len(Natconfig.PortPairs) && s.managesPair(&Natconfig.PortPairs[index])
+}
+
 func (s *server) ControlDump(ctx context.Context, in *upd.DumpControlRequest) (*upd.Reply, error) {
 	enable := in.GetEnableTrace()
 	dumpType := in.GetTraceType()
 	if dumpType < upd.TraceType_DUMP_DROP || dumpType > upd.TraceType_DUMP_KNI {
 		return nil, fmt.Errorf("Bad value of dump type: %d", dumpType)
 	}
-	DumpEnabled[dumpType] = enable
+	if s.instance != nil {
+		s.instance.dumpEnabled[dumpType] = enable
+	} else {
+		DumpEnabled[dumpType] = enable
+	}
 
 	return &upd.Reply{
 		Msg: "Success",
@@ -57,7 +126,7 @@ func (s *server) ControlDump(ctx context.Context, in *upd.DumpControlRequest) (*
 
 func (s *server) ChangeInterfaceAddress(ctx context.Context, in *upd.InterfaceAddressChangeRequest) (*upd.Reply, error) {
 	portId := in.GetInterfaceId()
-	port, _ := Natconfig.getPortAndPairByID(portId)
+	port, pp := s.getPortAndPairByID(portId)
 	if port == nil {
 		return nil, fmt.Errorf("Interface with ID %d not found", portId)
 	}
@@ -65,13 +134,26 @@ func (s *server) ChangeInterfaceAddress(ctx context.Context, in *upd.InterfaceAd
 	if err != nil {
 		return nil, err
 	}
+	if port.familyDisabled(subnet6 != nil) {
+		return nil, fmt.Errorf("IP family of address is disabled on interface with ID %d", portId)
+	}
+	keep := in.GetKeepSessions()
+	if keep && port.Type != iPUBLIC {
+		return nil, fmt.Errorf("Sessions of old address can be kept only on public port, interface with ID %d is private", portId)
+	}
 
+	// Sessions are switched to retired address together with address
+	// change, so that no new session gets old address after it
+	var kept bool
 	var str string
 	if subnet4 != nil {
 		oldaddr := port.Subnet.Addr
 		oldmask := port.Subnet.Mask
+		pp.mutex.Lock()
+		kept = keep && oldaddr != subnet4.Addr && pp.retirePublicAddress(false)
 		port.Subnet.Addr = subnet4.Addr
 		port.Subnet.Mask = subnet4.Mask
+		pp.mutex.Unlock()
 		err = port.setLinkIPv4KNIAddress(port.Subnet.Addr, port.Subnet.Mask, oldaddr, oldmask, Natconfig.bringUpKniInterfaces)
 		port.Subnet.addressAcquired = err == nil
 		str = port.Subnet.String()
@@ -79,8 +161,20 @@ func (s *server) ChangeInterfaceAddress(ctx context.Context, in *upd.InterfaceAd
 	if subnet6 != nil {
 		oldaddr := port.Subnet6.Addr
 		oldmask := port.Subnet6.Mask
+		pp.mutex.Lock()
+		kept = keep && oldaddr != subnet6.Addr && pp.retirePublicAddress(true)
 		port.Subnet6.Addr = subnet6.Addr
 		port.Subnet6.Mask = subnet6.Mask
+		pp.mutex.Unlock()
+		port.Subnet6.duplicate = false
+		if !port.DAD.Disable && (oldaddr != port.Subnet6.Addr || !port.Subnet6.addressAcquired) {
+			port.Subnet6.addressAcquired = false
+			packet.CalculateIPv6MulticastAddrForDstIP(&port.Subnet6.multicastAddr, port.Subnet6.Addr)
+			if mac, duplicate := port.detectDuplicateAddress(port.Subnet6.Addr); duplicate {
+				port.Subnet6.duplicate = true
+				return nil, fmt.Errorf("Address %s is already used by host %s", port.Subnet6.Addr.String(), mac.String())
+			}
+		}
 		if !port.Subnet6.addressAcquired {
 			port.setLinkIPv6KNIAddress(port.Subnet6.llAddr, SingleIPMask, zeroIPv6Addr, zeroIPv6Addr, Natconfig.bringUpKniInterfaces)
 		}
@@ -95,6 +189,9 @@ func (s *server) ChangeInterfaceAddress(ctx context.Context, in *upd.InterfaceAd
 	if err != nil {
 		return nil, err
 	}
+	if kept {
+		str += ", sessions of old address are kept until they expire"
+	}
 
 	return &upd.Reply{
 		Msg: fmt.Sprintf("Successfully set port %d subnet to %s", portId, str),
@@ -103,7 +200,7 @@ func (s *server) ChangeInterfaceAddress(ctx context.Context, in *upd.InterfaceAd
 
 func (s *server) ChangePortForwarding(ctx context.Context, in *upd.PortForwardingChangeRequest) (*upd.Reply, error) {
 	portId := in.GetInterfaceId()
-	port, pp := Natconfig.getPortAndPairByID(portId)
+	port, pp := s.getPortAndPairByID(portId)
 	if port == nil {
 		return nil, fmt.Errorf("Interface with ID %d not found", portId)
 	}
@@ -116,19 +213,1419 @@ func (s *server) ChangePortForwarding(ctx context.Context, in *upd.PortForwardin
 	if err != nil {
 		return nil, err
 	}
+	if port.Type == iPUBLIC && in.GetEnableForwarding() && pp.isLocalPort(fp.Port) &&
+		(fp.Protocol.id == types.TCPNumber || fp.Protocol.id == types.UDPNumber) {
+		return nil, fmt.Errorf("Port %d is reserved in local-ports of interface %d and cannot be forwarded", fp.Port, portId)
+	}
 
-	pp.mutex.Lock()
-	if port.Type == iPUBLIC {
-		pp.deleteOldConnection(fp.Protocol.ipv6, fp.Protocol.id, int(fp.Port))
-	} else {
-		port.deletePortForwardingEntry(fp.Protocol.ipv6, fp.Protocol.id, int(fp.Port))
+	pp.setForwardedPort(port, fp, in.GetEnableForwarding())
+
+	return &upd.Reply{
+		Msg: "Success",
+	}, nil
+}
+
+func (s *server) getNeighborsPort(portId uint32) (*ipPort, error) {
+	port, _ := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+	if port.staticArpMode {
+		return nil, fmt.Errorf("Interface with ID %d uses static destination MAC address %s, neighbor table is not used",
+			portId, port.DstMACAddress.String())
+	}
+	return port, nil
+}
+
+func (s *server) GetNeighbors(ctx context.Context, in *upd.NeighborsRequest) (*upd.NeighborsReply, error) {
+	port, err := s.getNeighborsPort(in.GetInterfaceId())
+	if err != nil {
+		return nil, err
+	}
+
+	neighbors := []*upd.Neighbor{}
+	port.arpTable.Range(func(k, v interface{}) bool {
+		var addr []byte
+		switch ip := k.(type) {
+		case types.IPv4Address:
+			a := types.IPv4ToBytes(ip)
+			addr = []byte{a[3], a[2], a[1], a[0]}
+		case types.IPv6Address:
+			addr = append([]byte{}, ip[:]...)
+		}
+		entry := v.(neighborEntry)
+		neighbors = append(neighbors, &upd.Neighbor{
+			Address: &upd.IPAddress{
+				Address: addr,
+			},
+			MacAddress: append([]byte{}, entry.mac[:]...),
+			Static:     entry.static,
+		})
+		return true
+	})
+	sort.Slice(neighbors, func(i, j int) bool {
+		a, b := neighbors[i].GetAddress().GetAddress(), neighbors[j].GetAddress().GetAddress()
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return bytes.Compare(a, b) < 0
+	})
+
+	return &upd.NeighborsReply{
+		Neighbors: neighbors,
+		Tenant:    port.tenant,
+	}, nil
+}
+
+func (s *server) AddStaticNeighbor(ctx context.Context, in *upd.NeighborChangeRequest) (*upd.Reply, error) {
+	port, err := s.getNeighborsPort(in.GetInterfaceId())
+	if err != nil {
+		return nil, err
+	}
+	ip, err := convertNeighborAddress(in.GetAddress())
+	if err != nil {
+		return nil, err
+	}
+	if len(in.GetMacAddress()) != types.EtherAddrLen {
+		return nil, fmt.Errorf("Bad MAC address length %d", len(in.GetMacAddress()))
+	}
+	var mac types.MACAddress
+	copy(mac[:], in.GetMacAddress())
+
+	port.arpTable.Store(ip, neighborEntry{
+		mac:    mac,
+		static: true,
+	})
+
+	return &upd.Reply{
+		Msg: fmt.Sprintf("Successfully added static neighbor %s with MAC %s to port %d",
+			net.IP(in.GetAddress().GetAddress()).String(), mac.String(), in.GetInterfaceId()),
+	}, nil
+}
+
+func (s *server) DeleteNeighbor(ctx context.Context, in *upd.NeighborChangeRequest) (*upd.Reply, error) {
+	port, err := s.getNeighborsPort(in.GetInterfaceId())
+	if err != nil {
+		return nil, err
+	}
+	ip, err := convertNeighborAddress(in.GetAddress())
+	if err != nil {
+		return nil, err
 	}
-	if in.GetEnableForwarding() {
-		port.enableStaticPortForward(fp)
+	if _, found := port.arpTable.Load(ip); !found {
+		return nil, fmt.Errorf("Neighbor %s not found on port %d",
+			net.IP(in.GetAddress().GetAddress()).String(), in.GetInterfaceId())
 	}
-	pp.mutex.Unlock()
+	port.arpTable.Delete(ip)
 
 	return &upd.Reply{
 		Msg: "Success",
 	}, nil
 }
+
+func (s *server) FlushNeighbors(ctx context.Context, in *upd.NeighborsFlushRequest) (*upd.Reply, error) {
+	port, err := s.getNeighborsPort(in.GetInterfaceId())
+	if err != nil {
+		return nil, err
+	}
+
+	count := port.flushNeighbors(in.GetFlushStatic())
+
+	return &upd.Reply{
+		Msg: fmt.Sprintf("Successfully flushed %d neighbors on port %d", count, in.GetInterfaceId()),
+	}, nil
+}
+
+func dhcpLeaseReply(port *ipPort, ipv6 bool) *upd.DHCPLeaseReply {
+	reply := &upd.DHCPLeaseReply{
+		InterfaceId: uint32(port.Index),
+		Ipv6:        ipv6,
+		Tenant:      port.tenant,
+	}
+
+	var lease dhcpLease
+	if ipv6 {
+		reply.State = upd.DHCPState(port.Subnet6.dhcpState())
+		lease = port.Subnet6.ds.lease
+		if port.Subnet6.addressAcquired {
+			ones, _ := net.IPMask(port.Subnet6.Mask[:]).Size()
+			reply.Subnet = &upd.Subnet{
+				Address: &upd.IPAddress{
+					Address: append([]byte{}, port.Subnet6.Addr[:]...),
+				},
+				MaskBitsNumber: uint32(ones),
+			}
+		}
+	} else {
+		reply.State = upd.DHCPState(port.Subnet.dhcpState())
+		lease = port.Subnet.ds.lease
+		if port.Subnet.addressAcquired {
+			a := types.IPv4ToBytes(port.Subnet.Addr)
+			m := types.IPv4ToBytes(port.Subnet.Mask)
+			ones, _ := net.IPv4Mask(m[3], m[2], m[1], m[0]).Size()
+			reply.Subnet = &upd.Subnet{
+				Address: &upd.IPAddress{
+					Address: []byte{a[3], a[2], a[1], a[0]},
+				},
+				MaskBitsNumber: uint32(ones),
+			}
+		}
+	}
+
+	if reply.State == upd.DHCPState_DHCP_BOUND || reply.State == upd.DHCPState_DHCP_RENEWING {
+		if lease.server != nil {
+			reply.Server = &upd.IPAddress{
+				Address: lease.server,
+			}
+		}
+		reply.LeaseSeconds = uint32(lease.duration / time.Second)
+		reply.RenewSeconds = uint32(lease.t1 / time.Second)
+		reply.RebindSeconds = uint32(lease.t2 / time.Second)
+		reply.LeaseObtained = lease.obtained.Unix()
+		reply.LeaseExpires = lease.obtained.Add(lease.duration).Unix()
+	}
+	return reply
+}
+
+func (s *server) GetDHCPLease(ctx context.Context, in *upd.DHCPLeaseRequest) (*upd.DHCPLeaseReply, error) {
+	portId := in.GetInterfaceId()
+	port, _ := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+	return dhcpLeaseReply(port, in.GetIpv6()), nil
+}
+
+func (s *server) ControlDHCP(ctx context.Context, in *upd.DHCPControlRequest) (*upd.DHCPLeaseReply, error) {
+	portId := in.GetInterfaceId()
+	port, _ := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+
+	ipv6 := in.GetIpv6()
+	var state int
+	if ipv6 {
+		state = port.Subnet6.dhcpState()
+	} else {
+		state = port.Subnet.dhcpState()
+	}
+	if state == dhcpStateStatic {
+		return nil, fmt.Errorf("Interface with ID %d has static address, DHCP client is not used", portId)
+	}
+
+	switch in.GetAction() {
+	case upd.DHCPAction_DHCP_ACTION_UNSPECIFIED:
+		return nil, fmt.Errorf("DHCP action for interface with ID %d is not specified", portId)
+	case upd.DHCPAction_DHCP_RENEW, upd.DHCPAction_DHCP_RELEASE:
+		if state != dhcpStateBound && state != dhcpStateRenewing {
+			return nil, fmt.Errorf("Interface with ID %d has no DHCP lease", portId)
+		}
+		if in.GetAction() == upd.DHCPAction_DHCP_RENEW {
+			if ipv6 {
+				port.sendDHCPv6RenewRequest()
+			} else {
+				port.sendDHCPRenewRequest()
+			}
+		} else {
+			if ipv6 {
+				port.sendDHCPv6ReleaseRequest()
+			} else {
+				port.sendDHCPReleaseRequest()
+			}
+		}
+	case upd.DHCPAction_DHCP_RESTART:
+		if ipv6 {
+			port.Subnet6.addressAcquired = false
+			port.Subnet6.ds = dhcpv6State{}
+			port.sendDHCPv6SolicitRequest()
+		} else {
+			port.Subnet.addressAcquired = false
+			port.Subnet.ds = dhcpState{}
+			port.sendDHCPDiscoverRequest()
+		}
+	default:
+		return nil, fmt.Errorf("Bad DHCP action %d", in.GetAction())
+	}
+
+	return dhcpLeaseReply(port, ipv6), nil
+}
+
+func convertTraceTypes(traceTypes []upd.TraceType) ([DirKNI + 1]bool, error) {
+	var dirs [DirKNI + 1]bool
+	if len(traceTypes) == 0 {
+		return dirs, fmt.Errorf("At least one dump type should be specified")
+	}
+	for _, t := range traceTypes {
+		if t < upd.TraceType_DUMP_DROP || t > upd.TraceType_DUMP_KNI {
+			return dirs, fmt.Errorf("Bad value of dump type: %d", t)
+		}
+		dirs[t] = true
+	}
+	return dirs, nil
+}
+
+// convertInterfaceIDs returns indexes of ports of dump. Empty list
+// means all ports managed by server.
+func (s *server) convertInterfaceIDs(ids []uint32) ([]uint16, error) {
+	ports := []uint16{}
+	if len(ids) == 0 && s.instance != nil {
+		for i := range Natconfig.PortPairs {
+			pp := &Natconfig.PortPairs[i]
+			if s.managesPair(pp) {
+				ports = append(ports, pp.PrivatePort.Index, pp.PublicPort.Index)
+			}
+		}
+	}
+	for _, id := range ids {
+		port, _ := s.getPortAndPairByID(id)
+		if port == nil {
+			return nil, fmt.Errorf("Interface with ID %d not found", id)
+		}
+		ports = append(ports, port.Index)
+	}
+	return ports, nil
+}
+
+func (s *server) StreamDump(in *upd.DumpStreamRequest, stream upd.Updater_StreamDumpServer) error {
+	dirs, err := convertTraceTypes(in.GetTraceTypes())
+	if err != nil {
+		return err
+	}
+	ports, err := s.convertInterfaceIDs(in.GetInterfaceIds())
+	if err != nil {
+		return err
+	}
+
+	sink := newDumpSink("grpc stream", dirs, ports)
+	addDumpSink(sink)
+	defer removeDumpSink(sink)
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-sink.done:
+			return nil
+		case dp := <-sink.packets:
+			err := stream.Send(&upd.DumpedPacket{
+				InterfaceId: uint32(dp.port),
+				TraceType:   upd.TraceType(dp.dir),
+				Timestamp:   dp.timestamp.UnixNano(),
+				Data:        dp.data,
+				Dropped:     atomic.LoadUint64(&sink.dropped),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *server) ConnectDumpSink(ctx context.Context, in *upd.DumpSinkConnectRequest) (*upd.Reply, error) {
+	dirs, err := convertTraceTypes(in.GetTraceTypes())
+	if err != nil {
+		return nil, err
+	}
+	ports, err := s.convertInterfaceIDs(in.GetInterfaceIds())
+	if err != nil {
+		return nil, err
+	}
+	err = connectDumpSink(in.GetAddress(), in.GetUseTls(), in.GetCaCertificate(), in.GetInsecureSkipVerify(), dirs, ports, s.instance)
+	if err != nil {
+		return nil, err
+	}
+
+	return &upd.Reply{
+		Msg: fmt.Sprintf("Successfully connected dump sink %s", in.GetAddress()),
+	}, nil
+}
+
+func (s *server) DisconnectDumpSink(ctx context.Context, in *upd.DumpSinkDisconnectRequest) (*upd.Reply, error) {
+	dropped, err := disconnectDumpSink(in.GetAddress(), s.instance)
+	if err != nil {
+		return nil, err
+	}
+
+	return &upd.Reply{
+		Msg: fmt.Sprintf("Successfully disconnected dump sink %s, %d packets were dropped", in.GetAddress(), dropped),
+	}, nil
+}
+
+func (s *server) SendWakeOnLAN(ctx context.Context, in *upd.WakeOnLANRequest) (*upd.Reply, error) {
+	portId := in.GetInterfaceId()
+	port, _ := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+	if port.Type != iPRIVATE {
+		return nil, fmt.Errorf("Interface with ID %d is not private, Wake-on-LAN packets are sent only to private networks", portId)
+	}
+	if len(in.GetMacAddress()) != types.EtherAddrLen {
+		return nil, fmt.Errorf("Bad MAC address length %d", len(in.GetMacAddress()))
+	}
+	var mac types.MACAddress
+	copy(mac[:], in.GetMacAddress())
+
+	if err := port.sendWakeOnLAN(mac, in.GetPassword()); err != nil {
+		return nil, err
+	}
+
+	return &upd.Reply{
+		Msg: fmt.Sprintf("Successfully sent Wake-on-LAN packet for %s to port %d", mac.String(), portId),
+	}, nil
+}
+
+func (s *server) GetPortStatistics(ctx context.Context, in *upd.PortStatisticsRequest) (*upd.PortStatisticsReply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+	xstats, err := getNICXStats(port.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := port.getStats()
+	reply := &upd.PortStatisticsReply{
+		InterfaceId: portId,
+		Tenant:      pp.Tenant,
+		NatCounters: []*upd.Counter{
+			{Name: "rx-packets", Value: stats.rxPackets},
+			{Name: "rx-bytes", Value: stats.rxBytes},
+			{Name: "tx-packets", Value: stats.txPackets},
+			{Name: "tx-bytes", Value: stats.txBytes},
+			{Name: "kni-packets", Value: stats.kniPackets},
+			{Name: "drop-packets", Value: stats.dropPackets},
+		},
+	}
+	if port.Type == iPUBLIC {
+		reply.NatCounters = append(reply.NatCounters,
+			&upd.Counter{Name: "sessions-created", Value: stats.sessions})
+	}
+	if Natconfig.PersistentStatistics.enabled() {
+		lifetime := port.lifetimeStats()
+		reply.NatCounters = append(reply.NatCounters,
+			&upd.Counter{Name: "lifetime-rx-packets", Value: lifetime.rxPackets},
+			&upd.Counter{Name: "lifetime-rx-bytes", Value: lifetime.rxBytes},
+			&upd.Counter{Name: "lifetime-tx-packets", Value: lifetime.txPackets},
+			&upd.Counter{Name: "lifetime-tx-bytes", Value: lifetime.txBytes},
+			&upd.Counter{Name: "lifetime-kni-packets", Value: lifetime.kniPackets},
+			&upd.Counter{Name: "lifetime-drop-packets", Value: lifetime.dropPackets})
+		if port.Type == iPUBLIC {
+			reply.NatCounters = append(reply.NatCounters,
+				&upd.Counter{Name: "lifetime-sessions-created", Value: lifetime.sessions})
+		}
+	}
+	translated, dropped, held := port.fragments.counters()
+	reply.NatCounters = append(reply.NatCounters,
+		&upd.Counter{Name: "fragments-translated", Value: translated},
+		&upd.Counter{Name: "fragments-dropped", Value: dropped},
+		&upd.Counter{Name: "fragments-held", Value: held})
+	options, stripped, optionsDropped, jumbograms, jumbogramsDropped := port.ipOptionsCounters()
+	reply.NatCounters = append(reply.NatCounters,
+		&upd.Counter{Name: "ipv4-options-packets", Value: options},
+		&upd.Counter{Name: "ipv4-options-stripped", Value: stripped},
+		&upd.Counter{Name: "ipv4-options-dropped", Value: optionsDropped},
+		&upd.Counter{Name: "ipv6-jumbograms", Value: jumbograms},
+		&upd.Counter{Name: "ipv6-jumbograms-dropped", Value: jumbogramsDropped})
+	if pp.DecrementTTL || (port.Type == iPRIVATE && len(pp.privateRoutes) != 0) {
+		reply.NatCounters = append(reply.NatCounters,
+			&upd.Counter{Name: "ttl-expired-packets", Value: atomic.LoadUint64(&port.ttlExpired)})
+	}
+	if port.KNIOffload.enabled() {
+		c := port.kniOffloadCounters()
+		reply.NatCounters = append(reply.NatCounters,
+			&upd.Counter{Name: "kni-gso-packets", Value: c.segmented},
+			&upd.Counter{Name: "kni-gso-segments", Value: c.segments},
+			&upd.Counter{Name: "kni-oversized-dropped", Value: c.oversized},
+			&upd.Counter{Name: "kni-gso-queue-dropped", Value: c.dropped},
+			&upd.Counter{Name: "kni-gro-coalesced", Value: c.coalesced})
+	}
+	for path, name := range checksumPathNames {
+		reply.NatCounters = append(reply.NatCounters,
+			&upd.Counter{Name: name, Value: atomic.LoadUint64(&port.checksums[path])})
+	}
+	if port.Type == iPRIVATE && len(pp.privateRoutes) != 0 {
+		reply.NatCounters = append(reply.NatCounters,
+			&upd.Counter{Name: "private-routed-packets", Value: atomic.LoadUint64(&port.privateRouted.routed)},
+			&upd.Counter{Name: "private-route-unresolved", Value: atomic.LoadUint64(&port.privateRouted.unresolved)})
+	}
+	if port.Type == iPUBLIC && pp.PortSharing.enabled() {
+		refused, reclaimed := pp.sharingCounters()
+		reply.NatCounters = append(reply.NatCounters,
+			&upd.Counter{Name: "port-sharing-refused", Value: refused},
+			&upd.Counter{Name: "port-sharing-reclaimed", Value: reclaimed})
+	}
+	if port.Type == iPUBLIC && pp.FloodMitigation.enabled() {
+		sources, packets, blocks := pp.blocklist.counters()
+		reply.NatCounters = append(reply.NatCounters,
+			&upd.Counter{Name: "blocked-sources", Value: sources},
+			&upd.Counter{Name: "blocked-packets", Value: packets},
+			&upd.Counter{Name: "source-blocks", Value: blocks})
+	}
+	if port.Type == iPUBLIC && pp.PrivatePort.health != nil {
+		hosts, packets := pp.forwardHealthCounters()
+		reply.NatCounters = append(reply.NatCounters,
+			&upd.Counter{Name: "unreachable-forward-hosts", Value: hosts},
+			&upd.Counter{Name: "unreachable-forward-packets", Value: packets})
+	}
+	if port.external != nil {
+		reply.NatCounters = append(reply.NatCounters,
+			&upd.Counter{Name: "stun-requests", Value: atomic.LoadUint64(&port.external.requests)},
+			&upd.Counter{Name: "stun-responses", Value: atomic.LoadUint64(&port.external.responses)})
+	}
+	if port.Type == iPUBLIC && pp.TCPTimeoutReset {
+		reply.NatCounters = append(reply.NatCounters,
+			&upd.Counter{Name: "tcp-timeout-resets", Value: atomic.LoadUint64(&pp.timeoutResets)})
+	}
+	if port.resolver != nil {
+		outstanding, deferred, refused := port.resolutionCounters()
+		reply.NatCounters = append(reply.NatCounters,
+			&upd.Counter{Name: "neighbor-resolutions-outstanding", Value: outstanding},
+			&upd.Counter{Name: "neighbor-requests-deferred", Value: deferred},
+			&upd.Counter{Name: "neighbor-resolutions-refused", Value: refused})
+	}
+	if port.incomplete != nil {
+		entries, created, refused, expired := port.ndProtectionCounters()
+		reply.NatCounters = append(reply.NatCounters,
+			&upd.Counter{Name: "nd-incomplete-entries", Value: entries},
+			&upd.Counter{Name: "nd-incomplete-created", Value: created},
+			&upd.Counter{Name: "nd-incomplete-refused", Value: refused},
+			&upd.Counter{Name: "nd-incomplete-expired", Value: expired})
+	}
+	if port.Type == iPUBLIC && pp.egressPolicer != nil {
+		packets, bytes := pp.egressPolicer.droppedCounters()
+		reply.NatCounters = append(reply.NatCounters,
+			&upd.Counter{Name: "egress-policer-drop-packets", Value: packets},
+			&upd.Counter{Name: "egress-policer-drop-bytes", Value: bytes})
+	}
+	if port.Type == iPRIVATE && pp.CaptivePortal.enabled() {
+		reply.NatCounters = append(reply.NatCounters, pp.portal.counters()...)
+	}
+	if port.identities != nil {
+		reply.NatCounters = append(reply.NatCounters, port.identities.counters()...)
+	}
+	if port.Type == iPUBLIC && pp.IdleAddressRelease.enabled() {
+		reply.NatCounters = append(reply.NatCounters, pp.idleRelease.counters()...)
+	}
+	if port.Type == iPUBLIC && Natconfig.SessionAuthorization.enabled() {
+		reply.NatCounters = append(reply.NatCounters, pp.authorizations.counters()...)
+	}
+	if port.scheduler != nil {
+		reply.NatCounters = append(reply.NatCounters, port.scheduler.counters()...)
+	}
+	if port.Type == iPUBLIC && pp.aging != nil {
+		reply.NatCounters = append(reply.NatCounters, pp.sessionLifetimeCounters()...)
+	}
+	for _, c := range xstats {
+		reply.NicCounters = append(reply.NicCounters, &upd.Counter{
+			Name:  c.name,
+			Value: c.value,
+		})
+	}
+	return reply, nil
+}
+
+func (s *server) GetLinkStatus(ctx context.Context, in *upd.LinkStatusRequest) (*upd.LinkStatusReply, error) {
+	portId := in.GetInterfaceId()
+	port, _ := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+
+	link := port.getLinkStatus()
+	return &upd.LinkStatusReply{
+		InterfaceId:     portId,
+		Up:              link.up,
+		SpeedMbps:       link.speed,
+		FullDuplex:      link.fullDuplex,
+		Autonegotiation: link.autoNegotiation,
+		Tenant:          port.tenant,
+	}, nil
+}
+
+func (s *server) GetExternalAddress(ctx context.Context, in *upd.ExternalAddressRequest) (*upd.ExternalAddressReply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+	if port.external == nil {
+		return nil, fmt.Errorf("External address discovery of interface %d is not enabled in config", portId)
+	}
+
+	reply := &upd.ExternalAddressReply{
+		InterfaceId: portId,
+		Server:      hostPortAddress(&pp.STUN.Server),
+		Tenant:      port.tenant,
+	}
+	addr, p, learned := port.externalIPv4()
+	if !learned.IsZero() {
+		reply.Address = hostAddress(addr)
+		reply.Port = uint32(p)
+		reply.Learned = learned.Unix()
+	}
+	return reply, nil
+}
+
+func (s *server) GetFlowGraph(ctx context.Context, in *upd.FlowGraphRequest) (*upd.FlowGraphReply, error) {
+	interval := in.GetIntervalMs()
+	if interval == 0 {
+		interval = defaultFlowGraphInterval
+	}
+	if interval > maxFlowGraphInterval {
+		return nil, fmt.Errorf("Flow graph interval should not exceed %d milliseconds", maxFlowGraphInterval)
+	}
+
+	t := &flowTopology{}
+	for i := range Natconfig.PortPairs {
+		if s.managesPair(&Natconfig.PortPairs[i]) {
+			t.addFlowTopology(i)
+		}
+	}
+
+	// Rates are measured by counters which packet handlers update
+	// while request waits
+	start := time.Now()
+	before := t.sampleEdges()
+	select {
+	case <-time.After(time.Duration(interval) * time.Millisecond):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	after := t.sampleEdges()
+	seconds := time.Since(start).Seconds()
+
+	reply := &upd.FlowGraphReply{}
+	for _, n := range t.nodes {
+		node := &upd.FlowNode{
+			Id:   n.id,
+			Kind: n.kind,
+			Name: n.name,
+			Pair: uint32(n.pair),
+		}
+		if n.port != nil {
+			node.InterfaceId = uint32(n.port.Index)
+		}
+		reply.Nodes = append(reply.Nodes, node)
+	}
+	for i, e := range t.edges {
+		edge := &upd.FlowEdge{
+			From:    e.from,
+			To:      e.to,
+			Counted: e.counter != nil,
+		}
+		if e.counter != nil {
+			edge.Packets = after[i]
+			edge.PacketsPerSecond = float64(after[i]-before[i]) / seconds
+		}
+		reply.Edges = append(reply.Edges, edge)
+	}
+	return reply, nil
+}
+
+func (s *server) SetMaintenance(ctx context.Context, in *upd.MaintenanceRequest) (*upd.MaintenanceReply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+	if !in.GetEnable() && (in.GetFlushSessions() || in.GetReject()) {
+		return nil, fmt.Errorf("Sessions are flushed and rejected only when port pair enters maintenance")
+	}
+
+	flushed := pp.setMaintenance(in.GetEnable(), in.GetReject(), in.GetFlushSessions())
+	reply := pp.maintenanceReply(portId)
+	reply.FlushedSessions = uint64(flushed)
+	return reply, nil
+}
+
+func (s *server) GetMaintenance(ctx context.Context, in *upd.MaintenanceStatusRequest) (*upd.MaintenanceReply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+	return pp.maintenanceReply(portId), nil
+}
+
+func (s *server) SetCaptivePortalHost(ctx context.Context, in *upd.CaptivePortalHostRequest) (*upd.CaptivePortalHostsReply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+	if !pp.CaptivePortal.enabled() {
+		return nil, fmt.Errorf("Captive portal of interface %d is not enabled in config", portId)
+	}
+	if err := pp.setPortalHost(in.GetHost()); err != nil {
+		return nil, err
+	}
+	return pp.portalHostsReply(portId), nil
+}
+
+func (s *server) GetCaptivePortalHosts(ctx context.Context, in *upd.CaptivePortalHostsRequest) (*upd.CaptivePortalHostsReply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+	if !pp.CaptivePortal.enabled() {
+		return nil, fmt.Errorf("Captive portal of interface %d is not enabled in config", portId)
+	}
+	return pp.portalHostsReply(portId), nil
+}
+
+func (s *server) GetHostIdentities(ctx context.Context, in *upd.HostIdentitiesRequest) (*upd.HostIdentitiesReply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+	t := pp.PrivatePort.identities
+	if t == nil {
+		return nil, fmt.Errorf("Host identity of interface %d is not enabled in config", portId)
+	}
+	return &upd.HostIdentitiesReply{
+		Hosts:    t.report(),
+		Overflow: atomic.LoadUint64(&t.overflow),
+		Tenant:   pp.Tenant,
+	}, nil
+}
+
+func (pp *portPair) maintenanceReply(portId uint32) *upd.MaintenanceReply {
+	active, reject, since, refused := pp.maintenanceStatus()
+	return &upd.MaintenanceReply{
+		InterfaceId:     portId,
+		Maintenance:     active,
+		Reject:          reject,
+		Since:           since,
+		RefusedSessions: refused,
+		ActiveSessions:  uint64(pp.totalActiveSessions()),
+		Tenant:          pp.Tenant,
+	}
+}
+
+func (s *server) GetNftablesRuleset(ctx context.Context, in *upd.NftablesRulesetRequest) (*upd.NftablesRulesetReply, error) {
+	return &upd.NftablesRulesetReply{
+		Ruleset: nftablesRuleset(s.managedPairs()),
+	}, nil
+}
+
+func (s *server) TracePacket(ctx context.Context, in *upd.PacketTraceRequest) (*upd.PacketTraceReply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+	if in.GetProtocol() > 255 || in.GetSourcePort() > 65535 || in.GetDestinationPort() > 65535 || in.GetHopLimit() > 255 {
+		return nil, fmt.Errorf("Bad packet protocol %d, ports %d and %d or hop limit %d", in.GetProtocol(),
+			in.GetSourcePort(), in.GetDestinationPort(), in.GetHopLimit())
+	}
+	src, err := convertNeighborAddress(in.GetSource())
+	if err != nil {
+		return nil, err
+	}
+	dst, err := convertNeighborAddress(in.GetDestination())
+	if err != nil {
+		return nil, err
+	}
+
+	p := &tracedPacket{
+		protocol:      uint8(in.GetProtocol()),
+		newConnection: in.GetNewConnection(),
+		hopLimit:      uint8(in.GetHopLimit()),
+		ipOptions:     in.GetIpOptions(),
+	}
+	if p.hopLimit == 0 {
+		p.hopLimit = 64
+	}
+	srcPort := uint16(in.GetSourcePort())
+	dstPort := uint16(in.GetDestinationPort())
+	// Protocols without ports are traced by addresses only
+	if !isTranslatedProtocol(p.protocol) {
+		srcPort, dstPort = 0, 0
+	}
+	icmp := types.ICMPNumber
+	switch a := src.(type) {
+	case types.IPv4Address:
+		d, ok := dst.(types.IPv4Address)
+		if !ok {
+			return nil, fmt.Errorf("Source and destination addresses should be of the same IP family")
+		}
+		p.src, p.dst = Tuple{addr: a, port: srcPort}, Tuple{addr: d, port: dstPort}
+	case types.IPv6Address:
+		d, ok := dst.(types.IPv6Address)
+		if !ok {
+			return nil, fmt.Errorf("Source and destination addresses should be of the same IP family")
+		}
+		p.ipv6 = true
+		p.src, p.dst = Tuple6{addr: a, port: srcPort}, Tuple6{addr: d, port: dstPort}
+		icmp = types.ICMPv6Number
+	}
+	if isICMPProtocol(p.protocol) && p.protocol != uint8(icmp) {
+		return nil, fmt.Errorf("Bad packet protocol %d, only ICMP queries of packet IP family are traced", p.protocol)
+	}
+	// ICMP queries are translated by their identifier
+	if p.protocol == uint8(icmp) {
+		if p.ipv6 {
+			p.dst = Tuple6{addr: p.dst.(Tuple6).addr, port: srcPort}
+		} else {
+			p.dst = Tuple{addr: p.dst.(Tuple).addr, port: srcPort}
+		}
+	}
+
+	t := pp.tracePacket(port, p)
+	reply := &upd.PacketTraceReply{
+		Verdict:           traceVerdictLookup[t.verdict],
+		OutputInterfaceId: uint32(t.output.Index),
+		Tenant:            pp.Tenant,
+	}
+	for _, step := range t.steps {
+		reply.Steps = append(reply.Steps, &upd.TraceStep{
+			Stage:  step.stage,
+			Result: step.result,
+		})
+	}
+	reply.Source, reply.SourcePort = tupleAddress(t.out.src)
+	reply.Destination, reply.DestinationPort = tupleAddress(t.out.dst)
+	return reply, nil
+}
+
+func (s *server) GetForwardedHandshakes(ctx context.Context, in *upd.ForwardedHandshakesRequest) (*upd.ForwardedHandshakesReply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+	if !pp.HandshakeTracking.enabled() {
+		return nil, fmt.Errorf("Handshake tracking of interface %d is not enabled in config", portId)
+	}
+
+	reply := &upd.ForwardedHandshakesReply{
+		Forwards: []*upd.ForwardedHandshakes{},
+		Tenant:   pp.Tenant,
+	}
+	pp.handshakes.Range(func(k, v interface{}) bool {
+		reply.Forwards = append(reply.Forwards, v.(*handshakeTracker).report(&pp.HandshakeTracking, k.(handshakeForward)))
+		return true
+	})
+	sort.Slice(reply.Forwards, func(i, j int) bool {
+		a, b := reply.Forwards[i], reply.Forwards[j]
+		if a.Ipv6 != b.Ipv6 {
+			return !a.Ipv6
+		}
+		return a.Port < b.Port
+	})
+	return reply, nil
+}
+
+func (s *server) ChangeEgressPolicer(ctx context.Context, in *upd.EgressPolicerChangeRequest) (*upd.Reply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+	if pp.egressPolicer == nil {
+		return nil, fmt.Errorf("Egress policer of interface %d is not enabled in config", portId)
+	}
+
+	cfg := egressPolicerConfig{
+		Rate:      in.GetRate(),
+		Burst:     in.GetBurst(),
+		HostRate:  in.GetHostRate(),
+		HostBurst: in.GetHostBurst(),
+	}
+	for _, h := range in.GetHosts() {
+		addr := h.GetAddress().GetAddress()
+		if len(addr) != net.IPv4len && len(addr) != net.IPv6len {
+			return nil, fmt.Errorf("Bad egress policer host address length %d", len(addr))
+		}
+		cfg.Hosts = append(cfg.Hosts, egressPolicerHost{
+			Address: net.IP(addr).String(),
+			Rate:    h.GetRate(),
+			Burst:   h.GetBurst(),
+		})
+	}
+	if err := cfg.check(); err != nil {
+		return nil, err
+	}
+	pp.egressPolicer.setConfig(cfg)
+
+	return &upd.Reply{
+		Msg: fmt.Sprintf("Successfully changed egress policer of port %d", pp.PublicPort.Index),
+	}, nil
+}
+
+func (s *server) GetSubscribers(ctx context.Context, in *upd.SubscribersRequest) (*upd.SubscribersReply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+
+	policerCounters := func(c [3]uint64) *upd.PolicerCounters {
+		return &upd.PolicerCounters{
+			ConformBytes: c[0],
+			ExceedBytes:  c[1],
+			DropBytes:    c[2],
+		}
+	}
+	subscribers := []*upd.Subscriber{}
+	for _, sc := range pp.getSubscribersCounters() {
+		sub := &upd.Subscriber{
+			Egress:  policerCounters(sc.egress),
+			Ingress: policerCounters(sc.ingress),
+		}
+		if sc.address != nil {
+			sub.Address = hostAddress(sc.address)
+		}
+		if sc.hasMAC {
+			sub.MacAddress = append([]byte{}, sc.mac[:]...)
+		}
+		subscribers = append(subscribers, sub)
+	}
+	sort.Slice(subscribers, func(i, j int) bool {
+		a, b := subscribers[i].GetAddress().GetAddress(), subscribers[j].GetAddress().GetAddress()
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return bytes.Compare(a, b) < 0
+	})
+
+	return &upd.SubscribersReply{
+		Subscribers: subscribers,
+		Tenant:      pp.Tenant,
+	}, nil
+}
+
+// hostAddress converts types.IPv4Address or types.IPv6Address to GRPC
+// form.
+func hostAddress(address interface{}) *upd.IPAddress {
+	var addr []byte
+	switch ip := address.(type) {
+	case types.IPv4Address:
+		a := types.IPv4ToBytes(ip)
+		addr = []byte{a[3], a[2], a[1], a[0]}
+	case types.IPv6Address:
+		addr = append([]byte{}, ip[:]...)
+	}
+	return &upd.IPAddress{
+		Address: addr,
+	}
+}
+
+func (s *server) GetTopTalkers(ctx context.Context, in *upd.TopTalkersRequest) (*upd.TopTalkersReply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+
+	reply := &upd.TopTalkersReply{
+		Protocols:    []*upd.ProtocolCounters{},
+		Hosts:        []*upd.Talker{},
+		Destinations: []*upd.Talker{},
+		Tenant:       pp.Tenant,
+	}
+	for protocol, pc := range pp.protocolCounters() {
+		reply.Protocols = append(reply.Protocols, &upd.ProtocolCounters{
+			Protocol:       uint32(protocol),
+			EgressPackets:  pc.egressPackets,
+			EgressBytes:    pc.egressBytes,
+			IngressPackets: pc.ingressPackets,
+			IngressBytes:   pc.ingressBytes,
+		})
+	}
+	sort.Slice(reply.Protocols, func(i, j int) bool {
+		return reply.Protocols[i].GetProtocol() < reply.Protocols[j].GetProtocol()
+	})
+
+	talkers := func(list []talker) []*upd.Talker {
+		result := make([]*upd.Talker, len(list))
+		for i := range list {
+			result[i] = &upd.Talker{
+				Address:      hostAddress(list[i].address),
+				Packets:      list[i].packets,
+				Bytes:        list[i].bytes,
+				ErrorPackets: list[i].errorPackets,
+				ErrorBytes:   list[i].errorBytes,
+			}
+		}
+		return result
+	}
+	hosts, destinations := pp.talkers.top(int(in.GetCount()))
+	reply.Hosts = talkers(hosts)
+	reply.Destinations = talkers(destinations)
+	return reply, nil
+}
+
+// sessionAddress converts address of saved session to GRPC form.
+func sessionAddress(addr string) *upd.IPAddress {
+	ip := net.ParseIP(addr)
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return &upd.IPAddress{
+		Address: ip,
+	}
+}
+
+func (s *server) ExportSessions(ctx context.Context, in *upd.SessionsExportRequest) (*upd.SessionSnapshot, error) {
+	snapshot := &upd.SessionSnapshot{
+		HostName: Natconfig.HostName,
+		Time:     time.Now().UnixNano(),
+	}
+	now := time.Now()
+	for _, saved := range collectSessions() {
+		if !s.managesPairIndex(saved.Pair) {
+			continue
+		}
+		snapshot.Sessions = append(snapshot.Sessions, &upd.Session{
+			Pair:                 uint32(saved.Pair),
+			Ipv6:                 saved.IPv6,
+			Protocol:             uint32(saved.Protocol),
+			PublicAddress:        sessionAddress(saved.PublicAddress),
+			PublicPort:           uint32(saved.PublicPort),
+			PrivateAddress:       sessionAddress(saved.PrivateAddress),
+			PrivatePort:          uint32(saved.PrivatePort),
+			LastUsed:             saved.LastUsed.UnixNano(),
+			FinCount:             uint32(saved.FinCount),
+			TerminationDirection: uint32(saved.TerminationDirection),
+			Tenant:               saved.Tenant,
+			Country:              saved.Country,
+			RemainingLifetimeMs:  uint32(sessionRemainingLifetime(saved.LastUsed, now) / time.Millisecond),
+		})
+	}
+	return snapshot, nil
+}
+
+func (s *server) ImportSessions(ctx context.Context, in *upd.SessionSnapshot) (*upd.Reply, error) {
+	sessions := []savedSession{}
+	for _, session := range in.GetSessions() {
+		pub, priv := session.GetPublicAddress().GetAddress(), session.GetPrivateAddress().GetAddress()
+		if session.GetProtocol() > 255 || session.GetPublicPort() > 65535 || session.GetPrivatePort() > 65535 ||
+			(len(pub) != net.IPv4len && len(pub) != net.IPv6len) || (len(priv) != net.IPv4len && len(priv) != net.IPv6len) {
+			return nil, fmt.Errorf("Bad session of port pair %d", session.GetPair())
+		}
+		// Sessions of port pairs of other NAT instances are not
+		// restored like sessions of unknown port pairs
+		if !s.managesPairIndex(int(session.GetPair())) {
+			continue
+		}
+		sessions = append(sessions, savedSession{
+			Pair:                 int(session.GetPair()),
+			IPv6:                 session.GetIpv6(),
+			Protocol:             uint8(session.GetProtocol()),
+			PublicAddress:        net.IP(pub).String(),
+			PublicPort:           uint16(session.GetPublicPort()),
+			PrivateAddress:       net.IP(priv).String(),
+			PrivatePort:          uint16(session.GetPrivatePort()),
+			LastUsed:             time.Unix(0, session.GetLastUsed()),
+			FinCount:             uint8(session.GetFinCount()),
+			TerminationDirection: terminationDirection(session.GetTerminationDirection()),
+			Tenant:               session.GetTenant(),
+		})
+	}
+	restored := restoreSessions(sessions)
+
+	return &upd.Reply{
+		Msg: fmt.Sprintf("Imported %d of %d sessions", restored, len(in.GetSessions())),
+	}, nil
+}
+
+func (s *server) ControlRouteAnnouncement(ctx context.Context, in *upd.RouteAnnouncementRequest) (*upd.Reply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+	if !pp.RouteAnnouncement.Enable {
+		return nil, fmt.Errorf("Route announcement of interface %d is not enabled in config", portId)
+	}
+
+	pp.setAnnouncement(in.GetAnnounce())
+	msg := fmt.Sprintf("Public addresses of port %d are withdrawn", pp.PublicPort.Index)
+	if reason := pp.withdrawReason(); reason == "" {
+		msg = fmt.Sprintf("Public addresses of port %d are announced", pp.PublicPort.Index)
+	} else if in.GetAnnounce() {
+		msg = fmt.Sprintf("Public addresses of port %d stay withdrawn, %s", pp.PublicPort.Index, reason)
+	}
+	return &upd.Reply{
+		Msg: msg,
+	}, nil
+}
+
+func (s *server) DeleteSession(ctx context.Context, in *upd.SessionDeleteRequest) (*upd.Reply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+	if in.GetProtocol() > 255 || in.GetPort() > 65535 {
+		return nil, fmt.Errorf("Bad session protocol %d or port %d", in.GetProtocol(), in.GetPort())
+	}
+	addr, err := convertNeighborAddress(in.GetAddress())
+	if err != nil {
+		return nil, err
+	}
+	var key interface{}
+	ipv6 := false
+	switch a := addr.(type) {
+	case types.IPv6Address:
+		key = Tuple6{addr: a, port: uint16(in.GetPort())}
+		ipv6 = true
+	case types.IPv4Address:
+		key = Tuple{addr: a, port: uint16(in.GetPort())}
+	}
+
+	pubPort, err := pp.deleteSession(port, ipv6, uint8(in.GetProtocol()), key)
+	if err != nil {
+		return nil, err
+	}
+	return &upd.Reply{
+		Msg: fmt.Sprintf("Deleted session of public port %d on port %s", pubPort, pp.PublicPort.logName()),
+	}, nil
+}
+
+func (s *server) ChangeBlackholeRule(ctx context.Context, in *upd.BlackholeRuleChangeRequest) (*upd.Reply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+	rule := &blackholeRule{
+		Destination: in.GetRule().GetDestination(),
+	}
+	if a := in.GetRule().GetAction(); a != "" {
+		action, ok := blackholeActionLookup[a]
+		if !ok {
+			return nil, fmt.Errorf("Bad blackhole action %s", a)
+		}
+		rule.Action = action
+	}
+
+	if err := pp.changeBlackholeRule(rule, in.GetEnable()); err != nil {
+		return nil, err
+	}
+	return &upd.Reply{
+		Msg: "Success",
+	}, nil
+}
+
+func (s *server) GetBlackholeRules(ctx context.Context, in *upd.BlackholeRulesRequest) (*upd.BlackholeRulesReply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+
+	reply := &upd.BlackholeRulesReply{
+		Tenant: pp.Tenant,
+	}
+	for _, rule := range pp.blackholeRules() {
+		r := &upd.BlackholeRule{
+			Destination: rule.Destination,
+			Action:      rule.Action.String(),
+			Packets:     atomic.LoadUint64(&rule.packets),
+		}
+		for _, ip := range rule.addresses() {
+			r.Addresses = append(r.Addresses, &upd.IPAddress{
+				Address: ip,
+			})
+		}
+		reply.Rules = append(reply.Rules, r)
+	}
+	return reply, nil
+}
+
+func (s *server) GetApplications(ctx context.Context, in *upd.ApplicationsRequest) (*upd.ApplicationsReply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+	if pp.apps == nil {
+		return nil, fmt.Errorf("Application identification is not enabled for interface %d", portId)
+	}
+
+	reply := &upd.ApplicationsReply{
+		Applications: []*upd.ApplicationCounters{},
+		Tenant:       pp.Tenant,
+	}
+	active := pp.applicationActiveSessions()
+	for app := appOther; app < len(pp.apps.names); app++ {
+		ac := &pp.apps.counters[app]
+		sessions := atomic.LoadUint64(&ac.sessions)
+		if sessions == 0 {
+			continue
+		}
+		reply.Applications = append(reply.Applications, &upd.ApplicationCounters{
+			Name:           pp.apps.names[app],
+			Sessions:       sessions,
+			ActiveSessions: uint32(active[app]),
+			EgressPackets:  atomic.LoadUint64(&ac.egressPackets),
+			EgressBytes:    atomic.LoadUint64(&ac.egressBytes),
+			IngressPackets: atomic.LoadUint64(&ac.ingressPackets),
+			IngressBytes:   atomic.LoadUint64(&ac.ingressBytes),
+		})
+	}
+	return reply, nil
+}
+
+func (s *server) GetCountries(ctx context.Context, in *upd.CountriesRequest) (*upd.CountriesReply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+	if pp.countries == nil {
+		return nil, fmt.Errorf("Country accounting is not enabled for interface %d", portId)
+	}
+
+	reply := &upd.CountriesReply{
+		Countries: []*upd.CountryCounters{},
+		Tenant:    pp.Tenant,
+	}
+	for c := countryUnknown; c < countriesCount; c++ {
+		cc := &pp.countries[c]
+		counters := &upd.CountryCounters{
+			Country:        countryName(uint16(c)),
+			Sessions:       atomic.LoadUint64(&cc.sessions),
+			EgressPackets:  atomic.LoadUint64(&cc.egressPackets),
+			EgressBytes:    atomic.LoadUint64(&cc.egressBytes),
+			IngressPackets: atomic.LoadUint64(&cc.ingressPackets),
+			IngressBytes:   atomic.LoadUint64(&cc.ingressBytes),
+			DeniedPackets:  atomic.LoadUint64(&cc.deniedPackets),
+		}
+		if counters.EgressPackets != 0 || counters.IngressPackets != 0 || counters.DeniedPackets != 0 {
+			reply.Countries = append(reply.Countries, counters)
+		}
+	}
+	return reply, nil
+}
+
+func (s *server) SetPortTriggers(ctx context.Context, in *upd.PortTriggersChangeRequest) (*upd.Reply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+
+	rules := []portTrigger{}
+	for _, t := range in.GetTriggers() {
+		var rule portTrigger
+		var ok bool
+		if rule.Protocol, ok = triggerProtocolLookup[t.GetProtocol()]; !ok {
+			return nil, fmt.Errorf("Bad port trigger protocol: %s", t.GetProtocol())
+		}
+		if rule.OpenProtocol, ok = triggerProtocolLookup[t.GetOpenProtocol()]; !ok {
+			return nil, fmt.Errorf("Bad port trigger protocol: %s", t.GetOpenProtocol())
+		}
+		var err error
+		if rule.Ports, err = parsePortSpan(t.GetPorts()); err != nil {
+			return nil, err
+		}
+		for _, s := range t.GetOpenPorts() {
+			span, err := parsePortSpan(s)
+			if err != nil {
+				return nil, err
+			}
+			rule.OpenPorts = append(rule.OpenPorts, span)
+		}
+		rules = append(rules, rule)
+	}
+	if err := pp.setPortTriggers(rules); err != nil {
+		return nil, err
+	}
+	return &upd.Reply{
+		Msg: "Success",
+	}, nil
+}
+
+func (s *server) GetPortTriggers(ctx context.Context, in *upd.PortTriggersRequest) (*upd.PortTriggersReply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+
+	reply := &upd.PortTriggersReply{
+		Triggers: []*upd.PortTrigger{},
+		Opened:   []*upd.TriggeredPort{},
+		Tenant:   pp.Tenant,
+	}
+	for _, rule := range pp.portTriggers() {
+		t := &upd.PortTrigger{
+			Protocol:     rule.Protocol.String(),
+			Ports:        rule.Ports.String(),
+			OpenProtocol: rule.OpenProtocol.String(),
+		}
+		for _, span := range rule.OpenPorts {
+			t.OpenPorts = append(t.OpenPorts, span.String())
+		}
+		reply.Triggers = append(reply.Triggers, t)
+	}
+
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+	for _, o := range pp.openings {
+		reply.Opened = append(reply.Opened, &upd.TriggeredPort{
+			Ipv6:     o.ipv6,
+			Protocol: uint32(o.protocol),
+			Port:     uint32(o.port),
+			Host:     hostAddress(o.host),
+		})
+	}
+	return reply, nil
+}
+
+func (s *server) ChangeSessionLogSampling(ctx context.Context, in *upd.SessionLogSamplingRequest) (*upd.Reply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+
+	cfg := sessionLogSampling{
+		Rate: in.GetRate(),
+	}
+	for _, h := range in.GetSubscribers() {
+		addr := h.GetAddress()
+		if len(addr) != net.IPv4len && len(addr) != net.IPv6len {
+			return nil, fmt.Errorf("Bad session log subscriber address length %d", len(addr))
+		}
+		cfg.Subscribers = append(cfg.Subscribers, net.IP(addr).String())
+	}
+	for _, mac := range in.GetSubscriberMacAddresses() {
+		if len(mac) != types.EtherAddrLen {
+			return nil, fmt.Errorf("Bad MAC address length %d", len(mac))
+		}
+		if pp.PrivatePort.identities == nil {
+			return nil, fmt.Errorf("Session log subscriber MAC addresses of interface %d require host-identity in config", portId)
+		}
+		cfg.Subscribers = append(cfg.Subscribers, net.HardwareAddr(mac).String())
+	}
+	if err := pp.setLogSampling(cfg); err != nil {
+		return nil, err
+	}
+
+	return &upd.Reply{
+		Msg: fmt.Sprintf("Successfully changed session log sampling of port %d", pp.PublicPort.Index),
+	}, nil
+}
+
+func (s *server) GetRunningConfig(ctx context.Context, in *upd.RunningConfigRequest) (*upd.RunningConfigReply, error) {
+	data, err := s.runningConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return &upd.RunningConfigReply{
+		Config: string(data),
+	}, nil
+}
+
+func (s *server) CommitConfig(ctx context.Context, in *upd.CommitConfigRequest) (*upd.Reply, error) {
+	timeout := time.Duration(in.GetConfirmTimeout()) * time.Second
+	if timeout > maxCommitConfirmTimeout {
+		return nil, fmt.Errorf("Confirm timeout should not exceed %v", maxCommitConfirmTimeout)
+	}
+
+	commitMutex.Lock()
+	defer commitMutex.Unlock()
+	if s.conflictingCommit() != nil {
+		return nil, fmt.Errorf("Previous commit is waiting for confirmation, it should be confirmed or rolled back first")
+	}
+	previous, err := s.runningConfig()
+	if err != nil {
+		return nil, err
+	}
+	count, err := s.commitOrRestore(ctx, []byte(in.GetConfig()), previous)
+	if err != nil {
+		return nil, err
+	}
+	s.raiseConfigReloadEvent("commit", count)
+
+	if timeout == 0 {
+		return &upd.Reply{
+			Msg: fmt.Sprintf("Successfully committed %d changes", count),
+		}, nil
+	}
+	c := &pendingCommit{
+		updater:  s,
+		previous: previous,
+	}
+	c.timer = time.AfterFunc(timeout, c.expire)
+	pendingCommits[s.instance] = c
+	return &upd.Reply{
+		Msg: fmt.Sprintf("Successfully committed %d changes, they are rolled back unless commit is confirmed in %v", count, timeout),
+	}, nil
+}
+
+func (s *server) ConfirmCommit(ctx context.Context, in *upd.ConfirmCommitRequest) (*upd.Reply, error) {
+	commitMutex.Lock()
+	defer commitMutex.Unlock()
+	c := pendingCommits[s.instance]
+	if c == nil {
+		return nil, fmt.Errorf("No commit is waiting for confirmation")
+	}
+	c.timer.Stop()
+	delete(pendingCommits, s.instance)
+
+	return &upd.Reply{
+		Msg: "Successfully confirmed commit",
+	}, nil
+}
+
+func (s *server) RollbackCommit(ctx context.Context, in *upd.RollbackCommitRequest) (*upd.Reply, error) {
+	commitMutex.Lock()
+	defer commitMutex.Unlock()
+	c := pendingCommits[s.instance]
+	if c == nil {
+		return nil, fmt.Errorf("No commit is waiting for confirmation")
+	}
+	c.timer.Stop()
+	delete(pendingCommits, s.instance)
+	count, err := s.commit(ctx, c.previous)
+	s.raiseConfigReloadEvent("rollback", count)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to roll back commit: %v", err)
+	}
+
+	return &upd.Reply{
+		Msg: fmt.Sprintf("Successfully rolled back %d changes", count),
+	}, nil
+}
+
+func (s *server) FindSessions(ctx context.Context, in *upd.SessionsFindRequest) (*upd.SessionsFindReply, error) {
+	portId := in.GetInterfaceId()
+	port, pp := s.getPortAndPairByID(portId)
+	if port == nil {
+		return nil, fmt.Errorf("Interface with ID %d not found", portId)
+	}
+	filter, err := convertSessionFilter(in.GetFilter())
+	if err != nil {
+		return nil, err
+	}
+	limit := int(in.GetLimit())
+	if limit == 0 {
+		limit = defaultSessionSearchLimit
+	} else if limit > maxSessionSearchLimit {
+		return nil, fmt.Errorf("Session search limit %d is bigger than %d", limit, maxSessionSearchLimit)
+	}
+	index := 0
+	for i := range Natconfig.PortPairs {
+		if &Natconfig.PortPairs[i] == pp {
+			index = i
+		}
+	}
+
+	sessions, next := pp.findSessions(index, filter, in.GetCursor(), limit)
+	return &upd.SessionsFindReply{
+		Sessions:   sessions,
+		NextCursor: next,
+		Tenant:     pp.Tenant,
+	}, nil
+}
@@ -0,0 +1,278 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+
+	upd "github.com/intel-go/nff-go-nat/updatecfg"
+)
+
+const (
+	// Failed handshake samples kept per forwarded port when it is not
+	// configured
+	defaultHandshakeSamples = 16
+	// Maximum number of pending handshakes of one forwarded port when
+	// it is not configured. SYNs of other sources are counted but not
+	// tracked until old handshakes end.
+	defaultMaxPendingHandshakes = 4096
+	// Expired handshakes are found at most this often
+	handshakeExpiryInterval = time.Second
+)
+
+// Reasons of failed handshakes which are reported in samples.
+const (
+	// Private server didn't answer SYN
+	handshakeNoReply = "no-reply"
+	// Private server answered SYN with RST
+	handshakeRefused = "refused"
+	// Remote host didn't acknowledge SYN-ACK of private server or
+	// reset connection, like port scanners do
+	handshakeIncomplete = "incomplete"
+)
+
+// Tracking of TCP handshakes of remote hosts with private servers of
+// forwarded ports. Handshake fails if it is not completed in timeout
+// seconds, zero timeout disables tracking.
+type handshakeTrackingConfig struct {
+	Timeout int `json:"timeout"`
+	// Failed handshakes which are kept as samples of every forwarded
+	// port
+	Samples int `json:"samples"`
+	// Maximum number of pending handshakes of one forwarded port
+	MaxPending int `json:"max-pending"`
+}
+
+// Public port and IP family of forwarded TCP port.
+type handshakeForward struct {
+	ipv6 bool
+	port uint16
+}
+
+// Handshake which was started by SYN of remote host.
+type pendingHandshake struct {
+	started time.Time
+	// Private server answered with SYN-ACK
+	answered bool
+}
+
+type failedHandshake struct {
+	// Tuple or Tuple6 of remote host
+	remote interface{}
+	time   time.Time
+	reason string
+}
+
+// Handshakes of one forwarded port. It is used from packet handlers
+// running on several cores, so it is protected by a mutex.
+type handshakeTracker struct {
+	mutex   sync.Mutex
+	pending map[interface{}]pendingHandshake
+	// Number of pending handshakes, checked without locking mutex
+	size    int32
+	expired time.Time
+	// Ring of recent failed handshakes, next is index of oldest one
+	samples []failedHandshake
+	next    int
+	// Handshakes by outcome, overflow counts SYNs which were not
+	// tracked because too many handshakes were pending
+	attempts, completed, noReply, refused, incomplete, overflow uint64
+}
+
+func (cfg *handshakeTrackingConfig) enabled() bool {
+	return cfg.Timeout != 0
+}
+
+func (cfg *handshakeTrackingConfig) check() error {
+	if cfg.Timeout < 0 || cfg.Samples < 0 || cfg.MaxPending < 0 {
+		return errors.New("Values of handshake-tracking should not be negative")
+	}
+	if cfg.Samples == 0 {
+		cfg.Samples = defaultHandshakeSamples
+	}
+	if cfg.MaxPending == 0 {
+		cfg.MaxPending = defaultMaxPendingHandshakes
+	}
+	return nil
+}
+
+func (cfg *handshakeTrackingConfig) timeout() time.Duration {
+	return time.Duration(cfg.Timeout) * time.Second
+}
+
+// handshakeTracker returns tracker of forwarded port, it is created
+// when first SYN arrives. Trackers of removed forwarded ports are kept
+// with their counters.
+func (pp *portPair) handshakeTracker(ipv6 bool, port uint16, create bool) *handshakeTracker {
+	key := handshakeForward{ipv6: ipv6, port: port}
+	if v, ok := pp.handshakes.Load(key); ok {
+		return v.(*handshakeTracker)
+	}
+	if !create {
+		return nil
+	}
+	v, _ := pp.handshakes.LoadOrStore(key, &handshakeTracker{
+		pending: map[interface{}]pendingHandshake{},
+	})
+	return v.(*handshakeTracker)
+}
+
+// trackInboundHandshake follows packet of remote host to forwarded TCP
+// port. SYN starts handshake and ACK completes it if private server
+// answered.
+func (pp *portPair) trackInboundHandshake(ipv6 bool, port uint16, pktTCP *packet.TCPHdr, remote interface{}) {
+	flags := pktTCP.TCPFlags & (types.TCPFlagSyn | types.TCPFlagAck | types.TCPFlagRst)
+	if flags == types.TCPFlagSyn {
+		pp.handshakeTracker(ipv6, port, true).start(&pp.HandshakeTracking, remote, time.Now())
+		return
+	}
+	if flags != types.TCPFlagAck && flags&types.TCPFlagRst == 0 {
+		return
+	}
+	t := pp.handshakeTracker(ipv6, port, false)
+	if t == nil || atomic.LoadInt32(&t.size) == 0 {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	h, ok := t.pending[remote]
+	if !ok || !h.answered {
+		return
+	}
+	if flags == types.TCPFlagAck {
+		t.end(remote, "", time.Now())
+	} else {
+		t.end(remote, handshakeIncomplete, time.Now())
+	}
+}
+
+// trackOutboundHandshake follows packet of private server of forwarded
+// TCP port to remote host. SYN-ACK answers handshake and RST refuses
+// it.
+func (pp *portPair) trackOutboundHandshake(ipv6 bool, port uint16, pktTCP *packet.TCPHdr, remote interface{}) {
+	if pktTCP.TCPFlags&(types.TCPFlagSyn|types.TCPFlagRst) == 0 {
+		return
+	}
+	t := pp.handshakeTracker(ipv6, port, false)
+	if t == nil || atomic.LoadInt32(&t.size) == 0 {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	h, ok := t.pending[remote]
+	if !ok {
+		return
+	}
+	if pktTCP.TCPFlags&types.TCPFlagRst != 0 {
+		if !h.answered {
+			t.end(remote, handshakeRefused, time.Now())
+		}
+	} else if pktTCP.TCPFlags&types.TCPFlagAck != 0 {
+		h.answered = true
+		t.pending[remote] = h
+	}
+}
+
+// start remembers handshake of remote host. Retransmitted SYNs don't
+// restart pending handshake.
+func (t *handshakeTracker) start(cfg *handshakeTrackingConfig, remote interface{}, now time.Time) {
+	atomic.AddUint64(&t.attempts, 1)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if now.Sub(t.expired) >= handshakeExpiryInterval {
+		t.forgetExpired(cfg, now)
+	}
+	if _, ok := t.pending[remote]; ok {
+		return
+	}
+	if len(t.pending) >= cfg.MaxPending {
+		atomic.AddUint64(&t.overflow, 1)
+		return
+	}
+	t.pending[remote] = pendingHandshake{started: now}
+	atomic.StoreInt32(&t.size, int32(len(t.pending)))
+	if t.samples == nil {
+		t.samples = make([]failedHandshake, 0, cfg.Samples)
+	}
+}
+
+// end removes pending handshake which completed or failed with
+// specified reason. Mutex should be locked.
+func (t *handshakeTracker) end(remote interface{}, reason string, now time.Time) {
+	delete(t.pending, remote)
+	atomic.StoreInt32(&t.size, int32(len(t.pending)))
+	switch reason {
+	case "":
+		atomic.AddUint64(&t.completed, 1)
+		return
+	case handshakeNoReply:
+		atomic.AddUint64(&t.noReply, 1)
+	case handshakeRefused:
+		atomic.AddUint64(&t.refused, 1)
+	default:
+		atomic.AddUint64(&t.incomplete, 1)
+	}
+	sample := failedHandshake{remote: remote, time: now, reason: reason}
+	if len(t.samples) < cap(t.samples) {
+		t.samples = append(t.samples, sample)
+	} else if len(t.samples) != 0 {
+		t.samples[t.next] = sample
+		t.next = (t.next + 1) % len(t.samples)
+	}
+}
+
+// forgetExpired fails handshakes which were not completed in timeout.
+// Mutex should be locked.
+func (t *handshakeTracker) forgetExpired(cfg *handshakeTrackingConfig, now time.Time) {
+	t.expired = now
+	for remote, h := range t.pending {
+		if now.Sub(h.started) <= cfg.timeout() {
+			continue
+		}
+		// Handshake failed when it timed out, not when it is found
+		ended := h.started.Add(cfg.timeout())
+		if h.answered {
+			t.end(remote, handshakeIncomplete, ended)
+		} else {
+			t.end(remote, handshakeNoReply, ended)
+		}
+	}
+}
+
+// report returns counters of tracker and its samples from oldest to
+// newest. Expired handshakes are failed first.
+func (t *handshakeTracker) report(cfg *handshakeTrackingConfig, forward handshakeForward) *upd.ForwardedHandshakes {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.forgetExpired(cfg, time.Now())
+	reply := &upd.ForwardedHandshakes{
+		Ipv6:       forward.ipv6,
+		Port:       uint32(forward.port),
+		Attempts:   atomic.LoadUint64(&t.attempts),
+		Completed:  atomic.LoadUint64(&t.completed),
+		Pending:    uint64(len(t.pending)),
+		NoReply:    atomic.LoadUint64(&t.noReply),
+		Refused:    atomic.LoadUint64(&t.refused),
+		Incomplete: atomic.LoadUint64(&t.incomplete),
+		Overflow:   atomic.LoadUint64(&t.overflow),
+		Failed:     []*upd.FailedHandshake{},
+	}
+	for i := range t.samples {
+		s := &t.samples[(t.next+i)%len(t.samples)]
+		f := &upd.FailedHandshake{
+			Time:   s.time.UnixNano(),
+			Reason: s.reason,
+		}
+		f.Address, f.Port = tupleAddress(s.remote)
+		reply.Failed = append(reply.Failed, f)
+	}
+	return reply
+}
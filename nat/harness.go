@@ -0,0 +1,167 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/intel-go/nff-go/flow"
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// Harness runs packet handlers of NAT on packets given by caller
+// instead of packets received from network cards, so that tests can
+// check translation and control plane handling packet by packet.
+// DPDK is initialized without hugepages with virtual devices of flow
+// graph config, e.g. net_ring, and flow graph is not started. Packets
+// which handlers send themselves, e.g. ARP replies and DHCP requests,
+// are captured by harness instead of being sent to ports. DPDK can be
+// initialized only once, so there is one harness in process.
+type Harness struct {
+	mutex sync.Mutex
+	sent  []HarnessPacket
+}
+
+// HarnessPacket is packet sent by handlers to port with index Port.
+type HarnessPacket struct {
+	Port uint16
+	Data []byte
+}
+
+// Set when harness is created
+var harness *Harness
+
+// NewHarness reads config file, initializes DPDK with its virtual
+// devices and state of its port pairs. Injected packets and packets
+// sent by handlers are not returned to mempool, so mbuf-number of flow
+// graph should be enough for all packets of test run.
+func NewHarness(configFile string) (*Harness, error) {
+	if harness != nil {
+		return nil, errors.New("Packet path harness is already created")
+	}
+	if err := ReadConfig(configFile, false, false); err != nil {
+		return nil, err
+	}
+	if NeedKNI {
+		return nil, errors.New("Packet path harness cannot use KNI interfaces")
+	}
+	ealArgs, err := EALArgs(true)
+	if err != nil {
+		return nil, err
+	}
+	fg := &Natconfig.FlowGraph
+	NoHWTXChecksum = true
+	err = flow.SystemInit(&flow.Config{
+		CPUList:          fg.CPUList,
+		DPDKArgs:         append([]string{"--log-level=0"}, ealArgs...),
+		DisableScheduler: true,
+		MbufNumber:       fg.MbufNumber,
+		MbufCacheSize:    fg.MbufCacheSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := flow.SystemInitPortsAndMemory(); err != nil {
+		return nil, err
+	}
+	h := &Harness{}
+	for i := range Natconfig.PortPairs {
+		Natconfig.PortPairs[i].initState()
+	}
+	harness = h
+	sendToPort = h.capture
+	return h, nil
+}
+
+func (h *Harness) port(pair int, public bool) (*ipPort, error) {
+	if pair < 0 || pair >= len(Natconfig.PortPairs) {
+		return nil, fmt.Errorf("Port pair %d not found", pair)
+	}
+	if public {
+		return &Natconfig.PortPairs[pair].PublicPort, nil
+	}
+	return &Natconfig.PortPairs[pair].PrivatePort, nil
+}
+
+// Inject runs packet handler of public or private port of port pair
+// with index pair on Ethernet frame data. It returns direction chosen
+// by handler, e.g. DirSEND, and frame after handler changed it.
+func (h *Harness) Inject(pair int, public bool, data []byte) (uint, []byte, error) {
+	if _, err := h.port(pair, public); err != nil {
+		return DirDROP, nil, err
+	}
+	pkt, err := packet.NewPacket()
+	if err != nil {
+		return DirDROP, nil, err
+	}
+	if !packet.GeneratePacketFromByte(pkt, data) {
+		return DirDROP, nil, fmt.Errorf("Packet of %d bytes doesn't fit into mbuf", len(data))
+	}
+	ctx := pairIndex{
+		index: pair,
+	}
+	var dir uint
+	if public {
+		dir = publicToPrivateCounted(pkt, ctx)
+	} else {
+		dir = privateToPublicCounted(pkt, ctx)
+	}
+	out := append([]byte{}, pkt.GetRawPacketBytes()...)
+	return dir, out, nil
+}
+
+// Sent returns packets sent by handlers since previous call.
+func (h *Harness) Sent() []HarnessPacket {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	sent := h.sent
+	h.sent = nil
+	return sent
+}
+
+func (h *Harness) capture(pkt *packet.Packet, index uint16) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.sent = append(h.sent, HarnessPacket{
+		Port: index,
+		Data: append([]byte{}, pkt.GetRawPacketBytes()...),
+	})
+}
+
+// StartDHCP sends DHCP discover request from port which acquires
+// address with DHCP, like DHCP client does when it starts.
+func (h *Harness) StartDHCP(pair int, public bool) error {
+	port, err := h.port(pair, public)
+	if err != nil {
+		return err
+	}
+	if port.ipv4Disabled || port.Subnet.addressAcquired {
+		return fmt.Errorf("Port %d doesn't acquire IPv4 address with DHCP", port.Index)
+	}
+	port.sendDHCPDiscoverRequest()
+	return nil
+}
+
+// Address returns IPv4 subnet of port and whether port has address.
+func (h *Harness) Address(pair int, public bool) (string, bool, error) {
+	port, err := h.port(pair, public)
+	if err != nil {
+		return "", false, err
+	}
+	return port.Subnet.String(), port.Subnet.addressAcquired, nil
+}
+
+// MACAddress returns MAC address of port which handlers use as source
+// address of packets they send.
+func (h *Harness) MACAddress(pair int, public bool) (types.MACAddress, error) {
+	port, err := h.port(pair, public)
+	if err != nil {
+		return types.MACAddress{}, err
+	}
+	return port.SrcMACAddress, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"sync/atomic"
+
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// Code of time exceeded message for both ICMP and ICMPv6
+const icmpCodeTTLExceeded = 0
+
+// forwardHopLimit decrements IPv4 TTL or IPv6 hop limit of packet
+// which port pair forwards between its ports when port pair acts as
+// router. Packet with TTL which expires is dropped and, if answer is
+// true, its source gets time exceeded error from address of receiving
+// port, so that traceroute shows NAT as a hop. Errors are not sent
+// about ICMP errors and fragments without transport header. It
+// returns false if packet is dropped.
+func (pp *portPair) forwardHopLimit(port *ipPort, pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr, answer bool) bool {
+	if !pp.DecrementTTL {
+		return true
+	}
+	if pktIPv4 != nil {
+		if hopLimitExpires(pktIPv4.TimeToLive) {
+			atomic.AddUint64(&port.ttlExpired, 1)
+			if answer {
+				port.sendTimeExceeded(pkt, pktIPv4, nil)
+			}
+			port.dumpPacket(pkt, DirDROP)
+			return false
+		}
+		decrementIPv4TTL(pktIPv4)
+		return true
+	}
+	if hopLimitExpires(pktIPv6.HopLimits) {
+		atomic.AddUint64(&port.ttlExpired, 1)
+		if answer {
+			port.sendTimeExceeded(pkt, nil, pktIPv6)
+		}
+		port.dumpPacket(pkt, DirDROP)
+		return false
+	}
+	pktIPv6.HopLimits--
+	return true
+}
+
+// hopLimitExpires returns true if packet with IPv4 TTL or IPv6 hop
+// limit cannot be forwarded to next hop.
+func hopLimitExpires(hopLimit uint8) bool {
+	return hopLimit <= 1
+}
+
+// decrementIPv4TTL decrements TTL of IPv4 packet and updates header
+// checksum.
+func decrementIPv4TTL(pktIPv4 *packet.IPv4Hdr) {
+	// TTL shares 16 bit word with protocol
+	old := uint16(pktIPv4.TimeToLive)<<8 | uint16(pktIPv4.NextProtoID)
+	pktIPv4.TimeToLive--
+	if !NoCalculateChecksum {
+		hc := updateChecksum(packet.SwapBytesUint16(pktIPv4.HdrChecksum), old, old-0x100)
+		pktIPv4.HdrChecksum = packet.SwapBytesUint16(hc)
+	}
+}
+
+// sendTimeExceeded answers packet with time exceeded in transit error
+// from address of port. Errors are limited like all ICMP messages of
+// NAT.
+func (port *ipPort) sendTimeExceeded(pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) {
+	if pktIPv4 != nil {
+		if !port.Subnet.addressAcquired || !icmpLimiter.allow(port, packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr)) {
+			return
+		}
+		port.sendICMPError(pkt, pktIPv4, nil, icmpTypeTimeExceeded, icmpCodeTTLExceeded,
+			packet.SwapBytesIPv4Addr(port.Subnet.Addr), types.IPv6Address{}, 0)
+	} else {
+		if !port.Subnet6.addressAcquired || !icmpLimiter.allow(port, pktIPv6.SrcAddr) {
+			return
+		}
+		port.sendICMPError(pkt, nil, pktIPv6, icmpv6TypeTimeExceeded, icmpCodeTTLExceeded, 0, port.Subnet6.Addr, 0)
+	}
+}
@@ -0,0 +1,382 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/types"
+
+	upd "github.com/intel-go/nff-go-nat/updatecfg"
+)
+
+const (
+	// Private hosts tracked by one port pair when it is not
+	// configured. Hosts which appear after it is reached are not
+	// tracked until others are forgotten.
+	defaultMaxIdentityHosts = 65536
+	// Seconds after which host which was not seen is forgotten when
+	// it is not configured
+	defaultIdentityTimeout = 24 * 60 * 60
+	// IPv6 addresses kept per host, oldest one is forgotten when
+	// host uses another one
+	maxIdentityIPv6Addresses = 8
+)
+
+// Tracking of MAC addresses of private hosts. MAC address is learned
+// from ARP and ND of private port, from source of packets which
+// create sessions and from DHCP relay replies. Host keeps its identity
+// when it gets another address, so sessions, accounting, policing and
+// session log sampling can be attributed to device on private
+// networks with dynamic addresses. Hosts behind router of private
+// network share MAC address of router.
+type hostIdentityConfig struct {
+	Enable bool `json:"enable"`
+	// Maximum number of tracked hosts, zero means 65536
+	MaxHosts int `json:"max-hosts"`
+	// Seconds after which host which was not seen is forgotten, zero
+	// means a day
+	Timeout int `json:"timeout"`
+}
+
+// Private host identified by MAC address. Addresses are protected by
+// mutex of table, counters and last seen time are updated from
+// translation handlers, so they should be accessed only atomically.
+type hostIdentity struct {
+	mac     types.MACAddress
+	ipv4    types.IPv4Address
+	hasIPv4 bool
+	ipv6    []types.IPv6Address
+	// Times in nanoseconds since Unix epoch
+	firstSeen int64
+	lastSeen  int64
+	// Changes of IPv4 address
+	addressChanges uint64
+	egressPackets  uint64
+	egressBytes    uint64
+	ingressPackets uint64
+	ingressBytes   uint64
+}
+
+// hostIdentityTable is set for private port of port pair which tracks
+// hosts. Hosts are learned from packet handlers running on several
+// cores, so table is protected by a mutex, addresses are also kept in
+// a sync.Map which handlers read without locking.
+type hostIdentityTable struct {
+	config *hostIdentityConfig
+	port   *ipPort
+	mutex  sync.Mutex
+	hosts  map[types.MACAddress]*hostIdentity
+	// *hostIdentity by types.IPv4Address or types.IPv6Address
+	addresses sync.Map
+	// Hosts which were not tracked because table was full
+	overflow uint64
+}
+
+// checkHostIdentity checks tracking options and enables tracking on
+// private port. MAC addresses of subscribers are matched only when
+// hosts are tracked.
+func (pp *portPair) checkHostIdentity() error {
+	cfg := &pp.HostIdentity
+	if cfg.MaxHosts < 0 || cfg.Timeout < 0 {
+		return errors.New("Values of host-identity should not be negative")
+	}
+	if !cfg.Enable {
+		for _, sc := range pp.Policing.Subscribers {
+			if sc.MAC != "" {
+				return fmt.Errorf("Subscriber MAC address %s of port %s requires host-identity", sc.MAC, pp.PrivatePort.logName())
+			}
+		}
+		for _, s := range pp.SessionLogSampling.Subscribers {
+			if _, err := net.ParseMAC(s); err == nil {
+				return fmt.Errorf("Session-log-sampling subscriber MAC address %s of port %s requires host-identity", s, pp.PrivatePort.logName())
+			}
+		}
+		return nil
+	}
+	if cfg.MaxHosts == 0 {
+		cfg.MaxHosts = defaultMaxIdentityHosts
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultIdentityTimeout
+	}
+	pp.PrivatePort.identities = &hostIdentityTable{
+		config: cfg,
+		port:   &pp.PrivatePort,
+		hosts:  map[types.MACAddress]*hostIdentity{},
+	}
+	return nil
+}
+
+func (cfg *hostIdentityConfig) timeout() time.Duration {
+	return time.Duration(cfg.Timeout) * time.Second
+}
+
+// parseSubscriberMAC converts MAC address of subscriber.
+func parseSubscriberMAC(s string) (types.MACAddress, error) {
+	var mac types.MACAddress
+	hw, err := net.ParseMAC(s)
+	if err != nil || len(hw) != types.EtherAddrLen {
+		return mac, fmt.Errorf("Bad subscriber MAC address %s", s)
+	}
+	copy(mac[:], hw)
+	return mac, nil
+}
+
+// learn remembers that host with MAC address uses IP address which is
+// either types.IPv4Address in host byte order or types.IPv6Address.
+// Address which another host used before is moved to this host.
+func (t *hostIdentityTable) learn(ip interface{}, mac types.MACAddress) {
+	if mac[0]&1 != 0 || mac == (types.MACAddress{}) {
+		return
+	}
+	switch a := ip.(type) {
+	case types.IPv4Address:
+		if a == 0 {
+			return
+		}
+	case types.IPv6Address:
+		if a == zeroIPv6Addr {
+			return
+		}
+	default:
+		return
+	}
+	now := time.Now().UnixNano()
+	if v, ok := t.addresses.Load(ip); ok && v.(*hostIdentity).mac == mac {
+		atomic.StoreInt64(&v.(*hostIdentity).lastSeen, now)
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	host := t.hosts[mac]
+	if host == nil {
+		if len(t.hosts) >= t.config.MaxHosts {
+			atomic.AddUint64(&t.overflow, 1)
+			return
+		}
+		host = &hostIdentity{
+			mac:       mac,
+			firstSeen: now,
+		}
+		t.hosts[mac] = host
+	}
+	atomic.StoreInt64(&host.lastSeen, now)
+	if v, ok := t.addresses.Load(ip); ok {
+		owner := v.(*hostIdentity)
+		if owner == host {
+			return
+		}
+		owner.removeAddress(ip)
+	}
+	switch a := ip.(type) {
+	case types.IPv4Address:
+		if host.hasIPv4 {
+			t.addresses.Delete(host.ipv4)
+			atomic.AddUint64(&host.addressChanges, 1)
+			raiseEvent(EventHostAddressChanged, t.port, map[string]interface{}{
+				"mac":         mac.String(),
+				"old-address": StringIPv4Int(uint32(host.ipv4)),
+				"new-address": StringIPv4Int(uint32(a)),
+			})
+		}
+		host.ipv4, host.hasIPv4 = a, true
+	case types.IPv6Address:
+		if len(host.ipv6) >= maxIdentityIPv6Addresses {
+			t.addresses.Delete(host.ipv6[0])
+			host.ipv6 = host.ipv6[1:]
+		}
+		host.ipv6 = append(host.ipv6, a)
+	}
+	t.addresses.Store(ip, host)
+}
+
+// removeAddress forgets address of host. Mutex of table should be
+// locked.
+func (host *hostIdentity) removeAddress(ip interface{}) {
+	switch a := ip.(type) {
+	case types.IPv4Address:
+		if host.hasIPv4 && host.ipv4 == a {
+			host.hasIPv4 = false
+		}
+	case types.IPv6Address:
+		for i := range host.ipv6 {
+			if host.ipv6[i] == a {
+				host.ipv6 = append(host.ipv6[:i], host.ipv6[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// currentAddress returns IPv4 address of host or its newest IPv6
+// address, nil if host has none. Mutex of table should be locked.
+func (host *hostIdentity) currentAddress() interface{} {
+	if host.hasIPv4 {
+		return host.ipv4
+	}
+	if len(host.ipv6) != 0 {
+		return host.ipv6[len(host.ipv6)-1]
+	}
+	return nil
+}
+
+// lookup returns host which uses address, nil if it is not known.
+func (t *hostIdentityTable) lookup(ip interface{}) *hostIdentity {
+	if v, ok := t.addresses.Load(ip); ok {
+		return v.(*hostIdentity)
+	}
+	return nil
+}
+
+// account adds translated packet to traffic of private host with
+// address.
+func (t *hostIdentityTable) account(ip interface{}, egress bool, length uint) {
+	host := t.lookup(ip)
+	if host == nil {
+		return
+	}
+	atomic.StoreInt64(&host.lastSeen, time.Now().UnixNano())
+	if egress {
+		atomic.AddUint64(&host.egressPackets, 1)
+		atomic.AddUint64(&host.egressBytes, uint64(length))
+	} else {
+		atomic.AddUint64(&host.ingressPackets, 1)
+		atomic.AddUint64(&host.ingressBytes, uint64(length))
+	}
+}
+
+// addressOf returns current address of host with MAC address.
+func (t *hostIdentityTable) addressOf(mac types.MACAddress) interface{} {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if host := t.hosts[mac]; host != nil {
+		return host.currentAddress()
+	}
+	return nil
+}
+
+// forgetIdle removes hosts which were not seen in timeout.
+func (t *hostIdentityTable) forgetIdle(now time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for mac, host := range t.hosts {
+		if now.Sub(time.Unix(0, atomic.LoadInt64(&host.lastSeen))) <= t.config.timeout() {
+			continue
+		}
+		if host.hasIPv4 {
+			t.addresses.Delete(host.ipv4)
+		}
+		for _, a := range host.ipv6 {
+			t.addresses.Delete(a)
+		}
+		delete(t.hosts, mac)
+	}
+}
+
+// report returns tracked hosts ordered by MAC address.
+func (t *hostIdentityTable) report() []*upd.HostIdentity {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	result := make([]*upd.HostIdentity, 0, len(t.hosts))
+	for _, host := range t.hosts {
+		h := &upd.HostIdentity{
+			MacAddress:     append([]byte{}, host.mac[:]...),
+			Addresses:      []*upd.IPAddress{},
+			FirstSeen:      host.firstSeen,
+			LastSeen:       atomic.LoadInt64(&host.lastSeen),
+			AddressChanges: atomic.LoadUint64(&host.addressChanges),
+			EgressPackets:  atomic.LoadUint64(&host.egressPackets),
+			EgressBytes:    atomic.LoadUint64(&host.egressBytes),
+			IngressPackets: atomic.LoadUint64(&host.ingressPackets),
+			IngressBytes:   atomic.LoadUint64(&host.ingressBytes),
+		}
+		if host.hasIPv4 {
+			h.Addresses = append(h.Addresses, hostAddress(host.ipv4))
+		}
+		for _, a := range host.ipv6 {
+			h.Addresses = append(h.Addresses, hostAddress(a))
+		}
+		result = append(result, h)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return bytes.Compare(result[i].MacAddress, result[j].MacAddress) < 0
+	})
+	return result
+}
+
+// hostIdentity returns tracked private host which uses address, nil
+// if hosts are not tracked or host is not known.
+func (pp *portPair) hostIdentity(ip interface{}) *hostIdentity {
+	if pp.PrivatePort.identities == nil {
+		return nil
+	}
+	return pp.PrivatePort.identities.lookup(ip)
+}
+
+// sessionMAC returns MAC address of private host of session. Dynamic
+// session keeps MAC address of host which created it, private host of
+// forwarded port is looked up by its address.
+func (pp *portPair) sessionMAC(pme *portMapEntry, private interface{}) (types.MACAddress, bool) {
+	if pme.mac != (types.MACAddress{}) {
+		return pme.mac, true
+	}
+	if !pme.static {
+		return types.MACAddress{}, false
+	}
+	var host *hostIdentity
+	switch t := private.(type) {
+	case Tuple:
+		host = pp.hostIdentity(t.addr)
+	case Tuple6:
+		host = pp.hostIdentity(t.addr)
+	}
+	if host == nil {
+		return types.MACAddress{}, false
+	}
+	return host.mac, true
+}
+
+// StartHostIdentity starts forgetting idle private hosts of port pairs
+// which track them.
+func StartHostIdentity() {
+	enabled := false
+	for i := range Natconfig.PortPairs {
+		enabled = enabled || Natconfig.PortPairs[i].PrivatePort.identities != nil
+	}
+	if !enabled {
+		return
+	}
+	go func() {
+		for {
+			time.Sleep(connectionTimeout)
+			now := time.Now()
+			for i := range Natconfig.PortPairs {
+				if t := Natconfig.PortPairs[i].PrivatePort.identities; t != nil {
+					t.forgetIdle(now)
+				}
+			}
+		}
+	}()
+}
+
+// counters returns host identity counters of port pair.
+func (t *hostIdentityTable) counters() []*upd.Counter {
+	t.mutex.Lock()
+	hosts := len(t.hosts)
+	t.mutex.Unlock()
+	return []*upd.Counter{
+		&upd.Counter{Name: "identity-hosts", Value: uint64(hosts)},
+		&upd.Counter{Name: "identity-overflow", Value: atomic.LoadUint64(&t.overflow)},
+	}
+}
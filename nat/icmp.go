@@ -19,7 +19,8 @@ func (port *ipPort) handleICMP(protocol uint8, pkt *packet.Packet, key interface
 	var packetSentToUs bool
 	var packetSentToMulticast bool
 	if protocol == types.ICMPNumber {
-		if packet.SwapBytesIPv4Addr(pkt.GetIPv4NoCheck().DstAddr) == port.Subnet.Addr {
+		dst := packet.SwapBytesIPv4Addr(pkt.GetIPv4NoCheck().DstAddr)
+		if dst == port.Subnet.Addr || port.isVLANAddress(pkt, dst) || port.isRetiredIPv4Address(dst) {
 			packetSentToUs = true
 		}
 		requestCode = types.ICMPTypeEchoRequest
@@ -28,10 +29,17 @@ func (port *ipPort) handleICMP(protocol uint8, pkt *packet.Packet, key interface
 		// address translation
 		ipv6 := pkt.GetIPv6NoCheck()
 		if ipv6.DstAddr == port.Subnet6.Addr ||
-			ipv6.DstAddr == port.Subnet6.llAddr {
+			ipv6.DstAddr == port.Subnet6.llAddr ||
+			port.isTemporaryAddress(ipv6.DstAddr) ||
+			port.isRetiredIPv6Address(ipv6.DstAddr) {
 			packetSentToUs = true
 		} else if ipv6.DstAddr == port.Subnet6.multicastAddr ||
-			ipv6.DstAddr == port.Subnet6.llMulticastAddr {
+			ipv6.DstAddr == port.Subnet6.llMulticastAddr ||
+			ipv6.DstAddr == allNodesMulticastAddr ||
+			port.isTemporaryMulticastAddress(ipv6.DstAddr) ||
+			port.isRetiredMulticastAddress(ipv6.DstAddr) {
+			// Neighbor advertisements which answer duplicate address
+			// detection are sent to all nodes
 			packetSentToMulticast = true
 		}
 		requestCode = types.ICMPv6TypeEchoRequest
@@ -50,6 +58,16 @@ func (port *ipPort) handleICMP(protocol uint8, pkt *packet.Packet, key interface
 
 	icmp := pkt.GetICMPNoCheck()
 
+	if packetSentToUs && port.handleSelfTestReply(protocol, pkt, icmp) {
+		return DirDROP
+	}
+	if packetSentToUs && port.handleForwardHealthReply(protocol, pkt, icmp) {
+		return DirDROP
+	}
+	if packetSentToUs && port.handlePathMTUReply(protocol, icmp) {
+		return DirDROP
+	}
+
 	// If there is KNI interface, direct all ICMP traffic which
 	// doesn't have an active translation entry. It may happen only
 	// for public->private translation when all packets are directed
@@ -59,7 +77,7 @@ func (port *ipPort) handleICMP(protocol uint8, pkt *packet.Packet, key interface
 		if key != nil {
 			_, ok := port.translationTable[protocol].Load(key)
 			if !ok || time.Since(port.getPortmap(ipv6, protocol)[packet.SwapBytesUint16(icmp.Identifier)].lastused) > connectionTimeout {
-				return DirKNI
+				return port.steerKNI(protocol, 0, DirKNI)
 			}
 		}
 	}
@@ -72,6 +90,21 @@ func (port *ipPort) handleICMP(protocol uint8, pkt *packet.Packet, key interface
 		return DirSEND
 	}
 
+	// Steering rules may leave echo requests unanswered
+	if dir, matched := port.matchKNISteering(protocol, 0); matched {
+		return dir
+	}
+
+	var source interface{}
+	if protocol == types.ICMPNumber {
+		source = packet.SwapBytesIPv4Addr(pkt.GetIPv4NoCheck().SrcAddr)
+	} else {
+		source = pkt.GetIPv6NoCheck().SrcAddr
+	}
+	if !port.allowControlPlane(controlPlaneICMP, source) || !icmpLimiter.allow(port, source) {
+		return DirDROP
+	}
+
 	// Return a packet back to sender
 	answerPacket, err := packet.NewPacket()
 	if err != nil {
@@ -84,15 +117,15 @@ func (port *ipPort) handleICMP(protocol uint8, pkt *packet.Packet, key interface
 		swapAddrIPv4(answerPacket)
 		answerPacket.ParseL4ForIPv4()
 		(answerPacket.GetICMPNoCheck()).Type = types.ICMPTypeEchoResponse
-		setIPv4ICMPChecksum(answerPacket, !NoCalculateChecksum, !NoHWTXChecksum)
+		setIPv4ICMPChecksum(answerPacket, !NoCalculateChecksum, port.hwTXChecksum)
 	} else {
 		swapAddrIPv6(answerPacket)
 		answerPacket.ParseL4ForIPv6()
 		(answerPacket.GetICMPNoCheck()).Type = types.ICMPv6TypeEchoResponse
-		setIPv6ICMPChecksum(answerPacket, !NoCalculateChecksum, !NoHWTXChecksum)
+		setIPv6ICMPChecksum(answerPacket, !NoCalculateChecksum, port.hwTXChecksum)
 	}
 
 	port.dumpPacket(answerPacket, DirSEND)
-	answerPacket.SendPacket(port.Index)
+	port.sendPacket(answerPacket)
 	return DirDROP
 }
@@ -0,0 +1,324 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/binary"
+
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// ICMP message types from RFC 792, RFC 950 and RFC 4443 which are
+// not defined by NFF-Go.
+const (
+	icmpTypeSourceQuench       = 4
+	icmpTypeTimeExceeded       = 11
+	icmpTypeParameterProblem   = 12
+	icmpTypeTimestampRequest   = 13
+	icmpTypeTimestampReply     = 14
+	icmpTypeInformationRequest = 15
+	icmpTypeInformationReply   = 16
+	icmpTypeAddressMaskRequest = 17
+	icmpTypeAddressMaskReply   = 18
+	icmpv6TypePacketTooBig     = 2
+	icmpv6TypeTimeExceeded     = 3
+	icmpv6TypeParameterProblem = 4
+)
+
+// isICMPQuery returns true for ICMP query messages which have
+// identifier. Identifier is translated like a port, so requests and
+// replies of the same query share a session.
+func isICMPQuery(protocol, icmpType uint8) bool {
+	if protocol == types.ICMPv6Number {
+		return icmpType == types.ICMPv6TypeEchoRequest || icmpType == types.ICMPv6TypeEchoResponse
+	}
+	switch icmpType {
+	case types.ICMPTypeEchoRequest, types.ICMPTypeEchoResponse,
+		icmpTypeTimestampRequest, icmpTypeTimestampReply,
+		icmpTypeInformationRequest, icmpTypeInformationReply,
+		icmpTypeAddressMaskRequest, icmpTypeAddressMaskReply:
+		return true
+	}
+	return false
+}
+
+// isICMPError returns true for ICMP error messages which quote
+// beginning of packet that caused them.
+func isICMPError(protocol, icmpType uint8) bool {
+	if protocol == types.ICMPv6Number {
+		switch icmpType {
+		case icmpv6TypeDestinationUnreachable, icmpv6TypePacketTooBig,
+			icmpv6TypeTimeExceeded, icmpv6TypeParameterProblem:
+			return true
+		}
+		return false
+	}
+	switch icmpType {
+	case icmpTypeDestinationUnreachable, icmpTypeSourceQuench,
+		icmpTypeTimeExceeded, icmpTypeParameterProblem:
+		return true
+	}
+	return false
+}
+
+// Packet quoted by ICMP error. Offsets are relative to beginning of
+// quoted IP header.
+type quotedPacket struct {
+	data     []byte
+	ipv6     bool
+	protocol uint8
+	// Offset of transport header
+	l4 int
+}
+
+// parseQuotedPacket finds IP header and ports or query identifier of
+// packet quoted by ICMP error. Quoted packet should have at least 8
+// bytes of transport header, which is required to be quoted by
+// RFC 792.
+func parseQuotedPacket(data []byte, ipv6 bool) (*quotedPacket, bool) {
+	q := &quotedPacket{
+		data: data,
+		ipv6: ipv6,
+	}
+	if ipv6 {
+		if len(data) < types.IPv6Len+8 || data[0]>>4 != 6 {
+			return nil, false
+		}
+		q.protocol = data[6]
+		q.l4 = types.IPv6Len
+	} else {
+		if len(data) < types.IPv4MinLen || data[0]>>4 != 4 {
+			return nil, false
+		}
+		q.l4 = int(data[0]&0x0f) * 4
+		// Later fragments have no transport header
+		if q.l4 < types.IPv4MinLen || len(data) < q.l4+8 || binary.BigEndian.Uint16(data[6:8])&0x1fff != 0 {
+			return nil, false
+		}
+		q.protocol = data[9]
+	}
+	switch q.protocol {
+	case types.TCPNumber, types.UDPNumber, udpLiteNumber, dccpNumber:
+		return q, true
+	case types.ICMPNumber, types.ICMPv6Number:
+		// Only errors about queries can be translated
+		if (q.protocol == types.ICMPv6Number) == ipv6 && isICMPQuery(q.protocol, data[q.l4]) {
+			return q, true
+		}
+	}
+	return nil, false
+}
+
+// addrOffset returns offset of source or destination address.
+func (q *quotedPacket) addrOffset(src bool) int {
+	switch {
+	case q.ipv6 && src:
+		return 8
+	case q.ipv6:
+		return 24
+	case src:
+		return 12
+	}
+	return 16
+}
+
+// portOffset returns offset of source or destination port. Both of
+// them are identifier for ICMP queries.
+func (q *quotedPacket) portOffset(src bool) int {
+	if q.protocol == types.ICMPNumber || q.protocol == types.ICMPv6Number {
+		return q.l4 + 4
+	}
+	if src {
+		return q.l4
+	}
+	return q.l4 + 2
+}
+
+// key returns session lookup key of source or destination of quoted
+// packet.
+func (q *quotedPacket) key(src bool) interface{} {
+	a := q.addrOffset(src)
+	port := binary.BigEndian.Uint16(q.data[q.portOffset(src):])
+	if q.ipv6 {
+		var addr types.IPv6Address
+		copy(addr[:], q.data[a:a+types.IPv6AddrLen])
+		return Tuple6{addr: addr, port: port}
+	}
+	return Tuple{addr: types.IPv4Address(binary.BigEndian.Uint32(q.data[a:])), port: port}
+}
+
+// checksumOffset returns offset of transport checksum or -1 if it was
+// not quoted or is absent.
+func (q *quotedPacket) checksumOffset() int {
+	offset := q.l4 + 2
+	switch q.protocol {
+	case types.TCPNumber:
+		offset = q.l4 + 16
+	case types.UDPNumber, udpLiteNumber, dccpNumber:
+		offset = q.l4 + 6
+	}
+	if len(q.data) < offset+2 {
+		return -1
+	}
+	// Zero UDP checksum means that datagram has no checksum
+	if q.protocol == types.UDPNumber && binary.BigEndian.Uint16(q.data[offset:]) == 0 {
+		return -1
+	}
+	return offset
+}
+
+// translate replaces source or destination address and port of quoted
+// packet. Quoted IPv4 header checksum and transport checksum are
+// updated, so that the host which receives error can match it with
+// its packet.
+func (q *quotedPacket) translate(src bool, v4addr types.IPv4Address, v6addr types.IPv6Address, port uint16) {
+	q.translateAddr(src, v4addr, v6addr)
+	q.replace(q.portOffset(src), port)
+}
+
+// translateAddr replaces source or destination address of quoted
+// packet and keeps its ports.
+func (q *quotedPacket) translateAddr(src bool, v4addr types.IPv4Address, v6addr types.IPv6Address) {
+	a := q.addrOffset(src)
+	if q.ipv6 {
+		for i := 0; i < types.IPv6AddrLen; i += 2 {
+			q.replace(a+i, binary.BigEndian.Uint16(v6addr[i:]))
+		}
+	} else {
+		q.replace(a, uint16(v4addr>>16))
+		q.replace(a+2, uint16(v4addr))
+	}
+}
+
+// replace sets 16 bit word of quoted packet and updates checksums
+// which cover it.
+func (q *quotedPacket) replace(offset int, word uint16) {
+	old := binary.BigEndian.Uint16(q.data[offset:])
+	binary.BigEndian.PutUint16(q.data[offset:], word)
+	// ICMPv4 checksum has no pseudo header
+	if cksum := q.checksumOffset(); cksum >= 0 && (q.protocol != types.ICMPNumber || offset >= q.l4) {
+		c := updateChecksum(binary.BigEndian.Uint16(q.data[cksum:]), old, word)
+		// Zero would mean that quoted datagram has no checksum
+		if c == 0 && (q.protocol == types.UDPNumber || q.protocol == udpLiteNumber) {
+			c = 0xffff
+		}
+		binary.BigEndian.PutUint16(q.data[cksum:], c)
+	}
+	if !q.ipv6 && offset < q.l4 {
+		binary.BigEndian.PutUint16(q.data[10:], updateChecksum(binary.BigEndian.Uint16(q.data[10:]), old, word))
+	}
+}
+
+// handleICMPError translates ICMP error by session of packet which it
+// quotes. Error received by public port quotes translated packet sent
+// by NAT, so its destination and quoted source are changed to private
+// host. Error sent by private host quotes translated packet received
+// by it, so its source and quoted destination are changed to public
+// address. ICMP header is not changed, so RFC 4884 length of quoted
+// packet, RFC 1191 next hop MTU and multipart extensions which follow
+// quoted packet are preserved.
+func (pp *portPair) handleICMPError(port *ipPort, protocol uint8, pkt *packet.Packet, pktVLAN *packet.VLANHdr, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) uint {
+	inbound := port.Type == iPUBLIC
+	ipv6 := pktIPv6 != nil
+	kniPresent := port.KNIName != ""
+	var addressAcquired bool
+	if ipv6 {
+		addressAcquired = port.Subnet6.addressAcquired
+	} else {
+		addressAcquired = port.Subnet.addressAcquired
+	}
+
+	payload, ok := pkt.GetPacketPayload()
+	var q *quotedPacket
+	var v interface{}
+	found := false
+	if ok {
+		if q, ok = parseQuotedPacket(payload, ipv6); ok {
+			// Quoted source of inbound error and quoted destination
+			// of outbound error are translated addresses
+			v, found = port.translationTable[q.protocol].Load(q.key(inbound))
+		}
+	}
+	if !found {
+		// Errors about traffic of host itself are sent to KNI
+		dir := DirDROP
+		if inbound && kniPresent && addressAcquired {
+			dir = DirKNI
+		}
+		port.dumpPacket(pkt, dir)
+		return dir
+	}
+	v4addr, v6addr, newPort, zeroAddr := getAddrFromTuple(v, ipv6)
+	if zeroAddr {
+		port.dumpPacket(pkt, DirKNI)
+		return DirKNI
+	}
+	// Errors are never sent about errors
+	if !pp.forwardHopLimit(port, pkt, pktIPv4, pktIPv6, false) {
+		return DirDROP
+	}
+
+	// Find MAC address of private host for inbound errors and of
+	// remote host for outbound errors
+	var mac types.MACAddress
+	vlanTag := port.opposite.Vlan
+	switch {
+	case inbound && ipv6:
+		mac, found = port.opposite.getMACForIPv6(v6addr)
+	case inbound:
+		mac, found = port.opposite.getMACForIPv4(v4addr)
+	case ipv6:
+		mac, found = port.opposite.getMACForIPv6(pktIPv6.DstAddr)
+	default:
+		if vlan := port.opposite.vlanByAddr(v4addr); vlan != nil {
+			vlanTag = vlan.Vlan
+			mac, found = port.opposite.getMACForVLAN(vlan, packet.SwapBytesIPv4Addr(pktIPv4.DstAddr))
+		} else {
+			mac, found = port.opposite.getMACForIPv4(packet.SwapBytesIPv4Addr(pktIPv4.DstAddr))
+		}
+	}
+	if !found {
+		port.dumpPacket(pkt, DirDROP)
+		return DirDROP
+	}
+
+	if inbound {
+		pp.countTraffic(protocol, false, pkt.GetPacketLen(), pktIPv4, pktIPv6, v4addr, v6addr)
+	} else {
+		pp.countTraffic(protocol, true, pkt.GetPacketLen(), pktIPv4, pktIPv6, 0, types.IPv6Address{})
+	}
+
+	pkt.Ether.DAddr = mac
+	pkt.Ether.SAddr = port.opposite.SrcMACAddress
+	if pktVLAN != nil {
+		pktVLAN.SetVLANTagIdentifier(vlanTag)
+	}
+	q.translate(inbound, v4addr, v6addr, newPort)
+	switch {
+	case inbound && ipv6:
+		pktIPv6.DstAddr = v6addr
+	case inbound:
+		pktIPv4.DstAddr = packet.SwapBytesIPv4Addr(v4addr)
+	case ipv6:
+		pktIPv6.SrcAddr = v6addr
+	default:
+		pktIPv4.SrcAddr = packet.SwapBytesIPv4Addr(v4addr)
+	}
+	if inbound {
+		pp.DSCP.Ingress.apply(pktIPv4, pktIPv6)
+	} else {
+		pp.DSCP.Egress.apply(pktIPv4, pktIPv6)
+		pp.applyFlowLabel(pktIPv6, protocol, 0, 0)
+	}
+	if ipv6 {
+		setIPv6ICMPChecksum(pkt, !NoCalculateChecksum, port.opposite.hwTXChecksum)
+	} else {
+		setIPv4ICMPChecksum(pkt, !NoCalculateChecksum, port.opposite.hwTXChecksum)
+	}
+
+	port.opposite.dumpPacket(pkt, DirSEND)
+	return DirSEND
+}
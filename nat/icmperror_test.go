@@ -0,0 +1,357 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+func TestICMPMessageTypes(t *testing.T) {
+	tests := []struct {
+		protocol uint8
+		icmpType uint8
+		query    bool
+		err      bool
+	}{
+		{types.ICMPNumber, types.ICMPTypeEchoRequest, true, false},
+		{types.ICMPNumber, types.ICMPTypeEchoResponse, true, false},
+		{types.ICMPNumber, icmpTypeTimestampRequest, true, false},
+		{types.ICMPNumber, icmpTypeTimestampReply, true, false},
+		{types.ICMPNumber, icmpTypeInformationRequest, true, false},
+		{types.ICMPNumber, icmpTypeInformationReply, true, false},
+		{types.ICMPNumber, icmpTypeAddressMaskRequest, true, false},
+		{types.ICMPNumber, icmpTypeAddressMaskReply, true, false},
+		{types.ICMPNumber, icmpTypeDestinationUnreachable, false, true},
+		{types.ICMPNumber, icmpTypeSourceQuench, false, true},
+		{types.ICMPNumber, icmpTypeTimeExceeded, false, true},
+		{types.ICMPNumber, icmpTypeParameterProblem, false, true},
+		// Redirect and router advertisement
+		{types.ICMPNumber, 5, false, false},
+		{types.ICMPNumber, 9, false, false},
+		{types.ICMPv6Number, types.ICMPv6TypeEchoRequest, true, false},
+		{types.ICMPv6Number, types.ICMPv6TypeEchoResponse, true, false},
+		{types.ICMPv6Number, icmpv6TypeDestinationUnreachable, false, true},
+		{types.ICMPv6Number, icmpv6TypePacketTooBig, false, true},
+		{types.ICMPv6Number, icmpv6TypeTimeExceeded, false, true},
+		{types.ICMPv6Number, icmpv6TypeParameterProblem, false, true},
+		// ICMPv4 query types are not ICMPv6 queries
+		{types.ICMPv6Number, icmpTypeTimestampRequest, false, false},
+		{types.ICMPv6Number, icmpTypeAddressMaskRequest, false, false},
+		// Neighbor solicitation
+		{types.ICMPv6Number, 135, false, false},
+	}
+	for _, tt := range tests {
+		if got := isICMPQuery(tt.protocol, tt.icmpType); got != tt.query {
+			t.Errorf("isICMPQuery(%d, %d) = %v, expected %v", tt.protocol, tt.icmpType, got, tt.query)
+		}
+		if got := isICMPError(tt.protocol, tt.icmpType); got != tt.err {
+			t.Errorf("isICMPError(%d, %d) = %v, expected %v", tt.protocol, tt.icmpType, got, tt.err)
+		}
+	}
+}
+
+func onesSum(sum uint32, data []byte) uint32 {
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(data[i])<<8 | uint32(data[i+1])
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	return sum
+}
+
+func foldChecksum(sum uint32) uint16 {
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// Packet which is quoted by ICMP error in tests.
+type testDatagram struct {
+	ipv6     bool
+	protocol uint8
+	// Type of ICMP query, source port is its identifier
+	icmpType uint8
+	// UDP datagram without checksum
+	noChecksum bool
+	body       []byte
+}
+
+// transportChecksum returns calculated checksum of transport header
+// and payload l4 with zero checksum.
+func (d *testDatagram) transportChecksum(src, dst, l4 []byte) uint16 {
+	var sum uint32
+	if d.protocol != types.ICMPNumber {
+		sum = onesSum(sum, src)
+		sum = onesSum(sum, dst)
+		sum += uint32(len(l4)) + uint32(d.protocol)
+	}
+	return foldChecksum(onesSum(sum, l4))
+}
+
+// transport returns transport header and payload with zero checksum
+// and offset of checksum.
+func (d *testDatagram) transport(sport, dport uint16) ([]byte, int) {
+	var l4 []byte
+	var cksum int
+	switch d.protocol {
+	case types.TCPNumber:
+		l4 = make([]byte, 20)
+		binary.BigEndian.PutUint32(l4[4:], 0x01020304)
+		l4[12] = 0x50
+		l4[13] = 0x02
+		binary.BigEndian.PutUint16(l4[14:], 0xffff)
+		cksum = 16
+	case types.UDPNumber, udpLiteNumber:
+		l4 = make([]byte, 8)
+		if d.protocol == types.UDPNumber {
+			binary.BigEndian.PutUint16(l4[4:], uint16(8+len(d.body)))
+		}
+		cksum = 6
+	default:
+		l4 = make([]byte, 8)
+		l4[0] = d.icmpType
+		binary.BigEndian.PutUint16(l4[4:], sport)
+		binary.BigEndian.PutUint16(l4[6:], 1)
+		return append(l4, d.body...), 2
+	}
+	binary.BigEndian.PutUint16(l4[0:], sport)
+	binary.BigEndian.PutUint16(l4[2:], dport)
+	return append(l4, d.body...), cksum
+}
+
+// build returns datagram with correct IPv4 header checksum and
+// transport checksum.
+func (d *testDatagram) build(src, dst []byte, sport, dport uint16) []byte {
+	l4, cksum := d.transport(sport, dport)
+	if !d.noChecksum {
+		c := d.transportChecksum(src, dst, l4)
+		if c == 0 && (d.protocol == types.UDPNumber || d.protocol == udpLiteNumber) {
+			c = 0xffff
+		}
+		binary.BigEndian.PutUint16(l4[cksum:], c)
+	}
+	if d.ipv6 {
+		ip := make([]byte, types.IPv6Len)
+		ip[0] = 0x60
+		binary.BigEndian.PutUint16(ip[4:], uint16(len(l4)))
+		ip[6] = d.protocol
+		ip[7] = 64
+		copy(ip[8:], src)
+		copy(ip[24:], dst)
+		return append(ip, l4...)
+	}
+	ip := make([]byte, types.IPv4MinLen)
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(types.IPv4MinLen+len(l4)))
+	binary.BigEndian.PutUint16(ip[4:], 0x1234)
+	// Don't fragment
+	ip[6] = 0x40
+	ip[8] = 64
+	ip[9] = d.protocol
+	copy(ip[12:], src)
+	copy(ip[16:], dst)
+	binary.BigEndian.PutUint16(ip[10:], foldChecksum(onesSum(0, ip)))
+	return append(ip, l4...)
+}
+
+var (
+	testPrivate4 = []byte{192, 168, 1, 10}
+	testPublic4  = []byte{198, 51, 100, 1}
+	testRemote4  = []byte{203, 0, 113, 7}
+	testPrivate6 = []byte{0xfd, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 10}
+	testPublic6  = []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+	testRemote6  = []byte{0x20, 0x01, 0x0d, 0xb8, 0xff, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 7}
+)
+
+func TestParseQuotedPacket(t *testing.T) {
+	tcp := testDatagram{protocol: types.TCPNumber}
+	laterFragment := tcp.build(testPublic4, testRemote4, 1024, 80)
+	laterFragment[7] = 1
+	badHeaderLength := tcp.build(testPublic4, testRemote4, 1024, 80)
+	badHeaderLength[0] = 0x44
+
+	tests := []struct {
+		name     string
+		data     []byte
+		ipv6     bool
+		protocol uint8
+		l4       int
+	}{
+		{"TCP", tcp.build(testPublic4, testRemote4, 1024, 80), false, types.TCPNumber, 20},
+		{"UDP with 8 bytes of header", (&testDatagram{protocol: types.UDPNumber}).build(testPublic4, testRemote4, 1024, 53), false, types.UDPNumber, 20},
+		{"UDP-Lite", (&testDatagram{protocol: udpLiteNumber}).build(testPublic4, testRemote4, 1024, 53), false, udpLiteNumber, 20},
+		{"echo request", (&testDatagram{protocol: types.ICMPNumber, icmpType: types.ICMPTypeEchoRequest}).build(testPublic4, testRemote4, 7, 7),
+			false, types.ICMPNumber, 20},
+		{"timestamp request", (&testDatagram{protocol: types.ICMPNumber, icmpType: icmpTypeTimestampRequest, body: make([]byte, 12)}).build(testPublic4, testRemote4, 7, 7),
+			false, types.ICMPNumber, 20},
+		{"address mask reply", (&testDatagram{protocol: types.ICMPNumber, icmpType: icmpTypeAddressMaskReply, body: make([]byte, 4)}).build(testRemote4, testPublic4, 7, 7),
+			false, types.ICMPNumber, 20},
+		{"information request", (&testDatagram{protocol: types.ICMPNumber, icmpType: icmpTypeInformationRequest}).build(testPublic4, testRemote4, 7, 7),
+			false, types.ICMPNumber, 20},
+		{"ICMPv6 echo request", (&testDatagram{ipv6: true, protocol: types.ICMPv6Number, icmpType: types.ICMPv6TypeEchoRequest}).build(testPublic6, testRemote6, 7, 7),
+			true, types.ICMPv6Number, types.IPv6Len},
+		{"TCP over IPv6", (&testDatagram{ipv6: true, protocol: types.TCPNumber}).build(testPublic6, testRemote6, 1024, 80), true, types.TCPNumber, types.IPv6Len},
+		{"error about error", (&testDatagram{protocol: types.ICMPNumber, icmpType: icmpTypeTimeExceeded}).build(testPublic4, testRemote4, 0, 0), false, 0, 0},
+		{"ICMPv6 in IPv4", (&testDatagram{protocol: types.ICMPv6Number, icmpType: types.ICMPv6TypeEchoRequest}).build(testPublic4, testRemote4, 7, 7), false, 0, 0},
+		{"ICMP in IPv6", (&testDatagram{ipv6: true, protocol: types.ICMPNumber, icmpType: icmpTypeTimestampRequest}).build(testPublic6, testRemote6, 7, 7), true, 0, 0},
+		{"not translated protocol", (&testDatagram{protocol: 47}).build(testPublic4, testRemote4, 0, 0), false, 0, 0},
+		{"later fragment", laterFragment, false, 0, 0},
+		{"bad header length", badHeaderLength, false, 0, 0},
+		{"truncated transport header", tcp.build(testPublic4, testRemote4, 1024, 80)[:types.IPv4MinLen+4], false, 0, 0},
+		{"IPv4 as IPv6", tcp.build(testPublic4, testRemote4, 1024, 80), true, 0, 0},
+		{"empty", nil, false, 0, 0},
+	}
+	for _, tt := range tests {
+		q, ok := parseQuotedPacket(tt.data, tt.ipv6)
+		if tt.protocol == 0 {
+			if ok {
+				t.Errorf("%s: quoted packet of protocol %d is parsed", tt.name, q.protocol)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("%s: quoted packet is not parsed", tt.name)
+			continue
+		}
+		if q.protocol != tt.protocol || q.l4 != tt.l4 {
+			t.Errorf("%s: protocol %d with transport header at %d, expected %d at %d", tt.name, q.protocol, q.l4, tt.protocol, tt.l4)
+		}
+	}
+}
+
+func TestQuotedPacketKey(t *testing.T) {
+	q, _ := parseQuotedPacket((&testDatagram{protocol: types.UDPNumber}).build(testPublic4, testRemote4, 1024, 53), false)
+	if key := q.key(true); key != (Tuple{addr: hostIPv4(198, 51, 100, 1), port: 1024}) {
+		t.Errorf("Source key is %v", key)
+	}
+	if key := q.key(false); key != (Tuple{addr: hostIPv4(203, 0, 113, 7), port: 53}) {
+		t.Errorf("Destination key is %v", key)
+	}
+
+	// Both keys of query have its identifier
+	q, _ = parseQuotedPacket((&testDatagram{ipv6: true, protocol: types.ICMPv6Number, icmpType: types.ICMPv6TypeEchoRequest}).build(testPublic6, testRemote6, 77, 0), true)
+	var addr types.IPv6Address
+	copy(addr[:], testRemote6)
+	if key := q.key(false); key != (Tuple6{addr: addr, port: 77}) {
+		t.Errorf("Destination key of echo request is %v", key)
+	}
+}
+
+// udpZeroChecksumBody returns payload which makes checksum of UDP
+// datagram from src to dst zero before it is replaced with 0xffff.
+func udpZeroChecksumBody(src, dst []byte, sport, dport uint16) []byte {
+	for w := 0; w <= 0xffff; w++ {
+		d := testDatagram{protocol: types.UDPNumber, body: []byte{byte(w >> 8), byte(w)}}
+		l4, _ := d.transport(sport, dport)
+		if d.transportChecksum(src, dst, l4) == 0 {
+			return d.body
+		}
+	}
+	return nil
+}
+
+func TestQuotedPacketTranslate(t *testing.T) {
+	// RFC 4884 pads quoted datagram to 128 bytes and appends
+	// extension structure with interface information object
+	rfc4884 := make([]byte, 128-types.IPv4MinLen-8)
+	rfc4884 = append(rfc4884, 0x20, 0x00, 0x12, 0x34, 0x00, 0x08, 0x02, 0x01, 0x00, 0x00, 0x00, 0x05)
+
+	tests := []struct {
+		name     string
+		datagram testDatagram
+		// Inbound error quotes packet of NAT, its source is
+		// translated. Outbound error quotes packet sent to private
+		// host, its destination is translated.
+		inbound bool
+		// Quoted bytes of transport header and payload, all if zero
+		quoted  int
+		trailer []byte
+	}{
+		{"inbound TCP", testDatagram{protocol: types.TCPNumber, body: []byte("GET")}, true, 0, nil},
+		{"outbound TCP", testDatagram{protocol: types.TCPNumber}, false, 0, nil},
+		{"TCP without quoted checksum", testDatagram{protocol: types.TCPNumber}, true, 8, nil},
+		{"UDP", testDatagram{protocol: types.UDPNumber, body: []byte{1, 2, 3}}, true, 0, nil},
+		{"UDP without checksum", testDatagram{protocol: types.UDPNumber, noChecksum: true, body: []byte{1, 2, 3}}, true, 0, nil},
+		{"UDP with checksum which becomes zero", testDatagram{protocol: types.UDPNumber,
+			body: udpZeroChecksumBody(testPrivate4, testRemote4, 5000, 53)}, true, 0, nil},
+		{"UDP-Lite", testDatagram{protocol: udpLiteNumber, body: []byte{1, 2, 3, 4}}, false, 0, nil},
+		{"UDP with RFC 4884 extension", testDatagram{protocol: types.UDPNumber}, true, 0, rfc4884},
+		{"echo request", testDatagram{protocol: types.ICMPNumber, icmpType: types.ICMPTypeEchoRequest, body: []byte("ping")}, true, 0, nil},
+		{"timestamp request", testDatagram{protocol: types.ICMPNumber, icmpType: icmpTypeTimestampRequest,
+			body: []byte{0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0}}, true, 0, nil},
+		{"timestamp reply", testDatagram{protocol: types.ICMPNumber, icmpType: icmpTypeTimestampReply,
+			body: []byte{0, 0, 1, 0, 0, 0, 1, 1, 0, 0, 1, 2}}, false, 0, nil},
+		{"address mask request", testDatagram{protocol: types.ICMPNumber, icmpType: icmpTypeAddressMaskRequest, body: make([]byte, 4)}, true, 0, nil},
+		{"address mask reply", testDatagram{protocol: types.ICMPNumber, icmpType: icmpTypeAddressMaskReply,
+			body: []byte{255, 255, 255, 0}}, false, 0, nil},
+		{"information request", testDatagram{protocol: types.ICMPNumber, icmpType: icmpTypeInformationRequest}, true, 0, nil},
+		{"echo request without quoted payload", testDatagram{protocol: types.ICMPNumber, icmpType: types.ICMPTypeEchoRequest,
+			body: make([]byte, 32)}, true, 8, nil},
+		{"inbound TCP over IPv6", testDatagram{ipv6: true, protocol: types.TCPNumber}, true, 0, nil},
+		{"outbound UDP over IPv6", testDatagram{ipv6: true, protocol: types.UDPNumber, body: []byte{9}}, false, 0, nil},
+		{"ICMPv6 echo request", testDatagram{ipv6: true, protocol: types.ICMPv6Number, icmpType: types.ICMPv6TypeEchoRequest,
+			body: []byte("ping")}, true, 0, nil},
+		{"ICMPv6 echo reply", testDatagram{ipv6: true, protocol: types.ICMPv6Number, icmpType: types.ICMPv6TypeEchoResponse}, false, 0, nil},
+	}
+	for _, tt := range tests {
+		d := &tt.datagram
+		private, public, remote := testPrivate4, testPublic4, testRemote4
+		l3 := types.IPv4MinLen
+		if d.ipv6 {
+			private, public, remote = testPrivate6, testPublic6, testRemote6
+			l3 = types.IPv6Len
+		}
+		query := d.protocol == types.ICMPNumber || d.protocol == types.ICMPv6Number
+		build := func(src, dst []byte, sport, dport uint16) []byte {
+			// Identifier of query is its translated port in both
+			// directions
+			if query && !tt.inbound {
+				sport = dport
+			}
+			data := d.build(src, dst, sport, dport)
+			if tt.quoted != 0 {
+				data = data[:l3+tt.quoted]
+			}
+			return append(data, tt.trailer...)
+		}
+
+		// Private host uses port 5000, NAT public port 1024, remote
+		// host port 53
+		var data, expected, addr []byte
+		var port uint16
+		if tt.inbound {
+			data = build(public, remote, 1024, 53)
+			expected = build(private, remote, 5000, 53)
+			addr, port = private, 5000
+		} else {
+			data = build(remote, private, 53, 5000)
+			expected = build(remote, public, 53, 1024)
+			addr, port = public, 1024
+		}
+
+		q, ok := parseQuotedPacket(data, d.ipv6)
+		if !ok {
+			t.Errorf("%s: quoted packet is not parsed", tt.name)
+			continue
+		}
+		var v4addr types.IPv4Address
+		var v6addr types.IPv6Address
+		if d.ipv6 {
+			copy(v6addr[:], addr)
+		} else {
+			v4addr = types.IPv4Address(binary.BigEndian.Uint32(addr))
+		}
+		q.translate(tt.inbound, v4addr, v6addr, port)
+		if !bytes.Equal(data, expected) {
+			t.Errorf("%s: translated quoted packet is\n%x\nexpected\n%x", tt.name, data, expected)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+type icmpAllocation int
+
+const (
+	// Identifiers are allocated one after another like TCP and UDP
+	// ports
+	icmpAllocationSequential icmpAllocation = iota
+	// Public identifier is the same as private one when it is free
+	// and in range, otherwise it is allocated sequentially
+	icmpAllocationPreserve
+)
+
+var icmpAllocationLookup = map[string]icmpAllocation{
+	"sequential": icmpAllocationSequential,
+	"preserve":   icmpAllocationPreserve,
+}
+
+// Translation of identifiers of ICMP and ICMPv6 queries of port pair.
+// Monitoring systems which match replies by identifier or which run
+// many probes from one host may need identifiers which stay the same
+// or which don't compete with TCP and UDP ports for a range.
+type icmpIdentifiersConfig struct {
+	// Public identifiers of dynamic ICMP sessions, all dynamic ports
+	// if not set. Range should be within dynamic ports.
+	Range      portSpan       `json:"range"`
+	Allocation icmpAllocation `json:"allocation"`
+}
+
+// UnmarshalJSON parses ICMP identifier allocation policy.
+func (out *icmpAllocation) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	result, ok := icmpAllocationLookup[s]
+	if !ok {
+		return errors.New("Bad ICMP identifiers allocation: " + s)
+	}
+
+	*out = result
+	return nil
+}
+
+// String returns allocation policy name as it is used in config file.
+func (allocation icmpAllocation) String() string {
+	for name, a := range icmpAllocationLookup {
+		if a == allocation {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// MarshalJSON writes allocation policy name as it is used in config
+// file.
+func (allocation icmpAllocation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(allocation.String())
+}
+
+func (cfg *icmpIdentifiersConfig) check() error {
+	if cfg.Range.first == 0 {
+		return nil
+	}
+	if cfg.Range.first < portStart || cfg.Range.last >= portEnd {
+		return fmt.Errorf("ICMP identifiers range %s should be between %d and %d", cfg.Range, portStart, portEnd-1)
+	}
+	return nil
+}
+
+func isICMPProtocol(protocol uint8) bool {
+	return protocol == types.ICMPNumber || protocol == types.ICMPv6Number
+}
+
+// dynamicPortRange returns first and after last port which dynamic
+// sessions of protocol get.
+func (pp *portPair) dynamicPortRange(protocol uint8) (int, int) {
+	if isICMPProtocol(protocol) && pp.ICMPIdentifiers.Range.first != 0 {
+		return int(pp.ICMPIdentifiers.Range.first), int(pp.ICMPIdentifiers.Range.last) + 1
+	}
+	return portStart, portEnd
+}
+
+// portPoolSize returns number of public ports which dynamic sessions
+// of protocol may get.
+func (pp *portPair) portPoolSize(protocol uint8) int {
+	first, end := pp.dynamicPortRange(protocol)
+	return end - first
+}
+
+// allocationCursor returns port that was allocated last for protocol.
+func (pp *portPair) allocationCursor(protocol uint8) *int {
+	if isICMPProtocol(protocol) {
+		return &pp.lastICMPPort
+	}
+	return &pp.lastport
+}
+
+// preservedICMPIdentifier returns identifier of private host if it may
+// be used as public identifier of new session and deletes its old
+// session. Mutex should be locked.
+func (pp *portPair) preservedICMPIdentifier(ipv6 bool, protocol uint8, id uint16, timeout time.Duration) (int, bool) {
+	if !isICMPProtocol(protocol) || pp.ICMPIdentifiers.Allocation != icmpAllocationPreserve {
+		return 0, false
+	}
+	first, end := pp.dynamicPortRange(protocol)
+	p := int(id)
+	if p < first || p >= end || pp.isLocalPort(id) {
+		return 0, false
+	}
+	pm := pp.getPublicPortPortmap(ipv6, protocol)
+	if pm[p].static || time.Since(pm[p].lastused) <= timeout {
+		return 0, false
+	}
+	pp.deleteOldConnection(ipv6, protocol, p)
+	return p, true
+}
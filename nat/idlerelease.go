@@ -0,0 +1,167 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	upd "github.com/intel-go/nff-go-nat/updatecfg"
+)
+
+// Public addresses are checked for sessions this often
+const idleReleaseCheckInterval = 10 * time.Second
+
+// Release of DHCP lease of public port which has no sessions, so that
+// port pairs which are idle don't hold addresses of provider. Lease is
+// acquired again when private host starts new session. Timeout is in
+// seconds, zero disables release. Only IPv4 address is released.
+type idleReleaseConfig struct {
+	Timeout int `json:"timeout"`
+}
+
+// Idle release state of port pair. It is changed by packet handlers
+// and by release goroutine, so it should be accessed only atomically.
+type idleReleaseState struct {
+	// Time of last check which found sessions, in nanoseconds since
+	// Unix epoch
+	active int64
+	// Set while lease is released because port pair was idle
+	released int32
+	// Set by packet handlers when lease should be acquired again,
+	// cleared by DHCP client when it restarts discovery
+	reacquire int32
+	// Released and acquired again leases
+	releases, reacquisitions uint64
+}
+
+func (cfg *idleReleaseConfig) enabled() bool {
+	return cfg.Timeout != 0
+}
+
+func (cfg *idleReleaseConfig) check(pp *portPair) error {
+	if cfg.Timeout < 0 {
+		return errors.New("Idle-address-release timeout should not be negative")
+	}
+	if !cfg.enabled() {
+		return nil
+	}
+	public := &pp.PublicPort
+	if public.Subnet.addressAcquired || pp.DisableIPv4 {
+		return errors.New("Idle address release requires DHCP address of public port " + public.ifName())
+	}
+	if public.KNIName != "" {
+		return errors.New("Idle address release requires public port " + public.ifName() + " without KNI interface")
+	}
+	return nil
+}
+
+func (cfg *idleReleaseConfig) timeout() time.Duration {
+	return time.Duration(cfg.Timeout) * time.Second
+}
+
+// usesPublicAddress returns true if port pair has forwarded ports or
+// active dynamic IPv4 sessions on address of public port. Mutex should
+// be locked.
+func (pp *portPair) usesPublicAddress() bool {
+	for _, protocol := range pp.sessionProtocols(false) {
+		pm := pp.getPublicPortPortmap(false, protocol)
+		for p := range pm {
+			if pm[p].static {
+				return true
+			}
+			// Sessions of VLAN subinterfaces don't use port address
+			if pm[p].addr == 0 && time.Since(pm[p].lastused) <= connectionTimeout {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// releaseIdleAddress releases DHCP lease of public port when port pair
+// didn't use its address for timeout. Sessions are checked and lease is
+// released with mutex locked, so that no session is created in
+// between.
+func (pp *portPair) releaseIdleAddress(now time.Time) {
+	public := &pp.PublicPort
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	if public.Subnet.dhcpState() != dhcpStateBound || pp.usesPublicAddress() {
+		atomic.StoreInt64(&pp.idleRelease.active, now.UnixNano())
+		if public.Subnet.addressAcquired {
+			// Lease was acquired again by control API
+			atomic.StoreInt32(&pp.idleRelease.released, 0)
+		}
+		return
+	}
+	if now.Sub(time.Unix(0, atomic.LoadInt64(&pp.idleRelease.active))) < pp.IdleAddressRelease.timeout() {
+		return
+	}
+	println("Releasing idle address", public.Subnet.String(), "of port", public.logName())
+	public.sendDHCPReleaseRequest()
+	atomic.StoreInt32(&pp.idleRelease.released, 1)
+	atomic.AddUint64(&pp.idleRelease.releases, 1)
+}
+
+// reacquireIdleAddress restarts DHCP client of public port if its
+// lease was released because port pair was idle. It is called when
+// private host starts new session while public port has no address,
+// session is created by retransmissions of host after address is
+// acquired.
+func (pp *portPair) reacquireIdleAddress() {
+	if !atomic.CompareAndSwapInt32(&pp.idleRelease.released, 1, 0) {
+		return
+	}
+	atomic.StoreInt64(&pp.idleRelease.active, time.Now().UnixNano())
+	atomic.AddUint64(&pp.idleRelease.reacquisitions, 1)
+	// DHCP client state belongs to its goroutine, it requests new
+	// lease with its next discover
+	atomic.StoreInt32(&pp.idleRelease.reacquire, 1)
+}
+
+// takeReacquireRequest returns true once after packet handlers asked to
+// acquire released lease again. It is called by DHCP client goroutine.
+func (pp *portPair) takeReacquireRequest() bool {
+	return atomic.CompareAndSwapInt32(&pp.idleRelease.reacquire, 1, 0)
+}
+
+// StartIdleAddressRelease starts releasing idle DHCP addresses of
+// public ports of port pairs which enable it.
+func StartIdleAddressRelease() {
+	enabled := false
+	now := time.Now().UnixNano()
+	for i := range Natconfig.PortPairs {
+		pp := &Natconfig.PortPairs[i]
+		if pp.IdleAddressRelease.enabled() {
+			enabled = true
+			atomic.StoreInt64(&pp.idleRelease.active, now)
+		}
+	}
+	if !enabled {
+		return
+	}
+	go func() {
+		for {
+			time.Sleep(idleReleaseCheckInterval)
+			now := time.Now()
+			for i := range Natconfig.PortPairs {
+				if Natconfig.PortPairs[i].IdleAddressRelease.enabled() {
+					Natconfig.PortPairs[i].releaseIdleAddress(now)
+				}
+			}
+		}
+	}()
+}
+
+// counters returns idle release counters of port pair.
+func (s *idleReleaseState) counters() []*upd.Counter {
+	return []*upd.Counter{
+		&upd.Counter{Name: "idle-address-releases", Value: atomic.LoadUint64(&s.releases)},
+		&upd.Counter{Name: "idle-address-reacquisitions", Value: atomic.LoadUint64(&s.reacquisitions)},
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	includeKey       = "include"
+	variablesKey     = "variables"
+	hostOverridesKey = "host-overrides"
+)
+
+// loadConfigFile reads config file and returns JSON document with all
+// includes, per-host overrides and variable substitutions
+// processed. Included files are merged first in order of their
+// appearance, then current file contents is merged on top of them,
+// so values from including file take precedence. Objects are merged
+// recursively while all other values, including arrays, are
+// replaced.
+func loadConfigFile(fileName string) ([]byte, error) {
+	tree, err := loadConfigTree(fileName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply overrides for the host where NAT is running
+	if overrides, ok := tree[hostOverridesKey]; ok {
+		delete(tree, hostOverridesKey)
+		hosts, ok := overrides.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("\"%s\" setting should be an object with host names as keys", hostOverridesKey)
+		}
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		if o, ok := hosts[hostname]; ok {
+			override, ok := o.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("Override for host \"%s\" should be an object", hostname)
+			}
+			fmt.Println("Applying configuration overrides for host", hostname)
+			mergeConfigTrees(tree, override)
+		}
+	}
+
+	variables := map[string]string{}
+	if v, ok := tree[variablesKey]; ok {
+		delete(tree, variablesKey)
+		vars, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("\"%s\" setting should be an object", variablesKey)
+		}
+		for name, value := range vars {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("Value of variable \"%s\" should be a string", name)
+			}
+			variables[name] = s
+		}
+	}
+
+	result, err := substituteVariables(tree, variables)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkConfigValues(tree); err != nil {
+		return nil, err
+	}
+	return json.Marshal(result)
+}
+
+// textPosition returns line and column of byte offset in text, both
+// start from 1.
+func textPosition(data []byte, offset int64) (int, int) {
+	line, column := 1, 1
+	for i := int64(0); i < offset && i < int64(len(data)); i++ {
+		if data[i] == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return line, column
+}
+
+func loadConfigTree(fileName string, stack []string) (map[string]interface{}, error) {
+	absName, err := filepath.Abs(fileName)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range stack {
+		if f == absName {
+			return nil, fmt.Errorf("Config file \"%s\" includes itself: %s", fileName, strings.Join(append(stack, absName), " -> "))
+		}
+	}
+	stack = append(stack, absName)
+
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := decodeConfigTree(data)
+	if err != nil {
+		if syntax, ok := err.(*json.SyntaxError); ok {
+			line, column := textPosition(data, syntax.Offset)
+			return nil, fmt.Errorf("Failed to parse config file \"%s\" at line %d column %d: %+v", fileName, line, column, err)
+		}
+		return nil, fmt.Errorf("Failed to parse config file \"%s\": %+v", fileName, err)
+	}
+	if err := migrateConfigTree(tree, fileName); err != nil {
+		return nil, err
+	}
+
+	inc, ok := tree[includeKey]
+	if !ok {
+		return tree, nil
+	}
+	delete(tree, includeKey)
+
+	var includes []string
+	switch v := inc.(type) {
+	case string:
+		includes = []string{v}
+	case []interface{}:
+		for _, i := range v {
+			s, ok := i.(string)
+			if !ok {
+				return nil, fmt.Errorf("Bad \"%s\" value in config file \"%s\", it should be a file name or an array of file names", includeKey, fileName)
+			}
+			includes = append(includes, s)
+		}
+	default:
+		return nil, fmt.Errorf("Bad \"%s\" value in config file \"%s\", it should be a file name or an array of file names", includeKey, fileName)
+	}
+
+	result := map[string]interface{}{}
+	for _, i := range includes {
+		// Relative paths are resolved against including file location
+		if !filepath.IsAbs(i) {
+			i = filepath.Join(filepath.Dir(fileName), i)
+		}
+		included, err := loadConfigTree(i, stack)
+		if err != nil {
+			return nil, err
+		}
+		mergeConfigTrees(result, included)
+	}
+	mergeConfigTrees(result, tree)
+	return result, nil
+}
+
+// decodeConfigTree decodes JSON document of config file. Numbers are
+// kept as json.Number, so that integers which don't fit into float64
+// mantissa are not rounded when tree is encoded again.
+func decodeConfigTree(data []byte) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree map[string]interface{}
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid data after top-level value")
+	}
+	return tree, nil
+}
+
+// mergeConfigTrees merges src into dst recursively.
+func mergeConfigTrees(dst, src map[string]interface{}) {
+	for k, v := range src {
+		srcObj, srcIsObj := v.(map[string]interface{})
+		dstObj, dstIsObj := dst[k].(map[string]interface{})
+		if srcIsObj && dstIsObj {
+			mergeConfigTrees(dstObj, srcObj)
+		} else {
+			dst[k] = v
+		}
+	}
+}
+
+func substituteVariables(node interface{}, variables map[string]string) (interface{}, error) {
+	switch v := node.(type) {
+	case string:
+		return expandString(v, variables)
+	case map[string]interface{}:
+		for k := range v {
+			n, err := substituteVariables(v[k], variables)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = n
+		}
+	case []interface{}:
+		for i := range v {
+			n, err := substituteVariables(v[i], variables)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = n
+		}
+	}
+	return node, nil
+}
+
+// expandString replaces all ${NAME} references in a string value with
+// variable values. Variables from config file take precedence over
+// environment variables. Use $$ to get a literal $ character.
+func expandString(s string, variables map[string]string) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+
+	var result strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' {
+			result.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '$' {
+			result.WriteByte('$')
+			i++
+			continue
+		}
+		if i+1 >= len(s) || s[i+1] != '{' {
+			return "", fmt.Errorf("Bad variable reference in \"%s\", use ${NAME} or $$", s)
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("Unterminated variable reference in \"%s\"", s)
+		}
+		name := s[i+2 : i+end]
+		value, ok := variables[name]
+		if !ok {
+			value, ok = os.LookupEnv(name)
+		}
+		if !ok {
+			return "", fmt.Errorf("Variable \"%s\" used in \"%s\" is not defined", name, s)
+		}
+		result.WriteString(value)
+		i += end
+	}
+	return result.String(), nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+)
+
+// Logically separate NAT instance in its own config section. Port
+// pairs of instance are managed by its own control API server which
+// doesn't see port pairs of other instances, and it has its own
+// dumps and session export. All instances share DPDK initialization,
+// flow graph and memory pools of the process.
+type natInstance struct {
+	Name string `json:"name"`
+	// TCP address of control API server of instance
+	GRPCAddress string           `json:"grpc-address"`
+	ControlAPI  controlAPIConfig `json:"control-api"`
+	PortPairs   []portPair       `json:"port-pairs"`
+	// Pcap dumps enabled by control API of instance
+	dumpEnabled [DirKNI + 1]bool
+}
+
+// mergeInstances checks instances and appends their port pairs to
+// port pairs of config, so that they are handled like any other
+// port pair. Port pairs remember their instance and get its name as
+// tenant unless tenant is set.
+func (c *Config) mergeInstances() error {
+	names := map[string]bool{}
+	addresses := map[string]bool{
+		GRPCServerPort: true,
+	}
+	for i := range c.Instances {
+		inst := &c.Instances[i]
+		if inst.Name == "" {
+			return errors.New("NAT instance should have a name")
+		}
+		if names[inst.Name] {
+			return errors.New("Duplicate NAT instance name " + inst.Name)
+		}
+		names[inst.Name] = true
+		if inst.GRPCAddress == "" {
+			return errors.New("NAT instance " + inst.Name + " should have grpc-address")
+		}
+		if addresses[inst.GRPCAddress] {
+			return errors.New("Control API address " + inst.GRPCAddress + " of NAT instance " + inst.Name + " is already used")
+		}
+		addresses[inst.GRPCAddress] = true
+		if err := inst.ControlAPI.check(); err != nil {
+			return err
+		}
+		if len(inst.PortPairs) == 0 {
+			return errors.New("NAT instance " + inst.Name + " has no port pairs")
+		}
+
+		for j := range inst.PortPairs {
+			pp := &inst.PortPairs[j]
+			if pp.Tenant == "" {
+				pp.Tenant = inst.Name
+			}
+			pp.instance = inst
+		}
+		c.PortPairs = append(c.PortPairs, inst.PortPairs...)
+		// Port pairs are used only from config list
+		inst.PortPairs = nil
+	}
+	return nil
+}
+
+// dumpEnabled returns true if dump of direction is enabled for port
+// globally or by control API of its instance.
+func (port *ipPort) dumpEnabled(dir uint) bool {
+	return DumpEnabled[dir] || (port.instance != nil && port.instance.dumpEnabled[dir])
+}
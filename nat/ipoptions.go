@@ -0,0 +1,233 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// IPv6 next header value of hop-by-hop options header
+const ipv6HopByHopNumber = 0
+
+// Fate of IPv4 packets with options, e.g. record route or timestamp,
+// and of IPv6 jumbograms of RFC 2675.
+type ipOptionsAction int
+
+const (
+	// Packets are handled like packets without options
+	ipOptionsPass ipOptionsAction = iota
+	ipOptionsDrop
+	// Options are removed from IPv4 header before packet is handled
+	ipOptionsStrip
+)
+
+var ipOptionsActionLookup = map[string]ipOptionsAction{
+	"pass":  ipOptionsPass,
+	"drop":  ipOptionsDrop,
+	"strip": ipOptionsStrip,
+}
+
+// Port pair policy for packets which headers are longer than
+// fixed IP header. Jumbograms cannot be stripped because their length
+// is only in hop-by-hop option.
+type ipOptionsPolicy struct {
+	IPv4       ipOptionsAction `json:"ipv4"`
+	Jumbograms ipOptionsAction `json:"jumbograms"`
+}
+
+// Counters of packets with IPv4 options and IPv6 jumbograms received
+// by port.
+type ipOptionsCounters struct {
+	options           uint64
+	optionsStripped   uint64
+	optionsDropped    uint64
+	jumbograms        uint64
+	jumbogramsDropped uint64
+}
+
+// UnmarshalJSON parses IP options action.
+func (out *ipOptionsAction) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	result, ok := ipOptionsActionLookup[s]
+	if !ok {
+		return errors.New("Bad IP options action: " + s)
+	}
+
+	*out = result
+	return nil
+}
+
+// MarshalJSON writes action name as it is used in config file.
+func (action ipOptionsAction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(action.String())
+}
+
+// String returns action name as it is used in config file.
+func (action ipOptionsAction) String() string {
+	for name, a := range ipOptionsActionLookup {
+		if a == action {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+func (policy *ipOptionsPolicy) check() error {
+	if policy.Jumbograms == ipOptionsStrip {
+		return errors.New("IPv6 jumbograms cannot be stripped, ip-options jumbograms should be pass or drop")
+	}
+	return nil
+}
+
+// ipv4HeaderLen returns length of IPv4 header with options.
+func ipv4HeaderLen(pktIPv4 *packet.IPv4Hdr) int {
+	return int(pktIPv4.VersionIhl&0x0f) << 2
+}
+
+func hasIPv4Options(pktIPv4 *packet.IPv4Hdr) bool {
+	return ipv4HeaderLen(pktIPv4) > types.IPv4MinLen
+}
+
+// isIPv6Jumbogram returns true if IPv6 packet has zero payload length
+// and hop-by-hop options header, which carries jumbo payload length.
+func isIPv6Jumbogram(pktIPv6 *packet.IPv6Hdr) bool {
+	return pktIPv6.PayloadLen == 0 && pktIPv6.Proto == ipv6HopByHopNumber
+}
+
+// action returns fate of IPv6 jumbogram or of IPv4 packet with
+// options.
+func (policy *ipOptionsPolicy) action(ipv6 bool) ipOptionsAction {
+	if ipv6 {
+		return policy.Jumbograms
+	}
+	return policy.IPv4
+}
+
+// applyIPOptionsPolicy counts packets with IPv4 options and IPv6
+// jumbograms and applies port pair policy to them. It returns false
+// in third value if packet should be dropped. Stripped packet has new
+// headers which are returned instead of original ones.
+func (pp *portPair) applyIPOptionsPolicy(port *ipPort, pkt *packet.Packet, pktVLAN *packet.VLANHdr, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) (*packet.VLANHdr, *packet.IPv4Hdr, bool) {
+	c := &port.ipOptions
+	if pktIPv6 != nil {
+		if !isIPv6Jumbogram(pktIPv6) {
+			return pktVLAN, nil, true
+		}
+		atomic.AddUint64(&c.jumbograms, 1)
+		if pp.IPOptions.action(true) == ipOptionsDrop {
+			atomic.AddUint64(&c.jumbogramsDropped, 1)
+			port.dumpPacket(pkt, DirDROP)
+			return pktVLAN, nil, false
+		}
+		return pktVLAN, nil, true
+	}
+
+	if !hasIPv4Options(pktIPv4) {
+		return pktVLAN, pktIPv4, true
+	}
+	atomic.AddUint64(&c.options, 1)
+	switch pp.IPOptions.action(false) {
+	case ipOptionsDrop:
+		atomic.AddUint64(&c.optionsDropped, 1)
+		port.dumpPacket(pkt, DirDROP)
+		return pktVLAN, pktIPv4, false
+	case ipOptionsStrip:
+		if !stripIPv4Options(pkt, pktVLAN, pktIPv4) {
+			atomic.AddUint64(&c.optionsDropped, 1)
+			port.dumpPacket(pkt, DirDROP)
+			return pktVLAN, pktIPv4, false
+		}
+		atomic.AddUint64(&c.optionsStripped, 1)
+		pktVLAN = pkt.ParseL3CheckVLAN()
+		return pktVLAN, pkt.GetIPv4CheckVLAN(), true
+	}
+	return pktVLAN, pktIPv4, true
+}
+
+// stripIPv4Options removes options from IPv4 header of packet. Length
+// of transport segment doesn't change, so only IPv4 header checksum
+// is updated. It returns false if header is malformed.
+func stripIPv4Options(pkt *packet.Packet, pktVLAN *packet.VLANHdr, pktIPv4 *packet.IPv4Hdr) bool {
+	hdrLen := ipv4HeaderLen(pktIPv4)
+	totalLen := int(packet.SwapBytesUint16(pktIPv4.TotalLength))
+	if totalLen < hdrLen {
+		return false
+	}
+	optLen := hdrLen - types.IPv4MinLen
+	hc := packet.SwapBytesUint16(pktIPv4.HdrChecksum)
+	hc = updateChecksum(hc, uint16(pktIPv4.VersionIhl)<<8, uint16(0x40|types.IPv4MinLen>>2)<<8)
+	hc = updateChecksum(hc, uint16(totalLen), uint16(totalLen-optLen))
+	// Removed options don't contribute to checksum any more
+	opts := (*[60]byte)(unsafe.Pointer(uintptr(unsafe.Pointer(pktIPv4)) + types.IPv4MinLen))[:optLen]
+	hc = updateChecksum(hc, ^internetChecksum(opts), 0)
+	pktIPv4.VersionIhl = 0x40 | types.IPv4MinLen>>2
+	pktIPv4.TotalLength = packet.SwapBytesUint16(uint16(totalLen - optLen))
+	pktIPv4.HdrChecksum = packet.SwapBytesUint16(hc)
+
+	l3offset := uint(types.EtherLen)
+	if pktVLAN != nil {
+		l3offset += types.VLANLen
+	}
+	return pkt.DecapsulateHead(l3offset+types.IPv4MinLen, uint(optLen))
+}
+
+// ipv4OptionsChecksums calculates IPv4 header checksum and transport
+// checksum at offset in transport header of packet with IPv4 options.
+// NFF-Go functions assume fixed IPv4 header, so they cover neither
+// options in header checksum nor correct segment length. ICMP
+// checksum has no pseudo header. Negative offset means that only
+// header checksum is calculated.
+func ipv4OptionsChecksums(l3 *packet.IPv4Hdr, l4 unsafe.Pointer, offset int, pseudoHdr bool) {
+	hdrLen := ipv4HeaderLen(l3)
+	l3.HdrChecksum = 0
+	hdr := (*[60]byte)(unsafe.Pointer(l3))[:hdrLen]
+	l3.HdrChecksum = packet.SwapBytesUint16(internetChecksum(hdr))
+	if offset < 0 {
+		return
+	}
+
+	segmentLen := int(packet.SwapBytesUint16(l3.TotalLength)) - hdrLen
+	if segmentLen < offset+2 {
+		return
+	}
+	segment := (*[1 << 16]byte)(l4)[:segmentLen]
+	cksum := (*uint16)(unsafe.Pointer(uintptr(l4) + uintptr(offset)))
+	*cksum = 0
+	c := internetChecksum(segment)
+	if pseudoHdr {
+		src := packet.SwapBytesIPv4Addr(l3.SrcAddr)
+		dst := packet.SwapBytesIPv4Addr(l3.DstAddr)
+		// Pseudo header words are added to zero words of
+		// checksummed data
+		c = updateChecksum(c, 0, uint16(src>>16))
+		c = updateChecksum(c, 0, uint16(src))
+		c = updateChecksum(c, 0, uint16(dst>>16))
+		c = updateChecksum(c, 0, uint16(dst))
+		c = updateChecksum(c, 0, uint16(l3.NextProtoID))
+		c = updateChecksum(c, 0, uint16(segmentLen))
+	}
+	if c == 0 && l3.NextProtoID == types.UDPNumber {
+		c = 0xffff
+	}
+	*cksum = packet.SwapBytesUint16(c)
+}
+
+// ipOptionsCounters returns numbers of packets with IPv4 options,
+// stripped and dropped ones, IPv6 jumbograms and dropped ones.
+func (port *ipPort) ipOptionsCounters() (uint64, uint64, uint64, uint64, uint64) {
+	c := &port.ipOptions
+	return atomic.LoadUint64(&c.options), atomic.LoadUint64(&c.optionsStripped), atomic.LoadUint64(&c.optionsDropped),
+		atomic.LoadUint64(&c.jumbograms), atomic.LoadUint64(&c.jumbogramsDropped)
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/intel-go/nff-go/flow"
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// Forwarded ports of public port which are sent to explicit address
+// and port of KNI interface, so that services of host may listen on
+// other ports than forwarded ones. Destination of packets sent to KNI
+// is rewritten, and source of replies which KNI interface sends is
+// rewritten back.
+type kniForwarding struct {
+	// Forwarded port key to KNI key and KNI key to forwarded port
+	// key, TCP and UDP tables
+	toKNI   [2]sync.Map
+	fromKNI [2]sync.Map
+	// Number of forwarded ports, replies are not parsed when there
+	// are none
+	count int32
+}
+
+func kniForwardingIndex(protocol uint8) int {
+	if protocol == types.UDPNumber {
+		return 1
+	}
+	return 0
+}
+
+// rewritesKNI returns true if forwarded port is sent to explicit
+// address of KNI interface.
+func (fp *forwardedPort) rewritesKNI() bool {
+	if !fp.KNI {
+		return false
+	}
+	if fp.Destination.ipv6 {
+		return fp.Destination.Addr6 != zeroIPv6Addr
+	}
+	return fp.Destination.Addr4 != 0
+}
+
+// enableKNIForward starts rewriting packets of forwarded port key to
+// KNI key and replies back.
+func (port *ipPort) enableKNIForward(protocol uint8, key, kniKey interface{}) {
+	port.deleteKNIForward(protocol, key)
+	i := kniForwardingIndex(protocol)
+	port.kniForwards.toKNI[i].Store(key, kniKey)
+	port.kniForwards.fromKNI[i].Store(kniKey, key)
+	atomic.AddInt32(&port.kniForwards.count, 1)
+}
+
+// deleteKNIForward stops rewriting packets of forwarded port key.
+func (port *ipPort) deleteKNIForward(protocol uint8, key interface{}) {
+	i := kniForwardingIndex(protocol)
+	kniKey, found := port.kniForwards.toKNI[i].Load(key)
+	if !found {
+		return
+	}
+	port.kniForwards.toKNI[i].Delete(key)
+	port.kniForwards.fromKNI[i].Delete(kniKey)
+	atomic.AddInt32(&port.kniForwards.count, -1)
+}
+
+// rewriteToKNI sets destination of packet of forwarded port to address
+// and port of KNI interface if port is forwarded to explicit address.
+// Kernel checks checksums of packets it receives from KNI, so they are
+// always updated in software.
+func (port *ipPort) rewriteToKNI(pkt *packet.Packet, protocol uint8, key interface{}, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr,
+	pktTCP *packet.TCPHdr, pktUDP *packet.UDPHdr) {
+	if atomic.LoadInt32(&port.kniForwards.count) == 0 || (pktTCP == nil && pktUDP == nil) {
+		return
+	}
+	v, found := port.kniForwards.toKNI[kniForwardingIndex(protocol)].Load(key)
+	if !found {
+		return
+	}
+	old := saveTranslatedHeader(pktIPv4, pktIPv6)
+	v4addr, v6addr, newPort, _ := getAddrFromTuple(v, pktIPv6 != nil)
+	if pktIPv6 != nil {
+		pktIPv6.DstAddr = v6addr
+	} else {
+		pktIPv4.DstAddr = packet.SwapBytesIPv4Addr(v4addr)
+	}
+	setPacketDstPort(pkt, pktIPv6 != nil, newPort, pktTCP, pktUDP, nil, nil, &old)
+}
+
+// rewriteFromKNI sets source of reply sent by KNI interface from
+// address and port of forwarded port back to forwarded port.
+func (port *ipPort) rewriteFromKNI(pkt *packet.Packet) {
+	if atomic.LoadInt32(&port.kniForwards.count) == 0 {
+		return
+	}
+	pktIPv4, pktIPv6, _ := pkt.ParseAllKnownL3CheckVLAN()
+	if pktIPv4 == nil && pktIPv6 == nil {
+		return
+	}
+	if pktIPv4 != nil && isIPv4LaterFragment(pktIPv4) {
+		return
+	}
+	protocol, pktTCP, pktUDP, _, srcPort, _ := ParseAllKnownL4(pkt, pktIPv4, pktIPv6)
+	if pktTCP == nil && pktUDP == nil {
+		return
+	}
+	ipv6 := pktIPv6 != nil
+	var kniKey interface{}
+	if ipv6 {
+		kniKey = Tuple6{addr: pktIPv6.SrcAddr, port: srcPort}
+	} else {
+		kniKey = Tuple{addr: packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr), port: srcPort}
+	}
+	v, found := port.kniForwards.fromKNI[kniForwardingIndex(protocol)].Load(kniKey)
+	if !found {
+		return
+	}
+	old := saveTranslatedHeader(pktIPv4, pktIPv6)
+	v4addr, v6addr, newPort, _ := getAddrFromTuple(v, ipv6)
+	if ipv6 {
+		pktIPv6.SrcAddr = v6addr
+	} else {
+		pktIPv4.SrcAddr = packet.SwapBytesIPv4Addr(v4addr)
+	}
+	setPacketSrcPort(pkt, ipv6, newPort, pktTCP, pktUDP, nil, nil, &old)
+}
+
+func publicKNIOutput(pkt *packet.Packet, ctx flow.UserContext) {
+	Natconfig.PortPairs[ctx.(pairIndex).index].PublicPort.rewriteFromKNI(pkt)
+}
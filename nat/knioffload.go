@@ -0,0 +1,423 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/flow"
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+	"github.com/vishvananda/netlink"
+)
+
+const (
+	// NFF-Go creates KNI devices with 2048 byte buffers, so frames of
+	// KNI interface including VLAN tag should fit them
+	maxKNIOffloadMTU = 2048 - types.EtherLen - types.VLANLen
+	// MTU of port when it is not configured
+	defaultKNIPortMTU = 1500
+)
+
+// Segmentation offloads of KNI interface. Linux sends TCP segments up
+// to MTU of KNI interface which NAT splits to MTU of port (GSO), and
+// consecutive TCP segments of a flow which NAT sends to KNI interface
+// are coalesced up to MTU of KNI interface (GRO). Zero MTU disables
+// offloads.
+type kniOffloadConfig struct {
+	// MTU of KNI interface which NAT sets in Linux
+	MTU int `json:"mtu"`
+	// MTU of network card port, zero means 1500
+	PortMTU int `json:"port-mtu"`
+	// Coalesce TCP segments sent to KNI interface
+	GRO bool `json:"gro"`
+}
+
+// Packets of KNI interface which went through offloads.
+type kniOffloadCounters struct {
+	// Packets from KNI interface which were segmented and their
+	// segments
+	segmented uint64
+	segments  uint64
+	// Packets from KNI interface larger than port MTU which cannot be
+	// segmented
+	oversized uint64
+	// Packets from KNI interface which segments didn't fit queue
+	dropped uint64
+	// Segments sent to KNI interface which were coalesced with
+	// previous segment
+	coalesced uint64
+}
+
+func (cfg *kniOffloadConfig) enabled() bool {
+	return cfg.MTU != 0
+}
+
+func (cfg *kniOffloadConfig) portMTU() int {
+	if cfg.PortMTU == 0 {
+		return defaultKNIPortMTU
+	}
+	return cfg.PortMTU
+}
+
+func (cfg *kniOffloadConfig) check(port *ipPort) error {
+	if !cfg.enabled() {
+		if cfg.PortMTU != 0 || cfg.GRO {
+			return fmt.Errorf("KNI offloads of port %d require mtu", port.Index)
+		}
+		return nil
+	}
+	if port.KNIName == "" {
+		return fmt.Errorf("KNI offloads of port %d require KNI interface", port.Index)
+	}
+	if cfg.portMTU() < minPathMTU || cfg.MTU <= cfg.portMTU() || cfg.MTU > maxKNIOffloadMTU {
+		return fmt.Errorf("KNI MTU of port %d should be larger than port MTU %d and at most %d, port MTU at least %d",
+			port.Index, cfg.portMTU(), maxKNIOffloadMTU, minPathMTU)
+	}
+	return nil
+}
+
+// setKNIMTU sets MTU of KNI interface in Linux.
+func (port *ipPort) setKNIMTU() error {
+	link, err := netlink.LinkByName(port.KNIName)
+	if err != nil {
+		return fmt.Errorf("Failed to find KNI interface %s: %v", port.KNIName, err)
+	}
+	if err := netlink.LinkSetMTU(link, port.KNIOffload.MTU); err != nil {
+		return fmt.Errorf("Failed to set MTU %d of KNI interface %s: %v", port.KNIOffload.MTU, port.KNIName, err)
+	}
+	return nil
+}
+
+func (port *ipPort) kniOffloadCounters() kniOffloadCounters {
+	c := &port.kniOffload
+	return kniOffloadCounters{
+		segmented: atomic.LoadUint64(&c.segmented),
+		segments:  atomic.LoadUint64(&c.segments),
+		oversized: atomic.LoadUint64(&c.oversized),
+		dropped:   atomic.LoadUint64(&c.dropped),
+		coalesced: atomic.LoadUint64(&c.coalesced),
+	}
+}
+
+// Offsets of headers of TCP segment in Ethernet frame.
+type tcpFrame struct {
+	ipv6 bool
+	// IP header, TCP header and payload
+	l3, l4, data int
+}
+
+// parseTCPFrame locates headers of TCP segment in frame which IP
+// header starts at l3offset. Only segments in IPv4 packets without
+// options which are not fragments and in IPv6 packets without extension
+// headers are parsed. Frame should have no padding after IP packet.
+func parseTCPFrame(frame []byte, l3offset int) (tcpFrame, bool) {
+	f := tcpFrame{l3: l3offset}
+	if len(frame) < l3offset+types.IPv6Len {
+		return f, false
+	}
+	ip := frame[l3offset:]
+	switch ip[0] >> 4 {
+	case 4:
+		if ip[0]&0x0f != types.IPv4MinLen>>2 || ip[9] != types.TCPNumber ||
+			binary.BigEndian.Uint16(ip[6:])&(ipv4MoreFragments|ipv4FragmentOffset) != 0 ||
+			int(binary.BigEndian.Uint16(ip[2:])) != len(ip) {
+			return f, false
+		}
+		f.l4 = l3offset + types.IPv4MinLen
+	case 6:
+		if ip[6] != types.TCPNumber || int(binary.BigEndian.Uint16(ip[4:]))+types.IPv6Len != len(ip) {
+			return f, false
+		}
+		f.ipv6 = true
+		f.l4 = l3offset + types.IPv6Len
+	default:
+		return f, false
+	}
+	if len(frame) < f.l4+types.TCPMinLen {
+		return f, false
+	}
+	f.data = f.l4 + int(frame[f.l4+12]>>4)*4
+	return f, f.data >= f.l4+types.TCPMinLen && f.data <= len(frame)
+}
+
+// checksum returns TCP checksum of segment in frame, it is zero when
+// checksum field of segment is correct.
+func (f *tcpFrame) checksum(frame []byte) uint16 {
+	segmentLen := len(frame) - f.l4
+	var pseudo []byte
+	if f.ipv6 {
+		pseudo = make([]byte, 40)
+		copy(pseudo, frame[f.l3+8:f.l3+types.IPv6Len])
+		binary.BigEndian.PutUint32(pseudo[32:], uint32(segmentLen))
+		pseudo[39] = types.TCPNumber
+	} else {
+		pseudo = make([]byte, 12)
+		copy(pseudo, frame[f.l3+12:f.l3+types.IPv4MinLen])
+		pseudo[9] = types.TCPNumber
+		binary.BigEndian.PutUint16(pseudo[10:], uint16(segmentLen))
+	}
+	sum := uint32(^internetChecksum(pseudo)) + uint32(^internetChecksum(frame[f.l4:]))
+	sum = sum&0xffff + sum>>16
+	return ^uint16(sum)
+}
+
+// setLength sets IP length of frame and calculates IPv4 header
+// checksum and TCP checksum.
+func (f *tcpFrame) setLength(frame []byte) {
+	ip := frame[f.l3:]
+	if f.ipv6 {
+		binary.BigEndian.PutUint16(ip[4:], uint16(len(ip)-types.IPv6Len))
+	} else {
+		binary.BigEndian.PutUint16(ip[2:], uint16(len(ip)))
+		binary.BigEndian.PutUint16(ip[10:], 0)
+		binary.BigEndian.PutUint16(ip[10:], internetChecksum(ip[:types.IPv4MinLen]))
+	}
+	binary.BigEndian.PutUint16(frame[f.l4+16:], 0)
+	binary.BigEndian.PutUint16(frame[f.l4+16:], f.checksum(frame))
+}
+
+// segmentTCPFrame splits TCP segment in frame into frames which IP
+// packets fit mtu. Every segment repeats headers of frame with its own
+// sequence number, IPv4 identification and lengths, FIN and PSH flags
+// stay in last segment and CWR flag in first one. It returns nil if
+// frame is not TCP segment with data which can be split.
+func segmentTCPFrame(frame []byte, l3offset, mtu int) [][]byte {
+	f, ok := parseTCPFrame(frame, l3offset)
+	if !ok {
+		return nil
+	}
+	flags := frame[f.l4+13]
+	mss := mtu - (f.data - f.l3)
+	payload := frame[f.data:]
+	if mss <= 0 || len(payload) <= mss || flags&(types.TCPFlagSyn|types.TCPFlagRst) != 0 {
+		return nil
+	}
+	seq := binary.BigEndian.Uint32(frame[f.l4+4:])
+	id := binary.BigEndian.Uint16(frame[f.l3+4:])
+	segments := make([][]byte, 0, (len(payload)+mss-1)/mss)
+	for offset := 0; offset < len(payload); offset += mss {
+		end := offset + mss
+		if end > len(payload) {
+			end = len(payload)
+		}
+		segment := make([]byte, f.data+end-offset)
+		copy(segment, frame[:f.data])
+		copy(segment[f.data:], payload[offset:end])
+		if !f.ipv6 {
+			binary.BigEndian.PutUint16(segment[f.l3+4:], id+uint16(len(segments)))
+		}
+		binary.BigEndian.PutUint32(segment[f.l4+4:], seq+uint32(offset))
+		segmentFlags := flags
+		if end != len(payload) {
+			segmentFlags &^= types.TCPFlagFin | types.TCPFlagPsh
+		}
+		if offset != 0 {
+			segmentFlags &^= types.TCPFlagCwr
+		}
+		segment[f.l4+13] = segmentFlags
+		f.setLength(segment)
+		segments = append(segments, segment)
+	}
+	return segments
+}
+
+// mergeTCPFrames returns frame of TCP segment in first followed by
+// data of next segment of the same flow, or nil if segments cannot be
+// coalesced into IP packet which fits mtu. Segments are coalesced when
+// they have correct checksums, the same IP and TCP headers except
+// lengths, IPv4 identification, sequence number and checksums, carry
+// data in order and only first segment has ACK flag without others.
+// PSH flag of next segment is kept.
+func mergeTCPFrames(first, next []byte, l3offset, mtu int) []byte {
+	a, ok := parseTCPFrame(first, l3offset)
+	if !ok {
+		return nil
+	}
+	b, ok := parseTCPFrame(next, l3offset)
+	if !ok || a.ipv6 != b.ipv6 || a.data != b.data {
+		return nil
+	}
+	firstLen, nextLen := len(first)-a.data, len(next)-b.data
+	if firstLen == 0 || nextLen == 0 || len(first)-a.l3+nextLen > mtu {
+		return nil
+	}
+	// Version, traffic class, flow label, hop limit and addresses of
+	// IPv6 or type of service, DF flag, TTL, protocol and addresses
+	// of IPv4 should match
+	var same [][2]int
+	if a.ipv6 {
+		same = [][2]int{{0, 4}, {6, types.IPv6Len}}
+	} else {
+		same = [][2]int{{0, 2}, {6, 10}, {12, types.IPv4MinLen}}
+	}
+	// Ports, acknowledgement, header length, window, urgent pointer
+	// and options of TCP should match
+	same = append(same, [2]int{a.l4 - a.l3, a.l4 - a.l3 + 4}, [2]int{a.l4 - a.l3 + 8, a.l4 - a.l3 + 13},
+		[2]int{a.l4 - a.l3 + 14, a.l4 - a.l3 + 16}, [2]int{a.l4 - a.l3 + 18, a.data - a.l3})
+	for _, r := range same {
+		if string(first[a.l3+r[0]:a.l3+r[1]]) != string(next[b.l3+r[0]:b.l3+r[1]]) {
+			return nil
+		}
+	}
+	nextFlags := next[b.l4+13]
+	if first[a.l4+13] != types.TCPFlagAck || nextFlags&^types.TCPFlagPsh != types.TCPFlagAck {
+		return nil
+	}
+	if binary.BigEndian.Uint32(first[a.l4+4:])+uint32(firstLen) != binary.BigEndian.Uint32(next[b.l4+4:]) {
+		return nil
+	}
+	if a.checksum(first) != 0 || b.checksum(next) != 0 {
+		return nil
+	}
+	merged := make([]byte, len(first)+nextLen)
+	copy(merged, first)
+	copy(merged[len(first):], next[b.data:])
+	merged[a.l4+13] = nextFlags
+	a.setLength(merged)
+	return merged
+}
+
+func frameL3Offset(pkt *packet.Packet) int {
+	if pkt.Ether.EtherType == types.SwapVLANNumber {
+		return types.EtherLen + types.VLANLen
+	}
+	return types.EtherLen
+}
+
+// Segments of packets from KNI interface which wait for their
+// generator, segments which don't fit are dropped.
+const kniSegmentQueueLength = 4096
+
+// Segmentation of flow of packets from KNI interface of port. Handler
+// of flow queues segments of large TCP packets and generator puts them
+// back into flow, so segments pass the same egress scheduling,
+// policing and sender as other packets of flow.
+type kniSegmenter struct {
+	port     *ipPort
+	segments chan []byte
+}
+
+func (s *kniSegmenter) Copy() interface{} {
+	return s
+}
+
+func (s *kniSegmenter) Delete() {
+}
+
+// setKNISegmentation adds segmentation of packets from KNI interface
+// of port to flow and returns flow merged with their segments.
+func (port *ipPort) setKNISegmentation(f *flow.Flow) (*flow.Flow, error) {
+	s := &kniSegmenter{
+		port:     port,
+		segments: make(chan []byte, kniSegmentQueueLength),
+	}
+	if err := flow.SetHandlerDrop(f, kniSegmentation, s); err != nil {
+		return nil, err
+	}
+	return flow.SetMerger(f, flow.SetGenerator(kniSegmentGenerator, s))
+}
+
+// segment queues TCP segment from KNI interface which is larger than
+// port MTU as segments which fit it. Other packets which don't fit
+// port MTU are dropped. It returns false if packet is replaced by its
+// segments or dropped.
+func (s *kniSegmenter) segment(pkt *packet.Packet) bool {
+	port := s.port
+	mtu := port.KNIOffload.portMTU()
+	l3offset := frameL3Offset(pkt)
+	if int(pkt.GetPacketLen())-l3offset <= mtu {
+		return true
+	}
+	segments := segmentTCPFrame(pkt.GetRawPacketBytes(), l3offset, mtu)
+	if segments == nil {
+		atomic.AddUint64(&port.kniOffload.oversized, 1)
+		port.dumpPacket(pkt, DirDROP)
+		return false
+	}
+	// Packet is dropped rather than its segments partly queued when
+	// generator falls behind
+	if len(s.segments)+len(segments) > cap(s.segments) {
+		atomic.AddUint64(&port.kniOffload.dropped, 1)
+		port.dumpPacket(pkt, DirDROP)
+		return false
+	}
+	for _, segment := range segments {
+		// Another clone of handler may fill queue in the meantime
+		select {
+		case s.segments <- segment:
+		default:
+			atomic.AddUint64(&port.kniOffload.dropped, 1)
+			return false
+		}
+	}
+	atomic.AddUint64(&port.kniOffload.segmented, 1)
+	atomic.AddUint64(&port.kniOffload.segments, uint64(len(segments)))
+	return false
+}
+
+func kniSegmentation(pkt *packet.Packet, ctx flow.UserContext) bool {
+	return ctx.(*kniSegmenter).segment(pkt)
+}
+
+// kniSegmentGenerator fills packet which generator allocated with next
+// queued segment, it waits until there is one.
+func kniSegmentGenerator(pkt *packet.Packet, ctx flow.UserContext) {
+	packet.GeneratePacketFromByte(pkt, <-ctx.(*kniSegmenter).segments)
+}
+
+// coalesceToKNI coalesces consecutive TCP segments of a flow in burst
+// which is sent to KNI interface of port. Segments which are appended
+// to previous segment are dropped.
+func (port *ipPort) coalesceToKNI(pkts []*packet.Packet, mask *[vectorBurstSize]bool, answers *[vectorBurstSize]bool) {
+	mtu := port.KNIOffload.MTU
+	last := -1
+	var lastFrame []byte
+	var lastOffset int
+	var lastChanged bool
+	flush := func() {
+		if lastChanged {
+			pkt := pkts[last]
+			length := pkt.GetPacketLen()
+			if !pkt.EncapsulateTail(length, uint(len(lastFrame))-length) || !pkt.PacketBytesChange(0, lastFrame) {
+				common.LogWarning(common.No, "Failed to coalesce TCP segments sent to KNI interface", port.KNIName)
+			}
+		}
+		lastChanged = false
+	}
+	for i := range pkts {
+		if !mask[i] {
+			continue
+		}
+		answers[i] = true
+		pkt := pkts[i]
+		l3offset := frameL3Offset(pkt)
+		frame := pkt.GetRawPacketBytes()
+		if last >= 0 && l3offset == lastOffset {
+			if merged := mergeTCPFrames(lastFrame, frame, l3offset, mtu); merged != nil {
+				lastFrame, lastChanged = merged, true
+				answers[i] = false
+				atomic.AddUint64(&port.kniOffload.coalesced, 1)
+				continue
+			}
+		}
+		flush()
+		last, lastFrame, lastOffset = i, frame, l3offset
+	}
+	if last >= 0 {
+		flush()
+	}
+}
+
+func publicKNICoalescing(pkts []*packet.Packet, mask *[vectorBurstSize]bool, answers *[vectorBurstSize]bool, ctx flow.UserContext) {
+	Natconfig.PortPairs[ctx.(pairIndex).index].PublicPort.coalesceToKNI(pkts, mask, answers)
+}
+
+func privateKNICoalescing(pkts []*packet.Packet, mask *[vectorBurstSize]bool, answers *[vectorBurstSize]bool, ctx flow.UserContext) {
+	Natconfig.PortPairs[ctx.(pairIndex).index].PrivatePort.coalesceToKNI(pkts, mask, answers)
+}
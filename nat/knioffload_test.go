@@ -0,0 +1,226 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+// testTCPFrame returns Ethernet frame of TCP segment with correct
+// checksums.
+func testTCPFrame(ipv6 bool, seq uint32, flags uint8, options []byte, dataLen int) []byte {
+	l3 := types.EtherLen
+	l4 := l3 + types.IPv4MinLen
+	if ipv6 {
+		l4 = l3 + types.IPv6Len
+	}
+	frame := make([]byte, l4+types.TCPMinLen+len(options)+dataLen)
+	ip := frame[l3:]
+	if ipv6 {
+		binary.BigEndian.PutUint16(frame[12:], types.IPV6Number)
+		ip[0] = 0x60
+		ip[6] = types.TCPNumber
+		ip[7] = 64
+		copy(ip[8:], testPrivate6)
+		copy(ip[24:], []byte{0x20, 0x01, 0x0d, 0xb8, 15: 1})
+	} else {
+		binary.BigEndian.PutUint16(frame[12:], types.IPV4Number)
+		ip[0] = 0x45
+		binary.BigEndian.PutUint16(ip[4:], 100)
+		binary.BigEndian.PutUint16(ip[6:], ipv4DontFragment)
+		ip[8] = 64
+		ip[9] = types.TCPNumber
+		copy(ip[12:], testPrivate4)
+		copy(ip[16:], testRemote4)
+	}
+	tcp := frame[l4:]
+	binary.BigEndian.PutUint16(tcp[0:], 40000)
+	binary.BigEndian.PutUint16(tcp[2:], 443)
+	binary.BigEndian.PutUint32(tcp[4:], seq)
+	binary.BigEndian.PutUint32(tcp[8:], 7777)
+	tcp[12] = byte((types.TCPMinLen+len(options))/4) << 4
+	tcp[13] = flags
+	binary.BigEndian.PutUint16(tcp[14:], 512)
+	copy(tcp[types.TCPMinLen:], options)
+	for i := range frame[l4+types.TCPMinLen+len(options):] {
+		frame[l4+types.TCPMinLen+len(options)+i] = byte(seq + uint32(i))
+	}
+	f := tcpFrame{ipv6: ipv6, l3: l3, l4: l4, data: l4 + types.TCPMinLen + len(options)}
+	f.setLength(frame)
+	return frame
+}
+
+func TestParseTCPFrame(t *testing.T) {
+	padded := append(testTCPFrame(false, 1, types.TCPFlagAck, nil, 0), 0, 0, 0, 0, 0, 0)
+	fragment := testTCPFrame(false, 1, types.TCPFlagAck, nil, 100)
+	binary.BigEndian.PutUint16(fragment[types.EtherLen+6:], ipv4MoreFragments)
+	udp := testTCPFrame(false, 1, types.TCPFlagAck, nil, 100)
+	udp[types.EtherLen+9] = types.UDPNumber
+	tests := []struct {
+		name  string
+		frame []byte
+		ok    bool
+		data  int
+	}{
+		{"IPv4 segment", testTCPFrame(false, 1, types.TCPFlagAck, nil, 100), true, 54},
+		{"IPv4 segment with options", testTCPFrame(false, 1, types.TCPFlagAck, []byte{1, 1, 8, 10, 0, 0, 0, 1, 0, 0, 0, 2}, 100), true, 66},
+		{"IPv6 segment", testTCPFrame(true, 1, types.TCPFlagAck, nil, 100), true, 74},
+		{"padded frame", padded, false, 0},
+		{"fragment", fragment, false, 0},
+		{"UDP datagram", udp, false, 0},
+		{"truncated frame", make([]byte, 20), false, 0},
+	}
+	for _, tt := range tests {
+		f, ok := parseTCPFrame(tt.frame, types.EtherLen)
+		if ok != tt.ok || (ok && f.data != tt.data) {
+			t.Errorf("%s: frame is parsed %v with data at %d, expected %v with data at %d", tt.name, ok, f.data, tt.ok, tt.data)
+		}
+	}
+}
+
+func TestSegmentTCPFrame(t *testing.T) {
+	timestamps := []byte{1, 1, 8, 10, 0, 0, 0, 1, 0, 0, 0, 2}
+	tests := []struct {
+		name     string
+		ipv6     bool
+		flags    uint8
+		options  []byte
+		dataLen  int
+		segments []int
+	}{
+		{"IPv4 segment", false, types.TCPFlagAck | types.TCPFlagPsh, nil, 3000, []int{1460, 1460, 80}},
+		{"IPv4 segment with timestamps", false, types.TCPFlagAck | types.TCPFlagFin, timestamps, 2000, []int{1448, 552}},
+		{"IPv6 segment", true, types.TCPFlagAck | types.TCPFlagCwr, nil, 2000, []int{1440, 560}},
+		{"segment which fits", false, types.TCPFlagAck, nil, 1460, nil},
+		{"SYN segment", false, types.TCPFlagSyn, nil, 2000, nil},
+	}
+	for _, tt := range tests {
+		frame := testTCPFrame(tt.ipv6, 1000, tt.flags, tt.options, tt.dataLen)
+		orig, _ := parseTCPFrame(frame, types.EtherLen)
+		segments := segmentTCPFrame(frame, types.EtherLen, 1500)
+		if len(segments) != len(tt.segments) {
+			t.Errorf("%s: %d segments, expected %d", tt.name, len(segments), len(tt.segments))
+			continue
+		}
+		if segments == nil {
+			continue
+		}
+		var data []byte
+		for i, segment := range segments {
+			f, ok := parseTCPFrame(segment, types.EtherLen)
+			if !ok {
+				t.Errorf("%s: segment %d is not parsed", tt.name, i)
+				continue
+			}
+			if len(segment)-f.l3 > 1500 || len(segment)-f.data != tt.segments[i] {
+				t.Errorf("%s: segment %d has %d bytes of data in %d bytes, expected %d", tt.name, i,
+					len(segment)-f.data, len(segment)-f.l3, tt.segments[i])
+			}
+			if f.checksum(segment) != 0 || (!f.ipv6 && internetChecksum(segment[f.l3:f.l4]) != 0) {
+				t.Errorf("%s: segment %d has bad checksum", tt.name, i)
+			}
+			if seq := binary.BigEndian.Uint32(segment[f.l4+4:]); seq != 1000+uint32(len(data)) {
+				t.Errorf("%s: segment %d has sequence number %d, expected %d", tt.name, i, seq, 1000+len(data))
+			}
+			if !f.ipv6 {
+				if id := binary.BigEndian.Uint16(segment[f.l3+4:]); id != 100+uint16(i) {
+					t.Errorf("%s: segment %d has identification %d, expected %d", tt.name, i, id, 100+i)
+				}
+			}
+			flags := tt.flags
+			if i != len(segments)-1 {
+				flags &^= types.TCPFlagFin | types.TCPFlagPsh
+			}
+			if i != 0 {
+				flags &^= types.TCPFlagCwr
+			}
+			if segment[f.l4+13] != flags {
+				t.Errorf("%s: segment %d has flags %#x, expected %#x", tt.name, i, segment[f.l4+13], flags)
+			}
+			data = append(data, segment[f.data:]...)
+		}
+		if string(data) != string(frame[orig.data:]) {
+			t.Errorf("%s: segments don't carry data of packet", tt.name)
+		}
+	}
+}
+
+func TestMergeTCPFrames(t *testing.T) {
+	first := testTCPFrame(false, 1000, types.TCPFlagAck, nil, 800)
+	tests := []struct {
+		name   string
+		first  []byte
+		next   []byte
+		merged bool
+	}{
+		{"next segment", first, testTCPFrame(false, 1800, types.TCPFlagAck|types.TCPFlagPsh, nil, 800), true},
+		{"next IPv6 segment", testTCPFrame(true, 1000, types.TCPFlagAck, nil, 800),
+			testTCPFrame(true, 1800, types.TCPFlagAck, nil, 800), true},
+		{"segment out of order", first, testTCPFrame(false, 1900, types.TCPFlagAck, nil, 800), false},
+		{"too large packet", first, testTCPFrame(false, 1800, types.TCPFlagAck, nil, 1300), false},
+		{"first segment with PSH", testTCPFrame(false, 1000, types.TCPFlagAck|types.TCPFlagPsh, nil, 800),
+			testTCPFrame(false, 1800, types.TCPFlagAck, nil, 800), false},
+		{"FIN segment", first, testTCPFrame(false, 1800, types.TCPFlagAck|types.TCPFlagFin, nil, 800), false},
+		{"other options", first, testTCPFrame(false, 1800, types.TCPFlagAck, []byte{1, 1, 1, 1}, 800), false},
+		{"other family", first, testTCPFrame(true, 1800, types.TCPFlagAck, nil, 800), false},
+		{"segment without data", first, testTCPFrame(false, 1800, types.TCPFlagAck, nil, 0), false},
+	}
+	for _, tt := range tests {
+		merged := mergeTCPFrames(tt.first, tt.next, types.EtherLen, 2000)
+		if (merged != nil) != tt.merged {
+			t.Errorf("%s: segments are merged %v, expected %v", tt.name, merged != nil, tt.merged)
+			continue
+		}
+		if merged == nil {
+			continue
+		}
+		f, ok := parseTCPFrame(merged, types.EtherLen)
+		a, _ := parseTCPFrame(tt.first, types.EtherLen)
+		b, _ := parseTCPFrame(tt.next, types.EtherLen)
+		if !ok || f.checksum(merged) != 0 || (!f.ipv6 && internetChecksum(merged[f.l3:f.l4]) != 0) {
+			t.Errorf("%s: merged segment is malformed", tt.name)
+			continue
+		}
+		if string(merged[f.data:]) != string(tt.first[a.data:])+string(tt.next[b.data:]) {
+			t.Errorf("%s: merged segment doesn't carry data of both segments", tt.name)
+		}
+		if merged[f.l4+13] != tt.next[b.l4+13] {
+			t.Errorf("%s: merged segment has flags %#x, expected %#x", tt.name, merged[f.l4+13], tt.next[b.l4+13])
+		}
+	}
+
+	corrupted := testTCPFrame(false, 1800, types.TCPFlagAck, nil, 800)
+	corrupted[len(corrupted)-1]++
+	if mergeTCPFrames(first, corrupted, types.EtherLen, 2000) != nil {
+		t.Errorf("Segment with bad checksum is merged")
+	}
+}
+
+func TestKNIOffloadCheck(t *testing.T) {
+	kni := &ipPort{KNIName: "pub-kni"}
+	tests := []struct {
+		name  string
+		port  *ipPort
+		cfg   kniOffloadConfig
+		valid bool
+	}{
+		{"disabled", &ipPort{}, kniOffloadConfig{}, true},
+		{"KNI MTU", kni, kniOffloadConfig{MTU: 2000, GRO: true}, true},
+		{"KNI MTU and port MTU", kni, kniOffloadConfig{MTU: 2000, PortMTU: 1492}, true},
+		{"without KNI interface", &ipPort{}, kniOffloadConfig{MTU: 2000}, false},
+		{"GRO without MTU", kni, kniOffloadConfig{GRO: true}, false},
+		{"KNI MTU smaller than port MTU", kni, kniOffloadConfig{MTU: 1400}, false},
+		{"KNI MTU larger than buffers", kni, kniOffloadConfig{MTU: 9000}, false},
+		{"too small port MTU", kni, kniOffloadConfig{MTU: 2000, PortMTU: 1000}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.check(tt.port); (err == nil) != tt.valid {
+			t.Errorf("%s: error is %v, expected valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+const (
+	// Routes of KNI interfaces are updated this often
+	kniRoutesInterval = time.Second
+	// Metric of default routes via KNI interfaces. It is higher than
+	// metrics which are usually used for management interfaces of
+	// host, so their default routes are preferred.
+	kniDefaultRouteMetric = 1000
+)
+
+// SetKNIRoutes makes NAT install routes of port subnets and learned
+// default gateways of public ports via KNI interfaces into routing
+// table of host.
+var SetKNIRoutes bool
+
+// Routes which NAT installed via KNI interfaces. They are removed
+// when NAT stops.
+type kniRouteTable struct {
+	mutex   sync.Mutex
+	stopped bool
+	// Installed routes by port index and by destination and gateway
+	routes map[uint16]map[string]kniRoute
+}
+
+// Route via KNI interface. Route which kernel already had, e.g. route
+// of subnet of address set on KNI interface, is not owned and is left
+// in place when it is not needed any more.
+type kniRoute struct {
+	route netlink.Route
+	owned bool
+}
+
+var kniRoutes = newKNIRouteTable()
+
+func newKNIRouteTable() *kniRouteTable {
+	return &kniRouteTable{
+		routes: map[uint16]map[string]kniRoute{},
+	}
+}
+
+// ipv4Net converts IPv4 address and mask in host byte order to
+// net.IPNet.
+func ipv4Net(addr, mask types.IPv4Address) *net.IPNet {
+	return &net.IPNet{
+		IP:   net.IPv4(byte(addr>>24), byte(addr>>16), byte(addr>>8), byte(addr)),
+		Mask: net.IPv4Mask(byte(mask>>24), byte(mask>>16), byte(mask>>8), byte(mask)),
+	}
+}
+
+// StartKNIRoutes starts updates of routes via KNI interfaces when they
+// are enabled.
+func StartKNIRoutes() {
+	if !SetKNIRoutes {
+		return
+	}
+	go func() {
+		for {
+			if !updateKNIRoutes() {
+				return
+			}
+			time.Sleep(kniRoutesInterval)
+		}
+	}()
+}
+
+// updateKNIRoutes installs missing routes and removes routes which are
+// not needed any more. It returns false when routes were removed by
+// RemoveKNIRoutes.
+func updateKNIRoutes() bool {
+	kniRoutes.mutex.Lock()
+	defer kniRoutes.mutex.Unlock()
+	if kniRoutes.stopped {
+		return false
+	}
+	for i := range Natconfig.PortPairs {
+		pp := &Natconfig.PortPairs[i]
+		for _, port := range []*ipPort{&pp.PublicPort, &pp.PrivatePort} {
+			if port.KNIName == "" {
+				continue
+			}
+			link, err := netlink.LinkByName(port.KNIName)
+			if err != nil {
+				continue
+			}
+			kniRoutes.update(port, port.wantedKNIRoutes(link.Attrs().Index))
+		}
+	}
+	return true
+}
+
+// wantedKNIRoutes returns routes of port subnets and, for public port,
+// default routes via gateways learned with DHCP and router
+// advertisements.
+func (port *ipPort) wantedKNIRoutes(linkIndex int) map[string]netlink.Route {
+	routes := map[string]netlink.Route{}
+	add := func(dst *net.IPNet, gw net.IP, metric int) {
+		route := netlink.Route{
+			LinkIndex: linkIndex,
+			Dst:       dst,
+			Gw:        gw,
+			Priority:  metric,
+		}
+		if gw == nil {
+			route.Scope = netlink.SCOPE_LINK
+		}
+		routes[fmt.Sprint(dst, " via ", gw)] = route
+	}
+
+	if port.Subnet.addressAcquired {
+		add(ipv4Net(port.Subnet.Addr&port.Subnet.Mask, port.Subnet.Mask), nil, 0)
+	}
+	if port.Subnet6.addressAcquired {
+		prefix := port.Subnet6.andMask(port.Subnet6.Addr)
+		add(&net.IPNet{
+			IP:   append(net.IP{}, prefix[:]...),
+			Mask: append(net.IPMask{}, port.Subnet6.Mask[:]...),
+		}, nil, 0)
+	}
+	if port.Type != iPUBLIC {
+		return routes
+	}
+	if port.Subnet.addressAcquired && port.Subnet.ds.lease.router != nil {
+		add(nil, port.Subnet.ds.lease.router, kniDefaultRouteMetric)
+	}
+	if r := port.currentRouter(); r != nil && time.Now().Before(r.expires) {
+		add(nil, append(net.IP{}, r.addr[:]...), kniDefaultRouteMetric)
+	}
+	return routes
+}
+
+// update makes installed routes of port equal to wanted ones. Mutex
+// should be locked.
+func (table *kniRouteTable) update(port *ipPort, wanted map[string]netlink.Route) {
+	installed := table.routes[port.Index]
+	if installed == nil {
+		installed = map[string]kniRoute{}
+		table.routes[port.Index] = installed
+	}
+	for key, r := range installed {
+		if _, ok := wanted[key]; ok {
+			continue
+		}
+		delete(installed, key)
+		if !r.owned {
+			continue
+		}
+		if err := netlink.RouteDel(&r.route); err != nil {
+			fmt.Printf("Failed to remove route %s from interface %s: %+v\n", key, port.KNIName, err)
+		} else {
+			fmt.Println("Removed route", key, "from interface", port.KNIName)
+		}
+	}
+	for key, route := range wanted {
+		if _, ok := installed[key]; ok {
+			continue
+		}
+		err := netlink.RouteAdd(&route)
+		if err == syscall.EEXIST {
+			installed[key] = kniRoute{route: route}
+			continue
+		}
+		if err != nil {
+			fmt.Printf("Failed to install route %s on interface %s: %+v\n", key, port.KNIName, err)
+			continue
+		}
+		fmt.Println("Installed route", key, "on interface", port.KNIName)
+		installed[key] = kniRoute{route: route, owned: true}
+	}
+}
+
+// removeAll removes all routes which were installed and stops
+// updates of the table.
+func (table *kniRouteTable) removeAll() {
+	table.mutex.Lock()
+	defer table.mutex.Unlock()
+	table.stopped = true
+	for i := range Natconfig.PortPairs {
+		pp := &Natconfig.PortPairs[i]
+		for _, port := range []*ipPort{&pp.PublicPort, &pp.PrivatePort} {
+			if port.KNIName != "" {
+				table.update(port, nil)
+			}
+		}
+	}
+}
+
+// RemoveKNIRoutes removes routes which NAT installed via KNI
+// interfaces, including announced routes, and stops their updates.
+func RemoveKNIRoutes() {
+	kniRoutes.removeAll()
+	announcedRoutes.removeAll()
+}
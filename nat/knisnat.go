@@ -0,0 +1,222 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/intel-go/nff-go/flow"
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+const (
+	// Output of public translation which sends replies of sessions
+	// of private KNI host to KNI interface of private port
+	dirPrivateKNI = DirKNI + 1
+	// Output of private KNI traffic which is translated and sent to
+	// public port
+	dirKNIToPublic = DirKNI
+)
+
+// Source NAT of IPv4 traffic which host of private KNI interface
+// sends to public network. Without it such packets leave private port
+// untranslated. Host sessions get public address of port pair and
+// public ports from their own range, so they never compete with
+// sessions of private hosts and their replies are not taken by them.
+type kniSourceNATConfig struct {
+	Enable bool `json:"enable"`
+	// Public ports of host sessions, they should be in local-ports
+	Ports []portSpan `json:"ports"`
+}
+
+// Public ports of private KNI host sessions.
+type kniSourceNAT struct {
+	ports []uint16
+	// Index of port that is tried first for the next session
+	next int
+	set  localPortSet
+}
+
+// checkKNISourceNAT checks that ports of KNI host sessions are
+// reserved and builds their list. It is called after local ports and
+// STUN are checked.
+func (pp *portPair) checkKNISourceNAT() error {
+	cfg := &pp.KNISourceNAT
+	if !cfg.Enable {
+		return nil
+	}
+	if pp.PrivatePort.KNIName == "" {
+		return fmt.Errorf("KNI source NAT of port %s requires KNI interface of private port", pp.PublicPort.logName())
+	}
+	if pp.DisableIPv4 {
+		return fmt.Errorf("KNI source NAT of port %s requires IPv4", pp.PublicPort.logName())
+	}
+	if len(cfg.Ports) == 0 {
+		return fmt.Errorf("KNI source NAT of port %s should have ports", pp.PublicPort.logName())
+	}
+	nat := &kniSourceNAT{}
+	for _, span := range cfg.Ports {
+		for p := int(span.first); p <= int(span.last); p++ {
+			if p >= portEnd || !pp.isLocalPort(uint16(p)) {
+				return fmt.Errorf("Port %d of KNI source NAT of port %s should be in local-ports and less than %d",
+					p, pp.PublicPort.logName(), portEnd)
+			}
+			if !nat.contains(uint16(p)) {
+				nat.set.add(portSpan{first: uint16(p), last: uint16(p)})
+				nat.ports = append(nat.ports, uint16(p))
+			}
+		}
+	}
+	if pp.STUN.Interval != 0 && nat.contains(pp.STUN.SourcePort) {
+		return fmt.Errorf("STUN source-port %d of port %s is used by KNI source NAT", pp.STUN.SourcePort, pp.PublicPort.logName())
+	}
+	pp.kniSNAT = nat
+	return nil
+}
+
+func (nat *kniSourceNAT) contains(port uint16) bool {
+	return nat.set[port/64]&(1<<(port%64)) != 0
+}
+
+// findFreeKNIPort finds port of KNI host sessions which was not used
+// for session timeout and deletes its old connection. Mutex should be
+// locked.
+func (pp *portPair) findFreeKNIPort(protocol uint8) (int, bool) {
+	nat := pp.kniSNAT
+	pm := pp.getPublicPortPortmap(false, protocol)
+	for i := range nat.ports {
+		index := (nat.next + i) % len(nat.ports)
+		p := int(nat.ports[index])
+		if !pm[p].static && time.Since(pm[p].lastused) > connectionTimeout {
+			nat.next = index + 1
+			pp.deleteOldConnection(false, protocol, p)
+			return p, true
+		}
+	}
+	return 0, false
+}
+
+// allocateKNIConnection creates session of private KNI host and
+// returns its public address and port.
+func (pp *portPair) allocateKNIConnection(protocol uint8, privEntry, remoteEntry Tuple) (types.IPv4Address, uint16, error) {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	port, found := pp.findFreeKNIPort(protocol)
+	if !found {
+		return 0, 0, errors.New("All ports of KNI source NAT are allocated")
+	}
+	v4addr, _ := pp.addEgressSession(false, protocol, port, privEntry.addr, privEntry, remoteEntry)
+	return v4addr, uint16(port), nil
+}
+
+// isPrivateKNISession returns true if private entry of session belongs
+// to host of private KNI interface.
+func (pp *portPair) isPrivateKNISession(ipv6 bool, pme *portMapEntry, v4addr types.IPv4Address) bool {
+	return pp.kniSNAT != nil && !ipv6 && !pme.static && v4addr == pp.PrivatePort.Subnet.Addr
+}
+
+// privateKNIOutput translates packets which host of private KNI
+// interface sends to public network and passes other packets to
+// private port.
+func privateKNIOutput(pkt *packet.Packet, ctx flow.UserContext) uint {
+	pp := &Natconfig.PortPairs[ctx.(pairIndex).index]
+	return pp.translateFromPrivateKNI(pkt)
+}
+
+func (pp *portPair) translateFromPrivateKNI(pkt *packet.Packet) uint {
+	port := &pp.PrivatePort
+	public := &pp.PublicPort
+
+	pktVLAN := pkt.ParseL3CheckVLAN()
+	pktIPv4 := pkt.GetIPv4CheckVLAN()
+	if pktIPv4 == nil {
+		return DirSEND
+	}
+	src := packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr)
+	dst := packet.SwapBytesIPv4Addr(pktIPv4.DstAddr)
+	// Only traffic of KNI address to destinations outside of
+	// private subnet goes to public network
+	if src != port.Subnet.Addr || port.Subnet.checkAddrWithingSubnet(dst) || dst == BroadcastIPv4 || isIPv4Multicast(dst) {
+		return DirSEND
+	}
+	// Fragments are not translated, host should use path MTU
+	// discovery
+	if !public.Subnet.addressAcquired || isIPv4Fragment(pktIPv4) {
+		port.dumpPacket(pkt, DirDROP)
+		return DirDROP
+	}
+	protocol, pktTCP, pktUDP, pktICMP, srcPort, dstPort := ParseAllKnownL4(pkt, pktIPv4, nil)
+	if protocol == 0 || (pktICMP != nil && !isICMPQuery(protocol, pktICMP.Type)) {
+		port.dumpPacket(pkt, DirDROP)
+		return DirDROP
+	}
+
+	key := Tuple{addr: src, port: srcPort}
+	var v4addr types.IPv4Address
+	var newPort uint16
+	if v, found := port.translationTable[protocol].Load(key); found {
+		v4addr, _, newPort, _ = getAddrFromTuple(v, false)
+	} else {
+		var err error
+		v4addr, newPort, err = pp.allocateKNIConnection(protocol, key, Tuple{addr: dst, port: dstPort})
+		if err != nil {
+			println("Warning! Failed to allocate new connection of KNI host on port", public.logName(), ":", err.Error())
+			port.dumpPacket(pkt, DirDROP)
+			return DirDROP
+		}
+	}
+	pme := &public.getPortmap(false, protocol)[newPort]
+	pme.lastused = pp.MediaTimeout.lastused(protocol, srcPort, dstPort)
+
+	// Sessions of VLAN subinterfaces are sent to their VLANs
+	var mac types.MACAddress
+	var found bool
+	vlanTag := public.Vlan
+	if vlan := public.vlanByAddr(v4addr); vlan != nil {
+		vlanTag = vlan.Vlan
+		mac, found = public.getMACForVLAN(vlan, dst)
+	} else {
+		mac, found = public.getMACForIPv4(dst)
+	}
+	if !found {
+		port.dumpPacket(pkt, DirDROP)
+		return DirDROP
+	}
+	countSessionPacket(pme, pkt)
+
+	old := saveTranslatedHeader(pktIPv4, nil)
+	pkt.Ether.DAddr = mac
+	pkt.Ether.SAddr = public.SrcMACAddress
+	if pktVLAN != nil {
+		pktVLAN.SetVLANTagIdentifier(vlanTag)
+	}
+	pktIPv4.SrcAddr = packet.SwapBytesIPv4Addr(v4addr)
+	setPacketSrcPort(pkt, false, newPort, pktTCP, pktUDP, pktICMP, public, &old)
+	public.dumpPacket(pkt, DirSEND)
+	return dirKNIToPublic
+}
+
+// translateToPrivateKNI translates destination of packet of private
+// KNI host session back to KNI address and port. KNI interface has
+// MAC address of its port and needs complete checksums.
+func (pp *portPair) translateToPrivateKNI(pkt *packet.Packet, pktVLAN *packet.VLANHdr, pktIPv4 *packet.IPv4Hdr, pme *portMapEntry,
+	newPort uint16, pktTCP *packet.TCPHdr, pktUDP *packet.UDPHdr, pktICMP *packet.ICMPHdr) uint {
+	port := &pp.PrivatePort
+	countSessionPacket(pme, pkt)
+
+	old := saveTranslatedHeader(pktIPv4, nil)
+	pkt.Ether.DAddr = port.SrcMACAddress
+	if pktVLAN != nil {
+		pktVLAN.SetVLANTagIdentifier(port.Vlan)
+	}
+	pktIPv4.DstAddr = packet.SwapBytesIPv4Addr(port.Subnet.Addr)
+	setPacketDstPort(pkt, false, newPort, pktTCP, pktUDP, pktICMP, nil, &old)
+	port.dumpPacket(pkt, DirKNI)
+	return dirPrivateKNI
+}
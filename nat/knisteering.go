@@ -0,0 +1,174 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+type kniSteeringAction int
+
+const (
+	kniSteeringKNI kniSteeringAction = iota
+	kniSteeringDrop
+)
+
+var kniSteeringActionLookup = map[string]kniSteeringAction{
+	"kni":  kniSteeringKNI,
+	"drop": kniSteeringDrop,
+}
+
+// IP protocol of steering rule, name or protocol number.
+type kniSteeringProtocol uint8
+
+var kniSteeringProtocolLookup = map[string]kniSteeringProtocol{
+	"TCP":   types.TCPNumber,
+	"UDP":   types.UDPNumber,
+	"ICMP":  types.ICMPNumber,
+	"ICMP6": types.ICMPv6Number,
+	"IGMP":  igmpNumber,
+}
+
+// Rule which decides whether traffic that is not translated goes to
+// KNI interface of port or is dropped, e.g. only BGP sessions reach
+// routing daemon of host. Rules apply to packets which have no
+// session and are addressed to port or have no translation, i.e.
+// packets which would be sent to KNI or dropped without rules. First
+// matching rule of port wins, packets which match no rule keep their
+// fate. Neighbor discovery, DHCP and translated sessions are always
+// handled by NAT.
+type kniSteeringRule struct {
+	Protocol kniSteeringProtocol `json:"protocol"`
+	// Destination TCP and UDP ports, empty matches all ports
+	Ports  []portSpan        `json:"ports"`
+	Action kniSteeringAction `json:"action"`
+}
+
+// UnmarshalJSON parses KNI steering action.
+func (out *kniSteeringAction) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	result, ok := kniSteeringActionLookup[s]
+	if !ok {
+		return errors.New("Bad KNI steering action: " + s)
+	}
+
+	*out = result
+	return nil
+}
+
+// MarshalJSON writes action name as it is used in config file.
+func (action kniSteeringAction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(action.String())
+}
+
+// String returns action name as it is used in config file.
+func (action kniSteeringAction) String() string {
+	for name, a := range kniSteeringActionLookup {
+		if a == action {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// UnmarshalJSON parses protocol name or number.
+func (out *kniSteeringProtocol) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	result, ok := kniSteeringProtocolLookup[s]
+	if !ok {
+		n, err := strconv.ParseUint(s, 10, 8)
+		if err != nil {
+			return errors.New("Bad KNI steering protocol: " + s)
+		}
+		result = kniSteeringProtocol(n)
+	}
+
+	*out = result
+	return nil
+}
+
+// MarshalJSON writes protocol name or number as it is used in config file.
+func (protocol kniSteeringProtocol) MarshalJSON() ([]byte, error) {
+	return json.Marshal(protocol.String())
+}
+
+// String returns protocol name as it is used in config file.
+func (protocol kniSteeringProtocol) String() string {
+	for name, p := range kniSteeringProtocolLookup {
+		if p == protocol {
+			return name
+		}
+	}
+	return strconv.Itoa(int(protocol))
+}
+
+// checkKNISteering checks that rules of port which send traffic to
+// KNI have KNI interface and that only TCP and UDP rules have ports.
+func (port *ipPort) checkKNISteering() error {
+	for i := range port.KNISteering {
+		rule := &port.KNISteering[i]
+		if rule.Action == kniSteeringKNI && port.KNIName == "" {
+			return errors.New("KNI steering rule of port " + port.logName() + " requires kni-name")
+		}
+		if len(rule.Ports) != 0 && rule.Protocol != types.TCPNumber && rule.Protocol != types.UDPNumber {
+			return errors.New("Only TCP and UDP KNI steering rules may have ports, protocol " + rule.Protocol.String() + " has them")
+		}
+	}
+	return nil
+}
+
+// matches returns true if rule applies to packet of protocol with
+// destination port.
+func (rule *kniSteeringRule) matches(protocol uint8, dstPort uint16) bool {
+	if uint8(rule.Protocol) != protocol {
+		return false
+	}
+	if len(rule.Ports) == 0 {
+		return true
+	}
+	for _, span := range rule.Ports {
+		if dstPort >= span.first && dstPort <= span.last {
+			return true
+		}
+	}
+	return false
+}
+
+// matchKNISteering returns fate of packet by first matching steering
+// rule of port, false if no rule matches.
+func (port *ipPort) matchKNISteering(protocol uint8, dstPort uint16) (uint, bool) {
+	for i := range port.KNISteering {
+		rule := &port.KNISteering[i]
+		if !rule.matches(protocol, dstPort) {
+			continue
+		}
+		if rule.Action == kniSteeringKNI {
+			return DirKNI, true
+		}
+		return DirDROP, true
+	}
+	return DirDROP, false
+}
+
+// steerKNI returns fate of packet which is not translated, dir if no
+// steering rule of port matches it.
+func (port *ipPort) steerKNI(protocol uint8, dstPort uint16, dir uint) uint {
+	if d, matched := port.matchKNISteering(protocol, dstPort); matched {
+		return d
+	}
+	return dir
+}
@@ -0,0 +1,114 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+/*
+#include <string.h>
+#include <rte_ethdev.h>
+
+// Link status fields are bit fields which are not accessible from Go.
+static void nat_link_get(uint16_t port, uint32_t *speed, int *up, int *full_duplex, int *autoneg) {
+	struct rte_eth_link link;
+	memset(&link, 0, sizeof(link));
+	rte_eth_link_get_nowait(port, &link);
+	*speed = link.link_speed;
+	*up = link.link_status == ETH_LINK_UP;
+	*full_duplex = link.link_duplex == ETH_LINK_FULL_DUPLEX;
+	*autoneg = link.link_autoneg == ETH_LINK_AUTONEG;
+}
+*/
+import "C"
+
+import (
+	"time"
+)
+
+// Link state is polled because NFF-Go configures ports without link
+// status change interrupts.
+const linkCheckInterval = 500 * time.Millisecond
+
+// Link state of network card port.
+type linkStatus struct {
+	up bool
+	// Speed in Mbps
+	speed           uint32
+	fullDuplex      bool
+	autoNegotiation bool
+}
+
+func readLinkStatus(port uint16) linkStatus {
+	var speed C.uint32_t
+	var up, fullDuplex, autoneg C.int
+	C.nat_link_get(C.uint16_t(port), &speed, &up, &fullDuplex, &autoneg)
+	return linkStatus{
+		up:              up != 0,
+		speed:           uint32(speed),
+		fullDuplex:      fullDuplex != 0,
+		autoNegotiation: autoneg != 0,
+	}
+}
+
+// getLinkStatus returns link state found by last check. Link is
+// reported down until it is checked for the first time.
+func (port *ipPort) getLinkStatus() linkStatus {
+	if v := port.link.Load(); v != nil {
+		return v.(linkStatus)
+	}
+	return linkStatus{}
+}
+
+// StartLinkMonitor starts checking link state of all ports. It
+// should be called after ports are started.
+func StartLinkMonitor() {
+	ports := []*ipPort{}
+	for i := range Natconfig.PortPairs {
+		pp := &Natconfig.PortPairs[i]
+		ports = append(ports, &pp.PrivatePort, &pp.PublicPort)
+	}
+	for _, port := range ports {
+		port.link.Store(readLinkStatus(port.Index))
+	}
+	go monitorLinks(ports)
+}
+
+func monitorLinks(ports []*ipPort) {
+	for {
+		time.Sleep(linkCheckInterval)
+		for _, port := range ports {
+			old := port.getLinkStatus()
+			link := readLinkStatus(port.Index)
+			if link == old {
+				continue
+			}
+			port.link.Store(link)
+			if link.up == old.up {
+				continue
+			}
+			port.linkChanged(link)
+		}
+	}
+}
+
+// linkChanged reacts to port link going up or down.
+func (port *ipPort) linkChanged(link linkStatus) {
+	eventType := EventLinkDown
+	details := map[string]interface{}{
+		"source": "nic",
+	}
+	if link.up {
+		eventType = EventLinkUp
+		details["speed-mbps"] = link.speed
+		details["full-duplex"] = link.fullDuplex
+		println("Port", port.logName(), "link is up,", link.speed, "Mbps")
+	} else {
+		println("Port", port.logName(), "link is down")
+		// Neighbors may be connected to another switch port or
+		// replaced while link is down
+		if port.FlushNeighborsOnLinkDown {
+			port.flushNeighbors(false)
+		}
+	}
+	raiseEvent(eventType, port, details)
+}
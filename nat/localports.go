@@ -0,0 +1,101 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+// Public TCP and UDP ports of port pair which are reserved for
+// traffic that NAT and host of its KNI interfaces originate from
+// public address, e.g. STUN requests, session log and conntrack
+// export or host services. Dynamic sessions, DMZ host and port
+// triggers never use reserved ports, so replies to local traffic are
+// not taken by subscriber sessions. Bit of port is set if it is
+// reserved.
+type localPortSet [65536 / 64]uint64
+
+func (set *localPortSet) add(span portSpan) {
+	for p := int(span.first); p <= int(span.last); p++ {
+		set[p/64] |= 1 << uint(p%64)
+	}
+}
+
+// isLocalPort returns true if public port is reserved for local
+// traffic of port pair.
+func (pp *portPair) isLocalPort(port uint16) bool {
+	set := pp.localPorts
+	return set != nil && set[port/64]&(1<<(port%64)) != 0
+}
+
+// isDynamicPort returns true if sessions of port pair may get public
+// port, i.e. port is in dynamic range and is not reserved.
+func (pp *portPair) isDynamicPort(port uint16) bool {
+	return port >= portStart && port < portEnd && !pp.isLocalPort(port)
+}
+
+// checkLocalPorts builds set of reserved ports of port pair and checks
+// that they are not forwarded to private hosts. Ports below portStart
+// and starting from portEnd are never dynamic, so reserving them is
+// allowed but not necessary.
+func (pp *portPair) checkLocalPorts() error {
+	if len(pp.LocalPorts) == 0 {
+		return nil
+	}
+	set := &localPortSet{}
+	for _, span := range pp.LocalPorts {
+		set.add(span)
+	}
+	pp.localPorts = set
+
+	for i := range pp.PublicPort.ForwardPorts {
+		fp := &pp.PublicPort.ForwardPorts[i]
+		if (fp.Protocol.id == types.TCPNumber || fp.Protocol.id == types.UDPNumber) && pp.isLocalPort(fp.Port) {
+			return fmt.Errorf("Port %d of protocol %s is forwarded on port %s, it should not be in local-ports",
+				fp.Port, fp.Protocol.String(), pp.PublicPort.logName())
+		}
+	}
+	for _, rule := range pp.PortTriggers {
+		for _, span := range rule.OpenPorts {
+			if pp.localPortsOverlap(span) {
+				return fmt.Errorf("Port trigger %s %s opens ports %s which overlap local-ports of port %s",
+					rule.Protocol, rule.Ports, span, pp.PublicPort.logName())
+			}
+		}
+	}
+	return nil
+}
+
+func (pp *portPair) localPortsOverlap(span portSpan) bool {
+	for p := int(span.first); p <= int(span.last); p++ {
+		if pp.isLocalPort(uint16(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkLocalSourcePort checks that source port which NAT uses for its
+// own traffic from public address of port pair cannot be allocated to
+// sessions.
+func (pp *portPair) checkLocalSourcePort(port uint16, option string) error {
+	if pp.isDynamicPort(port) {
+		return fmt.Errorf("%s %d of port %s is a dynamic port, it should be less than %d, at least %d or in local-ports",
+			option, port, pp.PublicPort.logName(), portStart, portEnd)
+	}
+	return nil
+}
+
+// localUDPAddr returns local address of socket which NAT opens in
+// Linux for its own messages, nil if source port is not configured.
+func localUDPAddr(port uint16) *net.UDPAddr {
+	if port == 0 {
+		return nil
+	}
+	return &net.UDPAddr{Port: int(port)}
+}
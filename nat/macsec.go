@@ -0,0 +1,321 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/flow"
+	"github.com/intel-go/nff-go/packet"
+)
+
+const (
+	macsecEtherType = 0x88e5
+	// Ethernet addresses which precede SecTAG
+	macsecAddrLen = 12
+	// SecTAG with EtherType and explicit SCI
+	macsecTagLen      = 16
+	macsecTagNoSCILen = 8
+	macsecICVLen      = 16
+	macsecSCILen      = 8
+	// Bits of TCI and AN octet
+	macsecTCIVersion = 0x80
+	macsecTCIES      = 0x40
+	macsecTCISC      = 0x20
+	macsecTCISCB     = 0x10
+	macsecTCIE       = 0x08
+	macsecTCIC       = 0x04
+	macsecANMask     = 0x03
+	// Frames with less secure data have its length in SecTAG
+	macsecShortLength = 48
+	// Port identifier in SCI of transmitted frames
+	macsecPortIdentifier = 1
+)
+
+// MACsec of public port with static secure association keys. Frames
+// are protected with GCM-AES-128 or GCM-AES-256 chosen by key length,
+// with confidentiality offset zero and explicit SCI. NFF-Go has no
+// access to security offload of network cards, so frames are
+// encrypted and decrypted in software. Key agreement protocol is not
+// supported, keys of peer are configured statically.
+type macsecConfig struct {
+	// Transmit secure association
+	TX macsecKey `json:"tx"`
+	// Receive secure associations of peer, one per association
+	// number
+	RX []macsecKey `json:"rx"`
+	// Accepted frames may have packet numbers this much lower than
+	// highest received packet number
+	ReplayWindow uint32 `json:"replay-window"`
+	// Receive unprotected frames as they are instead of dropping them
+	AllowUnprotected bool `json:"allow-unprotected"`
+	state            *macsecState
+}
+
+// Secure association key by association number. Key is hexadecimal.
+type macsecKey struct {
+	AN  uint8  `json:"an"`
+	Key string `json:"key"`
+}
+
+type macsecState struct {
+	sci  [macsecSCILen]byte
+	txAN uint8
+	tx   cipher.AEAD
+	rx   [macsecANMask + 1]*macsecRXSA
+	// Last packet number of transmitted frames, transmission stops
+	// when it is exhausted
+	txPN uint32
+}
+
+type macsecRXSA struct {
+	aead cipher.AEAD
+	// Highest packet number of received valid frames
+	highestPN uint32
+}
+
+func (cfg *macsecConfig) enabled() bool {
+	return cfg.TX.Key != ""
+}
+
+func newMACsecAEAD(k *macsecKey) (cipher.AEAD, error) {
+	if k.AN > macsecANMask {
+		return nil, fmt.Errorf("MACsec association number %d should be 0 to 3", k.AN)
+	}
+	key, err := hex.DecodeString(k.Key)
+	if err != nil || (len(key) != 16 && len(key) != 32) {
+		return nil, fmt.Errorf("MACsec key of association %d should be 16 or 32 bytes in hexadecimal", k.AN)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// check parses keys of port.
+func (cfg *macsecConfig) check(port *ipPort) error {
+	if !cfg.enabled() {
+		if len(cfg.RX) != 0 {
+			return errors.New("MACsec receive keys of port " + port.logName() + " require transmit key")
+		}
+		return nil
+	}
+	if port.Type != iPUBLIC {
+		return errors.New("MACsec is supported only on public port")
+	}
+	if len(cfg.RX) == 0 {
+		return errors.New("MACsec of port " + port.logName() + " requires receive keys")
+	}
+	state := &macsecState{
+		txAN: cfg.TX.AN,
+	}
+	var err error
+	if state.tx, err = newMACsecAEAD(&cfg.TX); err != nil {
+		return err
+	}
+	for i := range cfg.RX {
+		k := &cfg.RX[i]
+		aead, err := newMACsecAEAD(k)
+		if err != nil {
+			return err
+		}
+		if state.rx[k.AN] != nil {
+			return fmt.Errorf("MACsec receive association %d is specified twice", k.AN)
+		}
+		state.rx[k.AN] = &macsecRXSA{
+			aead: aead,
+		}
+	}
+	cfg.state = state
+	return nil
+}
+
+// initSCI sets SCI of transmitted frames which is made of port MAC
+// address, so it is called after MAC addresses are known.
+func (cfg *macsecConfig) initSCI(port *ipPort) {
+	if cfg.state == nil {
+		return
+	}
+	copy(cfg.state.sci[:], port.SrcMACAddress[:])
+	binary.BigEndian.PutUint16(cfg.state.sci[len(port.SrcMACAddress):], macsecPortIdentifier)
+}
+
+// packetBytes returns bytes of single segment packet without copying
+// them.
+func packetBytes(pkt *packet.Packet) ([]byte, bool) {
+	length := pkt.GetPacketLen()
+	if pkt.GetPacketSegmentLen() != length {
+		return nil, false
+	}
+	return (*[math.MaxUint16]byte)(pkt.StartAtOffset(0))[:length:length], true
+}
+
+// protect encrypts frame in place and adds SecTAG and ICV to it. It
+// returns false if frame cannot be protected.
+func (s *macsecState) protect(pkt *packet.Packet) bool {
+	pn := atomic.AddUint32(&s.txPN, 1)
+	if pn == 0 {
+		// Packet number is exhausted, key should be replaced
+		atomic.StoreUint32(&s.txPN, math.MaxUint32)
+		return false
+	}
+	length := pkt.GetPacketLen()
+	if length < macsecAddrLen {
+		return false
+	}
+	secure := length - macsecAddrLen
+	if !pkt.EncapsulateHead(macsecAddrLen, macsecTagLen) {
+		return false
+	}
+	if !pkt.EncapsulateTail(pkt.GetPacketLen(), macsecICVLen) {
+		return false
+	}
+	data, ok := packetBytes(pkt)
+	if !ok {
+		return false
+	}
+	tag := data[macsecAddrLen : macsecAddrLen+macsecTagLen]
+	binary.BigEndian.PutUint16(tag[0:], macsecEtherType)
+	tag[2] = macsecTCISC | macsecTCIE | macsecTCIC | s.txAN
+	tag[3] = 0
+	if secure < macsecShortLength {
+		tag[3] = uint8(secure)
+	}
+	binary.BigEndian.PutUint32(tag[4:], pn)
+	copy(tag[8:], s.sci[:])
+
+	var iv [macsecSCILen + 4]byte
+	copy(iv[:], s.sci[:])
+	binary.BigEndian.PutUint32(iv[macsecSCILen:], pn)
+	header := macsecAddrLen + macsecTagLen
+	plaintext := data[header : header+int(secure)]
+	s.tx.Seal(plaintext[:0], iv[:], plaintext, data[:header])
+	return true
+}
+
+// replayed returns true if frame with packet number pn is outside of
+// replay window below highest received packet number. Window zero
+// accepts only packet numbers higher than highest one.
+func replayed(pn, highest, window uint32) bool {
+	if window == 0 {
+		return pn <= highest
+	}
+	return highest > window && pn < highest-window
+}
+
+// validate checks and decrypts protected frame in place and removes
+// SecTAG and ICV from it. It returns false if frame should be dropped.
+func (cfg *macsecConfig) validate(pkt *packet.Packet) bool {
+	s := cfg.state
+	data, ok := packetBytes(pkt)
+	if !ok || len(data) < macsecAddrLen+2 {
+		return false
+	}
+	if binary.BigEndian.Uint16(data[macsecAddrLen:]) != macsecEtherType {
+		return cfg.AllowUnprotected
+	}
+	if len(data) < macsecAddrLen+macsecTagNoSCILen+macsecICVLen {
+		return false
+	}
+	tci := data[macsecAddrLen+2]
+	if tci&macsecTCIVersion != 0 || (tci&macsecTCIE != 0) != (tci&macsecTCIC != 0) {
+		return false
+	}
+	sa := s.rx[tci&macsecANMask]
+	if sa == nil {
+		return false
+	}
+	pn := binary.BigEndian.Uint32(data[macsecAddrLen+4:])
+	if pn == 0 {
+		return false
+	}
+	if replayed(pn, atomic.LoadUint32(&sa.highestPN), cfg.ReplayWindow) {
+		return false
+	}
+
+	// Peer SCI is either explicit or made of source MAC address when
+	// peer is an end station
+	var iv [macsecSCILen + 4]byte
+	tagLen := macsecTagNoSCILen
+	if tci&macsecTCISC != 0 {
+		tagLen = macsecTagLen
+		if len(data) < macsecAddrLen+tagLen+macsecICVLen {
+			return false
+		}
+		copy(iv[:], data[macsecAddrLen+macsecTagNoSCILen:macsecAddrLen+macsecTagLen])
+	} else if tci&macsecTCIES != 0 || tci&macsecTCISCB == 0 {
+		copy(iv[:], data[6:macsecAddrLen])
+		binary.BigEndian.PutUint16(iv[6:], macsecPortIdentifier)
+	} else {
+		// Single copy broadcast SCI is not known
+		return false
+	}
+	binary.BigEndian.PutUint32(iv[macsecSCILen:], pn)
+
+	header := macsecAddrLen + tagLen
+	secure := len(data) - header - macsecICVLen
+	if sl := int(data[macsecAddrLen+3]); sl != 0 && sl != secure {
+		if sl > secure {
+			return false
+		}
+		// Frame was padded to minimum Ethernet size
+		secure = sl
+	}
+	icvEnd := header + secure + macsecICVLen
+	if tci&macsecTCIE != 0 {
+		if _, err := sa.aead.Open(data[header:header], iv[:], data[header:icvEnd], data[:header]); err != nil {
+			return false
+		}
+	} else if _, err := sa.aead.Open(nil, iv[:], data[header+secure:icvEnd], data[:header+secure]); err != nil {
+		return false
+	}
+
+	// Packet numbers of frames received by different cores may
+	// come out of order
+	for {
+		highest := atomic.LoadUint32(&sa.highestPN)
+		if pn <= highest || atomic.CompareAndSwapUint32(&sa.highestPN, highest, pn) {
+			break
+		}
+	}
+
+	if !pkt.DecapsulateTail(uint(header+secure), uint(len(data)-header-secure)) {
+		return false
+	}
+	return pkt.DecapsulateHead(macsecAddrLen, uint(tagLen))
+}
+
+// sendToPort sends packet generated by NAT to port with index. Packet
+// path harness replaces it to capture sent packets.
+var sendToPort = func(pkt *packet.Packet, index uint16) {
+	pkt.SendPacket(index)
+}
+
+// sendPacket sends packet generated by NAT directly to port, it is
+// protected first if port uses MACsec.
+func (port *ipPort) sendPacket(pkt *packet.Packet) {
+	if port.MACsec.state != nil && !port.MACsec.state.protect(pkt) {
+		common.LogWarning(common.No, "Failed to protect MACsec frame on port", port.logName())
+		return
+	}
+	sendToPort(pkt, port.Index)
+}
+
+func macsecInput(pkt *packet.Packet, ctx flow.UserContext) bool {
+	return Natconfig.PortPairs[ctx.(pairIndex).index].PublicPort.MACsec.validate(pkt)
+}
+
+func macsecOutput(pkt *packet.Packet, ctx flow.UserContext) bool {
+	return Natconfig.PortPairs[ctx.(pairIndex).index].PublicPort.MACsec.state.protect(pkt)
+}
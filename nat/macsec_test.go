@@ -0,0 +1,41 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMACsecReplayWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		pn       uint32
+		highest  uint32
+		window   uint32
+		replayed bool
+	}{
+		{"first frame without window", 1, 0, 0, false},
+		{"next frame without window", 11, 10, 0, false},
+		{"repeated frame without window", 10, 10, 0, true},
+		{"older frame without window", 9, 10, 0, true},
+		{"frame far ahead without window", math.MaxUint32, 10, 0, false},
+		{"first frame with window", 1, 0, 64, false},
+		{"repeated frame with window", 100, 100, 64, false},
+		{"frame inside window", 50, 100, 64, false},
+		{"frame at window edge", 36, 100, 64, false},
+		{"frame below window", 35, 100, 64, true},
+		{"old frame while highest is inside window", 1, 64, 64, false},
+		{"old frame while highest is just above window", 0, 65, 64, true},
+		{"newer frame with window", 101, 100, 64, false},
+		{"frame below window of one", 98, 100, 1, true},
+		{"frame at window of one", 99, 100, 1, false},
+	}
+	for _, tt := range tests {
+		if got := replayed(tt.pn, tt.highest, tt.window); got != tt.replayed {
+			t.Errorf("%s: replayed(%d, %d, %d) = %v, expected %v", tt.name, tt.pn, tt.highest, tt.window, got, tt.replayed)
+		}
+	}
+}
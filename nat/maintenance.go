@@ -0,0 +1,156 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/packet"
+)
+
+// Maintenance mode of port pair which is set with control API, e.g.
+// before network cards or cables are replaced. Port pair in
+// maintenance creates no new dynamic sessions and its forwarded ports
+// accept no new TCP connections, while existing sessions are kept
+// unless they are flushed. Values are read by packet handlers without
+// lock.
+type maintenanceState struct {
+	// Set to 1 while port pair is in maintenance
+	active int32
+	// Set to 1 when new sessions are answered with TCP reset or ICMP
+	// port unreachable instead of being dropped
+	reject int32
+	// Unix time when maintenance started
+	since int64
+	// New sessions refused since maintenance started
+	refused uint64
+}
+
+func (pp *portPair) inMaintenance() bool {
+	return atomic.LoadInt32(&pp.maintenance.active) != 0
+}
+
+// maintenanceRejects returns true if port pair is in maintenance and
+// answers new sessions.
+func (pp *portPair) maintenanceRejects() bool {
+	return pp.inMaintenance() && atomic.LoadInt32(&pp.maintenance.reject) != 0
+}
+
+// refusesNewSessions returns true if NAT is draining or port pair is
+// in maintenance.
+func (pp *portPair) refusesNewSessions() bool {
+	return isDraining() || pp.inMaintenance()
+}
+
+// portInMaintenance returns true if port belongs to port pair in
+// maintenance.
+func portInMaintenance(port *ipPort) bool {
+	_, pp := Natconfig.getPortAndPairByID(uint32(port.Index))
+	return pp != nil && pp.inMaintenance()
+}
+
+// setMaintenance puts port pair into maintenance or takes it out.
+// Dynamic sessions are removed if flush is set when maintenance
+// starts. It returns number of removed sessions.
+func (pp *portPair) setMaintenance(enable, reject, flush bool) int {
+	state := &pp.maintenance
+	wasActive := pp.inMaintenance()
+	flushed := 0
+	if enable {
+		r := int32(0)
+		if reject {
+			r = 1
+		}
+		atomic.StoreInt32(&state.reject, r)
+		if !wasActive {
+			atomic.StoreUint64(&state.refused, 0)
+			atomic.StoreInt64(&state.since, time.Now().Unix())
+			atomic.StoreInt32(&state.active, 1)
+		}
+		if flush {
+			flushed = pp.flushDynamicSessions()
+		}
+	} else {
+		atomic.StoreInt32(&state.active, 0)
+		atomic.StoreInt32(&state.reject, 0)
+	}
+
+	if enable != wasActive {
+		if enable {
+			common.LogWarning(common.No, "Port pair of port", pp.PublicPort.logName(), "is in maintenance, removed", flushed, "sessions")
+		} else {
+			common.LogWarning(common.No, "Port pair of port", pp.PublicPort.logName(), "left maintenance")
+		}
+		raiseEvent(EventMaintenance, &pp.PublicPort, map[string]interface{}{
+			"maintenance":      enable,
+			"flushed-sessions": flushed,
+		})
+	}
+	// Routes of public addresses are withdrawn during maintenance
+	if pp.RouteAnnouncement.Enable {
+		updateAnnouncements()
+	}
+	return flushed
+}
+
+// flushDynamicSessions removes all dynamic sessions of port pair and
+// returns their number. Forwarded ports are kept.
+func (pp *portPair) flushDynamicSessions() int {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	count := 0
+	for _, ipv6 := range pp.ipFamilies() {
+		for _, protocol := range pp.sessionProtocols(ipv6) {
+			pm := pp.getPublicPortPortmap(ipv6, protocol)
+			if pm == nil {
+				continue
+			}
+			for p := portStart; p < portEnd; p++ {
+				if pm[p].static || pm[p].lastused.IsZero() {
+					continue
+				}
+				pp.deleteOldConnection(ipv6, protocol, p)
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// refuseMaintenanceSession answers packet which would start a new
+// session of port pair in maintenance if port pair rejects new
+// sessions. New TCP connections get reset and UDP datagrams get ICMP
+// port unreachable.
+func (pp *portPair) refuseMaintenanceSession(port *ipPort, pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr, pktTCP *packet.TCPHdr, pktUDP *packet.UDPHdr) {
+	atomic.AddUint64(&pp.maintenance.refused, 1)
+	if !pp.maintenanceRejects() {
+		return
+	}
+	var source interface{}
+	if pktIPv4 != nil {
+		source = packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr)
+	} else {
+		source = pktIPv6.SrcAddr
+	}
+	if pktTCP != nil && isNewTCPConnection(pktTCP) {
+		port.sendTCPReset(pkt, pktIPv4, pktIPv6, pktTCP)
+	} else if pktUDP != nil && icmpLimiter.allow(port, source) {
+		port.sendPortUnreachable(pkt, pktIPv4, pktIPv6)
+	}
+}
+
+// maintenanceStatus returns whether port pair is in maintenance,
+// whether it rejects new sessions, time when maintenance started and
+// number of refused sessions.
+func (pp *portPair) maintenanceStatus() (bool, bool, int64, uint64) {
+	state := &pp.maintenance
+	if !pp.inMaintenance() {
+		return false, false, 0, 0
+	}
+	return true, atomic.LoadInt32(&state.reject) != 0, atomic.LoadInt64(&state.since), atomic.LoadUint64(&state.refused)
+}
@@ -0,0 +1,57 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+	"time"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+// Maximum timeout of media sessions in seconds
+const maxMediaTimeout = 3600
+
+// Timeout of UDP sessions of RTP and RTCP media which differs from
+// timeout of other sessions, so that media sessions survive hold
+// periods of calls without keeping all UDP sessions long.
+type mediaTimeoutConfig struct {
+	// Private or remote UDP ports of media sessions
+	Ports []portSpan `json:"ports"`
+	// Seconds without packets after which media session expires, zero
+	// means general session timeout
+	Timeout int `json:"timeout"`
+	// Media sessions are kept with last used time shifted by
+	// difference of timeouts, like sessions waiting for port reuse
+	lastusedShift time.Duration
+}
+
+func (cfg *mediaTimeoutConfig) check() error {
+	if cfg.Timeout < 0 || cfg.Timeout > maxMediaTimeout {
+		return errors.New("Media session timeout should be between 0 and 3600 seconds")
+	}
+	if cfg.Timeout != 0 && len(cfg.Ports) == 0 {
+		return errors.New("Media session timeout requires media ports")
+	}
+	if cfg.Timeout != 0 {
+		cfg.lastusedShift = time.Duration(cfg.Timeout)*time.Second - connectionTimeout
+	}
+	return nil
+}
+
+// lastused returns last used time of session which has just
+// translated a packet.
+func (cfg *mediaTimeoutConfig) lastused(protocol uint8, privatePort, remotePort uint16) time.Time {
+	now := time.Now()
+	if cfg.lastusedShift == 0 || protocol != types.UDPNumber {
+		return now
+	}
+	for _, span := range cfg.Ports {
+		if span.contains(privatePort) || span.contains(remotePort) {
+			return now.Add(cfg.lastusedShift)
+		}
+	}
+	return now
+}
@@ -0,0 +1,191 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/packet"
+)
+
+const (
+	// Maximum number of sources blocked by one port pair. When it is
+	// reached, new sources are not blocked until old blocks expire.
+	maxBlockedSources = 65536
+	// Block time which is used when it is not configured, in seconds
+	defaultFloodBlockTime = 60
+	// Expired blocks are removed at most this often
+	blocklistExpiryInterval = time.Second
+)
+
+// Reasons of blocks which are reported in events.
+const (
+	floodReasonPackets     = "packet-rate"
+	floodReasonConnections = "connection-rate"
+)
+
+// Automatic blocking of public sources which flood forwarded ports.
+// Rates are in packets or new TCP connections per second from one
+// source, zero rate means no limit.
+type floodMitigationConfig struct {
+	PacketRate      float64 `json:"packet-rate"`
+	PacketBurst     int     `json:"packet-burst"`
+	ConnectionRate  float64 `json:"connection-rate"`
+	ConnectionBurst int     `json:"connection-burst"`
+	// Seconds which source stays blocked, zero means default
+	BlockTime   int `json:"block-time"`
+	packets     *rateLimiter
+	connections *rateLimiter
+}
+
+// Public sources which packets are dropped until block expires. All
+// packets of blocked sources received by public port are dropped. It
+// is used from packet handlers running on several cores, so it is
+// protected by a mutex.
+type blocklist struct {
+	mutex   sync.Mutex
+	sources map[interface{}]time.Time
+	// Number of blocked sources, checked without locking mutex
+	size    int32
+	expired time.Time
+	// Packets dropped because source is blocked, sources blocked so
+	// far
+	droppedPackets uint64
+	blocks         uint64
+}
+
+func (cfg *floodMitigationConfig) enabled() bool {
+	return cfg.PacketRate != 0 || cfg.ConnectionRate != 0
+}
+
+// check checks rates and creates per-source limiters.
+func (cfg *floodMitigationConfig) check() error {
+	if cfg.PacketRate < 0 || cfg.PacketBurst < 0 || cfg.ConnectionRate < 0 || cfg.ConnectionBurst < 0 || cfg.BlockTime < 0 {
+		return fmt.Errorf("Values of flood-mitigation should not be negative")
+	}
+	cfg.packets = newRateLimiter(rateLimitConfig{
+		PerSourceRate:  cfg.PacketRate,
+		PerSourceBurst: cfg.PacketBurst,
+	})
+	cfg.connections = newRateLimiter(rateLimitConfig{
+		PerSourceRate:  cfg.ConnectionRate,
+		PerSourceBurst: cfg.ConnectionBurst,
+	})
+	return nil
+}
+
+func (cfg *floodMitigationConfig) blockTime() time.Duration {
+	if cfg.BlockTime == 0 {
+		return defaultFloodBlockTime * time.Second
+	}
+	return time.Duration(cfg.BlockTime) * time.Second
+}
+
+// isBlocked returns true if source is blocked. Expired blocks are
+// removed.
+func (bl *blocklist) isBlocked(source interface{}) bool {
+	now := time.Now()
+	bl.mutex.Lock()
+	defer bl.mutex.Unlock()
+	if now.Sub(bl.expired) >= blocklistExpiryInterval {
+		bl.forgetExpired(now)
+	}
+	expires, ok := bl.sources[source]
+	if !ok || now.After(expires) {
+		return false
+	}
+	atomic.AddUint64(&bl.droppedPackets, 1)
+	return true
+}
+
+// block adds source to blocklist until specified time. It returns
+// false if source is already blocked or blocklist is full.
+func (bl *blocklist) block(source interface{}, until time.Time) bool {
+	bl.mutex.Lock()
+	defer bl.mutex.Unlock()
+	if bl.sources == nil {
+		bl.sources = map[interface{}]time.Time{}
+	}
+	if _, ok := bl.sources[source]; ok {
+		return false
+	}
+	if len(bl.sources) >= maxBlockedSources {
+		bl.forgetExpired(time.Now())
+		if len(bl.sources) >= maxBlockedSources {
+			return false
+		}
+	}
+	bl.sources[source] = until
+	atomic.StoreInt32(&bl.size, int32(len(bl.sources)))
+	atomic.AddUint64(&bl.blocks, 1)
+	return true
+}
+
+// forgetExpired removes sources which blocks expired. Mutex should be
+// locked.
+func (bl *blocklist) forgetExpired(now time.Time) {
+	bl.expired = now
+	for source, expires := range bl.sources {
+		if now.After(expires) {
+			delete(bl.sources, source)
+		}
+	}
+	atomic.StoreInt32(&bl.size, int32(len(bl.sources)))
+}
+
+// counters returns numbers of currently blocked sources, packets
+// dropped because of blocks and sources blocked so far.
+func (bl *blocklist) counters() (uint64, uint64, uint64) {
+	return uint64(atomic.LoadInt32(&bl.size)), atomic.LoadUint64(&bl.droppedPackets), atomic.LoadUint64(&bl.blocks)
+}
+
+// sourceAddress returns source address of packet as
+// types.IPv4Address or types.IPv6Address.
+func sourceAddress(pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) interface{} {
+	if pktIPv6 != nil {
+		return pktIPv6.SrcAddr
+	}
+	return packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr)
+}
+
+// isBlockedSource returns true if packet received by public port
+// comes from blocked source.
+func (pp *portPair) isBlockedSource(pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) bool {
+	// Most of the time nobody is blocked
+	if atomic.LoadInt32(&pp.blocklist.size) == 0 {
+		return false
+	}
+	return pp.blocklist.isBlocked(sourceAddress(pktIPv4, pktIPv6))
+}
+
+// checkFlood accounts packet sent to forwarded port against packet
+// and new connection rates of its source. Source which exceeds any of
+// them is blocked and false is returned.
+func (pp *portPair) checkFlood(pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr, pktTCP *packet.TCPHdr) bool {
+	cfg := &pp.FloodMitigation
+	source := sourceAddress(pktIPv4, pktIPv6)
+	reason := ""
+	if !cfg.packets.allow(&pp.PublicPort, source) {
+		reason = floodReasonPackets
+	} else if pktTCP != nil && isNewTCPConnection(pktTCP) && !cfg.connections.allow(&pp.PublicPort, source) {
+		reason = floodReasonConnections
+	}
+	if reason == "" {
+		return true
+	}
+
+	blockTime := cfg.blockTime()
+	if pp.blocklist.block(source, time.Now().Add(blockTime)) {
+		raiseEvent(EventSourceBlocked, &pp.PublicPort, map[string]interface{}{
+			"source":     fmt.Sprint(source),
+			"reason":     reason,
+			"block-time": int(blockTime.Seconds()),
+		})
+	}
+	return false
+}
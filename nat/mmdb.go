@@ -0,0 +1,333 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sync"
+)
+
+// MaxMind DB format data types.
+const (
+	mmdbExtended = iota
+	mmdbPointer
+	mmdbString
+	mmdbDouble
+	mmdbBytes
+	mmdbUint16
+	mmdbUint32
+	mmdbMap
+	mmdbInt32
+	mmdbUint64
+	mmdbUint128
+	mmdbArray
+	mmdbContainer
+	mmdbEndMarker
+	mmdbBoolean
+	mmdbFloat
+)
+
+var mmdbMetadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// Maps and arrays may be nested this deep, so that crafted database
+// cannot exhaust stack
+const mmdbMaxDepth = 32
+
+// Reader of MaxMind DB file, e.g. GeoLite2 Country or City database.
+// Whole file is kept in memory and is never changed, so lookups don't
+// need locking.
+type mmdbReader struct {
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	// Beginning of data section
+	dataStart uint
+	// Node where IPv4 addresses start in IPv6 tree
+	ipv4Start uint
+	// Countries of decoded records, data offset to country index
+	countries sync.Map
+}
+
+// openMMDB reads and checks database file.
+func openMMDB(name string) (*mmdbReader, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	start := bytes.LastIndex(data, mmdbMetadataMarker)
+	if start < 0 {
+		return nil, fmt.Errorf("File %s is not a MaxMind DB database", name)
+	}
+	start += len(mmdbMetadataMarker)
+	r := &mmdbReader{
+		data: data,
+	}
+	v, _, err := r.decode(uint(start), uint(start))
+	if err != nil {
+		return nil, fmt.Errorf("Bad metadata of MaxMind DB database %s: %v", name, err)
+	}
+	metadata, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Bad metadata of MaxMind DB database %s", name)
+	}
+	field := func(name string) uint {
+		n, _ := metadata[name].(uint64)
+		return uint(n)
+	}
+	r.nodeCount = field("node_count")
+	r.recordSize = field("record_size")
+	r.ipVersion = field("ip_version")
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("Unsupported record size %d of MaxMind DB database %s", r.recordSize, name)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, fmt.Errorf("Unsupported IP version %d of MaxMind DB database %s", r.ipVersion, name)
+	}
+	// Search tree is followed by 16 zero bytes
+	r.dataStart = r.nodeCount*r.recordSize/4 + 16
+	if r.dataStart > uint(start) {
+		return nil, fmt.Errorf("Search tree of MaxMind DB database %s is truncated", name)
+	}
+	if r.ipVersion == 6 {
+		for i := 0; i < 96 && r.ipv4Start < r.nodeCount; i++ {
+			r.ipv4Start = r.record(r.ipv4Start, 0)
+		}
+	}
+	return r, nil
+}
+
+// record returns left or right record of search tree node.
+func (r *mmdbReader) record(node, bit uint) uint {
+	switch r.recordSize {
+	case 24:
+		b := r.data[node*6+bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		b := r.data[node*7:]
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	}
+	return uint(binary.BigEndian.Uint32(r.data[node*8+bit*4:]))
+}
+
+// lookup returns data section offset of record of IPv4 or IPv6
+// address in network byte order.
+func (r *mmdbReader) lookup(ip []byte) (uint, bool) {
+	node := uint(0)
+	if len(ip) == 4 {
+		node = r.ipv4Start
+	} else if r.ipVersion == 4 {
+		return 0, false
+	}
+	for i := 0; i < len(ip)*8 && node < r.nodeCount; i++ {
+		node = r.record(node, uint(ip[i/8]>>(7-uint(i%8))&1))
+	}
+	// Records between node count and data section start don't point
+	// anywhere
+	if node < r.nodeCount+16 {
+		return 0, false
+	}
+	offset := node - r.nodeCount - 16
+	if r.dataStart+offset >= uint(len(r.data)) {
+		return 0, false
+	}
+	return offset, true
+}
+
+// decode decodes value at offset and returns it with offset of next
+// value. Pointers are relative to base. Maps are decoded to
+// map[string]interface{}, arrays to []interface{} and unsigned
+// integers to uint64.
+func (r *mmdbReader) decode(offset, base uint) (interface{}, uint, error) {
+	return r.decodeValue(offset, base, 0)
+}
+
+// decodeValue decodes value which is nested into depth maps and
+// arrays.
+func (r *mmdbReader) decodeValue(offset, base uint, depth int) (interface{}, uint, error) {
+	if depth > mmdbMaxDepth {
+		return nil, 0, errors.New("Data is nested too deep")
+	}
+	next := func(n uint) ([]byte, error) {
+		if offset+n > uint(len(r.data)) {
+			return nil, errors.New("Unexpected end of data")
+		}
+		b := r.data[offset : offset+n]
+		offset += n
+		return b, nil
+	}
+	b, err := next(1)
+	if err != nil {
+		return nil, 0, err
+	}
+	ctrl := b[0]
+	kind := uint(ctrl >> 5)
+
+	if kind == mmdbPointer {
+		n := uint(ctrl>>3&3) + 1
+		b, err := next(n)
+		if err != nil {
+			return nil, 0, err
+		}
+		ptr := uint(ctrl & 7)
+		if n == 4 {
+			ptr = 0
+		}
+		for _, c := range b {
+			ptr = ptr<<8 | uint(c)
+		}
+		switch n {
+		case 2:
+			ptr += 2048
+		case 3:
+			ptr += 526336
+		}
+		if base+ptr >= uint(len(r.data)) {
+			return nil, 0, errors.New("Pointer is out of data")
+		}
+		// Pointer may point only to a value, so that pointers can't
+		// make loops
+		if uint(r.data[base+ptr]>>5) == mmdbPointer {
+			return nil, 0, errors.New("Pointer points to pointer")
+		}
+		v, _, err := r.decodeValue(base+ptr, base, depth)
+		return v, offset, err
+	}
+
+	if kind == mmdbExtended {
+		b, err := next(1)
+		if err != nil {
+			return nil, 0, err
+		}
+		kind = 7 + uint(b[0])
+	}
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		b, err := next(size - 28)
+		if err != nil {
+			return nil, 0, err
+		}
+		n := uint(0)
+		for _, c := range b {
+			n = n<<8 | uint(c)
+		}
+		size = []uint{29, 285, 65821}[size-29] + n
+	}
+
+	// Every map entry takes at least two bytes and every array element
+	// at least one, so that size can't make huge allocation
+	remaining := uint(len(r.data)) - offset
+	switch kind {
+	case mmdbMap:
+		if size > remaining/2 {
+			return nil, 0, errors.New("Map size exceeds data")
+		}
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			k, o, err := r.decodeValue(offset, base, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, errors.New("Map key is not a string")
+			}
+			v, o, err := r.decodeValue(o, base, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[key] = v
+			offset = o
+		}
+		return m, offset, nil
+	case mmdbArray:
+		if size > remaining {
+			return nil, 0, errors.New("Array size exceeds data")
+		}
+		a := make([]interface{}, size)
+		for i := range a {
+			v, o, err := r.decodeValue(offset, base, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a[i] = v
+			offset = o
+		}
+		return a, offset, nil
+	case mmdbBoolean:
+		return size != 0, offset, nil
+	case mmdbContainer, mmdbEndMarker:
+		return nil, offset, nil
+	}
+
+	b, err = next(size)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch kind {
+	case mmdbString:
+		return string(b), offset, nil
+	case mmdbBytes:
+		return append([]byte{}, b...), offset, nil
+	case mmdbDouble:
+		if size != 8 {
+			return nil, 0, errors.New("Bad double size")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), offset, nil
+	case mmdbFloat:
+		if size != 4 {
+			return nil, 0, errors.New("Bad float size")
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(b)), offset, nil
+	case mmdbUint16, mmdbUint32, mmdbUint64, mmdbUint128:
+		// Only low 64 bits of 128 bit integers are kept
+		n := uint64(0)
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, offset, nil
+	case mmdbInt32:
+		n := int32(0)
+		for _, c := range b {
+			n = n<<8 | int32(c)
+		}
+		return n, offset, nil
+	}
+	return nil, 0, fmt.Errorf("Unknown data type %d", kind)
+}
+
+// country returns country index of address. Country of registration is
+// used for addresses which have no country, e.g. anycast networks.
+func (r *mmdbReader) country(ip []byte) uint16 {
+	offset, found := r.lookup(ip)
+	if !found {
+		return countryUnknown
+	}
+	if c, ok := r.countries.Load(offset); ok {
+		return c.(uint16)
+	}
+
+	c := uint16(countryUnknown)
+	if v, _, err := r.decode(r.dataStart+offset, r.dataStart); err == nil {
+		rec, _ := v.(map[string]interface{})
+		for _, name := range []string{"country", "registered_country"} {
+			m, _ := rec[name].(map[string]interface{})
+			if code, ok := m["iso_code"].(string); ok {
+				c = countryIndex(code)
+				break
+			}
+		}
+	}
+	r.countries.Store(offset, c)
+	return c
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// nestedArrays returns depth arrays of one element with empty string
+// inside.
+func nestedArrays(depth int) []byte {
+	return append(bytes.Repeat([]byte{0x01, 0x04}, depth), 0x40)
+}
+
+func TestMMDBDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		value interface{}
+		next  uint
+		err   bool
+	}{
+		{"string", []byte{0x42, 'a', 'b'}, "ab", 3, false},
+		{"empty string", []byte{0x40}, "", 1, false},
+		{"uint16", []byte{0xa2, 0x01, 0x02}, uint64(0x0102), 3, false},
+		{"uint32", []byte{0xc1, 0x05}, uint64(5), 2, false},
+		{"int32", []byte{0x04, 0x01, 0xff, 0xff, 0xff, 0xfe}, int32(-2), 6, false},
+		{"boolean", []byte{0x01, 0x07}, true, 2, false},
+		{"map", []byte{0xe1, 0x41, 'k', 0x41, 'v'}, map[string]interface{}{"k": "v"}, 5, false},
+		{"array", []byte{0x02, 0x04, 0x41, 'a', 0xc1, 0x01}, []interface{}{"a", uint64(1)}, 6, false},
+		{"pointer", []byte{0x20, 0x03, 0x00, 0x41, 'p'}, "p", 2, false},
+		{"nested arrays", nestedArrays(mmdbMaxDepth), nil, 0, false},
+		{"truncated string", []byte{0x43, 'a'}, nil, 0, true},
+		{"truncated size", []byte{0x5d}, nil, 0, true},
+		{"pointer out of data", []byte{0x20, 0x10}, nil, 0, true},
+		{"pointer to pointer", []byte{0x20, 0x02, 0x20, 0x04, 0x41, 'x'}, nil, 0, true},
+		{"map key is not string", []byte{0xe1, 0xc1, 0x01, 0x41, 'v'}, nil, 0, true},
+		{"array size exceeds data", []byte{0x1d, 0x04, 0xff}, nil, 0, true},
+		{"map size exceeds data", []byte{0xfe, 0xff, 0xff, 0x41, 'k', 0x41, 'v'}, nil, 0, true},
+		{"too deep nesting", nestedArrays(mmdbMaxDepth + 1), nil, 0, true},
+		{"self referencing map", []byte{0xe1, 0x41, 'k', 0x20, 0x00}, nil, 0, true},
+		{"bad double size", []byte{0x64, 0, 0, 0, 0}, nil, 0, true},
+		{"unknown type", []byte{0x00, 0x09}, nil, 0, true},
+	}
+	for _, tt := range tests {
+		r := &mmdbReader{
+			data: tt.data,
+		}
+		v, next, err := r.decode(0, 0)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: decoded %v, expected error", tt.name, v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if tt.value == nil {
+			continue
+		}
+		if !reflect.DeepEqual(v, tt.value) || next != tt.next {
+			t.Errorf("%s: decoded %#v with next offset %d, expected %#v with %d", tt.name, v, next, tt.value, tt.next)
+		}
+	}
+}
+
+// mmdbTestTree returns reader of IPv4 database with 24 bit records and
+// two nodes. Addresses with first bit set are not found, addresses
+// starting with bits 00 have record left and with bits 01 have record
+// right. Data section has one string.
+func mmdbTestTree(left, right uint) *mmdbReader {
+	r := &mmdbReader{
+		nodeCount:  2,
+		recordSize: 24,
+		ipVersion:  4,
+	}
+	put := func(b []byte, rec uint) {
+		b[0], b[1], b[2] = byte(rec>>16), byte(rec>>8), byte(rec)
+	}
+	tree := make([]byte, 12)
+	put(tree[0:], 1)
+	put(tree[3:], r.nodeCount)
+	put(tree[6:], left)
+	put(tree[9:], right)
+	r.data = append(append(tree, make([]byte, 16)...), 0x41, 'x')
+	r.dataStart = uint(len(tree) + 16)
+	return r
+}
+
+func TestMMDBLookup(t *testing.T) {
+	tests := []struct {
+		name   string
+		record uint
+		offset uint
+		found  bool
+	}{
+		{"first data record", 18, 0, true},
+		{"second byte of data", 19, 1, true},
+		{"node count", 2, 0, false},
+		{"just above node count", 3, 0, false},
+		{"last reserved record", 17, 0, false},
+		{"record after data", 18 + 2, 0, false},
+	}
+	for _, tt := range tests {
+		r := mmdbTestTree(tt.record, 2)
+		offset, found := r.lookup([]byte{0, 0, 0, 0})
+		if found != tt.found || (found && offset != tt.offset) {
+			t.Errorf("%s: lookup returned offset %d found %v, expected offset %d found %v", tt.name, offset, found, tt.offset, tt.found)
+		}
+	}
+
+	r := mmdbTestTree(18, 18)
+	if _, found := r.lookup([]byte{128, 0, 0, 0}); found {
+		t.Errorf("Address with first bit set is found")
+	}
+	if offset, found := r.lookup([]byte{64, 0, 0, 0}); !found || offset != 0 {
+		t.Errorf("Address 64.0.0.0 is not found")
+	}
+	if _, found := r.lookup(make([]byte, 16)); found {
+		t.Errorf("IPv6 address is found in IPv4 database")
+	}
+}
@@ -0,0 +1,488 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+const (
+	igmpNumber = 2
+
+	igmpTypeMembershipQuery    = 0x11
+	igmpTypeV1MembershipReport = 0x12
+	igmpTypeV2MembershipReport = 0x16
+	igmpTypeLeaveGroup         = 0x17
+	igmpTypeV3MembershipReport = 0x22
+
+	// IGMPv3 group record types
+	igmpModeIsInclude       = 1
+	igmpModeIsExclude       = 2
+	igmpChangeToIncludeMode = 3
+	igmpChangeToExcludeMode = 4
+	igmpAllowNewSources     = 5
+
+	igmpMessageLen = 8
+	// IGMPv3 query without sources
+	igmpV3QueryLen = 12
+	// IPv4 header with router alert option
+	igmpIPHeaderLen = types.IPv4MinLen + 4
+
+	// Default timers of RFC 2236
+	igmpQueryInterval       = 125 * time.Second
+	igmpQueryResponseTime   = 10 * time.Second
+	igmpMembershipInterval  = 2*igmpQueryInterval + igmpQueryResponseTime
+	igmpLastMemberQueryTime = 2 * time.Second
+	// Maximum response times in queries in units of 1/10 second
+	igmpQueryResponseCode      = 100
+	igmpLastMemberResponseCode = 10
+	igmpTimerInterval          = time.Second
+	// Robustness variable and query interval code of IGMPv3 and MLDv2
+	// queries
+	querierRobustness   = 2
+	querierIntervalCode = 125
+
+	icmpv6TypeMLDQuery = 130
+	// MLDv2 query without sources
+	mldV2QueryLen = 28
+	// Maximum response delay of MLD queries in milliseconds
+	mldQueryResponseDelay = 10000
+	// Hop-by-hop options header with router alert option
+	ipv6RouterAlertHeaderLen = 8
+)
+
+var (
+	// 224.0.0.1 and 224.0.0.2 in host byte order
+	allSystemsGroup = types.IPv4Address(0xe0000001)
+	allRoutersGroup = types.IPv4Address(0xe0000002)
+)
+
+// Multicast groups forwarded from public to private port. Private
+// hosts join groups with IGMP, NAT forwards only groups which have
+// members on private side.
+type multicastConfig struct {
+	// Group ranges in a form of address/prefix length,
+	// e.g. 239.1.0.0/16
+	Groups []string `json:"groups"`
+	// Send reports to public network on behalf of private hosts
+	IGMPProxy bool `json:"igmp-proxy"`
+	// Version of IGMP queries sent on private port, 2 by default or 3
+	IGMPVersion int `json:"igmp-version"`
+	// Send MLDv2 general queries on private port for snooping
+	// switches
+	MLDQuerier bool `json:"mld-querier"`
+	ranges     []ipv4Subnet
+	// Map of joined groups to time.Time when membership expires
+	members sync.Map
+	// Serializes membership changes
+	mutex sync.Mutex
+}
+
+func (mc *multicastConfig) check() error {
+	for _, g := range mc.Groups {
+		ip, ipnet, err := net.ParseCIDR(g)
+		if err != nil || ip.To4() == nil {
+			return errors.New("Bad multicast group range " + g)
+		}
+		var subnet ipv4Subnet
+		subnet.Addr, _ = convertIPv4(ipnet.IP.To4())
+		subnet.Mask, _ = convertIPv4(ipnet.Mask)
+		if !isIPv4Multicast(subnet.Addr) {
+			return errors.New("Multicast group range " + g + " should be within 224.0.0.0/4")
+		}
+		mc.ranges = append(mc.ranges, subnet)
+	}
+	if mc.IGMPVersion == 0 {
+		mc.IGMPVersion = 2
+	}
+	if mc.IGMPVersion != 2 && mc.IGMPVersion != 3 {
+		return errors.New("IGMP querier version should be 2 or 3")
+	}
+	if mc.MLDQuerier && !mc.enabled() {
+		return errors.New("MLD querier requires multicast groups")
+	}
+	return nil
+}
+
+func (mc *multicastConfig) enabled() bool {
+	return len(mc.ranges) != 0
+}
+
+func isIPv4Multicast(addr types.IPv4Address) bool {
+	return addr&0xf0000000 == 0xe0000000
+}
+
+// configured returns true if group may be forwarded. Link local
+// groups 224.0.0.0/24 are never forwarded.
+func (mc *multicastConfig) configured(group types.IPv4Address) bool {
+	if group&0xffffff00 == 0xe0000000 {
+		return false
+	}
+	for i := range mc.ranges {
+		if mc.ranges[i].checkAddrWithingSubnet(group) {
+			return true
+		}
+	}
+	return false
+}
+
+func (mc *multicastConfig) hasMembers(group types.IPv4Address) bool {
+	v, ok := mc.members.Load(group)
+	return ok && time.Now().Before(v.(time.Time))
+}
+
+func multicastMAC(group types.IPv4Address) types.MACAddress {
+	return types.MACAddress{0x01, 0x00, 0x5e, byte(group>>16) & 0x7f, byte(group >> 8), byte(group)}
+}
+
+// internetChecksum calculates checksum of RFC 1071.
+func internetChecksum(data []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(data[i:]))
+	}
+	if len(data)%2 != 0 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// getIGMPMessage returns IGMP message of a packet or nil if packet is
+// malformed.
+func getIGMPMessage(pkt *packet.Packet, pktVLAN *packet.VLANHdr, pktIPv4 *packet.IPv4Hdr) []byte {
+	l3offset := types.EtherLen
+	if pktVLAN != nil {
+		l3offset += types.VLANLen
+	}
+	hdrLen := int(pktIPv4.VersionIhl&0x0f) << 2
+	totalLen := int(packet.SwapBytesUint16(pktIPv4.TotalLength))
+	raw := pkt.GetRawPacketBytes()
+	if totalLen < hdrLen+igmpMessageLen || l3offset+totalLen > len(raw) {
+		return nil
+	}
+	msg := raw[l3offset+hdrLen : l3offset+totalLen]
+	if internetChecksum(msg) != 0 {
+		return nil
+	}
+	return msg
+}
+
+func igmpGroup(b []byte) types.IPv4Address {
+	group, _ := convertIPv4(b)
+	return group
+}
+
+// handlePublicMulticast forwards multicast packets received on public
+// port to private port and answers queries of upstream router. It
+// returns false if packet should be processed as unicast.
+func (pp *portPair) handlePublicMulticast(pkt *packet.Packet, pktVLAN *packet.VLANHdr, pktIPv4 *packet.IPv4Hdr) (uint, bool) {
+	mc := &pp.Multicast
+	port := &pp.PublicPort
+	group := packet.SwapBytesIPv4Addr(pktIPv4.DstAddr)
+
+	if pktIPv4.NextProtoID == igmpNumber {
+		if msg := getIGMPMessage(pkt, pktVLAN, pktIPv4); msg != nil && msg[0] == igmpTypeMembershipQuery && mc.IGMPProxy {
+			pp.answerIGMPQuery(igmpGroup(msg[4:8]))
+		}
+		port.dumpPacket(pkt, DirDROP)
+		return DirDROP, true
+	}
+
+	if !mc.configured(group) {
+		return DirDROP, false
+	}
+	if !mc.hasMembers(group) || pktIPv4.TimeToLive <= 1 {
+		port.dumpPacket(pkt, DirDROP)
+		return DirDROP, true
+	}
+
+	pktIPv4.TimeToLive--
+	if !NoCalculateChecksum {
+		pktIPv4.HdrChecksum = packet.SwapBytesUint16(packet.CalculateIPv4Checksum(pktIPv4))
+	}
+	pkt.Ether.SAddr = port.opposite.SrcMACAddress
+	if pktVLAN != nil {
+		pktVLAN.SetVLANTagIdentifier(port.opposite.Vlan)
+	}
+	port.opposite.dumpPacket(pkt, DirSEND)
+	return DirSEND, true
+}
+
+// handlePrivateIGMP tracks group membership of private hosts.
+func (pp *portPair) handlePrivateIGMP(pkt *packet.Packet, pktVLAN *packet.VLANHdr, pktIPv4 *packet.IPv4Hdr) {
+	msg := getIGMPMessage(pkt, pktVLAN, pktIPv4)
+	if msg == nil {
+		return
+	}
+
+	switch msg[0] {
+	case igmpTypeV1MembershipReport, igmpTypeV2MembershipReport:
+		pp.joinGroup(igmpGroup(msg[4:8]))
+	case igmpTypeLeaveGroup:
+		pp.leaveGroup(igmpGroup(msg[4:8]))
+	case igmpTypeV3MembershipReport:
+		records := int(binary.BigEndian.Uint16(msg[6:8]))
+		rec := msg[igmpMessageLen:]
+		for i := 0; i < records && len(rec) >= 8; i++ {
+			recType := rec[0]
+			sources := int(binary.BigEndian.Uint16(rec[2:4]))
+			group := igmpGroup(rec[4:8])
+			// Source filters are not supported, host is a member if
+			// it wants to receive any sources of group
+			switch {
+			case recType == igmpModeIsExclude || recType == igmpChangeToExcludeMode:
+				pp.joinGroup(group)
+			case recType == igmpAllowNewSources && sources != 0:
+				pp.joinGroup(group)
+			case (recType == igmpModeIsInclude || recType == igmpChangeToIncludeMode) && sources != 0:
+				pp.joinGroup(group)
+			case (recType == igmpModeIsInclude || recType == igmpChangeToIncludeMode) && sources == 0:
+				pp.leaveGroup(group)
+			}
+			length := 8 + 4*sources + 4*int(rec[1])
+			if length > len(rec) {
+				break
+			}
+			rec = rec[length:]
+		}
+	}
+}
+
+func (pp *portPair) joinGroup(group types.IPv4Address) {
+	mc := &pp.Multicast
+	if !mc.configured(group) {
+		return
+	}
+	mc.mutex.Lock()
+	joined := !mc.hasMembers(group)
+	mc.members.Store(group, time.Now().Add(igmpMembershipInterval))
+	mc.mutex.Unlock()
+	if joined && mc.IGMPProxy {
+		pp.PublicPort.sendIGMP(igmpTypeV2MembershipReport, 0, group, group)
+	}
+}
+
+// leaveGroup asks private hosts whether there are other members of
+// a group. Membership expires soon if nobody answers.
+func (pp *portPair) leaveGroup(group types.IPv4Address) {
+	mc := &pp.Multicast
+	if !mc.configured(group) {
+		return
+	}
+	mc.mutex.Lock()
+	expires := time.Now().Add(igmpLastMemberQueryTime)
+	v, ok := mc.members.Load(group)
+	if ok && v.(time.Time).After(expires) {
+		mc.members.Store(group, expires)
+	}
+	mc.mutex.Unlock()
+	if ok {
+		pp.sendIGMPQuery(igmpLastMemberResponseCode, group, group)
+	}
+}
+
+// answerIGMPQuery reports joined groups to upstream router. Zero
+// group means general query.
+func (pp *portPair) answerIGMPQuery(group types.IPv4Address) {
+	mc := &pp.Multicast
+	if group != 0 {
+		if mc.hasMembers(group) {
+			pp.PublicPort.sendIGMP(igmpTypeV2MembershipReport, 0, group, group)
+		}
+		return
+	}
+	mc.members.Range(func(k, v interface{}) bool {
+		if time.Now().Before(v.(time.Time)) {
+			g := k.(types.IPv4Address)
+			pp.PublicPort.sendIGMP(igmpTypeV2MembershipReport, 0, g, g)
+		}
+		return true
+	})
+}
+
+// expireGroups forgets groups which have no members any more.
+func (pp *portPair) expireGroups() {
+	mc := &pp.Multicast
+	expired := []types.IPv4Address{}
+	mc.mutex.Lock()
+	mc.members.Range(func(k, v interface{}) bool {
+		if !time.Now().Before(v.(time.Time)) {
+			mc.members.Delete(k)
+			expired = append(expired, k.(types.IPv4Address))
+		}
+		return true
+	})
+	mc.mutex.Unlock()
+	if mc.IGMPProxy {
+		for _, g := range expired {
+			pp.PublicPort.sendIGMP(igmpTypeLeaveGroup, 0, g, allRoutersGroup)
+		}
+	}
+}
+
+// sendIGMPQuery sends general or group specific query of configured
+// IGMP version on private port.
+func (pp *portPair) sendIGMPQuery(maxResp uint8, group, dst types.IPv4Address) {
+	msgLen := igmpMessageLen
+	if pp.Multicast.IGMPVersion == 3 {
+		msgLen = igmpV3QueryLen
+	}
+	msg := make([]byte, msgLen)
+	msg[0] = igmpTypeMembershipQuery
+	msg[1] = maxResp
+	binary.BigEndian.PutUint32(msg[4:], uint32(group))
+	if msgLen == igmpV3QueryLen {
+		// Suppress flag is clear, number of sources is zero
+		msg[8] = querierRobustness
+		msg[9] = querierIntervalCode
+	}
+	pp.PrivatePort.sendIGMPMessage(msg, dst)
+}
+
+// sendIGMP sends IGMPv2 message from port address.
+func (port *ipPort) sendIGMP(msgType, maxResp uint8, group, dst types.IPv4Address) {
+	msg := make([]byte, igmpMessageLen)
+	msg[0] = msgType
+	msg[1] = maxResp
+	binary.BigEndian.PutUint32(msg[4:], uint32(group))
+	port.sendIGMPMessage(msg, dst)
+}
+
+// sendIGMPMessage calculates checksum of IGMP message and sends it
+// from port address with router alert option.
+func (port *ipPort) sendIGMPMessage(message []byte, dst types.IPv4Address) {
+	if !port.Subnet.addressAcquired {
+		return
+	}
+
+	pkt, err := packet.NewPacket()
+	if err != nil {
+		common.LogFatal(common.Debug, err)
+	}
+	packet.InitEmptyIPv4Packet(pkt, uint(igmpIPHeaderLen-types.IPv4MinLen+len(message)))
+	pkt.Ether.SAddr = port.SrcMACAddress
+	pkt.Ether.DAddr = multicastMAC(dst)
+
+	ipv4 := pkt.GetIPv4NoCheck()
+	ipv4.VersionIhl = 0x40 | igmpIPHeaderLen>>2
+	ipv4.TypeOfService = 0xc0
+	ipv4.PacketID = 0
+	ipv4.FragmentOffset = 0
+	ipv4.TimeToLive = 1
+	ipv4.NextProtoID = igmpNumber
+	ipv4.SrcAddr = packet.SwapBytesIPv4Addr(port.Subnet.Addr)
+	ipv4.DstAddr = packet.SwapBytesIPv4Addr(dst)
+	ipv4.HdrChecksum = 0
+
+	hdr := pkt.GetRawPacketBytes()[types.EtherLen:]
+	// Router alert option
+	copy(hdr[types.IPv4MinLen:], []byte{0x94, 0x04, 0x00, 0x00})
+	msg := hdr[igmpIPHeaderLen : igmpIPHeaderLen+len(message)]
+	copy(msg, message)
+	binary.BigEndian.PutUint16(msg[2:], 0)
+	binary.BigEndian.PutUint16(msg[2:], internetChecksum(msg))
+	// Header contains option which checksum offloading has to know
+	if !port.hwTXChecksum {
+		binary.BigEndian.PutUint16(hdr[10:], internetChecksum(hdr[:igmpIPHeaderLen]))
+	}
+
+	l2len := uint32(types.EtherLen)
+	if port.Vlan != 0 {
+		pkt.AddVLANTag(port.Vlan)
+		l2len += types.VLANLen
+	}
+	if port.hwTXChecksum {
+		pkt.SetTXIPv4OLFlags(l2len, igmpIPHeaderLen)
+	}
+
+	port.dumpPacket(pkt, DirSEND)
+	port.sendPacket(pkt)
+}
+
+// sendMLDQuery sends MLDv2 general query to all nodes from link local
+// address of port. MLD messages have hop limit 1 and router alert
+// option.
+func (port *ipPort) sendMLDQuery() {
+	if port.isTentative(port.Subnet6.llAddr) {
+		return
+	}
+
+	pkt, err := packet.NewPacket()
+	if err != nil {
+		common.LogFatal(common.Debug, err)
+	}
+	packet.InitEmptyIPv6Packet(pkt, ipv6RouterAlertHeaderLen+mldV2QueryLen)
+	pkt.Ether.SAddr = port.SrcMACAddress
+	pkt.Ether.DAddr = types.MACAddress{0x33, 0x33, 0, 0, 0, 0x01}
+
+	ipv6 := pkt.GetIPv6NoCheck()
+	ipv6.Proto = 0
+	ipv6.HopLimits = 1
+	ipv6.SrcAddr = port.Subnet6.llAddr
+	ipv6.DstAddr = allNodesMulticastAddr
+
+	payload := pkt.GetRawPacketBytes()[types.EtherLen+types.IPv6Len:]
+	// Hop-by-hop header with router alert for MLD and PadN option
+	copy(payload, []byte{types.ICMPv6Number, 0, 0x05, 0x02, 0x00, 0x00, 0x01, 0x00})
+	msg := payload[ipv6RouterAlertHeaderLen : ipv6RouterAlertHeaderLen+mldV2QueryLen]
+	msg[0] = icmpv6TypeMLDQuery
+	binary.BigEndian.PutUint16(msg[4:], mldQueryResponseDelay)
+	// Multicast address is unspecified, suppress flag is clear and
+	// number of sources is zero
+	msg[24] = querierRobustness
+	msg[25] = querierIntervalCode
+
+	// Checksum covers IPv6 pseudo header of ICMPv6 message
+	pseudo := make([]byte, 0, 40+mldV2QueryLen)
+	pseudo = append(pseudo, ipv6.SrcAddr[:]...)
+	pseudo = append(pseudo, ipv6.DstAddr[:]...)
+	pseudo = append(pseudo, 0, 0, 0, mldV2QueryLen, 0, 0, 0, types.ICMPv6Number)
+	pseudo = append(pseudo, msg...)
+	binary.BigEndian.PutUint16(msg[2:], internetChecksum(pseudo))
+
+	if port.Vlan != 0 {
+		pkt.AddVLANTag(port.Vlan)
+	}
+
+	port.dumpPacket(pkt, DirSEND)
+	port.sendPacket(pkt)
+}
+
+// StartMulticast starts IGMP querier on private ports and expiration
+// of group memberships for port pairs which forward multicast.
+func StartMulticast() {
+	for i := range Natconfig.PortPairs {
+		pp := &Natconfig.PortPairs[i]
+		if pp.Multicast.enabled() {
+			go pp.runIGMPTimers()
+		}
+	}
+}
+
+func (pp *portPair) runIGMPTimers() {
+	lastQuery := time.Time{}
+	for {
+		if pp.PrivatePort.Subnet.addressAcquired && time.Since(lastQuery) >= igmpQueryInterval {
+			pp.sendIGMPQuery(igmpQueryResponseCode, 0, allSystemsGroup)
+			if pp.Multicast.MLDQuerier {
+				pp.PrivatePort.sendMLDQuery()
+			}
+			lastQuery = time.Now()
+		}
+		pp.expireGroups()
+		time.Sleep(igmpTimerInterval)
+	}
+}
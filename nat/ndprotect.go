@@ -0,0 +1,156 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+const (
+	// Incomplete entries of ports when maximum is not configured
+	defaultMaxIncompleteNeighbors = 1024
+	// Neighbor which doesn't answer solicitations for this long is
+	// given up, it is three retransmissions of RFC 4861
+	incompleteNeighborTimeout = 3 * time.Second
+)
+
+// Protection of IPv6 neighbor cache against packets to many
+// addresses which don't exist, e.g. scans of large prefixes. Every
+// packet to an unknown neighbor creates an incomplete entry until
+// neighbor answers solicitation or entry times out. New incomplete
+// entries are limited by rate per port and by their number, while
+// solicitations of already incomplete entries and packets to known
+// neighbors are not limited by protection. Zero rate disables it.
+type ndProtectionConfig struct {
+	Rate          float64 `json:"rate"`
+	Burst         int     `json:"burst"`
+	MaxIncomplete int     `json:"max-incomplete"`
+}
+
+// Incomplete neighbor entries of port. Entries are created by packet
+// handlers running on several cores, so they are protected by a
+// mutex. Only packets to unknown neighbors take it.
+type incompleteNeighbors struct {
+	// Incomplete entries which were created, which were refused
+	// because of rate or number limits and which timed out
+	created uint64
+	refused uint64
+	expired uint64
+	mutex   sync.Mutex
+	// Time of first solicitation by address
+	entries map[types.IPv6Address]time.Time
+	bucket  tokenBucket
+	// Number of entries, read without lock when neighbors are learned
+	count int32
+}
+
+func (cfg *ndProtectionConfig) enabled() bool {
+	return cfg.Rate != 0
+}
+
+func (cfg *ndProtectionConfig) check() error {
+	if cfg.Rate < 0 || cfg.Burst < 0 || cfg.MaxIncomplete < 0 {
+		return errors.New("Values of nd-protection should not be negative")
+	}
+	return nil
+}
+
+func (cfg *ndProtectionConfig) maxIncomplete() int {
+	if cfg.MaxIncomplete == 0 {
+		return defaultMaxIncompleteNeighbors
+	}
+	return cfg.MaxIncomplete
+}
+
+// initNDProtection enables tracking of incomplete neighbor entries on
+// port if protection is configured and port uses IPv6 neighbor
+// discovery.
+func (port *ipPort) initNDProtection() {
+	cfg := &Natconfig.NDProtection
+	if !cfg.enabled() || port.ipv6Disabled || port.staticArpMode {
+		return
+	}
+	port.incomplete = &incompleteNeighbors{
+		entries: map[types.IPv6Address]time.Time{},
+		bucket: tokenBucket{
+			tokens: burstSize(cfg.Rate, cfg.Burst),
+			last:   time.Now(),
+		},
+	}
+}
+
+// expire removes entries which timed out. Caller holds mutex.
+func (in *incompleteNeighbors) expire(now time.Time) {
+	for ip, created := range in.entries {
+		if now.Sub(created) > incompleteNeighborTimeout {
+			delete(in.entries, ip)
+			atomic.AddUint64(&in.expired, 1)
+		}
+	}
+	atomic.StoreInt32(&in.count, int32(len(in.entries)))
+}
+
+// allowSolicitation returns true if NAT may solicit unknown neighbor
+// for a packet. Neighbors which are already incomplete are solicited
+// again, new incomplete entries are created within limits.
+func (port *ipPort) allowSolicitation(ip types.IPv6Address) bool {
+	in := port.incomplete
+	if in == nil {
+		return true
+	}
+	cfg := &Natconfig.NDProtection
+	now := time.Now()
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+
+	if created, found := in.entries[ip]; found {
+		if now.Sub(created) <= incompleteNeighborTimeout {
+			return true
+		}
+		delete(in.entries, ip)
+		atomic.AddUint64(&in.expired, 1)
+	}
+	if len(in.entries) >= cfg.maxIncomplete() {
+		in.expire(now)
+	}
+	if len(in.entries) >= cfg.maxIncomplete() || !in.bucket.take(now, cfg.Rate, burstSize(cfg.Rate, cfg.Burst)) {
+		atomic.AddUint64(&in.refused, 1)
+		return false
+	}
+	in.entries[ip] = now
+	atomic.AddUint64(&in.created, 1)
+	atomic.StoreInt32(&in.count, int32(len(in.entries)))
+	return true
+}
+
+// completeNeighbor removes incomplete entry of neighbor which
+// answered.
+func (port *ipPort) completeNeighbor(ip interface{}) {
+	in := port.incomplete
+	addr, ok := ip.(types.IPv6Address)
+	if in == nil || !ok || atomic.LoadInt32(&in.count) == 0 {
+		return
+	}
+	in.mutex.Lock()
+	delete(in.entries, addr)
+	atomic.StoreInt32(&in.count, int32(len(in.entries)))
+	in.mutex.Unlock()
+}
+
+// ndProtectionCounters returns number of incomplete entries which
+// didn't time out and numbers of created, refused and expired ones.
+func (port *ipPort) ndProtectionCounters() (uint64, uint64, uint64, uint64) {
+	in := port.incomplete
+	in.mutex.Lock()
+	in.expire(time.Now())
+	count := len(in.entries)
+	in.mutex.Unlock()
+	return uint64(count), atomic.LoadUint64(&in.created), atomic.LoadUint64(&in.refused), atomic.LoadUint64(&in.expired)
+}
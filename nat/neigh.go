@@ -12,18 +12,27 @@ import (
 
 func (port *ipPort) handleIPv6NeighborDiscovery(pkt *packet.Packet) uint {
 	icmp := pkt.GetICMPNoCheck()
+	if isNeighborDiscovery(icmp.Type) && !port.allowControlPlane(controlPlaneND, pkt.GetIPv6NoCheck().SrcAddr) {
+		return DirDROP
+	}
 	if icmp.Type == types.ICMPv6NeighborSolicitation {
+		pkt.ParseL7(types.ICMPv6Number)
+		msg := pkt.GetICMPv6NeighborSolicitationMessage()
+		dad := pkt.GetIPv6NoCheck().SrcAddr == zeroIPv6Addr
+		if dad {
+			port.handleDADSolicitation(pkt, msg.TargetAddr)
+		}
 		// If there is KNI interface, forward all of this here
 		if port.KNIName != "" {
 			return DirKNI
 		}
-		pkt.ParseL7(types.ICMPv6Number)
-		msg := pkt.GetICMPv6NeighborSolicitationMessage()
-		if msg.TargetAddr != port.Subnet6.Addr && msg.TargetAddr != port.Subnet6.llAddr {
+		// Tentative addresses are not answered
+		if dad || !port.ownsIPv6Address(msg.TargetAddr) {
 			return DirDROP
 		}
 		option := pkt.GetICMPv6NDSourceLinkLayerAddressOption(packet.ICMPv6NeighborSolicitationMessageSize)
-		if option != nil && option.Type == packet.ICMPv6NDSourceLinkLayerAddress {
+		if option != nil && option.Type == packet.ICMPv6NDSourceLinkLayerAddress &&
+			neighborLimiter.allow(port, pkt.GetIPv6NoCheck().SrcAddr) {
 			answerPacket, err := packet.NewPacket()
 			if err != nil {
 				common.LogFatal(common.Debug, err)
@@ -36,16 +45,34 @@ func (port *ipPort) handleIPv6NeighborDiscovery(pkt *packet.Packet) uint {
 				answerPacket.AddVLANTag(packet.SwapBytesUint16(vlan.TCI))
 			}
 
-			setIPv6ICMPChecksum(answerPacket, !NoCalculateChecksum, !NoHWTXChecksum)
+			setIPv6ICMPChecksum(answerPacket, !NoCalculateChecksum, port.hwTXChecksum)
 			port.dumpPacket(answerPacket, DirSEND)
-			answerPacket.SendPacket(port.Index)
+			port.sendPacket(answerPacket)
+		}
+	} else if icmp.Type == icmpv6RouterAdvertisement || icmp.Type == icmpv6Redirect {
+		if !port.RouterDiscovery.Enable {
+			return DirSEND
+		}
+		if icmp.Type == icmpv6RouterAdvertisement {
+			port.handleRouterAdvertisement(pkt)
+		} else {
+			port.handleRedirect(pkt)
+		}
+		if port.KNIName != "" {
+			return DirKNI
 		}
 	} else if icmp.Type == types.ICMPv6NeighborAdvertisement {
 		pkt.ParseL7(types.ICMPv6Number)
 		msg := pkt.GetICMPv6NeighborAdvertisementMessage()
 		option := pkt.GetICMPv6NDTargetLinkLayerAddressOption(packet.ICMPv6NeighborAdvertisementMessageSize)
-		if option != nil && option.Type == packet.ICMPv6NDTargetLinkLayerAddress {
-			port.arpTable.Store(msg.TargetAddr, option.LinkLayerAddress)
+		mac := pkt.Ether.SAddr
+		hasOption := option != nil && option.Type == packet.ICMPv6NDTargetLinkLayerAddress
+		if hasOption {
+			mac = option.LinkLayerAddress
+		}
+		// Advertisement of port own address means address conflict
+		if !port.handleAddressAdvertisement(msg.TargetAddr, mac) && hasOption {
+			port.storeNeighbor(msg.TargetAddr, mac)
 		}
 
 		if port.KNIName != "" {
@@ -62,29 +89,44 @@ func (port *ipPort) getMACForIPv6(ip types.IPv6Address) (types.MACAddress, bool)
 	if port.staticArpMode {
 		return port.DstMACAddress, true
 	} else {
-		v, found := port.arpTable.Load(ip)
+		// Off link destinations are sent to default router
+		if port.RouterDiscovery.Enable {
+			ip = port.nextHopIPv6(ip)
+		}
+		mac, found := port.loadNeighbor(ip)
 		if found {
-			return v.(types.MACAddress), true
+			return mac, true
+		}
+		if port.allowSolicitation(ip) && port.allowResolution(ip) {
+			port.sendNDNeighborSolicitationRequest(ip)
 		}
-		port.sendNDNeighborSolicitationRequest(ip)
 		return types.MACAddress{}, false
 	}
 }
 
 func (port *ipPort) sendNDNeighborSolicitationRequest(ip types.IPv6Address) {
+	if !neighborLimiter.allow(port, ip) {
+		return
+	}
+	port.startResolutionSpan(ip)
 	requestPacket, err := packet.NewPacket()
 	if err != nil {
 		common.LogFatal(common.Debug, err)
 	}
 
+	// Link local neighbors are solicited from link local address
+	src := port.Subnet6.Addr
+	if isIPv6LinkLocal(ip) {
+		src = port.Subnet6.llAddr
+	}
 	packet.InitICMPv6NeighborSolicitationPacket(requestPacket, port.SrcMACAddress,
-		port.Subnet6.Addr, ip)
+		src, ip)
 
 	if port.Vlan != 0 {
 		requestPacket.AddVLANTag(port.Vlan)
 	}
 
-	setIPv6ICMPChecksum(requestPacket, !NoCalculateChecksum, !NoHWTXChecksum)
+	setIPv6ICMPChecksum(requestPacket, !NoCalculateChecksum, port.hwTXChecksum)
 	port.dumpPacket(requestPacket, DirSEND)
-	requestPacket.SendPacket(port.Index)
+	port.sendPacket(requestPacket)
 }
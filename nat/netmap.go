@@ -0,0 +1,211 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// Static translation of private IPv4 prefix to public prefix of the
+// same size. Host part of address is kept, ports are not changed and
+// no sessions are created.
+type netmapRule struct {
+	Private ipv4Subnet `json:"private"`
+	Public  ipv4Subnet `json:"public"`
+}
+
+func prefixesOverlap(a, b *ipv4Subnet) bool {
+	mask := a.Mask & b.Mask
+	return a.Addr&mask == b.Addr&mask
+}
+
+// checkNetmap checks that netmap prefixes are static, have equal
+// sizes and don't overlap.
+func (pp *portPair) checkNetmap() error {
+	for i := range pp.Netmap {
+		rule := &pp.Netmap[i]
+		if !rule.Private.addressAcquired || !rule.Public.addressAcquired {
+			return fmt.Errorf("Netmap rule %d prefixes should not use DHCP", i)
+		}
+		if rule.Private.Mask != rule.Public.Mask {
+			return fmt.Errorf("Netmap rule %d prefixes %s and %s should have the same size", i, rule.Private.String(), rule.Public.String())
+		}
+		if rule.Public.checkAddrWithingSubnet(pp.PublicPort.Subnet.Addr) {
+			return fmt.Errorf("Netmap rule %d public prefix %s includes public port address", i, rule.Public.String())
+		}
+		for j := range pp.PublicPort.VLANs {
+			if rule.Public.checkAddrWithingSubnet(pp.PublicPort.VLANs[j].Subnet.Addr) {
+				return fmt.Errorf("Netmap rule %d public prefix %s includes address of VLAN subinterface", i, rule.Public.String())
+			}
+		}
+		for j := 0; j < i; j++ {
+			if prefixesOverlap(&rule.Private, &pp.Netmap[j].Private) || prefixesOverlap(&rule.Public, &pp.Netmap[j].Public) {
+				return fmt.Errorf("Netmap rules %d and %d overlap", j, i)
+			}
+		}
+	}
+	pp.PublicPort.netmap = pp.Netmap
+	return nil
+}
+
+// translate maps address from one prefix of rule to another.
+func (rule *netmapRule) translate(addr types.IPv4Address, inbound bool) types.IPv4Address {
+	to := &rule.Public
+	if inbound {
+		to = &rule.Private
+	}
+	return to.Addr&to.Mask | addr&^to.Mask
+}
+
+// findNetmapRule returns rule which public or private prefix includes
+// address or nil.
+func (port *ipPort) findNetmapRule(addr types.IPv4Address, public bool) *netmapRule {
+	for i := range port.netmap {
+		rule := &port.netmap[i]
+		if public && rule.Public.checkAddrWithingSubnet(addr) ||
+			!public && rule.Private.checkAddrWithingSubnet(addr) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// handleNetmap translates IPv4 packet which address belongs to netmap
+// rule. It returns false if no rule matches packet.
+func (pp *portPair) handleNetmap(port *ipPort, pkt *packet.Packet, pktVLAN *packet.VLANHdr, pktIPv4 *packet.IPv4Hdr) (uint, bool) {
+	inbound := port.Type == iPUBLIC
+	src := packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr)
+	dst := packet.SwapBytesIPv4Addr(pktIPv4.DstAddr)
+	var rule *netmapRule
+	if inbound {
+		rule = pp.PublicPort.findNetmapRule(dst, true)
+	} else if dst != port.Subnet.Addr && dst != BroadcastIPv4 && !isIPv4Multicast(dst) {
+		rule = pp.PublicPort.findNetmapRule(src, false)
+	}
+	if rule == nil {
+		return 0, false
+	}
+	icmp := pkt.GetICMPForIPv4()
+	answer := !isIPv4LaterFragment(pktIPv4) && (icmp == nil || !isICMPError(types.ICMPNumber, icmp.Type))
+	if !pp.forwardHopLimit(port, pkt, pktIPv4, nil, answer) {
+		return DirDROP, true
+	}
+
+	var host, oldAddr, newAddr types.IPv4Address
+	var mac types.MACAddress
+	var found bool
+	if inbound {
+		oldAddr, newAddr = dst, rule.translate(dst, true)
+		host = newAddr
+		mac, found = port.opposite.getMACForIPv4(newAddr)
+	} else {
+		oldAddr, newAddr = src, rule.translate(src, false)
+		host = src
+		mac, found = port.opposite.getMACForIPv4(dst)
+	}
+	if !found {
+		port.dumpPacket(pkt, DirDROP)
+		return DirDROP, true
+	}
+	if pp.policingActive(!inbound) && !pp.policeHost(host, !inbound, pkt.GetPacketLen()) {
+		port.dumpPacket(pkt, DirDROP)
+		return DirDROP, true
+	}
+	pp.countTraffic(pktIPv4.NextProtoID, !inbound, pkt.GetPacketLen(), pktIPv4, nil, host, types.IPv6Address{})
+
+	pkt.Ether.DAddr = mac
+	pkt.Ether.SAddr = port.opposite.SrcMACAddress
+	if pktVLAN != nil {
+		pktVLAN.SetVLANTagIdentifier(port.opposite.Vlan)
+	}
+	if inbound {
+		pktIPv4.DstAddr = packet.SwapBytesIPv4Addr(newAddr)
+		pp.DSCP.Ingress.apply(pktIPv4, nil)
+	} else {
+		pktIPv4.SrcAddr = packet.SwapBytesIPv4Addr(newAddr)
+		pp.DSCP.Egress.apply(pktIPv4, nil)
+	}
+	if translateNetmapError(pkt, pktIPv4, icmp, rule, inbound) {
+		setIPv4ICMPChecksum(pkt, !NoCalculateChecksum, port.opposite.hwTXChecksum)
+	} else {
+		updateNetmapL4Checksum(pkt, pktIPv4, oldAddr, newAddr)
+		setIPv4HdrChecksum(pkt, !NoCalculateChecksum, port.opposite.hwTXChecksum)
+	}
+
+	port.opposite.dumpPacket(pkt, DirSEND)
+	return DirSEND, true
+}
+
+// translateNetmapError translates packet quoted by ICMP error which
+// outer address is translated by rule. Inbound error quotes packet
+// sent by private host, so its quoted source is changed to private
+// address. Outbound error quotes packet received by private host, so
+// its quoted destination is changed to public address. It returns
+// false if packet is not an ICMP error or its quoted packet is not
+// translated.
+func translateNetmapError(pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, icmp *packet.ICMPHdr, rule *netmapRule, inbound bool) bool {
+	// ICMP checksum of fragment cannot be calculated
+	if icmp == nil || isIPv4Fragment(pktIPv4) || !isICMPError(types.ICMPNumber, icmp.Type) {
+		return false
+	}
+	payload, ok := pkt.GetPacketPayload()
+	if !ok {
+		return false
+	}
+	q, ok := parseQuotedPacket(payload, false)
+	if !ok {
+		return false
+	}
+	return rule.translateQuoted(q, inbound)
+}
+
+// translateQuoted changes quoted address of ICMP error if it belongs to
+// rule.
+func (rule *netmapRule) translateQuoted(q *quotedPacket, inbound bool) bool {
+	addr := types.IPv4Address(binary.BigEndian.Uint32(q.data[q.addrOffset(inbound):]))
+	if inbound && !rule.Public.checkAddrWithingSubnet(addr) || !inbound && !rule.Private.checkAddrWithingSubnet(addr) {
+		return false
+	}
+	q.translateAddr(inbound, rule.translate(addr, inbound), types.IPv6Address{})
+	return true
+}
+
+// updateNetmapL4Checksum updates TCP, UDP, UDP-Lite or DCCP checksum after address
+// change. Pseudo header checksum covers all fragments, so only first
+// fragment which has transport header is changed.
+func updateNetmapL4Checksum(pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, oldAddr, newAddr types.IPv4Address) {
+	if NoCalculateChecksum || isIPv4LaterFragment(pktIPv4) {
+		return
+	}
+	var cksum *uint16
+	pkt.ParseL4ForIPv4()
+	switch pktIPv4.NextProtoID {
+	case types.TCPNumber:
+		cksum = &pkt.GetTCPNoCheck().Cksum
+	case types.UDPNumber:
+		// Zero UDP checksum means that datagram has no checksum
+		if pktUDP := pkt.GetUDPNoCheck(); pktUDP.DgramCksum != 0 {
+			cksum = &pktUDP.DgramCksum
+		}
+	case udpLiteNumber, dccpNumber:
+		// Their checksums are at the same offset as UDP checksum and
+		// always cover pseudo header
+		cksum = &pkt.GetUDPNoCheck().DgramCksum
+	}
+	if cksum == nil {
+		return
+	}
+	c := packet.SwapBytesUint16(*cksum)
+	c = updateChecksum(c, uint16(oldAddr>>16), uint16(newAddr>>16))
+	c = updateChecksum(c, uint16(oldAddr), uint16(newAddr))
+	if (pktIPv4.NextProtoID == types.UDPNumber || pktIPv4.NextProtoID == udpLiteNumber) && c == 0 {
+		c = 0xffff
+	}
+	*cksum = packet.SwapBytesUint16(c)
+}
@@ -0,0 +1,64 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+func TestNetmapQuotedTranslation(t *testing.T) {
+	mask := hostIPv4(255, 255, 255, 0)
+	rule := &netmapRule{
+		Private: ipv4Subnet{Addr: hostIPv4(192, 168, 1, 0), Mask: mask, addressAcquired: true},
+		Public:  ipv4Subnet{Addr: hostIPv4(198, 51, 100, 0), Mask: mask, addressAcquired: true},
+	}
+	private := []byte{192, 168, 1, 10}
+	public := []byte{198, 51, 100, 10}
+	other := []byte{198, 51, 101, 10}
+
+	tests := []struct {
+		name     string
+		datagram testDatagram
+		inbound  bool
+		// Addresses of quoted packet before and after translation
+		src, dst       []byte
+		newSrc, newDst []byte
+		translated     bool
+	}{
+		{"inbound error about UDP", testDatagram{protocol: types.UDPNumber, body: []byte{1, 2}}, true,
+			public, testRemote4, private, testRemote4, true},
+		{"inbound error about TCP", testDatagram{protocol: types.TCPNumber}, true,
+			public, testRemote4, private, testRemote4, true},
+		{"inbound error about echo request", testDatagram{protocol: types.ICMPNumber, icmpType: types.ICMPTypeEchoRequest}, true,
+			public, testRemote4, private, testRemote4, true},
+		{"outbound error about TCP", testDatagram{protocol: types.TCPNumber, body: []byte("data")}, false,
+			testRemote4, private, testRemote4, public, true},
+		{"outbound error about timestamp request", testDatagram{protocol: types.ICMPNumber, icmpType: icmpTypeTimestampRequest,
+			body: make([]byte, 12)}, false, testRemote4, private, testRemote4, public, true},
+		{"quoted source outside of public prefix", testDatagram{protocol: types.UDPNumber}, true,
+			other, testRemote4, other, testRemote4, false},
+		{"quoted destination outside of private prefix", testDatagram{protocol: types.UDPNumber}, false,
+			testRemote4, public, testRemote4, public, false},
+	}
+	for _, tt := range tests {
+		d := &tt.datagram
+		data := d.build(tt.src, tt.dst, 5000, 53)
+		expected := d.build(tt.newSrc, tt.newDst, 5000, 53)
+		q, ok := parseQuotedPacket(data, false)
+		if !ok {
+			t.Errorf("%s: quoted packet is not parsed", tt.name)
+			continue
+		}
+		if got := rule.translateQuoted(q, tt.inbound); got != tt.translated {
+			t.Errorf("%s: translateQuoted returned %v, expected %v", tt.name, got, tt.translated)
+		}
+		if !bytes.Equal(data, expected) {
+			t.Errorf("%s: translated quoted packet is\n%x\nexpected\n%x", tt.name, data, expected)
+		}
+	}
+}
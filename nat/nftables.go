@@ -0,0 +1,328 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+// Rendering of static translations and filtering rules of port pairs
+// as nftables ruleset which Linux NAT would need for the same
+// decisions, e.g. to validate migration between Linux NAT and this
+// NAT or to reuse audit tools. Ruleset is only an output, NAT never
+// reads it. Every port pair is a table of inet family. Interfaces are
+// named after KNI interfaces of ports, ports without KNI interface
+// are named portN. Settings which nftables cannot express, e.g. port
+// triggers or domain names of blackhole rules, are written as
+// comments.
+
+type nftablesWriter struct {
+	b strings.Builder
+}
+
+// line writes line with indent level.
+func (w *nftablesWriter) line(indent int, format string, args ...interface{}) {
+	w.b.WriteString(strings.Repeat("\t", indent))
+	fmt.Fprintf(&w.b, format, args...)
+	w.b.WriteByte('\n')
+}
+
+// rule writes rule of chain.
+func (w *nftablesWriter) rule(format string, args ...interface{}) {
+	w.line(2, format, args...)
+}
+
+func (w *nftablesWriter) beginChain(name, hook string) {
+	w.line(1, "chain %s {", name)
+	w.rule("%s; policy accept;", hook)
+}
+
+func (w *nftablesWriter) endChain() {
+	w.line(1, "}")
+}
+
+// nftablesInterface returns name of port in ruleset.
+func nftablesInterface(port *ipPort) string {
+	if port.KNIName != "" {
+		return strconv.Quote(port.KNIName)
+	}
+	return strconv.Quote("port" + strconv.Itoa(int(port.Index)))
+}
+
+// nftablesProtocol returns nftables name of IP protocol.
+func nftablesProtocol(protocol uint8) string {
+	switch protocol {
+	case types.TCPNumber:
+		return "tcp"
+	case types.UDPNumber:
+		return "udp"
+	case udpLiteNumber:
+		return "udplite"
+	case dccpNumber:
+		return "dccp"
+	case types.ICMPNumber:
+		return "icmp"
+	case types.ICMPv6Number:
+		return "ipv6-icmp"
+	case igmpNumber:
+		return "igmp"
+	}
+	return strconv.Itoa(int(protocol))
+}
+
+// nftablesPrefix4 returns network prefix of subnet.
+func nftablesPrefix4(subnet *ipv4Subnet) string {
+	network := *subnet
+	network.Addr &= network.Mask
+	network.addressAcquired = true
+	return network.String()
+}
+
+// nftablesPrefix6 returns network prefix of subnet.
+func nftablesPrefix6(subnet *ipv6Subnet) string {
+	ones, _ := net.IPMask(subnet.Mask[:]).Size()
+	return nftablesAddress6(subnet.andMask(subnet.Addr)) + "/" + strconv.Itoa(ones)
+}
+
+// forwardsToKNIHost returns true if forwarded port is sent to KNI
+// interface without translation.
+func forwardsToKNIHost(fp *forwardedPort) bool {
+	if fp.Destination.ipv6 {
+		return fp.Destination.Addr6 == zeroIPv6Addr
+	}
+	return fp.Destination.Addr4 == 0
+}
+
+func nftablesAddress6(addr types.IPv6Address) string {
+	return net.IP(addr[:]).String()
+}
+
+// nftablesRuleset returns ruleset of port pairs.
+func nftablesRuleset(pairs []*portPair) string {
+	w := &nftablesWriter{}
+	w.line(0, "# Static translations and filtering rules of nff-go-nat in nftables notation.")
+	w.line(0, "# Dynamic sessions are shown as source NAT of private subnets.")
+	for _, pp := range pairs {
+		pp.mutex.Lock()
+		pp.writeNftables(w)
+		pp.mutex.Unlock()
+	}
+	return w.b.String()
+}
+
+// writeNftables writes table of port pair. Caller holds port pair
+// mutex.
+func (pp *portPair) writeNftables(w *nftablesWriter) {
+	w.line(0, "")
+	if pp.Tenant != "" {
+		w.line(0, "# Port pair of tenant %s", pp.Tenant)
+	}
+	w.line(0, "table inet nff_go_nat_%d {", pp.PublicPort.Index)
+
+	w.beginChain("prerouting", "type nat hook prerouting priority dstnat")
+	pp.writeNftablesForwards(w)
+	pp.writeNftablesNetmap(w, true)
+	pp.writeNftablesDMZ(w)
+	w.endChain()
+
+	w.beginChain("postrouting", "type nat hook postrouting priority srcnat")
+	pp.writeNftablesNetmap(w, false)
+	pp.writeNftablesSNAT(w)
+	w.endChain()
+
+	w.beginChain("forward", "type filter hook forward priority filter")
+	pp.writeNftablesBlackhole(w)
+	w.endChain()
+
+	w.beginChain("input", "type filter hook input priority filter")
+	pp.writeNftablesInput(w)
+	w.endChain()
+
+	for _, rule := range pp.portTriggers() {
+		w.line(1, "# Port trigger %s %s opens %s ports %v, nftables has no equivalent",
+			rule.Protocol, rule.Ports, rule.OpenProtocol, rule.OpenPorts)
+	}
+	w.line(0, "}")
+}
+
+// writeNftablesForwards writes destination NAT of forwarded ports of
+// public port. Source specific destinations precede destination of
+// forwarded port.
+func (pp *portPair) writeNftablesForwards(w *nftablesWriter) {
+	port := &pp.PublicPort
+	iif := nftablesInterface(port)
+	for i := range port.ForwardPorts {
+		fp := &port.ForwardPorts[i]
+		if forwardsToKNIHost(fp) {
+			// Packets go to KNI host unchanged, see input chain
+			continue
+		}
+		proto := nftablesProtocol(fp.Protocol.id)
+		if fp.Protocol.ipv6 {
+			if !port.Subnet6.addressAcquired {
+				continue
+			}
+			match := fmt.Sprintf("iifname %s ip6 daddr %s %s dport %d", iif, nftablesAddress6(port.Subnet6.Addr), proto, fp.Port)
+			for _, src := range fp.Sources {
+				w.rule("%s ip6 saddr %s dnat ip6 to [%s]:%d", match, nftablesPrefix6(&src.subnet6),
+					nftablesAddress6(src.Destination.Addr6), src.Destination.Port)
+			}
+			w.rule("%s dnat ip6 to [%s]:%d", match, nftablesAddress6(fp.Destination.Addr6), fp.Destination.Port)
+		} else {
+			if !port.Subnet.addressAcquired {
+				continue
+			}
+			match := fmt.Sprintf("iifname %s ip daddr %s %s dport %d", iif, StringIPv4Int(uint32(port.Subnet.Addr)), proto, fp.Port)
+			for _, src := range fp.Sources {
+				w.rule("%s ip saddr %s dnat ip to %s", match, nftablesPrefix4(&src.subnet), src.Destination.String())
+			}
+			w.rule("%s dnat ip to %s", match, fp.Destination.String())
+		}
+	}
+}
+
+// writeNftablesNetmap writes prefix translations of netmap rules in
+// destination NAT if inbound is true and in source NAT otherwise.
+func (pp *portPair) writeNftablesNetmap(w *nftablesWriter, inbound bool) {
+	for i := range pp.Netmap {
+		rule := &pp.Netmap[i]
+		if inbound {
+			w.rule("iifname %s ip daddr %s dnat ip prefix to %s", nftablesInterface(&pp.PublicPort),
+				nftablesPrefix4(&rule.Public), nftablesPrefix4(&rule.Private))
+		} else {
+			w.rule("oifname %s ip saddr %s snat ip prefix to %s", nftablesInterface(&pp.PublicPort),
+				nftablesPrefix4(&rule.Private), nftablesPrefix4(&rule.Public))
+		}
+	}
+}
+
+// writeNftablesDMZ writes destination NAT of public address to DMZ
+// hosts after all forwarded ports.
+func (pp *portPair) writeNftablesDMZ(w *nftablesWriter) {
+	port := &pp.PublicPort
+	iif := nftablesInterface(port)
+	if pp.dmz.hasAddr4 && port.Subnet.addressAcquired {
+		w.rule("iifname %s ip daddr %s dnat ip to %s", iif,
+			StringIPv4Int(uint32(port.Subnet.Addr)), StringIPv4Int(uint32(pp.dmz.addr4)))
+	}
+	if pp.dmz.hasAddr6 && port.Subnet6.addressAcquired {
+		w.rule("iifname %s ip6 daddr %s dnat ip6 to %s", iif,
+			nftablesAddress6(port.Subnet6.Addr), nftablesAddress6(pp.dmz.addr6))
+	}
+}
+
+// writeNftablesSNAT writes source NAT of dynamic sessions. Private
+// hosts of VLAN subinterfaces use addresses of their subinterfaces.
+func (pp *portPair) writeNftablesSNAT(w *nftablesWriter) {
+	pub, priv := &pp.PublicPort, &pp.PrivatePort
+	oif := nftablesInterface(pub)
+	ports := fmt.Sprintf("%d-%d", portStart, portEnd-1)
+	if len(pp.LocalPorts) != 0 {
+		w.rule("# Ports %v are reserved in local-ports and are not allocated to sessions", pp.LocalPorts)
+	}
+	if !pp.DisableIPv4 && pub.Subnet.addressAcquired && priv.Subnet.addressAcquired {
+		// Host of private KNI interface has its own ports
+		if pp.kniSNAT != nil {
+			spans := pp.KNISourceNAT.Ports
+			if len(spans) > 1 {
+				w.rule("# KNI source NAT uses ports %v, nftables snat takes one range", spans)
+			}
+			w.rule("oifname %s ip saddr %s snat ip to %s:%s", oif, StringIPv4Int(uint32(priv.Subnet.Addr)),
+				StringIPv4Int(uint32(pub.publicAddressForHost(priv.Subnet.Addr))), spans[0])
+		}
+		for i := range pub.VLANs {
+			vlan := &pub.VLANs[i]
+			for j := range vlan.PrivateSubnets {
+				w.rule("oifname %s ip saddr %s snat ip to %s:%s", oif, nftablesPrefix4(&vlan.PrivateSubnets[j]),
+					StringIPv4Int(uint32(vlan.Subnet.Addr)), ports)
+			}
+		}
+		w.rule("oifname %s ip saddr %s snat ip to %s:%s", oif, nftablesPrefix4(&priv.Subnet),
+			StringIPv4Int(uint32(pub.Subnet.Addr)), ports)
+	}
+	if !pp.DisableIPv6 && pub.Subnet6.addressAcquired && priv.Subnet6.addressAcquired {
+		w.rule("oifname %s ip6 saddr %s snat ip6 to [%s]:%s", oif, nftablesPrefix6(&priv.Subnet6),
+			nftablesAddress6(pub.Subnet6.Addr), ports)
+	}
+}
+
+// writeNftablesBlackhole writes current blackhole rules of port pair.
+// Domain names are written with addresses of their last resolution.
+func (pp *portPair) writeNftablesBlackhole(w *nftablesWriter) {
+	iif := nftablesInterface(&pp.PrivatePort)
+	for _, rule := range pp.blackholeRules() {
+		if rule.domain {
+			w.rule("# Addresses of %s", rule.Destination)
+		}
+		if rule.Action == blackholeKNI {
+			w.rule("# Traffic to %s is sent to KNI interface %s of private port", rule.Destination, iif)
+			continue
+		}
+		prefixes := rule.prefixes.Load().(*blackholePrefixes)
+		for i := range prefixes.v4 {
+			w.rule("iifname %s ip daddr %s drop", iif, nftablesPrefix4(&prefixes.v4[i]))
+		}
+		for i := range prefixes.v6 {
+			w.rule("iifname %s ip6 daddr %s drop", iif, nftablesPrefix6(&prefixes.v6[i]))
+		}
+	}
+}
+
+// writeNftablesInput writes fate of packets which are not translated
+// and are addressed to ports: forwarded ports of KNI host, KNI
+// steering rules and unsolicited inbound packets of public port
+// without KNI interface.
+func (pp *portPair) writeNftablesInput(w *nftablesWriter) {
+	for _, port := range []*ipPort{&pp.PublicPort, &pp.PrivatePort} {
+		iif := nftablesInterface(port)
+		for i := range port.ForwardPorts {
+			fp := &port.ForwardPorts[i]
+			if forwardsToKNIHost(fp) {
+				family := "ipv4"
+				if fp.Protocol.ipv6 {
+					family = "ipv6"
+				}
+				w.rule("iifname %s meta nfproto %s %s dport %d accept", iif, family, nftablesProtocol(fp.Protocol.id), fp.Port)
+			}
+		}
+		for _, rule := range port.KNISteering {
+			match := "meta l4proto " + nftablesProtocol(uint8(rule.Protocol))
+			if len(rule.Ports) != 0 {
+				spans := []string{}
+				for _, span := range rule.Ports {
+					spans = append(spans, span.String())
+				}
+				match += " th dport { " + strings.Join(spans, ", ") + " }"
+			}
+			if rule.Action == kniSteeringDrop {
+				pp.writeNftablesUnsolicited(w, "iifname "+iif+" "+match)
+			} else {
+				w.rule("iifname %s %s accept", iif, match)
+			}
+		}
+	}
+
+	if pp.PublicPort.KNIName == "" {
+		pp.writeNftablesUnsolicited(w, "iifname "+nftablesInterface(&pp.PublicPort)+" ct state new")
+	}
+}
+
+// writeNftablesUnsolicited writes rules which drop or answer packets
+// matched by match according to unsolicited inbound policy.
+func (pp *portPair) writeNftablesUnsolicited(w *nftablesWriter, match string) {
+	switch pp.UnsolicitedInbound.Action {
+	case unsolicitedReset:
+		w.rule("%s meta l4proto tcp reject with tcp reset", match)
+		w.rule("%s meta l4proto udp reject with icmpx type port-unreachable", match)
+	case unsolicitedUnreachable:
+		w.rule("%s meta l4proto { tcp, udp } reject with icmpx type port-unreachable", match)
+	}
+	w.rule("%s drop", match)
+}
@@ -0,0 +1,169 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+// hostIPv4 returns address in byte order of config, see convertIPv4.
+func hostIPv4(a, b, c, d byte) types.IPv4Address {
+	return types.BytesToIPv4(d, c, b, a)
+}
+
+func TestNftablesNotation(t *testing.T) {
+	protocols := []struct {
+		protocol uint8
+		name     string
+	}{
+		{types.TCPNumber, "tcp"},
+		{types.UDPNumber, "udp"},
+		{udpLiteNumber, "udplite"},
+		{dccpNumber, "dccp"},
+		{types.ICMPNumber, "icmp"},
+		{types.ICMPv6Number, "ipv6-icmp"},
+		{igmpNumber, "igmp"},
+		{47, "47"},
+	}
+	for _, tt := range protocols {
+		if got := nftablesProtocol(tt.protocol); got != tt.name {
+			t.Errorf("Protocol %d is %q, expected %q", tt.protocol, got, tt.name)
+		}
+	}
+
+	subnet := ipv4Subnet{
+		Addr: hostIPv4(10, 0, 0, 5),
+		Mask: hostIPv4(255, 255, 255, 0),
+	}
+	if got := nftablesPrefix4(&subnet); got != "10.0.0.0/24" {
+		t.Errorf("Prefix of 10.0.0.5/24 is %s, expected 10.0.0.0/24", got)
+	}
+	subnet6 := ipv6Subnet{
+		Addr: types.IPv6Address{0x20, 0x01, 0x0d, 0xb8, 15: 1},
+		Mask: types.IPv6Address{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+	if got := nftablesPrefix6(&subnet6); got != "2001:db8::/64" {
+		t.Errorf("Prefix of 2001:db8::1/64 is %s, expected 2001:db8::/64", got)
+	}
+
+	if got := nftablesInterface(&ipPort{Index: 3}); got != `"port3"` {
+		t.Errorf("Port without KNI interface is %s, expected \"port3\"", got)
+	}
+	if got := nftablesInterface(&ipPort{Index: 3, KNIName: "pub"}); got != `"pub"` {
+		t.Errorf("Port with KNI interface is %s, expected \"pub\"", got)
+	}
+}
+
+func TestNftablesRuleset(t *testing.T) {
+	mask := hostIPv4(255, 255, 255, 0)
+	pp := &portPair{
+		Tenant:      "blue",
+		DisableIPv6: true,
+		PublicPort: ipPort{
+			Index: 1,
+			Subnet: ipv4Subnet{
+				Addr:            hostIPv4(192, 0, 2, 1),
+				Mask:            mask,
+				addressAcquired: true,
+			},
+			ForwardPorts: []forwardedPort{
+				{
+					Port:        8080,
+					Destination: hostPort{Addr4: hostIPv4(10, 0, 0, 5), Port: 80},
+					Protocol:    protocolId{id: types.TCPNumber},
+					Sources: []forwardedSource{
+						{
+							Destination: hostPort{Addr4: hostIPv4(10, 0, 0, 6), Port: 80},
+							subnet: ipv4Subnet{
+								Addr:            hostIPv4(198, 51, 100, 0),
+								Mask:            mask,
+								addressAcquired: true,
+							},
+						},
+					},
+				},
+				// Sent to KNI host
+				{
+					Port:        53,
+					Destination: hostPort{Port: 53},
+					Protocol:    protocolId{id: types.UDPNumber},
+				},
+				// Public port has no IPv6 address
+				{
+					Port:        22,
+					Destination: hostPort{Addr6: types.IPv6Address{0xfd, 15: 5}, Port: 22, ipv6: true},
+					Protocol:    protocolId{id: types.TCPNumber, ipv6: true},
+				},
+			},
+		},
+		PrivatePort: ipPort{
+			Index:   0,
+			KNIName: "priv",
+			Subnet: ipv4Subnet{
+				Addr:            hostIPv4(10, 0, 0, 1),
+				Mask:            mask,
+				addressAcquired: true,
+			},
+		},
+		Netmap: []netmapRule{
+			{
+				Private: ipv4Subnet{Addr: hostIPv4(10, 1, 0, 0), Mask: mask, addressAcquired: true},
+				Public:  ipv4Subnet{Addr: hostIPv4(203, 0, 113, 0), Mask: mask, addressAcquired: true},
+			},
+		},
+		UnsolicitedInbound: unsolicitedPolicy{
+			Action: unsolicitedReset,
+		},
+	}
+	pp.dmz.addr4 = hostIPv4(10, 0, 0, 9)
+	pp.dmz.hasAddr4 = true
+
+	expected := []string{
+		"# Static translations and filtering rules of nff-go-nat in nftables notation.",
+		"# Dynamic sessions are shown as source NAT of private subnets.",
+		"",
+		"# Port pair of tenant blue",
+		"table inet nff_go_nat_1 {",
+		"\tchain prerouting {",
+		"\t\ttype nat hook prerouting priority dstnat; policy accept;",
+		"\t\tiifname \"port1\" ip daddr 192.0.2.1 tcp dport 8080 ip saddr 198.51.100.0/24 dnat ip to 10.0.0.6:80",
+		"\t\tiifname \"port1\" ip daddr 192.0.2.1 tcp dport 8080 dnat ip to 10.0.0.5:80",
+		"\t\tiifname \"port1\" ip daddr 203.0.113.0/24 dnat ip prefix to 10.1.0.0/24",
+		"\t\tiifname \"port1\" ip daddr 192.0.2.1 dnat ip to 10.0.0.9",
+		"\t}",
+		"\tchain postrouting {",
+		"\t\ttype nat hook postrouting priority srcnat; policy accept;",
+		"\t\toifname \"port1\" ip saddr 10.1.0.0/24 snat ip prefix to 203.0.113.0/24",
+		"\t\toifname \"port1\" ip saddr 10.0.0.0/24 snat ip to 192.0.2.1:1024-65499",
+		"\t}",
+		"\tchain forward {",
+		"\t\ttype filter hook forward priority filter; policy accept;",
+		"\t}",
+		"\tchain input {",
+		"\t\ttype filter hook input priority filter; policy accept;",
+		"\t\tiifname \"port1\" meta nfproto ipv4 udp dport 53 accept",
+		"\t\tiifname \"port1\" ct state new meta l4proto tcp reject with tcp reset",
+		"\t\tiifname \"port1\" ct state new meta l4proto udp reject with icmpx type port-unreachable",
+		"\t\tiifname \"port1\" ct state new drop",
+		"\t}",
+		"}",
+	}
+	got := strings.Split(strings.TrimSuffix(nftablesRuleset([]*portPair{pp}), "\n"), "\n")
+	for i := 0; i < len(got) || i < len(expected); i++ {
+		var g, e string
+		if i < len(got) {
+			g = got[i]
+		}
+		if i < len(expected) {
+			e = expected[i]
+		}
+		if g != e {
+			t.Errorf("Line %d is %q, expected %q", i+1, g, e)
+		}
+	}
+}
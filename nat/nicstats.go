@@ -0,0 +1,86 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+/*
+#include <rte_ethdev.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Error counters from basic statistics of network card port which
+// are gathered by DPDK driver independently of NAT packet counters.
+type nicStats struct {
+	// Packets dropped by card because there were no free receive
+	// descriptors
+	rxMissed uint64
+	rxErrors uint64
+	txErrors uint64
+	// Receive mbuf allocation failures
+	rxNoMbuf uint64
+}
+
+// Named counter of network card.
+type nicCounter struct {
+	name  string
+	value uint64
+}
+
+func getNICStats(port uint16) (nicStats, error) {
+	var stats C.struct_rte_eth_stats
+	if rc := C.rte_eth_stats_get(C.uint16_t(port), &stats); rc != 0 {
+		return nicStats{}, fmt.Errorf("Failed to get statistics of port %d, error %d", port, int(rc))
+	}
+	return nicStats{
+		rxMissed: uint64(stats.imissed),
+		rxErrors: uint64(stats.ierrors),
+		txErrors: uint64(stats.oerrors),
+		rxNoMbuf: uint64(stats.rx_nombuf),
+	}, nil
+}
+
+// getNICXStats returns extended statistics of network card port
+// sorted by name. Set of counters depends on driver, it usually
+// includes per queue counters, missed packets and mbuf allocation
+// failures.
+func getNICXStats(port uint16) ([]nicCounter, error) {
+	n := C.rte_eth_xstats_get_names(C.uint16_t(port), nil, 0)
+	if n < 0 {
+		return nil, fmt.Errorf("Failed to get extended statistics of port %d, error %d", port, int(n))
+	}
+	if n == 0 {
+		return []nicCounter{}, nil
+	}
+
+	names := make([]C.struct_rte_eth_xstat_name, n)
+	if rc := C.rte_eth_xstats_get_names(C.uint16_t(port), &names[0], C.uint(n)); rc != n {
+		return nil, fmt.Errorf("Failed to get extended statistics names of port %d, error %d", port, int(rc))
+	}
+	values := make([]C.struct_rte_eth_xstat, n)
+	got := C.rte_eth_xstats_get(C.uint16_t(port), &values[0], C.uint(n))
+	if got < 0 || got > n {
+		return nil, fmt.Errorf("Failed to get extended statistics of port %d, error %d", port, int(got))
+	}
+
+	result := make([]nicCounter, 0, int(got))
+	for i := 0; i < int(got); i++ {
+		id := values[i].id
+		if id >= C.uint64_t(n) {
+			continue
+		}
+		result = append(result, nicCounter{
+			name:  C.GoString(&names[id].name[0]),
+			value: uint64(values[i].value),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].name < result[j].name
+	})
+	return result, nil
+}
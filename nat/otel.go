@@ -0,0 +1,553 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/types"
+)
+
+const (
+	// Spans and metrics are exported this often when interval is not
+	// configured, in seconds
+	defaultOTelInterval = 10
+	otelSpanQueueSize   = 1024
+	otelTimeout         = 5 * time.Second
+	otelScopeName       = "github.com/intel-go/nff-go-nat"
+	// Neighbor resolution without answer ends with error after this
+	// time
+	otelResolutionTimeout = 3 * time.Second
+	// Span kinds and status codes of OTLP
+	otelSpanKindInternal = 1
+	otelSpanKindServer   = 2
+	otelStatusOK         = 1
+	otelStatusError      = 2
+	// Cumulative aggregation temporality of OTLP sums
+	otelCumulative = 2
+)
+
+// Export of control plane spans and datapath counters to OpenTelemetry
+// collector. Data is sent with OTLP over HTTP in JSON encoding, so no
+// OpenTelemetry SDK is needed.
+type otelConfig struct {
+	// Base URL of OTLP HTTP receiver, e.g. http://collector:4318.
+	// Paths /v1/traces and /v1/metrics are appended to it.
+	Endpoint string            `json:"endpoint"`
+	Headers  map[string]string `json:"headers"`
+	// Service name resource attribute, host-name is used by default
+	ServiceName string `json:"service-name"`
+	// Seconds between exports, zero means default
+	Interval int `json:"interval"`
+}
+
+// Finished or running span. Spans are created by control plane
+// handlers and finished spans are queued for export.
+type otelSpan struct {
+	traceID    [16]byte
+	spanID     [8]byte
+	name       string
+	kind       int
+	start      time.Time
+	end        time.Time
+	attributes map[string]interface{}
+	err        error
+}
+
+// Running DHCP transaction of port and address family
+type otelDHCPKey struct {
+	port uint16
+	ipv6 bool
+}
+
+// Running neighbor resolution of port
+type otelResolutionKey struct {
+	port uint16
+	addr interface{}
+}
+
+var (
+	otelSpans = make(chan *otelSpan, otelSpanQueueSize)
+	// Running spans by transaction, *otelSpan values
+	otelDHCPSpans       sync.Map
+	otelResolutionSpans sync.Map
+	// Serializes removal of running spans, so that every span is
+	// finished once
+	otelTakeMutex sync.Mutex
+	// Random identifiers of spans, rand.Rand is not safe for
+	// concurrent use
+	otelRandMutex sync.Mutex
+	otelRand      = rand.New(rand.NewSource(time.Now().UnixNano()))
+	// Start of cumulative counters
+	otelStartTime = time.Now()
+)
+
+func (cfg *otelConfig) enabled() bool {
+	return cfg.Endpoint != ""
+}
+
+func (cfg *otelConfig) check() error {
+	if !cfg.enabled() {
+		return nil
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return fmt.Errorf("Bad opentelemetry endpoint \"%s\": %+v", cfg.Endpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("Opentelemetry endpoint \"%s\" should use http or https scheme", cfg.Endpoint)
+	}
+	if cfg.Interval < 0 {
+		return errors.New("Opentelemetry interval should not be negative")
+	}
+	return nil
+}
+
+func (cfg *otelConfig) interval() time.Duration {
+	if cfg.Interval == 0 {
+		return defaultOTelInterval * time.Second
+	}
+	return time.Duration(cfg.Interval) * time.Second
+}
+
+func (cfg *otelConfig) serviceName() string {
+	if cfg.ServiceName != "" {
+		return cfg.ServiceName
+	}
+	if Natconfig.HostName != "" {
+		return Natconfig.HostName
+	}
+	return "nff-go-nat"
+}
+
+// startSpan returns new span, nil when export is disabled. Port is
+// optional.
+func startSpan(name string, kind int, port *ipPort) *otelSpan {
+	if !Natconfig.OpenTelemetry.enabled() {
+		return nil
+	}
+	s := &otelSpan{
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: map[string]interface{}{},
+	}
+	otelRandMutex.Lock()
+	otelRand.Read(s.traceID[:])
+	otelRand.Read(s.spanID[:])
+	otelRandMutex.Unlock()
+	if port != nil {
+		s.attributes["nat.port"] = int(port.Index)
+		if port.tenant != "" {
+			s.attributes["nat.tenant"] = port.tenant
+		}
+	}
+	return s
+}
+
+// finish ends span and queues it for export. Function never blocks,
+// spans are dropped if queue is full.
+func (s *otelSpan) finish(err error) {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	s.err = err
+	select {
+	case otelSpans <- s:
+	default:
+		common.LogWarning(common.No, "OpenTelemetry span queue is full, dropping span", s.name)
+	}
+}
+
+// takeSpan removes running span from table and returns it, nil if
+// there is none. Lookup is done without lock first because neighbor
+// tables are updated by datapath.
+func takeSpan(m *sync.Map, key interface{}) *otelSpan {
+	if _, found := m.Load(key); !found {
+		return nil
+	}
+	otelTakeMutex.Lock()
+	defer otelTakeMutex.Unlock()
+	v, found := m.Load(key)
+	if !found {
+		return nil
+	}
+	m.Delete(key)
+	return v.(*otelSpan)
+}
+
+// startDHCPSpan starts span of DHCP transaction of port unless one is
+// running already, so that retransmissions belong to the same span.
+func (port *ipPort) startDHCPSpan(ipv6 bool, request string) {
+	if !Natconfig.OpenTelemetry.enabled() {
+		return
+	}
+	name := "dhcp.transaction"
+	if ipv6 {
+		name = "dhcpv6.transaction"
+	}
+	s := startSpan(name, otelSpanKindInternal, port)
+	s.attributes["dhcp.request"] = request
+	otelDHCPSpans.LoadOrStore(otelDHCPKey{port.Index, ipv6}, s)
+}
+
+// finishDHCPSpan ends running DHCP transaction of port with acquired
+// address or with error.
+func (port *ipPort) finishDHCPSpan(ipv6 bool, address string, err error) {
+	if !Natconfig.OpenTelemetry.enabled() {
+		return
+	}
+	s := takeSpan(&otelDHCPSpans, otelDHCPKey{port.Index, ipv6})
+	if s == nil {
+		return
+	}
+	if address != "" {
+		s.attributes["dhcp.address"] = address
+	}
+	s.finish(err)
+}
+
+// startResolutionSpan starts span of ARP or ND resolution of address
+// unless it is being resolved already.
+func (port *ipPort) startResolutionSpan(addr interface{}) {
+	if !Natconfig.OpenTelemetry.enabled() {
+		return
+	}
+	key := otelResolutionKey{port.Index, addr}
+	if _, found := otelResolutionSpans.Load(key); found {
+		return
+	}
+	name := "arp.resolution"
+	if _, ok := addr.(types.IPv6Address); ok {
+		name = "nd.resolution"
+	}
+	s := startSpan(name, otelSpanKindInternal, port)
+	s.attributes["net.peer.ip"] = otelAddress(addr)
+	otelResolutionSpans.LoadOrStore(key, s)
+}
+
+// finishResolutionSpan ends resolution of address when its neighbor
+// entry is learned.
+func (port *ipPort) finishResolutionSpan(addr interface{}, mac types.MACAddress) {
+	if !Natconfig.OpenTelemetry.enabled() {
+		return
+	}
+	s := takeSpan(&otelResolutionSpans, otelResolutionKey{port.Index, addr})
+	if s == nil {
+		return
+	}
+	s.attributes["net.peer.mac"] = mac.String()
+	s.finish(nil)
+}
+
+// expireResolutionSpans ends resolutions which got no answer.
+func expireResolutionSpans(now time.Time) {
+	otelResolutionSpans.Range(func(k, v interface{}) bool {
+		if now.Sub(v.(*otelSpan).start) >= otelResolutionTimeout {
+			takeSpan(&otelResolutionSpans, k).finish(errors.New("No answer"))
+		}
+		return true
+	})
+}
+
+func otelAddress(addr interface{}) string {
+	switch a := addr.(type) {
+	case types.IPv4Address:
+		return StringIPv4Int(uint32(a))
+	case types.IPv6Address:
+		return net.IP(a[:]).String()
+	}
+	return fmt.Sprint(addr)
+}
+
+// StartOpenTelemetry starts exporting spans and counters to
+// OpenTelemetry collector if it is configured.
+func StartOpenTelemetry() {
+	cfg := &Natconfig.OpenTelemetry
+	if !cfg.enabled() {
+		return
+	}
+	go cfg.run()
+}
+
+func (cfg *otelConfig) run() {
+	client := &http.Client{
+		Timeout: otelTimeout,
+	}
+	ticker := time.NewTicker(cfg.interval())
+	spans := []*otelSpan{}
+	for {
+		select {
+		case s := <-otelSpans:
+			spans = append(spans, s)
+			if len(spans) < otelSpanQueueSize {
+				continue
+			}
+		case now := <-ticker.C:
+			expireResolutionSpans(now)
+			cfg.post(client, "/v1/metrics", cfg.metrics(now))
+		}
+		if len(spans) != 0 {
+			cfg.post(client, "/v1/traces", cfg.traces(spans))
+			spans = []*otelSpan{}
+		}
+	}
+}
+
+func (cfg *otelConfig) post(client *http.Client, path string, doc interface{}) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		common.LogWarning(common.No, "Failed to encode OpenTelemetry data:", err)
+		return
+	}
+	u := strings.TrimSuffix(cfg.Endpoint, "/") + path
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(data))
+	if err != nil {
+		common.LogWarning(common.No, "Failed to create OpenTelemetry request", u, ":", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		common.LogWarning(common.No, "Failed to send OpenTelemetry data to", u, ":", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		common.LogWarning(common.No, "OpenTelemetry collector", u, "returned status", resp.Status)
+	}
+}
+
+// OTLP JSON encoding. Identifiers are hexadecimal, 64 bit integers
+// are decimal strings.
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func otlpAttributes(attrs map[string]interface{}) []otlpKeyValue {
+	result := make([]otlpKeyValue, 0, len(attrs))
+	for k, v := range attrs {
+		var value map[string]interface{}
+		switch a := v.(type) {
+		case int:
+			value = map[string]interface{}{"intValue": strconv.Itoa(a)}
+		case bool:
+			value = map[string]interface{}{"boolValue": a}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(a)}
+		}
+		result = append(result, otlpKeyValue{k, value})
+	}
+	return result
+}
+
+func otlpTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func (cfg *otelConfig) resource() map[string]interface{} {
+	return map[string]interface{}{
+		"attributes": otlpAttributes(map[string]interface{}{
+			"service.name": cfg.serviceName(),
+		}),
+	}
+}
+
+func (cfg *otelConfig) traces(spans []*otelSpan) interface{} {
+	encoded := make([]map[string]interface{}, len(spans))
+	for i, s := range spans {
+		status := map[string]interface{}{"code": otelStatusOK}
+		if s.err != nil {
+			status = map[string]interface{}{"code": otelStatusError, "message": s.err.Error()}
+		}
+		encoded[i] = map[string]interface{}{
+			"traceId":           hex.EncodeToString(s.traceID[:]),
+			"spanId":            hex.EncodeToString(s.spanID[:]),
+			"name":              s.name,
+			"kind":              s.kind,
+			"startTimeUnixNano": otlpTime(s.start),
+			"endTimeUnixNano":   otlpTime(s.end),
+			"attributes":        otlpAttributes(s.attributes),
+			"status":            status,
+		}
+	}
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": cfg.resource(),
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": otelScopeName},
+						"spans": encoded,
+					},
+				},
+			},
+		},
+	}
+}
+
+// metrics returns port counters as cumulative sums, port pool
+// utilization as gauge and session lifetimes as histograms.
+func (cfg *otelConfig) metrics(now time.Time) interface{} {
+	type point struct {
+		attrs map[string]interface{}
+		value uint64
+	}
+	names := []string{"nat.port.rx.packets", "nat.port.rx.bytes", "nat.port.tx.packets", "nat.port.tx.bytes",
+		"nat.port.kni.packets", "nat.port.drop.packets"}
+	sums := make([][]point, len(names))
+	utilization := []point{}
+	for i := range Natconfig.PortPairs {
+		pp := &Natconfig.PortPairs[i]
+		for _, port := range []*ipPort{&pp.PublicPort, &pp.PrivatePort} {
+			attrs := map[string]interface{}{
+				"nat.port": int(port.Index),
+			}
+			if port.tenant != "" {
+				attrs["nat.tenant"] = port.tenant
+			}
+			stats := port.getStats()
+			for j, v := range []uint64{stats.rxPackets, stats.rxBytes, stats.txPackets, stats.txBytes,
+				stats.kniPackets, stats.dropPackets} {
+				sums[j] = append(sums[j], point{attrs, v})
+			}
+		}
+		attrs := map[string]interface{}{
+			"nat.port": int(pp.PublicPort.Index),
+		}
+		utilization = append(utilization, point{attrs, uint64(pp.portPoolUtilization())})
+	}
+
+	dataPoints := func(points []point, start bool) []interface{} {
+		result := make([]interface{}, len(points))
+		for i, p := range points {
+			dp := map[string]interface{}{
+				"attributes":   otlpAttributes(p.attrs),
+				"timeUnixNano": otlpTime(now),
+				"asInt":        strconv.FormatUint(p.value, 10),
+			}
+			if start {
+				dp["startTimeUnixNano"] = otlpTime(otelStartTime)
+			}
+			result[i] = dp
+		}
+		return result
+	}
+	metrics := []interface{}{}
+	for i, name := range names {
+		unit := "{packet}"
+		if strings.HasSuffix(name, ".bytes") {
+			unit = "By"
+		}
+		metrics = append(metrics, map[string]interface{}{
+			"name": name,
+			"unit": unit,
+			"sum": map[string]interface{}{
+				"aggregationTemporality": otelCumulative,
+				"isMonotonic":            true,
+				"dataPoints":             dataPoints(sums[i], true),
+			},
+		})
+	}
+	metrics = append(metrics, map[string]interface{}{
+		"name": "nat.port_pool.utilization",
+		"unit": "%",
+		"gauge": map[string]interface{}{
+			"dataPoints": dataPoints(utilization, false),
+		},
+	})
+	if Natconfig.SessionAging.enabled() {
+		metrics = append(metrics, sessionLifetimeMetrics(now)...)
+	}
+	return map[string]interface{}{
+		"resourceMetrics": []interface{}{
+			map[string]interface{}{
+				"resource": cfg.resource(),
+				"scopeMetrics": []interface{}{
+					map[string]interface{}{
+						"scope":   map[string]interface{}{"name": otelScopeName},
+						"metrics": metrics,
+					},
+				},
+			},
+		},
+	}
+}
+
+// sessionLifetimeMetrics returns lifetime histograms of port pairs
+// with public port and protocol attributes.
+func sessionLifetimeMetrics(now time.Time) []interface{} {
+	bounds := make([]float64, len(Natconfig.SessionAging.Buckets))
+	for i, b := range Natconfig.SessionAging.Buckets {
+		bounds[i] = float64(b)
+	}
+	durations, idle := []interface{}{}, []interface{}{}
+	dataPoints := func(points []interface{}, attrs map[string]interface{}, h *lifetimeHistogram) []interface{} {
+		counts := make([]string, len(h.counts))
+		for i, c := range h.counts {
+			counts[i] = strconv.FormatUint(c, 10)
+		}
+		return append(points, map[string]interface{}{
+			"attributes":        otlpAttributes(attrs),
+			"startTimeUnixNano": otlpTime(otelStartTime),
+			"timeUnixNano":      otlpTime(now),
+			"count":             strconv.FormatUint(h.count, 10),
+			"sum":               h.sum.Seconds(),
+			"bucketCounts":      counts,
+			"explicitBounds":    bounds,
+		})
+	}
+	for i := range Natconfig.PortPairs {
+		pp := &Natconfig.PortPairs[i]
+		d, idl := pp.sessionLifetimes()
+		for pi, protocol := range sessionAgingProtocols {
+			if !pp.hasAgingHistogram(pi) {
+				continue
+			}
+			attrs := map[string]interface{}{
+				"nat.port":     int(pp.PublicPort.Index),
+				"nat.protocol": protocol.name,
+			}
+			if pp.Tenant != "" {
+				attrs["nat.tenant"] = pp.Tenant
+			}
+			durations = dataPoints(durations, attrs, &d[pi])
+			idle = dataPoints(idle, attrs, &idl[pi])
+		}
+	}
+	histogram := func(name string, points []interface{}) interface{} {
+		return map[string]interface{}{
+			"name": name,
+			"unit": "s",
+			"histogram": map[string]interface{}{
+				"aggregationTemporality": otelCumulative,
+				"dataPoints":             points,
+			},
+		}
+	}
+	return []interface{}{
+		histogram("nat.session.duration", durations),
+		histogram("nat.session.idle_expired", idle),
+	}
+}
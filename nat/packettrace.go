@@ -0,0 +1,701 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// Packet which verdict is traced, e.g. to check what NAT would do
+// with traffic of a customer. Packet is described by its addresses and
+// ports instead of being a real packet, and tracing walks stages of
+// translation handlers in their order. Stages take their decisions
+// from the same functions as handlers but with lookups only. Tracing
+// never creates sessions, changes counters or neighbor tables and
+// nothing is sent. Checks which depend on packet rates, e.g. policing
+// and flood mitigation, are reported as not evaluated.
+type tracedPacket struct {
+	protocol uint8
+	ipv6     bool
+	// Tuple or Tuple6 of source and destination, port of ICMP echo
+	// is its identifier
+	src, dst interface{}
+	// TCP SYN without ACK
+	newConnection bool
+	// IPv4 TTL or IPv6 hop limit
+	hopLimit uint8
+	// IPv4 header has options or IPv6 packet is jumbogram
+	ipOptions bool
+}
+
+// Decision of one stage of packet handling.
+type traceStep struct {
+	stage  string
+	result string
+}
+
+type packetTrace struct {
+	steps   []traceStep
+	verdict uint
+	// Port which sends packet, which KNI interface receives it or
+	// which drops it
+	output *ipPort
+	// Packet after translation
+	out tracedPacket
+}
+
+var traceVerdictLookup = [DirKNI + 1]string{
+	"drop",
+	"send",
+	"kni",
+}
+
+func (t *packetTrace) step(stage, format string, args ...interface{}) {
+	t.steps = append(t.steps, traceStep{
+		stage:  stage,
+		result: fmt.Sprintf(format, args...),
+	})
+}
+
+func (t *packetTrace) finish(verdict uint, port *ipPort) *packetTrace {
+	t.verdict = verdict
+	t.output = port
+	return t
+}
+
+// headers returns IP headers with addresses and protocol of packet for
+// checks which take headers of real packets.
+func (p *tracedPacket) headers() (*packet.IPv4Hdr, *packet.IPv6Hdr) {
+	if p.ipv6 {
+		return nil, &packet.IPv6Hdr{
+			SrcAddr:   p.src.(Tuple6).addr,
+			DstAddr:   p.dst.(Tuple6).addr,
+			Proto:     p.protocol,
+			HopLimits: p.hopLimit,
+		}
+	}
+	return &packet.IPv4Hdr{
+		SrcAddr:     packet.SwapBytesIPv4Addr(p.src.(Tuple).addr),
+		DstAddr:     packet.SwapBytesIPv4Addr(p.dst.(Tuple).addr),
+		NextProtoID: p.protocol,
+		TimeToLive:  p.hopLimit,
+	}, nil
+}
+
+func traceTuple(t interface{}) string {
+	switch tuple := t.(type) {
+	case Tuple:
+		return fmt.Sprintf("%s:%d", StringIPv4Int(uint32(tuple.addr)), tuple.port)
+	case Tuple6:
+		return fmt.Sprintf("[%s]:%d", tuple.addr.String(), tuple.port)
+	}
+	return "none"
+}
+
+// countryDenied returns true if remote host of packet is denied by
+// country policy of port pair.
+func (pp *portPair) countryDenied(p *tracedPacket, egress bool) bool {
+	if !pp.Countries.restricted {
+		return false
+	}
+	hdr4, hdr6 := p.headers()
+	return pp.Countries.denied[remoteCountry(hdr4, hdr6, egress)]
+}
+
+// traceUnsolicited finishes trace of packet which is dropped because
+// it matches no session, answer of unsolicited inbound policy is
+// reported.
+func (pp *portPair) traceUnsolicited(t *packetTrace, port *ipPort) *packetTrace {
+	if t.out.protocol == types.TCPNumber || t.out.protocol == types.UDPNumber {
+		if pp.maintenanceRejects() {
+			t.step("unsolicited-inbound", "Port pair is in maintenance, packet is answered with TCP reset or ICMP port unreachable")
+		} else if pp.UnsolicitedInbound.Action != unsolicitedDrop {
+			t.step("unsolicited-inbound", "Packet is answered according to %s policy", pp.UnsolicitedInbound.Action)
+		}
+	}
+	return t.finish(DirDROP, port)
+}
+
+// traceRefused finishes trace of packet which would start new session
+// while port pair refuses them.
+func (pp *portPair) traceRefused(t *packetTrace, port *ipPort) *packetTrace {
+	if pp.inMaintenance() {
+		if pp.maintenanceRejects() {
+			t.step("maintenance", "Port pair is in maintenance, new session is answered with TCP reset or ICMP port unreachable")
+		} else {
+			t.step("maintenance", "Port pair is in maintenance, new session is dropped")
+		}
+	} else {
+		t.step("shutdown", "NAT is shutting down, new session is dropped")
+	}
+	return t.finish(DirDROP, port)
+}
+
+// traceNeighbor finishes trace of translated packet sent by port to
+// neighbor, packet is dropped if neighbor MAC address is not known.
+// Unlike sending packets it doesn't resolve unknown neighbors.
+func (t *packetTrace) traceNeighbor(port *ipPort, neighbor interface{}) *packetTrace {
+	if port.staticArpMode {
+		t.step("neighbor", "Port uses static destination MAC address %s", port.DstMACAddress.String())
+		return t.finish(DirSEND, port)
+	}
+	var name string
+	switch addr := neighbor.(type) {
+	case types.IPv4Address:
+		name = StringIPv4Int(uint32(addr))
+	case types.IPv6Address:
+		// Off link destinations are sent to default router
+		if port.RouterDiscovery.Enable {
+			addr = port.nextHopIPv6(addr)
+			neighbor = addr
+		}
+		name = addr.String()
+	}
+	mac, found := port.loadNeighbor(neighbor)
+	if !found {
+		t.step("neighbor", "MAC address of %s is not known, packet is dropped until it is resolved", name)
+		return t.finish(DirDROP, port)
+	}
+	t.step("neighbor", "Packet is sent to %s at %s", name, mac.String())
+	return t.finish(DirSEND, port)
+}
+
+// traceICMPToPort finishes trace of ICMP query to address of port
+// which matches no session. It is sent to KNI interface if port has it
+// and is answered by NAT otherwise.
+func (t *packetTrace) traceICMPToPort(port *ipPort) *packetTrace {
+	dir := uint(DirDROP)
+	if port.KNIName != "" {
+		dir = DirKNI
+	}
+	if d, matched := port.matchKNISteering(t.out.protocol, 0); matched {
+		t.step("kni-steering", "KNI steering rule sends packet to %s", traceVerdictLookup[d])
+		dir = d
+	}
+	if dir == DirKNI {
+		t.step("kni", "ICMP query without session is sent to KNI interface %s", port.KNIName)
+	} else {
+		t.step("icmp", "Echo request to port address is answered by NAT")
+	}
+	return t.finish(dir, port)
+}
+
+// traceIPOptions notes policy of port pair for packet with IPv4
+// options or IPv6 jumbogram. It returns false if packet is dropped.
+func (pp *portPair) traceIPOptions(t *packetTrace) bool {
+	if !t.out.ipOptions {
+		return true
+	}
+	action := pp.IPOptions.action(t.out.ipv6)
+	if action == ipOptionsDrop {
+		t.step("ip-options", "Packet with IP options is dropped")
+		return false
+	}
+	t.step("ip-options", "Packet with IP options is handled according to %s policy", action)
+	return true
+}
+
+// traceHopLimit decrements hop limit of packet which port pair
+// forwards as router. It returns false if hop limit expires, source
+// gets time exceeded error if answer is true.
+func (pp *portPair) traceHopLimit(t *packetTrace, answer bool) bool {
+	if !pp.DecrementTTL {
+		return true
+	}
+	if hopLimitExpires(t.out.hopLimit) {
+		if answer {
+			t.step("hop-limit", "Hop limit %d expires, source is sent time exceeded error", t.out.hopLimit)
+		} else {
+			t.step("hop-limit", "Hop limit %d expires, packet is dropped", t.out.hopLimit)
+		}
+		return false
+	}
+	t.out.hopLimit--
+	t.step("hop-limit", "Hop limit is decremented to %d", t.out.hopLimit)
+	return true
+}
+
+// traceAuthorization notes decision of authorization server about
+// session which packet would start. Sessions without cached decision
+// are dropped until server answers.
+func (pp *portPair) traceAuthorization(t *packetTrace, s *authorizedSession) bool {
+	cfg := &Natconfig.SessionAuthorization
+	if !cfg.authorizes(s.class) {
+		return true
+	}
+	allow, cached := pp.cachedAuthorization(s, time.Now().UnixNano())
+	switch {
+	case !cached:
+		t.step("authorization", "Session has no cached decision, packet is dropped until authorization server answers")
+	case allow:
+		t.step("authorization", "Authorization server allowed session")
+	default:
+		t.step("authorization", "Authorization server denied session")
+	}
+	return allow
+}
+
+// traceUnsupported finishes trace of packet which protocol has no
+// translation according to policy of port pair.
+func (pp *portPair) traceUnsupported(t *packetTrace, port *ipPort) *packetTrace {
+	p := &t.out
+	t.step("protocol", "Protocol %d is not translated, policy is %s", p.protocol, pp.UnsupportedProtocols.Action)
+	dir := uint(DirDROP)
+	switch pp.UnsupportedProtocols.Action {
+	case unsupportedKNI:
+		if port.KNIName != "" {
+			dir = DirKNI
+		}
+	case unsupportedPassThrough:
+		hdr4, hdr6 := p.headers()
+		if addr, neighbor, ok := pp.passThroughAddresses(port, hdr4, hdr6); ok {
+			var tuple interface{}
+			if p.ipv6 {
+				tuple = Tuple6{addr: addr.(types.IPv6Address)}
+			} else {
+				tuple = Tuple{addr: addr.(types.IPv4Address)}
+			}
+			if port.Type == iPUBLIC {
+				p.dst = tuple
+				t.step("pass-through", "Destination is translated to static NAT address %s", traceTuple(tuple))
+			} else {
+				p.src = tuple
+				t.step("pass-through", "Source is translated to public address %s", traceTuple(tuple))
+			}
+			if !pp.traceHopLimit(t, false) {
+				return t.finish(DirDROP, port)
+			}
+			return t.traceNeighbor(port.opposite, neighbor)
+		}
+		t.step("pass-through", "Packet doesn't match public or static NAT address")
+	}
+	if d, matched := port.matchKNISteering(p.protocol, 0); matched {
+		t.step("kni-steering", "KNI steering rule sends packet to %s", traceVerdictLookup[d])
+		dir = d
+	}
+	if dir == DirKNI {
+		t.step("kni", "Packet is sent to KNI interface %s", port.KNIName)
+	}
+	return t.finish(dir, port)
+}
+
+// isTranslatedProtocol returns true for protocols which have ports or
+// ICMP identifiers to translate.
+func isTranslatedProtocol(protocol uint8) bool {
+	return protocol == types.TCPNumber || protocol == types.UDPNumber || isICMPProtocol(protocol)
+}
+
+// tracePacket returns trace of packet received by port of port pair.
+func (pp *portPair) tracePacket(port *ipPort, p *tracedPacket) *packetTrace {
+	t := &packetTrace{out: *p}
+	if port.familyDisabled(p.ipv6) {
+		t.step("family", "IP family of packet is disabled on port")
+		return t.finish(DirDROP, port)
+	}
+	if port.Type == iPUBLIC {
+		return pp.tracePublicToPrivate(t)
+	}
+	return pp.tracePrivateToPublic(t)
+}
+
+func (pp *portPair) tracePublicToPrivate(t *packetTrace) *packetTrace {
+	port := &pp.PublicPort
+	p := &t.out
+	hdr4, hdr6 := p.headers()
+	src4, _, srcPort, _ := getAddrFromTuple(p.src, p.ipv6)
+	dst4, _, dstPort, _ := getAddrFromTuple(p.dst, p.ipv6)
+
+	if !pp.traceIPOptions(t) {
+		return t.finish(DirDROP, port)
+	}
+	if pp.isBlockedSource(hdr4, hdr6) {
+		t.step("flood-mitigation", "Source is blocked for flooding forwarded ports")
+		return t.finish(DirDROP, port)
+	}
+	if !p.ipv6 && pp.Multicast.enabled() && isIPv4Multicast(dst4) {
+		t.step("multicast", "Group traffic is forwarded to private hosts which joined group")
+		return t.finish(DirSEND, &pp.PrivatePort)
+	}
+	if !p.ipv6 {
+		if rule := port.findNetmapRule(dst4, true); rule != nil {
+			addr := rule.translate(dst4, true)
+			p.dst = Tuple{addr: addr, port: dstPort}
+			t.step("netmap", "Destination is translated by netmap rule %s to %s",
+				rule.Public.String(), rule.Private.String())
+			return t.traceNeighbor(&pp.PrivatePort, addr)
+		}
+	}
+	if !isTranslatedProtocol(p.protocol) {
+		return pp.traceUnsupported(t, port)
+	}
+
+	if p.protocol == types.UDPNumber {
+		if !p.ipv6 && dstPort == DHCPClientPort && srcPort == DHCPServerPort &&
+			!(port.Subnet.addressAcquired && !port.Subnet.ds.renewing) {
+			t.step("dhcp", "DHCP reply is handled by DHCP client of port")
+			return t.finish(DirDROP, port)
+		}
+		if p.ipv6 && dstPort == DHCPv6ClientPort && !(port.Subnet6.addressAcquired && !port.Subnet6.ds.renewing) {
+			t.step("dhcp", "DHCPv6 reply is handled by DHCPv6 client of port")
+			return t.finish(DirDROP, port)
+		}
+		if port.external != nil && !p.ipv6 && dstPort == pp.STUN.SourcePort && srcPort == pp.STUN.Server.Port &&
+			src4 == pp.STUN.Server.Addr4 && dst4 == port.Subnet.Addr {
+			t.step("stun", "STUN response is handled by external address discovery")
+			return t.finish(DirDROP, port)
+		}
+	}
+
+	v, found := port.translationTable[p.protocol].Load(p.dst)
+	pm := port.getPortmap(p.ipv6, p.protocol)
+	if p.protocol == types.ICMPNumber || p.protocol == types.ICMPv6Number {
+		sentToUs := dst4 == port.Subnet.Addr
+		if p.ipv6 {
+			dst6 := p.dst.(Tuple6).addr
+			sentToUs = dst6 == port.Subnet6.Addr || dst6 == port.Subnet6.llAddr
+		}
+		if sentToUs && (!found || time.Since(pm[dstPort].lastused) > connectionTimeout) {
+			return t.traceICMPToPort(port)
+		}
+	}
+	if !found {
+		t.step("session", "No session or forwarded port matches %s", traceTuple(p.dst))
+		if dmz, ok := pp.dmzDestination(p); ok {
+			t.step("dmz", "New session is opened to DMZ host %s", traceTuple(dmz))
+			v = dmz
+		} else {
+			addressAcquired := port.Subnet.addressAcquired
+			if p.ipv6 {
+				addressAcquired = port.Subnet6.addressAcquired
+			}
+			dir := uint(DirDROP)
+			if port.KNIName != "" && addressAcquired {
+				dir = DirKNI
+			}
+			if d, matched := port.matchKNISteering(p.protocol, dstPort); matched {
+				t.step("kni-steering", "KNI steering rule sends packet to %s", traceVerdictLookup[d])
+				dir = d
+			}
+			if dir == DirKNI {
+				t.step("kni", "Packet is sent to KNI interface %s", port.KNIName)
+				return t.finish(DirKNI, port)
+			}
+			return pp.traceUnsolicited(t, port)
+		}
+	} else {
+		pme := &pm[dstPort]
+		if pme.static {
+			t.step("session", "Forwarded port %d matches %s", dstPort, traceTuple(v))
+		} else if time.Since(pme.lastused) > connectionTimeout {
+			t.step("session", "Session of public port %d has expired", dstPort)
+			return pp.traceUnsolicited(t, port)
+		} else {
+			t.step("session", "Session of public port %d matches %s, it was used %v ago", dstPort, traceTuple(v),
+				time.Since(pme.lastused).Round(time.Millisecond))
+		}
+	}
+	v4addr, v6addr, newPort, zeroAddr := getAddrFromTuple(v, p.ipv6)
+	if found && pp.isPrivateKNISession(p.ipv6, &pm[dstPort], v4addr) {
+		p.dst = Tuple{addr: v4addr, port: newPort}
+		t.step("kni-source-nat", "Session of private KNI host, destination is translated to %s", traceTuple(p.dst))
+		return t.finish(DirKNI, &pp.PrivatePort)
+	}
+
+	static := found && pm[dstPort].static
+	if static {
+		if p.protocol == types.TCPNumber && p.newConnection && pp.refusesNewSessions() {
+			return pp.traceRefused(t, port)
+		}
+		if pp.FloodMitigation.enabled() {
+			t.step("flood-mitigation", "Packet and connection rates of source are not evaluated")
+		}
+		if pp.countryDenied(p, false) {
+			t.step("countries", "Country of source is denied")
+			return t.finish(DirDROP, port)
+		}
+		if p.protocol != types.TCPNumber || p.newConnection {
+			s := forwardedAuthorization(p.protocol, hdr4, hdr6, srcPort, dstPort, v4addr, v6addr, newPort)
+			if !pp.traceAuthorization(t, &s) {
+				return t.finish(DirDROP, port)
+			}
+		}
+	}
+	if fs := pm[dstPort].forwarded; fs != nil {
+		if last, ok := fs.lookup(p.src, time.Now().UnixNano()); ok {
+			t.step("forward-timeout", "Session of remote host with forwarded port was used %v ago",
+				time.Duration(time.Now().UnixNano()-*last).Round(time.Millisecond))
+		} else if p.protocol == types.TCPNumber && !p.newConnection {
+			t.step("forward-timeout", "Remote host has no session with forwarded port")
+			return pp.traceUnsolicited(t, port)
+		} else {
+			t.step("forward-timeout", "Packet starts session of remote host with forwarded port")
+		}
+	}
+	if pm[dstPort].sources != nil {
+		if d := sourceDestination(pm[dstPort].sources, hdr4, hdr6); d != nil {
+			v4addr, v6addr, newPort = d.Addr4, d.Addr6, d.Port
+			zeroAddr = (d.ipv6 && d.Addr6 == zeroIPv6Addr) || (!d.ipv6 && d.Addr4 == 0)
+			t.step("forward-sources", "Source prefix sends packet to %s", d.String())
+		}
+	}
+	if zeroAddr {
+		t.step("kni", "Packet is sent to KNI interface %s", port.KNIName)
+		return t.finish(DirKNI, port)
+	}
+	if !pp.traceHopLimit(t, true) {
+		return t.finish(DirDROP, port)
+	}
+	if static && pp.PrivatePort.isHostUnreachable(p.ipv6, v4addr, v6addr) {
+		t.step("forward-health", "Private host doesn't answer probes")
+		return t.finish(DirDROP, port)
+	}
+	if pp.Policing.active {
+		t.step("policing", "Rate plan of private host is not evaluated")
+	}
+
+	var neighbor interface{}
+	if p.ipv6 {
+		p.dst = Tuple6{addr: v6addr, port: newPort}
+		neighbor = v6addr
+	} else {
+		p.dst = Tuple{addr: v4addr, port: newPort}
+		neighbor = v4addr
+	}
+	t.step("translation", "Destination is translated to %s", traceTuple(p.dst))
+	t.traceMSSClamp(pp, p)
+	return t.traceNeighbor(&pp.PrivatePort, neighbor)
+}
+
+// traceMSSClamp notes MSS clamping of TCP SYN segment to path MTU.
+func (t *packetTrace) traceMSSClamp(pp *portPair, p *tracedPacket) {
+	if state := pp.PublicPort.pathMTU; state != nil && p.protocol == types.TCPNumber && p.newConnection {
+		t.step("path-mtu", "MSS option larger than %d is clamped to path MTU", state.mss(p.ipv6))
+	}
+}
+
+// dmzDestination returns private entry of session which would be
+// opened to DMZ host for packet which matches no session. It mirrors
+// openDMZSession without changing sessions.
+func (pp *portPair) dmzDestination(p *tracedPacket) (interface{}, bool) {
+	if (p.protocol != types.TCPNumber && p.protocol != types.UDPNumber) || pp.refusesNewSessions() {
+		return nil, false
+	}
+	public := &pp.PublicPort
+	var privEntry interface{}
+	var portNumber uint16
+	if p.ipv6 {
+		key := p.dst.(Tuple6)
+		if !pp.dmz.hasAddr6 || !public.Subnet6.addressAcquired || key.addr != public.Subnet6.Addr {
+			return nil, false
+		}
+		privEntry = Tuple6{addr: pp.dmz.addr6, port: key.port}
+		portNumber = key.port
+	} else {
+		key := p.dst.(Tuple)
+		if !pp.dmz.hasAddr4 || !public.Subnet.addressAcquired || key.addr != public.Subnet.Addr {
+			return nil, false
+		}
+		privEntry = Tuple{addr: pp.dmz.addr4, port: key.port}
+		portNumber = key.port
+	}
+	if portNumber >= portEnd || pp.isLocalPort(portNumber) {
+		return nil, false
+	}
+	pm := pp.getPublicPortPortmap(p.ipv6, p.protocol)
+	if pm[portNumber].static || time.Since(pm[portNumber].lastused) <= connectionTimeout {
+		return nil, false
+	}
+	if _, found := pp.PrivatePort.translationTable[p.protocol].Load(privEntry); found {
+		return nil, false
+	}
+	return privEntry, true
+}
+
+func (pp *portPair) tracePrivateToPublic(t *packetTrace) *packetTrace {
+	port := &pp.PrivatePort
+	public := &pp.PublicPort
+	p := &t.out
+	src4, _, srcPort, _ := getAddrFromTuple(p.src, p.ipv6)
+	dst4, dst6, dstPort, _ := getAddrFromTuple(p.dst, p.ipv6)
+	hdr4, hdr6 := p.headers()
+
+	if !pp.traceIPOptions(t) {
+		return t.finish(DirDROP, port)
+	}
+	if !p.ipv6 && pp.Multicast.enabled() && p.protocol == igmpNumber {
+		t.step("multicast", "IGMP message updates group membership of private host")
+		return t.finish(DirDROP, port)
+	}
+	for _, rule := range pp.blackholeRules() {
+		if !rule.matches(dst4, dst6, p.ipv6) {
+			continue
+		}
+		if rule.Action == blackholeKNI {
+			t.step("blackhole", "Destination matches blackhole rule %s, packet is sent to KNI interface %s",
+				rule.Destination, port.KNIName)
+			return t.finish(DirKNI, port)
+		}
+		t.step("blackhole", "Destination matches blackhole rule %s", rule.Destination)
+		return t.finish(DirDROP, port)
+	}
+	if pp.CaptivePortal.enabled() {
+		src, _ := packetAddresses(hdr4, hdr6)
+		mac, _ := port.loadNeighbor(src)
+		if !pp.portalAllows(port, mac, hdr4, hdr6) {
+			portal := pp.CaptivePortal.portalRedirects(p.ipv6, p.protocol, dstPort)
+			if portal == nil {
+				t.step("captive-portal", "Private host is not authenticated, packet is dropped")
+				return t.finish(DirDROP, port)
+			}
+			if p.ipv6 {
+				p.dst = Tuple6{addr: portal.(types.IPv6Address), port: pp.CaptivePortal.Port}
+			} else {
+				p.dst = Tuple{addr: portal.(types.IPv4Address), port: pp.CaptivePortal.Port}
+			}
+			dst4, dst6, dstPort, _ = getAddrFromTuple(p.dst, p.ipv6)
+			hdr4, hdr6 = p.headers()
+			t.step("captive-portal", "Private host is not authenticated, flow is redirected to portal %s", traceTuple(p.dst))
+		}
+	}
+	if target := pp.findPrivateRoute(hdr4, hdr6); target != nil {
+		if hopLimitExpires(p.hopLimit) {
+			t.step("private-route", "Hop limit %d expires, source is sent time exceeded error", p.hopLimit)
+			return t.finish(DirDROP, port)
+		}
+		p.hopLimit--
+		t.step("private-route", "Destination is in private subnet of port %s, packet is routed without translation",
+			target.logName())
+		if p.ipv6 {
+			return t.traceNeighbor(target, dst6)
+		}
+		return t.traceNeighbor(target, dst4)
+	}
+	if !p.ipv6 && dst4 != port.Subnet.Addr && dst4 != BroadcastIPv4 && !isIPv4Multicast(dst4) {
+		if rule := public.findNetmapRule(src4, false); rule != nil {
+			p.src = Tuple{addr: rule.translate(src4, false), port: srcPort}
+			t.step("netmap", "Source is translated by netmap rule %s to %s",
+				rule.Private.String(), rule.Public.String())
+			return t.traceNeighbor(public, dst4)
+		}
+	}
+	if !isTranslatedProtocol(p.protocol) {
+		return pp.traceUnsupported(t, port)
+	}
+
+	if p.protocol == types.UDPNumber && !p.ipv6 && pp.DHCPRelay.enabled() &&
+		dstPort == DHCPServerPort && srcPort == DHCPClientPort &&
+		(dst4 == BroadcastIPv4 || dst4 == port.Subnet.Addr || dst4 == port.Subnet.broadcastAddr()) {
+		t.step("dhcp-relay", "DHCP request is relayed to DHCP server")
+		return t.finish(DirSEND, public)
+	}
+
+	addressAcquired := port.Subnet.addressAcquired
+	sentToUs := dst4 == port.Subnet.Addr
+	publicAddressAcquired := public.Subnet.addressAcquired
+	if p.ipv6 {
+		addressAcquired = port.Subnet6.addressAcquired
+		sentToUs = dst6 == port.Subnet6.Addr || dst6 == port.Subnet6.llAddr ||
+			dst6 == port.Subnet6.multicastAddr || dst6 == port.Subnet6.llMulticastAddr
+		publicAddressAcquired = public.Subnet6.addressAcquired
+	}
+	if sentToUs && (p.protocol == types.ICMPNumber || p.protocol == types.ICMPv6Number) {
+		if _, found := port.translationTable[p.protocol].Load(p.src); !found {
+			return t.traceICMPToPort(port)
+		}
+	}
+	if port.KNIName != "" && addressAcquired && sentToUs {
+		dir := uint(DirKNI)
+		if d, matched := port.matchKNISteering(p.protocol, dstPort); matched {
+			t.step("kni-steering", "KNI steering rule sends packet to %s", traceVerdictLookup[d])
+			dir = d
+		}
+		if dir == DirKNI {
+			t.step("kni", "Packet to port address is sent to KNI interface %s", port.KNIName)
+		}
+		return t.finish(dir, port)
+	}
+
+	// Sessions of forwarded ports with idle timeout
+	var forwarded *forwardedSessions
+	v, found := port.translationTable[p.protocol].Load(p.src)
+	if found {
+		t.step("session", "Session of %s matches %s", traceTuple(p.src), traceTuple(v))
+		_, _, newPort, zeroAddr := getAddrFromTuple(v, p.ipv6)
+		if zeroAddr {
+			t.step("kni", "Packet is sent to KNI interface %s", port.KNIName)
+			return t.finish(DirKNI, port)
+		}
+		forwarded = public.getPortmap(p.ipv6, p.protocol)[newPort].forwarded
+		p.src = v
+	} else {
+		t.step("session", "No session matches %s", traceTuple(p.src))
+		if !addressAcquired || !publicAddressAcquired {
+			t.step("address", "Address of port is not acquired yet")
+			return t.finish(DirDROP, port)
+		}
+		if pp.refusesNewSessions() {
+			return pp.traceRefused(t, port)
+		}
+		if pp.countryDenied(p, true) {
+			t.step("countries", "Country of destination is denied")
+			return t.finish(DirDROP, port)
+		}
+		s := egressAuthorization(p.protocol, hdr4, hdr6, srcPort, dstPort)
+		if !pp.traceAuthorization(t, &s) {
+			return t.finish(DirDROP, port)
+		}
+		if p.ipv6 {
+			t.step("new-session", "New session is created from %s and free public port", public.Subnet6.Addr.String())
+			p.src = Tuple6{addr: public.Subnet6.Addr}
+		} else {
+			addr := public.publicAddressForHost(src4)
+			t.step("new-session", "New session is created from %s and free public port", StringIPv4Int(uint32(addr)))
+			p.src = Tuple{addr: addr}
+		}
+		if pp.PortSharing.enabled() {
+			t.step("port-sharing", "Share of public ports of private host is not evaluated")
+		}
+		if isICMPProtocol(p.protocol) && pp.ICMPIdentifiers.Allocation == icmpAllocationPreserve {
+			t.step("icmp-identifiers", "Public identifier is %d if it is free and in range", srcPort)
+		}
+		if p.protocol != types.ICMPNumber && p.protocol != types.ICMPv6Number {
+			for _, rule := range pp.portTriggers() {
+				if uint8(rule.Protocol) == p.protocol && rule.Ports.contains(dstPort) {
+					t.step("port-triggers", "Session opens %s ports %v to private host", rule.OpenProtocol, rule.OpenPorts)
+				}
+			}
+		}
+	}
+	if !pp.traceHopLimit(t, true) {
+		return t.finish(DirDROP, port)
+	}
+	if forwarded != nil {
+		if _, ok := forwarded.lookup(p.dst, time.Now().UnixNano()); !ok {
+			t.step("forward-timeout", "Remote host has no active session with forwarded port")
+			return t.finish(DirDROP, port)
+		}
+	}
+	if pp.Policing.active {
+		t.step("policing", "Rate plan of private host is not evaluated")
+	}
+	t.step("translation", "Source is translated to %s", traceTuple(p.src))
+	t.traceMSSClamp(pp, p)
+
+	if p.ipv6 {
+		return t.traceNeighbor(public, dst6)
+	}
+	addr, _, _, _ := getAddrFromTuple(p.src, false)
+	if vlan := public.vlanByAddr(addr); vlan != nil {
+		t.step("vlan", "Packet is sent to VLAN %d", vlan.Vlan)
+		if vlan.nextHop != 0 {
+			return t.traceNeighbor(public, vlan.nextHop)
+		}
+	}
+	return t.traceNeighbor(public, dst4)
+}
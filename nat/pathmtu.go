@@ -0,0 +1,370 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+const (
+	// Smallest path MTU, minimum link MTU of IPv6
+	minPathMTU = 1280
+	// Largest path MTU of jumbo frames
+	maxPathMTU = 9000
+	// Seconds between probes when they are not configured
+	defaultPathMTUInterval = 600
+	// Probes are repeated this soon when no target answered
+	pathMTURetryInterval = 10 * time.Second
+	// Port pairs are checked for due probes this often
+	pathMTUCheckInterval = time.Second
+	// Probe of a size is sent this many times before size is
+	// considered too large
+	pathMTUProbeAttempts = 2
+	pathMTUProbeTimeout  = 500 * time.Millisecond
+	// Echo identifier of path MTU probes
+	pathMTUEchoID = 0x5e21
+
+	tcpOptionEnd = 0
+	tcpOptionNOP = 1
+	tcpOptionMSS = 2
+)
+
+// Path MTU toward next hops of public port, e.g. tunnel endpoints or
+// PPPoE concentrator. NAT clamps MSS of TCP SYN segments in both
+// directions to path MTU and answers private packets larger than path
+// MTU which may not be fragmented with ICMP errors. Zero MTU disables
+// it.
+type pathMTUConfig struct {
+	// Largest path MTU which is used until probes find it
+	MTU int `json:"mtu"`
+	// Next hops which are probed with ICMP echo of decreasing sizes,
+	// path MTU is the smallest MTU of all targets. MTU is not probed
+	// without targets.
+	Targets []string `json:"targets"`
+	// Seconds between probes, zero means default
+	Interval int `json:"interval"`
+	targets  []interface{}
+}
+
+// Path MTU of public port which packet handlers use on several cores.
+type pathMTU struct {
+	// Current path MTU in bytes
+	current int32
+	// Sequence numbers of answered probes
+	replies chan uint16
+	// Time of next probes, used only by prober
+	next time.Time
+	seq  uint16
+}
+
+// checkPathMTU checks path MTU options and enables MSS clamping on
+// public port.
+func (pp *portPair) checkPathMTU() error {
+	cfg := &pp.PathMTU
+	if cfg.MTU == 0 {
+		if len(cfg.Targets) != 0 {
+			return fmt.Errorf("Path MTU targets of port pair %d require mtu", pp.PublicPort.Index)
+		}
+		return nil
+	}
+	if cfg.MTU < minPathMTU || cfg.MTU > maxPathMTU {
+		return fmt.Errorf("Path MTU of port pair %d should be between %d and %d", pp.PublicPort.Index, minPathMTU, maxPathMTU)
+	}
+	if cfg.Interval < 0 {
+		return fmt.Errorf("Path MTU probe interval of port pair %d should not be negative", pp.PublicPort.Index)
+	}
+	port := &pp.PublicPort
+	cfg.targets = nil
+	for _, s := range cfg.Targets {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return fmt.Errorf("Bad path MTU target address %s of port %d", s, port.Index)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			if port.ipv4Disabled {
+				return fmt.Errorf("Path MTU target %s of port %d requires IPv4 which is disabled", s, port.Index)
+			}
+			addr, _ := convertIPv4(ip4)
+			cfg.targets = append(cfg.targets, addr)
+		} else {
+			if port.ipv6Disabled {
+				return fmt.Errorf("Path MTU target %s of port %d requires IPv6 which is disabled", s, port.Index)
+			}
+			var addr types.IPv6Address
+			copy(addr[:], ip)
+			cfg.targets = append(cfg.targets, addr)
+		}
+	}
+	port.pathMTU = &pathMTU{
+		current: int32(cfg.MTU),
+		replies: make(chan uint16, 1),
+	}
+	return nil
+}
+
+func (cfg *pathMTUConfig) interval() time.Duration {
+	if cfg.Interval == 0 {
+		return defaultPathMTUInterval * time.Second
+	}
+	return time.Duration(cfg.Interval) * time.Second
+}
+
+// mss returns largest MSS of TCP segments of IP family which fit
+// path MTU.
+func (state *pathMTU) mss(ipv6 bool) uint16 {
+	mtu := uint16(atomic.LoadInt32(&state.current))
+	if ipv6 {
+		return mtu - types.IPv6Len - types.TCPMinLen
+	}
+	return mtu - types.IPv4MinLen - types.TCPMinLen
+}
+
+// clampMSSOption lowers MSS option in TCP options which start at even
+// offset of TCP header to mss. It returns TCP checksum cksum updated
+// for new option value and false if options have no MSS larger than
+// mss.
+func clampMSSOption(options []byte, mss, cksum uint16) (uint16, bool) {
+	for i := 0; i < len(options); {
+		switch options[i] {
+		case tcpOptionEnd:
+			return cksum, false
+		case tcpOptionNOP:
+			i++
+			continue
+		}
+		if i+1 >= len(options) || options[i+1] < 2 || i+int(options[i+1]) > len(options) {
+			return cksum, false
+		}
+		if options[i] != tcpOptionMSS || options[i+1] != 4 {
+			i += int(options[i+1])
+			continue
+		}
+		old := binary.BigEndian.Uint16(options[i+2:])
+		if old <= mss {
+			return cksum, false
+		}
+		binary.BigEndian.PutUint16(options[i+2:], mss)
+		// Value at odd offset spans two checksum words with its
+		// bytes swapped
+		if i%2 != 0 {
+			return updateChecksum(cksum, old>>8|old<<8, mss>>8|mss<<8), true
+		}
+		return updateChecksum(cksum, old, mss), true
+	}
+	return cksum, false
+}
+
+// clampTCPMSS lowers MSS option of TCP SYN segment to path MTU and
+// updates TCP checksum.
+func (state *pathMTU) clampTCPMSS(pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr, pktTCP *packet.TCPHdr) {
+	if pktTCP.TCPFlags&types.TCPFlagSyn == 0 {
+		return
+	}
+	hdrLen := int(pktTCP.DataOff&0xf0) >> 2
+	var segmentLen int
+	if pktIPv4 != nil {
+		segmentLen = int(packet.SwapBytesUint16(pktIPv4.TotalLength)) - ipv4HeaderLen(pktIPv4)
+	} else {
+		segmentLen = int(packet.SwapBytesUint16(pktIPv6.PayloadLen))
+	}
+	if hdrLen > segmentLen {
+		hdrLen = segmentLen
+	}
+	if hdrLen <= types.TCPMinLen {
+		return
+	}
+	options := (*[60]byte)(unsafe.Pointer(uintptr(unsafe.Pointer(pktTCP)) + types.TCPMinLen))[:hdrLen-types.TCPMinLen]
+	cksum, clamped := clampMSSOption(options, state.mss(pktIPv6 != nil), packet.SwapBytesUint16(pktTCP.Cksum))
+	if clamped && !NoCalculateChecksum {
+		pktTCP.Cksum = packet.SwapBytesUint16(cksum)
+	}
+}
+
+// fitsPathMTU answers private packet larger than path MTU which may
+// not be fragmented with ICMP fragmentation needed or ICMPv6 packet too
+// big error from address of port. It returns false if packet is
+// dropped.
+func (pp *portPair) fitsPathMTU(port *ipPort, pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) bool {
+	state := pp.PublicPort.pathMTU
+	if state == nil {
+		return true
+	}
+	mtu := int(atomic.LoadInt32(&state.current))
+	if pktIPv4 != nil {
+		if int(packet.SwapBytesUint16(pktIPv4.TotalLength)) <= mtu ||
+			packet.SwapBytesUint16(pktIPv4.FragmentOffset)&ipv4DontFragment == 0 {
+			return true
+		}
+		if port.Subnet.addressAcquired && icmpLimiter.allow(port, packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr)) {
+			port.sendICMPError(pkt, pktIPv4, nil, icmpTypeDestinationUnreachable, icmpCodeFragmentationNeeded,
+				packet.SwapBytesIPv4Addr(port.Subnet.Addr), types.IPv6Address{}, uint32(mtu))
+		}
+	} else {
+		if int(packet.SwapBytesUint16(pktIPv6.PayloadLen))+types.IPv6Len <= mtu {
+			return true
+		}
+		if port.Subnet6.addressAcquired && icmpLimiter.allow(port, pktIPv6.SrcAddr) {
+			port.sendICMPError(pkt, nil, pktIPv6, icmpv6TypePacketTooBig, 0, 0, port.Subnet6.Addr, uint32(mtu))
+		}
+	}
+	port.dumpPacket(pkt, DirDROP)
+	return false
+}
+
+// handlePathMTUReply returns true if ICMP packet sent to port address
+// is echo reply to path MTU probe.
+func (port *ipPort) handlePathMTUReply(protocol uint8, icmp *packet.ICMPHdr) bool {
+	if port.pathMTU == nil || packet.SwapBytesUint16(icmp.Identifier) != pathMTUEchoID || icmp.Code != 0 {
+		return false
+	}
+	if (protocol == types.ICMPNumber && icmp.Type != types.ICMPTypeEchoResponse) ||
+		(protocol == types.ICMPv6Number && icmp.Type != types.ICMPv6TypeEchoResponse) {
+		return false
+	}
+	select {
+	case port.pathMTU.replies <- packet.SwapBytesUint16(icmp.SeqNum):
+	default:
+	}
+	return true
+}
+
+// wait returns true if probe with sequence number seq is answered
+// before timeout. Replies to earlier probes are skipped.
+func (state *pathMTU) wait(seq uint16, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case s := <-state.replies:
+			if s == seq {
+				return true
+			}
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+// searchPathMTU returns largest size between low and high with which
+// probe passes, assuming that all smaller sizes pass too. It returns
+// zero if probe of low size fails.
+func searchPathMTU(low, high int, probe func(size int) bool) int {
+	if probe(high) {
+		return high
+	}
+	if !probe(low) {
+		return 0
+	}
+	for high-low > 1 {
+		mid := (low + high) / 2
+		if probe(mid) {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return low
+}
+
+// probeSize returns true if target answers echo request of size
+// bytes which may not be fragmented.
+func (port *ipPort) probeSize(target interface{}, mac types.MACAddress, size int) bool {
+	state := port.pathMTU
+	for i := 0; i < pathMTUProbeAttempts; i++ {
+		state.seq++
+		port.sendSizedEchoRequest(target, mac, pathMTUEchoID, state.seq, size)
+		if state.wait(state.seq, pathMTUProbeTimeout) {
+			return true
+		}
+	}
+	return false
+}
+
+// probePathMTUTarget returns path MTU toward target or zero if target
+// cannot be probed or doesn't answer.
+func (port *ipPort) probePathMTUTarget(target interface{}, maxMTU int) int {
+	var mac types.MACAddress
+	var found bool
+	switch addr := target.(type) {
+	case types.IPv4Address:
+		if !port.Subnet.addressAcquired {
+			return 0
+		}
+		mac, found = port.getMACForIPv4(addr)
+	case types.IPv6Address:
+		if !port.Subnet6.addressAcquired && !isIPv6LinkLocal(addr) {
+			return 0
+		}
+		mac, found = port.getMACForIPv6(addr)
+	}
+	if !found {
+		return 0
+	}
+	return searchPathMTU(minPathMTU, maxMTU, func(size int) bool {
+		return port.probeSize(target, mac, size)
+	})
+}
+
+// probePathMTU probes targets of port pair when probes are due and
+// updates path MTU with the smallest MTU found. Changes are reported
+// with events.
+func (pp *portPair) probePathMTU() {
+	port := &pp.PublicPort
+	state := port.pathMTU
+	now := time.Now()
+	if now.Before(state.next) {
+		return
+	}
+	mtu := 0
+	for _, target := range pp.PathMTU.targets {
+		if found := port.probePathMTUTarget(target, pp.PathMTU.MTU); found != 0 && (mtu == 0 || found < mtu) {
+			mtu = found
+		}
+	}
+	// Targets are probed again soon while their neighbors are
+	// resolved
+	if mtu == 0 {
+		state.next = now.Add(pathMTURetryInterval)
+		return
+	}
+	state.next = now.Add(pp.PathMTU.interval())
+	if old := int(atomic.SwapInt32(&state.current, int32(mtu))); old != mtu {
+		common.LogWarning(common.No, "Path MTU of port", port.logName(), "changed from", old, "to", mtu)
+		raiseEvent(EventPathMTUChanged, port, map[string]interface{}{
+			"old-mtu": old,
+			"mtu":     mtu,
+		})
+	}
+}
+
+// StartPathMTUDiscovery starts probing path MTU toward next hops for
+// port pairs which configure targets.
+func StartPathMTUDiscovery() {
+	pairs := []*portPair{}
+	for i := range Natconfig.PortPairs {
+		if Natconfig.PortPairs[i].PublicPort.pathMTU != nil && len(Natconfig.PortPairs[i].PathMTU.targets) != 0 {
+			pairs = append(pairs, &Natconfig.PortPairs[i])
+		}
+	}
+	if len(pairs) == 0 {
+		return
+	}
+	go func() {
+		for {
+			for _, pp := range pairs {
+				pp.probePathMTU()
+			}
+			time.Sleep(pathMTUCheckInterval)
+		}
+	}()
+}
@@ -0,0 +1,139 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestClampMSSOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []byte
+		clamped bool
+		mss     uint16
+	}{
+		{"MSS option", []byte{2, 4, 0x05, 0xb4}, true, 1400},
+		{"MSS after window scale", []byte{3, 3, 7, 2, 4, 0x05, 0xb4, 0}, true, 1400},
+		{"MSS at odd offset", []byte{1, 2, 4, 0x05, 0xb4, 1, 1, 1}, true, 1400},
+		{"MSS after SACK permitted and timestamps",
+			[]byte{4, 2, 8, 10, 1, 2, 3, 4, 5, 6, 7, 8, 2, 4, 0x23, 0x28, 1, 1, 1, 1}, true, 1400},
+		{"smaller MSS", []byte{2, 4, 0x05, 0x00}, false, 1280},
+		{"equal MSS", []byte{2, 4, 0x05, 0x78}, false, 1400},
+		{"no MSS", []byte{1, 1, 4, 2}, false, 0},
+		{"MSS after end of options", []byte{0, 0, 0, 0, 2, 4, 0x05, 0xb4}, false, 1460},
+		{"truncated option", []byte{3, 3, 7, 2, 4, 0x05}, false, 0},
+		{"zero option length", []byte{8, 0, 2, 4, 0x05, 0xb4, 0, 0}, false, 1460},
+	}
+	for _, tt := range tests {
+		segment := append(make([]byte, 20), tt.options...)
+		segment[12] = byte(len(segment)/4) << 4
+		cksum := internetChecksum(segment)
+		binary.BigEndian.PutUint16(segment[16:], cksum)
+
+		newCksum, clamped := clampMSSOption(segment[20:], 1400, cksum)
+		if clamped != tt.clamped {
+			t.Errorf("%s: MSS is clamped %v, expected %v", tt.name, clamped, tt.clamped)
+			continue
+		}
+		for i := 0; i+3 < len(segment)-20; i++ {
+			if segment[20+i] == tcpOptionMSS && segment[21+i] == 4 && tt.mss != 0 {
+				if mss := binary.BigEndian.Uint16(segment[22+i:]); mss != tt.mss {
+					t.Errorf("%s: MSS is %d, expected %d", tt.name, mss, tt.mss)
+				}
+				break
+			}
+		}
+		if !clamped {
+			continue
+		}
+		binary.BigEndian.PutUint16(segment[16:], 0)
+		if expected := internetChecksum(segment); newCksum != expected {
+			t.Errorf("%s: checksum is %#x, expected %#x", tt.name, newCksum, expected)
+		}
+	}
+}
+
+func TestPathMTUMSS(t *testing.T) {
+	state := &pathMTU{current: 1492}
+	if mss := state.mss(false); mss != 1452 {
+		t.Errorf("IPv4 MSS of PPPoE path is %d, expected 1452", mss)
+	}
+	if mss := state.mss(true); mss != 1432 {
+		t.Errorf("IPv6 MSS of PPPoE path is %d, expected 1432", mss)
+	}
+}
+
+func TestSearchPathMTU(t *testing.T) {
+	tests := []struct {
+		name   string
+		pmtu   int
+		result int
+		probes int
+	}{
+		{"path with largest MTU", 1500, 1500, 1},
+		{"PPPoE path", 1492, 1492, 10},
+		{"tunnel path", 1400, 1400, 10},
+		{"smallest MTU", 1280, 1280, 9},
+		{"target which doesn't answer", 0, 0, 2},
+	}
+	for _, tt := range tests {
+		probes := 0
+		result := searchPathMTU(minPathMTU, 1500, func(size int) bool {
+			probes++
+			return size <= tt.pmtu
+		})
+		if result != tt.result || probes != tt.probes {
+			t.Errorf("%s: path MTU is %d after %d probes, expected %d after %d", tt.name, result, probes, tt.result, tt.probes)
+		}
+	}
+}
+
+func TestPathMTUWait(t *testing.T) {
+	state := &pathMTU{replies: make(chan uint16, 1)}
+	go func() {
+		state.replies <- 1
+		state.replies <- 2
+	}()
+	if !state.wait(2, time.Second) {
+		t.Errorf("Answered probe is not found after reply to earlier probe")
+	}
+	if state.wait(3, 10*time.Millisecond) {
+		t.Errorf("Unanswered probe is found")
+	}
+}
+
+func TestCheckPathMTU(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     pathMTUConfig
+		valid   bool
+		enabled bool
+	}{
+		{"disabled", pathMTUConfig{}, true, false},
+		{"configured MTU", pathMTUConfig{MTU: 1492}, true, true},
+		{"probed targets", pathMTUConfig{MTU: 1500, Targets: []string{"192.0.2.1", "2001:db8::1"}, Interval: 60}, true, true},
+		{"targets without MTU", pathMTUConfig{Targets: []string{"192.0.2.1"}}, false, false},
+		{"too small MTU", pathMTUConfig{MTU: 576}, false, false},
+		{"too large MTU", pathMTUConfig{MTU: 65535}, false, false},
+		{"negative interval", pathMTUConfig{MTU: 1500, Interval: -1}, false, false},
+		{"bad target", pathMTUConfig{MTU: 1500, Targets: []string{"gateway"}}, false, false},
+	}
+	for _, tt := range tests {
+		pp := &portPair{PathMTU: tt.cfg}
+		err := pp.checkPathMTU()
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: error is %v, expected valid %v", tt.name, err, tt.valid)
+		}
+		if (pp.PublicPort.pathMTU != nil) != tt.enabled {
+			t.Errorf("%s: path MTU is enabled %v, expected %v", tt.name, pp.PublicPort.pathMTU != nil, tt.enabled)
+		}
+		if tt.enabled && len(pp.PathMTU.targets) != len(tt.cfg.Targets) {
+			t.Errorf("%s: %d targets are parsed, expected %d", tt.name, len(pp.PathMTU.targets), len(tt.cfg.Targets))
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Counters are saved this often when interval is not configured
+const defaultStatisticsSaveInterval = 60
+
+// Persistence of cumulative port counters, so that long-term
+// accounting survives restarts and upgrades. Counters are saved to
+// file every interval seconds and on exit, and NAT adds saved counters
+// to its own on start. Empty file disables persistence.
+type statisticsPersistenceConfig struct {
+	File     string `json:"file"`
+	Interval int    `json:"interval"`
+}
+
+// Lifetime counters of port saved to statistics file. Port is
+// identified by index and MAC address like in DHCP lease file.
+type savedPortStats struct {
+	Port        uint16 `json:"port"`
+	MAC         string `json:"mac"`
+	RxPackets   uint64 `json:"rx-packets"`
+	RxBytes     uint64 `json:"rx-bytes"`
+	TxPackets   uint64 `json:"tx-packets"`
+	TxBytes     uint64 `json:"tx-bytes"`
+	KNIPackets  uint64 `json:"kni-packets"`
+	DropPackets uint64 `json:"drop-packets"`
+	Sessions    uint64 `json:"sessions"`
+}
+
+// Statistics file is written by saver goroutine and on exit
+var statisticsFileMutex sync.Mutex
+
+func (cfg *statisticsPersistenceConfig) enabled() bool {
+	return cfg.File != ""
+}
+
+func (cfg *statisticsPersistenceConfig) check() error {
+	if cfg.Interval < 0 {
+		return errors.New("Persistent-statistics interval should not be negative")
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = defaultStatisticsSaveInterval
+	}
+	return nil
+}
+
+// lifetimeStats returns counters of port since first start with
+// persistent statistics, which are saved counters of previous runs
+// and counters of this run.
+func (port *ipPort) lifetimeStats() portStats {
+	s := port.getStats()
+	return portStats{
+		rxPackets:   s.rxPackets + atomic.LoadUint64(&port.savedStats.rxPackets),
+		rxBytes:     s.rxBytes + atomic.LoadUint64(&port.savedStats.rxBytes),
+		txPackets:   s.txPackets + atomic.LoadUint64(&port.savedStats.txPackets),
+		txBytes:     s.txBytes + atomic.LoadUint64(&port.savedStats.txBytes),
+		kniPackets:  s.kniPackets + atomic.LoadUint64(&port.savedStats.kniPackets),
+		dropPackets: s.dropPackets + atomic.LoadUint64(&port.savedStats.dropPackets),
+		sessions:    s.sessions + atomic.LoadUint64(&port.savedStats.sessions),
+	}
+}
+
+// SaveStatistics writes lifetime counters of all ports to statistics
+// file if persistence is configured.
+func SaveStatistics() error {
+	if !Natconfig.PersistentStatistics.enabled() {
+		return nil
+	}
+	statisticsFileMutex.Lock()
+	defer statisticsFileMutex.Unlock()
+
+	saved := []savedPortStats{}
+	for i := range Natconfig.PortPairs {
+		pp := &Natconfig.PortPairs[i]
+		for _, port := range []*ipPort{&pp.PublicPort, &pp.PrivatePort} {
+			s := port.lifetimeStats()
+			saved = append(saved, savedPortStats{
+				Port:        port.Index,
+				MAC:         port.SrcMACAddress.String(),
+				RxPackets:   s.rxPackets,
+				RxBytes:     s.rxBytes,
+				TxPackets:   s.txPackets,
+				TxBytes:     s.txBytes,
+				KNIPackets:  s.kniPackets,
+				DropPackets: s.dropPackets,
+				Sessions:    s.sessions,
+			})
+		}
+	}
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(Natconfig.PersistentStatistics.File, data)
+}
+
+// loadStatistics reads statistics file and makes its counters base
+// of lifetime counters of ports. Counters of other network cards are
+// ignored. Missing file is not an error.
+func loadStatistics() {
+	cfg := &Natconfig.PersistentStatistics
+	if !cfg.enabled() {
+		return
+	}
+	data, err := ioutil.ReadFile(cfg.File)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			println("Warning! Failed to read statistics file", cfg.File, ":", err.Error())
+		}
+		return
+	}
+	var saved []savedPortStats
+	if err := json.Unmarshal(data, &saved); err != nil {
+		println("Warning! Failed to parse statistics file", cfg.File, ":", err.Error())
+		return
+	}
+
+	for i := range saved {
+		s := &saved[i]
+		for j := range Natconfig.PortPairs {
+			pp := &Natconfig.PortPairs[j]
+			for _, port := range []*ipPort{&pp.PublicPort, &pp.PrivatePort} {
+				if port.Index != s.Port || port.SrcMACAddress.String() != s.MAC {
+					continue
+				}
+				atomic.StoreUint64(&port.savedStats.rxPackets, s.RxPackets)
+				atomic.StoreUint64(&port.savedStats.rxBytes, s.RxBytes)
+				atomic.StoreUint64(&port.savedStats.txPackets, s.TxPackets)
+				atomic.StoreUint64(&port.savedStats.txBytes, s.TxBytes)
+				atomic.StoreUint64(&port.savedStats.kniPackets, s.KNIPackets)
+				atomic.StoreUint64(&port.savedStats.dropPackets, s.DropPackets)
+				atomic.StoreUint64(&port.savedStats.sessions, s.Sessions)
+			}
+		}
+	}
+	println("Restored lifetime counters of", len(saved), "ports from", cfg.File)
+}
+
+// StartStatisticsPersistence starts saving lifetime counters
+// periodically if persistence is configured.
+func StartStatisticsPersistence() {
+	cfg := &Natconfig.PersistentStatistics
+	if !cfg.enabled() {
+		return
+	}
+	go func() {
+		for {
+			time.Sleep(time.Duration(cfg.Interval) * time.Second)
+			if err := SaveStatistics(); err != nil {
+				println("Warning! Failed to write statistics file", cfg.File, ":", err.Error())
+			}
+		}
+	}()
+}
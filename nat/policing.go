@@ -0,0 +1,372 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+// Maximum number of subscribers tracked by one port pair. When it is
+// reached, idle subscribers are forgotten.
+const maxSubscribers = 65536
+
+// Two rate policer as described in RFC 2698. Rates are in kilobits
+// per second, bursts are in bytes. Packets within committed rate
+// conform, packets above it and within peak rate exceed but are
+// passed, packets above peak rate are dropped. Zero committed rate
+// means no policing, zero peak rate means peak rate equal to
+// committed rate.
+type policerConfig struct {
+	CIR uint64 `json:"cir"`
+	CBS uint64 `json:"cbs"`
+	PIR uint64 `json:"pir"`
+	PBS uint64 `json:"pbs"`
+}
+
+// Policers of egress (private to public) and ingress (public to
+// private) traffic of a subscriber.
+type subscriberPolicy struct {
+	Egress  policerConfig `json:"egress"`
+	Ingress policerConfig `json:"ingress"`
+}
+
+// Private host which has its own rate plan. Host is identified either
+// by address or by MAC address when host identity is tracked.
+type subscriberConfig struct {
+	Address string        `json:"address"`
+	MAC     string        `json:"mac"`
+	Egress  policerConfig `json:"egress"`
+	Ingress policerConfig `json:"ingress"`
+}
+
+// Port pair subscribers policing. Private hosts which are not listed
+// in subscribers get default policy.
+type policingConfig struct {
+	Default     subscriberPolicy   `json:"default"`
+	Subscribers []subscriberConfig `json:"subscribers"`
+	// Policies by types.IPv4Address, types.IPv6Address or
+	// types.MACAddress
+	policies map[interface{}]subscriberPolicy
+	active   bool
+}
+
+type policer struct {
+	config    policerConfig
+	committed tokenBucket
+	peak      tokenBucket
+	// Bytes of conforming, exceeding and dropped packets
+	conformBytes uint64
+	exceedBytes  uint64
+	dropBytes    uint64
+}
+
+// Subscriber table entry. Entries of hosts with known MAC address are
+// keyed by MAC address, so they survive address changes. Policers are
+// used from packet handlers running on several cores, so they are
+// protected by a mutex.
+type subscriber struct {
+	mutex   sync.Mutex
+	egress  policer
+	ingress policer
+}
+
+// Counters of subscriber as they are reported by control API.
+type subscriberCounters struct {
+	// Current address of subscriber, nil if it has none
+	address interface{}
+	mac     types.MACAddress
+	hasMAC  bool
+	egress  [3]uint64
+	ingress [3]uint64
+}
+
+func (cfg *policerConfig) enabled() bool {
+	return cfg.CIR != 0
+}
+
+func (cfg *policerConfig) check(name string) error {
+	if cfg.CIR == 0 {
+		if cfg.CBS != 0 || cfg.PIR != 0 || cfg.PBS != 0 {
+			return fmt.Errorf("Policer %s requires cir", name)
+		}
+		return nil
+	}
+	if cfg.PIR != 0 && cfg.PIR < cfg.CIR {
+		return fmt.Errorf("Policer %s pir should not be less than cir", name)
+	}
+	if (cfg.CBS != 0 && cfg.CBS < policerMinBurst) || (cfg.PBS != 0 && cfg.PBS < policerMinBurst) {
+		return fmt.Errorf("Policer %s bursts should be at least %d bytes", name, policerMinBurst)
+	}
+	return nil
+}
+
+func (policy *subscriberPolicy) check(name string) error {
+	if err := policy.Egress.check(name + " egress"); err != nil {
+		return err
+	}
+	return policy.Ingress.check(name + " ingress")
+}
+
+func (policy *subscriberPolicy) enabled() bool {
+	return policy.Egress.enabled() || policy.Ingress.enabled()
+}
+
+// check parses subscriber addresses and checks policers.
+func (cfg *policingConfig) check() error {
+	if err := cfg.Default.check("default"); err != nil {
+		return err
+	}
+	cfg.active = cfg.Default.enabled()
+	cfg.policies = map[interface{}]subscriberPolicy{}
+	for i := range cfg.Subscribers {
+		sc := &cfg.Subscribers[i]
+		name := sc.Address
+		var key interface{}
+		if sc.MAC != "" {
+			if sc.Address != "" {
+				return fmt.Errorf("Subscriber %s should have either address or MAC address", sc.Address)
+			}
+			mac, err := parseSubscriberMAC(sc.MAC)
+			if err != nil {
+				return err
+			}
+			name, key = sc.MAC, mac
+		} else {
+			ip := net.ParseIP(sc.Address)
+			if ip == nil {
+				return errors.New("Bad subscriber address " + sc.Address)
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				key, _ = convertIPv4(ip4)
+			} else {
+				var addr types.IPv6Address
+				copy(addr[:], ip.To16())
+				key = addr
+			}
+		}
+		policy := subscriberPolicy{
+			Egress:  sc.Egress,
+			Ingress: sc.Ingress,
+		}
+		if err := policy.check("of subscriber " + name); err != nil {
+			return err
+		}
+		if _, ok := cfg.policies[key]; ok {
+			return fmt.Errorf("Subscriber %s is listed more than once", name)
+		}
+		cfg.policies[key] = policy
+		cfg.active = cfg.active || policy.enabled()
+	}
+	return nil
+}
+
+func newPolicer(cfg policerConfig) policer {
+	now := time.Now()
+	p := policer{config: cfg}
+	p.committed = tokenBucket{
+		tokens: burstBytes(cfg.CIR, cfg.CBS),
+		last:   now,
+	}
+	p.peak = tokenBucket{
+		tokens: burstBytes(p.peakRate(), cfg.PBS),
+		last:   now,
+	}
+	return p
+}
+
+func (p *policer) peakRate() uint64 {
+	if p.config.PIR != 0 {
+		return p.config.PIR
+	}
+	return p.config.CIR
+}
+
+// police marks packet of specified length and returns false if it
+// should be dropped.
+func (p *policer) police(now time.Time, length uint) bool {
+	amount := float64(length)
+	if !p.config.enabled() {
+		atomic.AddUint64(&p.conformBytes, uint64(length))
+		return true
+	}
+
+	peakRate := p.peakRate()
+	p.peak.refill(now, bytesRate(peakRate), burstBytes(peakRate, p.config.PBS))
+	p.committed.refill(now, bytesRate(p.config.CIR), burstBytes(p.config.CIR, p.config.CBS))
+	if p.peak.tokens < amount {
+		atomic.AddUint64(&p.dropBytes, uint64(length))
+		return false
+	}
+	p.peak.tokens -= amount
+	if p.committed.tokens < amount {
+		atomic.AddUint64(&p.exceedBytes, uint64(length))
+		return true
+	}
+	p.committed.tokens -= amount
+	atomic.AddUint64(&p.conformBytes, uint64(length))
+	return true
+}
+
+// idle returns true if buckets of policer are full, so forgetting
+// policer doesn't change policing.
+func (p *policer) idle(now time.Time) bool {
+	if !p.config.enabled() {
+		return true
+	}
+	peakRate := p.peakRate()
+	return p.peak.full(now, bytesRate(peakRate), burstBytes(peakRate, p.config.PBS)) &&
+		p.committed.full(now, bytesRate(p.config.CIR), burstBytes(p.config.CIR, p.config.CBS))
+}
+
+func (sub *subscriber) idle(now time.Time) bool {
+	sub.mutex.Lock()
+	defer sub.mutex.Unlock()
+	return sub.egress.idle(now) && sub.ingress.idle(now)
+}
+
+func (p *policer) counters() [3]uint64 {
+	return [3]uint64{
+		atomic.LoadUint64(&p.conformBytes),
+		atomic.LoadUint64(&p.exceedBytes),
+		atomic.LoadUint64(&p.dropBytes),
+	}
+}
+
+// getSubscriber returns subscriber table entry of private host. Entry
+// is created when host sends or receives first packet. Policy of MAC
+// address of host takes precedence over policy of its address, and
+// entry keyed by address is removed when MAC address of host becomes
+// known. It returns nil if host is not policed.
+func (pp *portPair) getSubscriber(host interface{}) *subscriber {
+	key := host
+	if id := pp.hostIdentity(host); id != nil {
+		key = id.mac
+	}
+	if v, ok := pp.subscribers.Load(key); ok {
+		return v.(*subscriber)
+	}
+	policy, ok := pp.Policing.policies[key]
+	if !ok {
+		policy, ok = pp.Policing.policies[host]
+	}
+	if !ok {
+		policy = pp.Policing.Default
+	}
+	if !policy.enabled() {
+		return nil
+	}
+	if atomic.LoadInt32(&pp.subscribersCount) >= maxSubscribers {
+		pp.forgetIdleSubscribers(time.Now())
+	}
+	sub := &subscriber{
+		egress:  newPolicer(policy.Egress),
+		ingress: newPolicer(policy.Ingress),
+	}
+	v, loaded := pp.subscribers.LoadOrStore(key, sub)
+	if !loaded {
+		atomic.AddInt32(&pp.subscribersCount, 1)
+		if key != host {
+			if _, ok := pp.subscribers.Load(host); ok {
+				pp.subscribers.Delete(host)
+				atomic.AddInt32(&pp.subscribersCount, -1)
+			}
+		}
+	}
+	return v.(*subscriber)
+}
+
+// forgetIdleSubscribers removes subscribers which buckets are already
+// full, so forgetting them doesn't change policing, only their
+// counters start again from zero. If there are no such subscribers,
+// all subscribers are forgotten. Only one handler forgets subscribers
+// at a time, others don't wait for it.
+func (pp *portPair) forgetIdleSubscribers(now time.Time) {
+	if !atomic.CompareAndSwapInt32(&pp.subscribersForgetting, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&pp.subscribersForgetting, 0)
+
+	count := int32(0)
+	pp.subscribers.Range(func(k, v interface{}) bool {
+		if v.(*subscriber).idle(now) {
+			pp.subscribers.Delete(k)
+		} else {
+			count++
+		}
+		return true
+	})
+	if count >= maxSubscribers {
+		pp.subscribers.Range(func(k, v interface{}) bool {
+			pp.subscribers.Delete(k)
+			return true
+		})
+		count = 0
+	}
+	atomic.StoreInt32(&pp.subscribersCount, count)
+}
+
+// policeSubscriber checks packet of private host against its egress or
+// ingress policer. It returns false if packet should be dropped.
+// Host is either types.IPv4Address or types.IPv6Address.
+func (pp *portPair) policeSubscriber(host interface{}, egress bool, length uint) bool {
+	sub := pp.getSubscriber(host)
+	if sub == nil {
+		return true
+	}
+	now := time.Now()
+	sub.mutex.Lock()
+	defer sub.mutex.Unlock()
+	if egress {
+		return sub.egress.police(now, length)
+	}
+	return sub.ingress.police(now, length)
+}
+
+// policingActive returns true if packets of private hosts sent in
+// specified direction are policed.
+func (pp *portPair) policingActive(egress bool) bool {
+	return pp.Policing.active || (egress && pp.egressPolicer != nil)
+}
+
+// policeHost checks packet of private host against its subscriber
+// policer and egress packet against host class of egress policer too.
+// It returns false if packet should be dropped. Host is either
+// types.IPv4Address or types.IPv6Address.
+func (pp *portPair) policeHost(host interface{}, egress bool, length uint) bool {
+	if egress && pp.egressPolicer != nil && !pp.egressPolicer.allowHost(host, length) {
+		return false
+	}
+	return !pp.Policing.active || pp.policeSubscriber(host, egress, length)
+}
+
+// getSubscribersCounters returns counters of all subscribers in
+// subscriber table.
+func (pp *portPair) getSubscribersCounters() []subscriberCounters {
+	result := []subscriberCounters{}
+	pp.subscribers.Range(func(k, v interface{}) bool {
+		sub := v.(*subscriber)
+		sc := subscriberCounters{
+			address: k,
+			egress:  sub.egress.counters(),
+			ingress: sub.ingress.counters(),
+		}
+		if mac, ok := k.(types.MACAddress); ok {
+			sc.address = pp.PrivatePort.identities.addressOf(mac)
+			sc.mac, sc.hasMAC = mac, true
+		} else if id := pp.hostIdentity(k); id != nil {
+			sc.mac, sc.hasMAC = id.mac, true
+		}
+		result = append(result, sc)
+		return true
+	})
+	return result
+}
@@ -0,0 +1,43 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestForgetIdleSubscribers(t *testing.T) {
+	pp := &portPair{
+		Policing: policingConfig{
+			Default: subscriberPolicy{Egress: policerConfig{CIR: 8}},
+			active:  true,
+		},
+	}
+	busy, idle := hostIPv4(192, 168, 1, 1), hostIPv4(192, 168, 1, 2)
+	if !pp.policeSubscriber(busy, true, 1000) || pp.getSubscriber(idle) == nil {
+		t.Fatalf("Subscribers are not policed")
+	}
+
+	pp.forgetIdleSubscribers(time.Now())
+	if _, ok := pp.subscribers.Load(busy); !ok {
+		t.Errorf("Busy subscriber is forgotten")
+	}
+	if _, ok := pp.subscribers.Load(idle); ok {
+		t.Errorf("Idle subscriber is not forgotten")
+	}
+	if pp.subscribersCount != 1 {
+		t.Errorf("%d subscribers are counted, expected 1", pp.subscribersCount)
+	}
+
+	// Full subscriber table doesn't let new hosts pass unpoliced
+	pp.subscribersCount = maxSubscribers
+	if pp.getSubscriber(idle) == nil {
+		t.Errorf("New subscriber is not policed when subscriber table is full")
+	}
+	if pp.subscribersCount != 2 {
+		t.Errorf("%d subscribers are counted, expected 2", pp.subscribersCount)
+	}
+}
@@ -7,6 +7,7 @@ package nat
 import (
 	"errors"
 	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -33,42 +34,119 @@ func (port *ipPort) deletePortForwardingEntry(ipv6 bool, protocol uint8, portNum
 	port.translationTable[protocol].Delete(key)
 }
 
+// sessionRemainingLifetime returns time until dynamic session which
+// was used last at lastused expires, zero if it has already expired.
+func sessionRemainingLifetime(lastused, now time.Time) time.Duration {
+	remaining := lastused.Add(connectionTimeout).Sub(now)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
 func (pp *portPair) deleteOldConnection(ipv6 bool, protocol uint8, port int) {
 	pubTable := pp.PublicPort.translationTable[protocol]
 	pm := pp.getPublicPortPortmap(ipv6, protocol)
 
-	pub2priKey := pp.PublicPort.makePortAddrTuple(ipv6, uint16(port))
+	pub2priKey := pp.sessionPublicKey(ipv6, pm[port], uint16(port))
 	pri2pubKey, found := pubTable.Load(pub2priKey)
 
 	if found {
 		pp.PrivatePort.translationTable[protocol].Delete(pri2pubKey)
 		pubTable.Delete(pub2priKey)
 	}
+	if pm[port].static {
+		pp.PublicPort.deleteKNIForward(protocol, pub2priKey)
+	}
+	for _, k := range sourceDestinationKeys(pm[port].sources) {
+		pp.PrivatePort.translationTable[protocol].Delete(k)
+	}
+	pp.portFreed(&pm[port])
 	pm[port] = portMapEntry{}
 }
 
+// deleteSession removes dynamic session which has key tuple on port
+// and frees its public port immediately. It returns public port of
+// removed session.
+func (pp *portPair) deleteSession(port *ipPort, ipv6 bool, protocol uint8, key interface{}) (uint16, error) {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	pm := pp.getPublicPortPortmap(ipv6, protocol)
+	if pm == nil {
+		return 0, errors.New("Protocol " + strconv.Itoa(int(protocol)) + " has no sessions")
+	}
+	v, found := port.translationTable[protocol].Load(key)
+	if !found {
+		return 0, errors.New("Session is not found")
+	}
+	pubKey := key
+	if port.Type == iPRIVATE {
+		pubKey = v
+	}
+	_, _, pubPort, _ := getAddrFromTuple(pubKey, ipv6)
+	if pubPort < portStart || pubPort >= portEnd || pm[pubPort].static {
+		return 0, errors.New("Session is a forwarded port, it can be deleted only with port forwarding request")
+	}
+	// Public port may be already reused by another session
+	if pp.sessionPublicKey(ipv6, pm[pubPort], pubPort) != pubKey {
+		return 0, errors.New("Session is not found")
+	}
+	pp.deleteOldConnection(ipv6, protocol, int(pubPort))
+	return pubPort, nil
+}
+
 // This function currently is not thread safe and should be executed
-// under a global lock
-func (pp *portPair) allocNewPort(ipv6 bool, protocol uint8) (int, error) {
+// under a global lock. Private port of ICMP query is its identifier.
+func (pp *portPair) allocNewPort(ipv6 bool, protocol uint8, host interface{}, privPort uint16) (int, error) {
+	idleTimeout, ok := pp.sharePortPool(ipv6, protocol, host)
+	if !ok {
+		return 0, errors.New("Private host already has its share of public ports")
+	}
+	if p, found := pp.preservedICMPIdentifier(ipv6, protocol, privPort, connectionTimeout); found {
+		return p, nil
+	}
+	if p, found := pp.findFreePort(ipv6, protocol, connectionTimeout); found {
+		return p, nil
+	}
+	// Idle UDP mappings are reclaimed only when there are no expired
+	// ports
+	if idleTimeout < connectionTimeout {
+		if p, found := pp.findFreePort(ipv6, protocol, idleTimeout); found {
+			atomic.AddUint64(&pp.sharingStats.reclaimed, 1)
+			return p, nil
+		}
+	}
+	return 0, errors.New("WARNING! All ports are allocated! Trying again")
+}
+
+// findFreePort finds dynamic port which was not used for specified
+// time and deletes its old connection. Ports reserved for local
+// traffic are skipped. ICMP identifiers are searched in their range
+// starting from identifier that was allocated last.
+func (pp *portPair) findFreePort(ipv6 bool, protocol uint8, timeout time.Duration) (int, bool) {
 	pm := pp.getPublicPortPortmap(ipv6, protocol)
-	for {
-		for p := pp.lastport; p < portEnd; p++ {
-			if !pm[p].static && time.Since(pm[p].lastused) > connectionTimeout {
-				pp.lastport = p
-				pp.deleteOldConnection(ipv6, protocol, p)
-				return p, nil
-			}
+	first, end := pp.dynamicPortRange(protocol)
+	lastport := pp.allocationCursor(protocol)
+	if *lastport < first || *lastport >= end {
+		*lastport = first
+	}
+	for p := *lastport; p < end; p++ {
+		if !pm[p].static && !pp.isLocalPort(uint16(p)) && time.Since(pm[p].lastused) > timeout {
+			*lastport = p
+			pp.deleteOldConnection(ipv6, protocol, p)
+			return p, true
 		}
+	}
 
-		for p := portStart; p < pp.lastport; p++ {
-			if !pm[p].static && time.Since(pm[p].lastused) > connectionTimeout {
-				pp.lastport = p
-				pp.deleteOldConnection(ipv6, protocol, p)
-				return p, nil
-			}
+	for p := first; p < *lastport; p++ {
+		if !pm[p].static && !pp.isLocalPort(uint16(p)) && time.Since(pm[p].lastused) > timeout {
+			*lastport = p
+			pp.deleteOldConnection(ipv6, protocol, p)
+			return p, true
 		}
-		return 0, errors.New("WARNING! All ports are allocated! Trying again")
 	}
+	return 0, false
 }
 
 func (pp *portPair) getPublicPortPortmap(ipv6 bool, protocol uint8) []portMapEntry {
@@ -79,6 +157,25 @@ func (pp *portPair) getPublicPortPortmap(ipv6 bool, protocol uint8) []portMapEnt
 	}
 }
 
+// sessionPublicKey returns public lookup key of session which uses
+// public port. Dynamic IPv4 sessions may use address of VLAN
+// subinterface and dynamic IPv6 sessions may use temporary address.
+func (pp *portPair) sessionPublicKey(ipv6 bool, pme portMapEntry, portNumber uint16) interface{} {
+	if ipv6 && pme.temporary != nil {
+		return Tuple6{
+			addr: pme.temporary.addr,
+			port: portNumber,
+		}
+	}
+	if !ipv6 && pme.addr != 0 {
+		return Tuple{
+			addr: pme.addr,
+			port: portNumber,
+		}
+	}
+	return pp.PublicPort.makePortAddrTuple(ipv6, portNumber)
+}
+
 func (port *ipPort) makePortAddrTuple(ipv6 bool, portNumber uint16) interface{} {
 	if ipv6 {
 		return Tuple6{
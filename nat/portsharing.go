@@ -0,0 +1,242 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"container/heap"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+const defaultPortSharingThreshold = 90
+
+// Fair sharing of public port pool between private hosts when pool
+// nears exhaustion.
+type portSharingConfig struct {
+	// Utilization of port pool of a protocol in percents when
+	// sharing rules apply, zero means default
+	Threshold int `json:"threshold"`
+	// Ports reserved for every active host when pool utilization is
+	// above threshold. Hosts which have more ports get new ones only
+	// from ports which are not reserved for other hosts.
+	MinPortsPerHost int `json:"min-ports-per-host"`
+	// UDP mappings idle for this number of seconds are reclaimed
+	// when pool utilization is above threshold and there are no
+	// expired ports
+	UDPIdleTimeout int `json:"udp-idle-timeout"`
+}
+
+// Public port pool of one protocol.
+type portPoolKey struct {
+	ipv6     bool
+	protocol uint8
+}
+
+// Active dynamic ports of port pool. Ports are counted when they are
+// allocated to private hosts and uncounted when their sessions are
+// deleted or expire, so port pool is never recounted.
+type portPoolUsage struct {
+	active int
+	// Ports which active hosts lack to their minimum
+	reserved int
+	// Ports by types.IPv4Address or types.IPv6Address of private host
+	hosts map[interface{}]*hostPorts
+	// Counted ports by time when their sessions may expire
+	expiry portExpiryHeap
+}
+
+// Counted dynamic ports of private host in port pool.
+type hostPorts struct {
+	usage *portPoolUsage
+	host  interface{}
+	ports int
+}
+
+// Counted port which session expires not earlier than at.
+type portExpiry struct {
+	at    time.Time
+	port  uint16
+	owner *hostPorts
+}
+
+// Min heap of counted ports by expiration time.
+type portExpiryHeap []portExpiry
+
+// Port sharing counters which are reported by control API.
+type portSharingStats struct {
+	// New sessions refused because host had its share of ports
+	refused uint64
+	// UDP mappings reclaimed before session timeout
+	reclaimed uint64
+}
+
+func (cfg *portSharingConfig) enabled() bool {
+	return cfg.MinPortsPerHost != 0 || cfg.UDPIdleTimeout != 0
+}
+
+func (cfg *portSharingConfig) check() error {
+	if cfg.Threshold < 0 || cfg.Threshold > 100 {
+		return fmt.Errorf("Port sharing threshold should be between 0 and 100 percents")
+	}
+	if cfg.MinPortsPerHost < 0 || cfg.MinPortsPerHost > numPorts {
+		return fmt.Errorf("Port sharing min-ports-per-host should be between 0 and %d", numPorts)
+	}
+	if cfg.UDPIdleTimeout < 0 || time.Duration(cfg.UDPIdleTimeout)*time.Second >= connectionTimeout {
+		return fmt.Errorf("Port sharing udp-idle-timeout should be less than %d seconds", int(connectionTimeout.Seconds()))
+	}
+	return nil
+}
+
+func (cfg *portSharingConfig) threshold() int {
+	if cfg.Threshold == 0 {
+		return defaultPortSharingThreshold
+	}
+	return cfg.Threshold
+}
+
+func (h portExpiryHeap) Len() int           { return len(h) }
+func (h portExpiryHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h portExpiryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *portExpiryHeap) Push(x interface{}) {
+	*h = append(*h, x.(portExpiry))
+}
+
+func (h *portExpiryHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// reservation returns ports which active host with n ports lacks to
+// its minimum.
+func (cfg *portSharingConfig) reservation(n int) int {
+	if n == 0 || n >= cfg.MinPortsPerHost {
+		return 0
+	}
+	return cfg.MinPortsPerHost - n
+}
+
+// countHostPorts adds delta to ports of host. Mutex should be locked.
+func (pp *portPair) countHostPorts(h *hostPorts, delta int) {
+	usage := h.usage
+	usage.reserved -= pp.PortSharing.reservation(h.ports)
+	h.ports += delta
+	usage.active += delta
+	usage.reserved += pp.PortSharing.reservation(h.ports)
+	if h.ports == 0 {
+		delete(usage.hosts, h.host)
+	}
+}
+
+// getPortPoolUsage returns active ports of private hosts in public
+// port pool. Ports of sessions which expired are uncounted first,
+// each counted port is checked once per session timeout. Mutex should
+// be locked.
+func (pp *portPair) getPortPoolUsage(ipv6 bool, protocol uint8) *portPoolUsage {
+	key := portPoolKey{ipv6, protocol}
+	if pp.portPools == nil {
+		pp.portPools = map[portPoolKey]*portPoolUsage{}
+	}
+	usage, ok := pp.portPools[key]
+	if !ok {
+		usage = &portPoolUsage{
+			hosts: map[interface{}]*hostPorts{},
+		}
+		pp.portPools[key] = usage
+	}
+
+	now := time.Now()
+	pm := pp.getPublicPortPortmap(ipv6, protocol)
+	for len(usage.expiry) != 0 && !usage.expiry[0].at.After(now) {
+		e := heap.Pop(&usage.expiry).(portExpiry)
+		pme := &pm[e.port]
+		// Port was freed or reused
+		if pme.share != e.owner {
+			continue
+		}
+		if at := pme.lastused.Add(connectionTimeout); at.After(now) {
+			e.at = at
+			heap.Push(&usage.expiry, e)
+			continue
+		}
+		pme.share = nil
+		pp.countHostPorts(e.owner, -1)
+	}
+	return usage
+}
+
+// sharePortPool applies port sharing rules to new session of private
+// host. It returns timeout after which idle ports may be reused and
+// false if host should not get a new port. Mutex should be locked.
+func (pp *portPair) sharePortPool(ipv6 bool, protocol uint8, host interface{}) (time.Duration, bool) {
+	cfg := &pp.PortSharing
+	if !cfg.enabled() {
+		return connectionTimeout, true
+	}
+	usage := pp.getPortPoolUsage(ipv6, protocol)
+	if usage.active*100 < pp.portPoolSize(protocol)*cfg.threshold() {
+		return connectionTimeout, true
+	}
+	if cfg.MinPortsPerHost != 0 {
+		var ports int
+		if h, ok := usage.hosts[host]; ok {
+			ports = h.ports
+		}
+		// Hosts below their minimum always get free ports
+		if ports >= cfg.MinPortsPerHost && pp.portPoolSize(protocol)-usage.active <= usage.reserved {
+			atomic.AddUint64(&pp.sharingStats.refused, 1)
+			return 0, false
+		}
+	}
+	if cfg.UDPIdleTimeout != 0 && protocol == types.UDPNumber {
+		return time.Duration(cfg.UDPIdleTimeout) * time.Second, true
+	}
+	return connectionTimeout, true
+}
+
+// portAllocated counts port of new session of private host. Mutex
+// should be locked.
+func (pp *portPair) portAllocated(ipv6 bool, protocol uint8, port int, host interface{}) {
+	if !pp.PortSharing.enabled() {
+		return
+	}
+	usage := pp.getPortPoolUsage(ipv6, protocol)
+	h, ok := usage.hosts[host]
+	if !ok {
+		h = &hostPorts{
+			usage: usage,
+			host:  host,
+		}
+		usage.hosts[host] = h
+	}
+	pp.countHostPorts(h, 1)
+	pme := &pp.getPublicPortPortmap(ipv6, protocol)[port]
+	pme.share = h
+	heap.Push(&usage.expiry, portExpiry{
+		at:    pme.lastused.Add(connectionTimeout),
+		port:  uint16(port),
+		owner: h,
+	})
+}
+
+// portFreed uncounts port of session which is deleted. Mutex should be
+// locked.
+func (pp *portPair) portFreed(pme *portMapEntry) {
+	if pme.share != nil {
+		pp.countHostPorts(pme.share, -1)
+		pme.share = nil
+	}
+}
+
+// sharingCounters returns numbers of refused sessions and reclaimed
+// UDP mappings.
+func (pp *portPair) sharingCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&pp.sharingStats.refused), atomic.LoadUint64(&pp.sharingStats.reclaimed)
+}
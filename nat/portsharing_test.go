@@ -0,0 +1,124 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+// portSharingTestPair returns port pair which ICMP pool has ports
+// 1024-1033, port sharing applies above 5 active ports and reserves 2
+// ports for every active host.
+func portSharingTestPair() *portPair {
+	pp := &portPair{}
+	pp.PortSharing = portSharingConfig{
+		Threshold:       50,
+		MinPortsPerHost: 2,
+	}
+	pp.ICMPIdentifiers.Range = portSpan{1024, 1033}
+	pp.PublicPort.portmap = make([][]portMapEntry, 256)
+	pp.PublicPort.portmap[types.ICMPNumber] = make([]portMapEntry, portEnd)
+	return pp
+}
+
+func TestPortSharingReservation(t *testing.T) {
+	pp := portSharingTestPair()
+	pm := pp.PublicPort.portmap[types.ICMPNumber]
+	a, b, c, d := types.IPv4Address(1), types.IPv4Address(2), types.IPv4Address(3), types.IPv4Address(4)
+	next := 1024
+	allocate := func(host types.IPv4Address) {
+		pm[next].lastused = time.Now()
+		pp.portAllocated(false, types.ICMPNumber, next, host)
+		next++
+	}
+	allowed := func(host types.IPv4Address) bool {
+		_, ok := pp.sharePortPool(false, types.ICMPNumber, host)
+		return ok
+	}
+
+	steps := []struct {
+		name     string
+		allocate []types.IPv4Address
+		free     int
+		host     types.IPv4Address
+		allowed  bool
+		active   int
+		reserved int
+	}{
+		{"below threshold", []types.IPv4Address{a, a, a, a}, 0, a, true, 4, 0},
+		{"above threshold without other hosts", []types.IPv4Address{a}, 0, a, true, 5, 0},
+		{"free ports exceed reservations", []types.IPv4Address{b, c}, 0, a, true, 7, 2},
+		{"free ports are reserved", []types.IPv4Address{a}, 0, a, false, 8, 2},
+		{"host below minimum", nil, 0, b, true, 8, 2},
+		{"new host", nil, 0, d, true, 8, 2},
+		{"one reservation is left", []types.IPv4Address{b}, 0, a, false, 9, 1},
+		{"host takes reserved port", nil, 0, c, true, 9, 1},
+		{"freed port is not reserved", nil, 1024, a, true, 8, 1},
+		{"last free port is reserved for other host", []types.IPv4Address{a}, 0, b, false, 9, 1},
+	}
+	for _, s := range steps {
+		for _, host := range s.allocate {
+			allocate(host)
+		}
+		if s.free != 0 {
+			pp.portFreed(&pm[s.free])
+		}
+		if got := allowed(s.host); got != s.allowed {
+			t.Errorf("%s: host %d is allowed %v, expected %v", s.name, s.host, got, s.allowed)
+		}
+		usage := pp.getPortPoolUsage(false, types.ICMPNumber)
+		if usage.active != s.active || usage.reserved != s.reserved {
+			t.Errorf("%s: %d active ports and %d reserved, expected %d and %d", s.name, usage.active, usage.reserved, s.active, s.reserved)
+		}
+	}
+	if refused, _ := pp.sharingCounters(); refused != 3 {
+		t.Errorf("%d sessions are refused, expected 3", refused)
+	}
+}
+
+func TestPortSharingExpiry(t *testing.T) {
+	pp := portSharingTestPair()
+	pm := pp.PublicPort.portmap[types.ICMPNumber]
+	host, other := types.IPv4Address(1), types.IPv4Address(2)
+	for p := 1024; p < 1027; p++ {
+		pm[p].lastused = time.Now()
+		pp.portAllocated(false, types.ICMPNumber, p, host)
+	}
+	// Port 1026 is reused by other host
+	pp.portFreed(&pm[1026])
+	pm[1026] = portMapEntry{lastused: time.Now()}
+	pp.portAllocated(false, types.ICMPNumber, 1026, other)
+
+	// All ports are due to be checked, session of port 1024 expired
+	usage := pp.portPools[portPoolKey{false, types.ICMPNumber}]
+	old := time.Now().Add(-2 * connectionTimeout)
+	for i := range usage.expiry {
+		usage.expiry[i].at = old
+	}
+	pm[1024].lastused = old
+	pp.getPortPoolUsage(false, types.ICMPNumber)
+	if usage.active != 2 || usage.hosts[host].ports != 1 || usage.hosts[other].ports != 1 {
+		t.Fatalf("%d active ports, expected 2", usage.active)
+	}
+	if pm[1024].share != nil || pm[1025].share == nil {
+		t.Errorf("Expired port is counted or active port is not")
+	}
+	// Active ports are checked again when they may expire
+	if len(usage.expiry) != 2 || !usage.expiry[0].at.After(time.Now()) || !usage.expiry[1].at.After(time.Now()) {
+		t.Errorf("Active ports are not checked again")
+	}
+
+	pp.portFreed(&pm[1025])
+	pp.portFreed(&pm[1025])
+	if usage.active != 1 || usage.reserved != 1 {
+		t.Errorf("%d active ports and %d reserved after port is freed, expected 1 and 1", usage.active, usage.reserved)
+	}
+	if _, ok := usage.hosts[host]; ok {
+		t.Errorf("Host without ports is kept")
+	}
+}
@@ -0,0 +1,320 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+// Ports opened by triggers are checked this often and are closed when
+// their triggering session is gone
+const triggerCheckInterval = 5 * time.Second
+
+type triggerProtocol uint8
+
+var triggerProtocolLookup = map[string]triggerProtocol{
+	"TCP": types.TCPNumber,
+	"UDP": types.UDPNumber,
+}
+
+// Range of ports, single port has equal first and last port.
+type portSpan struct {
+	first uint16
+	last  uint16
+}
+
+// Port trigger rule. Outbound session of private host to trigger port
+// opens inbound ports to this host, the same public port is translated
+// to the same private port. Opened ports are closed when triggering
+// session ends. Rules apply to both IPv4 and IPv6.
+type portTrigger struct {
+	Protocol     triggerProtocol `json:"protocol"`
+	Ports        portSpan        `json:"ports"`
+	OpenProtocol triggerProtocol `json:"open-protocol"`
+	OpenPorts    []portSpan      `json:"open-ports"`
+}
+
+// Inbound port opened for private host by trigger.
+type triggerOpening struct {
+	ipv6     bool
+	protocol uint8
+	port     uint16
+	host     interface{}
+	// Protocol, public port and private tuple of triggering session
+	triggerProtocol uint8
+	triggerPort     uint16
+	triggerKey      interface{}
+}
+
+// UnmarshalJSON parses trigger protocol.
+func (out *triggerProtocol) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	result, ok := triggerProtocolLookup[s]
+	if !ok {
+		return errors.New("Bad port trigger protocol: " + s)
+	}
+
+	*out = result
+	return nil
+}
+
+// MarshalJSON writes protocol name as it is used in config file.
+func (protocol triggerProtocol) MarshalJSON() ([]byte, error) {
+	return json.Marshal(protocol.String())
+}
+
+// String returns protocol name as it is used in config file.
+func (protocol triggerProtocol) String() string {
+	for name, p := range triggerProtocolLookup {
+		if p == protocol {
+			return name
+		}
+	}
+	return "unknown(" + strconv.Itoa(int(protocol)) + ")"
+}
+
+// parsePortSpan parses port number or range of ports first-last.
+func parsePortSpan(s string) (portSpan, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) > 2 {
+		return portSpan{}, errors.New("Bad port range " + s)
+	}
+	var ports [2]uint16
+	for i := range ports {
+		p, err := strconv.ParseUint(parts[i%len(parts)], 10, 16)
+		if err != nil || p == 0 {
+			return portSpan{}, errors.New("Bad port range " + s)
+		}
+		ports[i] = uint16(p)
+	}
+	if ports[0] > ports[1] {
+		return portSpan{}, errors.New("Bad port range " + s)
+	}
+	return portSpan{
+		first: ports[0],
+		last:  ports[1],
+	}, nil
+}
+
+// UnmarshalJSON parses port number or string with port number or
+// range of ports.
+func (out *portSpan) UnmarshalJSON(b []byte) error {
+	var port uint16
+	if err := json.Unmarshal(b, &port); err == nil {
+		b = []byte(strconv.Quote(strconv.Itoa(int(port))))
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	span, err := parsePortSpan(s)
+	if err != nil {
+		return err
+	}
+	*out = span
+	return nil
+}
+
+// MarshalJSON writes port or range of ports as it is used in config file.
+func (span portSpan) MarshalJSON() ([]byte, error) {
+	return json.Marshal(span.String())
+}
+
+// String returns range as it is used in config file.
+func (span portSpan) String() string {
+	if span.first == span.last {
+		return strconv.Itoa(int(span.first))
+	}
+	return fmt.Sprintf("%d-%d", span.first, span.last)
+}
+
+func (span portSpan) contains(port uint16) bool {
+	return port >= span.first && port <= span.last
+}
+
+// check checks that rule has ports to open which fit into public port
+// maps.
+func (rule *portTrigger) check() error {
+	if rule.Protocol == 0 || rule.Ports.first == 0 || rule.OpenProtocol == 0 {
+		return errors.New("Port trigger should have protocol, ports and open protocol")
+	}
+	if len(rule.OpenPorts) == 0 {
+		return fmt.Errorf("Port trigger %s %s has no ports to open", rule.Protocol, rule.Ports)
+	}
+	for _, span := range rule.OpenPorts {
+		if span.last >= portEnd {
+			return fmt.Errorf("Port trigger %s %s opens port %d, opened ports should be less than %d",
+				rule.Protocol, rule.Ports, span.last, portEnd)
+		}
+	}
+	return nil
+}
+
+// portTriggers returns current trigger rules of port pair. List is
+// replaced when rules change, so it may be used by packet handlers
+// without locking.
+func (pp *portPair) portTriggers() []portTrigger {
+	list := pp.triggers.Load()
+	if list == nil {
+		return nil
+	}
+	return list.([]portTrigger)
+}
+
+// setPortTriggers checks rules and replaces all trigger rules of port
+// pair. Ports which are already open stay open until their triggering
+// sessions end.
+func (pp *portPair) setPortTriggers(rules []portTrigger) error {
+	for i := range rules {
+		if err := rules[i].check(); err != nil {
+			return err
+		}
+	}
+	pp.triggers.Store(rules)
+	return nil
+}
+
+// triggerPorts opens inbound ports of rules which are triggered by new
+// egress session of private host.
+func (pp *portPair) triggerPorts(ipv6 bool, protocol uint8, dstPort uint16, privKey interface{}, pubPort uint16) {
+	var host interface{}
+	if ipv6 {
+		host = privKey.(Tuple6).addr
+	} else {
+		host = privKey.(Tuple).addr
+	}
+	for _, rule := range pp.portTriggers() {
+		if uint8(rule.Protocol) != protocol || !rule.Ports.contains(dstPort) {
+			continue
+		}
+		pp.mutex.Lock()
+		for _, span := range rule.OpenPorts {
+			for p := int(span.first); p <= int(span.last); p++ {
+				pp.openTriggeredPort(&triggerOpening{
+					ipv6:            ipv6,
+					protocol:        uint8(rule.OpenProtocol),
+					port:            uint16(p),
+					host:            host,
+					triggerProtocol: protocol,
+					triggerPort:     pubPort,
+					triggerKey:      privKey,
+				})
+			}
+		}
+		pp.mutex.Unlock()
+	}
+}
+
+// openTriggeredPort adds translation of opened port. Ports which are
+// forwarded, reserved for local traffic, used by other sessions or
+// opened for other hosts are skipped. Port which is already opened for the same host is kept
+// open by the newest triggering session. This function should be
+// called under port pair lock.
+func (pp *portPair) openTriggeredPort(o *triggerOpening) {
+	pm := pp.getPublicPortPortmap(o.ipv6, o.protocol)
+	if pm == nil || pm[o.port].static || pp.isLocalPort(o.port) {
+		return
+	}
+	if old := pm[o.port].trigger; old != nil {
+		if old.host == o.host {
+			old.triggerProtocol = o.triggerProtocol
+			old.triggerPort = o.triggerPort
+			old.triggerKey = o.triggerKey
+		}
+		return
+	}
+	if time.Since(pm[o.port].lastused) <= connectionTimeout {
+		return
+	}
+
+	var pubEntry, privEntry interface{}
+	var vlanAddr types.IPv4Address
+	if o.ipv6 {
+		pubEntry = Tuple6{addr: pp.PublicPort.Subnet6.Addr, port: o.port}
+		privEntry = Tuple6{addr: o.host.(types.IPv6Address), port: o.port}
+	} else {
+		addr := pp.PublicPort.publicAddressForHost(o.host.(types.IPv4Address))
+		if addr != pp.PublicPort.Subnet.Addr {
+			vlanAddr = addr
+		}
+		pubEntry = Tuple{addr: addr, port: o.port}
+		privEntry = Tuple{addr: o.host.(types.IPv4Address), port: o.port}
+	}
+	// Private port may be used by session which host started itself
+	if _, found := pp.PrivatePort.translationTable[o.protocol].Load(privEntry); found {
+		return
+	}
+
+	pp.deleteOldConnection(o.ipv6, o.protocol, int(o.port))
+	pm[o.port] = portMapEntry{
+		created:  time.Now(),
+		lastused: time.Now(),
+		addr:     vlanAddr,
+		trigger:  o,
+	}
+	pp.PublicPort.translationTable[o.protocol].Store(pubEntry, privEntry)
+	pp.PrivatePort.translationTable[o.protocol].Store(privEntry, pubEntry)
+	pp.openings = append(pp.openings, o)
+}
+
+// triggerAlive returns true if triggering session of opened port is
+// still active. This function should be called under port pair lock.
+func (pp *portPair) triggerAlive(o *triggerOpening) bool {
+	pm := pp.getPublicPortPortmap(o.ipv6, o.triggerProtocol)
+	pme := pm[o.triggerPort]
+	if time.Since(pme.lastused) > connectionTimeout {
+		return false
+	}
+	v, found := pp.PublicPort.translationTable[o.triggerProtocol].Load(pp.sessionPublicKey(o.ipv6, pme, o.triggerPort))
+	return found && v == o.triggerKey
+}
+
+// checkOpenings keeps ports of active triggering sessions open and
+// closes other opened ports.
+func (pp *portPair) checkOpenings() {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	openings := pp.openings[:0]
+	for _, o := range pp.openings {
+		pm := pp.getPublicPortPortmap(o.ipv6, o.protocol)
+		// Opened port may be deleted by session delete request
+		if pm[o.port].trigger != o {
+			continue
+		}
+		if pp.triggerAlive(o) {
+			pm[o.port].lastused = time.Now()
+			openings = append(openings, o)
+			continue
+		}
+		pp.deleteOldConnection(o.ipv6, o.protocol, int(o.port))
+	}
+	pp.openings = openings
+}
+
+// StartPortTriggers starts closing ports opened by triggers. Trigger
+// rules may be set at runtime, so it runs even if there are none in
+// config.
+func StartPortTriggers() {
+	go func() {
+		for {
+			time.Sleep(triggerCheckInterval)
+			for i := range Natconfig.PortPairs {
+				Natconfig.PortPairs[i].checkOpenings()
+			}
+		}
+	}()
+}
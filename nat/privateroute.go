@@ -0,0 +1,145 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// Counters of packets which private port routed to private ports of
+// other port pairs.
+type privateRouteCounters struct {
+	routed uint64
+	// Destination MAC address was not resolved yet
+	unresolved uint64
+}
+
+// checkPrivateRoutes finds private ports of other port pairs which are
+// listed in private-routes. It is called after all port pairs are
+// checked.
+func (pp *portPair) checkPrivateRoutes() error {
+	pp.privateRoutes = nil
+	for _, index := range pp.PrivateRoutes {
+		var target *ipPort
+		for i := range Natconfig.PortPairs {
+			other := &Natconfig.PortPairs[i]
+			if other.PrivatePort.Index == index {
+				target = &other.PrivatePort
+				if other == pp {
+					return fmt.Errorf("Private port %s cannot route to itself", pp.PrivatePort.logName())
+				}
+			}
+		}
+		if target == nil {
+			return fmt.Errorf("Private route of port %s refers to port with index %d which is not a private port", pp.PrivatePort.logName(), index)
+		}
+		if (target.Vlan == 0) != (pp.PrivatePort.Vlan == 0) {
+			return fmt.Errorf("Private route between ports %s and %s cannot connect VLAN-enabled and VLAN-disabled networks",
+				pp.PrivatePort.logName(), target.logName())
+		}
+		for _, t := range pp.privateRoutes {
+			if t == target {
+				return fmt.Errorf("Private route of port %s to port %s is listed twice", pp.PrivatePort.logName(), target.logName())
+			}
+		}
+		pp.privateRoutes = append(pp.privateRoutes, target)
+	}
+	return nil
+}
+
+// findPrivateRoute returns private port of other port pair which
+// subnet contains destination of packet. Addresses of that port and
+// broadcasts are not routed.
+func (pp *portPair) findPrivateRoute(pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) *ipPort {
+	for _, target := range pp.privateRoutes {
+		if pktIPv4 != nil {
+			dst := packet.SwapBytesIPv4Addr(pktIPv4.DstAddr)
+			if target.Subnet.addressAcquired && target.Subnet.checkAddrWithingSubnet(dst) &&
+				dst != target.Subnet.Addr && dst != target.Subnet.broadcastAddr() {
+				return target
+			}
+		} else if target.Subnet6.addressAcquired && target.Subnet6.checkAddrWithingSubnet(pktIPv6.DstAddr) &&
+			pktIPv6.DstAddr != target.Subnet6.Addr {
+			return target
+		}
+	}
+	return nil
+}
+
+// routePrivate forwards packet from private port to host in private
+// subnet of other port pair without translation, like a router between
+// their networks does. Packet without MAC address of destination is
+// dropped while its neighbor is resolved. It returns false if packet
+// is not routed.
+func (pp *portPair) routePrivate(port *ipPort, pkt *packet.Packet, pktVLAN *packet.VLANHdr, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) (uint, bool) {
+	if len(pp.privateRoutes) == 0 {
+		return 0, false
+	}
+	target := pp.findPrivateRoute(pktIPv4, pktIPv6)
+	if target == nil {
+		return 0, false
+	}
+
+	var mac types.MACAddress
+	var found bool
+	if pktIPv4 != nil {
+		if hopLimitExpires(pktIPv4.TimeToLive) {
+			icmp := pkt.GetICMPForIPv4()
+			if !isIPv4LaterFragment(pktIPv4) && (icmp == nil || !isICMPError(types.ICMPNumber, icmp.Type)) {
+				port.sendTimeExceeded(pkt, pktIPv4, nil)
+			}
+			atomic.AddUint64(&port.ttlExpired, 1)
+			port.dumpPacket(pkt, DirDROP)
+			return DirDROP, true
+		}
+		mac, found = target.getMACForIPv4(packet.SwapBytesIPv4Addr(pktIPv4.DstAddr))
+	} else {
+		if hopLimitExpires(pktIPv6.HopLimits) {
+			if icmp := pkt.GetICMPForIPv6(); icmp == nil || !isICMPError(types.ICMPv6Number, icmp.Type) {
+				port.sendTimeExceeded(pkt, nil, pktIPv6)
+			}
+			atomic.AddUint64(&port.ttlExpired, 1)
+			port.dumpPacket(pkt, DirDROP)
+			return DirDROP, true
+		}
+		mac, found = target.getMACForIPv6(pktIPv6.DstAddr)
+	}
+	if !found {
+		atomic.AddUint64(&port.privateRouted.unresolved, 1)
+		port.dumpPacket(pkt, DirDROP)
+		return DirDROP, true
+	}
+
+	pkt.Ether.DAddr = mac
+	pkt.Ether.SAddr = target.SrcMACAddress
+	if pktVLAN != nil {
+		pktVLAN.SetVLANTagIdentifier(target.Vlan)
+	}
+	if pktIPv4 != nil {
+		decrementIPv4TTL(pktIPv4)
+	} else {
+		pktIPv6.HopLimits--
+	}
+
+	// Flow graph sends packets of port pair only to its own ports, so
+	// routed packet is sent as a copy
+	out, err := packet.NewPacket()
+	if err != nil {
+		port.dumpPacket(pkt, DirDROP)
+		return DirDROP, true
+	}
+	data := pkt.GetRawPacketBytes()
+	packet.GeneratePacketFromByte(out, data)
+	atomic.AddUint64(&port.privateRouted.routed, 1)
+	atomic.AddUint64(&target.stats.txPackets, 1)
+	atomic.AddUint64(&target.stats.txBytes, uint64(len(data)))
+	target.dumpPacket(out, DirSEND)
+	target.sendPacket(out)
+	return DirDROP, true
+}
@@ -0,0 +1,247 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"sync/atomic"
+
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// Fate of packets with IP protocols which NAT cannot translate
+// because they have no ports, e.g. GRE or OSPF.
+type unsupportedProtocolAction int
+
+const (
+	unsupportedDrop unsupportedProtocolAction = iota
+	// Send packets to KNI interface of port where they were received
+	unsupportedKNI
+	// Translate packets 1:1 between public port address and static
+	// NAT address of a private host
+	unsupportedPassThrough
+)
+
+var unsupportedProtocolActionLookup = map[string]unsupportedProtocolAction{
+	"drop":         unsupportedDrop,
+	"kni":          unsupportedKNI,
+	"pass-through": unsupportedPassThrough,
+}
+
+// Port pair policy for IP protocols which are not translated.
+type unsupportedProtocolsPolicy struct {
+	Action unsupportedProtocolAction `json:"action"`
+	// Private hosts which receive all inbound packets of
+	// unsupported protocols with pass-through action
+	StaticNATAddress  string `json:"static-nat-address"`
+	StaticNATAddress6 string `json:"static-nat-address6"`
+	addr4             types.IPv4Address
+	addr6             types.IPv6Address
+	hasAddr4          bool
+	hasAddr6          bool
+}
+
+// UnmarshalJSON parses unsupported protocols action.
+func (out *unsupportedProtocolAction) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	result, ok := unsupportedProtocolActionLookup[s]
+	if !ok {
+		return errors.New("Bad unsupported protocols action: " + s)
+	}
+
+	*out = result
+	return nil
+}
+
+// MarshalJSON writes action name as it is used in config file.
+func (action unsupportedProtocolAction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(action.String())
+}
+
+// String returns action name as it is used in config file.
+func (action unsupportedProtocolAction) String() string {
+	for name, a := range unsupportedProtocolActionLookup {
+		if a == action {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// check parses static NAT addresses and checks that they belong to
+// private port subnets.
+func (policy *unsupportedProtocolsPolicy) check(pp *portPair) error {
+	if policy.StaticNATAddress != "" {
+		ip := net.ParseIP(policy.StaticNATAddress).To4()
+		if ip == nil {
+			return errors.New("Bad static NAT IPv4 address: " + policy.StaticNATAddress)
+		}
+		policy.addr4, _ = convertIPv4(ip)
+		if !pp.PrivatePort.Subnet.checkAddrWithingSubnet(policy.addr4) {
+			return errors.New("Static NAT address " + policy.StaticNATAddress +
+				" should be within subnet " + pp.PrivatePort.Subnet.String())
+		}
+		policy.hasAddr4 = true
+	}
+	if policy.StaticNATAddress6 != "" {
+		ip := net.ParseIP(policy.StaticNATAddress6)
+		if ip == nil || ip.To4() != nil {
+			return errors.New("Bad static NAT IPv6 address: " + policy.StaticNATAddress6)
+		}
+		copy(policy.addr6[:], ip.To16())
+		if !pp.PrivatePort.Subnet6.checkAddrWithingSubnet(policy.addr6) {
+			return errors.New("Static NAT address " + policy.StaticNATAddress6 +
+				" should be within subnet " + pp.PrivatePort.Subnet6.String())
+		}
+		policy.hasAddr6 = true
+	}
+	if policy.Action == unsupportedPassThrough && !policy.hasAddr4 && !policy.hasAddr6 {
+		return errors.New("Pass-through action for unsupported protocols requires static-nat-address or static-nat-address6")
+	}
+	return nil
+}
+
+// handleUnsupportedProtocol applies port pair policy to a packet
+// which protocol has no translation. Packet is dumped according to
+// its fate.
+func (pp *portPair) handleUnsupportedProtocol(port *ipPort, pkt *packet.Packet, pktVLAN *packet.VLANHdr, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) uint {
+	var protocol uint8
+	if pktIPv4 != nil {
+		protocol = pktIPv4.NextProtoID
+	} else {
+		protocol = pktIPv6.Proto
+	}
+	atomic.AddUint64(&port.protocolPackets[protocol], 1)
+
+	dir := DirDROP
+	switch pp.UnsupportedProtocols.Action {
+	case unsupportedKNI:
+		if port.KNIName != "" {
+			dir = DirKNI
+		}
+	case unsupportedPassThrough:
+		if pp.passThrough(port, pkt, pktVLAN, pktIPv4, pktIPv6) {
+			// Translated packet cannot be quoted in error to its
+			// source, so expired packets are dropped silently
+			if !pp.forwardHopLimit(port, pkt, pktIPv4, pktIPv6, false) {
+				return DirDROP
+			}
+			port.opposite.dumpPacket(pkt, DirSEND)
+			return DirSEND
+		}
+	}
+	dir = port.steerKNI(protocol, 0, dir)
+	port.dumpPacket(pkt, dir)
+	return dir
+}
+
+// passThroughAddresses returns new address of packet which passes
+// through between public port address and static NAT address and
+// neighbor which opposite port sends it to. It returns false if
+// packet cannot be translated.
+func (pp *portPair) passThroughAddresses(port *ipPort, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) (interface{}, interface{}, bool) {
+	policy := &pp.UnsupportedProtocols
+	public := &pp.PublicPort
+	inbound := port.Type == iPUBLIC
+	if pktIPv4 != nil {
+		if !policy.hasAddr4 || !public.Subnet.addressAcquired {
+			return nil, nil, false
+		}
+		if inbound {
+			if packet.SwapBytesIPv4Addr(pktIPv4.DstAddr) != public.Subnet.Addr {
+				return nil, nil, false
+			}
+			return policy.addr4, policy.addr4, true
+		}
+		if packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr) != policy.addr4 {
+			return nil, nil, false
+		}
+		return public.Subnet.Addr, packet.SwapBytesIPv4Addr(pktIPv4.DstAddr), true
+	}
+	if !policy.hasAddr6 || !public.Subnet6.addressAcquired {
+		return nil, nil, false
+	}
+	if inbound {
+		if pktIPv6.DstAddr != public.Subnet6.Addr {
+			return nil, nil, false
+		}
+		return policy.addr6, policy.addr6, true
+	}
+	if pktIPv6.SrcAddr != policy.addr6 {
+		return nil, nil, false
+	}
+	return public.Subnet6.Addr, pktIPv6.DstAddr, true
+}
+
+// passThrough translates addresses of a packet between public port
+// address and static NAT address. It returns false if packet cannot
+// be translated.
+func (pp *portPair) passThrough(port *ipPort, pkt *packet.Packet, pktVLAN *packet.VLANHdr, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) bool {
+	addr, neighbor, ok := pp.passThroughAddresses(port, pktIPv4, pktIPv6)
+	if !ok {
+		return false
+	}
+	inbound := port.Type == iPUBLIC
+	dscp := &pp.DSCP.Egress
+	if inbound {
+		dscp = &pp.DSCP.Ingress
+	}
+
+	var mac types.MACAddress
+	var found bool
+	if pktIPv4 != nil {
+		if mac, found = port.opposite.getMACForIPv4(neighbor.(types.IPv4Address)); !found {
+			return false
+		}
+		if inbound {
+			pktIPv4.DstAddr = packet.SwapBytesIPv4Addr(addr.(types.IPv4Address))
+		} else {
+			pktIPv4.SrcAddr = packet.SwapBytesIPv4Addr(addr.(types.IPv4Address))
+		}
+		dscp.apply(pktIPv4, nil)
+		// Only IPv4 header checksum is updated because protocol
+		// is not known
+		if !NoCalculateChecksum {
+			pktIPv4.HdrChecksum = packet.SwapBytesUint16(packet.CalculateIPv4Checksum(pktIPv4))
+		}
+	} else {
+		if mac, found = port.opposite.getMACForIPv6(neighbor.(types.IPv6Address)); !found {
+			return false
+		}
+		if inbound {
+			pktIPv6.DstAddr = addr.(types.IPv6Address)
+		} else {
+			pktIPv6.SrcAddr = addr.(types.IPv6Address)
+		}
+		dscp.apply(nil, pktIPv6)
+	}
+
+	pkt.Ether.DAddr = mac
+	pkt.Ether.SAddr = port.opposite.SrcMACAddress
+	if pktVLAN != nil {
+		pktVLAN.SetVLANTagIdentifier(port.opposite.Vlan)
+	}
+	return true
+}
+
+// getProtocolCounters returns numbers of received packets of
+// unsupported protocols by protocol number. Protocols without
+// packets are omitted.
+func (port *ipPort) getProtocolCounters() map[uint8]uint64 {
+	result := map[uint8]uint64{}
+	for i := range port.protocolPackets {
+		if n := atomic.LoadUint64(&port.protocolPackets[i]); n != 0 {
+			result[uint8(i)] = n
+		}
+	}
+	return result
+}
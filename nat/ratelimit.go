@@ -0,0 +1,189 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Maximum number of sources tracked by one rate limiter. When it is
+// reached, idle sources are forgotten.
+const maxRateLimitSources = 65536
+
+// Limits for packets generated by NAT itself. Rates are in packets
+// per second, zero rate means no limit.
+type rateLimitConfig struct {
+	Rate           float64 `json:"rate"`
+	Burst          int     `json:"burst"`
+	PerSourceRate  float64 `json:"per-source-rate"`
+	PerSourceBurst int     `json:"per-source-burst"`
+}
+
+// Per-source buckets are kept separately for every port because
+// private networks of different port pairs may use the same
+// addresses.
+type limiterSource struct {
+	port uint16
+	addr interface{}
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter checks that packets generated by NAT don't exceed
+// global and per-source limits. It is called from packet handlers
+// running on several cores, so it is protected by a mutex. This is
+// acceptable because only packets generated by NAT are checked.
+type rateLimiter struct {
+	config    rateLimitConfig
+	mutex     sync.Mutex
+	global    tokenBucket
+	perSource map[limiterSource]*tokenBucket
+	// Number of packets which were not generated because of limits
+	limited uint64
+}
+
+var (
+	// Limits ICMP messages sent by NAT
+	icmpLimiter *rateLimiter
+	// Limits ARP and ND messages sent by NAT
+	neighborLimiter *rateLimiter
+)
+
+func (cfg *rateLimitConfig) check(name string) error {
+	if cfg.Rate < 0 || cfg.Burst < 0 || cfg.PerSourceRate < 0 || cfg.PerSourceBurst < 0 {
+		return fmt.Errorf("Values of %s should not be negative", name)
+	}
+	return nil
+}
+
+func burstSize(rate float64, burst int) float64 {
+	if burst > 0 {
+		return float64(burst)
+	}
+	if rate < 1 {
+		return 1
+	}
+	return rate
+}
+
+func newRateLimiter(cfg rateLimitConfig) *rateLimiter {
+	now := time.Now()
+	return &rateLimiter{
+		config: cfg,
+		global: tokenBucket{
+			tokens: burstSize(cfg.Rate, cfg.Burst),
+			last:   now,
+		},
+		perSource: map[limiterSource]*tokenBucket{},
+	}
+}
+
+// take refills bucket according to time passed since last packet and
+// takes one token from it if possible.
+func (tb *tokenBucket) take(now time.Time, rate, burst float64) bool {
+	return tb.takeAmount(now, rate, burst, 1)
+}
+
+// takeAmount refills bucket and takes specified number of tokens from
+// it if there are enough of them.
+func (tb *tokenBucket) takeAmount(now time.Time, rate, burst, amount float64) bool {
+	tb.refill(now, rate, burst)
+	if tb.tokens < amount {
+		return false
+	}
+	tb.tokens -= amount
+	return true
+}
+
+// full returns true if bucket would be full after refill.
+func (tb *tokenBucket) full(now time.Time, rate, burst float64) bool {
+	return tb.tokens+now.Sub(tb.last).Seconds()*rate >= burst
+}
+
+func (tb *tokenBucket) refill(now time.Time, rate, burst float64) {
+	tb.tokens += now.Sub(tb.last).Seconds() * rate
+	if tb.tokens > burst {
+		tb.tokens = burst
+	}
+	tb.last = now
+}
+
+// allow returns true if NAT may send one more packet through port in
+// response to or on behalf of specified source address. Source is
+// either types.IPv4Address or types.IPv6Address.
+func (rl *rateLimiter) allow(port *ipPort, addr interface{}) bool {
+	if rl == nil || (rl.config.Rate == 0 && rl.config.PerSourceRate == 0) {
+		return true
+	}
+	source := limiterSource{port.Index, addr}
+
+	now := time.Now()
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	// Tokens are taken only when both buckets allow packet, so that
+	// packet suppressed by one limit doesn't use up the other one
+	var tb *tokenBucket
+	if rl.config.PerSourceRate != 0 {
+		burst := burstSize(rl.config.PerSourceRate, rl.config.PerSourceBurst)
+		var ok bool
+		tb, ok = rl.perSource[source]
+		if !ok {
+			if len(rl.perSource) >= maxRateLimitSources {
+				rl.forgetIdleSources(now, burst)
+			}
+			tb = &tokenBucket{
+				tokens: burst,
+				last:   now,
+			}
+			rl.perSource[source] = tb
+		}
+		tb.refill(now, rl.config.PerSourceRate, burst)
+	}
+	if rl.config.Rate != 0 {
+		rl.global.refill(now, rl.config.Rate, burstSize(rl.config.Rate, rl.config.Burst))
+	}
+	if (tb != nil && tb.tokens < 1) || (rl.config.Rate != 0 && rl.global.tokens < 1) {
+		atomic.AddUint64(&rl.limited, 1)
+		return false
+	}
+	if tb != nil {
+		tb.tokens--
+	}
+	if rl.config.Rate != 0 {
+		rl.global.tokens--
+	}
+	return true
+}
+
+// forgetIdleSources removes sources which buckets are already full,
+// so forgetting them doesn't change limiting. If there are no such
+// sources, all sources are forgotten.
+func (rl *rateLimiter) forgetIdleSources(now time.Time, burst float64) {
+	idle := time.Duration(burst / rl.config.PerSourceRate * float64(time.Second))
+	for source, tb := range rl.perSource {
+		if now.Sub(tb.last) >= idle {
+			delete(rl.perSource, source)
+		}
+	}
+	if len(rl.perSource) >= maxRateLimitSources {
+		rl.perSource = map[limiterSource]*tokenBucket{}
+	}
+}
+
+// limitedPackets returns number of packets which were suppressed by
+// limiter.
+func (rl *rateLimiter) limitedPackets() uint64 {
+	if rl == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&rl.limited)
+}
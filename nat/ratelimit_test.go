@@ -0,0 +1,138 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+func TestBurstSize(t *testing.T) {
+	tests := []struct {
+		rate  float64
+		burst int
+		size  float64
+	}{
+		{10, 5, 5},
+		{10, 0, 10},
+		{0.5, 0, 1},
+		{0, 0, 1},
+	}
+	for _, tt := range tests {
+		if got := burstSize(tt.rate, tt.burst); got != tt.size {
+			t.Errorf("burstSize(%v, %d) = %v, expected %v", tt.rate, tt.burst, got, tt.size)
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Unix(1000, 0)
+	steps := []struct {
+		after   time.Duration
+		amount  float64
+		allowed bool
+	}{
+		// Bucket starts full with 2 tokens, rate is 10 tokens per second
+		{0, 1, true},
+		{0, 1, true},
+		{0, 1, false},
+		{50 * time.Millisecond, 1, false},
+		{100 * time.Millisecond, 1, true},
+		{100 * time.Millisecond, 1, false},
+		// Refill is capped by burst
+		{10 * time.Second, 3, false},
+		{10 * time.Second, 2, true},
+		{10 * time.Second, 0.5, false},
+	}
+	tb := &tokenBucket{
+		tokens: 2,
+		last:   start,
+	}
+	for i, s := range steps {
+		now := start.Add(s.after)
+		if got := tb.takeAmount(now, 10, 2, s.amount); got != s.allowed {
+			t.Errorf("Step %d: takeAmount(%v) = %v, expected %v, %v tokens left", i, s.amount, got, s.allowed, tb.tokens)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	port := &ipPort{Index: 1}
+	other := &ipPort{Index: 2}
+	host := types.IPv4Address(0x0a000001)
+	otherHost := types.IPv4Address(0x0a000002)
+
+	var disabled *rateLimiter
+	if !disabled.allow(port, host) || disabled.limitedPackets() != 0 {
+		t.Errorf("Missing limiter limits packets")
+	}
+	if rl := newRateLimiter(rateLimitConfig{}); !rl.allow(port, host) {
+		t.Errorf("Limiter without rates limits packets")
+	}
+
+	// Rates are low enough that test doesn't refill buckets
+	rl := newRateLimiter(rateLimitConfig{
+		Rate:           0.001,
+		Burst:          4,
+		PerSourceRate:  0.001,
+		PerSourceBurst: 2,
+	})
+	steps := []struct {
+		port    *ipPort
+		addr    interface{}
+		allowed bool
+	}{
+		{port, host, true},
+		{port, host, true},
+		{port, host, false},
+		// Other source has its own bucket
+		{port, otherHost, true},
+		// Same address on other port is other source
+		{other, host, true},
+		// Global burst is exhausted
+		{other, otherHost, false},
+		{other, host, false},
+	}
+	for i, s := range steps {
+		if got := rl.allow(s.port, s.addr); got != s.allowed {
+			t.Errorf("Step %d: allow(%d, %v) = %v, expected %v", i, s.port.Index, s.addr, got, s.allowed)
+		}
+	}
+	if n := rl.limitedPackets(); n != 3 {
+		t.Errorf("%d packets are limited, expected 3", n)
+	}
+
+	// Packets refused by global limit didn't take per-source tokens,
+	// and packet refused by per-source limit doesn't take global
+	// token
+	for i, allowed := range []bool{true, true, false} {
+		rl.global.tokens = 1
+		if got := rl.allow(other, otherHost); got != allowed {
+			t.Errorf("Packet %d after global refill: allow = %v, expected %v", i, got, allowed)
+		}
+	}
+	if rl.global.tokens < 1 {
+		t.Errorf("Per-source limit took global token")
+	}
+}
+
+func TestForgetIdleSources(t *testing.T) {
+	now := time.Unix(1000, 0)
+	rl := newRateLimiter(rateLimitConfig{
+		PerSourceRate: 1,
+	})
+	// Bucket of one token is refilled in one second
+	rl.perSource[limiterSource{1, types.IPv4Address(1)}] = &tokenBucket{last: now.Add(-2 * time.Second)}
+	rl.perSource[limiterSource{1, types.IPv4Address(2)}] = &tokenBucket{last: now}
+	rl.forgetIdleSources(now, 1)
+	if len(rl.perSource) != 1 {
+		t.Fatalf("%d sources are left, expected 1", len(rl.perSource))
+	}
+	if _, ok := rl.perSource[limiterSource{1, types.IPv4Address(2)}]; !ok {
+		t.Errorf("Active source is forgotten")
+	}
+}
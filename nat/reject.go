@@ -0,0 +1,281 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// Reaction to inbound packets which don't belong to any translation.
+type unsolicitedAction int
+
+const (
+	// Drop packets silently
+	unsolicitedDrop unsolicitedAction = iota
+	// Send TCP reset for TCP packets and ICMP port unreachable for
+	// UDP packets
+	unsolicitedReset
+	// Send ICMP port unreachable for TCP and UDP packets
+	unsolicitedUnreachable
+)
+
+const (
+	icmpTypeDestinationUnreachable   = 3
+	icmpCodePortUnreachable          = 3
+	icmpv6TypeDestinationUnreachable = 1
+	icmpv6CodePortUnreachable        = 4
+	icmpCodeFragmentationNeeded      = 4
+	// ICMPv6 error should not exceed minimum IPv6 MTU (RFC 4443)
+	icmpv6MaxQuoteLen = 1280 - types.IPv6Len - types.ICMPLen
+)
+
+var unsolicitedActionLookup = map[string]unsolicitedAction{
+	"drop":             unsolicitedDrop,
+	"reset":            unsolicitedReset,
+	"icmp-unreachable": unsolicitedUnreachable,
+}
+
+// Port pair policy for unsolicited inbound packets.
+type unsolicitedPolicy struct {
+	Action    unsolicitedAction `json:"action"`
+	RateLimit rateLimitConfig   `json:"rate-limit"`
+	limiter   *rateLimiter
+}
+
+// UnmarshalJSON parses unsolicited inbound packets action.
+func (out *unsolicitedAction) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	result, ok := unsolicitedActionLookup[s]
+	if !ok {
+		return errors.New("Bad unsolicited inbound action: " + s)
+	}
+
+	*out = result
+	return nil
+}
+
+// String returns action name as it is used in config file.
+func (action unsolicitedAction) String() string {
+	for name, a := range unsolicitedActionLookup {
+		if a == action {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// MarshalJSON writes action name as it is used in config file.
+func (action unsolicitedAction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(action.String())
+}
+
+// rejectUnsolicited answers inbound packet which doesn't belong to
+// any translation according to port pair policy. Packet itself is
+// dropped by caller.
+func (pp *portPair) rejectUnsolicited(pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr, pktTCP *packet.TCPHdr, pktUDP *packet.UDPHdr) {
+	policy := &pp.UnsolicitedInbound
+	action := policy.Action
+	// Port pair in maintenance tells remote hosts that it doesn't
+	// accept connections
+	if pp.maintenanceRejects() {
+		action = unsolicitedReset
+	}
+	if action == unsolicitedDrop || (pktTCP == nil && pktUDP == nil) {
+		return
+	}
+	port := &pp.PublicPort
+
+	// Answer only packets addressed to NAT itself. Packets sent to
+	// broadcast or multicast addresses are never answered.
+	if pkt.Ether.DAddr != port.SrcMACAddress {
+		return
+	}
+	var source interface{}
+	if pktIPv4 != nil {
+		if !port.Subnet.addressAcquired || packet.SwapBytesIPv4Addr(pktIPv4.DstAddr) != port.Subnet.Addr {
+			return
+		}
+		source = packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr)
+	} else {
+		if !port.Subnet6.addressAcquired ||
+			(pktIPv6.DstAddr != port.Subnet6.Addr && pktIPv6.DstAddr != port.Subnet6.llAddr && !port.isTemporaryAddress(pktIPv6.DstAddr)) {
+			return
+		}
+		source = pktIPv6.SrcAddr
+	}
+	// Resets are never answered
+	if pktTCP != nil && pktTCP.TCPFlags&types.TCPFlagRst != 0 {
+		return
+	}
+
+	if !policy.limiter.allow(port, source) {
+		return
+	}
+	if pktTCP != nil && action == unsolicitedReset {
+		port.sendTCPReset(pkt, pktIPv4, pktIPv6, pktTCP)
+	} else if icmpLimiter.allow(port, source) {
+		port.sendPortUnreachable(pkt, pktIPv4, pktIPv6)
+	}
+}
+
+// sendTCPReset answers TCP segment with reset according to RFC 793.
+func (port *ipPort) sendTCPReset(pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr, pktTCP *packet.TCPHdr) {
+	tcpHdrLen := uint32(pktTCP.DataOff&0xf0) >> 2
+	var segmentLen uint32
+	if pktIPv4 != nil {
+		ipHdrLen := uint32(pktIPv4.VersionIhl&0x0f) << 2
+		segmentLen = uint32(packet.SwapBytesUint16(pktIPv4.TotalLength)) - ipHdrLen - tcpHdrLen
+	} else {
+		segmentLen = uint32(packet.SwapBytesUint16(pktIPv6.PayloadLen)) - tcpHdrLen
+	}
+	if pktTCP.TCPFlags&types.TCPFlagSyn != 0 {
+		segmentLen++
+	}
+	if pktTCP.TCPFlags&types.TCPFlagFin != 0 {
+		segmentLen++
+	}
+
+	answerPacket, err := packet.NewPacket()
+	if err != nil {
+		common.LogFatal(common.Debug, err)
+	}
+	if pktIPv4 != nil {
+		packet.InitEmptyIPv4TCPPacket(answerPacket, 0)
+		ipv4 := answerPacket.GetIPv4NoCheck()
+		ipv4.TypeOfService = 0
+		ipv4.PacketID = 0
+		ipv4.FragmentOffset = 0
+		ipv4.SrcAddr = pktIPv4.DstAddr
+		ipv4.DstAddr = pktIPv4.SrcAddr
+	} else {
+		packet.InitEmptyIPv6TCPPacket(answerPacket, 0)
+		ipv6 := answerPacket.GetIPv6NoCheck()
+		ipv6.SrcAddr = pktIPv6.DstAddr
+		ipv6.DstAddr = pktIPv6.SrcAddr
+	}
+	answerPacket.Ether.SAddr = port.SrcMACAddress
+	answerPacket.Ether.DAddr = pkt.Ether.SAddr
+
+	tcp := answerPacket.GetTCPNoCheck()
+	tcp.SrcPort = pktTCP.DstPort
+	tcp.DstPort = pktTCP.SrcPort
+	if pktTCP.TCPFlags&types.TCPFlagAck != 0 {
+		tcp.SentSeq = pktTCP.RecvAck
+		tcp.RecvAck = 0
+		tcp.TCPFlags = types.TCPFlagRst
+	} else {
+		tcp.SentSeq = 0
+		tcp.RecvAck = packet.SwapBytesUint32(packet.SwapBytesUint32(pktTCP.SentSeq) + segmentLen)
+		tcp.TCPFlags = types.TCPFlagRst | types.TCPFlagAck
+	}
+	tcp.RxWin = 0
+	tcp.TCPUrp = 0
+
+	port.sendAnswer(answerPacket, pkt, pktIPv4 != nil, types.TCPNumber)
+}
+
+// sendPortUnreachable answers packet with ICMP or ICMPv6 port
+// unreachable error which quotes beginning of the packet.
+func (port *ipPort) sendPortUnreachable(pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) {
+	if pktIPv4 != nil {
+		port.sendICMPError(pkt, pktIPv4, nil, icmpTypeDestinationUnreachable, icmpCodePortUnreachable, pktIPv4.DstAddr, types.IPv6Address{}, 0)
+	} else {
+		port.sendICMPError(pkt, nil, pktIPv6, icmpv6TypeDestinationUnreachable, icmpv6CodePortUnreachable, 0, pktIPv6.DstAddr, 0)
+	}
+}
+
+// sendICMPError answers packet with ICMP or ICMPv6 error of specified
+// type and code which quotes beginning of the packet. Error is sent
+// from source address in network byte order or from IPv6 source
+// address. Second word of error is next, e.g. MTU of packet too big
+// error.
+func (port *ipPort) sendICMPError(pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr, icmpType, icmpCode uint8,
+	src4 types.IPv4Address, src6 types.IPv6Address, next uint32) {
+	l3offset := types.EtherLen
+	if pkt.Ether.EtherType == types.SwapVLANNumber {
+		l3offset += types.VLANLen
+	}
+	raw := pkt.GetRawPacketBytes()[l3offset:]
+
+	var quoteLen int
+	if pktIPv4 != nil {
+		// IP header and first 8 bytes of datagram (RFC 792)
+		quoteLen = int(pktIPv4.VersionIhl&0x0f)<<2 + 8
+	} else {
+		quoteLen = icmpv6MaxQuoteLen
+	}
+	if quoteLen > len(raw) {
+		quoteLen = len(raw)
+	}
+
+	answerPacket, err := packet.NewPacket()
+	if err != nil {
+		common.LogFatal(common.Debug, err)
+	}
+	if pktIPv4 != nil {
+		packet.InitEmptyIPv4ICMPPacket(answerPacket, uint(quoteLen))
+		ipv4 := answerPacket.GetIPv4NoCheck()
+		ipv4.TypeOfService = 0
+		ipv4.PacketID = 0
+		ipv4.FragmentOffset = 0
+		ipv4.SrcAddr = src4
+		ipv4.DstAddr = pktIPv4.SrcAddr
+	} else {
+		packet.InitEmptyIPv6ICMPPacket(answerPacket, uint(quoteLen))
+		ipv6 := answerPacket.GetIPv6NoCheck()
+		ipv6.SrcAddr = src6
+		ipv6.DstAddr = pktIPv6.SrcAddr
+	}
+	answerPacket.Ether.SAddr = port.SrcMACAddress
+	answerPacket.Ether.DAddr = pkt.Ether.SAddr
+
+	icmp := answerPacket.GetICMPNoCheck()
+	icmp.Type = icmpType
+	icmp.Code = icmpCode
+	// Unused or MTU fields of destination unreachable, time exceeded
+	// and packet too big messages
+	icmp.Identifier = packet.SwapBytesUint16(uint16(next >> 16))
+	icmp.SeqNum = packet.SwapBytesUint16(uint16(next))
+
+	payload, _ := answerPacket.GetPacketPayload()
+	copy(payload, raw[:quoteLen])
+
+	protocol := uint8(types.ICMPNumber)
+	if pktIPv6 != nil {
+		protocol = types.ICMPv6Number
+	}
+	port.sendAnswer(answerPacket, pkt, pktIPv4 != nil, protocol)
+}
+
+// sendAnswer tags answer with VLAN of original packet, calculates
+// checksums and sends answer.
+func (port *ipPort) sendAnswer(answerPacket, pkt *packet.Packet, ipv4 bool, protocol uint8) {
+	vlan := pkt.GetVLAN()
+	if vlan != nil {
+		answerPacket.AddVLANTag(packet.SwapBytesUint16(vlan.TCI))
+	}
+
+	switch {
+	case ipv4 && protocol == types.TCPNumber:
+		setIPv4TCPChecksum(answerPacket, !NoCalculateChecksum, port.hwTXChecksum)
+	case ipv4:
+		setIPv4ICMPChecksum(answerPacket, !NoCalculateChecksum, port.hwTXChecksum)
+	case protocol == types.TCPNumber:
+		setIPv6TCPChecksum(answerPacket, !NoCalculateChecksum, port.hwTXChecksum)
+	default:
+		setIPv6ICMPChecksum(answerPacket, !NoCalculateChecksum, port.hwTXChecksum)
+	}
+	port.dumpPacket(answerPacket, DirSEND)
+	port.sendPacket(answerPacket)
+}
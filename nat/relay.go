@@ -0,0 +1,188 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+const (
+	// Relay destination which means private subnet broadcast address
+	relayToBroadcast = "broadcast"
+
+	// Wake-on-LAN magic packet is sent to discard port
+	wolPort = 9
+	// Magic packet is 6 bytes of 0xff followed by 16 repetitions of
+	// target MAC address
+	wolSyncLen   = 6
+	wolMACRepeat = 16
+)
+
+// UDP port which datagrams received by public port are relayed to
+// private network, e.g. Wake-on-LAN or discovery protocols.
+type broadcastRelay struct {
+	Port uint16 `json:"port"`
+	// Either "broadcast" for private subnet broadcast address or
+	// address of a private host
+	Destination string `json:"destination"`
+	host        types.IPv4Address
+}
+
+// checkBroadcastRelays parses relay destinations and checks that
+// destination hosts belong to private port subnet.
+func (pp *portPair) checkBroadcastRelays() error {
+	ports := map[uint16]bool{}
+	for i := range pp.BroadcastRelay {
+		relay := &pp.BroadcastRelay[i]
+		if relay.Port == 0 {
+			return errors.New("Broadcast relay port should not be zero")
+		}
+		if ports[relay.Port] {
+			return fmt.Errorf("Broadcast relay for UDP port %d is specified more than once", relay.Port)
+		}
+		ports[relay.Port] = true
+
+		if relay.Destination == relayToBroadcast {
+			continue
+		}
+		ip := net.ParseIP(relay.Destination).To4()
+		if ip == nil {
+			return errors.New("Bad broadcast relay destination: " + relay.Destination)
+		}
+		relay.host, _ = convertIPv4(ip)
+		if !pp.PrivatePort.Subnet.checkAddrWithingSubnet(relay.host) {
+			return errors.New("Broadcast relay destination " + relay.Destination +
+				" should be within subnet " + pp.PrivatePort.Subnet.String())
+		}
+	}
+	return nil
+}
+
+// broadcastAddr returns directed broadcast address of subnet. Limited
+// broadcast address is used for single address subnets.
+func (subnet *ipv4Subnet) broadcastAddr() types.IPv4Address {
+	if subnet.Mask == 0xffffffff {
+		return BroadcastIPv4
+	}
+	return subnet.Addr | ^subnet.Mask
+}
+
+func (pp *portPair) findBroadcastRelay(port uint16) *broadcastRelay {
+	for i := range pp.BroadcastRelay {
+		if pp.BroadcastRelay[i].Port == port {
+			return &pp.BroadcastRelay[i]
+		}
+	}
+	return nil
+}
+
+// relayBroadcast forwards UDP datagram received by public port to
+// private network if its destination port is relayed. Datagram
+// should be addressed to public port address, public subnet
+// broadcast or limited broadcast address. Source address is not
+// translated. It returns false if packet is not relayed.
+func (pp *portPair) relayBroadcast(pkt *packet.Packet, pktVLAN *packet.VLANHdr, pktIPv4 *packet.IPv4Hdr, pktUDP *packet.UDPHdr) bool {
+	relay := pp.findBroadcastRelay(packet.SwapBytesUint16(pktUDP.DstPort))
+	if relay == nil {
+		return false
+	}
+	public := &pp.PublicPort
+	private := &pp.PrivatePort
+	if !public.Subnet.addressAcquired || !private.Subnet.addressAcquired {
+		return false
+	}
+	dst := packet.SwapBytesIPv4Addr(pktIPv4.DstAddr)
+	if dst != public.Subnet.Addr && dst != public.Subnet.broadcastAddr() && dst != BroadcastIPv4 {
+		return false
+	}
+	// Only first fragment contains UDP header, and checksum
+	// offloading doesn't support IP options
+	if pktIPv4.VersionIhl&0x0f != types.IPv4MinLen>>2 ||
+		packet.SwapBytesUint16(pktIPv4.FragmentOffset)&0x3fff != 0 {
+		return false
+	}
+	if pktIPv4.TimeToLive <= 1 {
+		return false
+	}
+
+	var mac types.MACAddress
+	var target types.IPv4Address
+	if relay.Destination == relayToBroadcast {
+		mac = BroadcastMAC
+		target = private.Subnet.broadcastAddr()
+	} else {
+		var found bool
+		if mac, found = private.getMACForIPv4(relay.host); !found {
+			return false
+		}
+		target = relay.host
+	}
+
+	pkt.Ether.DAddr = mac
+	pkt.Ether.SAddr = private.SrcMACAddress
+	if pktVLAN != nil {
+		pktVLAN.SetVLANTagIdentifier(private.Vlan)
+	}
+	pktIPv4.TimeToLive--
+	pktIPv4.DstAddr = packet.SwapBytesIPv4Addr(target)
+	setIPv4UDPChecksum(pkt, !NoCalculateChecksum, private.hwTXChecksum)
+	return true
+}
+
+// sendWakeOnLAN broadcasts Wake-on-LAN magic packet for specified MAC
+// address to port subnet. Optional SecureOn password is appended to
+// magic packet.
+func (port *ipPort) sendWakeOnLAN(mac types.MACAddress, password []byte) error {
+	if len(password) != 0 && len(password) != 4 && len(password) != types.EtherAddrLen {
+		return fmt.Errorf("Bad Wake-on-LAN password length %d", len(password))
+	}
+	if !port.Subnet.addressAcquired {
+		return fmt.Errorf("Interface with ID %d has no IPv4 address", port.Index)
+	}
+
+	pkt, err := packet.NewPacket()
+	if err != nil {
+		common.LogFatal(common.Debug, err)
+	}
+	magicLen := wolSyncLen + wolMACRepeat*types.EtherAddrLen
+	packet.InitEmptyIPv4UDPPacket(pkt, uint(magicLen+len(password)))
+	pkt.Ether.SAddr = port.SrcMACAddress
+	pkt.Ether.DAddr = BroadcastMAC
+
+	ipv4 := pkt.GetIPv4NoCheck()
+	ipv4.TypeOfService = 0
+	ipv4.PacketID = 0
+	ipv4.FragmentOffset = 0
+	ipv4.SrcAddr = packet.SwapBytesIPv4Addr(port.Subnet.Addr)
+	ipv4.DstAddr = packet.SwapBytesIPv4Addr(port.Subnet.broadcastAddr())
+
+	udp := pkt.GetUDPNoCheck()
+	udp.SrcPort = packet.SwapBytesUint16(wolPort)
+	udp.DstPort = packet.SwapBytesUint16(wolPort)
+
+	payload, _ := pkt.GetPacketPayload()
+	for i := 0; i < wolSyncLen; i++ {
+		payload[i] = 0xff
+	}
+	for i := 0; i < wolMACRepeat; i++ {
+		copy(payload[wolSyncLen+i*types.EtherAddrLen:], mac[:])
+	}
+	copy(payload[magicLen:], password)
+
+	if port.Vlan != 0 {
+		pkt.AddVLANTag(port.Vlan)
+	}
+
+	setIPv4UDPChecksum(pkt, !NoCalculateChecksum, port.hwTXChecksum)
+	port.dumpPacket(pkt, DirSEND)
+	port.sendPacket(pkt)
+	return nil
+}
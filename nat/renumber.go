@@ -0,0 +1,187 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"time"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+// Retired addresses are removed this often when their sessions are
+// gone
+const retiredAddressCheckInterval = 10 * time.Second
+
+// Public port address which was replaced by make-before-break
+// renumbering. Port still answers ARP or ND for it and translates
+// sessions which were created before address change, new sessions
+// use new address.
+type retiredAddress struct {
+	ipv6  bool
+	addr4 types.IPv4Address
+	// IPv6 address is referenced by its sessions like temporary
+	// address
+	addr6   *temporaryAddress
+	retired time.Time
+}
+
+func (r *retiredAddress) String() string {
+	if r.ipv6 {
+		return r.addr6.addr.String()
+	}
+	return r.addr4.String()
+}
+
+// retiredAddressList returns retired addresses of port. List is
+// replaced when addresses change, so it may be used by packet handlers
+// without locking.
+func (port *ipPort) retiredAddressList() []*retiredAddress {
+	list := port.retired.Load()
+	if list == nil {
+		return nil
+	}
+	return list.([]*retiredAddress)
+}
+
+func (port *ipPort) isRetiredIPv4Address(addr types.IPv4Address) bool {
+	for _, r := range port.retiredAddressList() {
+		if !r.ipv6 && r.addr4 == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func (port *ipPort) isRetiredIPv6Address(addr types.IPv6Address) bool {
+	for _, r := range port.retiredAddressList() {
+		if r.ipv6 && r.addr6.addr == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// isRetiredMulticastAddress returns true if address is solicited node
+// multicast address of retired IPv6 address.
+func (port *ipPort) isRetiredMulticastAddress(addr types.IPv6Address) bool {
+	for _, r := range port.retiredAddressList() {
+		if r.ipv6 && r.addr6.multicastAddr == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// retirePublicAddress keeps active dynamic sessions of current public
+// port address on this address before it is changed. Address is kept
+// as retired while it has sessions. It returns false if there are no
+// such sessions. Mutex should be locked.
+func (pp *portPair) retirePublicAddress(ipv6 bool) bool {
+	port := &pp.PublicPort
+	r := &retiredAddress{
+		ipv6:    ipv6,
+		retired: time.Now(),
+	}
+	if ipv6 {
+		if !port.Subnet6.addressAcquired {
+			return false
+		}
+		r.addr6 = newTemporaryAddress(port.Subnet6.Addr, r.retired)
+	} else {
+		if !port.Subnet.addressAcquired {
+			return false
+		}
+		r.addr4 = port.Subnet.Addr
+	}
+
+	kept := false
+	for _, protocol := range pp.sessionProtocols(ipv6) {
+		pm := pp.getPublicPortPortmap(ipv6, protocol)
+		if pm == nil {
+			continue
+		}
+		for p := portStart; p < portEnd; p++ {
+			pme := &pm[p]
+			if pme.static || time.Since(pme.lastused) > connectionTimeout {
+				continue
+			}
+			// Sessions of VLAN subinterfaces and temporary addresses
+			// don't use port address
+			if ipv6 && pme.temporary == nil {
+				pme.temporary = r.addr6
+				kept = true
+			} else if !ipv6 && pme.addr == 0 {
+				pme.addr = r.addr4
+				kept = true
+			}
+		}
+	}
+	if !kept {
+		return false
+	}
+	list := append(append([]*retiredAddress{}, port.retiredAddressList()...), r)
+	port.retired.Store(list)
+	println("Port", port.logName(), "keeps sessions of retired address", r.String())
+	return true
+}
+
+// expireRetiredAddresses removes retired addresses which have no
+// active sessions.
+func (pp *portPair) expireRetiredAddresses() {
+	port := &pp.PublicPort
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	list := port.retiredAddressList()
+	if len(list) == 0 {
+		return
+	}
+
+	used4 := map[types.IPv4Address]bool{}
+	used6 := map[*temporaryAddress]bool{}
+	for _, ipv6 := range pp.ipFamilies() {
+		for _, protocol := range pp.sessionProtocols(ipv6) {
+			pm := pp.getPublicPortPortmap(ipv6, protocol)
+			for p := portStart; p < portEnd; p++ {
+				if pm[p].static || time.Since(pm[p].lastused) > connectionTimeout {
+					continue
+				}
+				if ipv6 && pm[p].temporary != nil {
+					used6[pm[p].temporary] = true
+				} else if !ipv6 && pm[p].addr != 0 {
+					used4[pm[p].addr] = true
+				}
+			}
+		}
+	}
+
+	remaining := []*retiredAddress{}
+	for _, r := range list {
+		// Address may become port address again
+		if (r.ipv6 && used6[r.addr6] && r.addr6.addr != port.Subnet6.Addr) ||
+			(!r.ipv6 && used4[r.addr4] && r.addr4 != port.Subnet.Addr) {
+			remaining = append(remaining, r)
+			continue
+		}
+		println("Port", port.logName(), "removed retired address", r.String())
+	}
+	if len(remaining) != len(list) {
+		port.retired.Store(remaining)
+	}
+}
+
+// StartRetiredAddresses starts removing retired public addresses when
+// their sessions end. Addresses are retired by control API at
+// runtime, so it runs even if there are none.
+func StartRetiredAddresses() {
+	go func() {
+		for {
+			time.Sleep(retiredAddressCheckInterval)
+			for i := range Natconfig.PortPairs {
+				Natconfig.PortPairs[i].expireRetiredAddresses()
+			}
+		}
+	}()
+}
@@ -0,0 +1,228 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Longest interval between requests to one destination in
+	// milliseconds when it is not configured
+	defaultMaxResolutionBackoff = 10000
+	// Outstanding resolutions of port when maximum is not configured
+	defaultMaxOutstandingResolutions = 4096
+)
+
+// Pacing of ARP requests and IPv6 neighbor solicitations which NAT
+// sends to resolve destinations of packets, e.g. after start or flush
+// of neighbor table. Every packet to an unknown destination used to
+// send a request. With pacing, destination gets a resolution which
+// allows the next request only after backoff milliseconds, doubled
+// after every unanswered request up to max-backoff. Every interval
+// gets random jitter in percents, so that retries of many
+// destinations don't stay synchronized. New resolutions which exceed
+// rate are delayed by a random part of backoff, and their number is
+// limited by max-outstanding. Zero backoff disables pacing.
+type neighborResolutionConfig struct {
+	Backoff        int     `json:"backoff"`
+	MaxBackoff     int     `json:"max-backoff"`
+	Jitter         int     `json:"jitter"`
+	Rate           float64 `json:"rate"`
+	Burst          int     `json:"burst"`
+	MaxOutstanding int     `json:"max-outstanding"`
+}
+
+// Destination which is being resolved.
+type pendingResolution struct {
+	// Requests sent so far
+	attempts uint
+	// Time when next request may be sent
+	next time.Time
+}
+
+// Outstanding resolutions of port. Resolutions are created by packet
+// handlers running on several cores, so they are protected by a
+// mutex. Only packets to unknown destinations take it.
+type neighborResolver struct {
+	// Requests which were delayed by backoff or rate and new
+	// resolutions which were refused because of their number
+	deferred uint64
+	refused  uint64
+	mutex    sync.Mutex
+	// *pendingResolution by types.IPv4Address or types.IPv6Address
+	pending map[interface{}]*pendingResolution
+	bucket  tokenBucket
+	// Number of resolutions, read without lock when neighbors are
+	// learned
+	count int32
+}
+
+func (cfg *neighborResolutionConfig) enabled() bool {
+	return cfg.Backoff != 0
+}
+
+func (cfg *neighborResolutionConfig) check() error {
+	if cfg.Backoff < 0 || cfg.MaxBackoff < 0 || cfg.Rate < 0 || cfg.Burst < 0 || cfg.MaxOutstanding < 0 {
+		return errors.New("Values of neighbor-resolution should not be negative")
+	}
+	if cfg.Jitter < 0 || cfg.Jitter > 100 {
+		return errors.New("Neighbor-resolution jitter should be from 0 to 100 percents")
+	}
+	if !cfg.enabled() {
+		return nil
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = defaultMaxResolutionBackoff
+	}
+	if cfg.MaxBackoff < cfg.Backoff {
+		return errors.New("Neighbor-resolution max-backoff should not be less than backoff")
+	}
+	if cfg.MaxOutstanding == 0 {
+		cfg.MaxOutstanding = defaultMaxOutstandingResolutions
+	}
+	return nil
+}
+
+// interval returns time to wait after request number attempts with
+// jitter.
+func (cfg *neighborResolutionConfig) interval(attempts uint) time.Duration {
+	backoff := time.Duration(cfg.MaxBackoff) * time.Millisecond
+	if attempts < 32 {
+		if b := time.Duration(cfg.Backoff) * time.Millisecond << (attempts - 1); b < backoff {
+			backoff = b
+		}
+	}
+	return addJitter(backoff, cfg.Jitter)
+}
+
+// addJitter changes interval by random amount of up to jitter
+// percents in either direction.
+func addJitter(interval time.Duration, jitter int) time.Duration {
+	spread := int64(interval) * int64(jitter) / 100
+	if spread <= 0 {
+		return interval
+	}
+	return interval - time.Duration(spread) + time.Duration(rand.Int63n(2*spread+1))
+}
+
+// initNeighborResolver enables pacing of resolutions on port if it is
+// configured and port uses neighbor tables.
+func (port *ipPort) initNeighborResolver() {
+	cfg := &Natconfig.NeighborResolution
+	if !cfg.enabled() || port.staticArpMode {
+		return
+	}
+	port.resolver = &neighborResolver{
+		pending: map[interface{}]*pendingResolution{},
+		bucket: tokenBucket{
+			tokens: burstSize(cfg.Rate, cfg.Burst),
+			last:   time.Now(),
+		},
+	}
+}
+
+// expire removes resolutions which got no packets for maximum backoff
+// after their next request was allowed. Caller holds mutex.
+func (r *neighborResolver) expire(now time.Time, maxBackoff time.Duration) {
+	for ip, p := range r.pending {
+		if now.Sub(p.next) > maxBackoff {
+			delete(r.pending, ip)
+		}
+	}
+	atomic.StoreInt32(&r.count, int32(len(r.pending)))
+}
+
+// allowResolution returns true if NAT may send ARP request or neighbor
+// solicitation for ip which is types.IPv4Address in host byte order
+// or types.IPv6Address.
+func (port *ipPort) allowResolution(ip interface{}) bool {
+	r := port.resolver
+	if r == nil {
+		return true
+	}
+	cfg := &Natconfig.NeighborResolution
+	maxBackoff := time.Duration(cfg.MaxBackoff) * time.Millisecond
+	now := time.Now()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	p, found := r.pending[ip]
+	if found && now.Sub(p.next) > maxBackoff {
+		// Destination was not wanted for long, start over
+		delete(r.pending, ip)
+		found = false
+	}
+	if found {
+		if now.Before(p.next) {
+			atomic.AddUint64(&r.deferred, 1)
+			return false
+		}
+		p.attempts++
+		p.next = now.Add(cfg.interval(p.attempts))
+		return true
+	}
+
+	if len(r.pending) >= cfg.MaxOutstanding {
+		r.expire(now, maxBackoff)
+		if len(r.pending) >= cfg.MaxOutstanding {
+			atomic.AddUint64(&r.refused, 1)
+			return false
+		}
+	}
+	p = &pendingResolution{}
+	r.pending[ip] = p
+	atomic.StoreInt32(&r.count, int32(len(r.pending)))
+	if cfg.Rate != 0 && !r.bucket.take(now, cfg.Rate, burstSize(cfg.Rate, cfg.Burst)) {
+		// Request over rate is sent by a later packet after random
+		// part of backoff
+		p.next = now.Add(time.Duration(rand.Int63n(int64(cfg.Backoff)*int64(time.Millisecond) + 1)))
+		atomic.AddUint64(&r.deferred, 1)
+		return false
+	}
+	p.attempts = 1
+	p.next = now.Add(cfg.interval(p.attempts))
+	return true
+}
+
+// completeResolution removes resolution of neighbor which answered.
+func (port *ipPort) completeResolution(ip interface{}) {
+	r := port.resolver
+	if r == nil || atomic.LoadInt32(&r.count) == 0 {
+		return
+	}
+	r.mutex.Lock()
+	delete(r.pending, ip)
+	atomic.StoreInt32(&r.count, int32(len(r.pending)))
+	r.mutex.Unlock()
+}
+
+// flushResolutions forgets all resolutions, so that destinations of
+// flushed neighbor table are resolved with initial backoff.
+func (port *ipPort) flushResolutions() {
+	r := port.resolver
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	r.pending = map[interface{}]*pendingResolution{}
+	atomic.StoreInt32(&r.count, 0)
+	r.mutex.Unlock()
+}
+
+// resolutionCounters returns number of outstanding resolutions and
+// numbers of deferred requests and refused resolutions.
+func (port *ipPort) resolutionCounters() (uint64, uint64, uint64) {
+	r := port.resolver
+	r.mutex.Lock()
+	r.expire(time.Now(), time.Duration(Natconfig.NeighborResolution.MaxBackoff)*time.Millisecond)
+	count := len(r.pending)
+	r.mutex.Unlock()
+	return uint64(count), atomic.LoadUint64(&r.deferred), atomic.LoadUint64(&r.refused)
+}
@@ -0,0 +1,369 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// Router discovery constants from RFC 4861 and RFC 4191.
+const (
+	icmpv6RouterSolicitation  = 133
+	icmpv6RouterAdvertisement = 134
+	icmpv6Redirect            = 137
+	// Neighbor discovery messages are accepted only with this hop
+	// limit, so they cannot come from another link
+	ndHopLimit = 255
+	// Router solicitations sent when port starts and interval
+	// between them
+	maxRouterSolicitations     = 3
+	routerSolicitationInterval = 4 * time.Second
+	// Maximum number of default routers and redirected destinations
+	// which are remembered
+	maxDefaultRouters = 16
+	maxRedirects      = 4096
+	// Redirected destinations are forgotten after this time
+	redirectLifetime = 10 * time.Minute
+	// Expired routers and redirects are removed this often
+	routerExpiryInterval = time.Second
+)
+
+// Default router preferences from flags of router advertisement.
+const (
+	routerPreferenceLow = iota
+	routerPreferenceMedium
+	routerPreferenceHigh
+)
+
+var allRoutersMulticastAddr = types.IPv6Address{0xff, 0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x02}
+
+// Learning of IPv6 default routers of public port from router
+// advertisements. Destinations outside of port prefix are sent to
+// default router instead of being resolved directly.
+type routerDiscoveryConfig struct {
+	Enable bool `json:"enable"`
+	// Use next hops from ICMPv6 redirects sent by default router,
+	// they are ignored otherwise
+	AcceptRedirects bool `json:"accept-redirects"`
+}
+
+// Default router learned from router advertisement.
+type defaultRouter struct {
+	addr       types.IPv6Address
+	preference int
+	expires    time.Time
+}
+
+// Next hop of destination learned from redirect.
+type redirectEntry struct {
+	nextHop types.IPv6Address
+	expires time.Time
+}
+
+// Default routers of port. They are updated by packet handlers and
+// control code, so they are protected by a mutex, while packet
+// handlers read current router and redirects without locking.
+type routerState struct {
+	mutex   sync.Mutex
+	routers []defaultRouter
+	// Current default router, *defaultRouter which is nil when no
+	// router is known
+	current atomic.Value
+	// Next hops of redirected destinations, redirectEntry values by
+	// types.IPv6Address
+	redirects     sync.Map
+	redirectCount int32
+}
+
+func (cfg *routerDiscoveryConfig) check(port *ipPort) error {
+	if (cfg.Enable || cfg.AcceptRedirects) && port.Type != iPUBLIC {
+		return fmt.Errorf("Router discovery is allowed only on public port, private port %d has it", port.Index)
+	}
+	if cfg.AcceptRedirects && !cfg.Enable {
+		return fmt.Errorf("Redirects of port %d require router discovery", port.Index)
+	}
+	return nil
+}
+
+func isIPv6LinkLocal(addr types.IPv6Address) bool {
+	return addr[0] == 0xfe && addr[1]&0xc0 == 0x80
+}
+
+// currentRouter returns current default router or nil.
+func (port *ipPort) currentRouter() *defaultRouter {
+	v := port.routers.current.Load()
+	if v == nil {
+		return nil
+	}
+	return v.(*defaultRouter)
+}
+
+// nextHopIPv6 returns neighbor which should receive packet sent to
+// destination. Multicast, link local and port prefix destinations
+// are on link, other destinations are sent to their redirect target
+// or to default router. When no router is known,
+// destination is resolved directly.
+func (port *ipPort) nextHopIPv6(dst types.IPv6Address) types.IPv6Address {
+	now := time.Now()
+	if port.RouterDiscovery.AcceptRedirects && atomic.LoadInt32(&port.routers.redirectCount) != 0 {
+		if v, ok := port.routers.redirects.Load(dst); ok {
+			r := v.(redirectEntry)
+			if now.Before(r.expires) {
+				return r.nextHop
+			}
+		}
+	}
+	if dst[0] == 0xff || isIPv6LinkLocal(dst) || port.Subnet6.addressAcquired && port.Subnet6.checkAddrWithingSubnet(dst) {
+		return dst
+	}
+	if r := port.currentRouter(); r != nil && now.Before(r.expires) {
+		return r.addr
+	}
+	return dst
+}
+
+// ndOptionLinkLayerAddress finds source or target link layer address
+// option of neighbor discovery message.
+func ndOptionLinkLayerAddress(options []byte, optionType uint8) (types.MACAddress, bool) {
+	for len(options) >= 2 {
+		length := int(options[1]) * int(packet.ICMPv6NDMessageOptionUnitSize)
+		if length == 0 || length > len(options) {
+			break
+		}
+		if options[0] == optionType && length >= 8 {
+			var mac types.MACAddress
+			copy(mac[:], options[2:8])
+			return mac, true
+		}
+		options = options[length:]
+	}
+	return types.MACAddress{}, false
+}
+
+// validNDMessage checks that router message comes from link local
+// address of a neighbor on the same link.
+func validNDMessage(pkt *packet.Packet) bool {
+	pktIPv6 := pkt.GetIPv6NoCheck()
+	return isIPv6LinkLocal(pktIPv6.SrcAddr) && pktIPv6.HopLimits == ndHopLimit && pkt.GetICMPNoCheck().Code == 0
+}
+
+// handleRouterAdvertisement learns default router and its link layer
+// address from router advertisement. Router with zero lifetime is
+// forgotten.
+func (port *ipPort) handleRouterAdvertisement(pkt *packet.Packet) {
+	if !validNDMessage(pkt) {
+		return
+	}
+	icmp := pkt.GetICMPNoCheck()
+	// Hop limit, flags and router lifetime take place of echo
+	// identifier and sequence number
+	flags := uint8(packet.SwapBytesUint16(icmp.Identifier))
+	lifetime := time.Duration(packet.SwapBytesUint16(icmp.SeqNum)) * time.Second
+	payload, ok := pkt.GetPacketPayload()
+	// Reachable time and retransmission timer precede options
+	if !ok || len(payload) < 8 {
+		return
+	}
+	router := pkt.GetIPv6NoCheck().SrcAddr
+	if mac, found := ndOptionLinkLayerAddress(payload[8:], packet.ICMPv6NDSourceLinkLayerAddress); found {
+		port.storeNeighbor(router, mac)
+	}
+
+	preference := routerPreferenceMedium
+	switch (flags >> 3) & 3 {
+	case 1:
+		preference = routerPreferenceHigh
+	case 3:
+		preference = routerPreferenceLow
+	}
+
+	port.routers.mutex.Lock()
+	defer port.routers.mutex.Unlock()
+	i := 0
+	for ; i < len(port.routers.routers); i++ {
+		if port.routers.routers[i].addr == router {
+			break
+		}
+	}
+	switch {
+	case lifetime == 0 && i < len(port.routers.routers):
+		port.routers.routers = append(port.routers.routers[:i], port.routers.routers[i+1:]...)
+	case lifetime == 0:
+		return
+	case i < len(port.routers.routers):
+		port.routers.routers[i].preference = preference
+		port.routers.routers[i].expires = time.Now().Add(lifetime)
+	case len(port.routers.routers) < maxDefaultRouters:
+		port.routers.routers = append(port.routers.routers, defaultRouter{
+			addr:       router,
+			preference: preference,
+			expires:    time.Now().Add(lifetime),
+		})
+	}
+	port.selectDefaultRouter()
+}
+
+// handleRedirect remembers better next hop for destination which was
+// sent to current next hop. Target equal to destination means that
+// destination is on link.
+func (port *ipPort) handleRedirect(pkt *packet.Packet) {
+	if !port.RouterDiscovery.AcceptRedirects || !validNDMessage(pkt) {
+		return
+	}
+	payload, ok := pkt.GetPacketPayload()
+	// Reserved field is in place of echo identifier and sequence
+	// number, target and destination addresses precede options
+	if !ok || len(payload) < 32 {
+		return
+	}
+	var target, dst types.IPv6Address
+	copy(target[:], payload[:16])
+	copy(dst[:], payload[16:32])
+	if dst[0] == 0xff || (target != dst && !isIPv6LinkLocal(target)) {
+		return
+	}
+	// Only router which is current next hop may redirect
+	if port.nextHopIPv6(dst) != pkt.GetIPv6NoCheck().SrcAddr {
+		return
+	}
+	if mac, found := ndOptionLinkLayerAddress(payload[32:], packet.ICMPv6NDTargetLinkLayerAddress); found {
+		port.storeNeighbor(target, mac)
+	}
+	entry := redirectEntry{
+		nextHop: target,
+		expires: time.Now().Add(redirectLifetime),
+	}
+	if atomic.LoadInt32(&port.routers.redirectCount) >= maxRedirects {
+		if _, found := port.routers.redirects.Load(dst); !found {
+			return
+		}
+	}
+	if _, loaded := port.routers.redirects.LoadOrStore(dst, entry); loaded {
+		port.routers.redirects.Store(dst, entry)
+	} else {
+		atomic.AddInt32(&port.routers.redirectCount, 1)
+	}
+}
+
+// selectDefaultRouter makes router with the highest preference which
+// has not expired current. Mutex should be locked.
+func (port *ipPort) selectDefaultRouter() {
+	now := time.Now()
+	var best *defaultRouter
+	for i := range port.routers.routers {
+		r := &port.routers.routers[i]
+		if now.Before(r.expires) && (best == nil || r.preference > best.preference) {
+			best = r
+		}
+	}
+	old := port.currentRouter()
+	if best == nil {
+		if old != nil {
+			println("Port", port.logName(), "has no default IPv6 router")
+		}
+		port.routers.current.Store((*defaultRouter)(nil))
+		return
+	}
+	current := *best
+	if old == nil || old.addr != current.addr {
+		println("Port", port.logName(), "uses default IPv6 router", current.addr.String())
+	}
+	port.routers.current.Store(&current)
+}
+
+// expireRouters removes default routers which lifetime ended and
+// redirects which expired.
+func (port *ipPort) expireRouters() {
+	now := time.Now()
+	port.routers.mutex.Lock()
+	routers := port.routers.routers[:0]
+	for _, r := range port.routers.routers {
+		if now.Before(r.expires) {
+			routers = append(routers, r)
+		}
+	}
+	port.routers.routers = routers
+	port.selectDefaultRouter()
+	port.routers.mutex.Unlock()
+
+	port.routers.redirects.Range(func(k, v interface{}) bool {
+		if !now.Before(v.(redirectEntry).expires) {
+			port.routers.redirects.Delete(k)
+			atomic.AddInt32(&port.routers.redirectCount, -1)
+		}
+		return true
+	})
+}
+
+// StartRouterDiscovery solicits router advertisements on public ports
+// which learn default routers and starts expiration of learned
+// routers.
+func StartRouterDiscovery() {
+	for i := range Natconfig.PortPairs {
+		port := &Natconfig.PortPairs[i].PublicPort
+		if port.RouterDiscovery.Enable {
+			go port.runRouterDiscovery()
+		}
+	}
+}
+
+func (port *ipPort) runRouterDiscovery() {
+	solicitations := 0
+	lastSolicitation := time.Time{}
+	for {
+		// Solicitations are sent from link local address after it is
+		// checked
+		if solicitations < maxRouterSolicitations && port.currentRouter() == nil &&
+			!port.isTentative(port.Subnet6.llAddr) && time.Since(lastSolicitation) >= routerSolicitationInterval {
+			port.sendRouterSolicitation()
+			solicitations++
+			lastSolicitation = time.Now()
+		}
+		port.expireRouters()
+		time.Sleep(routerExpiryInterval)
+	}
+}
+
+// sendRouterSolicitation asks routers to send advertisements to all
+// nodes.
+func (port *ipPort) sendRouterSolicitation() {
+	requestPacket, err := packet.NewPacket()
+	if err != nil {
+		common.LogFatal(common.Debug, err)
+	}
+	packet.InitEmptyIPv6ICMPPacket(requestPacket, packet.ICMPv6NDSourceLinkLayerAddressOptionSize)
+
+	packet.CalculateIPv6BroadcastMACForDstMulticastIP(&requestPacket.Ether.DAddr, allRoutersMulticastAddr)
+	requestPacket.Ether.SAddr = port.SrcMACAddress
+
+	ipv6 := requestPacket.GetIPv6NoCheck()
+	ipv6.SrcAddr = port.Subnet6.llAddr
+	ipv6.DstAddr = allRoutersMulticastAddr
+
+	icmp := requestPacket.GetICMPNoCheck()
+	icmp.Type = icmpv6RouterSolicitation
+	icmp.Identifier = 0
+	icmp.SeqNum = 0
+	requestPacket.ParseL7(types.ICMPv6Number)
+	option := requestPacket.GetICMPv6NDSourceLinkLayerAddressOption(0)
+	option.Type = packet.ICMPv6NDSourceLinkLayerAddress
+	option.Length = uint8(packet.ICMPv6NDSourceLinkLayerAddressOptionSize / packet.ICMPv6NDMessageOptionUnitSize)
+	option.LinkLayerAddress = port.SrcMACAddress
+
+	if port.Vlan != 0 {
+		requestPacket.AddVLANTag(port.Vlan)
+	}
+
+	setIPv6ICMPChecksum(requestPacket, !NoCalculateChecksum, port.hwTXChecksum)
+	port.dumpPacket(requestPacket, DirSEND)
+	port.sendPacket(requestPacket)
+}
@@ -0,0 +1,129 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/json"
+)
+
+// Running configuration is configuration of NAT in config file format
+// as it is in effect now. It includes changes made with GRPC requests,
+// e.g. forwarded ports, static neighbors, port triggers and blackhole
+// rules, and addresses acquired with DHCP, so that it can be saved
+// and compared with config file.
+
+// MarshalJSON writes port settings in config file format. Destination
+// MAC address is written only in static ARP mode, and static
+// neighbors are taken from neighbor table.
+func (port *ipPort) MarshalJSON() ([]byte, error) {
+	type portSettings ipPort
+	out := struct {
+		*portSettings
+		DstMACAddress   string           `json:"dst-mac,omitempty"`
+		StaticNeighbors []staticNeighbor `json:"static-neighbors"`
+	}{
+		portSettings: (*portSettings)(port),
+	}
+	if port.staticArpMode {
+		out.DstMACAddress = port.DstMACAddress.String()
+	} else {
+		out.StaticNeighbors = port.currentStaticNeighbors()
+	}
+	return json.Marshal(&out)
+}
+
+// MarshalJSON writes port pair settings in config file format. Rules
+// which are replaced with GRPC requests are taken from their current
+// lists. Caller holds port pair mutex.
+func (pp *portPair) MarshalJSON() ([]byte, error) {
+	type pairSettings portPair
+	out := struct {
+		*pairSettings
+		EgressPolicer      egressPolicerConfig `json:"egress-policer"`
+		PortTriggers       []portTrigger       `json:"port-triggers"`
+		Blackhole          []*blackholeRule    `json:"blackhole"`
+		SessionLogSampling sessionLogSampling  `json:"session-log-sampling"`
+	}{
+		pairSettings:       (*pairSettings)(pp),
+		EgressPolicer:      pp.EgressPolicer,
+		PortTriggers:       pp.portTriggers(),
+		Blackhole:          pp.blackholeRules(),
+		SessionLogSampling: *pp.logSampling(),
+	}
+	if pp.egressPolicer != nil {
+		out.EgressPolicer = pp.egressPolicer.getConfig()
+	}
+	return json.Marshal(&out)
+}
+
+// managedPairs returns port pairs managed by server in config order.
+func (s *server) managedPairs() []*portPair {
+	pairs := []*portPair{}
+	for i := range Natconfig.PortPairs {
+		if s.managesPair(&Natconfig.PortPairs[i]) {
+			pairs = append(pairs, &Natconfig.PortPairs[i])
+		}
+	}
+	return pairs
+}
+
+// runningConfig returns running configuration in config file format.
+// Server of NAT instance returns only settings of its instance.
+func (s *server) runningConfig() ([]byte, error) {
+	return configDocument(Natconfig, s.instance, s.managedPairs())
+}
+
+// configDocument writes config with port pairs in config file format,
+// port pairs of NAT instances are written into their instances. Only
+// settings of instance and its port pairs are written if instance is
+// not nil.
+func configDocument(c *Config, instance *natInstance, pairs []*portPair) ([]byte, error) {
+	mainPairs := []json.RawMessage{}
+	instancePairs := map[*natInstance][]json.RawMessage{}
+	for _, pp := range pairs {
+		pp.mutex.Lock()
+		data, err := json.Marshal(pp)
+		pp.mutex.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		if pp.instance == nil {
+			mainPairs = append(mainPairs, data)
+		} else {
+			instancePairs[pp.instance] = append(instancePairs[pp.instance], data)
+		}
+	}
+
+	type instanceSettings natInstance
+	type runningInstance struct {
+		*instanceSettings
+		PortPairs []json.RawMessage `json:"port-pairs"`
+	}
+	if instance != nil {
+		return json.MarshalIndent(&runningInstance{
+			instanceSettings: (*instanceSettings)(instance),
+			PortPairs:        instancePairs[instance],
+		}, "", "    ")
+	}
+
+	type configSettings Config
+	out := struct {
+		*configSettings
+		PortPairs []json.RawMessage `json:"port-pairs"`
+		Instances []runningInstance `json:"instances"`
+	}{
+		configSettings: (*configSettings)(c),
+		PortPairs:      mainPairs,
+		Instances:      []runningInstance{},
+	}
+	for i := range c.Instances {
+		inst := &c.Instances[i]
+		out.Instances = append(out.Instances, runningInstance{
+			instanceSettings: (*instanceSettings)(inst),
+			PortPairs:        instancePairs[inst],
+		})
+	}
+	return json.MarshalIndent(&out, "", "    ")
+}
@@ -0,0 +1,408 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/flow"
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+const (
+	// Self test waits this long for links, addresses, neighbors and
+	// echo replies of every port
+	selfTestTimeout = 10 * time.Second
+	// Requests are repeated while self test waits
+	selfTestRetryInterval = 500 * time.Millisecond
+	// Echo identifier of self test probes, sequence number tells
+	// probes apart
+	selfTestEchoID = 0x5e1f
+)
+
+// Addresses which self test probes from port with ARP or neighbor
+// discovery and ICMP echo, e.g. gateways. Default routers learned
+// with DHCP and router advertisements are probed too.
+type selfTestConfig struct {
+	Targets []string `json:"targets"`
+	targets []interface{}
+}
+
+func (cfg *selfTestConfig) check(port *ipPort) error {
+	for _, s := range cfg.Targets {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return fmt.Errorf("Bad self test target address %s of port %d", s, port.Index)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			if port.ipv4Disabled {
+				return fmt.Errorf("Self test target %s of port %d requires IPv4 which is disabled", s, port.Index)
+			}
+			addr, _ := convertIPv4(ip4)
+			cfg.targets = append(cfg.targets, addr)
+		} else {
+			if port.ipv6Disabled {
+				return fmt.Errorf("Self test target %s of port %d requires IPv6 which is disabled", s, port.Index)
+			}
+			var addr types.IPv6Address
+			copy(addr[:], ip)
+			cfg.targets = append(cfg.targets, addr)
+		}
+	}
+	return nil
+}
+
+type selfTestResult int
+
+const (
+	selfTestPass selfTestResult = iota
+	selfTestWarn
+	selfTestFail
+)
+
+var selfTestResultNames = []string{"PASS", "WARN", "FAIL"}
+
+// ANSI colors of results when report is printed to terminal
+var selfTestResultColors = []string{"\033[32m", "\033[33m", "\033[31m"}
+
+type selfTestCheck struct {
+	port    *ipPort
+	name    string
+	result  selfTestResult
+	details string
+}
+
+// Echo reply which self test waits for.
+type selfTestProbeKey struct {
+	port uint16
+	seq  uint16
+	// types.IPv4Address or types.IPv6Address of probed target
+	addr interface{}
+}
+
+var (
+	// Set while self test runs, so that packet handlers don't look
+	// up probes otherwise
+	selfTestRunning int32
+	// Channels of probes which wait for echo replies by
+	// selfTestProbeKey
+	selfTestProbes sync.Map
+	selfTestSeq    uint32
+)
+
+// handleSelfTestReply returns true if ICMP packet sent to port address
+// is echo reply to self test probe.
+func (port *ipPort) handleSelfTestReply(protocol uint8, pkt *packet.Packet, icmp *packet.ICMPHdr) bool {
+	if atomic.LoadInt32(&selfTestRunning) == 0 ||
+		packet.SwapBytesUint16(icmp.Identifier) != selfTestEchoID || icmp.Code != 0 {
+		return false
+	}
+	key := selfTestProbeKey{
+		port: port.Index,
+		seq:  packet.SwapBytesUint16(icmp.SeqNum),
+	}
+	if protocol == types.ICMPNumber {
+		if icmp.Type != types.ICMPTypeEchoResponse {
+			return false
+		}
+		key.addr = packet.SwapBytesIPv4Addr(pkt.GetIPv4NoCheck().SrcAddr)
+	} else {
+		if icmp.Type != types.ICMPv6TypeEchoResponse {
+			return false
+		}
+		key.addr = pkt.GetIPv6NoCheck().SrcAddr
+	}
+	v, ok := selfTestProbes.Load(key)
+	if !ok {
+		return false
+	}
+	select {
+	case v.(chan struct{}) <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// sendEchoRequest sends ICMP or ICMPv6 echo request with identifier
+// id from port address to target.
+func (port *ipPort) sendEchoRequest(target interface{}, mac types.MACAddress, id, seq uint16) {
+	port.sendSizedEchoRequest(target, mac, id, seq, 0)
+}
+
+// sendSizedEchoRequest sends echo request which IP packet has size
+// bytes of zero data and may not be fragmented. Zero size means
+// request without data.
+func (port *ipPort) sendSizedEchoRequest(target interface{}, mac types.MACAddress, id, seq uint16, size int) {
+	pkt, err := packet.NewPacket()
+	if err != nil {
+		common.LogFatal(common.Debug, err)
+	}
+	ipv4addr, ipv4 := target.(types.IPv4Address)
+	if ipv4 {
+		var dataLen uint
+		if size != 0 {
+			dataLen = uint(size - types.IPv4MinLen - types.ICMPLen)
+		}
+		packet.InitEmptyIPv4ICMPPacket(pkt, dataLen)
+		hdr := pkt.GetIPv4NoCheck()
+		hdr.SrcAddr = packet.SwapBytesIPv4Addr(port.Subnet.Addr)
+		hdr.DstAddr = packet.SwapBytesIPv4Addr(ipv4addr)
+		if size != 0 {
+			hdr.FragmentOffset = packet.SwapBytesUint16(ipv4DontFragment)
+		}
+	} else {
+		ipv6addr := target.(types.IPv6Address)
+		var dataLen uint
+		if size != 0 {
+			dataLen = uint(size - types.IPv6Len - types.ICMPLen)
+		}
+		packet.InitEmptyIPv6ICMPPacket(pkt, dataLen)
+		hdr := pkt.GetIPv6NoCheck()
+		hdr.SrcAddr = port.Subnet6.Addr
+		if isIPv6LinkLocal(ipv6addr) {
+			hdr.SrcAddr = port.Subnet6.llAddr
+		}
+		hdr.DstAddr = ipv6addr
+	}
+	pkt.Ether.SAddr = port.SrcMACAddress
+	pkt.Ether.DAddr = mac
+
+	icmp := pkt.GetICMPNoCheck()
+	icmp.Type = types.ICMPv6TypeEchoRequest
+	if ipv4 {
+		icmp.Type = types.ICMPTypeEchoRequest
+	}
+	icmp.Code = 0
+	icmp.Identifier = packet.SwapBytesUint16(id)
+	icmp.SeqNum = packet.SwapBytesUint16(seq)
+	if size != 0 {
+		data, _ := pkt.GetPacketPayload()
+		for i := range data {
+			data[i] = 0
+		}
+	}
+
+	if port.Vlan != 0 {
+		pkt.AddVLANTag(port.Vlan)
+	}
+
+	if ipv4 {
+		setIPv4ICMPChecksum(pkt, !NoCalculateChecksum, port.hwTXChecksum)
+	} else {
+		setIPv6ICMPChecksum(pkt, !NoCalculateChecksum, port.hwTXChecksum)
+	}
+	port.dumpPacket(pkt, DirSEND)
+	port.sendPacket(pkt)
+}
+
+// waitFor calls condition until it returns true or deadline passes.
+func waitFor(deadline time.Time, condition func() bool) bool {
+	for {
+		if condition() {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(selfTestRetryInterval)
+	}
+}
+
+// selfTestTargets returns configured targets of port followed by
+// learned default routers which are not configured.
+func (port *ipPort) selfTestTargets() []interface{} {
+	targets := append([]interface{}{}, port.SelfTest.targets...)
+	known := map[interface{}]bool{}
+	for _, t := range targets {
+		known[t] = true
+	}
+	if port.Type != iPUBLIC {
+		return targets
+	}
+	if port.Subnet.addressAcquired && port.Subnet.ds.lease.router != nil {
+		if addr, err := convertIPv4(port.Subnet.ds.lease.router.To4()); err == nil && !known[addr] {
+			targets = append(targets, addr)
+		}
+	}
+	if r := port.currentRouter(); r != nil && !known[r.addr] {
+		targets = append(targets, r.addr)
+	}
+	return targets
+}
+
+// probeTarget resolves MAC address of target and checks that it
+// answers echo requests.
+func (port *ipPort) probeTarget(target interface{}, deadline time.Time) []selfTestCheck {
+	var name string
+	var resolve func() (types.MACAddress, bool)
+	addressAcquired := port.Subnet.addressAcquired
+	if addr, ok := target.(types.IPv4Address); ok {
+		name = addr.String()
+		resolve = func() (types.MACAddress, bool) {
+			return port.getMACForIPv4(addr)
+		}
+	} else {
+		addr := target.(types.IPv6Address)
+		name = addr.String()
+		addressAcquired = port.Subnet6.addressAcquired || isIPv6LinkLocal(addr)
+		resolve = func() (types.MACAddress, bool) {
+			return port.getMACForIPv6(addr)
+		}
+	}
+	if !addressAcquired {
+		return []selfTestCheck{{port, "probe " + name, selfTestFail, "port has no address of its family"}}
+	}
+
+	checks := []selfTestCheck{}
+	var mac types.MACAddress
+	if port.staticArpMode {
+		mac = port.DstMACAddress
+		checks = append(checks, selfTestCheck{port, "neighbor " + name, selfTestPass, "static dst-mac " + mac.String()})
+	} else if waitFor(deadline, func() bool {
+		var found bool
+		mac, found = resolve()
+		return found
+	}) {
+		checks = append(checks, selfTestCheck{port, "neighbor " + name, selfTestPass, "resolved to " + mac.String()})
+	} else {
+		return append(checks, selfTestCheck{port, "neighbor " + name, selfTestFail, "no ARP or neighbor advertisement reply"})
+	}
+
+	key := selfTestProbeKey{
+		port: port.Index,
+		seq:  uint16(atomic.AddUint32(&selfTestSeq, 1)),
+		addr: target,
+	}
+	replies := make(chan struct{}, 1)
+	selfTestProbes.Store(key, replies)
+	defer selfTestProbes.Delete(key)
+	for {
+		sent := time.Now()
+		port.sendEchoRequest(target, mac, selfTestEchoID, key.seq)
+		select {
+		case <-replies:
+			return append(checks, selfTestCheck{port, "echo " + name, selfTestPass,
+				"reply in " + time.Since(sent).Round(time.Microsecond).String()})
+		case <-time.After(selfTestRetryInterval):
+		}
+		if time.Now().After(deadline) {
+			return append(checks, selfTestCheck{port, "echo " + name, selfTestFail, "no echo reply"})
+		}
+	}
+}
+
+// selfTestPort checks MAC address, link, checksum offloading,
+// address and targets of port.
+func (port *ipPort) selfTestPort() []selfTestCheck {
+	deadline := time.Now().Add(selfTestTimeout)
+	checks := []selfTestCheck{}
+
+	if port.SrcMACAddress == (types.MACAddress{}) {
+		checks = append(checks, selfTestCheck{port, "MAC address", selfTestFail, "network card reported zero MAC address"})
+	} else {
+		checks = append(checks, selfTestCheck{port, "MAC address", selfTestPass, port.SrcMACAddress.String()})
+	}
+
+	if port.hwTXChecksum {
+		checks = append(checks, selfTestCheck{port, "checksum offloading", selfTestPass, "enabled"})
+	} else if flow.CheckHWCapability(flow.HWTXChecksumCapability, []uint16{port.Index})[0] && !port.ChecksumStrategy.allowsOffload() {
+		checks = append(checks, selfTestCheck{port, "checksum offloading", selfTestPass, "available, disabled by checksum-strategy " + port.ChecksumStrategy.String()})
+	} else if flow.CheckHWCapability(flow.HWTXChecksumCapability, []uint16{port.Index})[0] {
+		checks = append(checks, selfTestCheck{port, "checksum offloading", selfTestPass, "available, disabled with -nohwcsum"})
+	} else {
+		checks = append(checks, selfTestCheck{port, "checksum offloading", selfTestWarn, "not available, checksums are calculated in software"})
+	}
+
+	if waitFor(deadline, func() bool { return readLinkStatus(port.Index).up }) {
+		link := readLinkStatus(port.Index)
+		duplex := "half duplex"
+		if link.fullDuplex {
+			duplex = "full duplex"
+		}
+		checks = append(checks, selfTestCheck{port, "link", selfTestPass, fmt.Sprint(link.speed, " Mbps ", duplex)})
+	} else {
+		// Nothing can be received without link
+		return append(checks, selfTestCheck{port, "link", selfTestFail, "link is down, check cabling"})
+	}
+
+	if !port.ipv4Disabled {
+		if waitFor(deadline, func() bool { return port.Subnet.addressAcquired }) {
+			checks = append(checks, selfTestCheck{port, "IPv4 address", selfTestPass, port.Subnet.String()})
+		} else {
+			checks = append(checks, selfTestCheck{port, "IPv4 address", selfTestFail, "no DHCP lease"})
+		}
+	}
+	if !port.ipv6Disabled && port.Subnet6.addressAcquired {
+		if waitFor(deadline, func() bool { return !port.isTentative(port.Subnet6.Addr) }) {
+			checks = append(checks, selfTestCheck{port, "IPv6 address", selfTestPass, port.Subnet6.String()})
+		} else {
+			checks = append(checks, selfTestCheck{port, "IPv6 address", selfTestFail, "duplicate address detection did not finish"})
+		}
+	}
+
+	for _, target := range port.selfTestTargets() {
+		checks = append(checks, port.probeTarget(target, deadline)...)
+	}
+	return checks
+}
+
+// RunSelfTest checks all ports which were brought up and prints pass
+// or fail report. Packets should be already processed by flow
+// scheduler. It returns false if any check failed.
+func RunSelfTest() bool {
+	atomic.StoreInt32(&selfTestRunning, 1)
+	defer atomic.StoreInt32(&selfTestRunning, 0)
+
+	// Ports are checked concurrently because every port may wait
+	// until its timeout
+	results := make([][]selfTestCheck, len(Natconfig.PortPairs)*2)
+	var wg sync.WaitGroup
+	for i := range Natconfig.PortPairs {
+		pp := &Natconfig.PortPairs[i]
+		for j, port := range []*ipPort{&pp.PublicPort, &pp.PrivatePort} {
+			wg.Add(1)
+			go func(n int, port *ipPort) {
+				defer wg.Done()
+				results[n] = port.selfTestPort()
+			}(i*2+j, port)
+		}
+	}
+	wg.Wait()
+
+	colored := false
+	if fi, err := os.Stdout.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		colored = true
+	}
+	failed, warned := 0, 0
+	fmt.Println("Self test report:")
+	for _, checks := range results {
+		for _, c := range checks {
+			result := selfTestResultNames[c.result]
+			if colored {
+				result = selfTestResultColors[c.result] + result + "\033[0m"
+			}
+			side := "public"
+			if c.port.Type == iPRIVATE {
+				side = "private"
+			}
+			fmt.Printf("%s  %-7s port %-20s %-28s %s\n", result, side, c.port.logName(), c.name, c.details)
+			switch c.result {
+			case selfTestFail:
+				failed++
+			case selfTestWarn:
+				warned++
+			}
+		}
+	}
+	fmt.Printf("Self test finished: %d failed, %d warnings\n", failed, warned)
+	return failed == 0
+}
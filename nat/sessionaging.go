@@ -0,0 +1,270 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/intel-go/nff-go/types"
+
+	upd "github.com/intel-go/nff-go-nat/updatecfg"
+)
+
+// Upper bounds of lifetime histogram buckets in seconds when they are
+// not configured
+var defaultSessionAgingBuckets = []int{1, 5, 10, 30, 60, 120, 300, 600, 1800, 3600}
+
+// Protocols of lifetime histograms, ICMP and ICMPv6 sessions share one
+// histogram. UDP-Lite and DCCP histograms are reported only by port
+// pairs which translate them.
+var sessionAgingProtocols = []struct {
+	name   string
+	number uint8
+}{
+	{"tcp", types.TCPNumber},
+	{"udp", types.UDPNumber},
+	{"icmp", types.ICMPNumber},
+	{"udplite", udpLiteNumber},
+	{"dccp", dccpNumber},
+}
+
+// Histograms of lifetimes of ended dynamic sessions. Session tables
+// are scanned every interval seconds, zero interval disables
+// histograms. Sessions which disappeared since previous scan ended,
+// so lifetimes and reasons are accurate to scan interval.
+type sessionAgingConfig struct {
+	Interval int `json:"interval"`
+	// Upper bounds of histogram buckets in seconds in increasing
+	// order, there is also a bucket for longer lifetimes
+	Buckets []int `json:"buckets"`
+}
+
+// Lifetimes from creation of session to its last packet.
+type lifetimeHistogram struct {
+	// Sessions by bucket, last one counts sessions longer than all
+	// bounds
+	counts []uint64
+	count  uint64
+	sum    time.Duration
+}
+
+// Session seen by scan, IP family, protocol and public port identify
+// it.
+type agingSessionKey struct {
+	ipv6     bool
+	protocol uint8
+	port     uint16
+}
+
+type agingSession struct {
+	created  time.Time
+	lastused time.Time
+}
+
+// Lifetime histograms of port pair by protocol. Durations count all
+// ended sessions, idle counts sessions which ended because they saw no
+// packets for connection timeout. The rest were closed by TCP FIN or
+// RST or deleted with GRPC request.
+type sessionAging struct {
+	mutex     sync.Mutex
+	durations []lifetimeHistogram
+	idle      []lifetimeHistogram
+	// Sessions seen by previous scan, used only by scanner
+	known map[agingSessionKey]agingSession
+}
+
+func (cfg *sessionAgingConfig) enabled() bool {
+	return cfg.Interval != 0
+}
+
+func (cfg *sessionAgingConfig) check() error {
+	if cfg.Interval < 0 {
+		return errors.New("Session-aging interval should not be negative")
+	}
+	if len(cfg.Buckets) == 0 {
+		cfg.Buckets = defaultSessionAgingBuckets
+	}
+	for i, b := range cfg.Buckets {
+		if b <= 0 || (i != 0 && b <= cfg.Buckets[i-1]) {
+			return errors.New("Session-aging buckets should be positive and increasing")
+		}
+	}
+	return nil
+}
+
+// agingHistogramIndex returns index of lifetime histogram of protocol.
+func agingHistogramIndex(protocol uint8) int {
+	if protocol == types.ICMPv6Number {
+		protocol = types.ICMPNumber
+	}
+	for i, p := range sessionAgingProtocols {
+		if p.number == protocol {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasAgingHistogram returns true if port pair has sessions of protocol
+// of lifetime histogram with index pi.
+func (pp *portPair) hasAgingHistogram(pi int) bool {
+	protocol := sessionAgingProtocols[pi].number
+	return !hasUDPLayout(protocol) || pp.translatesProtocol(protocol)
+}
+
+func newLifetimeHistograms(buckets int) []lifetimeHistogram {
+	h := make([]lifetimeHistogram, len(sessionAgingProtocols))
+	for i := range h {
+		h[i].counts = make([]uint64, buckets+1)
+	}
+	return h
+}
+
+func newSessionAging(cfg *sessionAgingConfig) *sessionAging {
+	return &sessionAging{
+		durations: newLifetimeHistograms(len(cfg.Buckets)),
+		idle:      newLifetimeHistograms(len(cfg.Buckets)),
+		known:     map[agingSessionKey]agingSession{},
+	}
+}
+
+func (h *lifetimeHistogram) observe(buckets []int, lifetime time.Duration) {
+	if lifetime < 0 {
+		lifetime = 0
+	}
+	i := 0
+	for i < len(buckets) && lifetime > time.Duration(buckets[i])*time.Second {
+		i++
+	}
+	h.counts[i]++
+	h.count++
+	h.sum += lifetime
+}
+
+func copyLifetimeHistograms(in []lifetimeHistogram) []lifetimeHistogram {
+	out := make([]lifetimeHistogram, len(in))
+	for i := range in {
+		out[i] = in[i]
+		out[i].counts = append([]uint64{}, in[i].counts...)
+	}
+	return out
+}
+
+// sessionLifetimes returns copies of histograms of all ended sessions
+// and of sessions which ended by idle timeout.
+func (pp *portPair) sessionLifetimes() ([]lifetimeHistogram, []lifetimeHistogram) {
+	a := pp.aging
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return copyLifetimeHistograms(a.durations), copyLifetimeHistograms(a.idle)
+}
+
+// sessionLifetimeCounters returns histograms as cumulative counters
+// of sessions which lifetime is less or equal to bucket bound, e.g.
+// session-duration-tcp-le-60, and sums of lifetimes in seconds.
+func (pp *portPair) sessionLifetimeCounters() []*upd.Counter {
+	counters := []*upd.Counter{}
+	durations, idle := pp.sessionLifetimes()
+	add := func(name string, h *lifetimeHistogram) {
+		total := uint64(0)
+		for i, c := range h.counts {
+			total += c
+			bound := "inf"
+			if i < len(Natconfig.SessionAging.Buckets) {
+				bound = strconv.Itoa(Natconfig.SessionAging.Buckets[i])
+			}
+			counters = append(counters, &upd.Counter{Name: name + "-le-" + bound, Value: total})
+		}
+		counters = append(counters, &upd.Counter{Name: name + "-sum-seconds", Value: uint64(h.sum.Seconds())})
+	}
+	for pi, protocol := range sessionAgingProtocols {
+		if !pp.hasAgingHistogram(pi) {
+			continue
+		}
+		add("session-duration-"+protocol.name, &durations[pi])
+		add("session-idle-expired-"+protocol.name, &idle[pi])
+	}
+	return counters
+}
+
+// scanSessionAging compares dynamic sessions of port pair with
+// previous scan and adds lifetimes of sessions which ended to
+// histograms. Session which entry is unchanged but expired ended by
+// idle timeout, ports of idle sessions may be reused by new sessions
+// before scan sees them.
+func (pp *portPair) scanSessionAging(buckets []int, now time.Time) {
+	type endedSession struct {
+		protocol int
+		lifetime time.Duration
+		idle     bool
+	}
+	a := pp.aging
+	current := map[agingSessionKey]agingSession{}
+	ended := []endedSession{}
+
+	pp.mutex.Lock()
+	for _, ipv6 := range pp.ipFamilies() {
+		for _, protocol := range pp.sessionProtocols(ipv6) {
+			pm := pp.getPublicPortPortmap(ipv6, protocol)
+			for p := portStart; p < portEnd; p++ {
+				pme := &pm[p]
+				if pme.static || pme.created.IsZero() || now.Sub(pme.lastused) > connectionTimeout {
+					continue
+				}
+				current[agingSessionKey{ipv6, protocol, uint16(p)}] = agingSession{
+					created:  pme.created,
+					lastused: pme.lastused,
+				}
+			}
+		}
+	}
+	for key, old := range a.known {
+		if cur, ok := current[key]; ok && cur.created.Equal(old.created) {
+			continue
+		}
+		lastused := old.lastused
+		pme := &pp.getPublicPortPortmap(key.ipv6, key.protocol)[key.port]
+		if pme.created.Equal(old.created) {
+			lastused = pme.lastused
+		}
+		ended = append(ended, endedSession{
+			protocol: agingHistogramIndex(key.protocol),
+			lifetime: lastused.Sub(old.created),
+			idle:     now.Sub(lastused) > connectionTimeout,
+		})
+	}
+	pp.mutex.Unlock()
+
+	a.mutex.Lock()
+	for _, s := range ended {
+		a.durations[s.protocol].observe(buckets, s.lifetime)
+		if s.idle {
+			a.idle[s.protocol].observe(buckets, s.lifetime)
+		}
+	}
+	a.known = current
+	a.mutex.Unlock()
+}
+
+// StartSessionAging starts scanning session tables for lifetime
+// histograms if they are enabled.
+func StartSessionAging() {
+	cfg := &Natconfig.SessionAging
+	if !cfg.enabled() {
+		return
+	}
+	go func() {
+		for {
+			now := time.Now()
+			for i := range Natconfig.PortPairs {
+				Natconfig.PortPairs[i].scanSessionAging(cfg.Buckets, now)
+			}
+			time.Sleep(time.Duration(cfg.Interval) * time.Second)
+		}
+	}()
+}
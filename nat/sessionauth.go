@@ -0,0 +1,519 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+
+	upd "github.com/intel-go/nff-go-nat/updatecfg"
+)
+
+const (
+	// Values which are used when they are not configured
+	defaultAuthorizationTimeout      = 500 * time.Millisecond
+	defaultAuthorizationCacheTime    = 60
+	defaultAuthorizationFailureCache = 5
+	defaultAuthorizationMaxPending   = 1024
+	// Number of requests which are sent to authorization server at
+	// the same time
+	authorizationWorkers = 4
+	// Decisions cached by one port pair. When it is reached, new
+	// decisions are not cached until expired ones are removed.
+	maxAuthorizationDecisions = 65536
+	// Expired decisions are removed this often
+	authorizationSweepInterval = 10 * time.Second
+)
+
+// Classes of new sessions which may require authorization.
+type authorizationClass int
+
+const (
+	// Sessions started by private hosts
+	authorizeEgress authorizationClass = iota
+	// Connections of remote hosts to forwarded ports
+	authorizeForwarded
+	authorizationClasses
+)
+
+var authorizationClassLookup = map[string]authorizationClass{
+	"egress":    authorizeEgress,
+	"forwarded": authorizeForwarded,
+}
+
+// What happens to new sessions when authorization server cannot be
+// asked or doesn't answer in time.
+type authorizationFailurePolicy int
+
+const (
+	authorizationFailOpen authorizationFailurePolicy = iota
+	authorizationFailClosed
+)
+
+var authorizationFailurePolicyLookup = map[string]authorizationFailurePolicy{
+	"open":   authorizationFailOpen,
+	"closed": authorizationFailClosed,
+}
+
+// External authorization of new sessions by GRPC SessionAuthorizer
+// service, e.g. captive portal or parental controls. Decisions are
+// cached, first packet of session without cached decision is dropped
+// while server is asked, so that its retransmission gets the decision.
+type sessionAuthorizationConfig struct {
+	// Address of authorization server, empty address disables
+	// authorization
+	Address string `json:"address"`
+	// Connect to server with TLS, CA certificates PEM file verifies
+	// server certificate
+	TLS           bool   `json:"tls"`
+	CACertificate string `json:"ca-certificate"`
+	// Classes of sessions which are authorized
+	Classes []authorizationClass `json:"classes"`
+	// Milliseconds which server has to answer in
+	Timeout int `json:"timeout"`
+	// Seconds which decisions of server are cached for
+	CacheTime int `json:"cache-time"`
+	// Seconds which failure policy decision is cached for when server
+	// doesn't answer
+	FailureCacheTime int                        `json:"failure-cache-time"`
+	FailurePolicy    authorizationFailurePolicy `json:"failure-policy"`
+	// Maximum number of requests waiting for server
+	MaxPending int `json:"max-pending"`
+	classes    [authorizationClasses]bool
+}
+
+// Decision of authorization server is cached by session or by host
+// which is subject of class. Host decisions have zero protocol, nil
+// peer and zero port.
+type authorizationKey struct {
+	class    authorizationClass
+	protocol uint8
+	// Private host of egress session or remote host of forwarded
+	// session, types.IPv4Address or types.IPv6Address
+	host interface{}
+	// Remote address and port of egress session or private address
+	// and public port of forwarded session
+	peer interface{}
+	port uint16
+}
+
+type authorizationDecision struct {
+	allow bool
+	// Expiration time in nanoseconds since Unix epoch
+	expires int64
+}
+
+// New session which is authorized. Addresses are types.IPv4Address or
+// types.IPv6Address.
+type authorizedSession struct {
+	class                               authorizationClass
+	protocol                            uint8
+	private, remote                     interface{}
+	privatePort, remotePort, publicPort uint16
+}
+
+type authorizationRequest struct {
+	pp      *portPair
+	key     authorizationKey
+	session authorizedSession
+}
+
+// Authorization state of port pair. Counters are updated from
+// translation handlers, so they should be accessed only atomically.
+type sessionAuthorizations struct {
+	// Decisions by authorizationKey, *authorizationDecision values
+	decisions sync.Map
+	size      int32
+	// Keys of requests waiting for server
+	pending sync.Map
+	// Sessions allowed and denied by cached decisions, packets
+	// dropped while server was asked, requests which failed and
+	// requests which were not sent because too many were pending
+	allowed, denied, held, failed, overflow uint64
+}
+
+var authorizationQueue chan *authorizationRequest
+
+// UnmarshalJSON parses session authorization class.
+func (out *authorizationClass) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	result, ok := authorizationClassLookup[s]
+	if !ok {
+		return errors.New("Bad session authorization class: " + s)
+	}
+
+	*out = result
+	return nil
+}
+
+// String returns class name as it is used in config file.
+func (class authorizationClass) String() string {
+	for name, c := range authorizationClassLookup {
+		if c == class {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// MarshalJSON writes class name as it is used in config file.
+func (class authorizationClass) MarshalJSON() ([]byte, error) {
+	return json.Marshal(class.String())
+}
+
+// UnmarshalJSON parses session authorization failure policy.
+func (out *authorizationFailurePolicy) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	result, ok := authorizationFailurePolicyLookup[s]
+	if !ok {
+		return errors.New("Bad session authorization failure policy: " + s)
+	}
+
+	*out = result
+	return nil
+}
+
+// String returns policy name as it is used in config file.
+func (policy authorizationFailurePolicy) String() string {
+	for name, p := range authorizationFailurePolicyLookup {
+		if p == policy {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// MarshalJSON writes policy name as it is used in config file.
+func (policy authorizationFailurePolicy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(policy.String())
+}
+
+func (cfg *sessionAuthorizationConfig) enabled() bool {
+	return cfg.Address != ""
+}
+
+func (cfg *sessionAuthorizationConfig) check() error {
+	if !cfg.enabled() {
+		return nil
+	}
+	if len(cfg.Classes) == 0 {
+		return errors.New("Session authorization should have classes")
+	}
+	if cfg.Timeout < 0 || cfg.CacheTime < 0 || cfg.FailureCacheTime < 0 || cfg.MaxPending < 0 {
+		return errors.New("Values of session-authorization should not be negative")
+	}
+	for _, c := range cfg.Classes {
+		cfg.classes[c] = true
+	}
+	if cfg.CacheTime == 0 {
+		cfg.CacheTime = defaultAuthorizationCacheTime
+	}
+	if cfg.FailureCacheTime == 0 {
+		cfg.FailureCacheTime = defaultAuthorizationFailureCache
+	}
+	if cfg.MaxPending == 0 {
+		cfg.MaxPending = defaultAuthorizationMaxPending
+	}
+	return nil
+}
+
+// authorizes returns true if sessions of class are authorized.
+func (cfg *sessionAuthorizationConfig) authorizes(class authorizationClass) bool {
+	return cfg.enabled() && cfg.classes[class]
+}
+
+func (cfg *sessionAuthorizationConfig) timeout() time.Duration {
+	if cfg.Timeout == 0 {
+		return defaultAuthorizationTimeout
+	}
+	return time.Duration(cfg.Timeout) * time.Millisecond
+}
+
+func (cfg *sessionAuthorizationConfig) dialOptions() ([]grpc.DialOption, error) {
+	if !cfg.TLS && cfg.CACertificate == "" {
+		return []grpc.DialOption{grpc.WithInsecure()}, nil
+	}
+	config := &tls.Config{}
+	if cfg.CACertificate != "" {
+		pem, err := ioutil.ReadFile(cfg.CACertificate)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("Failed to parse session authorization CA file %s", cfg.CACertificate)
+		}
+	}
+	return []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(config))}, nil
+}
+
+// StartSessionAuthorization connects to authorization server and
+// starts sending requests to it. Connection is established in
+// background, requests fail according to failure policy until it is.
+func StartSessionAuthorization() error {
+	cfg := &Natconfig.SessionAuthorization
+	if !cfg.enabled() {
+		return nil
+	}
+	opts, err := cfg.dialOptions()
+	if err != nil {
+		return err
+	}
+	conn, err := grpc.Dial(cfg.Address, opts...)
+	if err != nil {
+		return fmt.Errorf("Failed to connect to session authorization server %s: %v", cfg.Address, err)
+	}
+	client := upd.NewSessionAuthorizerClient(conn)
+	authorizationQueue = make(chan *authorizationRequest, cfg.MaxPending)
+	for i := 0; i < authorizationWorkers; i++ {
+		go sendAuthorizationRequests(client, cfg)
+	}
+	go func() {
+		for {
+			time.Sleep(authorizationSweepInterval)
+			now := time.Now().UnixNano()
+			for i := range Natconfig.PortPairs {
+				Natconfig.PortPairs[i].authorizations.forgetExpired(now)
+			}
+		}
+	}()
+	return nil
+}
+
+func sendAuthorizationRequests(client upd.SessionAuthorizerClient, cfg *sessionAuthorizationConfig) {
+	for r := range authorizationQueue {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout())
+		reply, err := client.AuthorizeSession(ctx, r.pp.authorizationMessage(&r.session))
+		cancel()
+		a := &r.pp.authorizations
+		if err != nil {
+			atomic.AddUint64(&a.failed, 1)
+			a.store(r.key, cfg.FailurePolicy == authorizationFailOpen, cfg.FailureCacheTime)
+		} else {
+			cacheTime := cfg.CacheTime
+			if reply.CacheTime != 0 {
+				cacheTime = int(reply.CacheTime)
+			}
+			key := r.key
+			if reply.Host {
+				key = authorizationKey{class: key.class, host: key.host}
+			}
+			a.store(key, reply.Allow, cacheTime)
+		}
+		a.pending.Delete(r.key)
+	}
+}
+
+// keys returns cache keys of session and of its host.
+func (s *authorizedSession) keys() (authorizationKey, authorizationKey) {
+	if s.class == authorizeEgress {
+		return authorizationKey{class: s.class, protocol: s.protocol, host: s.private, peer: s.remote, port: s.remotePort},
+			authorizationKey{class: s.class, host: s.private}
+	}
+	return authorizationKey{class: s.class, protocol: s.protocol, host: s.remote, peer: s.private, port: s.publicPort},
+		authorizationKey{class: s.class, host: s.remote}
+}
+
+func (pp *portPair) authorizationMessage(s *authorizedSession) *upd.SessionAuthorizationRequest {
+	_, ipv6 := s.private.(types.IPv6Address)
+	return &upd.SessionAuthorizationRequest{
+		Class:          s.class.String(),
+		InterfaceId:    uint32(pp.PublicPort.Index),
+		Tenant:         pp.Tenant,
+		Ipv6:           ipv6,
+		Protocol:       uint32(s.protocol),
+		PrivateAddress: hostAddress(s.private),
+		PrivatePort:    uint32(s.privatePort),
+		RemoteAddress:  hostAddress(s.remote),
+		RemotePort:     uint32(s.remotePort),
+		PublicPort:     uint32(s.publicPort),
+	}
+}
+
+// authorizeSession returns true if new session may be created. Cached
+// decision of session or its host is used if there is one. Otherwise
+// server is asked in background and false is returned, so that packet
+// is dropped. When request cannot be queued, failure policy is used
+// without caching it.
+func (pp *portPair) authorizeSession(s *authorizedSession) bool {
+	cfg := &Natconfig.SessionAuthorization
+	if !cfg.authorizes(s.class) {
+		return true
+	}
+	a := &pp.authorizations
+	if allow, ok := pp.cachedAuthorization(s, time.Now().UnixNano()); ok {
+		if allow {
+			atomic.AddUint64(&a.allowed, 1)
+		} else {
+			atomic.AddUint64(&a.denied, 1)
+		}
+		return allow
+	}
+	key, _ := s.keys()
+	if _, loaded := a.pending.LoadOrStore(key, true); !loaded {
+		select {
+		case authorizationQueue <- &authorizationRequest{pp: pp, key: key, session: *s}:
+		default:
+			a.pending.Delete(key)
+			atomic.AddUint64(&a.overflow, 1)
+			return cfg.FailurePolicy == authorizationFailOpen
+		}
+	}
+	atomic.AddUint64(&a.held, 1)
+	return false
+}
+
+// cachedAuthorization returns decision cached for session or for its
+// host, second value is false if there is none.
+func (pp *portPair) cachedAuthorization(s *authorizedSession, now int64) (bool, bool) {
+	key, hostKey := s.keys()
+	for _, k := range []authorizationKey{key, hostKey} {
+		if allow, ok := pp.authorizations.lookup(k, now); ok {
+			return allow, true
+		}
+	}
+	return false, false
+}
+
+// egressAuthorization returns session which packet of private host
+// would start.
+func egressAuthorization(protocol uint8, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr, srcPort, dstPort uint16) authorizedSession {
+	s := authorizedSession{
+		class:       authorizeEgress,
+		protocol:    protocol,
+		privatePort: srcPort,
+		remotePort:  dstPort,
+	}
+	s.private, s.remote = packetAddresses(pktIPv4, pktIPv6)
+	return s
+}
+
+// forwardedAuthorization returns session of remote host with private
+// host of forwarded port.
+func forwardedAuthorization(protocol uint8, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr,
+	srcPort, dstPort uint16, v4addr types.IPv4Address, v6addr types.IPv6Address, newPort uint16) authorizedSession {
+	s := authorizedSession{
+		class:       authorizeForwarded,
+		protocol:    protocol,
+		privatePort: newPort,
+		remotePort:  srcPort,
+		publicPort:  dstPort,
+	}
+	s.remote, _ = packetAddresses(pktIPv4, pktIPv6)
+	if pktIPv6 != nil {
+		s.private = v6addr
+	} else {
+		s.private = v4addr
+	}
+	return s
+}
+
+// authorizeEgress authorizes packet of private host which would start
+// a new session.
+func (pp *portPair) authorizeEgress(protocol uint8, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr, srcPort, dstPort uint16) bool {
+	if !Natconfig.SessionAuthorization.authorizes(authorizeEgress) {
+		return true
+	}
+	s := egressAuthorization(protocol, pktIPv4, pktIPv6, srcPort, dstPort)
+	return pp.authorizeSession(&s)
+}
+
+// authorizeForwarded authorizes packet of remote host to forwarded
+// port. TCP connections are authorized by their SYN, packets of other
+// protocols don't belong to connections, so every packet is checked
+// against cached decisions.
+func (pp *portPair) authorizeForwarded(protocol uint8, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr, pktTCP *packet.TCPHdr,
+	srcPort, dstPort uint16, v4addr types.IPv4Address, v6addr types.IPv6Address, newPort uint16) bool {
+	if !Natconfig.SessionAuthorization.authorizes(authorizeForwarded) || (pktTCP != nil && !isNewTCPConnection(pktTCP)) {
+		return true
+	}
+	s := forwardedAuthorization(protocol, pktIPv4, pktIPv6, srcPort, dstPort, v4addr, v6addr, newPort)
+	return pp.authorizeSession(&s)
+}
+
+// packetAddresses returns source and destination addresses of packet
+// as types.IPv4Address or types.IPv6Address.
+func packetAddresses(pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) (interface{}, interface{}) {
+	if pktIPv6 != nil {
+		return pktIPv6.SrcAddr, pktIPv6.DstAddr
+	}
+	return packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr), packet.SwapBytesIPv4Addr(pktIPv4.DstAddr)
+}
+
+// lookup returns cached decision which didn't expire yet.
+func (a *sessionAuthorizations) lookup(key authorizationKey, now int64) (bool, bool) {
+	if atomic.LoadInt32(&a.size) == 0 {
+		return false, false
+	}
+	v, ok := a.decisions.Load(key)
+	if !ok {
+		return false, false
+	}
+	d := v.(*authorizationDecision)
+	if d.expires <= now {
+		return false, false
+	}
+	return d.allow, true
+}
+
+// store caches decision for cacheTime seconds unless cache is full.
+func (a *sessionAuthorizations) store(key authorizationKey, allow bool, cacheTime int) {
+	d := &authorizationDecision{
+		allow:   allow,
+		expires: time.Now().Add(time.Duration(cacheTime) * time.Second).UnixNano(),
+	}
+	if _, loaded := a.decisions.Load(key); !loaded && atomic.LoadInt32(&a.size) >= maxAuthorizationDecisions {
+		return
+	}
+	if _, loaded := a.decisions.LoadOrStore(key, d); loaded {
+		a.decisions.Store(key, d)
+	} else {
+		atomic.AddInt32(&a.size, 1)
+	}
+}
+
+func (a *sessionAuthorizations) forgetExpired(now int64) {
+	a.decisions.Range(func(k, v interface{}) bool {
+		if v.(*authorizationDecision).expires <= now {
+			a.decisions.Delete(k)
+			atomic.AddInt32(&a.size, -1)
+		}
+		return true
+	})
+}
+
+// counters returns authorization counters of port pair.
+func (a *sessionAuthorizations) counters() []*upd.Counter {
+	return []*upd.Counter{
+		&upd.Counter{Name: "authorization-allowed-sessions", Value: atomic.LoadUint64(&a.allowed)},
+		&upd.Counter{Name: "authorization-denied-sessions", Value: atomic.LoadUint64(&a.denied)},
+		&upd.Counter{Name: "authorization-held-packets", Value: atomic.LoadUint64(&a.held)},
+		&upd.Counter{Name: "authorization-failed-requests", Value: atomic.LoadUint64(&a.failed)},
+		&upd.Counter{Name: "authorization-overflow-requests", Value: atomic.LoadUint64(&a.overflow)},
+		&upd.Counter{Name: "authorization-cached-decisions", Value: uint64(atomic.LoadInt32(&a.size))},
+	}
+}
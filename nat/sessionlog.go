@@ -0,0 +1,263 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/types"
+)
+
+const (
+	// Session tables are scanned this often when interval is not
+	// configured, in seconds
+	defaultSessionLogInterval = 1
+	// Syslog priority of session records, facility local0 and
+	// severity informational
+	sessionLogPriority = 16*8 + 6
+	sessionLogAppName  = "nff-go-nat"
+	// RFC 5424 timestamp with microseconds
+	sessionLogTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// Logging of session creation and deletion to syslog collector.
+// Records are RFC 5424 messages sent over UDP, one message per
+// datagram.
+type sessionLogConfig struct {
+	// UDP address of syslog collector, host:port
+	Address string `json:"address"`
+	// Seconds between scans of session tables, zero means default
+	Interval int `json:"interval"`
+	// Local UDP port of records, zero lets Linux choose it
+	SourcePort uint16 `json:"source-port"`
+	addr       *net.UDPAddr
+}
+
+// Sampling of sessions of port pair which are logged. Sessions of
+// listed subscribers are always logged, one of every Rate other
+// sessions is logged. Choice is a hash of session tuples, so it
+// doesn't depend on order of sessions and both records of a session
+// are logged or skipped.
+type sessionLogSampling struct {
+	// One of this many sessions is logged, zero or one means all
+	Rate uint32 `json:"rate"`
+	// Private hosts which sessions are logged regardless of rate,
+	// addresses or MAC addresses when host identity is tracked
+	Subscribers []string `json:"subscribers"`
+	subscribers map[interface{}]bool
+}
+
+// Session as it was seen by last scan and whether its creation was
+// logged.
+type loggedSession struct {
+	conntrackSession
+	logged bool
+}
+
+// Sends session records to syslog collector.
+type sessionLogger struct {
+	conn *net.UDPConn
+}
+
+func (cfg *sessionLogConfig) enabled() bool {
+	return cfg.Address != ""
+}
+
+func (cfg *sessionLogConfig) check() error {
+	if !cfg.enabled() {
+		return nil
+	}
+	addr, err := net.ResolveUDPAddr("udp", cfg.Address)
+	if err != nil {
+		return fmt.Errorf("Bad session-log address \"%s\": %+v", cfg.Address, err)
+	}
+	if cfg.Interval < 0 {
+		return errors.New("Session-log interval should not be negative")
+	}
+	cfg.addr = addr
+	return nil
+}
+
+func (cfg *sessionLogConfig) interval() time.Duration {
+	if cfg.Interval == 0 {
+		return defaultSessionLogInterval * time.Second
+	}
+	return time.Duration(cfg.Interval) * time.Second
+}
+
+// check parses subscriber addresses.
+func (cfg *sessionLogSampling) check() error {
+	cfg.subscribers = map[interface{}]bool{}
+	for _, s := range cfg.Subscribers {
+		if hw, err := net.ParseMAC(s); err == nil && len(hw) == types.EtherAddrLen {
+			var mac types.MACAddress
+			copy(mac[:], hw)
+			cfg.subscribers[mac] = true
+			continue
+		}
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return fmt.Errorf("Bad session-log-sampling subscriber address %s", s)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			addr, _ := convertIPv4(ip4)
+			cfg.subscribers[addr] = true
+		} else {
+			var addr types.IPv6Address
+			copy(addr[:], ip.To16())
+			cfg.subscribers[addr] = true
+		}
+	}
+	return nil
+}
+
+// sampled returns true if session should be logged.
+func (cfg *sessionLogSampling) sampled(key conntrackSessionKey, session *conntrackSession) bool {
+	var host interface{}
+	if key.ipv6 {
+		host = session.private.(Tuple6).addr
+	} else {
+		host = session.private.(Tuple).addr
+	}
+	if cfg.subscribers[host] || (session.hasMAC && cfg.subscribers[session.mac]) {
+		return true
+	}
+	if cfg.Rate <= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte{key.protocol})
+	h.Write([]byte(sessionLogAddress(session.private)))
+	h.Write([]byte(sessionLogAddress(session.public)))
+	h.Write([]byte(sessionLogAddress(session.remote)))
+	return h.Sum32()%cfg.Rate == 0
+}
+
+// logSampling returns current sampling of port pair. It is replaced
+// by control API, so it may be used without locking.
+func (pp *portPair) logSampling() *sessionLogSampling {
+	cfg := pp.sessionSampling.Load()
+	if cfg == nil {
+		return &sessionLogSampling{}
+	}
+	return cfg.(*sessionLogSampling)
+}
+
+// setLogSampling checks sampling and replaces sampling of port pair.
+// It applies to sessions which are created after change.
+func (pp *portPair) setLogSampling(cfg sessionLogSampling) error {
+	if err := cfg.check(); err != nil {
+		return err
+	}
+	pp.sessionSampling.Store(&cfg)
+	return nil
+}
+
+// StartSessionLog starts logging sessions to syslog collector if it
+// is configured.
+func StartSessionLog() error {
+	cfg := &Natconfig.SessionLog
+	if !cfg.enabled() {
+		return nil
+	}
+	conn, err := net.DialUDP("udp", localUDPAddr(cfg.SourcePort), cfg.addr)
+	if err != nil {
+		return fmt.Errorf("Failed to open session-log socket: %+v", err)
+	}
+	logger := &sessionLogger{
+		conn: conn,
+	}
+	go logger.run(cfg)
+	return nil
+}
+
+// run periodically compares session tables with their previous scan
+// and logs created and deleted sessions. Sampling decision is made
+// when session is created and deletion is logged only for sessions
+// which creation was logged.
+func (l *sessionLogger) run(cfg *sessionLogConfig) {
+	known := map[conntrackSessionKey]loggedSession{}
+	for {
+		current := map[conntrackSessionKey]conntrackSession{}
+		for i := range Natconfig.PortPairs {
+			Natconfig.PortPairs[i].scanConntrackSessions(i, current)
+		}
+		next := make(map[conntrackSessionKey]loggedSession, len(current))
+		for key, cur := range current {
+			old, ok := known[key]
+			if ok && old.private == cur.private && old.remote == cur.remote && old.public == cur.public {
+				next[key] = old
+				continue
+			}
+			if ok && old.logged {
+				// Public port was reused by another session
+				l.log("DEL", key, &old.conntrackSession)
+			}
+			s := loggedSession{
+				conntrackSession: cur,
+				logged:           Natconfig.PortPairs[key.pair].logSampling().sampled(key, &cur),
+			}
+			if s.logged {
+				l.log("NEW", key, &cur)
+			}
+			next[key] = s
+		}
+		for key, old := range known {
+			if _, ok := current[key]; !ok && old.logged {
+				l.log("DEL", key, &old.conntrackSession)
+			}
+		}
+		known = next
+		time.Sleep(cfg.interval())
+	}
+}
+
+// log sends record about session.
+func (l *sessionLogger) log(event string, key conntrackSessionKey, session *conntrackSession) {
+	hostname := Natconfig.HostName
+	if hostname == "" {
+		hostname = "-"
+	}
+	protocol := "UDP"
+	if key.protocol == types.TCPNumber {
+		protocol = "TCP"
+	}
+	fields := []string{
+		event,
+		"protocol=" + protocol,
+		"private=" + sessionLogAddress(session.private),
+		"public=" + sessionLogAddress(session.public),
+		"remote=" + sessionLogAddress(session.remote),
+	}
+	if session.hasMAC {
+		fields = append(fields, "mac="+session.mac.String())
+	}
+	if tenant := Natconfig.PortPairs[key.pair].Tenant; tenant != "" {
+		fields = append(fields, "tenant="+tenant)
+	}
+	msg := fmt.Sprintf("<%d>1 %s %s %s - - - %s", sessionLogPriority, time.Now().UTC().Format(sessionLogTimeFormat),
+		hostname, sessionLogAppName, strings.Join(fields, " "))
+	if _, err := l.conn.Write([]byte(msg)); err != nil {
+		common.LogWarning(common.No, "Failed to send session record to", Natconfig.SessionLog.Address, ":", err)
+	}
+}
+
+// sessionLogAddress formats Tuple or Tuple6 as address and port.
+func sessionLogAddress(t interface{}) string {
+	switch v := t.(type) {
+	case Tuple:
+		return net.JoinHostPort(StringIPv4Int(uint32(v.addr)), strconv.Itoa(int(v.port)))
+	case Tuple6:
+		return net.JoinHostPort(net.IP(v.addr[:]).String(), strconv.Itoa(int(v.port)))
+	}
+	return "-"
+}
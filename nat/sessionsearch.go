@@ -0,0 +1,252 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+
+	upd "github.com/intel-go/nff-go-nat/updatecfg"
+)
+
+const (
+	// Sessions returned in one reply of session search when limit is
+	// not specified and at most
+	defaultSessionSearchLimit = 1000
+	maxSessionSearchLimit     = 10000
+	// Search position is IP family, index of protocol in
+	// sessionProtocols and public port in this order
+	sessionCursorFamilyShift   = 24
+	sessionCursorProtocolShift = 16
+)
+
+// Conditions of session search, zero values match all sessions.
+type sessionFilter struct {
+	protocol uint8
+	// types.IPv4Address or types.IPv6Address, nil matches all
+	public, private, remote interface{}
+	// Ports or ICMP query identifiers
+	publicPort, privatePort, remotePort uint16
+	minAge, maxAge                      time.Duration
+	minBytes, maxBytes                  uint64
+	kind                                upd.SessionKind
+	// MAC address of private host, nil matches all
+	privateMAC *types.MACAddress
+}
+
+// countSessionPacket accounts packet translated by session.
+func countSessionPacket(pme *portMapEntry, pkt *packet.Packet) {
+	atomic.AddUint64(&pme.packets, 1)
+	atomic.AddUint64(&pme.bytes, uint64(pkt.GetPacketLen()))
+}
+
+func convertFilterAddress(a *upd.IPAddress) (interface{}, error) {
+	if a == nil {
+		return nil, nil
+	}
+	return convertNeighborAddress(a)
+}
+
+func convertSessionFilter(in *upd.SessionFilter) (*sessionFilter, error) {
+	if in.GetProtocol() > 255 || in.GetPublicPort() > 65535 || in.GetPrivatePort() > 65535 || in.GetRemotePort() > 65535 {
+		return nil, fmt.Errorf("Bad session filter protocol or port")
+	}
+	if _, ok := upd.SessionKind_name[int32(in.GetKind())]; !ok {
+		return nil, fmt.Errorf("Bad session kind %d", in.GetKind())
+	}
+	f := &sessionFilter{
+		protocol:    uint8(in.GetProtocol()),
+		publicPort:  uint16(in.GetPublicPort()),
+		privatePort: uint16(in.GetPrivatePort()),
+		remotePort:  uint16(in.GetRemotePort()),
+		minAge:      time.Duration(in.GetMinAge()) * time.Second,
+		maxAge:      time.Duration(in.GetMaxAge()) * time.Second,
+		minBytes:    in.GetMinBytes(),
+		maxBytes:    in.GetMaxBytes(),
+		kind:        in.GetKind(),
+	}
+	var err error
+	if f.public, err = convertFilterAddress(in.GetPublicAddress()); err != nil {
+		return nil, err
+	}
+	if f.private, err = convertFilterAddress(in.GetPrivateAddress()); err != nil {
+		return nil, err
+	}
+	if f.remote, err = convertFilterAddress(in.GetRemoteAddress()); err != nil {
+		return nil, err
+	}
+	if mac := in.GetPrivateMacAddress(); len(mac) != 0 {
+		if len(mac) != types.EtherAddrLen {
+			return nil, fmt.Errorf("Bad MAC address length %d", len(mac))
+		}
+		f.privateMAC = &types.MACAddress{}
+		copy(f.privateMAC[:], mac)
+	}
+	return f, nil
+}
+
+// matchesFamily returns false if any address of filter belongs to
+// other IP family.
+func (f *sessionFilter) matchesFamily(ipv6 bool) bool {
+	for _, a := range []interface{}{f.public, f.private, f.remote} {
+		if a == nil {
+			continue
+		}
+		if _, v6 := a.(types.IPv6Address); v6 != ipv6 {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesEntry checks conditions which don't need session addresses.
+func (f *sessionFilter) matchesEntry(pme *portMapEntry, publicPort uint16, now time.Time) bool {
+	if (f.kind == upd.SessionKind_SESSIONS_DYNAMIC && pme.static) ||
+		(f.kind == upd.SessionKind_SESSIONS_STATIC && !pme.static) {
+		return false
+	}
+	if f.publicPort != 0 && f.publicPort != publicPort {
+		return false
+	}
+	age := now.Sub(pme.created)
+	if age < f.minAge || (f.maxAge != 0 && age > f.maxAge) {
+		return false
+	}
+	bytes := atomic.LoadUint64(&pme.bytes)
+	return bytes >= f.minBytes && (f.maxBytes == 0 || bytes <= f.maxBytes)
+}
+
+// matchesTuple returns true if Tuple or Tuple6 has address and port,
+// nil address and zero port match any.
+func matchesTuple(addr interface{}, port uint16, tuple interface{}) bool {
+	if addr == nil && port == 0 {
+		return true
+	}
+	var tupleAddr interface{}
+	var tuplePort uint16
+	switch t := tuple.(type) {
+	case Tuple:
+		tupleAddr, tuplePort = t.addr, t.port
+	case Tuple6:
+		tupleAddr, tuplePort = t.addr, t.port
+	default:
+		return false
+	}
+	return (addr == nil || addr == tupleAddr) && (port == 0 || port == tuplePort)
+}
+
+// tupleAddress converts address and port of Tuple or Tuple6 to GRPC
+// form.
+func tupleAddress(tuple interface{}) (*upd.IPAddress, uint32) {
+	switch t := tuple.(type) {
+	case Tuple:
+		return hostAddress(t.addr), uint32(t.port)
+	case Tuple6:
+		return hostAddress(t.addr), uint32(t.port)
+	}
+	return nil, 0
+}
+
+// findSessions returns up to limit sessions of port pair with config
+// index which match filter starting at cursor, and cursor of next
+// matching session which is zero when there are no more of them.
+// Cursor is search position plus one.
+func (pp *portPair) findSessions(index int, f *sessionFilter, cursor uint64, limit int) ([]*upd.Session, uint64) {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	now := time.Now()
+	sessions := []*upd.Session{}
+	start := uint64(0)
+	if cursor != 0 {
+		start = cursor - 1
+	}
+	for family, ipv6 := range []bool{false, true} {
+		if (ipv6 && pp.DisableIPv6) || (!ipv6 && pp.DisableIPv4) || !f.matchesFamily(ipv6) {
+			continue
+		}
+		for pi, protocol := range pp.sessionProtocols(ipv6) {
+			if f.protocol != 0 && f.protocol != protocol {
+				continue
+			}
+			block := uint64(family)<<sessionCursorFamilyShift | uint64(pi)<<sessionCursorProtocolShift
+			pm := pp.getPublicPortPortmap(ipv6, protocol)
+			if block+uint64(len(pm)) <= start {
+				continue
+			}
+			// Forwarded ports may be below dynamic port range
+			first := 0
+			if start > block {
+				first = int(start - block)
+			}
+			for p := first; p < len(pm); p++ {
+				pme := &pm[p]
+				if !pme.static && now.Sub(pme.lastused) > connectionTimeout {
+					continue
+				}
+				if !f.matchesEntry(pme, uint16(p), now) {
+					continue
+				}
+				pubKey := pp.sessionPublicKey(ipv6, *pme, uint16(p))
+				privKey, found := pp.PublicPort.translationTable[protocol].Load(pubKey)
+				if !found || !matchesTuple(f.public, 0, pubKey) ||
+					!matchesTuple(f.private, f.privatePort, privKey) ||
+					!matchesTuple(f.remote, f.remotePort, pme.remote) {
+					continue
+				}
+				if f.privateMAC != nil {
+					if mac, ok := pp.sessionMAC(pme, privKey); !ok || mac != *f.privateMAC {
+						continue
+					}
+				}
+				if len(sessions) == limit {
+					return sessions, block + uint64(p) + 1
+				}
+				sessions = append(sessions, pp.foundSession(index, ipv6, protocol, pme, pubKey, privKey, now))
+			}
+		}
+	}
+	return sessions, 0
+}
+
+func (pp *portPair) foundSession(index int, ipv6 bool, protocol uint8, pme *portMapEntry, pubKey, privKey interface{}, now time.Time) *upd.Session {
+	s := &upd.Session{
+		Pair:                 uint32(index),
+		Ipv6:                 ipv6,
+		Protocol:             uint32(protocol),
+		LastUsed:             pme.lastused.UnixNano(),
+		FinCount:             uint32(pme.finCount),
+		TerminationDirection: uint32(pme.terminationDirection),
+		Tenant:               pp.Tenant,
+		Static:               pme.static,
+		Created:              pme.created.UnixNano(),
+		Packets:              atomic.LoadUint64(&pme.packets),
+		Bytes:                atomic.LoadUint64(&pme.bytes),
+	}
+	if pme.country != countryUntagged {
+		s.Country = countryName(pme.country)
+	}
+	if !pme.static {
+		s.RemainingLifetimeMs = uint32(sessionRemainingLifetime(pme.lastused, now) / time.Millisecond)
+	}
+	s.PublicAddress, s.PublicPort = tupleAddress(pubKey)
+	// Forwarded ports sent to KNI interface have zero destination
+	if _, _, _, zeroAddr := getAddrFromTuple(privKey, ipv6); zeroAddr {
+		_, s.PrivatePort = tupleAddress(privKey)
+	} else {
+		s.PrivateAddress, s.PrivatePort = tupleAddress(privKey)
+	}
+	if pme.remote != nil {
+		s.RemoteAddress, s.RemotePort = tupleAddress(pme.remote)
+	}
+	if mac, ok := pp.sessionMAC(pme, privKey); ok {
+		s.PrivateMacAddress = append([]byte{}, mac[:]...)
+	}
+	return s
+}
@@ -0,0 +1,238 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"time"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+// Dynamic session saved to session state file so that NAT restarted
+// with new binary continues to translate established flows.
+type savedSession struct {
+	Pair                 int                  `json:"pair"`
+	IPv6                 bool                 `json:"ipv6"`
+	Protocol             uint8                `json:"protocol"`
+	PublicAddress        string               `json:"public-address"`
+	PublicPort           uint16               `json:"public-port"`
+	PrivateAddress       string               `json:"private-address"`
+	PrivatePort          uint16               `json:"private-port"`
+	LastUsed             time.Time            `json:"last-used"`
+	FinCount             uint8                `json:"fin-count"`
+	TerminationDirection terminationDirection `json:"termination-direction"`
+	Tenant               string               `json:"tenant,omitempty"`
+	// Country of remote host, it is not restored
+	Country string `json:"country,omitempty"`
+}
+
+// SaveSessions writes active dynamic sessions of all port pairs to
+// session state file if it is configured.
+func SaveSessions() error {
+	if Natconfig.SessionStateFile == "" {
+		return nil
+	}
+
+	sessions := collectSessions()
+	data, err := json.Marshal(sessions)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomically(Natconfig.SessionStateFile, data); err != nil {
+		return err
+	}
+	println("Saved", len(sessions), "sessions to", Natconfig.SessionStateFile)
+	return nil
+}
+
+// collectSessions returns active dynamic sessions of all port pairs.
+func collectSessions() []savedSession {
+	sessions := []savedSession{}
+	for i := range Natconfig.PortPairs {
+		sessions = append(sessions, Natconfig.PortPairs[i].saveSessions(i)...)
+	}
+	return sessions
+}
+
+func (pp *portPair) saveSessions(index int) []savedSession {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	sessions := []savedSession{}
+	for _, ipv6 := range pp.ipFamilies() {
+		for _, protocol := range pp.sessionProtocols(ipv6) {
+			pm := pp.getPublicPortPortmap(ipv6, protocol)
+			for p := portStart; p < portEnd; p++ {
+				// Ports opened by triggers are not saved, they are
+				// opened again by new triggering sessions
+				if pm[p].static || pm[p].trigger != nil || time.Since(pm[p].lastused) > connectionTimeout {
+					continue
+				}
+				pubKey := pp.sessionPublicKey(ipv6, pm[p], uint16(p))
+				v, found := pp.PublicPort.translationTable[protocol].Load(pubKey)
+				if !found {
+					continue
+				}
+				s := savedSession{
+					Pair:                 index,
+					IPv6:                 ipv6,
+					Protocol:             protocol,
+					PublicPort:           uint16(p),
+					LastUsed:             pm[p].lastused,
+					FinCount:             pm[p].finCount,
+					TerminationDirection: pm[p].terminationDirection,
+					Tenant:               pp.Tenant,
+				}
+				if pm[p].country != countryUntagged {
+					s.Country = countryName(pm[p].country)
+				}
+				if ipv6 {
+					pub, priv := pubKey.(Tuple6), v.(Tuple6)
+					s.PublicAddress = net.IP(pub.addr[:]).String()
+					s.PrivateAddress = net.IP(priv.addr[:]).String()
+					s.PrivatePort = priv.port
+				} else {
+					pub, priv := pubKey.(Tuple), v.(Tuple)
+					s.PublicAddress = StringIPv4Int(uint32(pub.addr))
+					s.PrivateAddress = StringIPv4Int(uint32(priv.addr))
+					s.PrivatePort = priv.port
+				}
+				sessions = append(sessions, s)
+			}
+		}
+	}
+	return sessions
+}
+
+// loadSessions restores sessions from session state file. Sessions
+// which don't match current port addresses or conflict with port
+// forwarding are skipped. Missing file is not an error.
+func loadSessions() {
+	if Natconfig.SessionStateFile == "" {
+		return
+	}
+	data, err := ioutil.ReadFile(Natconfig.SessionStateFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			println("Warning! Failed to read session state file", Natconfig.SessionStateFile, ":", err.Error())
+		}
+		return
+	}
+	var sessions []savedSession
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		println("Warning! Failed to parse session state file", Natconfig.SessionStateFile, ":", err.Error())
+		return
+	}
+
+	restored := restoreSessions(sessions)
+	println("Restored", restored, "of", len(sessions), "sessions from", Natconfig.SessionStateFile)
+}
+
+// restoreSessions adds sessions to translation tables of their port
+// pairs and returns number of restored sessions.
+func restoreSessions(sessions []savedSession) int {
+	restored := 0
+	for i := range sessions {
+		s := &sessions[i]
+		if s.Pair < 0 || s.Pair >= len(Natconfig.PortPairs) {
+			continue
+		}
+		pp := &Natconfig.PortPairs[s.Pair]
+		pp.mutex.Lock()
+		if pp.restoreSession(s) {
+			restored++
+		}
+		pp.mutex.Unlock()
+	}
+	return restored
+}
+
+func (pp *portPair) restoreSession(s *savedSession) bool {
+	if !pp.isDynamicPort(s.PublicPort) || time.Since(s.LastUsed) > connectionTimeout {
+		return false
+	}
+	// Private addresses of different tenants may be the same, so
+	// session of another tenant must not be restored even if it
+	// matches private subnet
+	if s.Tenant != "" && s.Tenant != pp.Tenant {
+		return false
+	}
+	pm := pp.getPublicPortPortmap(s.IPv6, s.Protocol)
+	if pm == nil || pm[s.PublicPort].static {
+		return false
+	}
+
+	// Public address has to be known already, otherwise it is not
+	// possible to check that session still belongs to this port
+	var pubEntry, privEntry interface{}
+	var vlanAddr types.IPv4Address
+	var temporary *temporaryAddress
+	if s.IPv6 {
+		pub, priv := net.ParseIP(s.PublicAddress), net.ParseIP(s.PrivateAddress)
+		if pub == nil || priv == nil || !pp.PublicPort.Subnet6.addressAcquired {
+			return false
+		}
+		var pubAddr, privAddr types.IPv6Address
+		copy(pubAddr[:], pub.To16())
+		copy(privAddr[:], priv.To16())
+		if !pp.PrivatePort.Subnet6.checkAddrWithingSubnet(privAddr) {
+			return false
+		}
+		// Sessions of temporary addresses keep them until they expire
+		if pubAddr != pp.PublicPort.Subnet6.Addr {
+			if temporary = pp.adoptTemporaryAddress(pubAddr); temporary == nil {
+				return false
+			}
+		}
+		pubEntry = Tuple6{addr: pubAddr, port: s.PublicPort}
+		privEntry = Tuple6{addr: privAddr, port: s.PrivatePort}
+	} else {
+		pub, priv := net.ParseIP(s.PublicAddress).To4(), net.ParseIP(s.PrivateAddress).To4()
+		if pub == nil || priv == nil || !pp.PublicPort.Subnet.addressAcquired {
+			return false
+		}
+		pubAddr, _ := convertIPv4(pub)
+		privAddr, _ := convertIPv4(priv)
+		if pubAddr != pp.PublicPort.publicAddressForHost(privAddr) || !pp.PrivatePort.Subnet.checkAddrWithingSubnet(privAddr) {
+			return false
+		}
+		if pubAddr != pp.PublicPort.Subnet.Addr {
+			vlanAddr = pubAddr
+		}
+		pubEntry = Tuple{addr: pubAddr, port: s.PublicPort}
+		privEntry = Tuple{addr: privAddr, port: s.PrivatePort}
+	}
+	if _, found := pp.PrivatePort.translationTable[s.Protocol].Load(privEntry); found {
+		return false
+	}
+	// Public port may be used by a running session when sessions are
+	// imported into working NAT
+	if _, found := pp.PublicPort.translationTable[s.Protocol].Load(pubEntry); found {
+		if time.Since(pm[s.PublicPort].lastused) <= connectionTimeout {
+			return false
+		}
+		pp.deleteOldConnection(s.IPv6, s.Protocol, int(s.PublicPort))
+	}
+
+	pp.portFreed(&pm[s.PublicPort])
+	// Creation time of restored session is not known, it is counted
+	// from restore
+	pm[s.PublicPort] = portMapEntry{
+		created:              time.Now(),
+		lastused:             s.LastUsed,
+		finCount:             s.FinCount,
+		terminationDirection: s.TerminationDirection,
+		addr:                 vlanAddr,
+		temporary:            temporary,
+	}
+	pp.PublicPort.translationTable[s.Protocol].Store(pubEntry, privEntry)
+	pp.PrivatePort.translationTable[s.Protocol].Store(privEntry, pubEntry)
+	return true
+}
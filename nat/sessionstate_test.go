@@ -0,0 +1,151 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+func TestRestoreSessionsSkipped(t *testing.T) {
+	saved := Natconfig
+	defer func() { Natconfig = saved }()
+	local := &localPortSet{}
+	local.add(portSpan{first: 9000, last: 9000})
+	Natconfig = &Config{PortPairs: []portPair{{Tenant: "blue", localPorts: local}}}
+
+	session := func(pair int, port uint16, lastUsed time.Duration, tenant string) savedSession {
+		return savedSession{
+			Pair:           pair,
+			Protocol:       types.TCPNumber,
+			PublicAddress:  "198.51.100.1",
+			PublicPort:     port,
+			PrivateAddress: "192.168.1.10",
+			PrivatePort:    40000,
+			LastUsed:       time.Now().Add(-lastUsed),
+			Tenant:         tenant,
+		}
+	}
+	tests := []struct {
+		name    string
+		session savedSession
+	}{
+		{"unknown port pair", session(1, 2000, 0, "blue")},
+		{"negative port pair", session(-1, 2000, 0, "blue")},
+		{"port out of dynamic range", session(0, 80, 0, "blue")},
+		{"reserved port", session(0, 9000, 0, "blue")},
+		{"session older than timeout", session(0, 2000, 2*connectionTimeout, "blue")},
+		{"session of another tenant", session(0, 2000, 0, "red")},
+	}
+	for _, tt := range tests {
+		if restored := restoreSessions([]savedSession{tt.session}); restored != 0 {
+			t.Errorf("%s: %d sessions are restored, expected none", tt.name, restored)
+		}
+	}
+}
+
+// testRestorePair returns port pair which public and private ports
+// have acquired addresses and empty session tables.
+func testRestorePair() portPair {
+	pp := portPair{
+		PublicPort: ipPort{
+			Subnet:  ipv4Subnet{Addr: hostIPv4(198, 51, 100, 1), Mask: hostIPv4(255, 255, 255, 0), addressAcquired: true},
+			Subnet6: ipv6Subnet{Mask: types.IPv6Address{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, addressAcquired: true},
+		},
+		PrivatePort: ipPort{
+			Subnet:  ipv4Subnet{Addr: hostIPv4(192, 168, 1, 1), Mask: hostIPv4(255, 255, 255, 0), addressAcquired: true},
+			Subnet6: ipv6Subnet{Mask: types.IPv6Address{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, addressAcquired: true},
+		},
+	}
+	copy(pp.PublicPort.Subnet6.Addr[:], testPublic6)
+	copy(pp.PrivatePort.Subnet6.Addr[:], []byte{0xfd, 15: 1})
+	pp.PublicPort.allocatePublicPortPortMap(nil)
+	pp.PublicPort.allocateLookupMap()
+	pp.PrivatePort.allocateLookupMap()
+	return pp
+}
+
+func TestRestoreSessions(t *testing.T) {
+	saved := Natconfig
+	defer func() { Natconfig = saved }()
+	Natconfig = &Config{PortPairs: []portPair{testRestorePair()}}
+	pp := &Natconfig.PortPairs[0]
+
+	lastUsed := time.Now().Add(-10 * time.Second)
+	sessions := []savedSession{
+		{
+			Protocol:             types.TCPNumber,
+			PublicAddress:        "198.51.100.1",
+			PublicPort:           2000,
+			PrivateAddress:       "192.168.1.10",
+			PrivatePort:          40000,
+			LastUsed:             lastUsed,
+			FinCount:             1,
+			TerminationDirection: pri2pub,
+		},
+		{
+			IPv6:           true,
+			Protocol:       types.UDPNumber,
+			PublicAddress:  net.IP(testPublic6).String(),
+			PublicPort:     3000,
+			PrivateAddress: net.IP(testPrivate6).String(),
+			PrivatePort:    5060,
+			LastUsed:       lastUsed,
+		},
+	}
+	if restored := restoreSessions(sessions); restored != len(sessions) {
+		t.Fatalf("%d sessions are restored, expected %d", restored, len(sessions))
+	}
+
+	var private6, public6 types.IPv6Address
+	copy(private6[:], testPrivate6)
+	copy(public6[:], testPublic6)
+	translations := []struct {
+		name     string
+		protocol uint8
+		public   interface{}
+		private  interface{}
+	}{
+		{"IPv4 TCP session", types.TCPNumber,
+			Tuple{addr: hostIPv4(198, 51, 100, 1), port: 2000}, Tuple{addr: hostIPv4(192, 168, 1, 10), port: 40000}},
+		{"IPv6 UDP session", types.UDPNumber, Tuple6{addr: public6, port: 3000}, Tuple6{addr: private6, port: 5060}},
+	}
+	for _, tt := range translations {
+		if v, found := pp.PublicPort.translationTable[tt.protocol].Load(tt.public); !found || v != tt.private {
+			t.Errorf("%s: public entry translates to %v, expected %v", tt.name, v, tt.private)
+		}
+		if v, found := pp.PrivatePort.translationTable[tt.protocol].Load(tt.private); !found || v != tt.public {
+			t.Errorf("%s: private entry translates to %v, expected %v", tt.name, v, tt.public)
+		}
+	}
+	pme := &pp.getPublicPortPortmap(false, types.TCPNumber)[2000]
+	if !pme.lastused.Equal(lastUsed) || pme.finCount != 1 || pme.terminationDirection != pri2pub || pme.static {
+		t.Errorf("Restored TCP session has last use %v, FIN count %d and termination direction %v, expected %v, 1 and %v",
+			pme.lastused, pme.finCount, pme.terminationDirection, lastUsed, pri2pub)
+	}
+
+	// Sessions which private hosts already have are not restored
+	// again
+	if restored := restoreSessions(sessions); restored != 0 {
+		t.Errorf("%d sessions are restored twice", restored)
+	}
+
+	// Restored sessions are saved again as they were
+	again := collectSessions()
+	if len(again) != len(sessions) {
+		t.Fatalf("%d sessions are saved after restore, expected %d", len(again), len(sessions))
+	}
+	for i := range again {
+		s, orig := again[i], sessions[i]
+		if s.IPv6 != orig.IPv6 || s.Protocol != orig.Protocol || s.PublicAddress != orig.PublicAddress ||
+			s.PublicPort != orig.PublicPort || s.PrivateAddress != orig.PrivateAddress || s.PrivatePort != orig.PrivatePort ||
+			!s.LastUsed.Equal(orig.LastUsed) || s.FinCount != orig.FinCount {
+			t.Errorf("Session %d is saved as %+v, expected %+v", i, s, orig)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// Interval between checks whether all sessions are finished while
+// draining
+const drainCheckInterval = time.Second
+
+// Graceful shutdown options.
+type shutdownConfig struct {
+	// Time in seconds to keep translating established sessions after
+	// SIGTERM, zero means to stop immediately
+	DrainTime uint `json:"drain-time"`
+	// Answer new TCP connections with reset while draining instead of
+	// dropping them
+	ResetNewConnections bool `json:"reset-new-connections"`
+}
+
+// Set to 1 when NAT stops accepting new sessions
+var draining int32
+
+func isDraining() bool {
+	return atomic.LoadInt32(&draining) != 0
+}
+
+// DrainSessions stops accepting new sessions and waits until all
+// established sessions are finished or drain time configured in
+// shutdown options passes.
+func DrainSessions() {
+	drainTime := time.Duration(Natconfig.Shutdown.DrainTime) * time.Second
+	if drainTime == 0 {
+		return
+	}
+	atomic.StoreInt32(&draining, 1)
+	println("Draining sessions for up to", Natconfig.Shutdown.DrainTime, "seconds")
+
+	deadline := time.Now().Add(drainTime)
+	for time.Now().Before(deadline) {
+		sessions := 0
+		for i := range Natconfig.PortPairs {
+			sessions += Natconfig.PortPairs[i].totalActiveSessions()
+		}
+		if sessions == 0 {
+			println("All sessions are finished")
+			return
+		}
+		time.Sleep(drainCheckInterval)
+	}
+	println("Drain time is over, remaining sessions are dropped")
+}
+
+// totalActiveSessions returns number of dynamic sessions of all
+// protocols.
+func (pp *portPair) totalActiveSessions() int {
+	count := 0
+	for _, ipv6 := range pp.ipFamilies() {
+		for _, protocol := range pp.sessionProtocols(ipv6) {
+			count += pp.activeSessions(ipv6, protocol)
+		}
+	}
+	return count
+}
+
+// isNewTCPConnection returns true for segments which start TCP
+// connection.
+func isNewTCPConnection(pktTCP *packet.TCPHdr) bool {
+	return pktTCP.TCPFlags&(types.TCPFlagSyn|types.TCPFlagAck|types.TCPFlagRst) == types.TCPFlagSyn
+}
+
+// refuseNewSession drops packet which would start a new session while
+// NAT is draining or port pair is in maintenance. New TCP connections
+// are answered with reset if shutdown options or maintenance require
+// it.
+func (pp *portPair) refuseNewSession(port *ipPort, pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr, pktTCP *packet.TCPHdr, pktUDP *packet.UDPHdr) uint {
+	if pp.inMaintenance() {
+		pp.refuseMaintenanceSession(port, pkt, pktIPv4, pktIPv6, pktTCP, pktUDP)
+	} else if pktTCP != nil && Natconfig.Shutdown.ResetNewConnections && isNewTCPConnection(pktTCP) {
+		port.sendTCPReset(pkt, pktIPv4, pktIPv6, pktTCP)
+	}
+	port.dumpPacket(pkt, DirDROP)
+	return DirDROP
+}
@@ -0,0 +1,663 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/types"
+)
+
+// Minimal read only SNMPv2c agent. It supports Get, GetNext and
+// GetBulk requests for a subset of SNMPv2-MIB system group, IF-MIB
+// interface tables and NFF-GO-NAT-MIB enterprise tables described in
+// mib/NFF-GO-NAT-MIB.txt.
+
+const (
+	snmpVersion2c = 1
+
+	snmpMaxPacketSize = 65507
+	snmpMaxVarBinds   = 128
+
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berNull        = 0x05
+	berOID         = 0x06
+	berSequence    = 0x30
+
+	snmpIPAddress      = 0x40
+	snmpCounter32      = 0x41
+	snmpGauge32        = 0x42
+	snmpTimeTicks      = 0x43
+	snmpCounter64      = 0x46
+	snmpNoSuchObject   = 0x80
+	snmpNoSuchInstance = 0x81
+	snmpEndOfMibView   = 0x82
+
+	snmpGetRequest     = 0xa0
+	snmpGetNextRequest = 0xa1
+	snmpResponse       = 0xa2
+	snmpSetRequest     = 0xa3
+	snmpGetBulkRequest = 0xa5
+
+	snmpErrNotWritable = 17
+
+	ifTypeEthernet = 6
+	ifStatusUp     = 1
+	ifStatusDown   = 2
+	// ifSpeed is Gauge32 in bits per second, faster links report
+	// maximum value and real speed is in ifHighSpeed
+	ifSpeedMax = 4294967295
+
+	natPortRolePrivate = 1
+	natPortRolePublic  = 2
+)
+
+var (
+	snmpSystemOID  = snmpOID{1, 3, 6, 1, 2, 1, 1}
+	snmpIfOID      = snmpOID{1, 3, 6, 1, 2, 1, 2}
+	snmpIfXOID     = snmpOID{1, 3, 6, 1, 2, 1, 31, 1, 1, 1}
+	snmpNatMIBOID  = snmpOID{1, 3, 6, 1, 4, 1, 343, 6, 100}
+	snmpNatObjects = snmpNatMIBOID.child(1)
+
+	errSNMPBadPacket = errors.New("Malformed SNMP packet")
+)
+
+type snmpOID []uint32
+
+func (oid snmpOID) compare(other snmpOID) int {
+	for i := 0; i < len(oid) && i < len(other); i++ {
+		if oid[i] < other[i] {
+			return -1
+		} else if oid[i] > other[i] {
+			return 1
+		}
+	}
+	return len(oid) - len(other)
+}
+
+func (oid snmpOID) child(ids ...uint32) snmpOID {
+	result := make(snmpOID, 0, len(oid)+len(ids))
+	result = append(result, oid...)
+	return append(result, ids...)
+}
+
+func (oid snmpOID) String() string {
+	str := ""
+	for i, id := range oid {
+		if i > 0 {
+			str += "."
+		}
+		str += strconv.FormatUint(uint64(id), 10)
+	}
+	return str
+}
+
+// Encoded SNMP value.
+type snmpValue struct {
+	tag  byte
+	data []byte
+}
+
+type snmpVariable struct {
+	oid snmpOID
+	get func() snmpValue
+}
+
+func berLength(length int) []byte {
+	if length < 0x80 {
+		return []byte{byte(length)}
+	}
+	var buf []byte
+	for ; length > 0; length >>= 8 {
+		buf = append([]byte{byte(length)}, buf...)
+	}
+	return append([]byte{0x80 | byte(len(buf))}, buf...)
+}
+
+func berTLV(tag byte, contents ...[]byte) []byte {
+	size := 0
+	for _, c := range contents {
+		size += len(c)
+	}
+	result := append([]byte{tag}, berLength(size)...)
+	for _, c := range contents {
+		result = append(result, c...)
+	}
+	return result
+}
+
+func berInt(v int64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(v))
+	// Strip redundant sign bytes
+	for len(buf) > 1 &&
+		((buf[0] == 0 && buf[1]&0x80 == 0) || (buf[0] == 0xff && buf[1]&0x80 != 0)) {
+		buf = buf[1:]
+	}
+	return buf
+}
+
+func berUint(v uint64) []byte {
+	buf := make([]byte, 9)
+	binary.BigEndian.PutUint64(buf[1:], v)
+	for len(buf) > 1 && buf[0] == 0 && buf[1]&0x80 == 0 {
+		buf = buf[1:]
+	}
+	return buf
+}
+
+// berEncodeOID encodes OID. First two arcs are combined into one
+// subidentifier.
+func berEncodeOID(oid snmpOID) []byte {
+	if len(oid) < 2 {
+		return []byte{0}
+	}
+	buf := berSubidentifier(nil, oid[0]*40+oid[1])
+	for _, id := range oid[2:] {
+		buf = berSubidentifier(buf, id)
+	}
+	return buf
+}
+
+// berSubidentifier appends base-128 encoding of OID subidentifier to
+// buf.
+func berSubidentifier(buf []byte, id uint32) []byte {
+	enc := []byte{byte(id & 0x7f)}
+	for id >>= 7; id > 0; id >>= 7 {
+		enc = append([]byte{byte(id&0x7f) | 0x80}, enc...)
+	}
+	return append(buf, enc...)
+}
+
+// berDecodeOID decodes OID. First subidentifier combines the first
+// two arcs as 40*arc1+arc2 and may take several bytes when arc1 is 2.
+func berDecodeOID(data []byte) (snmpOID, error) {
+	if len(data) == 0 {
+		return nil, errSNMPBadPacket
+	}
+	var oid snmpOID
+	var id uint32
+	for i, b := range data {
+		// Subidentifier doesn't fit into 32 bits
+		if id>>25 != 0 {
+			return nil, errSNMPBadPacket
+		}
+		id = id<<7 | uint32(b&0x7f)
+		if b&0x80 != 0 {
+			if i == len(data)-1 {
+				return nil, errSNMPBadPacket
+			}
+			continue
+		}
+		if len(oid) == 0 {
+			arc1 := id / 40
+			if arc1 > 2 {
+				arc1 = 2
+			}
+			oid = append(oid, arc1, id-40*arc1)
+		} else {
+			oid = append(oid, id)
+		}
+		id = 0
+	}
+	return oid, nil
+}
+
+// berRead reads one TLV from data and returns its tag, contents and
+// remaining data.
+func berRead(data []byte) (byte, []byte, []byte, error) {
+	if len(data) < 2 {
+		return 0, nil, nil, errSNMPBadPacket
+	}
+	tag := data[0]
+	length := int(data[1])
+	data = data[2:]
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 || len(data) < n {
+			return 0, nil, nil, errSNMPBadPacket
+		}
+		length = 0
+		for _, b := range data[:n] {
+			length = length<<8 | int(b)
+		}
+		data = data[n:]
+	}
+	if length < 0 || length > len(data) {
+		return 0, nil, nil, errSNMPBadPacket
+	}
+	return tag, data[:length], data[length:], nil
+}
+
+func berReadInt(data []byte) (int64, []byte, error) {
+	tag, contents, rest, err := berRead(data)
+	if err != nil {
+		return 0, nil, err
+	}
+	if tag != berInteger || len(contents) == 0 || len(contents) > 8 {
+		return 0, nil, errSNMPBadPacket
+	}
+	v := int64(int8(contents[0]))
+	for _, b := range contents[1:] {
+		v = v<<8 | int64(b)
+	}
+	return v, rest, nil
+}
+
+func snmpInteger(v int) snmpValue {
+	return snmpValue{tag: berInteger, data: berInt(int64(v))}
+}
+
+func snmpString(s string) snmpValue {
+	return snmpValue{tag: berOctetString, data: []byte(s)}
+}
+
+func snmpBytes(b []byte) snmpValue {
+	return snmpValue{tag: berOctetString, data: b}
+}
+
+func snmpObjectID(oid snmpOID) snmpValue {
+	return snmpValue{tag: berOID, data: berEncodeOID(oid)}
+}
+
+func snmpIPv4Address(addr uint32) snmpValue {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, addr)
+	return snmpValue{tag: snmpIPAddress, data: buf}
+}
+
+func snmpCounter32Value(v uint64) snmpValue {
+	return snmpValue{tag: snmpCounter32, data: berUint(uint64(uint32(v)))}
+}
+
+func snmpGauge32Value(v int) snmpValue {
+	return snmpValue{tag: snmpGauge32, data: berUint(uint64(uint32(v)))}
+}
+
+func snmpCounter64Value(v uint64) snmpValue {
+	return snmpValue{tag: snmpCounter64, data: berUint(v)}
+}
+
+type snmpAgent struct {
+	community string
+	started   time.Time
+	mib       []snmpVariable
+}
+
+// StartSNMPAgent starts SNMP agent listening on UDP address for
+// requests with specified community.
+func StartSNMPAgent(address, community string) error {
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return err
+	}
+	agent := &snmpAgent{
+		community: community,
+		started:   time.Now(),
+	}
+	agent.mib = agent.buildMIB()
+
+	go func() {
+		buf := make([]byte, snmpMaxPacketSize)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				common.LogWarning(common.No, "Error while reading SNMP request:", err)
+				continue
+			}
+			reply, err := agent.handleRequest(buf[:n])
+			if err != nil {
+				common.LogWarning(common.Debug, "Bad SNMP request from", addr, ":", err)
+				continue
+			}
+			if reply == nil {
+				continue
+			}
+			if _, err := conn.WriteTo(reply, addr); err != nil {
+				common.LogWarning(common.No, "Error while sending SNMP response:", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Ports ordered by their DPDK index. SNMP ifIndex of a port is its
+// DPDK index plus one.
+func snmpPorts() []*ipPort {
+	ports := []*ipPort{}
+	for i := range Natconfig.PortPairs {
+		pp := &Natconfig.PortPairs[i]
+		ports = append(ports, &pp.PrivatePort, &pp.PublicPort)
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		return ports[i].Index < ports[j].Index
+	})
+	return ports
+}
+
+func (port *ipPort) ifIndex() uint32 {
+	return uint32(port.Index) + 1
+}
+
+func (port *ipPort) ifName() string {
+	if port.KNIName != "" {
+		return port.KNIName
+	}
+	return "port" + strconv.Itoa(int(port.Index))
+}
+
+func (port *ipPort) ifDescr() string {
+	if port.Type == iPUBLIC {
+		return "NFF-Go NAT public port " + strconv.Itoa(int(port.Index))
+	}
+	return "NFF-Go NAT private port " + strconv.Itoa(int(port.Index))
+}
+
+// buildMIB creates a sorted list of all variables served by agent.
+// Values are computed when they are requested.
+func (agent *snmpAgent) buildMIB() []snmpVariable {
+	mib := []snmpVariable{}
+	add := func(oid snmpOID, get func() snmpValue) {
+		mib = append(mib, snmpVariable{oid: oid, get: get})
+	}
+
+	// SNMPv2-MIB system group
+	add(snmpSystemOID.child(1, 0), func() snmpValue { return snmpString("NFF-Go NAT") })
+	add(snmpSystemOID.child(2, 0), func() snmpValue { return snmpObjectID(snmpNatMIBOID) })
+	add(snmpSystemOID.child(3, 0), func() snmpValue {
+		return snmpValue{tag: snmpTimeTicks, data: berUint(uint64(uint32(time.Since(agent.started) / (10 * time.Millisecond))))}
+	})
+	add(snmpSystemOID.child(5, 0), func() snmpValue { return snmpString(Natconfig.HostName) })
+
+	ports := snmpPorts()
+
+	// IF-MIB interfaces group and ifXTable
+	add(snmpIfOID.child(1, 0), func() snmpValue { return snmpInteger(len(ports)) })
+	for _, port := range ports {
+		p := port
+		idx := p.ifIndex()
+		entry := snmpIfOID.child(2, 1)
+		add(entry.child(1, idx), func() snmpValue { return snmpInteger(int(idx)) })
+		add(entry.child(2, idx), func() snmpValue { return snmpString(p.ifDescr()) })
+		add(entry.child(3, idx), func() snmpValue { return snmpInteger(ifTypeEthernet) })
+		add(entry.child(5, idx), func() snmpValue {
+			speed := uint64(p.getLinkStatus().speed) * 1000000
+			if speed > ifSpeedMax {
+				speed = ifSpeedMax
+			}
+			return snmpGauge32Value(int(speed))
+		})
+		add(entry.child(6, idx), func() snmpValue { return snmpBytes(p.SrcMACAddress[:]) })
+		add(entry.child(7, idx), func() snmpValue {
+			if portInMaintenance(p) {
+				return snmpInteger(ifStatusDown)
+			}
+			return snmpInteger(ifStatusUp)
+		})
+		add(entry.child(8, idx), func() snmpValue {
+			if p.getLinkStatus().up {
+				return snmpInteger(ifStatusUp)
+			}
+			return snmpInteger(ifStatusDown)
+		})
+		add(entry.child(10, idx), func() snmpValue { return snmpCounter32Value(p.getStats().rxBytes) })
+		add(entry.child(11, idx), func() snmpValue { return snmpCounter32Value(p.getStats().rxPackets) })
+		add(entry.child(13, idx), func() snmpValue { return snmpCounter32Value(p.getStats().dropPackets) })
+		add(entry.child(14, idx), func() snmpValue {
+			stats, _ := getNICStats(p.Index)
+			return snmpCounter32Value(stats.rxErrors)
+		})
+		add(entry.child(16, idx), func() snmpValue { return snmpCounter32Value(p.getStats().txBytes) })
+		add(entry.child(17, idx), func() snmpValue { return snmpCounter32Value(p.getStats().txPackets) })
+		add(entry.child(20, idx), func() snmpValue {
+			stats, _ := getNICStats(p.Index)
+			return snmpCounter32Value(stats.txErrors)
+		})
+
+		add(snmpIfXOID.child(1, idx), func() snmpValue { return snmpString(p.ifName()) })
+		add(snmpIfXOID.child(6, idx), func() snmpValue { return snmpCounter64Value(p.getStats().rxBytes) })
+		add(snmpIfXOID.child(7, idx), func() snmpValue { return snmpCounter64Value(p.getStats().rxPackets) })
+		add(snmpIfXOID.child(10, idx), func() snmpValue { return snmpCounter64Value(p.getStats().txBytes) })
+		add(snmpIfXOID.child(11, idx), func() snmpValue { return snmpCounter64Value(p.getStats().txPackets) })
+		add(snmpIfXOID.child(15, idx), func() snmpValue { return snmpGauge32Value(int(p.getLinkStatus().speed)) })
+	}
+
+	// NFF-GO-NAT-MIB natPairTable
+	for i := range Natconfig.PortPairs {
+		pp := &Natconfig.PortPairs[i]
+		idx := uint32(i + 1)
+		entry := snmpNatObjects.child(1, 1)
+		sessions := func(ipv6 bool, protocol uint8) func() snmpValue {
+			return func() snmpValue { return snmpGauge32Value(pp.activeSessions(ipv6, protocol)) }
+		}
+		add(entry.child(1, idx), func() snmpValue { return snmpInteger(int(idx)) })
+		add(entry.child(2, idx), func() snmpValue { return snmpInteger(int(pp.PrivatePort.ifIndex())) })
+		add(entry.child(3, idx), func() snmpValue { return snmpInteger(int(pp.PublicPort.ifIndex())) })
+		add(entry.child(4, idx), sessions(false, types.TCPNumber))
+		add(entry.child(5, idx), sessions(false, types.UDPNumber))
+		add(entry.child(6, idx), sessions(false, types.ICMPNumber))
+		add(entry.child(7, idx), sessions(true, types.TCPNumber))
+		add(entry.child(8, idx), sessions(true, types.UDPNumber))
+		add(entry.child(9, idx), sessions(true, types.ICMPv6Number))
+		add(entry.child(10, idx), func() snmpValue { return snmpGauge32Value(numPorts) })
+		add(entry.child(11, idx), func() snmpValue { return snmpGauge32Value(pp.portPoolUtilization()) })
+	}
+
+	// NFF-GO-NAT-MIB natPortTable
+	for _, port := range ports {
+		p := port
+		idx := p.ifIndex()
+		entry := snmpNatObjects.child(2, 1)
+		add(entry.child(1, idx), func() snmpValue { return snmpInteger(int(idx)) })
+		add(entry.child(2, idx), func() snmpValue {
+			if p.Type == iPUBLIC {
+				return snmpInteger(natPortRolePublic)
+			}
+			return snmpInteger(natPortRolePrivate)
+		})
+		add(entry.child(3, idx), func() snmpValue {
+			if !p.Subnet.addressAcquired {
+				return snmpIPv4Address(0)
+			}
+			return snmpIPv4Address(uint32(p.Subnet.Addr))
+		})
+		add(entry.child(4, idx), func() snmpValue {
+			if !p.Subnet.addressAcquired {
+				return snmpInteger(0)
+			}
+			return snmpInteger(bits.OnesCount32(uint32(p.Subnet.Mask)))
+		})
+		add(entry.child(5, idx), func() snmpValue { return snmpInteger(p.Subnet.dhcpState()) })
+		add(entry.child(6, idx), func() snmpValue {
+			if !p.Subnet6.addressAcquired {
+				return snmpBytes(zeroIPv6Addr[:])
+			}
+			return snmpBytes(p.Subnet6.Addr[:])
+		})
+		add(entry.child(7, idx), func() snmpValue {
+			if !p.Subnet6.addressAcquired {
+				return snmpInteger(0)
+			}
+			ones, _ := net.IPMask(p.Subnet6.Mask[:]).Size()
+			return snmpInteger(ones)
+		})
+		add(entry.child(8, idx), func() snmpValue { return snmpInteger(p.Subnet6.dhcpState()) })
+		add(entry.child(9, idx), func() snmpValue { return snmpCounter64Value(p.getStats().kniPackets) })
+		add(entry.child(10, idx), func() snmpValue { return snmpCounter64Value(p.getStats().dropPackets) })
+	}
+
+	sort.Slice(mib, func(i, j int) bool {
+		return mib[i].oid.compare(mib[j].oid) < 0
+	})
+	return mib
+}
+
+// lookup returns index of the first variable with OID greater or
+// equal to specified.
+func (agent *snmpAgent) lookup(oid snmpOID) int {
+	return sort.Search(len(agent.mib), func(i int) bool {
+		return agent.mib[i].oid.compare(oid) >= 0
+	})
+}
+
+func (agent *snmpAgent) get(oid snmpOID) (snmpOID, snmpValue) {
+	i := agent.lookup(oid)
+	if i < len(agent.mib) && agent.mib[i].oid.compare(oid) == 0 {
+		return oid, agent.mib[i].get()
+	}
+	// Instance is missing if MIB has other instances of the same
+	// object, they may be located right before or after requested OID
+	for _, j := range []int{i - 1, i} {
+		if j >= 0 && j < len(agent.mib) && len(oid) > 0 && len(agent.mib[j].oid) == len(oid) &&
+			agent.mib[j].oid[:len(oid)-1].compare(oid[:len(oid)-1]) == 0 {
+			return oid, snmpValue{tag: snmpNoSuchInstance}
+		}
+	}
+	return oid, snmpValue{tag: snmpNoSuchObject}
+}
+
+func (agent *snmpAgent) getNext(oid snmpOID) (snmpOID, snmpValue) {
+	i := agent.lookup(oid)
+	if i < len(agent.mib) && agent.mib[i].oid.compare(oid) == 0 {
+		i++
+	}
+	if i >= len(agent.mib) {
+		return oid, snmpValue{tag: snmpEndOfMibView}
+	}
+	return agent.mib[i].oid, agent.mib[i].get()
+}
+
+// handleRequest parses SNMP request and returns encoded
+// response. Requests with unsupported version or wrong community are
+// silently ignored as required by RFC 3584.
+func (agent *snmpAgent) handleRequest(data []byte) ([]byte, error) {
+	tag, msg, _, err := berRead(data)
+	if err != nil || tag != berSequence {
+		return nil, errSNMPBadPacket
+	}
+	version, msg, err := berReadInt(msg)
+	if err != nil {
+		return nil, err
+	}
+	if version != snmpVersion2c {
+		return nil, nil
+	}
+	tag, community, msg, err := berRead(msg)
+	if err != nil || tag != berOctetString {
+		return nil, errSNMPBadPacket
+	}
+	if string(community) != agent.community {
+		return nil, nil
+	}
+	pduType, pdu, _, err := berRead(msg)
+	if err != nil {
+		return nil, err
+	}
+	requestID, pdu, err := berReadInt(pdu)
+	if err != nil {
+		return nil, err
+	}
+	// For GetBulk these are non-repeaters and max-repetitions
+	errorStatus, pdu, err := berReadInt(pdu)
+	if err != nil {
+		return nil, err
+	}
+	errorIndex, pdu, err := berReadInt(pdu)
+	if err != nil {
+		return nil, err
+	}
+	tag, list, _, err := berRead(pdu)
+	if err != nil || tag != berSequence {
+		return nil, errSNMPBadPacket
+	}
+	oids := []snmpOID{}
+	requestVarbinds := [][]byte{}
+	for len(list) > 0 {
+		var vb, rest []byte
+		tag, vb, rest, err = berRead(list)
+		if err != nil || tag != berSequence {
+			return nil, errSNMPBadPacket
+		}
+		requestVarbinds = append(requestVarbinds, list[:len(list)-len(rest)])
+		list = rest
+		tag, raw, _, err := berRead(vb)
+		if err != nil || tag != berOID {
+			return nil, errSNMPBadPacket
+		}
+		oid, err := berDecodeOID(raw)
+		if err != nil {
+			return nil, err
+		}
+		oids = append(oids, oid)
+	}
+
+	var varbinds [][]byte
+	addVarbind := func(oid snmpOID, value snmpValue) {
+		varbinds = append(varbinds, berTLV(berSequence,
+			berTLV(berOID, berEncodeOID(oid)),
+			berTLV(value.tag, value.data)))
+	}
+	var status, index int64
+
+	switch pduType {
+	case snmpGetRequest:
+		for _, oid := range oids {
+			addVarbind(agent.get(oid))
+		}
+	case snmpGetNextRequest:
+		for _, oid := range oids {
+			addVarbind(agent.getNext(oid))
+		}
+	case snmpGetBulkRequest:
+		nonRepeaters := int(errorStatus)
+		if nonRepeaters < 0 {
+			nonRepeaters = 0
+		}
+		if nonRepeaters > len(oids) {
+			nonRepeaters = len(oids)
+		}
+		for _, oid := range oids[:nonRepeaters] {
+			addVarbind(agent.getNext(oid))
+		}
+		repeaters := oids[nonRepeaters:]
+		for r := int64(0); r < errorIndex && len(repeaters) > 0 && len(varbinds)+len(repeaters) <= snmpMaxVarBinds; r++ {
+			end := true
+			for i, oid := range repeaters {
+				next, value := agent.getNext(oid)
+				addVarbind(next, value)
+				repeaters[i] = next
+				if value.tag != snmpEndOfMibView {
+					end = false
+				}
+			}
+			if end {
+				break
+			}
+		}
+	case snmpSetRequest:
+		status = snmpErrNotWritable
+		index = 1
+		varbinds = requestVarbinds
+	default:
+		return nil, errors.New("Unsupported SNMP PDU type " + strconv.Itoa(int(pduType)))
+	}
+
+	return berTLV(berSequence,
+		berTLV(berInteger, berInt(version)),
+		berTLV(berOctetString, community),
+		berTLV(snmpResponse,
+			berTLV(berInteger, berInt(requestID)),
+			berTLV(berInteger, berInt(status)),
+			berTLV(berInteger, berInt(index)),
+			berTLV(berSequence, varbinds...))), nil
+}
@@ -0,0 +1,298 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestBEREncoding(t *testing.T) {
+	lengths := []struct {
+		length  int
+		encoded []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x81, 0x80}},
+		{255, []byte{0x81, 0xff}},
+		{256, []byte{0x82, 0x01, 0x00}},
+		{65507, []byte{0x82, 0xff, 0xe3}},
+	}
+	for _, tt := range lengths {
+		if got := berLength(tt.length); !bytes.Equal(got, tt.encoded) {
+			t.Errorf("berLength(%d) = % x, expected % x", tt.length, got, tt.encoded)
+		}
+	}
+
+	ints := []struct {
+		v       int64
+		encoded []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x00, 0x80}},
+		{256, []byte{0x01, 0x00}},
+		{-1, []byte{0xff}},
+		{-128, []byte{0x80}},
+		{-129, []byte{0xff, 0x7f}},
+		{math.MaxInt64, []byte{0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{math.MinInt64, []byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+	}
+	for _, tt := range ints {
+		if got := berInt(tt.v); !bytes.Equal(got, tt.encoded) {
+			t.Errorf("berInt(%d) = % x, expected % x", tt.v, got, tt.encoded)
+		}
+		v, rest, err := berReadInt(berTLV(berInteger, tt.encoded))
+		if err != nil || v != tt.v || len(rest) != 0 {
+			t.Errorf("berReadInt of % x = %d, %v, expected %d", tt.encoded, v, err, tt.v)
+		}
+	}
+
+	uints := []struct {
+		v       uint64
+		encoded []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{255, []byte{0x00, 0xff}},
+		{math.MaxUint32, []byte{0x00, 0xff, 0xff, 0xff, 0xff}},
+		{math.MaxUint64, []byte{0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	}
+	for _, tt := range uints {
+		if got := berUint(tt.v); !bytes.Equal(got, tt.encoded) {
+			t.Errorf("berUint(%d) = % x, expected % x", tt.v, got, tt.encoded)
+		}
+	}
+
+	oids := []struct {
+		oid     snmpOID
+		encoded []byte
+	}{
+		{snmpOID{1, 3, 6, 1, 2, 1, 1, 1, 0}, []byte{0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00}},
+		{snmpOID{1, 3, 6, 1, 4, 1, 343}, []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0x82, 0x57}},
+		{snmpOID{2, 5, 16383, 16384}, []byte{0x55, 0xff, 0x7f, 0x81, 0x80, 0x00}},
+		{snmpOID{1, 3, math.MaxUint32}, []byte{0x2b, 0x8f, 0xff, 0xff, 0xff, 0x7f}},
+		{snmpOID{0, 39}, []byte{0x27}},
+		{snmpOID{2, 47}, []byte{0x7f}},
+		{snmpOID{2, 48, 1}, []byte{0x81, 0x00, 0x01}},
+		{snmpOID{2, 999, 3}, []byte{0x88, 0x37, 0x03}},
+	}
+	for _, tt := range oids {
+		if got := berEncodeOID(tt.oid); !bytes.Equal(got, tt.encoded) {
+			t.Errorf("berEncodeOID(%s) = % x, expected % x", tt.oid, got, tt.encoded)
+		}
+		oid, err := berDecodeOID(tt.encoded)
+		if err != nil || oid.compare(tt.oid) != 0 {
+			t.Errorf("berDecodeOID(% x) = %s, %v, expected %s", tt.encoded, oid, err, tt.oid)
+		}
+	}
+	for _, encoded := range [][]byte{{}, {0x82}, {0x2b, 0x82}, {0x2b, 0x06, 0xff}, {0x2b, 0x90, 0x80, 0x80, 0x80, 0x00}} {
+		if oid, err := berDecodeOID(encoded); err == nil {
+			t.Errorf("berDecodeOID(% x) = %s, expected error", encoded, oid)
+		}
+	}
+}
+
+func TestBERRead(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		tag      byte
+		contents []byte
+		rest     []byte
+		err      bool
+	}{
+		{"short form", []byte{0x04, 0x02, 'a', 'b', 0x05}, 0x04, []byte("ab"), []byte{0x05}, false},
+		{"empty contents", []byte{0x05, 0x00}, 0x05, []byte{}, []byte{}, false},
+		{"long form", []byte{0x04, 0x81, 0x01, 'x'}, 0x04, []byte("x"), []byte{}, false},
+		{"two byte long form", []byte{0x04, 0x82, 0x00, 0x01, 'x'}, 0x04, []byte("x"), []byte{}, false},
+		{"no length", []byte{0x04}, 0, nil, nil, true},
+		{"indefinite length", []byte{0x04, 0x80, 'x'}, 0, nil, nil, true},
+		{"too long length", []byte{0x04, 0x85, 0, 0, 0, 0, 1, 'x'}, 0, nil, nil, true},
+		{"truncated length", []byte{0x04, 0x82, 0x01}, 0, nil, nil, true},
+		{"truncated contents", []byte{0x04, 0x05, 'a'}, 0, nil, nil, true},
+	}
+	for _, tt := range tests {
+		tag, contents, rest, err := berRead(tt.data)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected error", tt.name)
+			}
+			continue
+		}
+		if err != nil || tag != tt.tag || !bytes.Equal(contents, tt.contents) || !bytes.Equal(rest, tt.rest) {
+			t.Errorf("%s: berRead = %#x, % x, % x, %v", tt.name, tag, contents, rest, err)
+		}
+	}
+	for _, data := range [][]byte{{0x04, 0x01, 0x00}, {0x02, 0x00}, {0x02, 0x09, 1, 2, 3, 4, 5, 6, 7, 8, 9}} {
+		if _, _, err := berReadInt(data); err == nil {
+			t.Errorf("berReadInt(% x) is expected to fail", data)
+		}
+	}
+}
+
+func snmpTestRequest(version int64, community string, pduType byte, a, b int64, oids ...snmpOID) []byte {
+	var varbinds [][]byte
+	for _, oid := range oids {
+		varbinds = append(varbinds, berTLV(berSequence, berTLV(berOID, berEncodeOID(oid)), berTLV(berNull)))
+	}
+	return berTLV(berSequence,
+		berTLV(berInteger, berInt(version)),
+		berTLV(berOctetString, []byte(community)),
+		berTLV(pduType,
+			berTLV(berInteger, berInt(42)),
+			berTLV(berInteger, berInt(a)),
+			berTLV(berInteger, berInt(b)),
+			berTLV(berSequence, varbinds...)))
+}
+
+type snmpTestVarbind struct {
+	oid   string
+	value snmpValue
+}
+
+// snmpTestResponse parses response and returns its error status and
+// index and its variables.
+func snmpTestResponse(t *testing.T, data []byte) (int64, int64, []snmpTestVarbind) {
+	fail := func() (int64, int64, []snmpTestVarbind) {
+		t.Fatalf("Malformed response % x", data)
+		return 0, 0, nil
+	}
+	_, msg, _, err := berRead(data)
+	if err != nil {
+		return fail()
+	}
+	if _, msg, err = berReadInt(msg); err != nil {
+		return fail()
+	}
+	if _, _, msg, err = berRead(msg); err != nil {
+		return fail()
+	}
+	tag, pdu, _, err := berRead(msg)
+	if err != nil || tag != snmpResponse {
+		return fail()
+	}
+	requestID, pdu, err := berReadInt(pdu)
+	if err != nil || requestID != 42 {
+		return fail()
+	}
+	status, pdu, err := berReadInt(pdu)
+	if err != nil {
+		return fail()
+	}
+	index, pdu, err := berReadInt(pdu)
+	if err != nil {
+		return fail()
+	}
+	_, list, _, err := berRead(pdu)
+	if err != nil {
+		return fail()
+	}
+	var varbinds []snmpTestVarbind
+	for len(list) > 0 {
+		var vb []byte
+		if _, vb, list, err = berRead(list); err != nil {
+			return fail()
+		}
+		_, raw, vb, err := berRead(vb)
+		if err != nil {
+			return fail()
+		}
+		oid, err := berDecodeOID(raw)
+		if err != nil {
+			return fail()
+		}
+		tag, value, _, err := berRead(vb)
+		if err != nil {
+			return fail()
+		}
+		varbinds = append(varbinds, snmpTestVarbind{oid.String(), snmpValue{tag: tag, data: value}})
+	}
+	return status, index, varbinds
+}
+
+func TestSNMPAgent(t *testing.T) {
+	sysDescr := snmpSystemOID.child(1, 0)
+	sysUpTime := snmpSystemOID.child(3, 0)
+	ifIndex1 := snmpIfOID.child(2, 1, 1, 1)
+	ifIndex2 := snmpIfOID.child(2, 1, 1, 2)
+	agent := &snmpAgent{
+		community: "public",
+		mib: []snmpVariable{
+			{sysDescr, func() snmpValue { return snmpString("nat") }},
+			{sysUpTime, func() snmpValue { return snmpValue{tag: snmpTimeTicks, data: berUint(100)} }},
+			{ifIndex1, func() snmpValue { return snmpInteger(1) }},
+			{ifIndex2, func() snmpValue { return snmpInteger(2) }},
+		},
+	}
+	str := func(s string) snmpValue { return snmpString(s) }
+	tests := []struct {
+		name     string
+		request  []byte
+		status   int64
+		index    int64
+		varbinds []snmpTestVarbind
+	}{
+		{"get", snmpTestRequest(snmpVersion2c, "public", snmpGetRequest, 0, 0, sysDescr, ifIndex2), 0, 0,
+			[]snmpTestVarbind{{sysDescr.String(), str("nat")}, {ifIndex2.String(), snmpInteger(2)}}},
+		{"get missing instance", snmpTestRequest(snmpVersion2c, "public", snmpGetRequest, 0, 0, snmpIfOID.child(2, 1, 1, 3)), 0, 0,
+			[]snmpTestVarbind{{snmpIfOID.child(2, 1, 1, 3).String(), snmpValue{tag: snmpNoSuchInstance}}}},
+		{"get missing object", snmpTestRequest(snmpVersion2c, "public", snmpGetRequest, 0, 0, snmpSystemOID.child(9)), 0, 0,
+			[]snmpTestVarbind{{snmpSystemOID.child(9).String(), snmpValue{tag: snmpNoSuchObject}}}},
+		{"get next", snmpTestRequest(snmpVersion2c, "public", snmpGetNextRequest, 0, 0, sysDescr, snmpIfOID), 0, 0,
+			[]snmpTestVarbind{{sysUpTime.String(), snmpValue{tag: snmpTimeTicks, data: berUint(100)}}, {ifIndex1.String(), snmpInteger(1)}}},
+		{"get next at end", snmpTestRequest(snmpVersion2c, "public", snmpGetNextRequest, 0, 0, ifIndex2), 0, 0,
+			[]snmpTestVarbind{{ifIndex2.String(), snmpValue{tag: snmpEndOfMibView}}}},
+		{"get bulk", snmpTestRequest(snmpVersion2c, "public", snmpGetBulkRequest, 1, 2, sysDescr, sysUpTime), 0, 0,
+			[]snmpTestVarbind{{sysUpTime.String(), snmpValue{tag: snmpTimeTicks, data: berUint(100)}},
+				{ifIndex1.String(), snmpInteger(1)}, {ifIndex2.String(), snmpInteger(2)}}},
+		{"get bulk stops at end", snmpTestRequest(snmpVersion2c, "public", snmpGetBulkRequest, 0, 5, ifIndex1), 0, 0,
+			[]snmpTestVarbind{{ifIndex2.String(), snmpInteger(2)}, {ifIndex2.String(), snmpValue{tag: snmpEndOfMibView}}}},
+		{"set", snmpTestRequest(snmpVersion2c, "public", snmpSetRequest, 0, 0, sysDescr), snmpErrNotWritable, 1,
+			[]snmpTestVarbind{{sysDescr.String(), snmpValue{tag: berNull, data: []byte{}}}}},
+	}
+	for _, tt := range tests {
+		reply, err := agent.handleRequest(tt.request)
+		if err != nil || reply == nil {
+			t.Errorf("%s: no reply, error %v", tt.name, err)
+			continue
+		}
+		status, index, varbinds := snmpTestResponse(t, reply)
+		if status != tt.status || index != tt.index {
+			t.Errorf("%s: error status %d index %d, expected %d and %d", tt.name, status, index, tt.status, tt.index)
+		}
+		if len(varbinds) != len(tt.varbinds) {
+			t.Errorf("%s: %d variables in response, expected %d", tt.name, len(varbinds), len(tt.varbinds))
+			continue
+		}
+		for i, vb := range varbinds {
+			want := tt.varbinds[i]
+			if vb.oid != want.oid || vb.value.tag != want.value.tag || !bytes.Equal(vb.value.data, want.value.data) {
+				t.Errorf("%s: variable %d is %s %#x % x, expected %s %#x % x", tt.name, i,
+					vb.oid, vb.value.tag, vb.value.data, want.oid, want.value.tag, want.value.data)
+			}
+		}
+	}
+
+	ignored := []struct {
+		name    string
+		request []byte
+	}{
+		{"SNMPv1", snmpTestRequest(0, "public", snmpGetRequest, 0, 0, sysDescr)},
+		{"wrong community", snmpTestRequest(snmpVersion2c, "private", snmpGetRequest, 0, 0, sysDescr)},
+	}
+	for _, tt := range ignored {
+		if reply, err := agent.handleRequest(tt.request); reply != nil || err != nil {
+			t.Errorf("%s: request is answered, error %v", tt.name, err)
+		}
+	}
+	for _, data := range [][]byte{{}, {0x30, 0x03, 0x02, 0x01}, snmpTestRequest(snmpVersion2c, "public", 0xa8, 0, 0, sysDescr)} {
+		if _, err := agent.handleRequest(data); err == nil {
+			t.Errorf("Malformed request % x is accepted", data)
+		}
+	}
+}
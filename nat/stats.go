@@ -0,0 +1,276 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gopacket/layers"
+
+	"github.com/intel-go/nff-go/flow"
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// Packet counters of a network port. Counters are updated from
+// translation handlers which may run on several cores
+// simultaneously, so they should be accessed only atomically.
+type portStats struct {
+	// Packets and bytes received from network card
+	rxPackets uint64
+	rxBytes   uint64
+	// Packets and bytes translated and sent to network card
+	txPackets uint64
+	txBytes   uint64
+	// Packets sent to KNI interface
+	kniPackets uint64
+	// Dropped packets
+	dropPackets uint64
+	// Dynamic sessions created, counted on public port
+	sessions uint64
+}
+
+// DHCP client states reported by monitoring interfaces.
+const (
+	dhcpStateStatic = iota + 1
+	dhcpStateDiscovering
+	dhcpStateRequesting
+	dhcpStateBound
+	dhcpStateRenewing
+	dhcpStateReleased
+)
+
+// Packet counters of one instance of translation handlers of port
+// pair. Every instance runs on its own core and counts packets in its
+// own counters, so cores don't contend for cache lines of port
+// counters. Instance is the only writer of its counters, so it
+// updates them with atomic stores instead of atomic adds.
+type instanceStats struct {
+	public  portStats
+	private portStats
+	// Keeps counters of different instances in different cache lines
+	_ [64]byte
+}
+
+// newInstanceStats allocates counters of handler instance and adds
+// them to counters of ports.
+func (pp *portPair) newInstanceStats() *instanceStats {
+	s := new(instanceStats)
+	for _, p := range []struct {
+		port  *ipPort
+		stats *portStats
+	}{{&pp.PublicPort, &s.public}, {&pp.PrivatePort, &s.private}} {
+		p.port.instanceStatsMutex.Lock()
+		p.port.instanceStats = append(p.port.instanceStats, p.stats)
+		p.port.instanceStatsMutex.Unlock()
+	}
+	return s
+}
+
+// deleteInstanceStats adds counters of stopped handler instance to
+// port counters and forgets them.
+func (pp *portPair) deleteInstanceStats(s *instanceStats) {
+	pp.PublicPort.deleteInstanceStats(&s.public)
+	pp.PrivatePort.deleteInstanceStats(&s.private)
+}
+
+func (port *ipPort) deleteInstanceStats(stats *portStats) {
+	port.instanceStatsMutex.Lock()
+	defer port.instanceStatsMutex.Unlock()
+	for i, s := range port.instanceStats {
+		if s != stats {
+			continue
+		}
+		atomic.AddUint64(&port.stats.rxPackets, atomic.LoadUint64(&s.rxPackets))
+		atomic.AddUint64(&port.stats.rxBytes, atomic.LoadUint64(&s.rxBytes))
+		atomic.AddUint64(&port.stats.txPackets, atomic.LoadUint64(&s.txPackets))
+		atomic.AddUint64(&port.stats.txBytes, atomic.LoadUint64(&s.txBytes))
+		atomic.AddUint64(&port.stats.kniPackets, atomic.LoadUint64(&s.kniPackets))
+		atomic.AddUint64(&port.stats.dropPackets, atomic.LoadUint64(&s.dropPackets))
+		port.instanceStats = append(port.instanceStats[:i], port.instanceStats[i+1:]...)
+		return
+	}
+}
+
+// of returns counters of port in instance counters.
+func (s *instanceStats) of(port *ipPort) *portStats {
+	if port.Type == iPUBLIC {
+		return &s.public
+	}
+	return &s.private
+}
+
+// increment adds value to counter which has only one writer.
+func increment(counter *uint64, value uint64) {
+	atomic.StoreUint64(counter, *counter+value)
+}
+
+// countPacket counts packet received by port in counters of handler
+// instance, or in port counters if instance has no counters.
+func (port *ipPort) countPacket(stats *instanceStats, length uint64, dir uint) {
+	if stats == nil {
+		port.countSharedPacket(length, dir)
+		return
+	}
+	rx, opposite := stats.of(port), stats.of(port.opposite)
+	increment(&rx.rxPackets, 1)
+	increment(&rx.rxBytes, length)
+	switch dir {
+	case DirSEND:
+		increment(&opposite.txPackets, 1)
+		increment(&opposite.txBytes, length)
+	case DirKNI:
+		increment(&rx.kniPackets, 1)
+	case dirPrivateKNI:
+		increment(&opposite.kniPackets, 1)
+	case DirDROP:
+		increment(&rx.dropPackets, 1)
+	}
+}
+
+func (port *ipPort) countSharedPacket(length uint64, dir uint) {
+	atomic.AddUint64(&port.stats.rxPackets, 1)
+	atomic.AddUint64(&port.stats.rxBytes, length)
+	switch dir {
+	case DirSEND:
+		atomic.AddUint64(&port.opposite.stats.txPackets, 1)
+		atomic.AddUint64(&port.opposite.stats.txBytes, length)
+	case DirKNI:
+		atomic.AddUint64(&port.stats.kniPackets, 1)
+	case dirPrivateKNI:
+		atomic.AddUint64(&port.opposite.stats.kniPackets, 1)
+	case DirDROP:
+		atomic.AddUint64(&port.stats.dropPackets, 1)
+	}
+}
+
+// getStats returns a copy of port counters with counters of all
+// handler instances added.
+func (port *ipPort) getStats() portStats {
+	port.instanceStatsMutex.Lock()
+	defer port.instanceStatsMutex.Unlock()
+	stats := portStats{
+		rxPackets:   atomic.LoadUint64(&port.stats.rxPackets),
+		rxBytes:     atomic.LoadUint64(&port.stats.rxBytes),
+		txPackets:   atomic.LoadUint64(&port.stats.txPackets),
+		txBytes:     atomic.LoadUint64(&port.stats.txBytes),
+		kniPackets:  atomic.LoadUint64(&port.stats.kniPackets),
+		dropPackets: atomic.LoadUint64(&port.stats.dropPackets),
+		sessions:    atomic.LoadUint64(&port.stats.sessions),
+	}
+	for _, s := range port.instanceStats {
+		stats.rxPackets += atomic.LoadUint64(&s.rxPackets)
+		stats.rxBytes += atomic.LoadUint64(&s.rxBytes)
+		stats.txPackets += atomic.LoadUint64(&s.txPackets)
+		stats.txBytes += atomic.LoadUint64(&s.txBytes)
+		stats.kniPackets += atomic.LoadUint64(&s.kniPackets)
+		stats.dropPackets += atomic.LoadUint64(&s.dropPackets)
+	}
+	return stats
+}
+
+func publicToPrivateCounted(pkt *packet.Packet, ctx flow.UserContext) uint {
+	pi := ctx.(pairIndex)
+	port := &Natconfig.PortPairs[pi.index].PublicPort
+	length := uint64(pkt.GetPacketLen())
+	dir := PublicToPrivateTranslation(pkt, ctx)
+	port.countPacket(pi.stats, length, dir)
+	return dir
+}
+
+func privateToPublicCounted(pkt *packet.Packet, ctx flow.UserContext) uint {
+	pi := ctx.(pairIndex)
+	port := &Natconfig.PortPairs[pi.index].PrivatePort
+	length := uint64(pkt.GetPacketLen())
+	dir := PrivateToPublicTranslation(pkt, ctx)
+	port.countPacket(pi.stats, length, dir)
+	return dir
+}
+
+// activeSessions returns number of dynamically allocated public
+// ports which are in use for specified protocol. Port map is read
+// without taking a lock so the value is approximate.
+func (pp *portPair) activeSessions(ipv6 bool, protocol uint8) int {
+	pm := pp.getPublicPortPortmap(ipv6, protocol)
+	if pm == nil {
+		return 0
+	}
+	count := 0
+	for p := portStart; p < portEnd; p++ {
+		if !pm[p].static && time.Since(pm[p].lastused) <= connectionTimeout {
+			count++
+		}
+	}
+	return count
+}
+
+// sessionProtocols returns protocols which have dynamically
+// allocated public ports.
+func (pp *portPair) sessionProtocols(ipv6 bool) []uint8 {
+	protocols := []uint8{types.TCPNumber, types.UDPNumber, types.ICMPNumber}
+	if ipv6 {
+		protocols[2] = types.ICMPv6Number
+	}
+	for _, p := range pp.TranslateProtocols {
+		protocols = append(protocols, uint8(p))
+	}
+	return protocols
+}
+
+// portPoolUtilization returns utilization in percents of the most
+// busy public port pool among all protocols.
+func (pp *portPair) portPoolUtilization() int {
+	max := 0
+	for _, ipv6 := range pp.ipFamilies() {
+		for _, protocol := range pp.sessionProtocols(ipv6) {
+			if n := pp.activeSessions(ipv6, protocol) * 100 / pp.portPoolSize(protocol); n > max {
+				max = n
+			}
+		}
+	}
+	return max
+}
+
+func (subnet *ipv4Subnet) dhcpState() int {
+	if subnet.addressAcquired {
+		if subnet.ds.lastDHCPPacketTypeSent == layers.DHCPMsgTypeUnspecified {
+			return dhcpStateStatic
+		}
+		if subnet.ds.renewing {
+			return dhcpStateRenewing
+		}
+		return dhcpStateBound
+	}
+	if subnet.ds.released {
+		return dhcpStateReleased
+	}
+	if subnet.ds.lastDHCPPacketTypeSent == layers.DHCPMsgTypeRequest {
+		return dhcpStateRequesting
+	}
+	return dhcpStateDiscovering
+}
+
+func (subnet *ipv6Subnet) dhcpState() int {
+	if subnet.duplicate {
+		return dhcpStateStatic
+	}
+	if subnet.addressAcquired {
+		if subnet.ds.lastDHCPv6PacketTypeSent == layers.DHCPv6MsgTypeUnspecified {
+			return dhcpStateStatic
+		}
+		if subnet.ds.renewing {
+			return dhcpStateRenewing
+		}
+		return dhcpStateBound
+	}
+	if subnet.ds.released {
+		return dhcpStateReleased
+	}
+	if subnet.ds.lastDHCPv6PacketTypeSent == layers.DHCPv6MsgTypeRequest {
+		return dhcpStateRequesting
+	}
+	return dhcpStateDiscovering
+}
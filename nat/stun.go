@@ -0,0 +1,320 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+const (
+	// Source port of binding requests when it is not configured,
+	// NAT never allocates ports starting from portEnd for sessions
+	defaultSTUNClientPort = portEnd
+	// Server port when it is not configured
+	defaultSTUNServerPort = 3478
+	// External address is forgotten when server doesn't answer
+	// this many requests
+	stunMaxUnanswered = 3
+	// Port pairs are checked for due requests this often
+	stunCheckInterval = time.Second
+
+	stunHeaderLen            = 20
+	stunMagicCookie          = 0x2112a442
+	stunBindingRequest       = 0x0001
+	stunBindingResponse      = 0x0101
+	stunAttrMappedAddress    = 0x0001
+	stunAttrXorMappedAddress = 0x0020
+	stunFamilyIPv4           = 0x01
+)
+
+// Discovery of external address of public port when NAT is behind
+// another NAT. STUN binding requests of RFC 5389 are sent from public
+// port address to IPv4 server every interval seconds, zero interval
+// disables discovery. Mapped address of response is the address which
+// server sees, and so other hosts of Internet. Source port of requests
+// should not be dynamic, i.e. it should be outside of dynamic range or
+// in local-ports of port pair.
+type stunConfig struct {
+	Server     hostPort `json:"server"`
+	Interval   int      `json:"interval"`
+	SourcePort uint16   `json:"source-port"`
+}
+
+// External address of public port learned from STUN server. Requests
+// are sent by discovery goroutine and responses are handled on packet
+// processing cores, so state is protected by a mutex. Only STUN
+// packets take it.
+type externalAddress struct {
+	requests  uint64
+	responses uint64
+	mutex     sync.Mutex
+	addr      types.IPv4Address
+	port      uint16
+	// Time of last response, zero while address is not known
+	learned time.Time
+	// Transaction of last request, responses to other ones are
+	// ignored
+	transaction [12]byte
+	// Requests since last response
+	unanswered int
+	// Time of next request, used only by discovery goroutine
+	next time.Time
+}
+
+// checkSTUN checks external address discovery options and enables it
+// on public port.
+func (pp *portPair) checkSTUN() error {
+	cfg := &pp.STUN
+	if cfg.Interval < 0 {
+		return errors.New("STUN interval should not be negative")
+	}
+	if cfg.Interval == 0 {
+		return nil
+	}
+	if cfg.Server.ipv6 || cfg.Server.Addr4 == 0 {
+		return fmt.Errorf("STUN server of public port %d should be IPv4 address", pp.PublicPort.Index)
+	}
+	if pp.DisableIPv4 {
+		return fmt.Errorf("STUN discovery of public port %d requires IPv4", pp.PublicPort.Index)
+	}
+	if cfg.Server.Port == 0 {
+		cfg.Server.Port = defaultSTUNServerPort
+	}
+	if cfg.SourcePort == 0 {
+		cfg.SourcePort = defaultSTUNClientPort
+	}
+	if err := pp.checkLocalSourcePort(cfg.SourcePort, "STUN source-port"); err != nil {
+		return err
+	}
+	pp.PublicPort.external = &externalAddress{}
+	return nil
+}
+
+// externalIPv4 returns external address and port of public port and
+// time when server confirmed them last. Zero time means that address
+// is not known.
+func (port *ipPort) externalIPv4() (types.IPv4Address, uint16, time.Time) {
+	ea := port.external
+	ea.mutex.Lock()
+	defer ea.mutex.Unlock()
+	return ea.addr, ea.port, ea.learned
+}
+
+// setExternalAddress stores new external address and reports its
+// change. Caller holds mutex.
+func (port *ipPort) setExternalAddress(addr types.IPv4Address, p uint16, now time.Time) {
+	ea := port.external
+	old := ea.addr
+	ea.addr, ea.port = addr, p
+	if addr == 0 {
+		ea.learned = time.Time{}
+	} else {
+		ea.learned = now
+	}
+	if addr == old {
+		return
+	}
+	oldAddress, newAddress := "", ""
+	if old != 0 {
+		oldAddress = StringIPv4Int(uint32(old))
+	}
+	if addr != 0 {
+		newAddress = StringIPv4Int(uint32(addr))
+	}
+	if newAddress == "" {
+		common.LogWarning(common.No, "External address", oldAddress, "of port", port.logName(), "is lost")
+	} else {
+		common.LogWarning(common.No, "External address of port", port.logName(), "is", newAddress)
+	}
+	raiseEvent(EventExternalAddressChanged, port, map[string]interface{}{
+		"old-address": oldAddress,
+		"new-address": newAddress,
+	})
+}
+
+// sendSTUNRequest sends binding request from source port to STUN
+// server if port has address and MAC address of server is known.
+func (port *ipPort) sendSTUNRequest(server *hostPort, srcPort uint16) {
+	if !port.Subnet.addressAcquired {
+		return
+	}
+	mac, found := port.getMACForIPv4(server.Addr4)
+	if !found {
+		return
+	}
+
+	ea := port.external
+	ea.mutex.Lock()
+	if _, err := rand.Read(ea.transaction[:]); err != nil {
+		ea.mutex.Unlock()
+		common.LogWarning(common.No, "Failed to generate STUN transaction:", err)
+		return
+	}
+	transaction := ea.transaction
+	ea.unanswered++
+	ea.mutex.Unlock()
+
+	pkt, err := packet.NewPacket()
+	if err != nil {
+		common.LogFatal(common.Debug, err)
+	}
+	packet.InitEmptyIPv4UDPPacket(pkt, stunHeaderLen)
+	pkt.Ether.SAddr = port.SrcMACAddress
+	pkt.Ether.DAddr = mac
+
+	pktIPv4 := pkt.GetIPv4NoCheck()
+	pktIPv4.SrcAddr = packet.SwapBytesIPv4Addr(port.Subnet.Addr)
+	pktIPv4.DstAddr = packet.SwapBytesIPv4Addr(server.Addr4)
+
+	pktUDP := pkt.GetUDPNoCheck()
+	pktUDP.SrcPort = packet.SwapBytesUint16(srcPort)
+	pktUDP.DstPort = packet.SwapBytesUint16(server.Port)
+
+	payload, _ := pkt.GetPacketPayload()
+	binary.BigEndian.PutUint16(payload[0:], stunBindingRequest)
+	binary.BigEndian.PutUint16(payload[2:], 0)
+	binary.BigEndian.PutUint32(payload[4:], stunMagicCookie)
+	copy(payload[8:stunHeaderLen], transaction[:])
+
+	if port.Vlan != 0 {
+		pkt.AddVLANTag(port.Vlan)
+	}
+	setIPv4UDPChecksum(pkt, !NoCalculateChecksum, port.hwTXChecksum)
+	port.dumpPacket(pkt, DirSEND)
+	port.sendPacket(pkt)
+	atomic.AddUint64(&ea.requests, 1)
+}
+
+// parseSTUNMappedAddress returns mapped IPv4 address and port of
+// binding response, XOR-MAPPED-ADDRESS is preferred over
+// MAPPED-ADDRESS of old servers.
+func parseSTUNMappedAddress(attrs []byte) (types.IPv4Address, uint16, bool) {
+	var addr types.IPv4Address
+	var port uint16
+	found := false
+	for len(attrs) >= 4 {
+		attrType := binary.BigEndian.Uint16(attrs[0:])
+		length := int(binary.BigEndian.Uint16(attrs[2:]))
+		if len(attrs) < 4+length {
+			break
+		}
+		value := attrs[4 : 4+length]
+		if (attrType == stunAttrXorMappedAddress || (attrType == stunAttrMappedAddress && !found)) &&
+			length == 8 && value[1] == stunFamilyIPv4 {
+			port = binary.BigEndian.Uint16(value[2:])
+			a := binary.BigEndian.Uint32(value[4:])
+			if attrType == stunAttrXorMappedAddress {
+				port ^= stunMagicCookie >> 16
+				a ^= stunMagicCookie
+			}
+			addr = types.IPv4Address(a)
+			found = true
+			if attrType == stunAttrXorMappedAddress {
+				break
+			}
+		}
+		// Attributes are padded to multiple of four bytes
+		length = (length + 3) &^ 3
+		if len(attrs) < 4+length {
+			break
+		}
+		attrs = attrs[4+length:]
+	}
+	return addr, port, found
+}
+
+// handleSTUNResponse returns true if UDP packet is sent by STUN server
+// to source port of requests at public port address. Binding response
+// to last request updates external address.
+func (port *ipPort) handleSTUNResponse(pp *portPair, pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, pktUDP *packet.UDPHdr) bool {
+	ea := port.external
+	server := &pp.STUN.Server
+	if ea == nil || pktIPv4 == nil ||
+		pktUDP.DstPort != packet.SwapBytesUint16(pp.STUN.SourcePort) ||
+		pktUDP.SrcPort != packet.SwapBytesUint16(server.Port) ||
+		packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr) != server.Addr4 ||
+		packet.SwapBytesIPv4Addr(pktIPv4.DstAddr) != port.Subnet.Addr {
+		return false
+	}
+
+	payload, ok := pkt.GetPacketPayload()
+	if !ok || len(payload) < stunHeaderLen ||
+		binary.BigEndian.Uint16(payload[0:]) != stunBindingResponse ||
+		binary.BigEndian.Uint32(payload[4:]) != stunMagicCookie {
+		return true
+	}
+	length := int(binary.BigEndian.Uint16(payload[2:]))
+	if len(payload) < stunHeaderLen+length {
+		return true
+	}
+
+	ea.mutex.Lock()
+	defer ea.mutex.Unlock()
+	if string(payload[8:stunHeaderLen]) != string(ea.transaction[:]) {
+		return true
+	}
+	addr, p, found := parseSTUNMappedAddress(payload[stunHeaderLen : stunHeaderLen+length])
+	if !found {
+		return true
+	}
+	atomic.AddUint64(&ea.responses, 1)
+	ea.unanswered = 0
+	port.setExternalAddress(addr, p, time.Now())
+	// Only one response of a transaction is accepted
+	ea.transaction = [12]byte{}
+	return true
+}
+
+// probeExternalAddress sends binding request of port pair if it is
+// due and forgets external address which server doesn't confirm.
+func (pp *portPair) probeExternalAddress(now time.Time) {
+	port := &pp.PublicPort
+	ea := port.external
+	if now.Before(ea.next) {
+		return
+	}
+	ea.next = now.Add(time.Duration(pp.STUN.Interval) * time.Second)
+
+	ea.mutex.Lock()
+	if ea.unanswered >= stunMaxUnanswered && ea.addr != 0 {
+		port.setExternalAddress(0, 0, now)
+	}
+	ea.mutex.Unlock()
+
+	port.sendSTUNRequest(&pp.STUN.Server, pp.STUN.SourcePort)
+}
+
+// StartExternalAddressDiscovery starts sending STUN binding requests
+// for port pairs which discover external address.
+func StartExternalAddressDiscovery() {
+	pairs := []*portPair{}
+	for i := range Natconfig.PortPairs {
+		if Natconfig.PortPairs[i].PublicPort.external != nil {
+			pairs = append(pairs, &Natconfig.PortPairs[i])
+		}
+	}
+	if len(pairs) == 0 {
+		return
+	}
+	go func() {
+		for {
+			now := time.Now()
+			for _, pp := range pairs {
+				pp.probeExternalAddress(now)
+			}
+			time.Sleep(stunCheckInterval)
+		}
+	}()
+}
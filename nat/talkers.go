@@ -0,0 +1,237 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// Maximum number of private hosts and of destinations tracked by top
+// talkers of one port pair.
+const maxTalkersEntries = 65536
+
+// Top talkers of port pair. Private hosts and destinations which
+// transfer most bytes are found in bounded memory, so counters of
+// talkers which are not heavy hitters may be overestimated.
+type talkersConfig struct {
+	// Number of tracked private hosts and of tracked destinations,
+	// zero disables top talkers
+	Entries int `json:"entries"`
+}
+
+// Translated traffic of one IP protocol.
+type protocolCounters struct {
+	egressPackets  uint64
+	egressBytes    uint64
+	ingressPackets uint64
+	ingressBytes   uint64
+}
+
+// Counters of tracked address. Traffic of address which replaced
+// another address in table includes traffic of replaced address which
+// is reported as error.
+type talker struct {
+	// types.IPv4Address or types.IPv6Address
+	address      interface{}
+	packets      uint64
+	bytes        uint64
+	errorPackets uint64
+	errorBytes   uint64
+	index        int
+}
+
+// Min heap of talkers by bytes.
+type talkersHeap []*talker
+
+// heavyHitters finds addresses with most traffic using Space-Saving
+// algorithm. When table is full, address with least traffic is
+// replaced by new address.
+type heavyHitters struct {
+	size    int
+	entries map[interface{}]*talker
+	heap    talkersHeap
+}
+
+// Top talkers tables of port pair. They are updated from packet
+// handlers running on several cores, so they are protected by a mutex.
+type topTalkers struct {
+	mutex        sync.Mutex
+	hosts        heavyHitters
+	destinations heavyHitters
+}
+
+func (cfg *talkersConfig) check() error {
+	if cfg.Entries < 0 || cfg.Entries > maxTalkersEntries {
+		return fmt.Errorf("Top talkers entries should be between 0 and %d", maxTalkersEntries)
+	}
+	return nil
+}
+
+func (h talkersHeap) Len() int           { return len(h) }
+func (h talkersHeap) Less(i, j int) bool { return h[i].bytes < h[j].bytes }
+
+func (h talkersHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *talkersHeap) Push(x interface{}) {
+	t := x.(*talker)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *talkersHeap) Pop() interface{} {
+	old := *h
+	t := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return t
+}
+
+func newHeavyHitters(size int) heavyHitters {
+	return heavyHitters{
+		size:    size,
+		entries: map[interface{}]*talker{},
+		heap:    make(talkersHeap, 0, size),
+	}
+}
+
+func (hh *heavyHitters) add(address interface{}, length uint) {
+	if t, ok := hh.entries[address]; ok {
+		t.packets++
+		t.bytes += uint64(length)
+		heap.Fix(&hh.heap, t.index)
+		return
+	}
+	if len(hh.heap) < hh.size {
+		t := &talker{
+			address: address,
+			packets: 1,
+			bytes:   uint64(length),
+		}
+		hh.entries[address] = t
+		heap.Push(&hh.heap, t)
+		return
+	}
+	t := hh.heap[0]
+	delete(hh.entries, t.address)
+	t.address = address
+	t.errorPackets = t.packets
+	t.errorBytes = t.bytes
+	t.packets++
+	t.bytes += uint64(length)
+	hh.entries[address] = t
+	heap.Fix(&hh.heap, 0)
+}
+
+// top returns copies of count talkers with most bytes in descending
+// order. Zero count means all tracked talkers.
+func (hh *heavyHitters) top(count int) []talker {
+	result := make([]talker, len(hh.heap))
+	for i, t := range hh.heap {
+		result[i] = *t
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].bytes > result[j].bytes
+	})
+	if count != 0 && count < len(result) {
+		result = result[:count]
+	}
+	return result
+}
+
+func newTopTalkers(cfg talkersConfig) *topTalkers {
+	return &topTalkers{
+		hosts:        newHeavyHitters(cfg.Entries),
+		destinations: newHeavyHitters(cfg.Entries),
+	}
+}
+
+func (tt *topTalkers) add(host, destination interface{}, length uint) {
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+	tt.hosts.add(host, length)
+	tt.destinations.add(destination, length)
+}
+
+// top returns count private hosts and count destinations with most
+// bytes.
+func (tt *topTalkers) top(count int) ([]talker, []talker) {
+	if tt == nil {
+		return []talker{}, []talker{}
+	}
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+	return tt.hosts.top(count), tt.destinations.top(count)
+}
+
+// countTraffic accounts translated packet of specified IP protocol.
+// Private host of ingress packet is its translated destination
+// address, private host of egress packet and public destination which
+// host talks to are taken from headers, so they should not be
+// translated yet. Traffic of private host is also accounted to its
+// MAC address when hosts are tracked.
+func (pp *portPair) countTraffic(protocol uint8, egress bool, length uint, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr,
+	v4host types.IPv4Address, v6host types.IPv6Address) {
+	pc := &pp.protocols[protocol]
+	if egress {
+		atomic.AddUint64(&pc.egressPackets, 1)
+		atomic.AddUint64(&pc.egressBytes, uint64(length))
+	} else {
+		atomic.AddUint64(&pc.ingressPackets, 1)
+		atomic.AddUint64(&pc.ingressBytes, uint64(length))
+	}
+	if pp.talkers == nil && pp.PrivatePort.identities == nil {
+		return
+	}
+
+	var host, destination interface{}
+	if pktIPv6 != nil {
+		if egress {
+			host, destination = pktIPv6.SrcAddr, pktIPv6.DstAddr
+		} else {
+			host, destination = v6host, pktIPv6.SrcAddr
+		}
+	} else {
+		if egress {
+			host, destination = packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr), packet.SwapBytesIPv4Addr(pktIPv4.DstAddr)
+		} else {
+			host, destination = v4host, packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr)
+		}
+	}
+	if pp.PrivatePort.identities != nil {
+		pp.PrivatePort.identities.account(host, egress, length)
+	}
+	if pp.talkers != nil {
+		pp.talkers.add(host, destination, length)
+	}
+}
+
+// protocolCounters returns translated traffic of IP protocols which
+// have any traffic, indexed by IP protocol number.
+func (pp *portPair) protocolCounters() map[uint8]protocolCounters {
+	result := map[uint8]protocolCounters{}
+	for i := range pp.protocols {
+		pc := &pp.protocols[i]
+		c := protocolCounters{
+			egressPackets:  atomic.LoadUint64(&pc.egressPackets),
+			egressBytes:    atomic.LoadUint64(&pc.egressBytes),
+			ingressPackets: atomic.LoadUint64(&pc.ingressPackets),
+			ingressBytes:   atomic.LoadUint64(&pc.ingressBytes),
+		}
+		if c.egressPackets != 0 || c.ingressPackets != 0 {
+			result[uint8(i)] = c
+		}
+	}
+	return result
+}
@@ -0,0 +1,190 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/intel-go/nff-go/common"
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// TCP session tables of port pairs which reset idle sessions are
+// checked this often
+const tcpTimeoutResetInterval = time.Second
+
+// Directions of acknowledgement numbers of session which are
+// remembered for resets.
+const (
+	tcpAckOutbound = iota
+	tcpAckInbound
+	// Both directions were seen
+	tcpAckedBoth = 1<<tcpAckOutbound | 1<<tcpAckInbound
+)
+
+// TCP session which timed out and which endpoints are reset.
+type timedOutSession struct {
+	ipv6                    bool
+	public, private, remote interface{}
+	// VLAN subinterface of session or nil for address of port
+	vlan *publicVLAN
+	acks [2]uint32
+}
+
+// recordTCPAck remembers acknowledgement number of translated TCP
+// segment so that endpoint can be reset with sequence number which it
+// accepts.
+func recordTCPAck(pme *portMapEntry, tcp *packet.TCPHdr, inbound bool) {
+	if tcp.TCPFlags&types.TCPFlagAck == 0 {
+		return
+	}
+	dir := tcpAckOutbound
+	if inbound {
+		dir = tcpAckInbound
+	}
+	pme.tcpAcks[dir] = packet.SwapBytesUint32(tcp.RecvAck)
+	pme.tcpAcked |= 1 << uint(dir)
+}
+
+// timedOutTCPSessions removes dynamic TCP sessions of port pair which
+// were idle for connection timeout and returns those which can be
+// reset: remote host is known and segments were seen in both
+// directions.
+func (pp *portPair) timedOutTCPSessions(now time.Time) []timedOutSession {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	sessions := []timedOutSession{}
+	for _, ipv6 := range pp.ipFamilies() {
+		pm := pp.getPublicPortPortmap(ipv6, types.TCPNumber)
+		for p := portStart; p < portEnd; p++ {
+			pme := &pm[p]
+			if pme.static || pme.trigger != nil || pme.remote == nil || pme.tcpAcked != tcpAckedBoth ||
+				now.Sub(pme.lastused) <= connectionTimeout {
+				continue
+			}
+			pubKey := pp.sessionPublicKey(ipv6, *pme, uint16(p))
+			privKey, found := pp.PublicPort.translationTable[types.TCPNumber].Load(pubKey)
+			if !found {
+				continue
+			}
+			s := timedOutSession{
+				ipv6:    ipv6,
+				public:  pubKey,
+				private: privKey,
+				remote:  pme.remote,
+				acks:    pme.tcpAcks,
+			}
+			if !ipv6 && pme.addr != 0 {
+				s.vlan = pp.PublicPort.vlanByAddr(pme.addr)
+			}
+			sessions = append(sessions, s)
+			pp.deleteOldConnection(ipv6, types.TCPNumber, p)
+		}
+	}
+	return sessions
+}
+
+// resetTimedOutSessions removes TCP sessions which timed out and
+// sends reset to their private and remote hosts, so that applications
+// don't wait for connections which NAT forgot.
+func (pp *portPair) resetTimedOutSessions(now time.Time) {
+	for _, s := range pp.timedOutTCPSessions(now) {
+		// Every host accepts reset with sequence number which it
+		// acknowledged last
+		pp.PrivatePort.sendSessionReset(s.ipv6, s.remote, s.private, s.acks[tcpAckOutbound], nil)
+		pp.PublicPort.sendSessionReset(s.ipv6, s.public, s.remote, s.acks[tcpAckInbound], s.vlan)
+		atomic.AddUint64(&pp.timeoutResets, 1)
+	}
+}
+
+// sendSessionReset sends TCP reset from src to dst Tuple or Tuple6
+// through port. Reset is not sent if MAC address of destination is
+// not known.
+func (port *ipPort) sendSessionReset(ipv6 bool, src, dst interface{}, seq uint32, vlan *publicVLAN) {
+	var mac types.MACAddress
+	var found bool
+	vlanTag := port.Vlan
+	srcAddr, srcAddr6, srcPort, _ := getAddrFromTuple(src, ipv6)
+	dstAddr, dstAddr6, dstPort, _ := getAddrFromTuple(dst, ipv6)
+	switch {
+	case ipv6:
+		mac, found = port.getMACForIPv6(dstAddr6)
+	case vlan != nil:
+		vlanTag = vlan.Vlan
+		mac, found = port.getMACForVLAN(vlan, dstAddr)
+	default:
+		mac, found = port.getMACForIPv4(dstAddr)
+	}
+	if !found {
+		return
+	}
+
+	pkt, err := packet.NewPacket()
+	if err != nil {
+		common.LogFatal(common.Debug, err)
+	}
+	if ipv6 {
+		packet.InitEmptyIPv6TCPPacket(pkt, 0)
+		pktIPv6 := pkt.GetIPv6NoCheck()
+		pktIPv6.SrcAddr = srcAddr6
+		pktIPv6.DstAddr = dstAddr6
+	} else {
+		packet.InitEmptyIPv4TCPPacket(pkt, 0)
+		pktIPv4 := pkt.GetIPv4NoCheck()
+		pktIPv4.TypeOfService = 0
+		pktIPv4.PacketID = 0
+		pktIPv4.FragmentOffset = 0
+		pktIPv4.SrcAddr = packet.SwapBytesIPv4Addr(srcAddr)
+		pktIPv4.DstAddr = packet.SwapBytesIPv4Addr(dstAddr)
+	}
+	pkt.Ether.SAddr = port.SrcMACAddress
+	pkt.Ether.DAddr = mac
+
+	tcp := pkt.GetTCPNoCheck()
+	tcp.SrcPort = packet.SwapBytesUint16(srcPort)
+	tcp.DstPort = packet.SwapBytesUint16(dstPort)
+	tcp.SentSeq = packet.SwapBytesUint32(seq)
+	tcp.RecvAck = 0
+	tcp.TCPFlags = types.TCPFlagRst
+	tcp.RxWin = 0
+	tcp.TCPUrp = 0
+
+	if vlanTag != 0 {
+		pkt.AddVLANTag(vlanTag)
+	}
+	if ipv6 {
+		setIPv6TCPChecksum(pkt, !NoCalculateChecksum, port.hwTXChecksum)
+	} else {
+		setIPv4TCPChecksum(pkt, !NoCalculateChecksum, port.hwTXChecksum)
+	}
+	port.dumpPacket(pkt, DirSEND)
+	port.sendPacket(pkt)
+}
+
+// StartTCPTimeoutResets starts resetting TCP sessions which time out
+// for port pairs which enable it.
+func StartTCPTimeoutResets() {
+	pairs := []*portPair{}
+	for i := range Natconfig.PortPairs {
+		if Natconfig.PortPairs[i].TCPTimeoutReset {
+			pairs = append(pairs, &Natconfig.PortPairs[i])
+		}
+	}
+	if len(pairs) == 0 {
+		return
+	}
+	go func() {
+		for {
+			now := time.Now()
+			for _, pp := range pairs {
+				pp.resetTimedOutSessions(now)
+			}
+			time.Sleep(tcpTimeoutResetInterval)
+		}
+	}()
+}
@@ -0,0 +1,264 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+const (
+	// Valid lifetime which is used when it is not configured, in
+	// seconds. It is TEMP_VALID_LIFETIME of RFC 4941.
+	defaultTemporaryValidLifetime = 7 * 24 * 60 * 60
+	// Temporary addresses are rotated and expired with this precision
+	temporaryAddressCheckInterval = 10 * time.Second
+	// Attempts to generate unique address, TEMP_IDGEN_RETRIES of
+	// RFC 4941
+	temporaryAddressRetries = 3
+)
+
+// Temporary IPv6 addresses of public port in RFC 4941 style. New
+// NAT66 sessions get current temporary address instead of port
+// address, and current address is replaced by a new one with random
+// interface identifier every preferred lifetime. Sessions keep their
+// address until they expire.
+type temporaryAddressConfig struct {
+	// Seconds after which new sessions get new address, zero
+	// disables temporary addresses
+	PreferredLifetime int `json:"preferred-lifetime"`
+	// Seconds after which address is removed even if sessions still
+	// use it, zero means default
+	ValidLifetime int `json:"valid-lifetime"`
+}
+
+// Temporary IPv6 address of public port.
+type temporaryAddress struct {
+	addr types.IPv6Address
+	// Solicited node multicast address of addr
+	multicastAddr types.IPv6Address
+	created       time.Time
+}
+
+func (cfg *temporaryAddressConfig) enabled() bool {
+	return cfg.PreferredLifetime != 0
+}
+
+func (cfg *temporaryAddressConfig) check(port *ipPort) error {
+	if !cfg.enabled() && cfg.ValidLifetime == 0 {
+		return nil
+	}
+	if port.Type != iPUBLIC {
+		return fmt.Errorf("Temporary addresses are allowed only on public port, private port %d has them", port.Index)
+	}
+	if cfg.PreferredLifetime < 0 || cfg.ValidLifetime < 0 {
+		return errors.New("Temporary addresses lifetimes should not be negative")
+	}
+	if cfg.preferredLifetime() < temporaryAddressCheckInterval {
+		return fmt.Errorf("Temporary addresses preferred lifetime should be at least %d seconds", int(temporaryAddressCheckInterval.Seconds()))
+	}
+	if cfg.validLifetime() < cfg.preferredLifetime() {
+		return errors.New("Temporary addresses valid lifetime should not be less than preferred lifetime")
+	}
+	return nil
+}
+
+func (cfg *temporaryAddressConfig) preferredLifetime() time.Duration {
+	return time.Duration(cfg.PreferredLifetime) * time.Second
+}
+
+func (cfg *temporaryAddressConfig) validLifetime() time.Duration {
+	if cfg.ValidLifetime == 0 {
+		return defaultTemporaryValidLifetime * time.Second
+	}
+	return time.Duration(cfg.ValidLifetime) * time.Second
+}
+
+// temporaryAddressList returns temporary addresses of port, the last
+// one is current. List is replaced when addresses change, so it may
+// be used by packet handlers without locking.
+func (port *ipPort) temporaryAddressList() []*temporaryAddress {
+	list := port.temporary.Load()
+	if list == nil {
+		return nil
+	}
+	return list.([]*temporaryAddress)
+}
+
+// currentTemporaryAddress returns address for new sessions or nil if
+// port address should be used.
+func (port *ipPort) currentTemporaryAddress() *temporaryAddress {
+	list := port.temporaryAddressList()
+	if len(list) == 0 {
+		return nil
+	}
+	return list[len(list)-1]
+}
+
+func (port *ipPort) findTemporaryAddress(addr types.IPv6Address) *temporaryAddress {
+	for _, t := range port.temporaryAddressList() {
+		if t.addr == addr {
+			return t
+		}
+	}
+	return nil
+}
+
+func (port *ipPort) isTemporaryAddress(addr types.IPv6Address) bool {
+	return port.findTemporaryAddress(addr) != nil
+}
+
+// isTemporaryMulticastAddress returns true if address is solicited
+// node multicast address of temporary address.
+func (port *ipPort) isTemporaryMulticastAddress(addr types.IPv6Address) bool {
+	for _, t := range port.temporaryAddressList() {
+		if t.multicastAddr == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func newTemporaryAddress(addr types.IPv6Address, created time.Time) *temporaryAddress {
+	t := &temporaryAddress{
+		addr:    addr,
+		created: created,
+	}
+	packet.CalculateIPv6MulticastAddrForDstIP(&t.multicastAddr, addr)
+	return t
+}
+
+// StartTemporaryAddresses starts rotation of temporary IPv6
+// addresses of public ports which have them enabled.
+func StartTemporaryAddresses() {
+	for i := range Natconfig.PortPairs {
+		pp := &Natconfig.PortPairs[i]
+		if pp.PublicPort.TemporaryAddresses.enabled() {
+			go pp.runTemporaryAddresses()
+		}
+	}
+}
+
+func (pp *portPair) runTemporaryAddresses() {
+	port := &pp.PublicPort
+	cfg := &port.TemporaryAddresses
+	for {
+		pp.expireTemporaryAddresses()
+		current := port.currentTemporaryAddress()
+		if port.Subnet6.addressAcquired &&
+			(current == nil || time.Since(current.created) >= cfg.preferredLifetime() || !port.Subnet6.checkAddrWithingSubnet(current.addr)) {
+			pp.generateTemporaryAddress()
+		}
+		time.Sleep(temporaryAddressCheckInterval)
+	}
+}
+
+// generateTemporaryAddress makes new current temporary address with
+// random interface identifier in prefix of port address. Address is
+// checked by duplicate address detection before it is used.
+func (pp *portPair) generateTemporaryAddress() {
+	port := &pp.PublicPort
+	subnet := port.Subnet6
+	for i := 0; i < temporaryAddressRetries; i++ {
+		var random types.IPv6Address
+		if _, err := rand.Read(random[:]); err != nil {
+			println("Warning! Failed to generate temporary address for port", port.logName(), ":", err.Error())
+			return
+		}
+		var addr types.IPv6Address
+		for j := range addr {
+			addr[j] = subnet.Addr[j]&subnet.Mask[j] | random[j]&^subnet.Mask[j]
+		}
+		if addr == subnet.Addr || addr == subnet.andMask(addr) || port.isTemporaryAddress(addr) {
+			continue
+		}
+		if !port.DAD.Disable {
+			probe := port.startProbe(addr)
+			if _, duplicate := port.runProbe(addr, probe); duplicate {
+				continue
+			}
+		}
+
+		pp.mutex.Lock()
+		list := append(append([]*temporaryAddress{}, port.temporaryAddressList()...), newTemporaryAddress(addr, time.Now()))
+		port.temporary.Store(list)
+		pp.mutex.Unlock()
+		println("Port", port.logName(), "uses temporary IPv6 address", addr.String())
+		return
+	}
+	println("Warning! Failed to find unique temporary address for port", port.logName())
+}
+
+// expireTemporaryAddresses removes addresses which are not current
+// and have no sessions. Addresses which valid lifetime ended or which
+// don't belong to prefix of port any more are removed together with
+// their sessions.
+func (pp *portPair) expireTemporaryAddresses() {
+	port := &pp.PublicPort
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	list := port.temporaryAddressList()
+	if len(list) == 0 {
+		return
+	}
+	now := time.Now()
+	used := map[*temporaryAddress]bool{}
+	for _, protocol := range pp.sessionProtocols(true) {
+		pm := port.portmap6[protocol]
+		for p := portStart; p < portEnd; p++ {
+			if pm[p].temporary != nil && !pm[p].static && now.Sub(pm[p].lastused) <= connectionTimeout {
+				used[pm[p].temporary] = true
+			}
+		}
+	}
+
+	current := list[len(list)-1]
+	remaining := []*temporaryAddress{}
+	removed := map[*temporaryAddress]bool{}
+	for _, t := range list {
+		invalid := now.Sub(t.created) >= port.TemporaryAddresses.validLifetime() || !port.Subnet6.checkAddrWithingSubnet(t.addr)
+		if !invalid && (t == current || used[t]) {
+			remaining = append(remaining, t)
+			continue
+		}
+		removed[t] = true
+		println("Port", port.logName(), "removed temporary IPv6 address", t.addr.String())
+	}
+	if len(removed) == 0 {
+		return
+	}
+	port.temporary.Store(remaining)
+	for _, protocol := range pp.sessionProtocols(true) {
+		pm := port.portmap6[protocol]
+		for p := portStart; p < portEnd; p++ {
+			if removed[pm[p].temporary] {
+				pp.deleteOldConnection(true, protocol, p)
+			}
+		}
+	}
+}
+
+// adoptTemporaryAddress returns temporary address of restored session.
+// Address which is not known yet is added as deprecated one, so
+// session keeps it until it expires. Mutex should be locked.
+func (pp *portPair) adoptTemporaryAddress(addr types.IPv6Address) *temporaryAddress {
+	port := &pp.PublicPort
+	if !port.TemporaryAddresses.enabled() || addr == port.Subnet6.Addr || !port.Subnet6.checkAddrWithingSubnet(addr) {
+		return nil
+	}
+	if t := port.findTemporaryAddress(addr); t != nil {
+		return t
+	}
+	t := newTemporaryAddress(addr, time.Now().Add(-port.TemporaryAddresses.preferredLifetime()))
+	list := append([]*temporaryAddress{t}, port.temporaryAddressList()...)
+	port.temporary.Store(list)
+	return t
+}
@@ -5,6 +5,7 @@
 package nat
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/intel-go/nff-go/flow"
@@ -23,51 +24,90 @@ type Tuple6 struct {
 	port uint16
 }
 
-func (pp *portPair) allocateNewEgressConnection(ipv6 bool, protocol uint8, privEntry interface{}) (types.IPv4Address, types.IPv6Address, uint16, error) {
+// allocateNewEgressConnection allocates public port for new session
+// of private entry. MAC address of private host is remembered in
+// session when host identity is tracked.
+func (pp *portPair) allocateNewEgressConnection(ipv6 bool, protocol uint8, privEntry, remoteEntry interface{}, mac types.MACAddress) (types.IPv4Address, types.IPv6Address, uint16, error) {
 	pp.mutex.Lock()
 
-	port, err := pp.allocNewPort(ipv6, protocol)
+	var host interface{}
+	var privPort uint16
+	if ipv6 {
+		host, privPort = privEntry.(Tuple6).addr, privEntry.(Tuple6).port
+	} else {
+		host, privPort = privEntry.(Tuple).addr, privEntry.(Tuple).port
+	}
+	port, err := pp.allocNewPort(ipv6, protocol, host, privPort)
 	if err != nil {
 		pp.mutex.Unlock()
 		return 0, types.IPv6Address{}, 0, err
 	}
+	v4addr, v6addr := pp.addEgressSession(ipv6, protocol, port, host, privEntry, remoteEntry)
+	if pp.PrivatePort.identities != nil {
+		pp.getPublicPortPortmap(ipv6, protocol)[port].mac = mac
+	}
+	pp.portAllocated(ipv6, protocol, port, host)
+
+	pp.mutex.Unlock()
+	return v4addr, v6addr, uint16(port), nil
+}
 
+// addEgressSession adds dynamic session of private entry which gets
+// public port and returns its public address. Mutex should be locked.
+func (pp *portPair) addEgressSession(ipv6 bool, protocol uint8, port int, host, privEntry, remoteEntry interface{}) (types.IPv4Address, types.IPv6Address) {
 	var pubEntry interface{}
 	var v4addr types.IPv4Address
 	var v6addr types.IPv6Address
+	var temporary *temporaryAddress
 	if ipv6 {
 		v6addr = pp.PublicPort.Subnet6.Addr
+		if temporary = pp.PublicPort.currentTemporaryAddress(); temporary != nil {
+			v6addr = temporary.addr
+		}
 		pubEntry = Tuple6{
 			addr: v6addr,
 			port: uint16(port),
 		}
 	} else {
-		v4addr = pp.PublicPort.Subnet.Addr
+		v4addr = pp.PublicPort.publicAddressForHost(host.(types.IPv4Address))
 		pubEntry = Tuple{
 			addr: v4addr,
 			port: uint16(port),
 		}
 	}
 
+	var vlanAddr types.IPv4Address
+	if !ipv6 && v4addr != pp.PublicPort.Subnet.Addr {
+		vlanAddr = v4addr
+	}
 	pp.getPublicPortPortmap(ipv6, protocol)[port] = portMapEntry{
+		created:              time.Now(),
 		lastused:             time.Now(),
 		finCount:             0,
 		terminationDirection: 0,
 		static:               false,
+		addr:                 vlanAddr,
+		remote:               remoteEntry,
+		temporary:            temporary,
 	}
 
 	// Add lookup entries for packet translation
 	pp.PublicPort.translationTable[protocol].Store(pubEntry, privEntry)
 	pp.PrivatePort.translationTable[protocol].Store(privEntry, pubEntry)
-
-	pp.mutex.Unlock()
-	return v4addr, v6addr, uint16(port), nil
+	atomic.AddUint64(&pp.PublicPort.stats.sessions, 1)
+	return v4addr, v6addr
 }
 
 // PublicToPrivateTranslation does ingress translation.
 func PublicToPrivateTranslation(pkt *packet.Packet, ctx flow.UserContext) uint {
-	pi := ctx.(pairIndex)
-	pp := &Natconfig.PortPairs[pi.index]
+	pp := &Natconfig.PortPairs[ctx.(pairIndex).index]
+	return pp.publicToPrivateTranslation(pkt, nil)
+}
+
+// publicToPrivateTranslation translates packet received by public
+// port. Session of packet may be already looked up by vector
+// translation, otherwise session is nil.
+func (pp *portPair) publicToPrivateTranslation(pkt *packet.Packet, session *sessionLookup) uint {
 	port := &pp.PublicPort
 
 	port.dumpPacket(pkt, DirSEND)
@@ -78,12 +118,45 @@ func PublicToPrivateTranslation(pkt *packet.Packet, ctx flow.UserContext) uint {
 		return dir
 	}
 
-	protocol, pktTCP, pktUDP, pktICMP, _, DstPort := ParseAllKnownL4(pkt, pktIPv4, pktIPv6)
-	if protocol == 0 {
-		// Only TCP, UDP and ICMP are supported now, all other protocols are ignored
+	// Packets with IPv4 options and jumbograms are handled
+	// according to port pair policy
+	var allowed bool
+	if pktVLAN, pktIPv4, allowed = pp.applyIPOptionsPolicy(port, pkt, pktVLAN, pktIPv4, pktIPv6); !allowed {
+		return DirDROP
+	}
+
+	// Sources which flooded forwarded ports are blocked for a while
+	if pp.isBlockedSource(pktIPv4, pktIPv6) {
 		port.dumpPacket(pkt, DirDROP)
 		return DirDROP
 	}
+
+	// Multicast traffic is forwarded without translation
+	if pktIPv4 != nil && pp.Multicast.enabled() && isIPv4Multicast(packet.SwapBytesIPv4Addr(pktIPv4.DstAddr)) {
+		if dir, handled := pp.handlePublicMulticast(pkt, pktVLAN, pktIPv4); handled {
+			return dir
+		}
+	}
+
+	// Whole prefixes are translated 1:1 without sessions
+	if pktIPv4 != nil && len(pp.Netmap) != 0 {
+		if dir, handled := pp.handleNetmap(port, pkt, pktVLAN, pktIPv4); handled {
+			return dir
+		}
+	}
+
+	// Fragments without transport header are translated as first
+	// fragment of their datagram
+	if pktIPv4 != nil && isIPv4LaterFragment(pktIPv4) {
+		return pp.handleLaterFragment(port, pkt, pktVLAN, pktIPv4)
+	}
+
+	protocol, pktTCP, pktUDP, pktICMP, SrcPort, DstPort := pp.parseTransport(pkt, pktIPv4, pktIPv6)
+	if protocol == 0 {
+		// Only TCP, UDP and ICMP are translated, all other
+		// protocols are handled according to port pair policy
+		return pp.handleUnsupportedProtocol(port, pkt, pktVLAN, pktIPv4, pktIPv6)
+	}
 	portNumber := DstPort
 	// Create a lookup key from packet destination address and port
 	var pub2priKey interface{}
@@ -98,6 +171,10 @@ func PublicToPrivateTranslation(pkt *packet.Packet, ctx flow.UserContext) uint {
 			port: portNumber,
 		}
 	}
+	// ICMP errors are translated by session of packet which they quote
+	if pktICMP != nil && isICMPError(protocol, pktICMP.Type) {
+		return pp.handleICMPError(port, protocol, pkt, pktVLAN, pktIPv4, pktIPv6)
+	}
 	// Check for ICMP traffic first
 	if pktICMP != nil {
 		dir := port.handleICMP(protocol, pkt, pub2priKey)
@@ -106,14 +183,26 @@ func PublicToPrivateTranslation(pkt *packet.Packet, ctx flow.UserContext) uint {
 			return dir
 		}
 	}
+	// Only ICMP queries have identifier which is translated
+	if pktICMP != nil && !isICMPQuery(protocol, pktICMP.Type) {
+		if port.KNIName != "" && ((pktIPv4 != nil && port.Subnet.addressAcquired) || (pktIPv6 != nil && port.Subnet6.addressAcquired)) {
+			dir = DirKNI
+		} else {
+			dir = DirDROP
+		}
+		dir = port.steerKNI(protocol, 0, dir)
+		port.dumpPacket(pkt, dir)
+		return dir
+	}
 	ipv6 := pktIPv6 != nil
 	// Check for DHCP traffic. We need to get an address if it not set yet
-	if pktUDP != nil {
+	if protocol == types.UDPNumber {
 		var handled bool
 		if ipv6 {
 			handled = port.handleDHCPv6(pkt)
 		} else {
-			handled = port.handleDHCP(pkt)
+			handled = port.handleDHCP(pkt) || pp.relayDHCPReply(pkt, pktIPv4, pktUDP) ||
+				port.handleSTUNResponse(pp, pkt, pktIPv4, pktUDP)
 		}
 		if handled {
 			port.dumpPacket(pkt, DirDROP)
@@ -122,56 +211,142 @@ func PublicToPrivateTranslation(pkt *packet.Packet, ctx flow.UserContext) uint {
 	}
 
 	// Do lookup
-	v, found := port.translationTable[protocol].Load(pub2priKey)
+	v, found := session.load(port, protocol, pub2priKey)
 	kniPresent := port.KNIName != ""
 
 	if !found {
 		// Store new local network entry in ARP cache
 		var addressAcquired bool
 		if ipv6 {
-			port.arpTable.Store(pktIPv6.SrcAddr, pkt.Ether.SAddr)
+			port.storeNeighbor(pktIPv6.SrcAddr, pkt.Ether.SAddr)
 			addressAcquired = port.Subnet6.addressAcquired
 		} else {
-			port.arpTable.Store(pktIPv4.SrcAddr, pkt.Ether.SAddr)
+			port.storeNeighbor(packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr), pkt.Ether.SAddr)
 			addressAcquired = port.Subnet.addressAcquired
 		}
 
-		// For ingress connections packets are allowed only if a
-		// connection has been previosly established with a egress
-		// (private to public) packet. So if lookup fails, this
-		// incoming packet is ignored unless there is a KNI
-		// interface. If KNI is present and its IP address is known,
-		// traffic is directed there.
-		if kniPresent && addressAcquired {
-			dir = DirKNI
-		} else {
-			dir = DirDROP
+		// Relayed UDP ports are forwarded to private network
+		if protocol == types.UDPNumber && !ipv6 && pp.relayBroadcast(pkt, pktVLAN, pktIPv4, pktUDP) {
+			port.opposite.dumpPacket(pkt, DirSEND)
+			return DirSEND
+		}
+
+		// Traffic of public address which matches no session gets
+		// a session to DMZ host if it is set
+		v, found = pp.openDMZSession(ipv6, protocol, pub2priKey)
+		if !found {
+			// For ingress connections packets are allowed only if a
+			// connection has been previosly established with a egress
+			// (private to public) packet. So if lookup fails, this
+			// incoming packet is ignored unless there is a KNI
+			// interface. If KNI is present and its IP address is
+			// known, traffic is directed there.
+			if kniPresent && addressAcquired {
+				dir = DirKNI
+			} else {
+				dir = DirDROP
+			}
+			dir = port.steerKNI(protocol, DstPort, dir)
+			if dir == DirDROP {
+				pp.rejectUnsolicited(pkt, pktIPv4, pktIPv6, pktTCP, pktUDP)
+			}
+			port.dumpPacket(pkt, dir)
+			return dir
 		}
-		port.dumpPacket(pkt, dir)
-		return dir
 	}
 	v4addr, v6addr, newPort, zeroAddr := getAddrFromTuple(v, ipv6)
 
 	portmap := port.getPortmap(ipv6, protocol)
 	// Check whether connection is too old
 	if portmap[portNumber].static || time.Since(portmap[portNumber].lastused) <= connectionTimeout {
-		portmap[portNumber].lastused = time.Now()
+		portmap[portNumber].lastused = pp.MediaTimeout.lastused(protocol, newPort, SrcPort)
 	} else {
 		// There was no transfer on this port for too long
 		// time. We don't allow it any more
 		pp.mutex.Lock()
 		pp.deleteOldConnection(pktIPv6 != nil, protocol, int(portNumber))
 		pp.mutex.Unlock()
+		pp.rejectUnsolicited(pkt, pktIPv4, pktIPv6, pktTCP, pktUDP)
+		port.dumpPacket(pkt, DirDROP)
+		return DirDROP
+	}
+
+	// Sessions of private KNI host go to its KNI interface
+	if pp.isPrivateKNISession(ipv6, &portmap[portNumber], v4addr) {
+		if isIPv4Fragment(pktIPv4) {
+			port.dumpPacket(pkt, DirDROP)
+			return DirDROP
+		}
+		return pp.translateToPrivateKNI(pkt, pktVLAN, pktIPv4, &portmap[portNumber], newPort, pktTCP, pktUDP, pktICMP)
+	}
+
+	// Forwarded ports don't accept new TCP connections during
+	// shutdown and maintenance
+	if pktTCP != nil && portmap[portNumber].static && pp.refusesNewSessions() && isNewTCPConnection(pktTCP) {
+		return pp.refuseNewSession(port, pkt, pktIPv4, pktIPv6, pktTCP, pktUDP)
+	}
+
+	// Sources which exceed rates of forwarded ports are blocked
+	if portmap[portNumber].static && pp.FloodMitigation.enabled() && !pp.checkFlood(pktIPv4, pktIPv6, pktTCP) {
+		port.dumpPacket(pkt, DirDROP)
+		return DirDROP
+	}
+
+	// Forwarded ports accept only sources of allowed countries
+	if portmap[portNumber].static && !pp.checkCountry(pktIPv4, pktIPv6, false) {
+		port.dumpPacket(pkt, DirDROP)
+		return DirDROP
+	}
+
+	// External server may deny new connections to forwarded ports
+	if portmap[portNumber].static &&
+		!pp.authorizeForwarded(protocol, pktIPv4, pktIPv6, pktTCP, SrcPort, portNumber, v4addr, v6addr, newPort) {
 		port.dumpPacket(pkt, DirDROP)
 		return DirDROP
 	}
 
+	// Forwarded ports with idle timeout accept packets of remote
+	// hosts only within their sessions
+	if fs := portmap[portNumber].forwarded; fs != nil {
+		var remote interface{}
+		if ipv6 {
+			remote = Tuple6{addr: pktIPv6.SrcAddr, port: SrcPort}
+		} else {
+			remote = Tuple{addr: packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr), port: SrcPort}
+		}
+		if !fs.inbound(remote, pktTCP, time.Now().UnixNano()) {
+			pp.rejectUnsolicited(pkt, pktIPv4, pktIPv6, pktTCP, pktUDP)
+			port.dumpPacket(pkt, DirDROP)
+			return DirDROP
+		}
+	}
+
+	// Forwarded ports may send some sources to other destinations
+	sourceMatched := false
+	if portmap[portNumber].sources != nil {
+		if d := sourceDestination(portmap[portNumber].sources, pktIPv4, pktIPv6); d != nil {
+			v4addr, v6addr, newPort = d.Addr4, d.Addr6, d.Port
+			zeroAddr = (d.ipv6 && d.Addr6 == zeroIPv6Addr) || (!d.ipv6 && d.Addr4 == 0)
+			sourceMatched = true
+		}
+	}
+
 	if !zeroAddr {
+		if !pp.forwardHopLimit(port, pkt, pktIPv4, pktIPv6, true) {
+			return DirDROP
+		}
+
 		// Check whether TCP connection could be reused
-		if pktTCP != nil && !portmap[portNumber].static {
+		if pktTCP != nil && !portmap[portNumber].static && portmap[portNumber].trigger == nil {
 			pp.checkTCPTermination(ipv6, pktTCP, int(portNumber), pub2pri)
 		}
 
+		// Private hosts of forwarded ports which don't answer
+		// probes are not sent packets
+		if portmap[portNumber].static && port.opposite.isHostUnreachable(ipv6, v4addr, v6addr) {
+			return pp.refuseUnreachableHost(pkt, pktIPv4, pktIPv6, pktTCP)
+		}
+
 		// Find corresponding MAC address
 		var mac types.MACAddress
 		var found bool
@@ -185,6 +360,64 @@ func PublicToPrivateTranslation(pkt *packet.Packet, ctx flow.UserContext) uint {
 			return DirDROP
 		}
 
+		// Enforce rate plan of private host
+		if pp.Policing.active {
+			var host interface{}
+			if ipv6 {
+				host = v6addr
+			} else {
+				host = v4addr
+			}
+			if !pp.policeSubscriber(host, false, pkt.GetPacketLen()) {
+				port.dumpPacket(pkt, DirDROP)
+				return DirDROP
+			}
+		}
+
+		// Handshakes of remote hosts with servers of forwarded
+		// ports are followed for failed connection counters
+		if pktTCP != nil && portmap[portNumber].static && pp.HandshakeTracking.enabled() {
+			var remote interface{}
+			if ipv6 {
+				remote = Tuple6{addr: pktIPv6.SrcAddr, port: SrcPort}
+			} else {
+				remote = Tuple{addr: packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr), port: SrcPort}
+			}
+			pp.trackInboundHandshake(ipv6, portNumber, pktTCP, remote)
+		}
+
+		// Account traffic by session, protocol, application, country
+		// and top talkers
+		countSessionPacket(&portmap[portNumber], pkt)
+		if pktTCP != nil && pp.TCPTimeoutReset {
+			recordTCPAck(&portmap[portNumber], pktTCP, true)
+		}
+		pp.countTraffic(protocol, false, pkt.GetPacketLen(), pktIPv4, pktIPv6, v4addr, v6addr)
+		pp.countApplication(&portmap[portNumber], pkt, protocol, false, SrcPort, portNumber)
+		pp.countCountry(&portmap[portNumber], pktIPv4, pktIPv6, false, pkt.GetPacketLen())
+
+		// Remember translation for other fragments of datagram
+		fragment := !ipv6 && isIPv4Fragment(pktIPv4)
+		if fragment {
+			pp.storeFragmentFlow(port, pktIPv4, pktUDP, v4addr, v4addr, mac, port.opposite.Vlan)
+		}
+
+		// Replies of captive portal come from destination which
+		// private host asked for
+		if pktTCP != nil && !fragment && pp.CaptivePortal.enabled() {
+			pp.restorePortalSource(pktIPv4, pktIPv6, pktTCP, v4addr, v6addr, newPort)
+		}
+
+		// SYN segments of both directions advertise MSS which fits
+		// path MTU
+		if pktTCP != nil && !fragment && pp.PublicPort.pathMTU != nil {
+			pp.PublicPort.pathMTU.clampTCPMSS(pktIPv4, pktIPv6, pktTCP)
+		}
+
+		// Changed header fields are remembered for incremental
+		// checksum update
+		old := saveTranslatedHeader(pktIPv4, pktIPv6)
+
 		// Do packet translation
 		pkt.Ether.DAddr = mac
 		pkt.Ether.SAddr = port.opposite.SrcMACAddress
@@ -193,14 +426,23 @@ func PublicToPrivateTranslation(pkt *packet.Packet, ctx flow.UserContext) uint {
 		}
 		if ipv6 {
 			pktIPv6.DstAddr = v6addr
-		} else {
+		} else if !fragment {
 			pktIPv4.DstAddr = packet.SwapBytesIPv4Addr(v4addr)
 		}
-		setPacketDstPort(pkt, ipv6, newPort, pktTCP, pktUDP, pktICMP)
+		pp.DSCP.Ingress.apply(pktIPv4, pktIPv6)
+		if fragment {
+			translateFirstFragment(pkt, pktIPv4, false, v4addr, newPort, pktTCP, pktUDP, pktICMP, port.opposite)
+		} else {
+			setPacketDstPort(pkt, ipv6, newPort, pktTCP, pktUDP, pktICMP, port.opposite, &old)
+		}
 
 		port.opposite.dumpPacket(pkt, DirSEND)
 		return DirSEND
 	} else {
+		// Forwarded ports may go to explicit address of KNI
+		if portmap[portNumber].static && !sourceMatched {
+			port.rewriteToKNI(pkt, protocol, pub2priKey, pktIPv4, pktIPv6, pktTCP, pktUDP)
+		}
 		port.dumpPacket(pkt, DirKNI)
 		return DirKNI
 	}
@@ -208,8 +450,14 @@ func PublicToPrivateTranslation(pkt *packet.Packet, ctx flow.UserContext) uint {
 
 // PrivateToPublicTranslation does egress translation.
 func PrivateToPublicTranslation(pkt *packet.Packet, ctx flow.UserContext) uint {
-	pi := ctx.(pairIndex)
-	pp := &Natconfig.PortPairs[pi.index]
+	pp := &Natconfig.PortPairs[ctx.(pairIndex).index]
+	return pp.privateToPublicTranslation(pkt, nil)
+}
+
+// privateToPublicTranslation translates packet received by private
+// port. Session of packet may be already looked up by vector
+// translation, otherwise session is nil.
+func (pp *portPair) privateToPublicTranslation(pkt *packet.Packet, session *sessionLookup) uint {
 	port := &pp.PrivatePort
 
 	port.dumpPacket(pkt, DirSEND)
@@ -220,12 +468,56 @@ func PrivateToPublicTranslation(pkt *packet.Packet, ctx flow.UserContext) uint {
 		return dir
 	}
 
-	protocol, pktTCP, pktUDP, pktICMP, SrcPort, _ := ParseAllKnownL4(pkt, pktIPv4, pktIPv6)
-	if protocol == 0 {
-		// Only TCP, UDP and ICMP are supported now, all other protocols are ignored
+	// Packets with IPv4 options and jumbograms are handled
+	// according to port pair policy
+	var allowed bool
+	if pktVLAN, pktIPv4, allowed = pp.applyIPOptionsPolicy(port, pkt, pktVLAN, pktIPv4, pktIPv6); !allowed {
+		return DirDROP
+	}
+
+	// Track multicast groups membership of private hosts
+	if pktIPv4 != nil && pp.Multicast.enabled() && pktIPv4.NextProtoID == igmpNumber {
+		pp.handlePrivateIGMP(pkt, pktVLAN, pktIPv4)
 		port.dumpPacket(pkt, DirDROP)
 		return DirDROP
 	}
+
+	// Traffic to blackholed destinations is contained
+	if dir, handled := pp.handleBlackhole(port, pkt, pktIPv4, pktIPv6); handled {
+		return dir
+	}
+
+	// Unauthenticated hosts may only open HTTP flows which go to
+	// captive portal
+	if dir, handled := pp.handleCaptivePortal(port, pkt, pktIPv4, pktIPv6); handled {
+		return dir
+	}
+
+	// Private subnets of other port pairs are routed without
+	// translation
+	if dir, handled := pp.routePrivate(port, pkt, pktVLAN, pktIPv4, pktIPv6); handled {
+		return dir
+	}
+
+	// Whole prefixes are translated 1:1 without sessions
+	if pktIPv4 != nil && len(pp.Netmap) != 0 {
+		if dir, handled := pp.handleNetmap(port, pkt, pktVLAN, pktIPv4); handled {
+			return dir
+		}
+	}
+
+	// Fragments without transport header are translated as first
+	// fragment of their datagram
+	if pktIPv4 != nil && isIPv4LaterFragment(pktIPv4) {
+		return pp.handleLaterFragment(port, pkt, pktVLAN, pktIPv4)
+	}
+
+	protocol, pktTCP, pktUDP, pktICMP, SrcPort, DstPort := pp.parseTransport(pkt, pktIPv4, pktIPv6)
+	if protocol == 0 {
+		// Only TCP, UDP and ICMP are translated, all other
+		// protocols are handled according to port pair policy
+		return pp.handleUnsupportedProtocol(port, pkt, pktVLAN, pktIPv4, pktIPv6)
+	}
 	portNumber := SrcPort
 	// Create a lookup key from packet source address and port
 	var pri2pubKey interface{}
@@ -250,12 +542,12 @@ func PrivateToPublicTranslation(pkt *packet.Packet, ctx flow.UserContext) uint {
 	}
 	ipv6 := pktIPv6 != nil
 	// Check for DHCP traffic. We need to get an address if it not set yet
-	if pktUDP != nil {
+	if protocol == types.UDPNumber {
 		var handled bool
 		if ipv6 {
 			handled = port.handleDHCPv6(pkt)
 		} else {
-			handled = port.handleDHCP(pkt)
+			handled = port.handleDHCP(pkt) || pp.relayDHCPRequest(pkt, pktIPv4, pktUDP)
 		}
 		if handled {
 			port.dumpPacket(pkt, DirDROP)
@@ -280,12 +572,23 @@ func PrivateToPublicTranslation(pkt *packet.Packet, ctx flow.UserContext) uint {
 	// If traffic is directed at private interface IP and KNI is
 	// present, this traffic is directed to KNI
 	if kniPresent && addressAcquired && packetSentToUs {
-		port.dumpPacket(pkt, DirKNI)
-		return DirKNI
+		dir = port.steerKNI(protocol, DstPort, DirKNI)
+		port.dumpPacket(pkt, dir)
+		return dir
+	}
+
+	// ICMP errors are translated by session of packet which they
+	// quote, other messages without identifier are not translated
+	if pktICMP != nil && isICMPError(protocol, pktICMP.Type) {
+		return pp.handleICMPError(port, protocol, pkt, pktVLAN, pktIPv4, pktIPv6)
+	}
+	if pktICMP != nil && !isICMPQuery(protocol, pktICMP.Type) {
+		port.dumpPacket(pkt, DirDROP)
+		return DirDROP
 	}
 
 	// Do lookup
-	v, found := port.translationTable[protocol].Load(pri2pubKey)
+	v, found := session.load(port, protocol, pri2pubKey)
 
 	var v4addr types.IPv4Address
 	var v6addr types.IPv6Address
@@ -296,45 +599,104 @@ func PrivateToPublicTranslation(pkt *packet.Packet, ctx flow.UserContext) uint {
 		// Store new local network entry in ARP cache
 		var publicAddressAcquired bool
 		if ipv6 {
-			port.arpTable.Store(pktIPv6.SrcAddr, pkt.Ether.SAddr)
+			port.storeNeighbor(pktIPv6.SrcAddr, pkt.Ether.SAddr)
 			publicAddressAcquired = port.opposite.Subnet6.addressAcquired
 		} else {
-			port.arpTable.Store(pktIPv4.SrcAddr, pkt.Ether.SAddr)
+			port.storeNeighbor(packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr), pkt.Ether.SAddr)
 			publicAddressAcquired = port.opposite.Subnet.addressAcquired
 		}
 
 		if !addressAcquired || !publicAddressAcquired {
+			// Public address released by idle port pair is acquired
+			// again for new session
+			if !ipv6 && !publicAddressAcquired {
+				pp.reacquireIdleAddress()
+			}
 			// No packets are allowed yet because ports address is not
 			// known yet
 			port.dumpPacket(pkt, DirDROP)
 			return DirDROP
 		}
+		// New sessions are not allowed during shutdown and
+		// maintenance
+		if pp.refusesNewSessions() {
+			return pp.refuseNewSession(port, pkt, pktIPv4, pktIPv6, pktTCP, pktUDP)
+		}
+		// Sessions are started only with hosts of allowed countries
+		if !pp.checkCountry(pktIPv4, pktIPv6, true) {
+			port.dumpPacket(pkt, DirDROP)
+			return DirDROP
+		}
+		// External server may deny new sessions
+		if !pp.authorizeEgress(protocol, pktIPv4, pktIPv6, SrcPort, DstPort) {
+			port.dumpPacket(pkt, DirDROP)
+			return DirDROP
+		}
+		// Remote host is remembered for session export
+		var remote interface{}
+		if ipv6 {
+			remote = Tuple6{addr: pktIPv6.DstAddr, port: DstPort}
+		} else {
+			remote = Tuple{addr: packet.SwapBytesIPv4Addr(pktIPv4.DstAddr), port: DstPort}
+		}
 		var err error
 		// Allocate new connection from private to public network
-		v4addr, v6addr, newPort, err = pp.allocateNewEgressConnection(pktIPv6 != nil, protocol, pri2pubKey)
+		v4addr, v6addr, newPort, err = pp.allocateNewEgressConnection(pktIPv6 != nil, protocol, pri2pubKey, remote, pkt.Ether.SAddr)
 
 		if err != nil {
-			println("Warning! Failed to allocate new connection", err)
+			println("Warning! Failed to allocate new connection on port", port.logName(), ":", err.Error())
 			port.dumpPacket(pkt, DirDROP)
 			return DirDROP
 		}
 		zeroAddr = false
+		// New session may open inbound ports to host
+		if pktICMP == nil && len(pp.portTriggers()) != 0 {
+			pp.triggerPorts(ipv6, protocol, DstPort, pri2pubKey, newPort)
+		}
 	} else {
 		v4addr, v6addr, newPort, zeroAddr = getAddrFromTuple(v, ipv6)
-		pp.PublicPort.getPortmap(ipv6, protocol)[newPort].lastused = time.Now()
+		pp.PublicPort.getPortmap(ipv6, protocol)[newPort].lastused = pp.MediaTimeout.lastused(protocol, portNumber, DstPort)
 	}
 
 	if !zeroAddr {
+		if !pp.forwardHopLimit(port, pkt, pktIPv4, pktIPv6, true) {
+			return DirDROP
+		}
+		if !pp.fitsPathMTU(port, pkt, pktIPv4, pktIPv6) {
+			return DirDROP
+		}
+
 		// Check whether TCP connection could be reused
-		if pktTCP != nil && !pp.PublicPort.getPortmap(ipv6, protocol)[newPort].static {
+		pme := &pp.PublicPort.getPortmap(ipv6, protocol)[newPort]
+		if pktTCP != nil && !pme.static && pme.trigger == nil {
 			pp.checkTCPTermination(ipv6, pktTCP, int(newPort), pri2pub)
 		}
 
-		// Find corresponding MAC address
+		// Destinations of forwarded ports with idle timeout send
+		// packets only to remote hosts with active sessions
+		if pme.forwarded != nil {
+			var remote interface{}
+			if ipv6 {
+				remote = Tuple6{addr: pktIPv6.DstAddr, port: DstPort}
+			} else {
+				remote = Tuple{addr: packet.SwapBytesIPv4Addr(pktIPv4.DstAddr), port: DstPort}
+			}
+			if !pme.forwarded.active(remote, time.Now().UnixNano()) {
+				port.dumpPacket(pkt, DirDROP)
+				return DirDROP
+			}
+		}
+
+		// Find corresponding MAC address. Sessions of VLAN
+		// subinterfaces are sent to their VLANs.
 		var mac types.MACAddress
 		var found bool
+		vlanTag := port.opposite.Vlan
 		if pktIPv6 != nil {
 			mac, found = port.opposite.getMACForIPv6(pktIPv6.DstAddr)
+		} else if vlan := port.opposite.vlanByAddr(v4addr); vlan != nil {
+			vlanTag = vlan.Vlan
+			mac, found = port.opposite.getMACForVLAN(vlan, packet.SwapBytesIPv4Addr(pktIPv4.DstAddr))
 		} else {
 			mac, found = port.opposite.getMACForIPv4(packet.SwapBytesIPv4Addr(pktIPv4.DstAddr))
 		}
@@ -343,18 +705,73 @@ func PrivateToPublicTranslation(pkt *packet.Packet, ctx flow.UserContext) uint {
 			return DirDROP
 		}
 
+		// Enforce rate plan and egress rate class of private host
+		if pp.policingActive(true) {
+			var host interface{}
+			if ipv6 {
+				host = pktIPv6.SrcAddr
+			} else {
+				host = packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr)
+			}
+			if !pp.policeHost(host, true, pkt.GetPacketLen()) {
+				port.dumpPacket(pkt, DirDROP)
+				return DirDROP
+			}
+		}
+
+		// Account traffic by session, protocol, application, country
+		// and top talkers
+		pp.countTraffic(protocol, true, pkt.GetPacketLen(), pktIPv4, pktIPv6, 0, types.IPv6Address{})
+		if pktTCP != nil && pme.static && pp.HandshakeTracking.enabled() {
+			var remote interface{}
+			if ipv6 {
+				remote = Tuple6{addr: pktIPv6.DstAddr, port: DstPort}
+			} else {
+				remote = Tuple{addr: packet.SwapBytesIPv4Addr(pktIPv4.DstAddr), port: DstPort}
+			}
+			pp.trackOutboundHandshake(ipv6, newPort, pktTCP, remote)
+		}
+		countSessionPacket(pme, pkt)
+		if pktTCP != nil && pp.TCPTimeoutReset {
+			recordTCPAck(pme, pktTCP, false)
+		}
+		pp.countApplication(pme, pkt, protocol, true, DstPort, newPort)
+		pp.countCountry(pme, pktIPv4, pktIPv6, true, pkt.GetPacketLen())
+
+		// Remember translation for other fragments of datagram
+		fragment := !ipv6 && isIPv4Fragment(pktIPv4)
+		if fragment {
+			pp.storeFragmentFlow(port, pktIPv4, pktUDP, v4addr, packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr), mac, vlanTag)
+		}
+
+		// SYN segments of both directions advertise MSS which fits
+		// path MTU
+		if pktTCP != nil && !fragment && pp.PublicPort.pathMTU != nil {
+			pp.PublicPort.pathMTU.clampTCPMSS(pktIPv4, pktIPv6, pktTCP)
+		}
+
+		// Changed header fields are remembered for incremental
+		// checksum update
+		old := saveTranslatedHeader(pktIPv4, pktIPv6)
+
 		// Do packet translation
 		pkt.Ether.DAddr = mac
 		pkt.Ether.SAddr = port.opposite.SrcMACAddress
 		if pktVLAN != nil {
-			pktVLAN.SetVLANTagIdentifier(port.opposite.Vlan)
+			pktVLAN.SetVLANTagIdentifier(vlanTag)
 		}
 		if ipv6 {
 			pktIPv6.SrcAddr = v6addr
-		} else {
+		} else if !fragment {
 			pktIPv4.SrcAddr = packet.SwapBytesIPv4Addr(v4addr)
 		}
-		setPacketSrcPort(pkt, ipv6, newPort, pktTCP, pktUDP, pktICMP)
+		pp.DSCP.Egress.apply(pktIPv4, pktIPv6)
+		pp.applyFlowLabel(pktIPv6, protocol, newPort, DstPort)
+		if fragment {
+			translateFirstFragment(pkt, pktIPv4, true, v4addr, newPort, pktTCP, pktUDP, pktICMP, port.opposite)
+		} else {
+			setPacketSrcPort(pkt, ipv6, newPort, pktTCP, pktUDP, pktICMP, port.opposite, &old)
+		}
 
 		port.opposite.dumpPacket(pkt, DirSEND)
 		return DirSEND
@@ -409,7 +826,7 @@ func (port *ipPort) parsePacketAndCheckARP(pkt *packet.Packet) (dir uint, vlanhd
 		pktIPv6 := pkt.GetIPv6CheckVLAN()
 		if pktIPv6 == nil {
 			arp := pkt.GetARPCheckVLAN()
-			if arp != nil {
+			if arp != nil && !port.ipv4Disabled {
 				dir := port.handleARP(pkt)
 				port.dumpPacket(pkt, dir)
 				return dir, pktVLAN, nil, nil
@@ -417,8 +834,16 @@ func (port *ipPort) parsePacketAndCheckARP(pkt *packet.Packet) (dir uint, vlanhd
 			port.dumpPacket(pkt, DirDROP)
 			return DirDROP, pktVLAN, nil, nil
 		}
+		if port.ipv6Disabled {
+			port.dumpPacket(pkt, DirDROP)
+			return DirDROP, pktVLAN, nil, nil
+		}
 		return DirSEND, pktVLAN, nil, pktIPv6
 	}
+	if port.ipv4Disabled {
+		port.dumpPacket(pkt, DirDROP)
+		return DirDROP, pktVLAN, nil, nil
+	}
 	return DirSEND, pktVLAN, pktIPv4, nil
 }
 
@@ -0,0 +1,164 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/intel-go/nff-go/packet"
+)
+
+// IP protocol numbers of transports which port pair may translate in
+// addition to TCP, UDP and ICMP. Both have source and destination
+// ports and checksum at the same offsets as UDP, so their headers are
+// translated as UDP headers.
+const (
+	dccpNumber    = 33
+	udpLiteNumber = 136
+)
+
+const (
+	udpLiteHeaderLen = 8
+	// Generic DCCP header with short sequence number
+	dccpHeaderLen = 12
+)
+
+type translatedProtocol uint8
+
+var translatedProtocolLookup = map[string]translatedProtocol{
+	"UDPLITE": udpLiteNumber,
+	"DCCP":    dccpNumber,
+}
+
+// UnmarshalJSON parses name of translated protocol.
+func (out *translatedProtocol) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	result, ok := translatedProtocolLookup[s]
+	if !ok {
+		return errors.New("Bad translated protocol: " + s)
+	}
+
+	*out = result
+	return nil
+}
+
+// String returns protocol name as it is used in config file.
+func (protocol translatedProtocol) String() string {
+	for name, p := range translatedProtocolLookup {
+		if p == protocol {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// MarshalJSON writes protocol name as it is used in config file.
+func (protocol translatedProtocol) MarshalJSON() ([]byte, error) {
+	return json.Marshal(protocol.String())
+}
+
+func (pp *portPair) checkTranslatedProtocols() error {
+	for i, p := range pp.TranslateProtocols {
+		for _, other := range pp.TranslateProtocols[:i] {
+			if p == other {
+				return fmt.Errorf("Protocol %s is listed twice in translate-protocols of port %s", p, pp.PublicPort.logName())
+			}
+		}
+	}
+	return nil
+}
+
+// translatesProtocol returns true if port pair has sessions of
+// protocol other than TCP, UDP and ICMP.
+func (pp *portPair) translatesProtocol(protocol uint8) bool {
+	for _, p := range pp.TranslateProtocols {
+		if uint8(p) == protocol {
+			return true
+		}
+	}
+	return false
+}
+
+// hasUDPLayout returns true for protocols which headers are handled
+// as UDP headers while their checksums have other rules.
+func hasUDPLayout(protocol uint8) bool {
+	return protocol == udpLiteNumber || protocol == dccpNumber
+}
+
+// parseTransport parses transport header like ParseAllKnownL4 and
+// also returns UDP-Lite and DCCP headers as UDP header if port pair
+// translates them. Datagrams with invalid checksum coverage are not
+// translated.
+func (pp *portPair) parseTransport(pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) (uint8, *packet.TCPHdr, *packet.UDPHdr, *packet.ICMPHdr, uint16, uint16) {
+	protocol, pktTCP, pktUDP, pktICMP, srcPort, dstPort := ParseAllKnownL4(pkt, pktIPv4, pktIPv6)
+	if protocol != 0 || len(pp.TranslateProtocols) == 0 {
+		return protocol, pktTCP, pktUDP, pktICMP, srcPort, dstPort
+	}
+
+	var length int
+	var fragment bool
+	if pktIPv4 != nil {
+		protocol = pktIPv4.NextProtoID
+		length = int(packet.SwapBytesUint16(pktIPv4.TotalLength)) - ipv4HeaderLen(pktIPv4)
+		fragment = isIPv4Fragment(pktIPv4)
+	} else {
+		protocol = pktIPv6.Proto
+		length = int(packet.SwapBytesUint16(pktIPv6.PayloadLen))
+	}
+	if !pp.translatesProtocol(protocol) || !checkCoverage(protocol, pkt.L4, length, fragment) {
+		return 0, nil, nil, nil, 0, 0
+	}
+	pktUDP = (*packet.UDPHdr)(pkt.L4)
+	return protocol, nil, pktUDP, nil, packet.SwapBytesUint16(pktUDP.SrcPort), packet.SwapBytesUint16(pktUDP.DstPort)
+}
+
+// checkCoverage checks header length and checksum coverage of
+// UDP-Lite (RFC 3828) or DCCP (RFC 4340) segment of length bytes.
+// Coverage of first fragment of datagram cannot be compared with its
+// length. Both checksums always cover pseudo header and transport
+// header, so translation updates them the same way whatever their
+// coverage is.
+func checkCoverage(protocol uint8, l4 unsafe.Pointer, length int, fragment bool) bool {
+	if protocol == udpLiteNumber {
+		if length < udpLiteHeaderLen {
+			return false
+		}
+		hdr := (*[udpLiteHeaderLen]byte)(l4)[:]
+		// Zero coverage means whole datagram, otherwise it includes
+		// header
+		coverage := int(binary.BigEndian.Uint16(hdr[4:]))
+		return coverage == 0 || (coverage >= udpLiteHeaderLen && (fragment || coverage <= length))
+	}
+
+	if length < dccpHeaderLen {
+		return false
+	}
+	hdr := (*[dccpHeaderLen]byte)(l4)[:]
+	// Data offset is in 32 bit words, extended sequence number
+	// makes header longer
+	offset := int(hdr[4]) * 4
+	minLen := dccpHeaderLen
+	if hdr[8]&1 != 0 {
+		minLen += 4
+	}
+	if offset < minLen {
+		return false
+	}
+	if fragment {
+		return true
+	}
+	// Zero CsCov means whole segment, others cover header and
+	// CsCov-1 words of data
+	cscov := int(hdr[5] & 0x0f)
+	return offset <= length && (cscov == 0 || offset+(cscov-1)*4 <= length)
+}
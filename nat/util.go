@@ -67,7 +67,7 @@ func (port *ipPort) startTrace(dir uint) *os.File {
 }
 
 func (port *ipPort) dumpPacket(pkt *packet.Packet, dir uint) {
-	if DumpEnabled[dir] {
+	if port.dumpEnabled(dir) {
 		port.dumpsync[dir].Lock()
 		if port.fdump[dir] == nil {
 			port.fdump[dir] = port.startTrace(dir)
@@ -79,6 +79,7 @@ func (port *ipPort) dumpPacket(pkt *packet.Packet, dir uint) {
 		}
 		port.dumpsync[dir].Unlock()
 	}
+	port.dumpPacketRemote(pkt, dir)
 }
 
 func (port *ipPort) closePortTraces() {
@@ -89,12 +90,14 @@ func (port *ipPort) closePortTraces() {
 	}
 }
 
-// CloseAllDumpFiles closes all debug dump files.
+// CloseAllDumpFiles closes all debug dump files and remote dump
+// sinks.
 func CloseAllDumpFiles() {
 	for i := range Natconfig.PortPairs {
 		Natconfig.PortPairs[i].PrivatePort.closePortTraces()
 		Natconfig.PortPairs[i].PublicPort.closePortTraces()
 	}
+	closeAllDumpSinks()
 }
 
 func convertSubnet(s *upd.Subnet) (*ipv4Subnet, *ipv6Subnet, error) {
@@ -116,6 +119,21 @@ func convertSubnet(s *upd.Subnet) (*ipv4Subnet, *ipv6Subnet, error) {
 	}, nil, nil
 }
 
+// convertNeighborAddress converts GRPC address to a key of ARP/ND
+// table.
+func convertNeighborAddress(a *upd.IPAddress) (interface{}, error) {
+	bytes := a.GetAddress()
+	if len(bytes) == types.IPv6AddrLen {
+		var addr6 types.IPv6Address
+		copy(addr6[:], bytes)
+		return addr6, nil
+	}
+	if len(bytes) != types.IPv4AddrLen {
+		return nil, fmt.Errorf("Bad IP address length %d", len(bytes))
+	}
+	return convertIPv4(bytes)
+}
+
 func convertForwardedPort(p *upd.ForwardedPort) (*forwardedPort, error) {
 	bytes := p.GetTargetAddress().GetAddress()
 	addr, err := convertIPv4(bytes)
@@ -136,69 +154,119 @@ func convertForwardedPort(p *upd.ForwardedPort) (*forwardedPort, error) {
 		return nil, fmt.Errorf("Bad protocol identifier %d", p.GetProtocol())
 	}
 
-	return &forwardedPort{
+	fp := &forwardedPort{
 		Port: uint16(p.GetSourcePortNumber()),
 		Destination: hostPort{
 			Addr4: addr,
 			Addr6: addr6,
 			Port:  uint16(p.GetTargetPortNumber()),
 			ipv6:  ipv6,
+			zone:  p.GetTargetZone(),
 		},
 		Protocol: protocolId{
 			id:   uint8(p.GetProtocol() &^ upd.Protocol_IPv6_Flag),
 			ipv6: p.GetProtocol()&upd.Protocol_IPv6_Flag != 0,
 		},
-	}, nil
+		KNI: p.GetKni(),
+	}
+	for _, s := range p.GetSources() {
+		d := hostPort{
+			Port: uint16(s.GetTargetPortNumber()),
+			zone: s.GetTargetZone(),
+		}
+		bytes := s.GetTargetAddress().GetAddress()
+		if d.Addr4, err = convertIPv4(bytes); err != nil {
+			if len(bytes) != types.IPv6AddrLen {
+				return nil, err
+			}
+			copy(d.Addr6[:], bytes)
+			d.ipv6 = true
+		}
+		fp.Sources = append(fp.Sources, forwardedSource{
+			Prefix:      s.GetPrefix(),
+			Destination: d,
+		})
+	}
+	return fp, nil
+}
+
+// hostPortAddress returns address of host:port in a form of GRPC
+// request.
+func hostPortAddress(hp *hostPort) *upd.IPAddress {
+	if hp.ipv6 {
+		return &upd.IPAddress{
+			Address: append([]byte{}, hp.Addr6[:]...),
+		}
+	}
+	a := types.IPv4ToBytes(hp.Addr4)
+	return &upd.IPAddress{
+		Address: []byte{a[3], a[2], a[1], a[0]},
+	}
+}
+
+// forwardedPortRequest converts forwarded port back to a form of GRPC
+// request.
+func forwardedPortRequest(fp *forwardedPort) *upd.ForwardedPort {
+	protocol := upd.Protocol(fp.Protocol.id)
+	if fp.Protocol.ipv6 {
+		protocol |= upd.Protocol_IPv6_Flag
+	}
+	p := &upd.ForwardedPort{
+		SourcePortNumber: uint32(fp.Port),
+		TargetAddress:    hostPortAddress(&fp.Destination),
+		TargetPortNumber: uint32(fp.Destination.Port),
+		Protocol:         protocol,
+		TargetZone:       fp.Destination.zone,
+		Kni:              fp.KNI,
+	}
+	for i := range fp.Sources {
+		src := &fp.Sources[i]
+		p.Sources = append(p.Sources, &upd.ForwardedSource{
+			Prefix:           src.Prefix,
+			TargetAddress:    hostPortAddress(&src.Destination),
+			TargetPortNumber: uint32(src.Destination.Port),
+			TargetZone:       src.Destination.zone,
+		})
+	}
+	return p
 }
 
-func setPacketDstPort(pkt *packet.Packet, ipv6 bool, port uint16, pktTCP *packet.TCPHdr, pktUDP *packet.UDPHdr, pktICMP *packet.ICMPHdr) {
+// setPacketDstPort sets destination port or ICMP identifier of
+// translated packet and its checksums which are set for out port. Old
+// header holds fields which were changed by translation.
+func setPacketDstPort(pkt *packet.Packet, ipv6 bool, port uint16, pktTCP *packet.TCPHdr, pktUDP *packet.UDPHdr, pktICMP *packet.ICMPHdr,
+	out *ipPort, old *translatedHeader) {
+	var oldPort uint16
 	if pktTCP != nil {
+		oldPort = packet.SwapBytesUint16(pktTCP.DstPort)
 		pktTCP.DstPort = packet.SwapBytesUint16(port)
-		if ipv6 {
-			setIPv6TCPChecksum(pkt, !NoCalculateChecksum, !NoHWTXChecksum)
-		} else {
-			setIPv4TCPChecksum(pkt, !NoCalculateChecksum, !NoHWTXChecksum)
-		}
 	} else if pktUDP != nil {
+		oldPort = packet.SwapBytesUint16(pktUDP.DstPort)
 		pktUDP.DstPort = packet.SwapBytesUint16(port)
-		if ipv6 {
-			setIPv6UDPChecksum(pkt, !NoCalculateChecksum, !NoHWTXChecksum)
-		} else {
-			setIPv4UDPChecksum(pkt, !NoCalculateChecksum, !NoHWTXChecksum)
-		}
 	} else {
+		oldPort = packet.SwapBytesUint16(pktICMP.Identifier)
 		pktICMP.Identifier = packet.SwapBytesUint16(port)
-		if ipv6 {
-			setIPv6ICMPChecksum(pkt, !NoCalculateChecksum, !NoHWTXChecksum)
-		} else {
-			setIPv4ICMPChecksum(pkt, !NoCalculateChecksum, !NoHWTXChecksum)
-		}
 	}
+	setTranslatedChecksums(pkt, ipv6, oldPort, port, pktTCP, pktUDP, pktICMP, out, old)
 }
 
-func setPacketSrcPort(pkt *packet.Packet, ipv6 bool, port uint16, pktTCP *packet.TCPHdr, pktUDP *packet.UDPHdr, pktICMP *packet.ICMPHdr) {
+// setPacketSrcPort sets source port or ICMP identifier of translated
+// packet and its checksums which are set for out port. Old header
+// holds fields which were changed by translation.
+func setPacketSrcPort(pkt *packet.Packet, ipv6 bool, port uint16, pktTCP *packet.TCPHdr, pktUDP *packet.UDPHdr, pktICMP *packet.ICMPHdr,
+	out *ipPort, old *translatedHeader) {
+	var oldPort uint16
 	if pktTCP != nil {
+		oldPort = packet.SwapBytesUint16(pktTCP.SrcPort)
 		pktTCP.SrcPort = packet.SwapBytesUint16(port)
-		if ipv6 {
-			setIPv6TCPChecksum(pkt, !NoCalculateChecksum, !NoHWTXChecksum)
-		} else {
-			setIPv4TCPChecksum(pkt, !NoCalculateChecksum, !NoHWTXChecksum)
-		}
 	} else if pktUDP != nil {
+		oldPort = packet.SwapBytesUint16(pktUDP.SrcPort)
 		pktUDP.SrcPort = packet.SwapBytesUint16(port)
-		if ipv6 {
-			setIPv6UDPChecksum(pkt, !NoCalculateChecksum, !NoHWTXChecksum)
-		} else {
-			setIPv4UDPChecksum(pkt, !NoCalculateChecksum, !NoHWTXChecksum)
-		}
 	} else {
+		oldPort = packet.SwapBytesUint16(pktICMP.Identifier)
 		pktICMP.Identifier = packet.SwapBytesUint16(port)
-		if ipv6 {
-			setIPv6ICMPChecksum(pkt, !NoCalculateChecksum, !NoHWTXChecksum)
-		} else {
-			setIPv4ICMPChecksum(pkt, !NoCalculateChecksum, !NoHWTXChecksum)
-		}
 	}
+	setTranslatedChecksums(pkt, ipv6, oldPort, port, pktTCP, pktUDP, pktICMP, out, old)
 }
 
 func ParseAllKnownL4(pkt *packet.Packet, pktIPv4 *packet.IPv4Hdr, pktIPv6 *packet.IPv6Hdr) (uint8, *packet.TCPHdr, *packet.UDPHdr, *packet.ICMPHdr, uint16, uint16) {
@@ -355,3 +423,28 @@ func (port *ipPort) setLinkIPv6KNIAddress(ipv6addr, mask, oldaddr, oldmask types
 	fmt.Println("Successfully set address", addr, "on KNI interface", port.KNIName)
 	return nil
 }
+
+// writeFileAtomically replaces file with data so that reader never
+// sees partially written contents. Data is written to temporary file
+// in the same directory, synced and renamed over file.
+func writeFileAtomically(name string, data []byte) error {
+	tmpName := name + ".tmp"
+	f, err := os.OpenFile(tmpName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmpName, name)
+	}
+	if err != nil {
+		os.Remove(tmpName)
+	}
+	return err
+}
@@ -0,0 +1,154 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"sync/atomic"
+
+	"github.com/intel-go/nff-go/flow"
+	"github.com/intel-go/nff-go/packet"
+)
+
+// Burst size of NFF-Go vector functions, it is a compile time
+// constant of NFF-Go
+const vectorBurstSize = 32
+
+// Counters of a burst which are added to port counters at once.
+type burstStats struct {
+	rxPackets   uint64
+	rxBytes     uint64
+	txPackets   uint64
+	txBytes     uint64
+	kniPackets  uint64
+	dropPackets uint64
+	// Packets sent to KNI interface of opposite port
+	oppositeKNIPackets uint64
+}
+
+func (s *burstStats) count(length uint64, dir uint) {
+	s.rxPackets++
+	s.rxBytes += length
+	switch dir {
+	case DirSEND:
+		s.txPackets++
+		s.txBytes += length
+	case DirKNI:
+		s.kniPackets++
+	case dirPrivateKNI:
+		s.oppositeKNIPackets++
+	case DirDROP:
+		s.dropPackets++
+	}
+}
+
+// add adds burst counters to counters of port which received burst.
+func (s *burstStats) add(port *ipPort) {
+	if s.rxPackets == 0 {
+		return
+	}
+	atomic.AddUint64(&port.stats.rxPackets, s.rxPackets)
+	atomic.AddUint64(&port.stats.rxBytes, s.rxBytes)
+	if s.txPackets != 0 {
+		atomic.AddUint64(&port.opposite.stats.txPackets, s.txPackets)
+		atomic.AddUint64(&port.opposite.stats.txBytes, s.txBytes)
+	}
+	if s.kniPackets != 0 {
+		atomic.AddUint64(&port.stats.kniPackets, s.kniPackets)
+	}
+	if s.dropPackets != 0 {
+		atomic.AddUint64(&port.stats.dropPackets, s.dropPackets)
+	}
+	if s.oppositeKNIPackets != 0 {
+		atomic.AddUint64(&port.opposite.stats.kniPackets, s.oppositeKNIPackets)
+	}
+}
+
+// Session of packet which is looked up before burst is translated.
+type sessionLookup struct {
+	protocol uint8
+	key      interface{}
+	value    interface{}
+	found    bool
+}
+
+// lookupSession finds session of packet received by port in the same
+// way as translation does it.
+func (pp *portPair) lookupSession(port *ipPort, pkt *packet.Packet) (session sessionLookup) {
+	pktIPv4, pktIPv6, _ := pkt.ParseAllKnownL3CheckVLAN()
+	if pktIPv4 == nil && pktIPv6 == nil {
+		return
+	}
+	if pktIPv4 != nil && isIPv4LaterFragment(pktIPv4) {
+		return
+	}
+	protocol, _, _, _, srcPort, dstPort := pp.parseTransport(pkt, pktIPv4, pktIPv6)
+	if protocol == 0 {
+		return
+	}
+	public := port.Type == iPUBLIC
+	switch {
+	case pktIPv4 != nil && public:
+		session.key = Tuple{addr: packet.SwapBytesIPv4Addr(pktIPv4.DstAddr), port: dstPort}
+	case pktIPv4 != nil:
+		session.key = Tuple{addr: packet.SwapBytesIPv4Addr(pktIPv4.SrcAddr), port: srcPort}
+	case public:
+		session.key = Tuple6{addr: pktIPv6.DstAddr, port: dstPort}
+	default:
+		session.key = Tuple6{addr: pktIPv6.SrcAddr, port: srcPort}
+	}
+	session.protocol = protocol
+	session.value, session.found = port.translationTable[protocol].Load(session.key)
+	return
+}
+
+// load returns session of translated packet. Session found before
+// burst was translated is used if translation uses the same key, even
+// if earlier packet of burst deleted it, just as if another core
+// deleted session while packet was translated. Sessions which were not
+// found are looked up again because earlier packets of burst may have
+// created them.
+func (session *sessionLookup) load(port *ipPort, protocol uint8, key interface{}) (interface{}, bool) {
+	if session != nil && session.found && session.protocol == protocol && session.key == key {
+		return session.value, true
+	}
+	return port.translationTable[protocol].Load(key)
+}
+
+// translateBurst translates packets of a burst received by port. All
+// sessions of burst are looked up before packets are translated, so
+// translation table entries are read one after another and translation
+// uses found entries without looking them up again. Verdicts are
+// assigned to the whole burst and counters are updated once per burst.
+func (pp *portPair) translateBurst(port *ipPort, pkts []*packet.Packet, mask *[vectorBurstSize]bool, answers *[vectorBurstSize]uint8,
+	translate func(*portPair, *packet.Packet, *sessionLookup) uint) {
+	var sessions [vectorBurstSize]sessionLookup
+	for i := range mask {
+		if mask[i] {
+			sessions[i] = pp.lookupSession(port, pkts[i])
+		}
+	}
+
+	var stats burstStats
+	for i := range mask {
+		if !mask[i] {
+			continue
+		}
+		length := uint64(pkts[i].GetPacketLen())
+		dir := translate(pp, pkts[i], &sessions[i])
+		answers[i] = uint8(dir)
+		stats.count(length, dir)
+	}
+	stats.add(port)
+}
+
+func publicToPrivateVector(pkts []*packet.Packet, mask *[vectorBurstSize]bool, answers *[vectorBurstSize]uint8, ctx flow.UserContext) {
+	pp := &Natconfig.PortPairs[ctx.(pairIndex).index]
+	pp.translateBurst(&pp.PublicPort, pkts, mask, answers, (*portPair).publicToPrivateTranslation)
+}
+
+func privateToPublicVector(pkts []*packet.Packet, mask *[vectorBurstSize]bool, answers *[vectorBurstSize]uint8, ctx flow.UserContext) {
+	pp := &Natconfig.PortPairs[ctx.(pairIndex).index]
+	pp.translateBurst(&pp.PrivatePort, pkts, mask, answers, (*portPair).privateToPublicTranslation)
+}
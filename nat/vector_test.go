@@ -0,0 +1,48 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/intel-go/nff-go/types"
+)
+
+func TestSessionLookupLoad(t *testing.T) {
+	port := &ipPort{translationTable: make([]*sync.Map, 256)}
+	for i := range port.translationTable {
+		port.translationTable[i] = new(sync.Map)
+	}
+	key := Tuple{addr: hostIPv4(192, 168, 1, 10), port: 5000}
+	other := Tuple{addr: hostIPv4(192, 168, 1, 10), port: 5001}
+	port.translationTable[types.UDPNumber].Store(key, "table")
+	port.translationTable[types.UDPNumber].Store(other, "other")
+	port.translationTable[types.TCPNumber].Store(key, "tcp")
+
+	tests := []struct {
+		name     string
+		session  *sessionLookup
+		protocol uint8
+		key      interface{}
+		value    interface{}
+	}{
+		{"scalar translation", nil, types.UDPNumber, key, "table"},
+		{"found session is reused", &sessionLookup{types.UDPNumber, key, "burst", true}, types.UDPNumber, key, "burst"},
+		{"session which was not found", &sessionLookup{types.UDPNumber, key, nil, false}, types.UDPNumber, key, "table"},
+		{"other key", &sessionLookup{types.UDPNumber, key, "burst", true}, types.UDPNumber, other, "other"},
+		{"other protocol", &sessionLookup{types.UDPNumber, key, "burst", true}, types.TCPNumber, key, "tcp"},
+		{"packet without session", &sessionLookup{}, types.UDPNumber, key, "table"},
+	}
+	for _, tt := range tests {
+		v, found := tt.session.load(port, tt.protocol, tt.key)
+		if !found || v != tt.value {
+			t.Errorf("%s: session is %v found %v, expected %v", tt.name, v, found, tt.value)
+		}
+	}
+	if v, found := (&sessionLookup{}).load(port, types.UDPNumber, Tuple{port: 1}); found {
+		t.Errorf("Unknown session %v is found", v)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nat
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/intel-go/nff-go/packet"
+	"github.com/intel-go/nff-go/types"
+)
+
+// VLAN subinterface of public port with its own public IPv4 subnet.
+// Dynamic IPv4 sessions of private hosts from its private subnets get
+// address of this subinterface and are sent to its VLAN.
+type publicVLAN struct {
+	Vlan   uint16     `json:"vlan-tag"`
+	Subnet ipv4Subnet `json:"subnet"`
+	// Router which receives all translated packets sent to this VLAN.
+	// When it is empty, destinations are resolved directly.
+	NextHop string `json:"next-hop"`
+	// Private hosts which sessions use this subinterface
+	PrivateSubnets []ipv4Subnet `json:"private-subnets"`
+	nextHop        types.IPv4Address
+}
+
+// checkPublicVLANs parses next hops and checks that VLAN
+// subinterfaces don't conflict with each other and with public port.
+func (pp *portPair) checkPublicVLANs() error {
+	if len(pp.PrivatePort.VLANs) != 0 {
+		return fmt.Errorf("VLAN subinterfaces are allowed only on public port, private port %d has them", pp.PrivatePort.Index)
+	}
+	port := &pp.PublicPort
+	if len(port.VLANs) == 0 {
+		return nil
+	}
+	if port.Vlan == 0 {
+		return fmt.Errorf("VLAN subinterfaces of port %d require that port has non-zero vlan tag", port.Index)
+	}
+	if port.KNIName != "" {
+		return fmt.Errorf("Using VLAN subinterfaces together with KNI is not supported yet.")
+	}
+	tags := map[uint16]bool{port.Vlan: true}
+	for i := range port.VLANs {
+		vlan := &port.VLANs[i]
+		if vlan.Vlan == 0 || vlan.Vlan > 4095 {
+			return fmt.Errorf("Bad vlan tag %d of VLAN subinterface of port %d", vlan.Vlan, port.Index)
+		}
+		if tags[vlan.Vlan] {
+			return fmt.Errorf("Vlan tag %d is used more than once on port %d", vlan.Vlan, port.Index)
+		}
+		tags[vlan.Vlan] = true
+		if !vlan.Subnet.addressAcquired {
+			return fmt.Errorf("VLAN subinterface %d of port %d requires static subnet", vlan.Vlan, port.Index)
+		}
+		if vlan.Subnet.Addr == port.Subnet.Addr || port.vlanByAddr(vlan.Subnet.Addr) != vlan {
+			return fmt.Errorf("Address of VLAN subinterface %d of port %d is used more than once", vlan.Vlan, port.Index)
+		}
+		if len(vlan.PrivateSubnets) == 0 {
+			return fmt.Errorf("VLAN subinterface %d of port %d requires private-subnets", vlan.Vlan, port.Index)
+		}
+		for j := range vlan.PrivateSubnets {
+			if !vlan.PrivateSubnets[j].addressAcquired {
+				return fmt.Errorf("Private subnets of VLAN subinterface %d of port %d should not use DHCP", vlan.Vlan, port.Index)
+			}
+		}
+		vlan.nextHop = 0
+		if vlan.NextHop != "" {
+			ip := net.ParseIP(vlan.NextHop)
+			if ip == nil || ip.To4() == nil {
+				return errors.New("Bad next hop address " + vlan.NextHop)
+			}
+			vlan.nextHop, _ = convertIPv4(ip.To4())
+			if !vlan.Subnet.checkAddrWithingSubnet(vlan.nextHop) {
+				return fmt.Errorf("Next hop %s of VLAN subinterface %d of port %d is outside of its subnet", vlan.NextHop, vlan.Vlan, port.Index)
+			}
+		}
+	}
+	return nil
+}
+
+// vlanByAddr returns VLAN subinterface which has specified address or
+// nil.
+func (port *ipPort) vlanByAddr(addr types.IPv4Address) *publicVLAN {
+	for i := range port.VLANs {
+		if port.VLANs[i].Subnet.Addr == addr {
+			return &port.VLANs[i]
+		}
+	}
+	return nil
+}
+
+// vlanForHost returns VLAN subinterface which private subnets include
+// private host or nil if host uses address of port. First matching
+// subinterface is used.
+func (port *ipPort) vlanForHost(host types.IPv4Address) *publicVLAN {
+	for i := range port.VLANs {
+		vlan := &port.VLANs[i]
+		for j := range vlan.PrivateSubnets {
+			if vlan.PrivateSubnets[j].checkAddrWithingSubnet(host) {
+				return vlan
+			}
+		}
+	}
+	return nil
+}
+
+// publicAddressForHost returns public IPv4 address for new session of
+// private host.
+func (port *ipPort) publicAddressForHost(host types.IPv4Address) types.IPv4Address {
+	if vlan := port.vlanForHost(host); vlan != nil {
+		return vlan.Subnet.Addr
+	}
+	return port.Subnet.Addr
+}
+
+// isVLANAddress returns true if packet received by port is addressed
+// to VLAN subinterface and has its vlan tag.
+func (port *ipPort) isVLANAddress(pkt *packet.Packet, addr types.IPv4Address) bool {
+	vlan := port.vlanByAddr(addr)
+	if vlan == nil {
+		return false
+	}
+	pktVLAN := pkt.GetVLAN()
+	return pktVLAN != nil && pktVLAN.GetVLANTagIdentifier() == vlan.Vlan
+}
+
+// getMACForVLAN finds MAC address for packet sent to destination
+// through VLAN subinterface. Next hop is resolved instead of
+// destination when it is configured.
+func (port *ipPort) getMACForVLAN(vlan *publicVLAN, dst types.IPv4Address) (types.MACAddress, bool) {
+	if port.staticArpMode {
+		return port.DstMACAddress, true
+	}
+	if vlan.nextHop != 0 {
+		dst = vlan.nextHop
+	}
+	mac, found := port.loadNeighbor(dst)
+	if found {
+		return mac, true
+	}
+	if port.allowResolution(dst) {
+		port.sendVLANARPRequest(dst, vlan.Subnet.Addr, vlan.Vlan)
+	}
+	return types.MACAddress{}, false
+}
+
+// publicVLANTag returns vlan tag of public port for session which
+// uses specified public address.
+func (port *ipPort) publicVLANTag(addr types.IPv4Address) uint16 {
+	if vlan := port.vlanByAddr(addr); vlan != nil {
+		return vlan.Vlan
+	}
+	return port.Vlan
+}
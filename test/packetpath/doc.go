@@ -0,0 +1,10 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package packetpath runs NAT packet handlers on crafted packets with
+// packet path harness and checks translated packets and packets which
+// NAT sends itself. Cases run in order and share NAT state, e.g.
+// sessions created by one case are used by next ones. Cases run with
+// go test as root.
+package packetpath
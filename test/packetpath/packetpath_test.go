@@ -0,0 +1,491 @@
+// Copyright 2019 Intel Corporation.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package packetpath
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/intel-go/nff-go-nat/nat"
+)
+
+var configFile = flag.String("config", "config.json", "Specify config file name.")
+
+var (
+	privateHostMAC = net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x02}
+	// Destination MAC of public port in static ARP mode
+	gatewayMAC    = net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x01, 0x02}
+	dhcpServerMAC = net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x02, 0x02}
+
+	privatePortIP = net.IP{192, 168, 14, 1}
+	privateHostIP = net.IP{192, 168, 14, 2}
+	publicPortIP  = net.IP{192, 168, 16, 1}
+	remoteHostIP  = net.IP{198, 51, 100, 1}
+	dhcpServerIP  = net.IP{203, 0, 113, 1}
+	dhcpClientIP  = net.IP{203, 0, 113, 10}
+)
+
+const (
+	// Indices of port pairs of config
+	staticPair = 0
+	dhcpPair   = 1
+	// Indices of ports of static pair and public port of DHCP pair
+	privatePortIndex = 0
+	publicPortIndex  = 1
+	dhcpPortIndex    = 3
+)
+
+// State shared by cases.
+type testState struct {
+	h *nat.Harness
+	// Public port of UDP session created by egress case
+	udpPublicPort layers.UDPPort
+	// Transaction of DHCP client
+	dhcpXid uint32
+}
+
+// Result of one case. Out is nil if case doesn't inject packet.
+type testResult struct {
+	dir  uint
+	out  gopacket.Packet
+	sent []nat.HarnessPacket
+}
+
+type testCase struct {
+	name   string
+	pair   int
+	public bool
+	// Action before packet is injected, e.g. start of DHCP client
+	before func(s *testState) error
+	// Packet injected into port, nil if case only checks packets sent
+	// by action
+	inject func(s *testState) ([]byte, error)
+	check  func(s *testState, r *testResult) error
+}
+
+func serialize(l ...gopacket.SerializableLayer) ([]byte, error) {
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{
+		FixLengths:       true,
+		ComputeChecksums: true,
+	}
+	if err := gopacket.SerializeLayers(buf, opts, l...); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func portMAC(s *testState, pair int, public bool) net.HardwareAddr {
+	mac, _ := s.h.MACAddress(pair, public)
+	return net.HardwareAddr(mac[:])
+}
+
+func ipv4Packet(s *testState, pair int, public bool, srcMAC net.HardwareAddr, src, dst net.IP, protocol layers.IPProtocol) (*layers.Ethernet, *layers.IPv4) {
+	return &layers.Ethernet{
+		SrcMAC:       srcMAC,
+		DstMAC:       portMAC(s, pair, public),
+		EthernetType: layers.EthernetTypeIPv4,
+	}, &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: protocol,
+		SrcIP:    src,
+		DstIP:    dst,
+	}
+}
+
+func udpPacket(s *testState, pair int, public bool, srcMAC net.HardwareAddr, src, dst net.IP, sport, dport layers.UDPPort, payload []byte) ([]byte, error) {
+	eth, ip := ipv4Packet(s, pair, public, srcMAC, src, dst, layers.IPProtocolUDP)
+	udp := &layers.UDP{
+		SrcPort: sport,
+		DstPort: dport,
+	}
+	udp.SetNetworkLayerForChecksum(ip)
+	return serialize(eth, ip, udp, gopacket.Payload(payload))
+}
+
+func tcpSYN(s *testState, pair int, public bool, srcMAC net.HardwareAddr, src, dst net.IP, sport, dport layers.TCPPort) ([]byte, error) {
+	eth, ip := ipv4Packet(s, pair, public, srcMAC, src, dst, layers.IPProtocolTCP)
+	tcp := &layers.TCP{
+		SrcPort: sport,
+		DstPort: dport,
+		Seq:     1000,
+		SYN:     true,
+		Window:  65535,
+	}
+	tcp.SetNetworkLayerForChecksum(ip)
+	return serialize(eth, ip, tcp)
+}
+
+func arpPacket(operation uint16, dstMAC net.HardwareAddr, srcIP, dstIP net.IP) ([]byte, error) {
+	targetHW := net.HardwareAddr{0, 0, 0, 0, 0, 0}
+	if operation == layers.ARPReply {
+		targetHW = dstMAC
+	}
+	return serialize(&layers.Ethernet{
+		SrcMAC:       privateHostMAC,
+		DstMAC:       dstMAC,
+		EthernetType: layers.EthernetTypeARP,
+	}, &layers.ARP{
+		AddrType:          layers.LinkTypeEthernet,
+		Protocol:          layers.EthernetTypeIPv4,
+		HwAddressSize:     6,
+		ProtAddressSize:   4,
+		Operation:         operation,
+		SourceHwAddress:   privateHostMAC,
+		SourceProtAddress: srcIP,
+		DstHwAddress:      targetHW,
+		DstProtAddress:    dstIP,
+	})
+}
+
+func dhcpReply(s *testState, msgType layers.DHCPMsgType) ([]byte, error) {
+	eth, ip := ipv4Packet(s, dhcpPair, true, dhcpServerMAC, dhcpServerIP, net.IPv4bcast, layers.IPProtocolUDP)
+	udp := &layers.UDP{
+		SrcPort: 67,
+		DstPort: 68,
+	}
+	udp.SetNetworkLayerForChecksum(ip)
+	mac := portMAC(s, dhcpPair, true)
+	dhcp := &layers.DHCPv4{
+		Operation:    layers.DHCPOpReply,
+		HardwareType: layers.LinkTypeEthernet,
+		HardwareLen:  6,
+		Xid:          s.dhcpXid,
+		YourClientIP: dhcpClientIP,
+		NextServerIP: dhcpServerIP,
+		ClientHWAddr: mac,
+		Options: layers.DHCPOptions{
+			layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(msgType)}),
+			layers.NewDHCPOption(layers.DHCPOptServerID, dhcpServerIP),
+			layers.NewDHCPOption(layers.DHCPOptSubnetMask, []byte{255, 255, 255, 0}),
+			layers.NewDHCPOption(layers.DHCPOptLeaseTime, []byte{0, 0, 0x0e, 0x10}),
+		},
+	}
+	return serialize(eth, ip, udp, dhcp)
+}
+
+func expectDir(r *testResult, dir uint) error {
+	if r.dir != dir {
+		return fmt.Errorf("direction is %d, expected %d", r.dir, dir)
+	}
+	return nil
+}
+
+// expectSent checks that handlers sent one packet to port and returns
+// it parsed.
+func expectSent(r *testResult, port uint16) (gopacket.Packet, error) {
+	if len(r.sent) != 1 {
+		return nil, fmt.Errorf("%d packets sent, expected 1", len(r.sent))
+	}
+	if r.sent[0].Port != port {
+		return nil, fmt.Errorf("packet sent to port %d, expected %d", r.sent[0].Port, port)
+	}
+	return gopacket.NewPacket(r.sent[0].Data, layers.LayerTypeEthernet, gopacket.Default), nil
+}
+
+func expectIPv4(p gopacket.Packet, dstMAC net.HardwareAddr, src, dst net.IP) error {
+	eth, _ := p.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+	ip, _ := p.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+	if eth == nil || ip == nil {
+		return fmt.Errorf("packet is not IPv4")
+	}
+	if eth.DstMAC.String() != dstMAC.String() {
+		return fmt.Errorf("destination MAC is %s, expected %s", eth.DstMAC, dstMAC)
+	}
+	if !ip.SrcIP.Equal(src) || !ip.DstIP.Equal(dst) {
+		return fmt.Errorf("addresses are %s->%s, expected %s->%s", ip.SrcIP, ip.DstIP, src, dst)
+	}
+	return nil
+}
+
+func expectDHCP(p gopacket.Packet, msgType layers.DHCPMsgType) (*layers.DHCPv4, error) {
+	dhcp, _ := p.Layer(layers.LayerTypeDHCPv4).(*layers.DHCPv4)
+	if dhcp == nil {
+		return nil, fmt.Errorf("packet is not DHCP")
+	}
+	for _, o := range dhcp.Options {
+		if o.Type == layers.DHCPOptMessageType && len(o.Data) == 1 {
+			if layers.DHCPMsgType(o.Data[0]) != msgType {
+				return nil, fmt.Errorf("DHCP message is %s, expected %s", layers.DHCPMsgType(o.Data[0]), msgType)
+			}
+			return dhcp, nil
+		}
+	}
+	return nil, fmt.Errorf("DHCP message has no type")
+}
+
+var testCases = []testCase{
+	{
+		name: "ARP request for private port address is answered",
+		pair: staticPair,
+		inject: func(s *testState) ([]byte, error) {
+			return arpPacket(layers.ARPRequest, layers.EthernetBroadcast, privateHostIP, privatePortIP)
+		},
+		check: func(s *testState, r *testResult) error {
+			if err := expectDir(r, nat.DirDROP); err != nil {
+				return err
+			}
+			p, err := expectSent(r, privatePortIndex)
+			if err != nil {
+				return err
+			}
+			arp, _ := p.Layer(layers.LayerTypeARP).(*layers.ARP)
+			if arp == nil || arp.Operation != layers.ARPReply {
+				return fmt.Errorf("answer is not ARP reply")
+			}
+			if !net.IP(arp.SourceProtAddress).Equal(privatePortIP) ||
+				net.HardwareAddr(arp.SourceHwAddress).String() != portMAC(s, staticPair, false).String() ||
+				net.HardwareAddr(arp.DstHwAddress).String() != privateHostMAC.String() {
+				return fmt.Errorf("ARP reply has wrong addresses")
+			}
+			return nil
+		},
+	},
+	{
+		name: "ARP request for other address is ignored",
+		pair: staticPair,
+		inject: func(s *testState) ([]byte, error) {
+			return arpPacket(layers.ARPRequest, layers.EthernetBroadcast, privateHostIP, net.IP{192, 168, 14, 99})
+		},
+		check: func(s *testState, r *testResult) error {
+			if len(r.sent) != 0 {
+				return fmt.Errorf("%d packets sent, expected none", len(r.sent))
+			}
+			return expectDir(r, nat.DirDROP)
+		},
+	},
+	{
+		name: "ARP reply of private host is learned",
+		pair: staticPair,
+		inject: func(s *testState) ([]byte, error) {
+			return arpPacket(layers.ARPReply, portMAC(s, staticPair, false), privateHostIP, privatePortIP)
+		},
+		check: func(s *testState, r *testResult) error {
+			return expectDir(r, nat.DirDROP)
+		},
+	},
+	{
+		name:   "ICMP echo request to public port address is answered",
+		pair:   staticPair,
+		public: true,
+		inject: func(s *testState) ([]byte, error) {
+			eth, ip := ipv4Packet(s, staticPair, true, gatewayMAC, remoteHostIP, publicPortIP, layers.IPProtocolICMPv4)
+			icmp := &layers.ICMPv4{
+				TypeCode: layers.CreateICMPv4TypeCode(layers.ICMPv4TypeEchoRequest, 0),
+				Id:       0x1234,
+				Seq:      1,
+			}
+			return serialize(eth, ip, icmp, gopacket.Payload("ping"))
+		},
+		check: func(s *testState, r *testResult) error {
+			if err := expectDir(r, nat.DirDROP); err != nil {
+				return err
+			}
+			p, err := expectSent(r, publicPortIndex)
+			if err != nil {
+				return err
+			}
+			if err := expectIPv4(p, gatewayMAC, publicPortIP, remoteHostIP); err != nil {
+				return err
+			}
+			icmp, _ := p.Layer(layers.LayerTypeICMPv4).(*layers.ICMPv4)
+			if icmp == nil || icmp.TypeCode.Type() != layers.ICMPv4TypeEchoReply || icmp.Id != 0x1234 {
+				return fmt.Errorf("answer is not echo reply")
+			}
+			return nil
+		},
+	},
+	{
+		name: "UDP from private host is translated to public address",
+		pair: staticPair,
+		inject: func(s *testState) ([]byte, error) {
+			return udpPacket(s, staticPair, false, privateHostMAC, privateHostIP, remoteHostIP, 5000, 53, []byte("query"))
+		},
+		check: func(s *testState, r *testResult) error {
+			if err := expectDir(r, nat.DirSEND); err != nil {
+				return err
+			}
+			if err := expectIPv4(r.out, gatewayMAC, publicPortIP, remoteHostIP); err != nil {
+				return err
+			}
+			udp, _ := r.out.Layer(layers.LayerTypeUDP).(*layers.UDP)
+			if udp == nil || udp.DstPort != 53 {
+				return fmt.Errorf("translated packet is not UDP to port 53")
+			}
+			if udp.SrcPort < 1024 {
+				return fmt.Errorf("public port %d is not dynamic", udp.SrcPort)
+			}
+			s.udpPublicPort = udp.SrcPort
+			return nil
+		},
+	},
+	{
+		name:   "UDP reply is translated back to private host",
+		pair:   staticPair,
+		public: true,
+		inject: func(s *testState) ([]byte, error) {
+			return udpPacket(s, staticPair, true, gatewayMAC, remoteHostIP, publicPortIP, 53, s.udpPublicPort, []byte("answer"))
+		},
+		check: func(s *testState, r *testResult) error {
+			if err := expectDir(r, nat.DirSEND); err != nil {
+				return err
+			}
+			if err := expectIPv4(r.out, privateHostMAC, remoteHostIP, privateHostIP); err != nil {
+				return err
+			}
+			udp, _ := r.out.Layer(layers.LayerTypeUDP).(*layers.UDP)
+			if udp == nil || udp.SrcPort != 53 || udp.DstPort != 5000 {
+				return fmt.Errorf("reply is not UDP from port 53 to port 5000")
+			}
+			return nil
+		},
+	},
+	{
+		name:   "TCP to forwarded port is sent to private host",
+		pair:   staticPair,
+		public: true,
+		inject: func(s *testState) ([]byte, error) {
+			return tcpSYN(s, staticPair, true, gatewayMAC, remoteHostIP, publicPortIP, 40000, 8080)
+		},
+		check: func(s *testState, r *testResult) error {
+			if err := expectDir(r, nat.DirSEND); err != nil {
+				return err
+			}
+			if err := expectIPv4(r.out, privateHostMAC, remoteHostIP, privateHostIP); err != nil {
+				return err
+			}
+			tcp, _ := r.out.Layer(layers.LayerTypeTCP).(*layers.TCP)
+			if tcp == nil || tcp.SrcPort != 40000 || tcp.DstPort != 80 {
+				return fmt.Errorf("forwarded packet is not TCP from port 40000 to port 80")
+			}
+			return nil
+		},
+	},
+	{
+		name:   "TCP to port without session is dropped",
+		pair:   staticPair,
+		public: true,
+		inject: func(s *testState) ([]byte, error) {
+			return tcpSYN(s, staticPair, true, gatewayMAC, remoteHostIP, publicPortIP, 40001, 8443)
+		},
+		check: func(s *testState, r *testResult) error {
+			return expectDir(r, nat.DirDROP)
+		},
+	},
+	{
+		name:   "DHCP client sends discover",
+		pair:   dhcpPair,
+		public: true,
+		before: func(s *testState) error {
+			return s.h.StartDHCP(dhcpPair, true)
+		},
+		check: func(s *testState, r *testResult) error {
+			p, err := expectSent(r, dhcpPortIndex)
+			if err != nil {
+				return err
+			}
+			dhcp, err := expectDHCP(p, layers.DHCPMsgTypeDiscover)
+			if err != nil {
+				return err
+			}
+			s.dhcpXid = dhcp.Xid
+			return nil
+		},
+	},
+	{
+		name:   "DHCP offer is answered with request",
+		pair:   dhcpPair,
+		public: true,
+		inject: func(s *testState) ([]byte, error) {
+			return dhcpReply(s, layers.DHCPMsgTypeOffer)
+		},
+		check: func(s *testState, r *testResult) error {
+			if err := expectDir(r, nat.DirDROP); err != nil {
+				return err
+			}
+			p, err := expectSent(r, dhcpPortIndex)
+			if err != nil {
+				return err
+			}
+			_, err = expectDHCP(p, layers.DHCPMsgTypeRequest)
+			return err
+		},
+	},
+	{
+		name:   "DHCP acknowledgement sets port address",
+		pair:   dhcpPair,
+		public: true,
+		inject: func(s *testState) ([]byte, error) {
+			return dhcpReply(s, layers.DHCPMsgTypeAck)
+		},
+		check: func(s *testState, r *testResult) error {
+			if err := expectDir(r, nat.DirDROP); err != nil {
+				return err
+			}
+			subnet, acquired, err := s.h.Address(dhcpPair, true)
+			if err != nil {
+				return err
+			}
+			if !acquired || subnet != "203.0.113.10/24" {
+				return fmt.Errorf("port address is %s, expected 203.0.113.10/24", subnet)
+			}
+			return nil
+		},
+	},
+}
+
+func runCase(s *testState, tc *testCase) error {
+	if tc.before != nil {
+		if err := tc.before(s); err != nil {
+			return err
+		}
+	}
+	r := &testResult{}
+	if tc.inject != nil {
+		data, err := tc.inject(s)
+		if err != nil {
+			return err
+		}
+		var out []byte
+		r.dir, out, err = s.h.Inject(tc.pair, tc.public, data)
+		if err != nil {
+			return err
+		}
+		r.out = gopacket.NewPacket(out, layers.LayerTypeEthernet, gopacket.Default)
+	}
+	r.sent = s.h.Sent()
+	return tc.check(s, r)
+}
+
+// TestPacketPath runs cases in order on one harness, later cases
+// depend on state left by former ones, so all cases after failed one
+// are still run.
+func TestPacketPath(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Packet path harness initializes DPDK, it should be run as root")
+	}
+	h, err := nat.NewHarness(*configFile)
+	if err != nil {
+		t.Fatal("Failed to create packet path harness:", err)
+	}
+	s := &testState{
+		h: h,
+	}
+	for i := range testCases {
+		tc := &testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			if err := runCase(s, tc); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
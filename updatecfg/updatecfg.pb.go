@@ -46,7 +46,7 @@ func (x TraceType) String() string {
 	return proto.EnumName(TraceType_name, int32(x))
 }
 func (TraceType) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_updatecfg_fa7d68b5067fd899, []int{0}
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{0}
 }
 
 type Protocol int32
@@ -81,7 +81,128 @@ func (x Protocol) String() string {
 	return proto.EnumName(Protocol_name, int32(x))
 }
 func (Protocol) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_updatecfg_fa7d68b5067fd899, []int{1}
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{1}
+}
+
+type DHCPState int32
+
+const (
+	DHCPState_DHCP_UNKNOWN     DHCPState = 0
+	DHCPState_DHCP_STATIC      DHCPState = 1
+	DHCPState_DHCP_DISCOVERING DHCPState = 2
+	DHCPState_DHCP_REQUESTING  DHCPState = 3
+	DHCPState_DHCP_BOUND       DHCPState = 4
+	DHCPState_DHCP_RENEWING    DHCPState = 5
+	DHCPState_DHCP_RELEASED    DHCPState = 6
+)
+
+var DHCPState_name = map[int32]string{
+	0: "DHCP_UNKNOWN",
+	1: "DHCP_STATIC",
+	2: "DHCP_DISCOVERING",
+	3: "DHCP_REQUESTING",
+	4: "DHCP_BOUND",
+	5: "DHCP_RENEWING",
+	6: "DHCP_RELEASED",
+}
+var DHCPState_value = map[string]int32{
+	"DHCP_UNKNOWN":     0,
+	"DHCP_STATIC":      1,
+	"DHCP_DISCOVERING": 2,
+	"DHCP_REQUESTING":  3,
+	"DHCP_BOUND":       4,
+	"DHCP_RENEWING":    5,
+	"DHCP_RELEASED":    6,
+}
+
+func (x DHCPState) String() string {
+	return proto.EnumName(DHCPState_name, int32(x))
+}
+func (DHCPState) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{2}
+}
+
+type DHCPAction int32
+
+const (
+	DHCPAction_DHCP_ACTION_UNSPECIFIED DHCPAction = 0
+	DHCPAction_DHCP_RENEW              DHCPAction = 1
+	DHCPAction_DHCP_RELEASE            DHCPAction = 2
+	DHCPAction_DHCP_RESTART            DHCPAction = 3
+)
+
+var DHCPAction_name = map[int32]string{
+	0: "DHCP_ACTION_UNSPECIFIED",
+	1: "DHCP_RENEW",
+	2: "DHCP_RELEASE",
+	3: "DHCP_RESTART",
+}
+var DHCPAction_value = map[string]int32{
+	"DHCP_ACTION_UNSPECIFIED": 0,
+	"DHCP_RENEW":              1,
+	"DHCP_RELEASE":            2,
+	"DHCP_RESTART":            3,
+}
+
+func (x DHCPAction) String() string {
+	return proto.EnumName(DHCPAction_name, int32(x))
+}
+func (DHCPAction) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{3}
+}
+
+type SessionKind int32
+
+const (
+	SessionKind_SESSIONS_ALL     SessionKind = 0
+	SessionKind_SESSIONS_DYNAMIC SessionKind = 1
+	SessionKind_SESSIONS_STATIC  SessionKind = 2
+)
+
+var SessionKind_name = map[int32]string{
+	0: "SESSIONS_ALL",
+	1: "SESSIONS_DYNAMIC",
+	2: "SESSIONS_STATIC",
+}
+var SessionKind_value = map[string]int32{
+	"SESSIONS_ALL":     0,
+	"SESSIONS_DYNAMIC": 1,
+	"SESSIONS_STATIC":  2,
+}
+
+func (x SessionKind) String() string {
+	return proto.EnumName(SessionKind_name, int32(x))
+}
+func (SessionKind) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{4}
+}
+
+// Captive portal state of private host. Hosts in default state get
+// state which is configured for hosts which are not listed.
+type CaptivePortalState int32
+
+const (
+	CaptivePortalState_PORTAL_DEFAULT         CaptivePortalState = 0
+	CaptivePortalState_PORTAL_AUTHENTICATED   CaptivePortalState = 1
+	CaptivePortalState_PORTAL_UNAUTHENTICATED CaptivePortalState = 2
+)
+
+var CaptivePortalState_name = map[int32]string{
+	0: "PORTAL_DEFAULT",
+	1: "PORTAL_AUTHENTICATED",
+	2: "PORTAL_UNAUTHENTICATED",
+}
+var CaptivePortalState_value = map[string]int32{
+	"PORTAL_DEFAULT":         0,
+	"PORTAL_AUTHENTICATED":   1,
+	"PORTAL_UNAUTHENTICATED": 2,
+}
+
+func (x CaptivePortalState) String() string {
+	return proto.EnumName(CaptivePortalState_name, int32(x))
+}
+func (CaptivePortalState) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{5}
 }
 
 type DumpControlRequest struct {
@@ -96,7 +217,7 @@ func (m *DumpControlRequest) Reset()         { *m = DumpControlRequest{} }
 func (m *DumpControlRequest) String() string { return proto.CompactTextString(m) }
 func (*DumpControlRequest) ProtoMessage()    {}
 func (*DumpControlRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_updatecfg_fa7d68b5067fd899, []int{0}
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{0}
 }
 func (m *DumpControlRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DumpControlRequest.Unmarshal(m, b)
@@ -130,6 +251,243 @@ func (m *DumpControlRequest) GetTraceType() TraceType {
 	return TraceType_DUMP_DROP
 }
 
+type DumpStreamRequest struct {
+	TraceTypes []TraceType `protobuf:"varint,1,rep,packed,name=trace_types,json=traceTypes,proto3,enum=updatecfg.TraceType" json:"trace_types,omitempty"`
+	// Empty list means all interfaces
+	InterfaceIds         []uint32 `protobuf:"varint,2,rep,packed,name=interface_ids,json=interfaceIds,proto3" json:"interface_ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DumpStreamRequest) Reset()         { *m = DumpStreamRequest{} }
+func (m *DumpStreamRequest) String() string { return proto.CompactTextString(m) }
+func (*DumpStreamRequest) ProtoMessage()    {}
+func (*DumpStreamRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{1}
+}
+func (m *DumpStreamRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DumpStreamRequest.Unmarshal(m, b)
+}
+func (m *DumpStreamRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DumpStreamRequest.Marshal(b, m, deterministic)
+}
+func (dst *DumpStreamRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DumpStreamRequest.Merge(dst, src)
+}
+func (m *DumpStreamRequest) XXX_Size() int {
+	return xxx_messageInfo_DumpStreamRequest.Size(m)
+}
+func (m *DumpStreamRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DumpStreamRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DumpStreamRequest proto.InternalMessageInfo
+
+func (m *DumpStreamRequest) GetTraceTypes() []TraceType {
+	if m != nil {
+		return m.TraceTypes
+	}
+	return nil
+}
+
+func (m *DumpStreamRequest) GetInterfaceIds() []uint32 {
+	if m != nil {
+		return m.InterfaceIds
+	}
+	return nil
+}
+
+type DumpedPacket struct {
+	InterfaceId uint32    `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	TraceType   TraceType `protobuf:"varint,2,opt,name=trace_type,json=traceType,proto3,enum=updatecfg.TraceType" json:"trace_type,omitempty"`
+	// Nanoseconds since epoch
+	Timestamp int64  `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Data      []byte `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	// Number of packets dropped for this stream so far
+	Dropped              uint64   `protobuf:"varint,5,opt,name=dropped,proto3" json:"dropped,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DumpedPacket) Reset()         { *m = DumpedPacket{} }
+func (m *DumpedPacket) String() string { return proto.CompactTextString(m) }
+func (*DumpedPacket) ProtoMessage()    {}
+func (*DumpedPacket) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{2}
+}
+func (m *DumpedPacket) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DumpedPacket.Unmarshal(m, b)
+}
+func (m *DumpedPacket) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DumpedPacket.Marshal(b, m, deterministic)
+}
+func (dst *DumpedPacket) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DumpedPacket.Merge(dst, src)
+}
+func (m *DumpedPacket) XXX_Size() int {
+	return xxx_messageInfo_DumpedPacket.Size(m)
+}
+func (m *DumpedPacket) XXX_DiscardUnknown() {
+	xxx_messageInfo_DumpedPacket.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DumpedPacket proto.InternalMessageInfo
+
+func (m *DumpedPacket) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *DumpedPacket) GetTraceType() TraceType {
+	if m != nil {
+		return m.TraceType
+	}
+	return TraceType_DUMP_DROP
+}
+
+func (m *DumpedPacket) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *DumpedPacket) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *DumpedPacket) GetDropped() uint64 {
+	if m != nil {
+		return m.Dropped
+	}
+	return 0
+}
+
+type DumpSinkConnectRequest struct {
+	Address string `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	UseTls  bool   `protobuf:"varint,2,opt,name=use_tls,json=useTls,proto3" json:"use_tls,omitempty"`
+	// PEM encoded CA certificate, system roots are used if empty
+	CaCertificate      []byte      `protobuf:"bytes,3,opt,name=ca_certificate,json=caCertificate,proto3" json:"ca_certificate,omitempty"`
+	InsecureSkipVerify bool        `protobuf:"varint,4,opt,name=insecure_skip_verify,json=insecureSkipVerify,proto3" json:"insecure_skip_verify,omitempty"`
+	TraceTypes         []TraceType `protobuf:"varint,5,rep,packed,name=trace_types,json=traceTypes,proto3,enum=updatecfg.TraceType" json:"trace_types,omitempty"`
+	// Empty list means all interfaces
+	InterfaceIds         []uint32 `protobuf:"varint,6,rep,packed,name=interface_ids,json=interfaceIds,proto3" json:"interface_ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DumpSinkConnectRequest) Reset()         { *m = DumpSinkConnectRequest{} }
+func (m *DumpSinkConnectRequest) String() string { return proto.CompactTextString(m) }
+func (*DumpSinkConnectRequest) ProtoMessage()    {}
+func (*DumpSinkConnectRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{3}
+}
+func (m *DumpSinkConnectRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DumpSinkConnectRequest.Unmarshal(m, b)
+}
+func (m *DumpSinkConnectRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DumpSinkConnectRequest.Marshal(b, m, deterministic)
+}
+func (dst *DumpSinkConnectRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DumpSinkConnectRequest.Merge(dst, src)
+}
+func (m *DumpSinkConnectRequest) XXX_Size() int {
+	return xxx_messageInfo_DumpSinkConnectRequest.Size(m)
+}
+func (m *DumpSinkConnectRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DumpSinkConnectRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DumpSinkConnectRequest proto.InternalMessageInfo
+
+func (m *DumpSinkConnectRequest) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *DumpSinkConnectRequest) GetUseTls() bool {
+	if m != nil {
+		return m.UseTls
+	}
+	return false
+}
+
+func (m *DumpSinkConnectRequest) GetCaCertificate() []byte {
+	if m != nil {
+		return m.CaCertificate
+	}
+	return nil
+}
+
+func (m *DumpSinkConnectRequest) GetInsecureSkipVerify() bool {
+	if m != nil {
+		return m.InsecureSkipVerify
+	}
+	return false
+}
+
+func (m *DumpSinkConnectRequest) GetTraceTypes() []TraceType {
+	if m != nil {
+		return m.TraceTypes
+	}
+	return nil
+}
+
+func (m *DumpSinkConnectRequest) GetInterfaceIds() []uint32 {
+	if m != nil {
+		return m.InterfaceIds
+	}
+	return nil
+}
+
+type DumpSinkDisconnectRequest struct {
+	Address              string   `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DumpSinkDisconnectRequest) Reset()         { *m = DumpSinkDisconnectRequest{} }
+func (m *DumpSinkDisconnectRequest) String() string { return proto.CompactTextString(m) }
+func (*DumpSinkDisconnectRequest) ProtoMessage()    {}
+func (*DumpSinkDisconnectRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{4}
+}
+func (m *DumpSinkDisconnectRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DumpSinkDisconnectRequest.Unmarshal(m, b)
+}
+func (m *DumpSinkDisconnectRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DumpSinkDisconnectRequest.Marshal(b, m, deterministic)
+}
+func (dst *DumpSinkDisconnectRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DumpSinkDisconnectRequest.Merge(dst, src)
+}
+func (m *DumpSinkDisconnectRequest) XXX_Size() int {
+	return xxx_messageInfo_DumpSinkDisconnectRequest.Size(m)
+}
+func (m *DumpSinkDisconnectRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DumpSinkDisconnectRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DumpSinkDisconnectRequest proto.InternalMessageInfo
+
+func (m *DumpSinkDisconnectRequest) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
 type IPAddress struct {
 	Address              []byte   `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -141,7 +499,7 @@ func (m *IPAddress) Reset()         { *m = IPAddress{} }
 func (m *IPAddress) String() string { return proto.CompactTextString(m) }
 func (*IPAddress) ProtoMessage()    {}
 func (*IPAddress) Descriptor() ([]byte, []int) {
-	return fileDescriptor_updatecfg_fa7d68b5067fd899, []int{1}
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{5}
 }
 func (m *IPAddress) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_IPAddress.Unmarshal(m, b)
@@ -180,7 +538,7 @@ func (m *Subnet) Reset()         { *m = Subnet{} }
 func (m *Subnet) String() string { return proto.CompactTextString(m) }
 func (*Subnet) ProtoMessage()    {}
 func (*Subnet) Descriptor() ([]byte, []int) {
-	return fileDescriptor_updatecfg_fa7d68b5067fd899, []int{2}
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{6}
 }
 func (m *Subnet) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Subnet.Unmarshal(m, b)
@@ -215,8 +573,11 @@ func (m *Subnet) GetMaskBitsNumber() uint32 {
 }
 
 type InterfaceAddressChangeRequest struct {
-	InterfaceId          uint32   `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
-	PortSubnet           *Subnet  `protobuf:"bytes,2,opt,name=port_subnet,json=portSubnet,proto3" json:"port_subnet,omitempty"`
+	InterfaceId uint32  `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	PortSubnet  *Subnet `protobuf:"bytes,2,opt,name=port_subnet,json=portSubnet,proto3" json:"port_subnet,omitempty"`
+	// Sessions of old public port address keep using it until they
+	// expire, new sessions use new address
+	KeepSessions         bool     `protobuf:"varint,3,opt,name=keep_sessions,json=keepSessions,proto3" json:"keep_sessions,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -226,7 +587,7 @@ func (m *InterfaceAddressChangeRequest) Reset()         { *m = InterfaceAddressC
 func (m *InterfaceAddressChangeRequest) String() string { return proto.CompactTextString(m) }
 func (*InterfaceAddressChangeRequest) ProtoMessage()    {}
 func (*InterfaceAddressChangeRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_updatecfg_fa7d68b5067fd899, []int{3}
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{7}
 }
 func (m *InterfaceAddressChangeRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_InterfaceAddressChangeRequest.Unmarshal(m, b)
@@ -260,21 +621,35 @@ func (m *InterfaceAddressChangeRequest) GetPortSubnet() *Subnet {
 	return nil
 }
 
+func (m *InterfaceAddressChangeRequest) GetKeepSessions() bool {
+	if m != nil {
+		return m.KeepSessions
+	}
+	return false
+}
+
 type ForwardedPort struct {
-	SourcePortNumber     uint32     `protobuf:"varint,1,opt,name=source_port_number,json=sourcePortNumber,proto3" json:"source_port_number,omitempty"`
-	TargetAddress        *IPAddress `protobuf:"bytes,2,opt,name=target_address,json=targetAddress,proto3" json:"target_address,omitempty"`
-	TargetPortNumber     uint32     `protobuf:"varint,3,opt,name=target_port_number,json=targetPortNumber,proto3" json:"target_port_number,omitempty"`
-	Protocol             Protocol   `protobuf:"varint,4,opt,name=protocol,proto3,enum=updatecfg.Protocol" json:"protocol,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
-	XXX_unrecognized     []byte     `json:"-"`
-	XXX_sizecache        int32      `json:"-"`
+	SourcePortNumber uint32     `protobuf:"varint,1,opt,name=source_port_number,json=sourcePortNumber,proto3" json:"source_port_number,omitempty"`
+	TargetAddress    *IPAddress `protobuf:"bytes,2,opt,name=target_address,json=targetAddress,proto3" json:"target_address,omitempty"`
+	TargetPortNumber uint32     `protobuf:"varint,3,opt,name=target_port_number,json=targetPortNumber,proto3" json:"target_port_number,omitempty"`
+	Protocol         Protocol   `protobuf:"varint,4,opt,name=protocol,proto3,enum=updatecfg.Protocol" json:"protocol,omitempty"`
+	// Zone of link local IPv6 target address, name or index of port
+	TargetZone string `protobuf:"bytes,5,opt,name=target_zone,json=targetZone,proto3" json:"target_zone,omitempty"`
+	// Other targets for remote hosts within prefixes
+	Sources []*ForwardedSource `protobuf:"bytes,6,rep,name=sources,proto3" json:"sources,omitempty"`
+	// Target is an address of KNI interface of port, packets are sent
+	// to KNI after their destination is rewritten
+	Kni                  bool     `protobuf:"varint,7,opt,name=kni,proto3" json:"kni,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *ForwardedPort) Reset()         { *m = ForwardedPort{} }
 func (m *ForwardedPort) String() string { return proto.CompactTextString(m) }
 func (*ForwardedPort) ProtoMessage()    {}
 func (*ForwardedPort) Descriptor() ([]byte, []int) {
-	return fileDescriptor_updatecfg_fa7d68b5067fd899, []int{4}
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{8}
 }
 func (m *ForwardedPort) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ForwardedPort.Unmarshal(m, b)
@@ -322,6 +697,90 @@ func (m *ForwardedPort) GetProtocol() Protocol {
 	return Protocol_UNKNOWN
 }
 
+func (m *ForwardedPort) GetTargetZone() string {
+	if m != nil {
+		return m.TargetZone
+	}
+	return ""
+}
+
+func (m *ForwardedPort) GetSources() []*ForwardedSource {
+	if m != nil {
+		return m.Sources
+	}
+	return nil
+}
+
+func (m *ForwardedPort) GetKni() bool {
+	if m != nil {
+		return m.Kni
+	}
+	return false
+}
+
+type ForwardedSource struct {
+	// Prefix of remote addresses, e.g. 198.51.100.0/24
+	Prefix               string     `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	TargetAddress        *IPAddress `protobuf:"bytes,2,opt,name=target_address,json=targetAddress,proto3" json:"target_address,omitempty"`
+	TargetPortNumber     uint32     `protobuf:"varint,3,opt,name=target_port_number,json=targetPortNumber,proto3" json:"target_port_number,omitempty"`
+	TargetZone           string     `protobuf:"bytes,4,opt,name=target_zone,json=targetZone,proto3" json:"target_zone,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *ForwardedSource) Reset()         { *m = ForwardedSource{} }
+func (m *ForwardedSource) String() string { return proto.CompactTextString(m) }
+func (*ForwardedSource) ProtoMessage()    {}
+func (*ForwardedSource) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{9}
+}
+func (m *ForwardedSource) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ForwardedSource.Unmarshal(m, b)
+}
+func (m *ForwardedSource) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ForwardedSource.Marshal(b, m, deterministic)
+}
+func (dst *ForwardedSource) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ForwardedSource.Merge(dst, src)
+}
+func (m *ForwardedSource) XXX_Size() int {
+	return xxx_messageInfo_ForwardedSource.Size(m)
+}
+func (m *ForwardedSource) XXX_DiscardUnknown() {
+	xxx_messageInfo_ForwardedSource.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ForwardedSource proto.InternalMessageInfo
+
+func (m *ForwardedSource) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *ForwardedSource) GetTargetAddress() *IPAddress {
+	if m != nil {
+		return m.TargetAddress
+	}
+	return nil
+}
+
+func (m *ForwardedSource) GetTargetPortNumber() uint32 {
+	if m != nil {
+		return m.TargetPortNumber
+	}
+	return 0
+}
+
+func (m *ForwardedSource) GetTargetZone() string {
+	if m != nil {
+		return m.TargetZone
+	}
+	return ""
+}
+
 type PortForwardingChangeRequest struct {
 	EnableForwarding     bool           `protobuf:"varint,1,opt,name=enable_forwarding,json=enableForwarding,proto3" json:"enable_forwarding,omitempty"`
 	InterfaceId          uint32         `protobuf:"varint,2,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
@@ -335,7 +794,7 @@ func (m *PortForwardingChangeRequest) Reset()         { *m = PortForwardingChang
 func (m *PortForwardingChangeRequest) String() string { return proto.CompactTextString(m) }
 func (*PortForwardingChangeRequest) ProtoMessage()    {}
 func (*PortForwardingChangeRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_updatecfg_fa7d68b5067fd899, []int{5}
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{10}
 }
 func (m *PortForwardingChangeRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_PortForwardingChangeRequest.Unmarshal(m, b)
@@ -376,169 +835,6309 @@ func (m *PortForwardingChangeRequest) GetPort() *ForwardedPort {
 	return nil
 }
 
-type Reply struct {
-	Msg                  string   `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+type Neighbor struct {
+	Address              *IPAddress `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	MacAddress           []byte     `protobuf:"bytes,2,opt,name=mac_address,json=macAddress,proto3" json:"mac_address,omitempty"`
+	Static               bool       `protobuf:"varint,3,opt,name=static,proto3" json:"static,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
 }
 
-func (m *Reply) Reset()         { *m = Reply{} }
-func (m *Reply) String() string { return proto.CompactTextString(m) }
-func (*Reply) ProtoMessage()    {}
-func (*Reply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_updatecfg_fa7d68b5067fd899, []int{6}
+func (m *Neighbor) Reset()         { *m = Neighbor{} }
+func (m *Neighbor) String() string { return proto.CompactTextString(m) }
+func (*Neighbor) ProtoMessage()    {}
+func (*Neighbor) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{11}
 }
-func (m *Reply) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_Reply.Unmarshal(m, b)
+func (m *Neighbor) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Neighbor.Unmarshal(m, b)
 }
-func (m *Reply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_Reply.Marshal(b, m, deterministic)
+func (m *Neighbor) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Neighbor.Marshal(b, m, deterministic)
 }
-func (dst *Reply) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_Reply.Merge(dst, src)
+func (dst *Neighbor) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Neighbor.Merge(dst, src)
 }
-func (m *Reply) XXX_Size() int {
-	return xxx_messageInfo_Reply.Size(m)
+func (m *Neighbor) XXX_Size() int {
+	return xxx_messageInfo_Neighbor.Size(m)
 }
-func (m *Reply) XXX_DiscardUnknown() {
-	xxx_messageInfo_Reply.DiscardUnknown(m)
+func (m *Neighbor) XXX_DiscardUnknown() {
+	xxx_messageInfo_Neighbor.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_Reply proto.InternalMessageInfo
+var xxx_messageInfo_Neighbor proto.InternalMessageInfo
 
-func (m *Reply) GetMsg() string {
+func (m *Neighbor) GetAddress() *IPAddress {
 	if m != nil {
-		return m.Msg
+		return m.Address
+	}
+	return nil
+}
+
+func (m *Neighbor) GetMacAddress() []byte {
+	if m != nil {
+		return m.MacAddress
+	}
+	return nil
+}
+
+func (m *Neighbor) GetStatic() bool {
+	if m != nil {
+		return m.Static
+	}
+	return false
+}
+
+type NeighborsRequest struct {
+	InterfaceId          uint32   `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NeighborsRequest) Reset()         { *m = NeighborsRequest{} }
+func (m *NeighborsRequest) String() string { return proto.CompactTextString(m) }
+func (*NeighborsRequest) ProtoMessage()    {}
+func (*NeighborsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{12}
+}
+func (m *NeighborsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NeighborsRequest.Unmarshal(m, b)
+}
+func (m *NeighborsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NeighborsRequest.Marshal(b, m, deterministic)
+}
+func (dst *NeighborsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NeighborsRequest.Merge(dst, src)
+}
+func (m *NeighborsRequest) XXX_Size() int {
+	return xxx_messageInfo_NeighborsRequest.Size(m)
+}
+func (m *NeighborsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_NeighborsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NeighborsRequest proto.InternalMessageInfo
+
+func (m *NeighborsRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+type NeighborsReply struct {
+	Neighbors []*Neighbor `protobuf:"bytes,1,rep,name=neighbors,proto3" json:"neighbors,omitempty"`
+	// Tenant of port pair, private subnets of different tenants may
+	// overlap
+	Tenant               string   `protobuf:"bytes,2,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NeighborsReply) Reset()         { *m = NeighborsReply{} }
+func (m *NeighborsReply) String() string { return proto.CompactTextString(m) }
+func (*NeighborsReply) ProtoMessage()    {}
+func (*NeighborsReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{13}
+}
+func (m *NeighborsReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NeighborsReply.Unmarshal(m, b)
+}
+func (m *NeighborsReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NeighborsReply.Marshal(b, m, deterministic)
+}
+func (dst *NeighborsReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NeighborsReply.Merge(dst, src)
+}
+func (m *NeighborsReply) XXX_Size() int {
+	return xxx_messageInfo_NeighborsReply.Size(m)
+}
+func (m *NeighborsReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_NeighborsReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NeighborsReply proto.InternalMessageInfo
+
+func (m *NeighborsReply) GetNeighbors() []*Neighbor {
+	if m != nil {
+		return m.Neighbors
+	}
+	return nil
+}
+
+func (m *NeighborsReply) GetTenant() string {
+	if m != nil {
+		return m.Tenant
 	}
 	return ""
 }
 
-func init() {
-	proto.RegisterType((*DumpControlRequest)(nil), "updatecfg.DumpControlRequest")
-	proto.RegisterType((*IPAddress)(nil), "updatecfg.IPAddress")
-	proto.RegisterType((*Subnet)(nil), "updatecfg.Subnet")
-	proto.RegisterType((*InterfaceAddressChangeRequest)(nil), "updatecfg.InterfaceAddressChangeRequest")
-	proto.RegisterType((*ForwardedPort)(nil), "updatecfg.ForwardedPort")
-	proto.RegisterType((*PortForwardingChangeRequest)(nil), "updatecfg.PortForwardingChangeRequest")
-	proto.RegisterType((*Reply)(nil), "updatecfg.Reply")
-	proto.RegisterEnum("updatecfg.TraceType", TraceType_name, TraceType_value)
-	proto.RegisterEnum("updatecfg.Protocol", Protocol_name, Protocol_value)
+type NeighborChangeRequest struct {
+	InterfaceId          uint32     `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	Address              *IPAddress `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	MacAddress           []byte     `protobuf:"bytes,3,opt,name=mac_address,json=macAddress,proto3" json:"mac_address,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+func (m *NeighborChangeRequest) Reset()         { *m = NeighborChangeRequest{} }
+func (m *NeighborChangeRequest) String() string { return proto.CompactTextString(m) }
+func (*NeighborChangeRequest) ProtoMessage()    {}
+func (*NeighborChangeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{14}
+}
+func (m *NeighborChangeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NeighborChangeRequest.Unmarshal(m, b)
+}
+func (m *NeighborChangeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NeighborChangeRequest.Marshal(b, m, deterministic)
+}
+func (dst *NeighborChangeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NeighborChangeRequest.Merge(dst, src)
+}
+func (m *NeighborChangeRequest) XXX_Size() int {
+	return xxx_messageInfo_NeighborChangeRequest.Size(m)
+}
+func (m *NeighborChangeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_NeighborChangeRequest.DiscardUnknown(m)
+}
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+var xxx_messageInfo_NeighborChangeRequest proto.InternalMessageInfo
+
+func (m *NeighborChangeRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *NeighborChangeRequest) GetAddress() *IPAddress {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *NeighborChangeRequest) GetMacAddress() []byte {
+	if m != nil {
+		return m.MacAddress
+	}
+	return nil
+}
+
+type NeighborsFlushRequest struct {
+	InterfaceId          uint32   `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	FlushStatic          bool     `protobuf:"varint,2,opt,name=flush_static,json=flushStatic,proto3" json:"flush_static,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NeighborsFlushRequest) Reset()         { *m = NeighborsFlushRequest{} }
+func (m *NeighborsFlushRequest) String() string { return proto.CompactTextString(m) }
+func (*NeighborsFlushRequest) ProtoMessage()    {}
+func (*NeighborsFlushRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{15}
+}
+func (m *NeighborsFlushRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NeighborsFlushRequest.Unmarshal(m, b)
+}
+func (m *NeighborsFlushRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NeighborsFlushRequest.Marshal(b, m, deterministic)
+}
+func (dst *NeighborsFlushRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NeighborsFlushRequest.Merge(dst, src)
+}
+func (m *NeighborsFlushRequest) XXX_Size() int {
+	return xxx_messageInfo_NeighborsFlushRequest.Size(m)
+}
+func (m *NeighborsFlushRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_NeighborsFlushRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NeighborsFlushRequest proto.InternalMessageInfo
+
+func (m *NeighborsFlushRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *NeighborsFlushRequest) GetFlushStatic() bool {
+	if m != nil {
+		return m.FlushStatic
+	}
+	return false
+}
+
+type DHCPLeaseRequest struct {
+	InterfaceId          uint32   `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	Ipv6                 bool     `protobuf:"varint,2,opt,name=ipv6,proto3" json:"ipv6,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DHCPLeaseRequest) Reset()         { *m = DHCPLeaseRequest{} }
+func (m *DHCPLeaseRequest) String() string { return proto.CompactTextString(m) }
+func (*DHCPLeaseRequest) ProtoMessage()    {}
+func (*DHCPLeaseRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{16}
+}
+func (m *DHCPLeaseRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DHCPLeaseRequest.Unmarshal(m, b)
+}
+func (m *DHCPLeaseRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DHCPLeaseRequest.Marshal(b, m, deterministic)
+}
+func (dst *DHCPLeaseRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DHCPLeaseRequest.Merge(dst, src)
+}
+func (m *DHCPLeaseRequest) XXX_Size() int {
+	return xxx_messageInfo_DHCPLeaseRequest.Size(m)
+}
+func (m *DHCPLeaseRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DHCPLeaseRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DHCPLeaseRequest proto.InternalMessageInfo
+
+func (m *DHCPLeaseRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *DHCPLeaseRequest) GetIpv6() bool {
+	if m != nil {
+		return m.Ipv6
+	}
+	return false
+}
+
+type DHCPControlRequest struct {
+	InterfaceId          uint32     `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	Ipv6                 bool       `protobuf:"varint,2,opt,name=ipv6,proto3" json:"ipv6,omitempty"`
+	Action               DHCPAction `protobuf:"varint,3,opt,name=action,proto3,enum=updatecfg.DHCPAction" json:"action,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *DHCPControlRequest) Reset()         { *m = DHCPControlRequest{} }
+func (m *DHCPControlRequest) String() string { return proto.CompactTextString(m) }
+func (*DHCPControlRequest) ProtoMessage()    {}
+func (*DHCPControlRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{17}
+}
+func (m *DHCPControlRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DHCPControlRequest.Unmarshal(m, b)
+}
+func (m *DHCPControlRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DHCPControlRequest.Marshal(b, m, deterministic)
+}
+func (dst *DHCPControlRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DHCPControlRequest.Merge(dst, src)
+}
+func (m *DHCPControlRequest) XXX_Size() int {
+	return xxx_messageInfo_DHCPControlRequest.Size(m)
+}
+func (m *DHCPControlRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DHCPControlRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DHCPControlRequest proto.InternalMessageInfo
+
+func (m *DHCPControlRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *DHCPControlRequest) GetIpv6() bool {
+	if m != nil {
+		return m.Ipv6
+	}
+	return false
+}
+
+func (m *DHCPControlRequest) GetAction() DHCPAction {
+	if m != nil {
+		return m.Action
+	}
+	return DHCPAction_DHCP_ACTION_UNSPECIFIED
+}
+
+type DHCPLeaseReply struct {
+	InterfaceId          uint32     `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	Ipv6                 bool       `protobuf:"varint,2,opt,name=ipv6,proto3" json:"ipv6,omitempty"`
+	State                DHCPState  `protobuf:"varint,3,opt,name=state,proto3,enum=updatecfg.DHCPState" json:"state,omitempty"`
+	Subnet               *Subnet    `protobuf:"bytes,4,opt,name=subnet,proto3" json:"subnet,omitempty"`
+	Server               *IPAddress `protobuf:"bytes,5,opt,name=server,proto3" json:"server,omitempty"`
+	LeaseSeconds         uint32     `protobuf:"varint,6,opt,name=lease_seconds,json=leaseSeconds,proto3" json:"lease_seconds,omitempty"`
+	RenewSeconds         uint32     `protobuf:"varint,7,opt,name=renew_seconds,json=renewSeconds,proto3" json:"renew_seconds,omitempty"`
+	RebindSeconds        uint32     `protobuf:"varint,8,opt,name=rebind_seconds,json=rebindSeconds,proto3" json:"rebind_seconds,omitempty"`
+	LeaseObtained        int64      `protobuf:"varint,9,opt,name=lease_obtained,json=leaseObtained,proto3" json:"lease_obtained,omitempty"`
+	LeaseExpires         int64      `protobuf:"varint,10,opt,name=lease_expires,json=leaseExpires,proto3" json:"lease_expires,omitempty"`
+	Tenant               string     `protobuf:"bytes,11,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *DHCPLeaseReply) Reset()         { *m = DHCPLeaseReply{} }
+func (m *DHCPLeaseReply) String() string { return proto.CompactTextString(m) }
+func (*DHCPLeaseReply) ProtoMessage()    {}
+func (*DHCPLeaseReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{18}
+}
+func (m *DHCPLeaseReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DHCPLeaseReply.Unmarshal(m, b)
+}
+func (m *DHCPLeaseReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DHCPLeaseReply.Marshal(b, m, deterministic)
+}
+func (dst *DHCPLeaseReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DHCPLeaseReply.Merge(dst, src)
+}
+func (m *DHCPLeaseReply) XXX_Size() int {
+	return xxx_messageInfo_DHCPLeaseReply.Size(m)
+}
+func (m *DHCPLeaseReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_DHCPLeaseReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DHCPLeaseReply proto.InternalMessageInfo
+
+func (m *DHCPLeaseReply) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *DHCPLeaseReply) GetIpv6() bool {
+	if m != nil {
+		return m.Ipv6
+	}
+	return false
+}
+
+func (m *DHCPLeaseReply) GetState() DHCPState {
+	if m != nil {
+		return m.State
+	}
+	return DHCPState_DHCP_UNKNOWN
+}
+
+func (m *DHCPLeaseReply) GetSubnet() *Subnet {
+	if m != nil {
+		return m.Subnet
+	}
+	return nil
+}
+
+func (m *DHCPLeaseReply) GetServer() *IPAddress {
+	if m != nil {
+		return m.Server
+	}
+	return nil
+}
+
+func (m *DHCPLeaseReply) GetLeaseSeconds() uint32 {
+	if m != nil {
+		return m.LeaseSeconds
+	}
+	return 0
+}
+
+func (m *DHCPLeaseReply) GetRenewSeconds() uint32 {
+	if m != nil {
+		return m.RenewSeconds
+	}
+	return 0
+}
+
+func (m *DHCPLeaseReply) GetRebindSeconds() uint32 {
+	if m != nil {
+		return m.RebindSeconds
+	}
+	return 0
+}
+
+func (m *DHCPLeaseReply) GetLeaseObtained() int64 {
+	if m != nil {
+		return m.LeaseObtained
+	}
+	return 0
+}
+
+func (m *DHCPLeaseReply) GetLeaseExpires() int64 {
+	if m != nil {
+		return m.LeaseExpires
+	}
+	return 0
+}
+
+func (m *DHCPLeaseReply) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+type WakeOnLANRequest struct {
+	InterfaceId uint32 `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	MacAddress  []byte `protobuf:"bytes,2,opt,name=mac_address,json=macAddress,proto3" json:"mac_address,omitempty"`
+	// Optional SecureOn password, 4 or 6 bytes
+	Password             []byte   `protobuf:"bytes,3,opt,name=password,proto3" json:"password,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WakeOnLANRequest) Reset()         { *m = WakeOnLANRequest{} }
+func (m *WakeOnLANRequest) String() string { return proto.CompactTextString(m) }
+func (*WakeOnLANRequest) ProtoMessage()    {}
+func (*WakeOnLANRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{19}
+}
+func (m *WakeOnLANRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WakeOnLANRequest.Unmarshal(m, b)
+}
+func (m *WakeOnLANRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WakeOnLANRequest.Marshal(b, m, deterministic)
+}
+func (dst *WakeOnLANRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WakeOnLANRequest.Merge(dst, src)
+}
+func (m *WakeOnLANRequest) XXX_Size() int {
+	return xxx_messageInfo_WakeOnLANRequest.Size(m)
+}
+func (m *WakeOnLANRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WakeOnLANRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WakeOnLANRequest proto.InternalMessageInfo
+
+func (m *WakeOnLANRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *WakeOnLANRequest) GetMacAddress() []byte {
+	if m != nil {
+		return m.MacAddress
+	}
+	return nil
+}
+
+func (m *WakeOnLANRequest) GetPassword() []byte {
+	if m != nil {
+		return m.Password
+	}
+	return nil
+}
+
+type PortStatisticsRequest struct {
+	InterfaceId          uint32   `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PortStatisticsRequest) Reset()         { *m = PortStatisticsRequest{} }
+func (m *PortStatisticsRequest) String() string { return proto.CompactTextString(m) }
+func (*PortStatisticsRequest) ProtoMessage()    {}
+func (*PortStatisticsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{20}
+}
+func (m *PortStatisticsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PortStatisticsRequest.Unmarshal(m, b)
+}
+func (m *PortStatisticsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PortStatisticsRequest.Marshal(b, m, deterministic)
+}
+func (dst *PortStatisticsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PortStatisticsRequest.Merge(dst, src)
+}
+func (m *PortStatisticsRequest) XXX_Size() int {
+	return xxx_messageInfo_PortStatisticsRequest.Size(m)
+}
+func (m *PortStatisticsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PortStatisticsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PortStatisticsRequest proto.InternalMessageInfo
+
+func (m *PortStatisticsRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+type Counter struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value                uint64   `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Counter) Reset()         { *m = Counter{} }
+func (m *Counter) String() string { return proto.CompactTextString(m) }
+func (*Counter) ProtoMessage()    {}
+func (*Counter) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{21}
+}
+func (m *Counter) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Counter.Unmarshal(m, b)
+}
+func (m *Counter) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Counter.Marshal(b, m, deterministic)
+}
+func (dst *Counter) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Counter.Merge(dst, src)
+}
+func (m *Counter) XXX_Size() int {
+	return xxx_messageInfo_Counter.Size(m)
+}
+func (m *Counter) XXX_DiscardUnknown() {
+	xxx_messageInfo_Counter.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Counter proto.InternalMessageInfo
+
+func (m *Counter) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Counter) GetValue() uint64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+type PortStatisticsReply struct {
+	InterfaceId uint32 `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	// Packet counters of NAT
+	NatCounters []*Counter `protobuf:"bytes,2,rep,name=nat_counters,json=natCounters,proto3" json:"nat_counters,omitempty"`
+	// Extended statistics of network card reported by DPDK driver
+	NicCounters          []*Counter `protobuf:"bytes,3,rep,name=nic_counters,json=nicCounters,proto3" json:"nic_counters,omitempty"`
+	Tenant               string     `protobuf:"bytes,4,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *PortStatisticsReply) Reset()         { *m = PortStatisticsReply{} }
+func (m *PortStatisticsReply) String() string { return proto.CompactTextString(m) }
+func (*PortStatisticsReply) ProtoMessage()    {}
+func (*PortStatisticsReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{22}
+}
+func (m *PortStatisticsReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PortStatisticsReply.Unmarshal(m, b)
+}
+func (m *PortStatisticsReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PortStatisticsReply.Marshal(b, m, deterministic)
+}
+func (dst *PortStatisticsReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PortStatisticsReply.Merge(dst, src)
+}
+func (m *PortStatisticsReply) XXX_Size() int {
+	return xxx_messageInfo_PortStatisticsReply.Size(m)
+}
+func (m *PortStatisticsReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_PortStatisticsReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PortStatisticsReply proto.InternalMessageInfo
+
+func (m *PortStatisticsReply) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *PortStatisticsReply) GetNatCounters() []*Counter {
+	if m != nil {
+		return m.NatCounters
+	}
+	return nil
+}
+
+func (m *PortStatisticsReply) GetNicCounters() []*Counter {
+	if m != nil {
+		return m.NicCounters
+	}
+	return nil
+}
+
+func (m *PortStatisticsReply) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+type LinkStatusRequest struct {
+	InterfaceId          uint32   `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LinkStatusRequest) Reset()         { *m = LinkStatusRequest{} }
+func (m *LinkStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*LinkStatusRequest) ProtoMessage()    {}
+func (*LinkStatusRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{23}
+}
+func (m *LinkStatusRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LinkStatusRequest.Unmarshal(m, b)
+}
+func (m *LinkStatusRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LinkStatusRequest.Marshal(b, m, deterministic)
+}
+func (dst *LinkStatusRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LinkStatusRequest.Merge(dst, src)
+}
+func (m *LinkStatusRequest) XXX_Size() int {
+	return xxx_messageInfo_LinkStatusRequest.Size(m)
+}
+func (m *LinkStatusRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LinkStatusRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LinkStatusRequest proto.InternalMessageInfo
+
+func (m *LinkStatusRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+type LinkStatusReply struct {
+	InterfaceId          uint32   `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	Up                   bool     `protobuf:"varint,2,opt,name=up,proto3" json:"up,omitempty"`
+	SpeedMbps            uint32   `protobuf:"varint,3,opt,name=speed_mbps,json=speedMbps,proto3" json:"speed_mbps,omitempty"`
+	FullDuplex           bool     `protobuf:"varint,4,opt,name=full_duplex,json=fullDuplex,proto3" json:"full_duplex,omitempty"`
+	Autonegotiation      bool     `protobuf:"varint,5,opt,name=autonegotiation,proto3" json:"autonegotiation,omitempty"`
+	Tenant               string   `protobuf:"bytes,6,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LinkStatusReply) Reset()         { *m = LinkStatusReply{} }
+func (m *LinkStatusReply) String() string { return proto.CompactTextString(m) }
+func (*LinkStatusReply) ProtoMessage()    {}
+func (*LinkStatusReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{24}
+}
+func (m *LinkStatusReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LinkStatusReply.Unmarshal(m, b)
+}
+func (m *LinkStatusReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LinkStatusReply.Marshal(b, m, deterministic)
+}
+func (dst *LinkStatusReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LinkStatusReply.Merge(dst, src)
+}
+func (m *LinkStatusReply) XXX_Size() int {
+	return xxx_messageInfo_LinkStatusReply.Size(m)
+}
+func (m *LinkStatusReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_LinkStatusReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LinkStatusReply proto.InternalMessageInfo
+
+func (m *LinkStatusReply) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *LinkStatusReply) GetUp() bool {
+	if m != nil {
+		return m.Up
+	}
+	return false
+}
+
+func (m *LinkStatusReply) GetSpeedMbps() uint32 {
+	if m != nil {
+		return m.SpeedMbps
+	}
+	return 0
+}
+
+func (m *LinkStatusReply) GetFullDuplex() bool {
+	if m != nil {
+		return m.FullDuplex
+	}
+	return false
+}
+
+func (m *LinkStatusReply) GetAutonegotiation() bool {
+	if m != nil {
+		return m.Autonegotiation
+	}
+	return false
+}
+
+func (m *LinkStatusReply) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+// Rates are in kilobits per second, bursts are in bytes
+type EgressPolicerHost struct {
+	Address              *IPAddress `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Rate                 uint64     `protobuf:"varint,2,opt,name=rate,proto3" json:"rate,omitempty"`
+	Burst                uint64     `protobuf:"varint,3,opt,name=burst,proto3" json:"burst,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *EgressPolicerHost) Reset()         { *m = EgressPolicerHost{} }
+func (m *EgressPolicerHost) String() string { return proto.CompactTextString(m) }
+func (*EgressPolicerHost) ProtoMessage()    {}
+func (*EgressPolicerHost) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{25}
+}
+func (m *EgressPolicerHost) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EgressPolicerHost.Unmarshal(m, b)
+}
+func (m *EgressPolicerHost) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EgressPolicerHost.Marshal(b, m, deterministic)
+}
+func (dst *EgressPolicerHost) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EgressPolicerHost.Merge(dst, src)
+}
+func (m *EgressPolicerHost) XXX_Size() int {
+	return xxx_messageInfo_EgressPolicerHost.Size(m)
+}
+func (m *EgressPolicerHost) XXX_DiscardUnknown() {
+	xxx_messageInfo_EgressPolicerHost.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EgressPolicerHost proto.InternalMessageInfo
+
+func (m *EgressPolicerHost) GetAddress() *IPAddress {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *EgressPolicerHost) GetRate() uint64 {
+	if m != nil {
+		return m.Rate
+	}
+	return 0
+}
+
+func (m *EgressPolicerHost) GetBurst() uint64 {
+	if m != nil {
+		return m.Burst
+	}
+	return 0
+}
+
+type EgressPolicerChangeRequest struct {
+	InterfaceId          uint32               `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	Rate                 uint64               `protobuf:"varint,2,opt,name=rate,proto3" json:"rate,omitempty"`
+	Burst                uint64               `protobuf:"varint,3,opt,name=burst,proto3" json:"burst,omitempty"`
+	HostRate             uint64               `protobuf:"varint,4,opt,name=host_rate,json=hostRate,proto3" json:"host_rate,omitempty"`
+	HostBurst            uint64               `protobuf:"varint,5,opt,name=host_burst,json=hostBurst,proto3" json:"host_burst,omitempty"`
+	Hosts                []*EgressPolicerHost `protobuf:"bytes,6,rep,name=hosts,proto3" json:"hosts,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *EgressPolicerChangeRequest) Reset()         { *m = EgressPolicerChangeRequest{} }
+func (m *EgressPolicerChangeRequest) String() string { return proto.CompactTextString(m) }
+func (*EgressPolicerChangeRequest) ProtoMessage()    {}
+func (*EgressPolicerChangeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{26}
+}
+func (m *EgressPolicerChangeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EgressPolicerChangeRequest.Unmarshal(m, b)
+}
+func (m *EgressPolicerChangeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EgressPolicerChangeRequest.Marshal(b, m, deterministic)
+}
+func (dst *EgressPolicerChangeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EgressPolicerChangeRequest.Merge(dst, src)
+}
+func (m *EgressPolicerChangeRequest) XXX_Size() int {
+	return xxx_messageInfo_EgressPolicerChangeRequest.Size(m)
+}
+func (m *EgressPolicerChangeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_EgressPolicerChangeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EgressPolicerChangeRequest proto.InternalMessageInfo
+
+func (m *EgressPolicerChangeRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *EgressPolicerChangeRequest) GetRate() uint64 {
+	if m != nil {
+		return m.Rate
+	}
+	return 0
+}
+
+func (m *EgressPolicerChangeRequest) GetBurst() uint64 {
+	if m != nil {
+		return m.Burst
+	}
+	return 0
+}
+
+func (m *EgressPolicerChangeRequest) GetHostRate() uint64 {
+	if m != nil {
+		return m.HostRate
+	}
+	return 0
+}
+
+func (m *EgressPolicerChangeRequest) GetHostBurst() uint64 {
+	if m != nil {
+		return m.HostBurst
+	}
+	return 0
+}
+
+func (m *EgressPolicerChangeRequest) GetHosts() []*EgressPolicerHost {
+	if m != nil {
+		return m.Hosts
+	}
+	return nil
+}
+
+type SubscribersRequest struct {
+	InterfaceId          uint32   `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SubscribersRequest) Reset()         { *m = SubscribersRequest{} }
+func (m *SubscribersRequest) String() string { return proto.CompactTextString(m) }
+func (*SubscribersRequest) ProtoMessage()    {}
+func (*SubscribersRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{27}
+}
+func (m *SubscribersRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SubscribersRequest.Unmarshal(m, b)
+}
+func (m *SubscribersRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SubscribersRequest.Marshal(b, m, deterministic)
+}
+func (dst *SubscribersRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SubscribersRequest.Merge(dst, src)
+}
+func (m *SubscribersRequest) XXX_Size() int {
+	return xxx_messageInfo_SubscribersRequest.Size(m)
+}
+func (m *SubscribersRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SubscribersRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SubscribersRequest proto.InternalMessageInfo
+
+func (m *SubscribersRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+// Bytes of packets which conform to committed rate, exceed it but
+// conform to peak rate and exceed peak rate and are dropped
+type PolicerCounters struct {
+	ConformBytes         uint64   `protobuf:"varint,1,opt,name=conform_bytes,json=conformBytes,proto3" json:"conform_bytes,omitempty"`
+	ExceedBytes          uint64   `protobuf:"varint,2,opt,name=exceed_bytes,json=exceedBytes,proto3" json:"exceed_bytes,omitempty"`
+	DropBytes            uint64   `protobuf:"varint,3,opt,name=drop_bytes,json=dropBytes,proto3" json:"drop_bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PolicerCounters) Reset()         { *m = PolicerCounters{} }
+func (m *PolicerCounters) String() string { return proto.CompactTextString(m) }
+func (*PolicerCounters) ProtoMessage()    {}
+func (*PolicerCounters) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{28}
+}
+func (m *PolicerCounters) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PolicerCounters.Unmarshal(m, b)
+}
+func (m *PolicerCounters) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PolicerCounters.Marshal(b, m, deterministic)
+}
+func (dst *PolicerCounters) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PolicerCounters.Merge(dst, src)
+}
+func (m *PolicerCounters) XXX_Size() int {
+	return xxx_messageInfo_PolicerCounters.Size(m)
+}
+func (m *PolicerCounters) XXX_DiscardUnknown() {
+	xxx_messageInfo_PolicerCounters.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PolicerCounters proto.InternalMessageInfo
+
+func (m *PolicerCounters) GetConformBytes() uint64 {
+	if m != nil {
+		return m.ConformBytes
+	}
+	return 0
+}
+
+func (m *PolicerCounters) GetExceedBytes() uint64 {
+	if m != nil {
+		return m.ExceedBytes
+	}
+	return 0
+}
+
+func (m *PolicerCounters) GetDropBytes() uint64 {
+	if m != nil {
+		return m.DropBytes
+	}
+	return 0
+}
+
+type Subscriber struct {
+	Address *IPAddress       `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Egress  *PolicerCounters `protobuf:"bytes,2,opt,name=egress,proto3" json:"egress,omitempty"`
+	Ingress *PolicerCounters `protobuf:"bytes,3,opt,name=ingress,proto3" json:"ingress,omitempty"`
+	// MAC address of private host when host identity tracking knows
+	// it. Subscriber of known MAC address keeps its policers when its
+	// address changes, address is its current address then and is
+	// empty if host has none.
+	MacAddress           []byte   `protobuf:"bytes,4,opt,name=mac_address,json=macAddress,proto3" json:"mac_address,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Subscriber) Reset()         { *m = Subscriber{} }
+func (m *Subscriber) String() string { return proto.CompactTextString(m) }
+func (*Subscriber) ProtoMessage()    {}
+func (*Subscriber) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{29}
+}
+func (m *Subscriber) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Subscriber.Unmarshal(m, b)
+}
+func (m *Subscriber) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Subscriber.Marshal(b, m, deterministic)
+}
+func (dst *Subscriber) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Subscriber.Merge(dst, src)
+}
+func (m *Subscriber) XXX_Size() int {
+	return xxx_messageInfo_Subscriber.Size(m)
+}
+func (m *Subscriber) XXX_DiscardUnknown() {
+	xxx_messageInfo_Subscriber.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Subscriber proto.InternalMessageInfo
+
+func (m *Subscriber) GetAddress() *IPAddress {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *Subscriber) GetEgress() *PolicerCounters {
+	if m != nil {
+		return m.Egress
+	}
+	return nil
+}
+
+func (m *Subscriber) GetIngress() *PolicerCounters {
+	if m != nil {
+		return m.Ingress
+	}
+	return nil
+}
+
+func (m *Subscriber) GetMacAddress() []byte {
+	if m != nil {
+		return m.MacAddress
+	}
+	return nil
+}
+
+type SubscribersReply struct {
+	Subscribers          []*Subscriber `protobuf:"bytes,1,rep,name=subscribers,proto3" json:"subscribers,omitempty"`
+	Tenant               string        `protobuf:"bytes,2,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *SubscribersReply) Reset()         { *m = SubscribersReply{} }
+func (m *SubscribersReply) String() string { return proto.CompactTextString(m) }
+func (*SubscribersReply) ProtoMessage()    {}
+func (*SubscribersReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{30}
+}
+func (m *SubscribersReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SubscribersReply.Unmarshal(m, b)
+}
+func (m *SubscribersReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SubscribersReply.Marshal(b, m, deterministic)
+}
+func (dst *SubscribersReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SubscribersReply.Merge(dst, src)
+}
+func (m *SubscribersReply) XXX_Size() int {
+	return xxx_messageInfo_SubscribersReply.Size(m)
+}
+func (m *SubscribersReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_SubscribersReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SubscribersReply proto.InternalMessageInfo
+
+func (m *SubscribersReply) GetSubscribers() []*Subscriber {
+	if m != nil {
+		return m.Subscribers
+	}
+	return nil
+}
+
+func (m *SubscribersReply) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+type SessionsExportRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SessionsExportRequest) Reset()         { *m = SessionsExportRequest{} }
+func (m *SessionsExportRequest) String() string { return proto.CompactTextString(m) }
+func (*SessionsExportRequest) ProtoMessage()    {}
+func (*SessionsExportRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{31}
+}
+func (m *SessionsExportRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SessionsExportRequest.Unmarshal(m, b)
+}
+func (m *SessionsExportRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SessionsExportRequest.Marshal(b, m, deterministic)
+}
+func (dst *SessionsExportRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SessionsExportRequest.Merge(dst, src)
+}
+func (m *SessionsExportRequest) XXX_Size() int {
+	return xxx_messageInfo_SessionsExportRequest.Size(m)
+}
+func (m *SessionsExportRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SessionsExportRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SessionsExportRequest proto.InternalMessageInfo
+
+type Session struct {
+	// Index of port pair in config
+	Pair uint32 `protobuf:"varint,1,opt,name=pair,proto3" json:"pair,omitempty"`
+	Ipv6 bool   `protobuf:"varint,2,opt,name=ipv6,proto3" json:"ipv6,omitempty"`
+	// IP protocol number
+	Protocol       uint32     `protobuf:"varint,3,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	PublicAddress  *IPAddress `protobuf:"bytes,4,opt,name=public_address,json=publicAddress,proto3" json:"public_address,omitempty"`
+	PublicPort     uint32     `protobuf:"varint,5,opt,name=public_port,json=publicPort,proto3" json:"public_port,omitempty"`
+	PrivateAddress *IPAddress `protobuf:"bytes,6,opt,name=private_address,json=privateAddress,proto3" json:"private_address,omitempty"`
+	PrivatePort    uint32     `protobuf:"varint,7,opt,name=private_port,json=privatePort,proto3" json:"private_port,omitempty"`
+	// Time of last packet in nanoseconds since Unix epoch
+	LastUsed             int64  `protobuf:"varint,8,opt,name=last_used,json=lastUsed,proto3" json:"last_used,omitempty"`
+	FinCount             uint32 `protobuf:"varint,9,opt,name=fin_count,json=finCount,proto3" json:"fin_count,omitempty"`
+	TerminationDirection uint32 `protobuf:"varint,10,opt,name=termination_direction,json=terminationDirection,proto3" json:"termination_direction,omitempty"`
+	// Tenant of port pair, sessions are not imported into port pair
+	// of other tenant
+	Tenant string `protobuf:"bytes,11,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	// Country of remote host when country accounting is enabled,
+	// ignored by import
+	Country string `protobuf:"bytes,12,opt,name=country,proto3" json:"country,omitempty"`
+	// Fields below are set only by FindSessions and ignored by import.
+	// Remote host which dynamic session was created for, if it is
+	// known
+	RemoteAddress *IPAddress `protobuf:"bytes,13,opt,name=remote_address,json=remoteAddress,proto3" json:"remote_address,omitempty"`
+	RemotePort    uint32     `protobuf:"varint,14,opt,name=remote_port,json=remotePort,proto3" json:"remote_port,omitempty"`
+	// Session is a forwarded port, private address of forwarded port
+	// sent to KNI interface is empty
+	Static bool `protobuf:"varint,15,opt,name=static,proto3" json:"static,omitempty"`
+	// Time of session creation in nanoseconds since Unix epoch
+	Created int64 `protobuf:"varint,16,opt,name=created,proto3" json:"created,omitempty"`
+	// Translated packets and bytes in both directions
+	Packets uint64 `protobuf:"varint,17,opt,name=packets,proto3" json:"packets,omitempty"`
+	Bytes   uint64 `protobuf:"varint,18,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	// Milliseconds until dynamic session expires unless it is used
+	// again, zero for forwarded ports. Idle sessions may be reclaimed
+	// earlier by port sharing. Set by ExportSessions too, ignored by
+	// import.
+	RemainingLifetimeMs uint32 `protobuf:"varint,19,opt,name=remaining_lifetime_ms,json=remainingLifetimeMs,proto3" json:"remaining_lifetime_ms,omitempty"`
+	// MAC address of private host when host identity tracking knows
+	// it, ignored by import
+	PrivateMacAddress    []byte   `protobuf:"bytes,20,opt,name=private_mac_address,json=privateMacAddress,proto3" json:"private_mac_address,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Session) Reset()         { *m = Session{} }
+func (m *Session) String() string { return proto.CompactTextString(m) }
+func (*Session) ProtoMessage()    {}
+func (*Session) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{32}
+}
+func (m *Session) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Session.Unmarshal(m, b)
+}
+func (m *Session) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Session.Marshal(b, m, deterministic)
+}
+func (dst *Session) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Session.Merge(dst, src)
+}
+func (m *Session) XXX_Size() int {
+	return xxx_messageInfo_Session.Size(m)
+}
+func (m *Session) XXX_DiscardUnknown() {
+	xxx_messageInfo_Session.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Session proto.InternalMessageInfo
+
+func (m *Session) GetPair() uint32 {
+	if m != nil {
+		return m.Pair
+	}
+	return 0
+}
+
+func (m *Session) GetIpv6() bool {
+	if m != nil {
+		return m.Ipv6
+	}
+	return false
+}
+
+func (m *Session) GetProtocol() uint32 {
+	if m != nil {
+		return m.Protocol
+	}
+	return 0
+}
+
+func (m *Session) GetPublicAddress() *IPAddress {
+	if m != nil {
+		return m.PublicAddress
+	}
+	return nil
+}
+
+func (m *Session) GetPublicPort() uint32 {
+	if m != nil {
+		return m.PublicPort
+	}
+	return 0
+}
+
+func (m *Session) GetPrivateAddress() *IPAddress {
+	if m != nil {
+		return m.PrivateAddress
+	}
+	return nil
+}
+
+func (m *Session) GetPrivatePort() uint32 {
+	if m != nil {
+		return m.PrivatePort
+	}
+	return 0
+}
+
+func (m *Session) GetLastUsed() int64 {
+	if m != nil {
+		return m.LastUsed
+	}
+	return 0
+}
+
+func (m *Session) GetFinCount() uint32 {
+	if m != nil {
+		return m.FinCount
+	}
+	return 0
+}
+
+func (m *Session) GetTerminationDirection() uint32 {
+	if m != nil {
+		return m.TerminationDirection
+	}
+	return 0
+}
+
+func (m *Session) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+func (m *Session) GetCountry() string {
+	if m != nil {
+		return m.Country
+	}
+	return ""
+}
+
+func (m *Session) GetRemoteAddress() *IPAddress {
+	if m != nil {
+		return m.RemoteAddress
+	}
+	return nil
+}
+
+func (m *Session) GetRemotePort() uint32 {
+	if m != nil {
+		return m.RemotePort
+	}
+	return 0
+}
+
+func (m *Session) GetStatic() bool {
+	if m != nil {
+		return m.Static
+	}
+	return false
+}
+
+func (m *Session) GetCreated() int64 {
+	if m != nil {
+		return m.Created
+	}
+	return 0
+}
+
+func (m *Session) GetPackets() uint64 {
+	if m != nil {
+		return m.Packets
+	}
+	return 0
+}
+
+func (m *Session) GetBytes() uint64 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+func (m *Session) GetRemainingLifetimeMs() uint32 {
+	if m != nil {
+		return m.RemainingLifetimeMs
+	}
+	return 0
+}
+
+func (m *Session) GetPrivateMacAddress() []byte {
+	if m != nil {
+		return m.PrivateMacAddress
+	}
+	return nil
+}
+
+// Dynamic sessions of NAT, also used as contents of session snapshot
+// files
+type SessionSnapshot struct {
+	HostName string `protobuf:"bytes,1,opt,name=host_name,json=hostName,proto3" json:"host_name,omitempty"`
+	// Time of export in nanoseconds since Unix epoch
+	Time                 int64      `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"`
+	Sessions             []*Session `protobuf:"bytes,3,rep,name=sessions,proto3" json:"sessions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *SessionSnapshot) Reset()         { *m = SessionSnapshot{} }
+func (m *SessionSnapshot) String() string { return proto.CompactTextString(m) }
+func (*SessionSnapshot) ProtoMessage()    {}
+func (*SessionSnapshot) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{33}
+}
+func (m *SessionSnapshot) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SessionSnapshot.Unmarshal(m, b)
+}
+func (m *SessionSnapshot) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SessionSnapshot.Marshal(b, m, deterministic)
+}
+func (dst *SessionSnapshot) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SessionSnapshot.Merge(dst, src)
+}
+func (m *SessionSnapshot) XXX_Size() int {
+	return xxx_messageInfo_SessionSnapshot.Size(m)
+}
+func (m *SessionSnapshot) XXX_DiscardUnknown() {
+	xxx_messageInfo_SessionSnapshot.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SessionSnapshot proto.InternalMessageInfo
+
+func (m *SessionSnapshot) GetHostName() string {
+	if m != nil {
+		return m.HostName
+	}
+	return ""
+}
+
+func (m *SessionSnapshot) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *SessionSnapshot) GetSessions() []*Session {
+	if m != nil {
+		return m.Sessions
+	}
+	return nil
+}
+
+type TopTalkersRequest struct {
+	InterfaceId uint32 `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	// Maximum number of hosts and destinations, zero means all tracked
+	Count                uint32   `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TopTalkersRequest) Reset()         { *m = TopTalkersRequest{} }
+func (m *TopTalkersRequest) String() string { return proto.CompactTextString(m) }
+func (*TopTalkersRequest) ProtoMessage()    {}
+func (*TopTalkersRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{34}
+}
+func (m *TopTalkersRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TopTalkersRequest.Unmarshal(m, b)
+}
+func (m *TopTalkersRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TopTalkersRequest.Marshal(b, m, deterministic)
+}
+func (dst *TopTalkersRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TopTalkersRequest.Merge(dst, src)
+}
+func (m *TopTalkersRequest) XXX_Size() int {
+	return xxx_messageInfo_TopTalkersRequest.Size(m)
+}
+func (m *TopTalkersRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TopTalkersRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TopTalkersRequest proto.InternalMessageInfo
+
+func (m *TopTalkersRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *TopTalkersRequest) GetCount() uint32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+// Translated traffic of IP protocol
+type ProtocolCounters struct {
+	// IP protocol number
+	Protocol             uint32   `protobuf:"varint,1,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	EgressPackets        uint64   `protobuf:"varint,2,opt,name=egress_packets,json=egressPackets,proto3" json:"egress_packets,omitempty"`
+	EgressBytes          uint64   `protobuf:"varint,3,opt,name=egress_bytes,json=egressBytes,proto3" json:"egress_bytes,omitempty"`
+	IngressPackets       uint64   `protobuf:"varint,4,opt,name=ingress_packets,json=ingressPackets,proto3" json:"ingress_packets,omitempty"`
+	IngressBytes         uint64   `protobuf:"varint,5,opt,name=ingress_bytes,json=ingressBytes,proto3" json:"ingress_bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ProtocolCounters) Reset()         { *m = ProtocolCounters{} }
+func (m *ProtocolCounters) String() string { return proto.CompactTextString(m) }
+func (*ProtocolCounters) ProtoMessage()    {}
+func (*ProtocolCounters) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{35}
+}
+func (m *ProtocolCounters) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ProtocolCounters.Unmarshal(m, b)
+}
+func (m *ProtocolCounters) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ProtocolCounters.Marshal(b, m, deterministic)
+}
+func (dst *ProtocolCounters) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ProtocolCounters.Merge(dst, src)
+}
+func (m *ProtocolCounters) XXX_Size() int {
+	return xxx_messageInfo_ProtocolCounters.Size(m)
+}
+func (m *ProtocolCounters) XXX_DiscardUnknown() {
+	xxx_messageInfo_ProtocolCounters.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ProtocolCounters proto.InternalMessageInfo
+
+func (m *ProtocolCounters) GetProtocol() uint32 {
+	if m != nil {
+		return m.Protocol
+	}
+	return 0
+}
+
+func (m *ProtocolCounters) GetEgressPackets() uint64 {
+	if m != nil {
+		return m.EgressPackets
+	}
+	return 0
+}
+
+func (m *ProtocolCounters) GetEgressBytes() uint64 {
+	if m != nil {
+		return m.EgressBytes
+	}
+	return 0
+}
+
+func (m *ProtocolCounters) GetIngressPackets() uint64 {
+	if m != nil {
+		return m.IngressPackets
+	}
+	return 0
+}
+
+func (m *ProtocolCounters) GetIngressBytes() uint64 {
+	if m != nil {
+		return m.IngressBytes
+	}
+	return 0
+}
+
+// Traffic of private host or destination in both directions. Counters
+// may include up to error packets and bytes of other addresses.
+type Talker struct {
+	Address              *IPAddress `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Packets              uint64     `protobuf:"varint,2,opt,name=packets,proto3" json:"packets,omitempty"`
+	Bytes                uint64     `protobuf:"varint,3,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	ErrorPackets         uint64     `protobuf:"varint,4,opt,name=error_packets,json=errorPackets,proto3" json:"error_packets,omitempty"`
+	ErrorBytes           uint64     `protobuf:"varint,5,opt,name=error_bytes,json=errorBytes,proto3" json:"error_bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *Talker) Reset()         { *m = Talker{} }
+func (m *Talker) String() string { return proto.CompactTextString(m) }
+func (*Talker) ProtoMessage()    {}
+func (*Talker) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{36}
+}
+func (m *Talker) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Talker.Unmarshal(m, b)
+}
+func (m *Talker) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Talker.Marshal(b, m, deterministic)
+}
+func (dst *Talker) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Talker.Merge(dst, src)
+}
+func (m *Talker) XXX_Size() int {
+	return xxx_messageInfo_Talker.Size(m)
+}
+func (m *Talker) XXX_DiscardUnknown() {
+	xxx_messageInfo_Talker.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Talker proto.InternalMessageInfo
+
+func (m *Talker) GetAddress() *IPAddress {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *Talker) GetPackets() uint64 {
+	if m != nil {
+		return m.Packets
+	}
+	return 0
+}
+
+func (m *Talker) GetBytes() uint64 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+func (m *Talker) GetErrorPackets() uint64 {
+	if m != nil {
+		return m.ErrorPackets
+	}
+	return 0
+}
+
+func (m *Talker) GetErrorBytes() uint64 {
+	if m != nil {
+		return m.ErrorBytes
+	}
+	return 0
+}
+
+type TopTalkersReply struct {
+	Protocols []*ProtocolCounters `protobuf:"bytes,1,rep,name=protocols,proto3" json:"protocols,omitempty"`
+	// Private hosts and public destinations with most bytes
+	Hosts                []*Talker `protobuf:"bytes,2,rep,name=hosts,proto3" json:"hosts,omitempty"`
+	Destinations         []*Talker `protobuf:"bytes,3,rep,name=destinations,proto3" json:"destinations,omitempty"`
+	Tenant               string    `protobuf:"bytes,4,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *TopTalkersReply) Reset()         { *m = TopTalkersReply{} }
+func (m *TopTalkersReply) String() string { return proto.CompactTextString(m) }
+func (*TopTalkersReply) ProtoMessage()    {}
+func (*TopTalkersReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{37}
+}
+func (m *TopTalkersReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TopTalkersReply.Unmarshal(m, b)
+}
+func (m *TopTalkersReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TopTalkersReply.Marshal(b, m, deterministic)
+}
+func (dst *TopTalkersReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TopTalkersReply.Merge(dst, src)
+}
+func (m *TopTalkersReply) XXX_Size() int {
+	return xxx_messageInfo_TopTalkersReply.Size(m)
+}
+func (m *TopTalkersReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_TopTalkersReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TopTalkersReply proto.InternalMessageInfo
+
+func (m *TopTalkersReply) GetProtocols() []*ProtocolCounters {
+	if m != nil {
+		return m.Protocols
+	}
+	return nil
+}
+
+func (m *TopTalkersReply) GetHosts() []*Talker {
+	if m != nil {
+		return m.Hosts
+	}
+	return nil
+}
+
+func (m *TopTalkersReply) GetDestinations() []*Talker {
+	if m != nil {
+		return m.Destinations
+	}
+	return nil
+}
+
+func (m *TopTalkersReply) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+type RouteAnnouncementRequest struct {
+	InterfaceId uint32 `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	// Announce public addresses when true, withdraw them otherwise
+	Announce             bool     `protobuf:"varint,2,opt,name=announce,proto3" json:"announce,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RouteAnnouncementRequest) Reset()         { *m = RouteAnnouncementRequest{} }
+func (m *RouteAnnouncementRequest) String() string { return proto.CompactTextString(m) }
+func (*RouteAnnouncementRequest) ProtoMessage()    {}
+func (*RouteAnnouncementRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{38}
+}
+func (m *RouteAnnouncementRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RouteAnnouncementRequest.Unmarshal(m, b)
+}
+func (m *RouteAnnouncementRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RouteAnnouncementRequest.Marshal(b, m, deterministic)
+}
+func (dst *RouteAnnouncementRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RouteAnnouncementRequest.Merge(dst, src)
+}
+func (m *RouteAnnouncementRequest) XXX_Size() int {
+	return xxx_messageInfo_RouteAnnouncementRequest.Size(m)
+}
+func (m *RouteAnnouncementRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RouteAnnouncementRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RouteAnnouncementRequest proto.InternalMessageInfo
+
+func (m *RouteAnnouncementRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *RouteAnnouncementRequest) GetAnnounce() bool {
+	if m != nil {
+		return m.Announce
+	}
+	return false
+}
+
+// Dynamic session is identified by public address and port when
+// interface is public port or by private address and port when it is
+// private port of port pair
+type SessionDeleteRequest struct {
+	InterfaceId uint32 `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	// IP protocol number of TCP, UDP, ICMP or ICMPv6
+	Protocol uint32     `protobuf:"varint,2,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	Address  *IPAddress `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	// Port or ICMP query identifier
+	Port                 uint32   `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SessionDeleteRequest) Reset()         { *m = SessionDeleteRequest{} }
+func (m *SessionDeleteRequest) String() string { return proto.CompactTextString(m) }
+func (*SessionDeleteRequest) ProtoMessage()    {}
+func (*SessionDeleteRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{39}
+}
+func (m *SessionDeleteRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SessionDeleteRequest.Unmarshal(m, b)
+}
+func (m *SessionDeleteRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SessionDeleteRequest.Marshal(b, m, deterministic)
+}
+func (dst *SessionDeleteRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SessionDeleteRequest.Merge(dst, src)
+}
+func (m *SessionDeleteRequest) XXX_Size() int {
+	return xxx_messageInfo_SessionDeleteRequest.Size(m)
+}
+func (m *SessionDeleteRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SessionDeleteRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SessionDeleteRequest proto.InternalMessageInfo
+
+func (m *SessionDeleteRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *SessionDeleteRequest) GetProtocol() uint32 {
+	if m != nil {
+		return m.Protocol
+	}
+	return 0
+}
+
+func (m *SessionDeleteRequest) GetAddress() *IPAddress {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *SessionDeleteRequest) GetPort() uint32 {
+	if m != nil {
+		return m.Port
+	}
+	return 0
+}
+
+type BlackholeRule struct {
+	// Prefix, address or domain name of destination
+	Destination string `protobuf:"bytes,1,opt,name=destination,proto3" json:"destination,omitempty"`
+	// Either "drop" or "kni", empty means "drop"
+	Action string `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"`
+	// Packets of private hosts which matched rule
+	Packets uint64 `protobuf:"varint,3,opt,name=packets,proto3" json:"packets,omitempty"`
+	// Resolved addresses of domain name
+	Addresses            []*IPAddress `protobuf:"bytes,4,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *BlackholeRule) Reset()         { *m = BlackholeRule{} }
+func (m *BlackholeRule) String() string { return proto.CompactTextString(m) }
+func (*BlackholeRule) ProtoMessage()    {}
+func (*BlackholeRule) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{40}
+}
+func (m *BlackholeRule) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BlackholeRule.Unmarshal(m, b)
+}
+func (m *BlackholeRule) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BlackholeRule.Marshal(b, m, deterministic)
+}
+func (dst *BlackholeRule) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BlackholeRule.Merge(dst, src)
+}
+func (m *BlackholeRule) XXX_Size() int {
+	return xxx_messageInfo_BlackholeRule.Size(m)
+}
+func (m *BlackholeRule) XXX_DiscardUnknown() {
+	xxx_messageInfo_BlackholeRule.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BlackholeRule proto.InternalMessageInfo
+
+func (m *BlackholeRule) GetDestination() string {
+	if m != nil {
+		return m.Destination
+	}
+	return ""
+}
+
+func (m *BlackholeRule) GetAction() string {
+	if m != nil {
+		return m.Action
+	}
+	return ""
+}
+
+func (m *BlackholeRule) GetPackets() uint64 {
+	if m != nil {
+		return m.Packets
+	}
+	return 0
+}
+
+func (m *BlackholeRule) GetAddresses() []*IPAddress {
+	if m != nil {
+		return m.Addresses
+	}
+	return nil
+}
+
+type BlackholeRuleChangeRequest struct {
+	Enable               bool           `protobuf:"varint,1,opt,name=enable,proto3" json:"enable,omitempty"`
+	InterfaceId          uint32         `protobuf:"varint,2,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	Rule                 *BlackholeRule `protobuf:"bytes,3,opt,name=rule,proto3" json:"rule,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *BlackholeRuleChangeRequest) Reset()         { *m = BlackholeRuleChangeRequest{} }
+func (m *BlackholeRuleChangeRequest) String() string { return proto.CompactTextString(m) }
+func (*BlackholeRuleChangeRequest) ProtoMessage()    {}
+func (*BlackholeRuleChangeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{41}
+}
+func (m *BlackholeRuleChangeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BlackholeRuleChangeRequest.Unmarshal(m, b)
+}
+func (m *BlackholeRuleChangeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BlackholeRuleChangeRequest.Marshal(b, m, deterministic)
+}
+func (dst *BlackholeRuleChangeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BlackholeRuleChangeRequest.Merge(dst, src)
+}
+func (m *BlackholeRuleChangeRequest) XXX_Size() int {
+	return xxx_messageInfo_BlackholeRuleChangeRequest.Size(m)
+}
+func (m *BlackholeRuleChangeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BlackholeRuleChangeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BlackholeRuleChangeRequest proto.InternalMessageInfo
+
+func (m *BlackholeRuleChangeRequest) GetEnable() bool {
+	if m != nil {
+		return m.Enable
+	}
+	return false
+}
+
+func (m *BlackholeRuleChangeRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *BlackholeRuleChangeRequest) GetRule() *BlackholeRule {
+	if m != nil {
+		return m.Rule
+	}
+	return nil
+}
+
+type BlackholeRulesRequest struct {
+	InterfaceId          uint32   `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BlackholeRulesRequest) Reset()         { *m = BlackholeRulesRequest{} }
+func (m *BlackholeRulesRequest) String() string { return proto.CompactTextString(m) }
+func (*BlackholeRulesRequest) ProtoMessage()    {}
+func (*BlackholeRulesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{42}
+}
+func (m *BlackholeRulesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BlackholeRulesRequest.Unmarshal(m, b)
+}
+func (m *BlackholeRulesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BlackholeRulesRequest.Marshal(b, m, deterministic)
+}
+func (dst *BlackholeRulesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BlackholeRulesRequest.Merge(dst, src)
+}
+func (m *BlackholeRulesRequest) XXX_Size() int {
+	return xxx_messageInfo_BlackholeRulesRequest.Size(m)
+}
+func (m *BlackholeRulesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BlackholeRulesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BlackholeRulesRequest proto.InternalMessageInfo
+
+func (m *BlackholeRulesRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+type BlackholeRulesReply struct {
+	Rules                []*BlackholeRule `protobuf:"bytes,1,rep,name=rules,proto3" json:"rules,omitempty"`
+	Tenant               string           `protobuf:"bytes,2,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *BlackholeRulesReply) Reset()         { *m = BlackholeRulesReply{} }
+func (m *BlackholeRulesReply) String() string { return proto.CompactTextString(m) }
+func (*BlackholeRulesReply) ProtoMessage()    {}
+func (*BlackholeRulesReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{43}
+}
+func (m *BlackholeRulesReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BlackholeRulesReply.Unmarshal(m, b)
+}
+func (m *BlackholeRulesReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BlackholeRulesReply.Marshal(b, m, deterministic)
+}
+func (dst *BlackholeRulesReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BlackholeRulesReply.Merge(dst, src)
+}
+func (m *BlackholeRulesReply) XXX_Size() int {
+	return xxx_messageInfo_BlackholeRulesReply.Size(m)
+}
+func (m *BlackholeRulesReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_BlackholeRulesReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BlackholeRulesReply proto.InternalMessageInfo
+
+func (m *BlackholeRulesReply) GetRules() []*BlackholeRule {
+	if m != nil {
+		return m.Rules
+	}
+	return nil
+}
+
+func (m *BlackholeRulesReply) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+type ApplicationsRequest struct {
+	InterfaceId          uint32   `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ApplicationsRequest) Reset()         { *m = ApplicationsRequest{} }
+func (m *ApplicationsRequest) String() string { return proto.CompactTextString(m) }
+func (*ApplicationsRequest) ProtoMessage()    {}
+func (*ApplicationsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{44}
+}
+func (m *ApplicationsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ApplicationsRequest.Unmarshal(m, b)
+}
+func (m *ApplicationsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ApplicationsRequest.Marshal(b, m, deterministic)
+}
+func (dst *ApplicationsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ApplicationsRequest.Merge(dst, src)
+}
+func (m *ApplicationsRequest) XXX_Size() int {
+	return xxx_messageInfo_ApplicationsRequest.Size(m)
+}
+func (m *ApplicationsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ApplicationsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ApplicationsRequest proto.InternalMessageInfo
+
+func (m *ApplicationsRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+// Translated traffic of sessions tagged with application
+type ApplicationCounters struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Sessions tagged since start and currently active dynamic sessions
+	Sessions             uint64   `protobuf:"varint,2,opt,name=sessions,proto3" json:"sessions,omitempty"`
+	ActiveSessions       uint32   `protobuf:"varint,3,opt,name=active_sessions,json=activeSessions,proto3" json:"active_sessions,omitempty"`
+	EgressPackets        uint64   `protobuf:"varint,4,opt,name=egress_packets,json=egressPackets,proto3" json:"egress_packets,omitempty"`
+	EgressBytes          uint64   `protobuf:"varint,5,opt,name=egress_bytes,json=egressBytes,proto3" json:"egress_bytes,omitempty"`
+	IngressPackets       uint64   `protobuf:"varint,6,opt,name=ingress_packets,json=ingressPackets,proto3" json:"ingress_packets,omitempty"`
+	IngressBytes         uint64   `protobuf:"varint,7,opt,name=ingress_bytes,json=ingressBytes,proto3" json:"ingress_bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ApplicationCounters) Reset()         { *m = ApplicationCounters{} }
+func (m *ApplicationCounters) String() string { return proto.CompactTextString(m) }
+func (*ApplicationCounters) ProtoMessage()    {}
+func (*ApplicationCounters) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{45}
+}
+func (m *ApplicationCounters) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ApplicationCounters.Unmarshal(m, b)
+}
+func (m *ApplicationCounters) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ApplicationCounters.Marshal(b, m, deterministic)
+}
+func (dst *ApplicationCounters) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ApplicationCounters.Merge(dst, src)
+}
+func (m *ApplicationCounters) XXX_Size() int {
+	return xxx_messageInfo_ApplicationCounters.Size(m)
+}
+func (m *ApplicationCounters) XXX_DiscardUnknown() {
+	xxx_messageInfo_ApplicationCounters.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ApplicationCounters proto.InternalMessageInfo
+
+func (m *ApplicationCounters) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ApplicationCounters) GetSessions() uint64 {
+	if m != nil {
+		return m.Sessions
+	}
+	return 0
+}
+
+func (m *ApplicationCounters) GetActiveSessions() uint32 {
+	if m != nil {
+		return m.ActiveSessions
+	}
+	return 0
+}
+
+func (m *ApplicationCounters) GetEgressPackets() uint64 {
+	if m != nil {
+		return m.EgressPackets
+	}
+	return 0
+}
+
+func (m *ApplicationCounters) GetEgressBytes() uint64 {
+	if m != nil {
+		return m.EgressBytes
+	}
+	return 0
+}
+
+func (m *ApplicationCounters) GetIngressPackets() uint64 {
+	if m != nil {
+		return m.IngressPackets
+	}
+	return 0
+}
+
+func (m *ApplicationCounters) GetIngressBytes() uint64 {
+	if m != nil {
+		return m.IngressBytes
+	}
+	return 0
+}
+
+type ApplicationsReply struct {
+	Applications         []*ApplicationCounters `protobuf:"bytes,1,rep,name=applications,proto3" json:"applications,omitempty"`
+	Tenant               string                 `protobuf:"bytes,2,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
+}
+
+func (m *ApplicationsReply) Reset()         { *m = ApplicationsReply{} }
+func (m *ApplicationsReply) String() string { return proto.CompactTextString(m) }
+func (*ApplicationsReply) ProtoMessage()    {}
+func (*ApplicationsReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{46}
+}
+func (m *ApplicationsReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ApplicationsReply.Unmarshal(m, b)
+}
+func (m *ApplicationsReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ApplicationsReply.Marshal(b, m, deterministic)
+}
+func (dst *ApplicationsReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ApplicationsReply.Merge(dst, src)
+}
+func (m *ApplicationsReply) XXX_Size() int {
+	return xxx_messageInfo_ApplicationsReply.Size(m)
+}
+func (m *ApplicationsReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_ApplicationsReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ApplicationsReply proto.InternalMessageInfo
+
+func (m *ApplicationsReply) GetApplications() []*ApplicationCounters {
+	if m != nil {
+		return m.Applications
+	}
+	return nil
+}
+
+func (m *ApplicationsReply) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+type CountriesRequest struct {
+	InterfaceId          uint32   `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CountriesRequest) Reset()         { *m = CountriesRequest{} }
+func (m *CountriesRequest) String() string { return proto.CompactTextString(m) }
+func (*CountriesRequest) ProtoMessage()    {}
+func (*CountriesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{47}
+}
+func (m *CountriesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CountriesRequest.Unmarshal(m, b)
+}
+func (m *CountriesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CountriesRequest.Marshal(b, m, deterministic)
+}
+func (dst *CountriesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CountriesRequest.Merge(dst, src)
+}
+func (m *CountriesRequest) XXX_Size() int {
+	return xxx_messageInfo_CountriesRequest.Size(m)
+}
+func (m *CountriesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CountriesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CountriesRequest proto.InternalMessageInfo
+
+func (m *CountriesRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+// Traffic of remote hosts of country
+type CountryCounters struct {
+	// ISO 3166 country code or "unknown"
+	Country        string `protobuf:"bytes,1,opt,name=country,proto3" json:"country,omitempty"`
+	Sessions       uint64 `protobuf:"varint,2,opt,name=sessions,proto3" json:"sessions,omitempty"`
+	EgressPackets  uint64 `protobuf:"varint,3,opt,name=egress_packets,json=egressPackets,proto3" json:"egress_packets,omitempty"`
+	EgressBytes    uint64 `protobuf:"varint,4,opt,name=egress_bytes,json=egressBytes,proto3" json:"egress_bytes,omitempty"`
+	IngressPackets uint64 `protobuf:"varint,5,opt,name=ingress_packets,json=ingressPackets,proto3" json:"ingress_packets,omitempty"`
+	IngressBytes   uint64 `protobuf:"varint,6,opt,name=ingress_bytes,json=ingressBytes,proto3" json:"ingress_bytes,omitempty"`
+	// Packets denied by country policy
+	DeniedPackets        uint64   `protobuf:"varint,7,opt,name=denied_packets,json=deniedPackets,proto3" json:"denied_packets,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CountryCounters) Reset()         { *m = CountryCounters{} }
+func (m *CountryCounters) String() string { return proto.CompactTextString(m) }
+func (*CountryCounters) ProtoMessage()    {}
+func (*CountryCounters) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{48}
+}
+func (m *CountryCounters) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CountryCounters.Unmarshal(m, b)
+}
+func (m *CountryCounters) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CountryCounters.Marshal(b, m, deterministic)
+}
+func (dst *CountryCounters) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CountryCounters.Merge(dst, src)
+}
+func (m *CountryCounters) XXX_Size() int {
+	return xxx_messageInfo_CountryCounters.Size(m)
+}
+func (m *CountryCounters) XXX_DiscardUnknown() {
+	xxx_messageInfo_CountryCounters.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CountryCounters proto.InternalMessageInfo
+
+func (m *CountryCounters) GetCountry() string {
+	if m != nil {
+		return m.Country
+	}
+	return ""
+}
+
+func (m *CountryCounters) GetSessions() uint64 {
+	if m != nil {
+		return m.Sessions
+	}
+	return 0
+}
+
+func (m *CountryCounters) GetEgressPackets() uint64 {
+	if m != nil {
+		return m.EgressPackets
+	}
+	return 0
+}
+
+func (m *CountryCounters) GetEgressBytes() uint64 {
+	if m != nil {
+		return m.EgressBytes
+	}
+	return 0
+}
+
+func (m *CountryCounters) GetIngressPackets() uint64 {
+	if m != nil {
+		return m.IngressPackets
+	}
+	return 0
+}
+
+func (m *CountryCounters) GetIngressBytes() uint64 {
+	if m != nil {
+		return m.IngressBytes
+	}
+	return 0
+}
+
+func (m *CountryCounters) GetDeniedPackets() uint64 {
+	if m != nil {
+		return m.DeniedPackets
+	}
+	return 0
+}
+
+type CountriesReply struct {
+	Countries            []*CountryCounters `protobuf:"bytes,1,rep,name=countries,proto3" json:"countries,omitempty"`
+	Tenant               string             `protobuf:"bytes,2,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *CountriesReply) Reset()         { *m = CountriesReply{} }
+func (m *CountriesReply) String() string { return proto.CompactTextString(m) }
+func (*CountriesReply) ProtoMessage()    {}
+func (*CountriesReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{49}
+}
+func (m *CountriesReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CountriesReply.Unmarshal(m, b)
+}
+func (m *CountriesReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CountriesReply.Marshal(b, m, deterministic)
+}
+func (dst *CountriesReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CountriesReply.Merge(dst, src)
+}
+func (m *CountriesReply) XXX_Size() int {
+	return xxx_messageInfo_CountriesReply.Size(m)
+}
+func (m *CountriesReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_CountriesReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CountriesReply proto.InternalMessageInfo
+
+func (m *CountriesReply) GetCountries() []*CountryCounters {
+	if m != nil {
+		return m.Countries
+	}
+	return nil
+}
+
+func (m *CountriesReply) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+// Port trigger rule, protocols are "TCP" or "UDP", ports are port
+// numbers or ranges like "6660-6669"
+type PortTrigger struct {
+	Protocol             string   `protobuf:"bytes,1,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	Ports                string   `protobuf:"bytes,2,opt,name=ports,proto3" json:"ports,omitempty"`
+	OpenProtocol         string   `protobuf:"bytes,3,opt,name=open_protocol,json=openProtocol,proto3" json:"open_protocol,omitempty"`
+	OpenPorts            []string `protobuf:"bytes,4,rep,name=open_ports,json=openPorts,proto3" json:"open_ports,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PortTrigger) Reset()         { *m = PortTrigger{} }
+func (m *PortTrigger) String() string { return proto.CompactTextString(m) }
+func (*PortTrigger) ProtoMessage()    {}
+func (*PortTrigger) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{50}
+}
+func (m *PortTrigger) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PortTrigger.Unmarshal(m, b)
+}
+func (m *PortTrigger) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PortTrigger.Marshal(b, m, deterministic)
+}
+func (dst *PortTrigger) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PortTrigger.Merge(dst, src)
+}
+func (m *PortTrigger) XXX_Size() int {
+	return xxx_messageInfo_PortTrigger.Size(m)
+}
+func (m *PortTrigger) XXX_DiscardUnknown() {
+	xxx_messageInfo_PortTrigger.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PortTrigger proto.InternalMessageInfo
+
+func (m *PortTrigger) GetProtocol() string {
+	if m != nil {
+		return m.Protocol
+	}
+	return ""
+}
+
+func (m *PortTrigger) GetPorts() string {
+	if m != nil {
+		return m.Ports
+	}
+	return ""
+}
+
+func (m *PortTrigger) GetOpenProtocol() string {
+	if m != nil {
+		return m.OpenProtocol
+	}
+	return ""
+}
+
+func (m *PortTrigger) GetOpenPorts() []string {
+	if m != nil {
+		return m.OpenPorts
+	}
+	return nil
+}
+
+// All trigger rules of port pair are replaced by rules of request
+type PortTriggersChangeRequest struct {
+	InterfaceId          uint32         `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	Triggers             []*PortTrigger `protobuf:"bytes,2,rep,name=triggers,proto3" json:"triggers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *PortTriggersChangeRequest) Reset()         { *m = PortTriggersChangeRequest{} }
+func (m *PortTriggersChangeRequest) String() string { return proto.CompactTextString(m) }
+func (*PortTriggersChangeRequest) ProtoMessage()    {}
+func (*PortTriggersChangeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{51}
+}
+func (m *PortTriggersChangeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PortTriggersChangeRequest.Unmarshal(m, b)
+}
+func (m *PortTriggersChangeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PortTriggersChangeRequest.Marshal(b, m, deterministic)
+}
+func (dst *PortTriggersChangeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PortTriggersChangeRequest.Merge(dst, src)
+}
+func (m *PortTriggersChangeRequest) XXX_Size() int {
+	return xxx_messageInfo_PortTriggersChangeRequest.Size(m)
+}
+func (m *PortTriggersChangeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PortTriggersChangeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PortTriggersChangeRequest proto.InternalMessageInfo
+
+func (m *PortTriggersChangeRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *PortTriggersChangeRequest) GetTriggers() []*PortTrigger {
+	if m != nil {
+		return m.Triggers
+	}
+	return nil
+}
+
+type PortTriggersRequest struct {
+	InterfaceId          uint32   `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PortTriggersRequest) Reset()         { *m = PortTriggersRequest{} }
+func (m *PortTriggersRequest) String() string { return proto.CompactTextString(m) }
+func (*PortTriggersRequest) ProtoMessage()    {}
+func (*PortTriggersRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{52}
+}
+func (m *PortTriggersRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PortTriggersRequest.Unmarshal(m, b)
+}
+func (m *PortTriggersRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PortTriggersRequest.Marshal(b, m, deterministic)
+}
+func (dst *PortTriggersRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PortTriggersRequest.Merge(dst, src)
+}
+func (m *PortTriggersRequest) XXX_Size() int {
+	return xxx_messageInfo_PortTriggersRequest.Size(m)
+}
+func (m *PortTriggersRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PortTriggersRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PortTriggersRequest proto.InternalMessageInfo
+
+func (m *PortTriggersRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+// Inbound port opened to private host
+type TriggeredPort struct {
+	Ipv6 bool `protobuf:"varint,1,opt,name=ipv6,proto3" json:"ipv6,omitempty"`
+	// IP protocol number
+	Protocol             uint32     `protobuf:"varint,2,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	Port                 uint32     `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
+	Host                 *IPAddress `protobuf:"bytes,4,opt,name=host,proto3" json:"host,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *TriggeredPort) Reset()         { *m = TriggeredPort{} }
+func (m *TriggeredPort) String() string { return proto.CompactTextString(m) }
+func (*TriggeredPort) ProtoMessage()    {}
+func (*TriggeredPort) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{53}
+}
+func (m *TriggeredPort) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TriggeredPort.Unmarshal(m, b)
+}
+func (m *TriggeredPort) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TriggeredPort.Marshal(b, m, deterministic)
+}
+func (dst *TriggeredPort) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TriggeredPort.Merge(dst, src)
+}
+func (m *TriggeredPort) XXX_Size() int {
+	return xxx_messageInfo_TriggeredPort.Size(m)
+}
+func (m *TriggeredPort) XXX_DiscardUnknown() {
+	xxx_messageInfo_TriggeredPort.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TriggeredPort proto.InternalMessageInfo
+
+func (m *TriggeredPort) GetIpv6() bool {
+	if m != nil {
+		return m.Ipv6
+	}
+	return false
+}
+
+func (m *TriggeredPort) GetProtocol() uint32 {
+	if m != nil {
+		return m.Protocol
+	}
+	return 0
+}
+
+func (m *TriggeredPort) GetPort() uint32 {
+	if m != nil {
+		return m.Port
+	}
+	return 0
+}
+
+func (m *TriggeredPort) GetHost() *IPAddress {
+	if m != nil {
+		return m.Host
+	}
+	return nil
+}
+
+type PortTriggersReply struct {
+	Triggers             []*PortTrigger   `protobuf:"bytes,1,rep,name=triggers,proto3" json:"triggers,omitempty"`
+	Opened               []*TriggeredPort `protobuf:"bytes,2,rep,name=opened,proto3" json:"opened,omitempty"`
+	Tenant               string           `protobuf:"bytes,3,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *PortTriggersReply) Reset()         { *m = PortTriggersReply{} }
+func (m *PortTriggersReply) String() string { return proto.CompactTextString(m) }
+func (*PortTriggersReply) ProtoMessage()    {}
+func (*PortTriggersReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{54}
+}
+func (m *PortTriggersReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PortTriggersReply.Unmarshal(m, b)
+}
+func (m *PortTriggersReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PortTriggersReply.Marshal(b, m, deterministic)
+}
+func (dst *PortTriggersReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PortTriggersReply.Merge(dst, src)
+}
+func (m *PortTriggersReply) XXX_Size() int {
+	return xxx_messageInfo_PortTriggersReply.Size(m)
+}
+func (m *PortTriggersReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_PortTriggersReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PortTriggersReply proto.InternalMessageInfo
+
+func (m *PortTriggersReply) GetTriggers() []*PortTrigger {
+	if m != nil {
+		return m.Triggers
+	}
+	return nil
+}
+
+func (m *PortTriggersReply) GetOpened() []*TriggeredPort {
+	if m != nil {
+		return m.Opened
+	}
+	return nil
+}
+
+func (m *PortTriggersReply) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+// Sampling of logged sessions of port pair is replaced by request
+type SessionLogSamplingRequest struct {
+	InterfaceId uint32 `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	// One of this many sessions is logged, zero or one means all
+	Rate uint32 `protobuf:"varint,2,opt,name=rate,proto3" json:"rate,omitempty"`
+	// Private hosts which sessions are always logged
+	Subscribers []*IPAddress `protobuf:"bytes,3,rep,name=subscribers,proto3" json:"subscribers,omitempty"`
+	// MAC addresses of private hosts which sessions are always logged
+	SubscriberMacAddresses [][]byte `protobuf:"bytes,4,rep,name=subscriber_mac_addresses,json=subscriberMacAddresses,proto3" json:"subscriber_mac_addresses,omitempty"`
+	XXX_NoUnkeyedLiteral   struct{} `json:"-"`
+	XXX_unrecognized       []byte   `json:"-"`
+	XXX_sizecache          int32    `json:"-"`
+}
+
+func (m *SessionLogSamplingRequest) Reset()         { *m = SessionLogSamplingRequest{} }
+func (m *SessionLogSamplingRequest) String() string { return proto.CompactTextString(m) }
+func (*SessionLogSamplingRequest) ProtoMessage()    {}
+func (*SessionLogSamplingRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{55}
+}
+func (m *SessionLogSamplingRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SessionLogSamplingRequest.Unmarshal(m, b)
+}
+func (m *SessionLogSamplingRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SessionLogSamplingRequest.Marshal(b, m, deterministic)
+}
+func (dst *SessionLogSamplingRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SessionLogSamplingRequest.Merge(dst, src)
+}
+func (m *SessionLogSamplingRequest) XXX_Size() int {
+	return xxx_messageInfo_SessionLogSamplingRequest.Size(m)
+}
+func (m *SessionLogSamplingRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SessionLogSamplingRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SessionLogSamplingRequest proto.InternalMessageInfo
+
+func (m *SessionLogSamplingRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *SessionLogSamplingRequest) GetRate() uint32 {
+	if m != nil {
+		return m.Rate
+	}
+	return 0
+}
+
+func (m *SessionLogSamplingRequest) GetSubscribers() []*IPAddress {
+	if m != nil {
+		return m.Subscribers
+	}
+	return nil
+}
+
+func (m *SessionLogSamplingRequest) GetSubscriberMacAddresses() [][]byte {
+	if m != nil {
+		return m.SubscriberMacAddresses
+	}
+	return nil
+}
+
+type RunningConfigRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RunningConfigRequest) Reset()         { *m = RunningConfigRequest{} }
+func (m *RunningConfigRequest) String() string { return proto.CompactTextString(m) }
+func (*RunningConfigRequest) ProtoMessage()    {}
+func (*RunningConfigRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{56}
+}
+func (m *RunningConfigRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RunningConfigRequest.Unmarshal(m, b)
+}
+func (m *RunningConfigRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RunningConfigRequest.Marshal(b, m, deterministic)
+}
+func (dst *RunningConfigRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RunningConfigRequest.Merge(dst, src)
+}
+func (m *RunningConfigRequest) XXX_Size() int {
+	return xxx_messageInfo_RunningConfigRequest.Size(m)
+}
+func (m *RunningConfigRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RunningConfigRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RunningConfigRequest proto.InternalMessageInfo
+
+// Configuration in effect in config file format, it includes changes
+// made with control API
+type RunningConfigReply struct {
+	Config               string   `protobuf:"bytes,1,opt,name=config,proto3" json:"config,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RunningConfigReply) Reset()         { *m = RunningConfigReply{} }
+func (m *RunningConfigReply) String() string { return proto.CompactTextString(m) }
+func (*RunningConfigReply) ProtoMessage()    {}
+func (*RunningConfigReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{57}
+}
+func (m *RunningConfigReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RunningConfigReply.Unmarshal(m, b)
+}
+func (m *RunningConfigReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RunningConfigReply.Marshal(b, m, deterministic)
+}
+func (dst *RunningConfigReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RunningConfigReply.Merge(dst, src)
+}
+func (m *RunningConfigReply) XXX_Size() int {
+	return xxx_messageInfo_RunningConfigReply.Size(m)
+}
+func (m *RunningConfigReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_RunningConfigReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RunningConfigReply proto.InternalMessageInfo
+
+func (m *RunningConfigReply) GetConfig() string {
+	if m != nil {
+		return m.Config
+	}
+	return ""
+}
+
+// Candidate config in running config format. Settings which may be
+// changed at runtime are replaced with its settings, all other
+// settings should be equal to running config.
+type CommitConfigRequest struct {
+	Config string `protobuf:"bytes,1,opt,name=config,proto3" json:"config,omitempty"`
+	// Commit is rolled back unless it is confirmed in this many
+	// seconds, zero means that commit doesn't need confirmation
+	ConfirmTimeout       uint32   `protobuf:"varint,2,opt,name=confirm_timeout,json=confirmTimeout,proto3" json:"confirm_timeout,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CommitConfigRequest) Reset()         { *m = CommitConfigRequest{} }
+func (m *CommitConfigRequest) String() string { return proto.CompactTextString(m) }
+func (*CommitConfigRequest) ProtoMessage()    {}
+func (*CommitConfigRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{58}
+}
+func (m *CommitConfigRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CommitConfigRequest.Unmarshal(m, b)
+}
+func (m *CommitConfigRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CommitConfigRequest.Marshal(b, m, deterministic)
+}
+func (dst *CommitConfigRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CommitConfigRequest.Merge(dst, src)
+}
+func (m *CommitConfigRequest) XXX_Size() int {
+	return xxx_messageInfo_CommitConfigRequest.Size(m)
+}
+func (m *CommitConfigRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CommitConfigRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CommitConfigRequest proto.InternalMessageInfo
+
+func (m *CommitConfigRequest) GetConfig() string {
+	if m != nil {
+		return m.Config
+	}
+	return ""
+}
+
+func (m *CommitConfigRequest) GetConfirmTimeout() uint32 {
+	if m != nil {
+		return m.ConfirmTimeout
+	}
+	return 0
+}
+
+type ConfirmCommitRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ConfirmCommitRequest) Reset()         { *m = ConfirmCommitRequest{} }
+func (m *ConfirmCommitRequest) String() string { return proto.CompactTextString(m) }
+func (*ConfirmCommitRequest) ProtoMessage()    {}
+func (*ConfirmCommitRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{59}
+}
+func (m *ConfirmCommitRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ConfirmCommitRequest.Unmarshal(m, b)
+}
+func (m *ConfirmCommitRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ConfirmCommitRequest.Marshal(b, m, deterministic)
+}
+func (dst *ConfirmCommitRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ConfirmCommitRequest.Merge(dst, src)
+}
+func (m *ConfirmCommitRequest) XXX_Size() int {
+	return xxx_messageInfo_ConfirmCommitRequest.Size(m)
+}
+func (m *ConfirmCommitRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ConfirmCommitRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ConfirmCommitRequest proto.InternalMessageInfo
+
+type RollbackCommitRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RollbackCommitRequest) Reset()         { *m = RollbackCommitRequest{} }
+func (m *RollbackCommitRequest) String() string { return proto.CompactTextString(m) }
+func (*RollbackCommitRequest) ProtoMessage()    {}
+func (*RollbackCommitRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{60}
+}
+func (m *RollbackCommitRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RollbackCommitRequest.Unmarshal(m, b)
+}
+func (m *RollbackCommitRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RollbackCommitRequest.Marshal(b, m, deterministic)
+}
+func (dst *RollbackCommitRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RollbackCommitRequest.Merge(dst, src)
+}
+func (m *RollbackCommitRequest) XXX_Size() int {
+	return xxx_messageInfo_RollbackCommitRequest.Size(m)
+}
+func (m *RollbackCommitRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RollbackCommitRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RollbackCommitRequest proto.InternalMessageInfo
+
+type Reply struct {
+	Msg                  string   `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Reply) Reset()         { *m = Reply{} }
+func (m *Reply) String() string { return proto.CompactTextString(m) }
+func (*Reply) ProtoMessage()    {}
+func (*Reply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{61}
+}
+func (m *Reply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Reply.Unmarshal(m, b)
+}
+func (m *Reply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Reply.Marshal(b, m, deterministic)
+}
+func (dst *Reply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Reply.Merge(dst, src)
+}
+func (m *Reply) XXX_Size() int {
+	return xxx_messageInfo_Reply.Size(m)
+}
+func (m *Reply) XXX_DiscardUnknown() {
+	xxx_messageInfo_Reply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Reply proto.InternalMessageInfo
+
+func (m *Reply) GetMsg() string {
+	if m != nil {
+		return m.Msg
+	}
+	return ""
+}
+
+// Conditions which all have to match, zero or missing values match
+// all sessions. Addresses match sessions of their IP family only.
+type SessionFilter struct {
+	// IP protocol number
+	Protocol       uint32     `protobuf:"varint,1,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	PublicAddress  *IPAddress `protobuf:"bytes,2,opt,name=public_address,json=publicAddress,proto3" json:"public_address,omitempty"`
+	PublicPort     uint32     `protobuf:"varint,3,opt,name=public_port,json=publicPort,proto3" json:"public_port,omitempty"`
+	PrivateAddress *IPAddress `protobuf:"bytes,4,opt,name=private_address,json=privateAddress,proto3" json:"private_address,omitempty"`
+	PrivatePort    uint32     `protobuf:"varint,5,opt,name=private_port,json=privatePort,proto3" json:"private_port,omitempty"`
+	RemoteAddress  *IPAddress `protobuf:"bytes,6,opt,name=remote_address,json=remoteAddress,proto3" json:"remote_address,omitempty"`
+	RemotePort     uint32     `protobuf:"varint,7,opt,name=remote_port,json=remotePort,proto3" json:"remote_port,omitempty"`
+	// Seconds since session creation
+	MinAge uint32 `protobuf:"varint,8,opt,name=min_age,json=minAge,proto3" json:"min_age,omitempty"`
+	MaxAge uint32 `protobuf:"varint,9,opt,name=max_age,json=maxAge,proto3" json:"max_age,omitempty"`
+	// Translated bytes in both directions
+	MinBytes uint64      `protobuf:"varint,10,opt,name=min_bytes,json=minBytes,proto3" json:"min_bytes,omitempty"`
+	MaxBytes uint64      `protobuf:"varint,11,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`
+	Kind     SessionKind `protobuf:"varint,12,opt,name=kind,proto3,enum=updatecfg.SessionKind" json:"kind,omitempty"`
+	// MAC address of private host which host identity tracking knows
+	PrivateMacAddress    []byte   `protobuf:"bytes,13,opt,name=private_mac_address,json=privateMacAddress,proto3" json:"private_mac_address,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SessionFilter) Reset()         { *m = SessionFilter{} }
+func (m *SessionFilter) String() string { return proto.CompactTextString(m) }
+func (*SessionFilter) ProtoMessage()    {}
+func (*SessionFilter) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{62}
+}
+func (m *SessionFilter) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SessionFilter.Unmarshal(m, b)
+}
+func (m *SessionFilter) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SessionFilter.Marshal(b, m, deterministic)
+}
+func (dst *SessionFilter) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SessionFilter.Merge(dst, src)
+}
+func (m *SessionFilter) XXX_Size() int {
+	return xxx_messageInfo_SessionFilter.Size(m)
+}
+func (m *SessionFilter) XXX_DiscardUnknown() {
+	xxx_messageInfo_SessionFilter.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SessionFilter proto.InternalMessageInfo
+
+func (m *SessionFilter) GetProtocol() uint32 {
+	if m != nil {
+		return m.Protocol
+	}
+	return 0
+}
+
+func (m *SessionFilter) GetPublicAddress() *IPAddress {
+	if m != nil {
+		return m.PublicAddress
+	}
+	return nil
+}
+
+func (m *SessionFilter) GetPublicPort() uint32 {
+	if m != nil {
+		return m.PublicPort
+	}
+	return 0
+}
+
+func (m *SessionFilter) GetPrivateAddress() *IPAddress {
+	if m != nil {
+		return m.PrivateAddress
+	}
+	return nil
+}
+
+func (m *SessionFilter) GetPrivatePort() uint32 {
+	if m != nil {
+		return m.PrivatePort
+	}
+	return 0
+}
+
+func (m *SessionFilter) GetRemoteAddress() *IPAddress {
+	if m != nil {
+		return m.RemoteAddress
+	}
+	return nil
+}
+
+func (m *SessionFilter) GetRemotePort() uint32 {
+	if m != nil {
+		return m.RemotePort
+	}
+	return 0
+}
+
+func (m *SessionFilter) GetMinAge() uint32 {
+	if m != nil {
+		return m.MinAge
+	}
+	return 0
+}
+
+func (m *SessionFilter) GetMaxAge() uint32 {
+	if m != nil {
+		return m.MaxAge
+	}
+	return 0
+}
+
+func (m *SessionFilter) GetMinBytes() uint64 {
+	if m != nil {
+		return m.MinBytes
+	}
+	return 0
+}
+
+func (m *SessionFilter) GetMaxBytes() uint64 {
+	if m != nil {
+		return m.MaxBytes
+	}
+	return 0
+}
+
+func (m *SessionFilter) GetKind() SessionKind {
+	if m != nil {
+		return m.Kind
+	}
+	return SessionKind_SESSIONS_ALL
+}
+
+func (m *SessionFilter) GetPrivateMacAddress() []byte {
+	if m != nil {
+		return m.PrivateMacAddress
+	}
+	return nil
+}
+
+type SessionsFindRequest struct {
+	InterfaceId uint32         `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	Filter      *SessionFilter `protobuf:"bytes,2,opt,name=filter,proto3" json:"filter,omitempty"`
+	// Maximum number of sessions in reply, zero means 1000, at most
+	// 10000
+	Limit uint32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	// Position to continue search from, zero starts from first session
+	Cursor               uint64   `protobuf:"varint,4,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SessionsFindRequest) Reset()         { *m = SessionsFindRequest{} }
+func (m *SessionsFindRequest) String() string { return proto.CompactTextString(m) }
+func (*SessionsFindRequest) ProtoMessage()    {}
+func (*SessionsFindRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{63}
+}
+func (m *SessionsFindRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SessionsFindRequest.Unmarshal(m, b)
+}
+func (m *SessionsFindRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SessionsFindRequest.Marshal(b, m, deterministic)
+}
+func (dst *SessionsFindRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SessionsFindRequest.Merge(dst, src)
+}
+func (m *SessionsFindRequest) XXX_Size() int {
+	return xxx_messageInfo_SessionsFindRequest.Size(m)
+}
+func (m *SessionsFindRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SessionsFindRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SessionsFindRequest proto.InternalMessageInfo
+
+func (m *SessionsFindRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *SessionsFindRequest) GetFilter() *SessionFilter {
+	if m != nil {
+		return m.Filter
+	}
+	return nil
+}
+
+func (m *SessionsFindRequest) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *SessionsFindRequest) GetCursor() uint64 {
+	if m != nil {
+		return m.Cursor
+	}
+	return 0
+}
+
+type SessionsFindReply struct {
+	Sessions []*Session `protobuf:"bytes,1,rep,name=sessions,proto3" json:"sessions,omitempty"`
+	// Cursor of next page, zero when no more sessions match
+	NextCursor           uint64   `protobuf:"varint,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	Tenant               string   `protobuf:"bytes,3,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SessionsFindReply) Reset()         { *m = SessionsFindReply{} }
+func (m *SessionsFindReply) String() string { return proto.CompactTextString(m) }
+func (*SessionsFindReply) ProtoMessage()    {}
+func (*SessionsFindReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{64}
+}
+func (m *SessionsFindReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SessionsFindReply.Unmarshal(m, b)
+}
+func (m *SessionsFindReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SessionsFindReply.Marshal(b, m, deterministic)
+}
+func (dst *SessionsFindReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SessionsFindReply.Merge(dst, src)
+}
+func (m *SessionsFindReply) XXX_Size() int {
+	return xxx_messageInfo_SessionsFindReply.Size(m)
+}
+func (m *SessionsFindReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_SessionsFindReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SessionsFindReply proto.InternalMessageInfo
+
+func (m *SessionsFindReply) GetSessions() []*Session {
+	if m != nil {
+		return m.Sessions
+	}
+	return nil
+}
+
+func (m *SessionsFindReply) GetNextCursor() uint64 {
+	if m != nil {
+		return m.NextCursor
+	}
+	return 0
+}
+
+func (m *SessionsFindReply) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+type ExternalAddressRequest struct {
+	InterfaceId          uint32   `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ExternalAddressRequest) Reset()         { *m = ExternalAddressRequest{} }
+func (m *ExternalAddressRequest) String() string { return proto.CompactTextString(m) }
+func (*ExternalAddressRequest) ProtoMessage()    {}
+func (*ExternalAddressRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{65}
+}
+func (m *ExternalAddressRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExternalAddressRequest.Unmarshal(m, b)
+}
+func (m *ExternalAddressRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExternalAddressRequest.Marshal(b, m, deterministic)
+}
+func (dst *ExternalAddressRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExternalAddressRequest.Merge(dst, src)
+}
+func (m *ExternalAddressRequest) XXX_Size() int {
+	return xxx_messageInfo_ExternalAddressRequest.Size(m)
+}
+func (m *ExternalAddressRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExternalAddressRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExternalAddressRequest proto.InternalMessageInfo
+
+func (m *ExternalAddressRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+// External address and port which STUN server sees for public port,
+// address is not set until server answers
+type ExternalAddressReply struct {
+	InterfaceId uint32     `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	Address     *IPAddress `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Port        uint32     `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
+	Server      *IPAddress `protobuf:"bytes,4,opt,name=server,proto3" json:"server,omitempty"`
+	// Time of last response of server
+	Learned              int64    `protobuf:"varint,5,opt,name=learned,proto3" json:"learned,omitempty"`
+	Tenant               string   `protobuf:"bytes,6,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ExternalAddressReply) Reset()         { *m = ExternalAddressReply{} }
+func (m *ExternalAddressReply) String() string { return proto.CompactTextString(m) }
+func (*ExternalAddressReply) ProtoMessage()    {}
+func (*ExternalAddressReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{66}
+}
+func (m *ExternalAddressReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExternalAddressReply.Unmarshal(m, b)
+}
+func (m *ExternalAddressReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExternalAddressReply.Marshal(b, m, deterministic)
+}
+func (dst *ExternalAddressReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExternalAddressReply.Merge(dst, src)
+}
+func (m *ExternalAddressReply) XXX_Size() int {
+	return xxx_messageInfo_ExternalAddressReply.Size(m)
+}
+func (m *ExternalAddressReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExternalAddressReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExternalAddressReply proto.InternalMessageInfo
+
+func (m *ExternalAddressReply) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *ExternalAddressReply) GetAddress() *IPAddress {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *ExternalAddressReply) GetPort() uint32 {
+	if m != nil {
+		return m.Port
+	}
+	return 0
+}
+
+func (m *ExternalAddressReply) GetServer() *IPAddress {
+	if m != nil {
+		return m.Server
+	}
+	return nil
+}
+
+func (m *ExternalAddressReply) GetLearned() int64 {
+	if m != nil {
+		return m.Learned
+	}
+	return 0
+}
+
+func (m *ExternalAddressReply) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+type FlowGraphRequest struct {
+	// Packet rates are measured over this many milliseconds, zero
+	// means 1000, at most 5000
+	IntervalMs           uint32   `protobuf:"varint,1,opt,name=interval_ms,json=intervalMs,proto3" json:"interval_ms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FlowGraphRequest) Reset()         { *m = FlowGraphRequest{} }
+func (m *FlowGraphRequest) String() string { return proto.CompactTextString(m) }
+func (*FlowGraphRequest) ProtoMessage()    {}
+func (*FlowGraphRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{67}
+}
+func (m *FlowGraphRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FlowGraphRequest.Unmarshal(m, b)
+}
+func (m *FlowGraphRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FlowGraphRequest.Marshal(b, m, deterministic)
+}
+func (dst *FlowGraphRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FlowGraphRequest.Merge(dst, src)
+}
+func (m *FlowGraphRequest) XXX_Size() int {
+	return xxx_messageInfo_FlowGraphRequest.Size(m)
+}
+func (m *FlowGraphRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_FlowGraphRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FlowGraphRequest proto.InternalMessageInfo
+
+func (m *FlowGraphRequest) GetIntervalMs() uint32 {
+	if m != nil {
+		return m.IntervalMs
+	}
+	return 0
+}
+
+// Flow function of NFF-Go flow graph. Kinds are "receiver",
+// "splitter", "handler", "stopper", "kni", "merger", "sender" and
+// "generator"
+type FlowNode struct {
+	Id   string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Kind string `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`
+	Name string `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	// Index of port pair in config
+	Pair uint32 `protobuf:"varint,4,opt,name=pair,proto3" json:"pair,omitempty"`
+	// Network card port of receivers, senders and KNI devices
+	InterfaceId          uint32   `protobuf:"varint,5,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FlowNode) Reset()         { *m = FlowNode{} }
+func (m *FlowNode) String() string { return proto.CompactTextString(m) }
+func (*FlowNode) ProtoMessage()    {}
+func (*FlowNode) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{68}
+}
+func (m *FlowNode) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FlowNode.Unmarshal(m, b)
+}
+func (m *FlowNode) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FlowNode.Marshal(b, m, deterministic)
+}
+func (dst *FlowNode) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FlowNode.Merge(dst, src)
+}
+func (m *FlowNode) XXX_Size() int {
+	return xxx_messageInfo_FlowNode.Size(m)
+}
+func (m *FlowNode) XXX_DiscardUnknown() {
+	xxx_messageInfo_FlowNode.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FlowNode proto.InternalMessageInfo
+
+func (m *FlowNode) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *FlowNode) GetKind() string {
+	if m != nil {
+		return m.Kind
+	}
+	return ""
+}
+
+func (m *FlowNode) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *FlowNode) GetPair() uint32 {
+	if m != nil {
+		return m.Pair
+	}
+	return 0
+}
+
+func (m *FlowNode) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+// Flow between flow functions. Packets of edges which NAT doesn't
+// count are not reported
+type FlowEdge struct {
+	From    string `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To      string `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	Counted bool   `protobuf:"varint,3,opt,name=counted,proto3" json:"counted,omitempty"`
+	// Packets since start and their rate during interval
+	Packets              uint64   `protobuf:"varint,4,opt,name=packets,proto3" json:"packets,omitempty"`
+	PacketsPerSecond     float64  `protobuf:"fixed64,5,opt,name=packets_per_second,json=packetsPerSecond,proto3" json:"packets_per_second,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FlowEdge) Reset()         { *m = FlowEdge{} }
+func (m *FlowEdge) String() string { return proto.CompactTextString(m) }
+func (*FlowEdge) ProtoMessage()    {}
+func (*FlowEdge) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{69}
+}
+func (m *FlowEdge) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FlowEdge.Unmarshal(m, b)
+}
+func (m *FlowEdge) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FlowEdge.Marshal(b, m, deterministic)
+}
+func (dst *FlowEdge) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FlowEdge.Merge(dst, src)
+}
+func (m *FlowEdge) XXX_Size() int {
+	return xxx_messageInfo_FlowEdge.Size(m)
+}
+func (m *FlowEdge) XXX_DiscardUnknown() {
+	xxx_messageInfo_FlowEdge.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FlowEdge proto.InternalMessageInfo
+
+func (m *FlowEdge) GetFrom() string {
+	if m != nil {
+		return m.From
+	}
+	return ""
+}
+
+func (m *FlowEdge) GetTo() string {
+	if m != nil {
+		return m.To
+	}
+	return ""
+}
+
+func (m *FlowEdge) GetCounted() bool {
+	if m != nil {
+		return m.Counted
+	}
+	return false
+}
+
+func (m *FlowEdge) GetPackets() uint64 {
+	if m != nil {
+		return m.Packets
+	}
+	return 0
+}
+
+func (m *FlowEdge) GetPacketsPerSecond() float64 {
+	if m != nil {
+		return m.PacketsPerSecond
+	}
+	return 0
+}
+
+type FlowGraphReply struct {
+	Nodes                []*FlowNode `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	Edges                []*FlowEdge `protobuf:"bytes,2,rep,name=edges,proto3" json:"edges,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *FlowGraphReply) Reset()         { *m = FlowGraphReply{} }
+func (m *FlowGraphReply) String() string { return proto.CompactTextString(m) }
+func (*FlowGraphReply) ProtoMessage()    {}
+func (*FlowGraphReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{70}
+}
+func (m *FlowGraphReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FlowGraphReply.Unmarshal(m, b)
+}
+func (m *FlowGraphReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FlowGraphReply.Marshal(b, m, deterministic)
+}
+func (dst *FlowGraphReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FlowGraphReply.Merge(dst, src)
+}
+func (m *FlowGraphReply) XXX_Size() int {
+	return xxx_messageInfo_FlowGraphReply.Size(m)
+}
+func (m *FlowGraphReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_FlowGraphReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FlowGraphReply proto.InternalMessageInfo
+
+func (m *FlowGraphReply) GetNodes() []*FlowNode {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+func (m *FlowGraphReply) GetEdges() []*FlowEdge {
+	if m != nil {
+		return m.Edges
+	}
+	return nil
+}
+
+// Port pair is identified by index of either of its ports
+type MaintenanceRequest struct {
+	InterfaceId uint32 `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	// Enter maintenance when true, leave it otherwise
+	Enable bool `protobuf:"varint,2,opt,name=enable,proto3" json:"enable,omitempty"`
+	// Remove dynamic sessions when entering maintenance
+	FlushSessions bool `protobuf:"varint,3,opt,name=flush_sessions,json=flushSessions,proto3" json:"flush_sessions,omitempty"`
+	// Answer new sessions with TCP reset or ICMP port unreachable
+	// instead of dropping them
+	Reject               bool     `protobuf:"varint,4,opt,name=reject,proto3" json:"reject,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MaintenanceRequest) Reset()         { *m = MaintenanceRequest{} }
+func (m *MaintenanceRequest) String() string { return proto.CompactTextString(m) }
+func (*MaintenanceRequest) ProtoMessage()    {}
+func (*MaintenanceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{71}
+}
+func (m *MaintenanceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MaintenanceRequest.Unmarshal(m, b)
+}
+func (m *MaintenanceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MaintenanceRequest.Marshal(b, m, deterministic)
+}
+func (dst *MaintenanceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MaintenanceRequest.Merge(dst, src)
+}
+func (m *MaintenanceRequest) XXX_Size() int {
+	return xxx_messageInfo_MaintenanceRequest.Size(m)
+}
+func (m *MaintenanceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_MaintenanceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MaintenanceRequest proto.InternalMessageInfo
+
+func (m *MaintenanceRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *MaintenanceRequest) GetEnable() bool {
+	if m != nil {
+		return m.Enable
+	}
+	return false
+}
+
+func (m *MaintenanceRequest) GetFlushSessions() bool {
+	if m != nil {
+		return m.FlushSessions
+	}
+	return false
+}
+
+func (m *MaintenanceRequest) GetReject() bool {
+	if m != nil {
+		return m.Reject
+	}
+	return false
+}
+
+type MaintenanceStatusRequest struct {
+	InterfaceId          uint32   `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MaintenanceStatusRequest) Reset()         { *m = MaintenanceStatusRequest{} }
+func (m *MaintenanceStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*MaintenanceStatusRequest) ProtoMessage()    {}
+func (*MaintenanceStatusRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{72}
+}
+func (m *MaintenanceStatusRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MaintenanceStatusRequest.Unmarshal(m, b)
+}
+func (m *MaintenanceStatusRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MaintenanceStatusRequest.Marshal(b, m, deterministic)
+}
+func (dst *MaintenanceStatusRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MaintenanceStatusRequest.Merge(dst, src)
+}
+func (m *MaintenanceStatusRequest) XXX_Size() int {
+	return xxx_messageInfo_MaintenanceStatusRequest.Size(m)
+}
+func (m *MaintenanceStatusRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_MaintenanceStatusRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MaintenanceStatusRequest proto.InternalMessageInfo
+
+func (m *MaintenanceStatusRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+type MaintenanceReply struct {
+	InterfaceId uint32 `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	Maintenance bool   `protobuf:"varint,2,opt,name=maintenance,proto3" json:"maintenance,omitempty"`
+	Reject      bool   `protobuf:"varint,3,opt,name=reject,proto3" json:"reject,omitempty"`
+	// Unix time when maintenance started
+	Since int64 `protobuf:"varint,4,opt,name=since,proto3" json:"since,omitempty"`
+	// Sessions removed by this request
+	FlushedSessions uint64 `protobuf:"varint,5,opt,name=flushed_sessions,json=flushedSessions,proto3" json:"flushed_sessions,omitempty"`
+	// New sessions refused since maintenance started
+	RefusedSessions uint64 `protobuf:"varint,6,opt,name=refused_sessions,json=refusedSessions,proto3" json:"refused_sessions,omitempty"`
+	// Dynamic sessions which are still active
+	ActiveSessions       uint64   `protobuf:"varint,7,opt,name=active_sessions,json=activeSessions,proto3" json:"active_sessions,omitempty"`
+	Tenant               string   `protobuf:"bytes,8,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MaintenanceReply) Reset()         { *m = MaintenanceReply{} }
+func (m *MaintenanceReply) String() string { return proto.CompactTextString(m) }
+func (*MaintenanceReply) ProtoMessage()    {}
+func (*MaintenanceReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{73}
+}
+func (m *MaintenanceReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MaintenanceReply.Unmarshal(m, b)
+}
+func (m *MaintenanceReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MaintenanceReply.Marshal(b, m, deterministic)
+}
+func (dst *MaintenanceReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MaintenanceReply.Merge(dst, src)
+}
+func (m *MaintenanceReply) XXX_Size() int {
+	return xxx_messageInfo_MaintenanceReply.Size(m)
+}
+func (m *MaintenanceReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_MaintenanceReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MaintenanceReply proto.InternalMessageInfo
+
+func (m *MaintenanceReply) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *MaintenanceReply) GetMaintenance() bool {
+	if m != nil {
+		return m.Maintenance
+	}
+	return false
+}
+
+func (m *MaintenanceReply) GetReject() bool {
+	if m != nil {
+		return m.Reject
+	}
+	return false
+}
+
+func (m *MaintenanceReply) GetSince() int64 {
+	if m != nil {
+		return m.Since
+	}
+	return 0
+}
+
+func (m *MaintenanceReply) GetFlushedSessions() uint64 {
+	if m != nil {
+		return m.FlushedSessions
+	}
+	return 0
+}
+
+func (m *MaintenanceReply) GetRefusedSessions() uint64 {
+	if m != nil {
+		return m.RefusedSessions
+	}
+	return 0
+}
+
+func (m *MaintenanceReply) GetActiveSessions() uint64 {
+	if m != nil {
+		return m.ActiveSessions
+	}
+	return 0
+}
+
+func (m *MaintenanceReply) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+type NftablesRulesetRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NftablesRulesetRequest) Reset()         { *m = NftablesRulesetRequest{} }
+func (m *NftablesRulesetRequest) String() string { return proto.CompactTextString(m) }
+func (*NftablesRulesetRequest) ProtoMessage()    {}
+func (*NftablesRulesetRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{74}
+}
+func (m *NftablesRulesetRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NftablesRulesetRequest.Unmarshal(m, b)
+}
+func (m *NftablesRulesetRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NftablesRulesetRequest.Marshal(b, m, deterministic)
+}
+func (dst *NftablesRulesetRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NftablesRulesetRequest.Merge(dst, src)
+}
+func (m *NftablesRulesetRequest) XXX_Size() int {
+	return xxx_messageInfo_NftablesRulesetRequest.Size(m)
+}
+func (m *NftablesRulesetRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_NftablesRulesetRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NftablesRulesetRequest proto.InternalMessageInfo
+
+// Static translations and filtering rules of port pairs in nftables
+// notation, it is only output and cannot be loaded into NAT
+type NftablesRulesetReply struct {
+	Ruleset              string   `protobuf:"bytes,1,opt,name=ruleset,proto3" json:"ruleset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NftablesRulesetReply) Reset()         { *m = NftablesRulesetReply{} }
+func (m *NftablesRulesetReply) String() string { return proto.CompactTextString(m) }
+func (*NftablesRulesetReply) ProtoMessage()    {}
+func (*NftablesRulesetReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{75}
+}
+func (m *NftablesRulesetReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NftablesRulesetReply.Unmarshal(m, b)
+}
+func (m *NftablesRulesetReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NftablesRulesetReply.Marshal(b, m, deterministic)
+}
+func (dst *NftablesRulesetReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NftablesRulesetReply.Merge(dst, src)
+}
+func (m *NftablesRulesetReply) XXX_Size() int {
+	return xxx_messageInfo_NftablesRulesetReply.Size(m)
+}
+func (m *NftablesRulesetReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_NftablesRulesetReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NftablesRulesetReply proto.InternalMessageInfo
+
+func (m *NftablesRulesetReply) GetRuleset() string {
+	if m != nil {
+		return m.Ruleset
+	}
+	return ""
+}
+
+// Packet received by port which verdict is traced without sending it
+type PacketTraceRequest struct {
+	InterfaceId uint32 `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	// IP protocol number, ports of protocols other than TCP, UDP, ICMP
+	// and ICMPv6 are ignored
+	Protocol uint32     `protobuf:"varint,2,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	Source   *IPAddress `protobuf:"bytes,3,opt,name=source,proto3" json:"source,omitempty"`
+	// Port or ICMP query identifier
+	SourcePort      uint32     `protobuf:"varint,4,opt,name=source_port,json=sourcePort,proto3" json:"source_port,omitempty"`
+	Destination     *IPAddress `protobuf:"bytes,5,opt,name=destination,proto3" json:"destination,omitempty"`
+	DestinationPort uint32     `protobuf:"varint,6,opt,name=destination_port,json=destinationPort,proto3" json:"destination_port,omitempty"`
+	// TCP packet is SYN which starts new connection
+	NewConnection bool `protobuf:"varint,7,opt,name=new_connection,json=newConnection,proto3" json:"new_connection,omitempty"`
+	// IPv4 TTL or IPv6 hop limit, zero is 64
+	HopLimit uint32 `protobuf:"varint,8,opt,name=hop_limit,json=hopLimit,proto3" json:"hop_limit,omitempty"`
+	// IPv4 header has options or IPv6 packet is jumbogram
+	IpOptions            bool     `protobuf:"varint,9,opt,name=ip_options,json=ipOptions,proto3" json:"ip_options,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PacketTraceRequest) Reset()         { *m = PacketTraceRequest{} }
+func (m *PacketTraceRequest) String() string { return proto.CompactTextString(m) }
+func (*PacketTraceRequest) ProtoMessage()    {}
+func (*PacketTraceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{76}
+}
+func (m *PacketTraceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PacketTraceRequest.Unmarshal(m, b)
+}
+func (m *PacketTraceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PacketTraceRequest.Marshal(b, m, deterministic)
+}
+func (dst *PacketTraceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PacketTraceRequest.Merge(dst, src)
+}
+func (m *PacketTraceRequest) XXX_Size() int {
+	return xxx_messageInfo_PacketTraceRequest.Size(m)
+}
+func (m *PacketTraceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PacketTraceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PacketTraceRequest proto.InternalMessageInfo
+
+func (m *PacketTraceRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *PacketTraceRequest) GetProtocol() uint32 {
+	if m != nil {
+		return m.Protocol
+	}
+	return 0
+}
+
+func (m *PacketTraceRequest) GetSource() *IPAddress {
+	if m != nil {
+		return m.Source
+	}
+	return nil
+}
+
+func (m *PacketTraceRequest) GetSourcePort() uint32 {
+	if m != nil {
+		return m.SourcePort
+	}
+	return 0
+}
+
+func (m *PacketTraceRequest) GetDestination() *IPAddress {
+	if m != nil {
+		return m.Destination
+	}
+	return nil
+}
+
+func (m *PacketTraceRequest) GetDestinationPort() uint32 {
+	if m != nil {
+		return m.DestinationPort
+	}
+	return 0
+}
+
+func (m *PacketTraceRequest) GetNewConnection() bool {
+	if m != nil {
+		return m.NewConnection
+	}
+	return false
+}
+
+func (m *PacketTraceRequest) GetHopLimit() uint32 {
+	if m != nil {
+		return m.HopLimit
+	}
+	return 0
+}
+
+func (m *PacketTraceRequest) GetIpOptions() bool {
+	if m != nil {
+		return m.IpOptions
+	}
+	return false
+}
+
+type TraceStep struct {
+	Stage                string   `protobuf:"bytes,1,opt,name=stage,proto3" json:"stage,omitempty"`
+	Result               string   `protobuf:"bytes,2,opt,name=result,proto3" json:"result,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TraceStep) Reset()         { *m = TraceStep{} }
+func (m *TraceStep) String() string { return proto.CompactTextString(m) }
+func (*TraceStep) ProtoMessage()    {}
+func (*TraceStep) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{77}
+}
+func (m *TraceStep) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TraceStep.Unmarshal(m, b)
+}
+func (m *TraceStep) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TraceStep.Marshal(b, m, deterministic)
+}
+func (dst *TraceStep) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TraceStep.Merge(dst, src)
+}
+func (m *TraceStep) XXX_Size() int {
+	return xxx_messageInfo_TraceStep.Size(m)
+}
+func (m *TraceStep) XXX_DiscardUnknown() {
+	xxx_messageInfo_TraceStep.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TraceStep proto.InternalMessageInfo
+
+func (m *TraceStep) GetStage() string {
+	if m != nil {
+		return m.Stage
+	}
+	return ""
+}
+
+func (m *TraceStep) GetResult() string {
+	if m != nil {
+		return m.Result
+	}
+	return ""
+}
+
+type PacketTraceReply struct {
+	Steps []*TraceStep `protobuf:"bytes,1,rep,name=steps,proto3" json:"steps,omitempty"`
+	// One of send, kni or drop
+	Verdict string `protobuf:"bytes,2,opt,name=verdict,proto3" json:"verdict,omitempty"`
+	// Port which sends packet to network or to its KNI interface, or
+	// which drops it
+	OutputInterfaceId uint32 `protobuf:"varint,3,opt,name=output_interface_id,json=outputInterfaceId,proto3" json:"output_interface_id,omitempty"`
+	// Packet after translation
+	Source               *IPAddress `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`
+	SourcePort           uint32     `protobuf:"varint,5,opt,name=source_port,json=sourcePort,proto3" json:"source_port,omitempty"`
+	Destination          *IPAddress `protobuf:"bytes,6,opt,name=destination,proto3" json:"destination,omitempty"`
+	DestinationPort      uint32     `protobuf:"varint,7,opt,name=destination_port,json=destinationPort,proto3" json:"destination_port,omitempty"`
+	Tenant               string     `protobuf:"bytes,8,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *PacketTraceReply) Reset()         { *m = PacketTraceReply{} }
+func (m *PacketTraceReply) String() string { return proto.CompactTextString(m) }
+func (*PacketTraceReply) ProtoMessage()    {}
+func (*PacketTraceReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{78}
+}
+func (m *PacketTraceReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PacketTraceReply.Unmarshal(m, b)
+}
+func (m *PacketTraceReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PacketTraceReply.Marshal(b, m, deterministic)
+}
+func (dst *PacketTraceReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PacketTraceReply.Merge(dst, src)
+}
+func (m *PacketTraceReply) XXX_Size() int {
+	return xxx_messageInfo_PacketTraceReply.Size(m)
+}
+func (m *PacketTraceReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_PacketTraceReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PacketTraceReply proto.InternalMessageInfo
+
+func (m *PacketTraceReply) GetSteps() []*TraceStep {
+	if m != nil {
+		return m.Steps
+	}
+	return nil
+}
+
+func (m *PacketTraceReply) GetVerdict() string {
+	if m != nil {
+		return m.Verdict
+	}
+	return ""
+}
+
+func (m *PacketTraceReply) GetOutputInterfaceId() uint32 {
+	if m != nil {
+		return m.OutputInterfaceId
+	}
+	return 0
+}
+
+func (m *PacketTraceReply) GetSource() *IPAddress {
+	if m != nil {
+		return m.Source
+	}
+	return nil
+}
+
+func (m *PacketTraceReply) GetSourcePort() uint32 {
+	if m != nil {
+		return m.SourcePort
+	}
+	return 0
+}
+
+func (m *PacketTraceReply) GetDestination() *IPAddress {
+	if m != nil {
+		return m.Destination
+	}
+	return nil
+}
+
+func (m *PacketTraceReply) GetDestinationPort() uint32 {
+	if m != nil {
+		return m.DestinationPort
+	}
+	return 0
+}
+
+func (m *PacketTraceReply) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+type ForwardedHandshakesRequest struct {
+	InterfaceId          uint32   `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ForwardedHandshakesRequest) Reset()         { *m = ForwardedHandshakesRequest{} }
+func (m *ForwardedHandshakesRequest) String() string { return proto.CompactTextString(m) }
+func (*ForwardedHandshakesRequest) ProtoMessage()    {}
+func (*ForwardedHandshakesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{79}
+}
+func (m *ForwardedHandshakesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ForwardedHandshakesRequest.Unmarshal(m, b)
+}
+func (m *ForwardedHandshakesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ForwardedHandshakesRequest.Marshal(b, m, deterministic)
+}
+func (dst *ForwardedHandshakesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ForwardedHandshakesRequest.Merge(dst, src)
+}
+func (m *ForwardedHandshakesRequest) XXX_Size() int {
+	return xxx_messageInfo_ForwardedHandshakesRequest.Size(m)
+}
+func (m *ForwardedHandshakesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ForwardedHandshakesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ForwardedHandshakesRequest proto.InternalMessageInfo
+
+func (m *ForwardedHandshakesRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+// Failed TCP handshake of remote host with private server
+type FailedHandshake struct {
+	Address *IPAddress `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Port    uint32     `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`
+	// Time of failure in nanoseconds since Unix epoch
+	Time int64 `protobuf:"varint,3,opt,name=time,proto3" json:"time,omitempty"`
+	// One of no-reply, refused or incomplete
+	Reason               string   `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FailedHandshake) Reset()         { *m = FailedHandshake{} }
+func (m *FailedHandshake) String() string { return proto.CompactTextString(m) }
+func (*FailedHandshake) ProtoMessage()    {}
+func (*FailedHandshake) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{80}
+}
+func (m *FailedHandshake) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FailedHandshake.Unmarshal(m, b)
+}
+func (m *FailedHandshake) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FailedHandshake.Marshal(b, m, deterministic)
+}
+func (dst *FailedHandshake) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FailedHandshake.Merge(dst, src)
+}
+func (m *FailedHandshake) XXX_Size() int {
+	return xxx_messageInfo_FailedHandshake.Size(m)
+}
+func (m *FailedHandshake) XXX_DiscardUnknown() {
+	xxx_messageInfo_FailedHandshake.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FailedHandshake proto.InternalMessageInfo
+
+func (m *FailedHandshake) GetAddress() *IPAddress {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *FailedHandshake) GetPort() uint32 {
+	if m != nil {
+		return m.Port
+	}
+	return 0
+}
+
+func (m *FailedHandshake) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *FailedHandshake) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+// TCP handshakes of forwarded port by outcome
+type ForwardedHandshakes struct {
+	Ipv6      bool   `protobuf:"varint,1,opt,name=ipv6,proto3" json:"ipv6,omitempty"`
+	Port      uint32 `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`
+	Attempts  uint64 `protobuf:"varint,3,opt,name=attempts,proto3" json:"attempts,omitempty"`
+	Completed uint64 `protobuf:"varint,4,opt,name=completed,proto3" json:"completed,omitempty"`
+	Pending   uint64 `protobuf:"varint,5,opt,name=pending,proto3" json:"pending,omitempty"`
+	// Private server didn't answer SYN
+	NoReply uint64 `protobuf:"varint,6,opt,name=no_reply,json=noReply,proto3" json:"no_reply,omitempty"`
+	// Private server answered SYN with RST
+	Refused uint64 `protobuf:"varint,7,opt,name=refused,proto3" json:"refused,omitempty"`
+	// Remote host didn't complete handshake answered by private server
+	Incomplete uint64 `protobuf:"varint,8,opt,name=incomplete,proto3" json:"incomplete,omitempty"`
+	// SYNs which were not tracked because too many handshakes were
+	// pending
+	Overflow uint64 `protobuf:"varint,9,opt,name=overflow,proto3" json:"overflow,omitempty"`
+	// Recent failed handshakes from oldest to newest
+	Failed               []*FailedHandshake `protobuf:"bytes,10,rep,name=failed,proto3" json:"failed,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *ForwardedHandshakes) Reset()         { *m = ForwardedHandshakes{} }
+func (m *ForwardedHandshakes) String() string { return proto.CompactTextString(m) }
+func (*ForwardedHandshakes) ProtoMessage()    {}
+func (*ForwardedHandshakes) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{81}
+}
+func (m *ForwardedHandshakes) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ForwardedHandshakes.Unmarshal(m, b)
+}
+func (m *ForwardedHandshakes) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ForwardedHandshakes.Marshal(b, m, deterministic)
+}
+func (dst *ForwardedHandshakes) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ForwardedHandshakes.Merge(dst, src)
+}
+func (m *ForwardedHandshakes) XXX_Size() int {
+	return xxx_messageInfo_ForwardedHandshakes.Size(m)
+}
+func (m *ForwardedHandshakes) XXX_DiscardUnknown() {
+	xxx_messageInfo_ForwardedHandshakes.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ForwardedHandshakes proto.InternalMessageInfo
+
+func (m *ForwardedHandshakes) GetIpv6() bool {
+	if m != nil {
+		return m.Ipv6
+	}
+	return false
+}
+
+func (m *ForwardedHandshakes) GetPort() uint32 {
+	if m != nil {
+		return m.Port
+	}
+	return 0
+}
+
+func (m *ForwardedHandshakes) GetAttempts() uint64 {
+	if m != nil {
+		return m.Attempts
+	}
+	return 0
+}
+
+func (m *ForwardedHandshakes) GetCompleted() uint64 {
+	if m != nil {
+		return m.Completed
+	}
+	return 0
+}
+
+func (m *ForwardedHandshakes) GetPending() uint64 {
+	if m != nil {
+		return m.Pending
+	}
+	return 0
+}
+
+func (m *ForwardedHandshakes) GetNoReply() uint64 {
+	if m != nil {
+		return m.NoReply
+	}
+	return 0
+}
+
+func (m *ForwardedHandshakes) GetRefused() uint64 {
+	if m != nil {
+		return m.Refused
+	}
+	return 0
+}
+
+func (m *ForwardedHandshakes) GetIncomplete() uint64 {
+	if m != nil {
+		return m.Incomplete
+	}
+	return 0
+}
+
+func (m *ForwardedHandshakes) GetOverflow() uint64 {
+	if m != nil {
+		return m.Overflow
+	}
+	return 0
+}
+
+func (m *ForwardedHandshakes) GetFailed() []*FailedHandshake {
+	if m != nil {
+		return m.Failed
+	}
+	return nil
+}
+
+type ForwardedHandshakesReply struct {
+	Forwards             []*ForwardedHandshakes `protobuf:"bytes,1,rep,name=forwards,proto3" json:"forwards,omitempty"`
+	Tenant               string                 `protobuf:"bytes,2,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
+}
+
+func (m *ForwardedHandshakesReply) Reset()         { *m = ForwardedHandshakesReply{} }
+func (m *ForwardedHandshakesReply) String() string { return proto.CompactTextString(m) }
+func (*ForwardedHandshakesReply) ProtoMessage()    {}
+func (*ForwardedHandshakesReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{82}
+}
+func (m *ForwardedHandshakesReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ForwardedHandshakesReply.Unmarshal(m, b)
+}
+func (m *ForwardedHandshakesReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ForwardedHandshakesReply.Marshal(b, m, deterministic)
+}
+func (dst *ForwardedHandshakesReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ForwardedHandshakesReply.Merge(dst, src)
+}
+func (m *ForwardedHandshakesReply) XXX_Size() int {
+	return xxx_messageInfo_ForwardedHandshakesReply.Size(m)
+}
+func (m *ForwardedHandshakesReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_ForwardedHandshakesReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ForwardedHandshakesReply proto.InternalMessageInfo
+
+func (m *ForwardedHandshakesReply) GetForwards() []*ForwardedHandshakes {
+	if m != nil {
+		return m.Forwards
+	}
+	return nil
+}
+
+func (m *ForwardedHandshakesReply) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+// New session which NAT asks authorization server to approve
+type SessionAuthorizationRequest struct {
+	// Class of session, egress for sessions started by private hosts or
+	// forwarded for connections of remote hosts to forwarded ports
+	Class string `protobuf:"bytes,1,opt,name=class,proto3" json:"class,omitempty"`
+	// Public port of port pair
+	InterfaceId uint32 `protobuf:"varint,2,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	Tenant      string `protobuf:"bytes,3,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	Ipv6        bool   `protobuf:"varint,4,opt,name=ipv6,proto3" json:"ipv6,omitempty"`
+	// IP protocol number
+	Protocol       uint32     `protobuf:"varint,5,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	PrivateAddress *IPAddress `protobuf:"bytes,6,opt,name=private_address,json=privateAddress,proto3" json:"private_address,omitempty"`
+	PrivatePort    uint32     `protobuf:"varint,7,opt,name=private_port,json=privatePort,proto3" json:"private_port,omitempty"`
+	RemoteAddress  *IPAddress `protobuf:"bytes,8,opt,name=remote_address,json=remoteAddress,proto3" json:"remote_address,omitempty"`
+	RemotePort     uint32     `protobuf:"varint,9,opt,name=remote_port,json=remotePort,proto3" json:"remote_port,omitempty"`
+	// Public port of forwarded port, zero for egress sessions
+	PublicPort           uint32   `protobuf:"varint,10,opt,name=public_port,json=publicPort,proto3" json:"public_port,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SessionAuthorizationRequest) Reset()         { *m = SessionAuthorizationRequest{} }
+func (m *SessionAuthorizationRequest) String() string { return proto.CompactTextString(m) }
+func (*SessionAuthorizationRequest) ProtoMessage()    {}
+func (*SessionAuthorizationRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{83}
+}
+func (m *SessionAuthorizationRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SessionAuthorizationRequest.Unmarshal(m, b)
+}
+func (m *SessionAuthorizationRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SessionAuthorizationRequest.Marshal(b, m, deterministic)
+}
+func (dst *SessionAuthorizationRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SessionAuthorizationRequest.Merge(dst, src)
+}
+func (m *SessionAuthorizationRequest) XXX_Size() int {
+	return xxx_messageInfo_SessionAuthorizationRequest.Size(m)
+}
+func (m *SessionAuthorizationRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SessionAuthorizationRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SessionAuthorizationRequest proto.InternalMessageInfo
+
+func (m *SessionAuthorizationRequest) GetClass() string {
+	if m != nil {
+		return m.Class
+	}
+	return ""
+}
+
+func (m *SessionAuthorizationRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *SessionAuthorizationRequest) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+func (m *SessionAuthorizationRequest) GetIpv6() bool {
+	if m != nil {
+		return m.Ipv6
+	}
+	return false
+}
+
+func (m *SessionAuthorizationRequest) GetProtocol() uint32 {
+	if m != nil {
+		return m.Protocol
+	}
+	return 0
+}
+
+func (m *SessionAuthorizationRequest) GetPrivateAddress() *IPAddress {
+	if m != nil {
+		return m.PrivateAddress
+	}
+	return nil
+}
+
+func (m *SessionAuthorizationRequest) GetPrivatePort() uint32 {
+	if m != nil {
+		return m.PrivatePort
+	}
+	return 0
+}
+
+func (m *SessionAuthorizationRequest) GetRemoteAddress() *IPAddress {
+	if m != nil {
+		return m.RemoteAddress
+	}
+	return nil
+}
+
+func (m *SessionAuthorizationRequest) GetRemotePort() uint32 {
+	if m != nil {
+		return m.RemotePort
+	}
+	return 0
+}
+
+func (m *SessionAuthorizationRequest) GetPublicPort() uint32 {
+	if m != nil {
+		return m.PublicPort
+	}
+	return 0
+}
+
+type SessionAuthorizationReply struct {
+	Allow bool `protobuf:"varint,1,opt,name=allow,proto3" json:"allow,omitempty"`
+	// Seconds which decision is cached for, zero means cache-time of
+	// config
+	CacheTime uint32 `protobuf:"varint,2,opt,name=cache_time,json=cacheTime,proto3" json:"cache_time,omitempty"`
+	// Decision applies to all new sessions of host in class, private
+	// host for egress and remote host for forwarded sessions
+	Host                 bool     `protobuf:"varint,3,opt,name=host,proto3" json:"host,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SessionAuthorizationReply) Reset()         { *m = SessionAuthorizationReply{} }
+func (m *SessionAuthorizationReply) String() string { return proto.CompactTextString(m) }
+func (*SessionAuthorizationReply) ProtoMessage()    {}
+func (*SessionAuthorizationReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{84}
+}
+func (m *SessionAuthorizationReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SessionAuthorizationReply.Unmarshal(m, b)
+}
+func (m *SessionAuthorizationReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SessionAuthorizationReply.Marshal(b, m, deterministic)
+}
+func (dst *SessionAuthorizationReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SessionAuthorizationReply.Merge(dst, src)
+}
+func (m *SessionAuthorizationReply) XXX_Size() int {
+	return xxx_messageInfo_SessionAuthorizationReply.Size(m)
+}
+func (m *SessionAuthorizationReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_SessionAuthorizationReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SessionAuthorizationReply proto.InternalMessageInfo
+
+func (m *SessionAuthorizationReply) GetAllow() bool {
+	if m != nil {
+		return m.Allow
+	}
+	return false
+}
+
+func (m *SessionAuthorizationReply) GetCacheTime() uint32 {
+	if m != nil {
+		return m.CacheTime
+	}
+	return 0
+}
+
+func (m *SessionAuthorizationReply) GetHost() bool {
+	if m != nil {
+		return m.Host
+	}
+	return false
+}
+
+// Private host is identified either by address or by MAC address
+type CaptivePortalHost struct {
+	Address              *IPAddress         `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	MacAddress           []byte             `protobuf:"bytes,2,opt,name=mac_address,json=macAddress,proto3" json:"mac_address,omitempty"`
+	State                CaptivePortalState `protobuf:"varint,3,opt,name=state,proto3,enum=updatecfg.CaptivePortalState" json:"state,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *CaptivePortalHost) Reset()         { *m = CaptivePortalHost{} }
+func (m *CaptivePortalHost) String() string { return proto.CompactTextString(m) }
+func (*CaptivePortalHost) ProtoMessage()    {}
+func (*CaptivePortalHost) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{85}
+}
+func (m *CaptivePortalHost) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CaptivePortalHost.Unmarshal(m, b)
+}
+func (m *CaptivePortalHost) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CaptivePortalHost.Marshal(b, m, deterministic)
+}
+func (dst *CaptivePortalHost) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CaptivePortalHost.Merge(dst, src)
+}
+func (m *CaptivePortalHost) XXX_Size() int {
+	return xxx_messageInfo_CaptivePortalHost.Size(m)
+}
+func (m *CaptivePortalHost) XXX_DiscardUnknown() {
+	xxx_messageInfo_CaptivePortalHost.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CaptivePortalHost proto.InternalMessageInfo
+
+func (m *CaptivePortalHost) GetAddress() *IPAddress {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *CaptivePortalHost) GetMacAddress() []byte {
+	if m != nil {
+		return m.MacAddress
+	}
+	return nil
+}
+
+func (m *CaptivePortalHost) GetState() CaptivePortalState {
+	if m != nil {
+		return m.State
+	}
+	return CaptivePortalState_PORTAL_DEFAULT
+}
+
+type CaptivePortalHostRequest struct {
+	InterfaceId uint32 `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	// Default state forgets host
+	Host                 *CaptivePortalHost `protobuf:"bytes,2,opt,name=host,proto3" json:"host,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *CaptivePortalHostRequest) Reset()         { *m = CaptivePortalHostRequest{} }
+func (m *CaptivePortalHostRequest) String() string { return proto.CompactTextString(m) }
+func (*CaptivePortalHostRequest) ProtoMessage()    {}
+func (*CaptivePortalHostRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{86}
+}
+func (m *CaptivePortalHostRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CaptivePortalHostRequest.Unmarshal(m, b)
+}
+func (m *CaptivePortalHostRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CaptivePortalHostRequest.Marshal(b, m, deterministic)
+}
+func (dst *CaptivePortalHostRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CaptivePortalHostRequest.Merge(dst, src)
+}
+func (m *CaptivePortalHostRequest) XXX_Size() int {
+	return xxx_messageInfo_CaptivePortalHostRequest.Size(m)
+}
+func (m *CaptivePortalHostRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CaptivePortalHostRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CaptivePortalHostRequest proto.InternalMessageInfo
+
+func (m *CaptivePortalHostRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *CaptivePortalHostRequest) GetHost() *CaptivePortalHost {
+	if m != nil {
+		return m.Host
+	}
+	return nil
+}
+
+type CaptivePortalHostsRequest struct {
+	InterfaceId          uint32   `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CaptivePortalHostsRequest) Reset()         { *m = CaptivePortalHostsRequest{} }
+func (m *CaptivePortalHostsRequest) String() string { return proto.CompactTextString(m) }
+func (*CaptivePortalHostsRequest) ProtoMessage()    {}
+func (*CaptivePortalHostsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{87}
+}
+func (m *CaptivePortalHostsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CaptivePortalHostsRequest.Unmarshal(m, b)
+}
+func (m *CaptivePortalHostsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CaptivePortalHostsRequest.Marshal(b, m, deterministic)
+}
+func (dst *CaptivePortalHostsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CaptivePortalHostsRequest.Merge(dst, src)
+}
+func (m *CaptivePortalHostsRequest) XXX_Size() int {
+	return xxx_messageInfo_CaptivePortalHostsRequest.Size(m)
+}
+func (m *CaptivePortalHostsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CaptivePortalHostsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CaptivePortalHostsRequest proto.InternalMessageInfo
+
+func (m *CaptivePortalHostsRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+type CaptivePortalHostsReply struct {
+	InterfaceId uint32 `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	// State of hosts which are not listed
+	DefaultState CaptivePortalState   `protobuf:"varint,2,opt,name=default_state,json=defaultState,proto3,enum=updatecfg.CaptivePortalState" json:"default_state,omitempty"`
+	Hosts        []*CaptivePortalHost `protobuf:"bytes,3,rep,name=hosts,proto3" json:"hosts,omitempty"`
+	// HTTP flows of unauthenticated hosts redirected to portal
+	RedirectedFlows      uint64   `protobuf:"varint,4,opt,name=redirected_flows,json=redirectedFlows,proto3" json:"redirected_flows,omitempty"`
+	Tenant               string   `protobuf:"bytes,5,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CaptivePortalHostsReply) Reset()         { *m = CaptivePortalHostsReply{} }
+func (m *CaptivePortalHostsReply) String() string { return proto.CompactTextString(m) }
+func (*CaptivePortalHostsReply) ProtoMessage()    {}
+func (*CaptivePortalHostsReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{88}
+}
+func (m *CaptivePortalHostsReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CaptivePortalHostsReply.Unmarshal(m, b)
+}
+func (m *CaptivePortalHostsReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CaptivePortalHostsReply.Marshal(b, m, deterministic)
+}
+func (dst *CaptivePortalHostsReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CaptivePortalHostsReply.Merge(dst, src)
+}
+func (m *CaptivePortalHostsReply) XXX_Size() int {
+	return xxx_messageInfo_CaptivePortalHostsReply.Size(m)
+}
+func (m *CaptivePortalHostsReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_CaptivePortalHostsReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CaptivePortalHostsReply proto.InternalMessageInfo
+
+func (m *CaptivePortalHostsReply) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+func (m *CaptivePortalHostsReply) GetDefaultState() CaptivePortalState {
+	if m != nil {
+		return m.DefaultState
+	}
+	return CaptivePortalState_PORTAL_DEFAULT
+}
+
+func (m *CaptivePortalHostsReply) GetHosts() []*CaptivePortalHost {
+	if m != nil {
+		return m.Hosts
+	}
+	return nil
+}
+
+func (m *CaptivePortalHostsReply) GetRedirectedFlows() uint64 {
+	if m != nil {
+		return m.RedirectedFlows
+	}
+	return 0
+}
+
+func (m *CaptivePortalHostsReply) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+type HostIdentitiesRequest struct {
+	InterfaceId          uint32   `protobuf:"varint,1,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HostIdentitiesRequest) Reset()         { *m = HostIdentitiesRequest{} }
+func (m *HostIdentitiesRequest) String() string { return proto.CompactTextString(m) }
+func (*HostIdentitiesRequest) ProtoMessage()    {}
+func (*HostIdentitiesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{89}
+}
+func (m *HostIdentitiesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HostIdentitiesRequest.Unmarshal(m, b)
+}
+func (m *HostIdentitiesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HostIdentitiesRequest.Marshal(b, m, deterministic)
+}
+func (dst *HostIdentitiesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HostIdentitiesRequest.Merge(dst, src)
+}
+func (m *HostIdentitiesRequest) XXX_Size() int {
+	return xxx_messageInfo_HostIdentitiesRequest.Size(m)
+}
+func (m *HostIdentitiesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_HostIdentitiesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HostIdentitiesRequest proto.InternalMessageInfo
+
+func (m *HostIdentitiesRequest) GetInterfaceId() uint32 {
+	if m != nil {
+		return m.InterfaceId
+	}
+	return 0
+}
+
+// Private host identified by MAC address which it uses in ARP, ND,
+// DHCP and translated packets. New IPv4 address of host replaces its
+// old one, IPv6 addresses are kept until host is forgotten.
+type HostIdentity struct {
+	MacAddress []byte       `protobuf:"bytes,1,opt,name=mac_address,json=macAddress,proto3" json:"mac_address,omitempty"`
+	Addresses  []*IPAddress `protobuf:"bytes,2,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	// Times in nanoseconds since Unix epoch
+	FirstSeen int64 `protobuf:"varint,3,opt,name=first_seen,json=firstSeen,proto3" json:"first_seen,omitempty"`
+	LastSeen  int64 `protobuf:"varint,4,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+	// Changes of IPv4 address
+	AddressChanges uint64 `protobuf:"varint,5,opt,name=address_changes,json=addressChanges,proto3" json:"address_changes,omitempty"`
+	// Translated traffic of host with all its addresses
+	EgressPackets        uint64   `protobuf:"varint,6,opt,name=egress_packets,json=egressPackets,proto3" json:"egress_packets,omitempty"`
+	EgressBytes          uint64   `protobuf:"varint,7,opt,name=egress_bytes,json=egressBytes,proto3" json:"egress_bytes,omitempty"`
+	IngressPackets       uint64   `protobuf:"varint,8,opt,name=ingress_packets,json=ingressPackets,proto3" json:"ingress_packets,omitempty"`
+	IngressBytes         uint64   `protobuf:"varint,9,opt,name=ingress_bytes,json=ingressBytes,proto3" json:"ingress_bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HostIdentity) Reset()         { *m = HostIdentity{} }
+func (m *HostIdentity) String() string { return proto.CompactTextString(m) }
+func (*HostIdentity) ProtoMessage()    {}
+func (*HostIdentity) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{90}
+}
+func (m *HostIdentity) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HostIdentity.Unmarshal(m, b)
+}
+func (m *HostIdentity) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HostIdentity.Marshal(b, m, deterministic)
+}
+func (dst *HostIdentity) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HostIdentity.Merge(dst, src)
+}
+func (m *HostIdentity) XXX_Size() int {
+	return xxx_messageInfo_HostIdentity.Size(m)
+}
+func (m *HostIdentity) XXX_DiscardUnknown() {
+	xxx_messageInfo_HostIdentity.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HostIdentity proto.InternalMessageInfo
+
+func (m *HostIdentity) GetMacAddress() []byte {
+	if m != nil {
+		return m.MacAddress
+	}
+	return nil
+}
+
+func (m *HostIdentity) GetAddresses() []*IPAddress {
+	if m != nil {
+		return m.Addresses
+	}
+	return nil
+}
+
+func (m *HostIdentity) GetFirstSeen() int64 {
+	if m != nil {
+		return m.FirstSeen
+	}
+	return 0
+}
+
+func (m *HostIdentity) GetLastSeen() int64 {
+	if m != nil {
+		return m.LastSeen
+	}
+	return 0
+}
+
+func (m *HostIdentity) GetAddressChanges() uint64 {
+	if m != nil {
+		return m.AddressChanges
+	}
+	return 0
+}
+
+func (m *HostIdentity) GetEgressPackets() uint64 {
+	if m != nil {
+		return m.EgressPackets
+	}
+	return 0
+}
+
+func (m *HostIdentity) GetEgressBytes() uint64 {
+	if m != nil {
+		return m.EgressBytes
+	}
+	return 0
+}
+
+func (m *HostIdentity) GetIngressPackets() uint64 {
+	if m != nil {
+		return m.IngressPackets
+	}
+	return 0
+}
+
+func (m *HostIdentity) GetIngressBytes() uint64 {
+	if m != nil {
+		return m.IngressBytes
+	}
+	return 0
+}
+
+type HostIdentitiesReply struct {
+	Hosts []*HostIdentity `protobuf:"bytes,1,rep,name=hosts,proto3" json:"hosts,omitempty"`
+	// Hosts which were not tracked because table was full
+	Overflow             uint64   `protobuf:"varint,2,opt,name=overflow,proto3" json:"overflow,omitempty"`
+	Tenant               string   `protobuf:"bytes,3,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HostIdentitiesReply) Reset()         { *m = HostIdentitiesReply{} }
+func (m *HostIdentitiesReply) String() string { return proto.CompactTextString(m) }
+func (*HostIdentitiesReply) ProtoMessage()    {}
+func (*HostIdentitiesReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_updatecfg_022d0bfb299e358c, []int{91}
+}
+func (m *HostIdentitiesReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HostIdentitiesReply.Unmarshal(m, b)
+}
+func (m *HostIdentitiesReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HostIdentitiesReply.Marshal(b, m, deterministic)
+}
+func (dst *HostIdentitiesReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HostIdentitiesReply.Merge(dst, src)
+}
+func (m *HostIdentitiesReply) XXX_Size() int {
+	return xxx_messageInfo_HostIdentitiesReply.Size(m)
+}
+func (m *HostIdentitiesReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_HostIdentitiesReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HostIdentitiesReply proto.InternalMessageInfo
+
+func (m *HostIdentitiesReply) GetHosts() []*HostIdentity {
+	if m != nil {
+		return m.Hosts
+	}
+	return nil
+}
+
+func (m *HostIdentitiesReply) GetOverflow() uint64 {
+	if m != nil {
+		return m.Overflow
+	}
+	return 0
+}
+
+func (m *HostIdentitiesReply) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*DumpControlRequest)(nil), "updatecfg.DumpControlRequest")
+	proto.RegisterType((*DumpStreamRequest)(nil), "updatecfg.DumpStreamRequest")
+	proto.RegisterType((*DumpedPacket)(nil), "updatecfg.DumpedPacket")
+	proto.RegisterType((*DumpSinkConnectRequest)(nil), "updatecfg.DumpSinkConnectRequest")
+	proto.RegisterType((*DumpSinkDisconnectRequest)(nil), "updatecfg.DumpSinkDisconnectRequest")
+	proto.RegisterType((*IPAddress)(nil), "updatecfg.IPAddress")
+	proto.RegisterType((*Subnet)(nil), "updatecfg.Subnet")
+	proto.RegisterType((*InterfaceAddressChangeRequest)(nil), "updatecfg.InterfaceAddressChangeRequest")
+	proto.RegisterType((*ForwardedPort)(nil), "updatecfg.ForwardedPort")
+	proto.RegisterType((*ForwardedSource)(nil), "updatecfg.ForwardedSource")
+	proto.RegisterType((*PortForwardingChangeRequest)(nil), "updatecfg.PortForwardingChangeRequest")
+	proto.RegisterType((*Neighbor)(nil), "updatecfg.Neighbor")
+	proto.RegisterType((*NeighborsRequest)(nil), "updatecfg.NeighborsRequest")
+	proto.RegisterType((*NeighborsReply)(nil), "updatecfg.NeighborsReply")
+	proto.RegisterType((*NeighborChangeRequest)(nil), "updatecfg.NeighborChangeRequest")
+	proto.RegisterType((*NeighborsFlushRequest)(nil), "updatecfg.NeighborsFlushRequest")
+	proto.RegisterType((*DHCPLeaseRequest)(nil), "updatecfg.DHCPLeaseRequest")
+	proto.RegisterType((*DHCPControlRequest)(nil), "updatecfg.DHCPControlRequest")
+	proto.RegisterType((*DHCPLeaseReply)(nil), "updatecfg.DHCPLeaseReply")
+	proto.RegisterType((*WakeOnLANRequest)(nil), "updatecfg.WakeOnLANRequest")
+	proto.RegisterType((*PortStatisticsRequest)(nil), "updatecfg.PortStatisticsRequest")
+	proto.RegisterType((*Counter)(nil), "updatecfg.Counter")
+	proto.RegisterType((*PortStatisticsReply)(nil), "updatecfg.PortStatisticsReply")
+	proto.RegisterType((*LinkStatusRequest)(nil), "updatecfg.LinkStatusRequest")
+	proto.RegisterType((*LinkStatusReply)(nil), "updatecfg.LinkStatusReply")
+	proto.RegisterType((*EgressPolicerHost)(nil), "updatecfg.EgressPolicerHost")
+	proto.RegisterType((*EgressPolicerChangeRequest)(nil), "updatecfg.EgressPolicerChangeRequest")
+	proto.RegisterType((*SubscribersRequest)(nil), "updatecfg.SubscribersRequest")
+	proto.RegisterType((*PolicerCounters)(nil), "updatecfg.PolicerCounters")
+	proto.RegisterType((*Subscriber)(nil), "updatecfg.Subscriber")
+	proto.RegisterType((*SubscribersReply)(nil), "updatecfg.SubscribersReply")
+	proto.RegisterType((*SessionsExportRequest)(nil), "updatecfg.SessionsExportRequest")
+	proto.RegisterType((*Session)(nil), "updatecfg.Session")
+	proto.RegisterType((*SessionSnapshot)(nil), "updatecfg.SessionSnapshot")
+	proto.RegisterType((*TopTalkersRequest)(nil), "updatecfg.TopTalkersRequest")
+	proto.RegisterType((*ProtocolCounters)(nil), "updatecfg.ProtocolCounters")
+	proto.RegisterType((*Talker)(nil), "updatecfg.Talker")
+	proto.RegisterType((*TopTalkersReply)(nil), "updatecfg.TopTalkersReply")
+	proto.RegisterType((*RouteAnnouncementRequest)(nil), "updatecfg.RouteAnnouncementRequest")
+	proto.RegisterType((*SessionDeleteRequest)(nil), "updatecfg.SessionDeleteRequest")
+	proto.RegisterType((*BlackholeRule)(nil), "updatecfg.BlackholeRule")
+	proto.RegisterType((*BlackholeRuleChangeRequest)(nil), "updatecfg.BlackholeRuleChangeRequest")
+	proto.RegisterType((*BlackholeRulesRequest)(nil), "updatecfg.BlackholeRulesRequest")
+	proto.RegisterType((*BlackholeRulesReply)(nil), "updatecfg.BlackholeRulesReply")
+	proto.RegisterType((*ApplicationsRequest)(nil), "updatecfg.ApplicationsRequest")
+	proto.RegisterType((*ApplicationCounters)(nil), "updatecfg.ApplicationCounters")
+	proto.RegisterType((*ApplicationsReply)(nil), "updatecfg.ApplicationsReply")
+	proto.RegisterType((*CountriesRequest)(nil), "updatecfg.CountriesRequest")
+	proto.RegisterType((*CountryCounters)(nil), "updatecfg.CountryCounters")
+	proto.RegisterType((*CountriesReply)(nil), "updatecfg.CountriesReply")
+	proto.RegisterType((*PortTrigger)(nil), "updatecfg.PortTrigger")
+	proto.RegisterType((*PortTriggersChangeRequest)(nil), "updatecfg.PortTriggersChangeRequest")
+	proto.RegisterType((*PortTriggersRequest)(nil), "updatecfg.PortTriggersRequest")
+	proto.RegisterType((*TriggeredPort)(nil), "updatecfg.TriggeredPort")
+	proto.RegisterType((*PortTriggersReply)(nil), "updatecfg.PortTriggersReply")
+	proto.RegisterType((*SessionLogSamplingRequest)(nil), "updatecfg.SessionLogSamplingRequest")
+	proto.RegisterType((*RunningConfigRequest)(nil), "updatecfg.RunningConfigRequest")
+	proto.RegisterType((*RunningConfigReply)(nil), "updatecfg.RunningConfigReply")
+	proto.RegisterType((*CommitConfigRequest)(nil), "updatecfg.CommitConfigRequest")
+	proto.RegisterType((*ConfirmCommitRequest)(nil), "updatecfg.ConfirmCommitRequest")
+	proto.RegisterType((*RollbackCommitRequest)(nil), "updatecfg.RollbackCommitRequest")
+	proto.RegisterType((*Reply)(nil), "updatecfg.Reply")
+	proto.RegisterType((*SessionFilter)(nil), "updatecfg.SessionFilter")
+	proto.RegisterType((*SessionsFindRequest)(nil), "updatecfg.SessionsFindRequest")
+	proto.RegisterType((*SessionsFindReply)(nil), "updatecfg.SessionsFindReply")
+	proto.RegisterType((*ExternalAddressRequest)(nil), "updatecfg.ExternalAddressRequest")
+	proto.RegisterType((*ExternalAddressReply)(nil), "updatecfg.ExternalAddressReply")
+	proto.RegisterType((*FlowGraphRequest)(nil), "updatecfg.FlowGraphRequest")
+	proto.RegisterType((*FlowNode)(nil), "updatecfg.FlowNode")
+	proto.RegisterType((*FlowEdge)(nil), "updatecfg.FlowEdge")
+	proto.RegisterType((*FlowGraphReply)(nil), "updatecfg.FlowGraphReply")
+	proto.RegisterType((*MaintenanceRequest)(nil), "updatecfg.MaintenanceRequest")
+	proto.RegisterType((*MaintenanceStatusRequest)(nil), "updatecfg.MaintenanceStatusRequest")
+	proto.RegisterType((*MaintenanceReply)(nil), "updatecfg.MaintenanceReply")
+	proto.RegisterType((*NftablesRulesetRequest)(nil), "updatecfg.NftablesRulesetRequest")
+	proto.RegisterType((*NftablesRulesetReply)(nil), "updatecfg.NftablesRulesetReply")
+	proto.RegisterType((*PacketTraceRequest)(nil), "updatecfg.PacketTraceRequest")
+	proto.RegisterType((*TraceStep)(nil), "updatecfg.TraceStep")
+	proto.RegisterType((*PacketTraceReply)(nil), "updatecfg.PacketTraceReply")
+	proto.RegisterType((*ForwardedHandshakesRequest)(nil), "updatecfg.ForwardedHandshakesRequest")
+	proto.RegisterType((*FailedHandshake)(nil), "updatecfg.FailedHandshake")
+	proto.RegisterType((*ForwardedHandshakes)(nil), "updatecfg.ForwardedHandshakes")
+	proto.RegisterType((*ForwardedHandshakesReply)(nil), "updatecfg.ForwardedHandshakesReply")
+	proto.RegisterType((*SessionAuthorizationRequest)(nil), "updatecfg.SessionAuthorizationRequest")
+	proto.RegisterType((*SessionAuthorizationReply)(nil), "updatecfg.SessionAuthorizationReply")
+	proto.RegisterType((*CaptivePortalHost)(nil), "updatecfg.CaptivePortalHost")
+	proto.RegisterType((*CaptivePortalHostRequest)(nil), "updatecfg.CaptivePortalHostRequest")
+	proto.RegisterType((*CaptivePortalHostsRequest)(nil), "updatecfg.CaptivePortalHostsRequest")
+	proto.RegisterType((*CaptivePortalHostsReply)(nil), "updatecfg.CaptivePortalHostsReply")
+	proto.RegisterType((*HostIdentitiesRequest)(nil), "updatecfg.HostIdentitiesRequest")
+	proto.RegisterType((*HostIdentity)(nil), "updatecfg.HostIdentity")
+	proto.RegisterType((*HostIdentitiesReply)(nil), "updatecfg.HostIdentitiesReply")
+	proto.RegisterEnum("updatecfg.TraceType", TraceType_name, TraceType_value)
+	proto.RegisterEnum("updatecfg.Protocol", Protocol_name, Protocol_value)
+	proto.RegisterEnum("updatecfg.DHCPState", DHCPState_name, DHCPState_value)
+	proto.RegisterEnum("updatecfg.DHCPAction", DHCPAction_name, DHCPAction_value)
+	proto.RegisterEnum("updatecfg.SessionKind", SessionKind_name, SessionKind_value)
+	proto.RegisterEnum("updatecfg.CaptivePortalState", CaptivePortalState_name, CaptivePortalState_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// UpdaterClient is the client API for Updater service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type UpdaterClient interface {
+	ControlDump(ctx context.Context, in *DumpControlRequest, opts ...grpc.CallOption) (*Reply, error)
+	ChangeInterfaceAddress(ctx context.Context, in *InterfaceAddressChangeRequest, opts ...grpc.CallOption) (*Reply, error)
+	ChangePortForwarding(ctx context.Context, in *PortForwardingChangeRequest, opts ...grpc.CallOption) (*Reply, error)
+	GetNeighbors(ctx context.Context, in *NeighborsRequest, opts ...grpc.CallOption) (*NeighborsReply, error)
+	AddStaticNeighbor(ctx context.Context, in *NeighborChangeRequest, opts ...grpc.CallOption) (*Reply, error)
+	DeleteNeighbor(ctx context.Context, in *NeighborChangeRequest, opts ...grpc.CallOption) (*Reply, error)
+	FlushNeighbors(ctx context.Context, in *NeighborsFlushRequest, opts ...grpc.CallOption) (*Reply, error)
+	GetDHCPLease(ctx context.Context, in *DHCPLeaseRequest, opts ...grpc.CallOption) (*DHCPLeaseReply, error)
+	ControlDHCP(ctx context.Context, in *DHCPControlRequest, opts ...grpc.CallOption) (*DHCPLeaseReply, error)
+	StreamDump(ctx context.Context, in *DumpStreamRequest, opts ...grpc.CallOption) (Updater_StreamDumpClient, error)
+	ConnectDumpSink(ctx context.Context, in *DumpSinkConnectRequest, opts ...grpc.CallOption) (*Reply, error)
+	DisconnectDumpSink(ctx context.Context, in *DumpSinkDisconnectRequest, opts ...grpc.CallOption) (*Reply, error)
+	SendWakeOnLAN(ctx context.Context, in *WakeOnLANRequest, opts ...grpc.CallOption) (*Reply, error)
+	GetPortStatistics(ctx context.Context, in *PortStatisticsRequest, opts ...grpc.CallOption) (*PortStatisticsReply, error)
+	GetLinkStatus(ctx context.Context, in *LinkStatusRequest, opts ...grpc.CallOption) (*LinkStatusReply, error)
+	ChangeEgressPolicer(ctx context.Context, in *EgressPolicerChangeRequest, opts ...grpc.CallOption) (*Reply, error)
+	GetSubscribers(ctx context.Context, in *SubscribersRequest, opts ...grpc.CallOption) (*SubscribersReply, error)
+	ExportSessions(ctx context.Context, in *SessionsExportRequest, opts ...grpc.CallOption) (*SessionSnapshot, error)
+	ImportSessions(ctx context.Context, in *SessionSnapshot, opts ...grpc.CallOption) (*Reply, error)
+	GetTopTalkers(ctx context.Context, in *TopTalkersRequest, opts ...grpc.CallOption) (*TopTalkersReply, error)
+	ControlRouteAnnouncement(ctx context.Context, in *RouteAnnouncementRequest, opts ...grpc.CallOption) (*Reply, error)
+	DeleteSession(ctx context.Context, in *SessionDeleteRequest, opts ...grpc.CallOption) (*Reply, error)
+	ChangeBlackholeRule(ctx context.Context, in *BlackholeRuleChangeRequest, opts ...grpc.CallOption) (*Reply, error)
+	GetBlackholeRules(ctx context.Context, in *BlackholeRulesRequest, opts ...grpc.CallOption) (*BlackholeRulesReply, error)
+	GetApplications(ctx context.Context, in *ApplicationsRequest, opts ...grpc.CallOption) (*ApplicationsReply, error)
+	GetCountries(ctx context.Context, in *CountriesRequest, opts ...grpc.CallOption) (*CountriesReply, error)
+	SetPortTriggers(ctx context.Context, in *PortTriggersChangeRequest, opts ...grpc.CallOption) (*Reply, error)
+	GetPortTriggers(ctx context.Context, in *PortTriggersRequest, opts ...grpc.CallOption) (*PortTriggersReply, error)
+	ChangeSessionLogSampling(ctx context.Context, in *SessionLogSamplingRequest, opts ...grpc.CallOption) (*Reply, error)
+	GetRunningConfig(ctx context.Context, in *RunningConfigRequest, opts ...grpc.CallOption) (*RunningConfigReply, error)
+	CommitConfig(ctx context.Context, in *CommitConfigRequest, opts ...grpc.CallOption) (*Reply, error)
+	ConfirmCommit(ctx context.Context, in *ConfirmCommitRequest, opts ...grpc.CallOption) (*Reply, error)
+	RollbackCommit(ctx context.Context, in *RollbackCommitRequest, opts ...grpc.CallOption) (*Reply, error)
+	FindSessions(ctx context.Context, in *SessionsFindRequest, opts ...grpc.CallOption) (*SessionsFindReply, error)
+	GetExternalAddress(ctx context.Context, in *ExternalAddressRequest, opts ...grpc.CallOption) (*ExternalAddressReply, error)
+	GetFlowGraph(ctx context.Context, in *FlowGraphRequest, opts ...grpc.CallOption) (*FlowGraphReply, error)
+	SetMaintenance(ctx context.Context, in *MaintenanceRequest, opts ...grpc.CallOption) (*MaintenanceReply, error)
+	GetMaintenance(ctx context.Context, in *MaintenanceStatusRequest, opts ...grpc.CallOption) (*MaintenanceReply, error)
+	GetNftablesRuleset(ctx context.Context, in *NftablesRulesetRequest, opts ...grpc.CallOption) (*NftablesRulesetReply, error)
+	TracePacket(ctx context.Context, in *PacketTraceRequest, opts ...grpc.CallOption) (*PacketTraceReply, error)
+	GetForwardedHandshakes(ctx context.Context, in *ForwardedHandshakesRequest, opts ...grpc.CallOption) (*ForwardedHandshakesReply, error)
+	SetCaptivePortalHost(ctx context.Context, in *CaptivePortalHostRequest, opts ...grpc.CallOption) (*CaptivePortalHostsReply, error)
+	GetCaptivePortalHosts(ctx context.Context, in *CaptivePortalHostsRequest, opts ...grpc.CallOption) (*CaptivePortalHostsReply, error)
+	GetHostIdentities(ctx context.Context, in *HostIdentitiesRequest, opts ...grpc.CallOption) (*HostIdentitiesReply, error)
+}
+
+type updaterClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewUpdaterClient(cc *grpc.ClientConn) UpdaterClient {
+	return &updaterClient{cc}
+}
+
+func (c *updaterClient) ControlDump(ctx context.Context, in *DumpControlRequest, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/ControlDump", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) ChangeInterfaceAddress(ctx context.Context, in *InterfaceAddressChangeRequest, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/ChangeInterfaceAddress", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) ChangePortForwarding(ctx context.Context, in *PortForwardingChangeRequest, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/ChangePortForwarding", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) GetNeighbors(ctx context.Context, in *NeighborsRequest, opts ...grpc.CallOption) (*NeighborsReply, error) {
+	out := new(NeighborsReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/GetNeighbors", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) AddStaticNeighbor(ctx context.Context, in *NeighborChangeRequest, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/AddStaticNeighbor", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) DeleteNeighbor(ctx context.Context, in *NeighborChangeRequest, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/DeleteNeighbor", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) FlushNeighbors(ctx context.Context, in *NeighborsFlushRequest, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/FlushNeighbors", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) GetDHCPLease(ctx context.Context, in *DHCPLeaseRequest, opts ...grpc.CallOption) (*DHCPLeaseReply, error) {
+	out := new(DHCPLeaseReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/GetDHCPLease", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) ControlDHCP(ctx context.Context, in *DHCPControlRequest, opts ...grpc.CallOption) (*DHCPLeaseReply, error) {
+	out := new(DHCPLeaseReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/ControlDHCP", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) StreamDump(ctx context.Context, in *DumpStreamRequest, opts ...grpc.CallOption) (Updater_StreamDumpClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Updater_serviceDesc.Streams[0], "/updatecfg.Updater/StreamDump", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &updaterStreamDumpClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Updater_StreamDumpClient interface {
+	Recv() (*DumpedPacket, error)
+	grpc.ClientStream
+}
+
+type updaterStreamDumpClient struct {
+	grpc.ClientStream
+}
+
+func (x *updaterStreamDumpClient) Recv() (*DumpedPacket, error) {
+	m := new(DumpedPacket)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *updaterClient) ConnectDumpSink(ctx context.Context, in *DumpSinkConnectRequest, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/ConnectDumpSink", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) DisconnectDumpSink(ctx context.Context, in *DumpSinkDisconnectRequest, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/DisconnectDumpSink", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) SendWakeOnLAN(ctx context.Context, in *WakeOnLANRequest, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/SendWakeOnLAN", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) GetPortStatistics(ctx context.Context, in *PortStatisticsRequest, opts ...grpc.CallOption) (*PortStatisticsReply, error) {
+	out := new(PortStatisticsReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/GetPortStatistics", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) GetLinkStatus(ctx context.Context, in *LinkStatusRequest, opts ...grpc.CallOption) (*LinkStatusReply, error) {
+	out := new(LinkStatusReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/GetLinkStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) ChangeEgressPolicer(ctx context.Context, in *EgressPolicerChangeRequest, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/ChangeEgressPolicer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) GetSubscribers(ctx context.Context, in *SubscribersRequest, opts ...grpc.CallOption) (*SubscribersReply, error) {
+	out := new(SubscribersReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/GetSubscribers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) ExportSessions(ctx context.Context, in *SessionsExportRequest, opts ...grpc.CallOption) (*SessionSnapshot, error) {
+	out := new(SessionSnapshot)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/ExportSessions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) ImportSessions(ctx context.Context, in *SessionSnapshot, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/ImportSessions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) GetTopTalkers(ctx context.Context, in *TopTalkersRequest, opts ...grpc.CallOption) (*TopTalkersReply, error) {
+	out := new(TopTalkersReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/GetTopTalkers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) ControlRouteAnnouncement(ctx context.Context, in *RouteAnnouncementRequest, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/ControlRouteAnnouncement", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) DeleteSession(ctx context.Context, in *SessionDeleteRequest, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/DeleteSession", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) ChangeBlackholeRule(ctx context.Context, in *BlackholeRuleChangeRequest, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/ChangeBlackholeRule", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) GetBlackholeRules(ctx context.Context, in *BlackholeRulesRequest, opts ...grpc.CallOption) (*BlackholeRulesReply, error) {
+	out := new(BlackholeRulesReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/GetBlackholeRules", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) GetApplications(ctx context.Context, in *ApplicationsRequest, opts ...grpc.CallOption) (*ApplicationsReply, error) {
+	out := new(ApplicationsReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/GetApplications", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) GetCountries(ctx context.Context, in *CountriesRequest, opts ...grpc.CallOption) (*CountriesReply, error) {
+	out := new(CountriesReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/GetCountries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) SetPortTriggers(ctx context.Context, in *PortTriggersChangeRequest, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/SetPortTriggers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) GetPortTriggers(ctx context.Context, in *PortTriggersRequest, opts ...grpc.CallOption) (*PortTriggersReply, error) {
+	out := new(PortTriggersReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/GetPortTriggers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) ChangeSessionLogSampling(ctx context.Context, in *SessionLogSamplingRequest, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/ChangeSessionLogSampling", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) GetRunningConfig(ctx context.Context, in *RunningConfigRequest, opts ...grpc.CallOption) (*RunningConfigReply, error) {
+	out := new(RunningConfigReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/GetRunningConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) CommitConfig(ctx context.Context, in *CommitConfigRequest, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/CommitConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) ConfirmCommit(ctx context.Context, in *ConfirmCommitRequest, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/ConfirmCommit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) RollbackCommit(ctx context.Context, in *RollbackCommitRequest, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/RollbackCommit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) FindSessions(ctx context.Context, in *SessionsFindRequest, opts ...grpc.CallOption) (*SessionsFindReply, error) {
+	out := new(SessionsFindReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/FindSessions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) GetExternalAddress(ctx context.Context, in *ExternalAddressRequest, opts ...grpc.CallOption) (*ExternalAddressReply, error) {
+	out := new(ExternalAddressReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/GetExternalAddress", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) GetFlowGraph(ctx context.Context, in *FlowGraphRequest, opts ...grpc.CallOption) (*FlowGraphReply, error) {
+	out := new(FlowGraphReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/GetFlowGraph", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) SetMaintenance(ctx context.Context, in *MaintenanceRequest, opts ...grpc.CallOption) (*MaintenanceReply, error) {
+	out := new(MaintenanceReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/SetMaintenance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) GetMaintenance(ctx context.Context, in *MaintenanceStatusRequest, opts ...grpc.CallOption) (*MaintenanceReply, error) {
+	out := new(MaintenanceReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/GetMaintenance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) GetNftablesRuleset(ctx context.Context, in *NftablesRulesetRequest, opts ...grpc.CallOption) (*NftablesRulesetReply, error) {
+	out := new(NftablesRulesetReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/GetNftablesRuleset", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) TracePacket(ctx context.Context, in *PacketTraceRequest, opts ...grpc.CallOption) (*PacketTraceReply, error) {
+	out := new(PacketTraceReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/TracePacket", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) GetForwardedHandshakes(ctx context.Context, in *ForwardedHandshakesRequest, opts ...grpc.CallOption) (*ForwardedHandshakesReply, error) {
+	out := new(ForwardedHandshakesReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/GetForwardedHandshakes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) SetCaptivePortalHost(ctx context.Context, in *CaptivePortalHostRequest, opts ...grpc.CallOption) (*CaptivePortalHostsReply, error) {
+	out := new(CaptivePortalHostsReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/SetCaptivePortalHost", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) GetCaptivePortalHosts(ctx context.Context, in *CaptivePortalHostsRequest, opts ...grpc.CallOption) (*CaptivePortalHostsReply, error) {
+	out := new(CaptivePortalHostsReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/GetCaptivePortalHosts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updaterClient) GetHostIdentities(ctx context.Context, in *HostIdentitiesRequest, opts ...grpc.CallOption) (*HostIdentitiesReply, error) {
+	out := new(HostIdentitiesReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.Updater/GetHostIdentities", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdaterServer is the server API for Updater service.
+type UpdaterServer interface {
+	ControlDump(context.Context, *DumpControlRequest) (*Reply, error)
+	ChangeInterfaceAddress(context.Context, *InterfaceAddressChangeRequest) (*Reply, error)
+	ChangePortForwarding(context.Context, *PortForwardingChangeRequest) (*Reply, error)
+	GetNeighbors(context.Context, *NeighborsRequest) (*NeighborsReply, error)
+	AddStaticNeighbor(context.Context, *NeighborChangeRequest) (*Reply, error)
+	DeleteNeighbor(context.Context, *NeighborChangeRequest) (*Reply, error)
+	FlushNeighbors(context.Context, *NeighborsFlushRequest) (*Reply, error)
+	GetDHCPLease(context.Context, *DHCPLeaseRequest) (*DHCPLeaseReply, error)
+	ControlDHCP(context.Context, *DHCPControlRequest) (*DHCPLeaseReply, error)
+	StreamDump(*DumpStreamRequest, Updater_StreamDumpServer) error
+	ConnectDumpSink(context.Context, *DumpSinkConnectRequest) (*Reply, error)
+	DisconnectDumpSink(context.Context, *DumpSinkDisconnectRequest) (*Reply, error)
+	SendWakeOnLAN(context.Context, *WakeOnLANRequest) (*Reply, error)
+	GetPortStatistics(context.Context, *PortStatisticsRequest) (*PortStatisticsReply, error)
+	GetLinkStatus(context.Context, *LinkStatusRequest) (*LinkStatusReply, error)
+	ChangeEgressPolicer(context.Context, *EgressPolicerChangeRequest) (*Reply, error)
+	GetSubscribers(context.Context, *SubscribersRequest) (*SubscribersReply, error)
+	ExportSessions(context.Context, *SessionsExportRequest) (*SessionSnapshot, error)
+	ImportSessions(context.Context, *SessionSnapshot) (*Reply, error)
+	GetTopTalkers(context.Context, *TopTalkersRequest) (*TopTalkersReply, error)
+	ControlRouteAnnouncement(context.Context, *RouteAnnouncementRequest) (*Reply, error)
+	DeleteSession(context.Context, *SessionDeleteRequest) (*Reply, error)
+	ChangeBlackholeRule(context.Context, *BlackholeRuleChangeRequest) (*Reply, error)
+	GetBlackholeRules(context.Context, *BlackholeRulesRequest) (*BlackholeRulesReply, error)
+	GetApplications(context.Context, *ApplicationsRequest) (*ApplicationsReply, error)
+	GetCountries(context.Context, *CountriesRequest) (*CountriesReply, error)
+	SetPortTriggers(context.Context, *PortTriggersChangeRequest) (*Reply, error)
+	GetPortTriggers(context.Context, *PortTriggersRequest) (*PortTriggersReply, error)
+	ChangeSessionLogSampling(context.Context, *SessionLogSamplingRequest) (*Reply, error)
+	GetRunningConfig(context.Context, *RunningConfigRequest) (*RunningConfigReply, error)
+	CommitConfig(context.Context, *CommitConfigRequest) (*Reply, error)
+	ConfirmCommit(context.Context, *ConfirmCommitRequest) (*Reply, error)
+	RollbackCommit(context.Context, *RollbackCommitRequest) (*Reply, error)
+	FindSessions(context.Context, *SessionsFindRequest) (*SessionsFindReply, error)
+	GetExternalAddress(context.Context, *ExternalAddressRequest) (*ExternalAddressReply, error)
+	GetFlowGraph(context.Context, *FlowGraphRequest) (*FlowGraphReply, error)
+	SetMaintenance(context.Context, *MaintenanceRequest) (*MaintenanceReply, error)
+	GetMaintenance(context.Context, *MaintenanceStatusRequest) (*MaintenanceReply, error)
+	GetNftablesRuleset(context.Context, *NftablesRulesetRequest) (*NftablesRulesetReply, error)
+	TracePacket(context.Context, *PacketTraceRequest) (*PacketTraceReply, error)
+	GetForwardedHandshakes(context.Context, *ForwardedHandshakesRequest) (*ForwardedHandshakesReply, error)
+	SetCaptivePortalHost(context.Context, *CaptivePortalHostRequest) (*CaptivePortalHostsReply, error)
+	GetCaptivePortalHosts(context.Context, *CaptivePortalHostsRequest) (*CaptivePortalHostsReply, error)
+	GetHostIdentities(context.Context, *HostIdentitiesRequest) (*HostIdentitiesReply, error)
+}
+
+func RegisterUpdaterServer(s *grpc.Server, srv UpdaterServer) {
+	s.RegisterService(&_Updater_serviceDesc, srv)
+}
+
+func _Updater_ControlDump_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DumpControlRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).ControlDump(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/ControlDump",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).ControlDump(ctx, req.(*DumpControlRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_ChangeInterfaceAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InterfaceAddressChangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).ChangeInterfaceAddress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/ChangeInterfaceAddress",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).ChangeInterfaceAddress(ctx, req.(*InterfaceAddressChangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_ChangePortForwarding_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PortForwardingChangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).ChangePortForwarding(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/ChangePortForwarding",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).ChangePortForwarding(ctx, req.(*PortForwardingChangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_GetNeighbors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NeighborsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).GetNeighbors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/GetNeighbors",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).GetNeighbors(ctx, req.(*NeighborsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_AddStaticNeighbor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NeighborChangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).AddStaticNeighbor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/AddStaticNeighbor",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).AddStaticNeighbor(ctx, req.(*NeighborChangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_DeleteNeighbor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NeighborChangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).DeleteNeighbor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/DeleteNeighbor",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).DeleteNeighbor(ctx, req.(*NeighborChangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_FlushNeighbors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NeighborsFlushRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).FlushNeighbors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/FlushNeighbors",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).FlushNeighbors(ctx, req.(*NeighborsFlushRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_GetDHCPLease_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DHCPLeaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).GetDHCPLease(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/GetDHCPLease",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).GetDHCPLease(ctx, req.(*DHCPLeaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_ControlDHCP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DHCPControlRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).ControlDHCP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/ControlDHCP",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).ControlDHCP(ctx, req.(*DHCPControlRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_StreamDump_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DumpStreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(UpdaterServer).StreamDump(m, &updaterStreamDumpServer{stream})
+}
+
+type Updater_StreamDumpServer interface {
+	Send(*DumpedPacket) error
+	grpc.ServerStream
+}
+
+type updaterStreamDumpServer struct {
+	grpc.ServerStream
+}
+
+func (x *updaterStreamDumpServer) Send(m *DumpedPacket) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Updater_ConnectDumpSink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DumpSinkConnectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).ConnectDumpSink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/ConnectDumpSink",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).ConnectDumpSink(ctx, req.(*DumpSinkConnectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_DisconnectDumpSink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DumpSinkDisconnectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).DisconnectDumpSink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/DisconnectDumpSink",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).DisconnectDumpSink(ctx, req.(*DumpSinkDisconnectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_SendWakeOnLAN_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WakeOnLANRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).SendWakeOnLAN(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/SendWakeOnLAN",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).SendWakeOnLAN(ctx, req.(*WakeOnLANRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_GetPortStatistics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PortStatisticsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).GetPortStatistics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/GetPortStatistics",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).GetPortStatistics(ctx, req.(*PortStatisticsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_GetLinkStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LinkStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).GetLinkStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/GetLinkStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).GetLinkStatus(ctx, req.(*LinkStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_ChangeEgressPolicer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EgressPolicerChangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).ChangeEgressPolicer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/ChangeEgressPolicer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).ChangeEgressPolicer(ctx, req.(*EgressPolicerChangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_GetSubscribers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubscribersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).GetSubscribers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/GetSubscribers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).GetSubscribers(ctx, req.(*SubscribersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_ExportSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionsExportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).ExportSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/ExportSessions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).ExportSessions(ctx, req.(*SessionsExportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_ImportSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionSnapshot)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).ImportSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/ImportSessions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).ImportSessions(ctx, req.(*SessionSnapshot))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_GetTopTalkers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TopTalkersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).GetTopTalkers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/GetTopTalkers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).GetTopTalkers(ctx, req.(*TopTalkersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_ControlRouteAnnouncement_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RouteAnnouncementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).ControlRouteAnnouncement(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/ControlRouteAnnouncement",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).ControlRouteAnnouncement(ctx, req.(*RouteAnnouncementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_DeleteSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionDeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).DeleteSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/DeleteSession",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).DeleteSession(ctx, req.(*SessionDeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_ChangeBlackholeRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlackholeRuleChangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).ChangeBlackholeRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/ChangeBlackholeRule",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).ChangeBlackholeRule(ctx, req.(*BlackholeRuleChangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_GetBlackholeRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlackholeRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).GetBlackholeRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/GetBlackholeRules",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).GetBlackholeRules(ctx, req.(*BlackholeRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_GetApplications_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApplicationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).GetApplications(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/GetApplications",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).GetApplications(ctx, req.(*ApplicationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_GetCountries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CountriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).GetCountries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/GetCountries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).GetCountries(ctx, req.(*CountriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_SetPortTriggers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PortTriggersChangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).SetPortTriggers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/SetPortTriggers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).SetPortTriggers(ctx, req.(*PortTriggersChangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_GetPortTriggers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PortTriggersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).GetPortTriggers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/GetPortTriggers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).GetPortTriggers(ctx, req.(*PortTriggersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_ChangeSessionLogSampling_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionLogSamplingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).ChangeSessionLogSampling(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/ChangeSessionLogSampling",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).ChangeSessionLogSampling(ctx, req.(*SessionLogSamplingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_GetRunningConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunningConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).GetRunningConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/GetRunningConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).GetRunningConfig(ctx, req.(*RunningConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_CommitConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommitConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).CommitConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/CommitConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).CommitConfig(ctx, req.(*CommitConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_ConfirmCommit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfirmCommitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).ConfirmCommit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/ConfirmCommit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).ConfirmCommit(ctx, req.(*ConfirmCommitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Updater_RollbackCommit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RollbackCommitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).RollbackCommit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/RollbackCommit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).RollbackCommit(ctx, req.(*RollbackCommitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
 
-// UpdaterClient is the client API for Updater service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type UpdaterClient interface {
-	ControlDump(ctx context.Context, in *DumpControlRequest, opts ...grpc.CallOption) (*Reply, error)
-	ChangeInterfaceAddress(ctx context.Context, in *InterfaceAddressChangeRequest, opts ...grpc.CallOption) (*Reply, error)
-	ChangePortForwarding(ctx context.Context, in *PortForwardingChangeRequest, opts ...grpc.CallOption) (*Reply, error)
+func _Updater_FindSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionsFindRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).FindSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/FindSessions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).FindSessions(ctx, req.(*SessionsFindRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-type updaterClient struct {
-	cc *grpc.ClientConn
+func _Updater_GetExternalAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExternalAddressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).GetExternalAddress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/GetExternalAddress",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).GetExternalAddress(ctx, req.(*ExternalAddressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func NewUpdaterClient(cc *grpc.ClientConn) UpdaterClient {
-	return &updaterClient{cc}
+func _Updater_GetFlowGraph_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FlowGraphRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).GetFlowGraph(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/GetFlowGraph",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).GetFlowGraph(ctx, req.(*FlowGraphRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *updaterClient) ControlDump(ctx context.Context, in *DumpControlRequest, opts ...grpc.CallOption) (*Reply, error) {
-	out := new(Reply)
-	err := c.cc.Invoke(ctx, "/updatecfg.Updater/ControlDump", in, out, opts...)
-	if err != nil {
+func _Updater_SetMaintenance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MaintenanceRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(UpdaterServer).SetMaintenance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/SetMaintenance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).SetMaintenance(ctx, req.(*MaintenanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *updaterClient) ChangeInterfaceAddress(ctx context.Context, in *InterfaceAddressChangeRequest, opts ...grpc.CallOption) (*Reply, error) {
-	out := new(Reply)
-	err := c.cc.Invoke(ctx, "/updatecfg.Updater/ChangeInterfaceAddress", in, out, opts...)
-	if err != nil {
+func _Updater_GetMaintenance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MaintenanceStatusRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(UpdaterServer).GetMaintenance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/GetMaintenance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).GetMaintenance(ctx, req.(*MaintenanceStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *updaterClient) ChangePortForwarding(ctx context.Context, in *PortForwardingChangeRequest, opts ...grpc.CallOption) (*Reply, error) {
-	out := new(Reply)
-	err := c.cc.Invoke(ctx, "/updatecfg.Updater/ChangePortForwarding", in, out, opts...)
-	if err != nil {
+func _Updater_GetNftablesRuleset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NftablesRulesetRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(UpdaterServer).GetNftablesRuleset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/GetNftablesRuleset",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).GetNftablesRuleset(ctx, req.(*NftablesRulesetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-// UpdaterServer is the server API for Updater service.
-type UpdaterServer interface {
-	ControlDump(context.Context, *DumpControlRequest) (*Reply, error)
-	ChangeInterfaceAddress(context.Context, *InterfaceAddressChangeRequest) (*Reply, error)
-	ChangePortForwarding(context.Context, *PortForwardingChangeRequest) (*Reply, error)
+func _Updater_TracePacket_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PacketTraceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).TracePacket(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/TracePacket",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).TracePacket(ctx, req.(*PacketTraceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterUpdaterServer(s *grpc.Server, srv UpdaterServer) {
-	s.RegisterService(&_Updater_serviceDesc, srv)
+func _Updater_GetForwardedHandshakes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForwardedHandshakesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdaterServer).GetForwardedHandshakes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.Updater/GetForwardedHandshakes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdaterServer).GetForwardedHandshakes(ctx, req.(*ForwardedHandshakesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _Updater_ControlDump_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DumpControlRequest)
+func _Updater_SetCaptivePortalHost_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CaptivePortalHostRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UpdaterServer).ControlDump(ctx, in)
+		return srv.(UpdaterServer).SetCaptivePortalHost(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/updatecfg.Updater/ControlDump",
+		FullMethod: "/updatecfg.Updater/SetCaptivePortalHost",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UpdaterServer).ControlDump(ctx, req.(*DumpControlRequest))
+		return srv.(UpdaterServer).SetCaptivePortalHost(ctx, req.(*CaptivePortalHostRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Updater_ChangeInterfaceAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(InterfaceAddressChangeRequest)
+func _Updater_GetCaptivePortalHosts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CaptivePortalHostsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UpdaterServer).ChangeInterfaceAddress(ctx, in)
+		return srv.(UpdaterServer).GetCaptivePortalHosts(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/updatecfg.Updater/ChangeInterfaceAddress",
+		FullMethod: "/updatecfg.Updater/GetCaptivePortalHosts",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UpdaterServer).ChangeInterfaceAddress(ctx, req.(*InterfaceAddressChangeRequest))
+		return srv.(UpdaterServer).GetCaptivePortalHosts(ctx, req.(*CaptivePortalHostsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Updater_ChangePortForwarding_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(PortForwardingChangeRequest)
+func _Updater_GetHostIdentities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HostIdentitiesRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UpdaterServer).ChangePortForwarding(ctx, in)
+		return srv.(UpdaterServer).GetHostIdentities(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/updatecfg.Updater/ChangePortForwarding",
+		FullMethod: "/updatecfg.Updater/GetHostIdentities",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UpdaterServer).ChangePortForwarding(ctx, req.(*PortForwardingChangeRequest))
+		return srv.(UpdaterServer).GetHostIdentities(ctx, req.(*HostIdentitiesRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -559,52 +7158,559 @@ var _Updater_serviceDesc = grpc.ServiceDesc{
 			MethodName: "ChangePortForwarding",
 			Handler:    _Updater_ChangePortForwarding_Handler,
 		},
+		{
+			MethodName: "GetNeighbors",
+			Handler:    _Updater_GetNeighbors_Handler,
+		},
+		{
+			MethodName: "AddStaticNeighbor",
+			Handler:    _Updater_AddStaticNeighbor_Handler,
+		},
+		{
+			MethodName: "DeleteNeighbor",
+			Handler:    _Updater_DeleteNeighbor_Handler,
+		},
+		{
+			MethodName: "FlushNeighbors",
+			Handler:    _Updater_FlushNeighbors_Handler,
+		},
+		{
+			MethodName: "GetDHCPLease",
+			Handler:    _Updater_GetDHCPLease_Handler,
+		},
+		{
+			MethodName: "ControlDHCP",
+			Handler:    _Updater_ControlDHCP_Handler,
+		},
+		{
+			MethodName: "ConnectDumpSink",
+			Handler:    _Updater_ConnectDumpSink_Handler,
+		},
+		{
+			MethodName: "DisconnectDumpSink",
+			Handler:    _Updater_DisconnectDumpSink_Handler,
+		},
+		{
+			MethodName: "SendWakeOnLAN",
+			Handler:    _Updater_SendWakeOnLAN_Handler,
+		},
+		{
+			MethodName: "GetPortStatistics",
+			Handler:    _Updater_GetPortStatistics_Handler,
+		},
+		{
+			MethodName: "GetLinkStatus",
+			Handler:    _Updater_GetLinkStatus_Handler,
+		},
+		{
+			MethodName: "ChangeEgressPolicer",
+			Handler:    _Updater_ChangeEgressPolicer_Handler,
+		},
+		{
+			MethodName: "GetSubscribers",
+			Handler:    _Updater_GetSubscribers_Handler,
+		},
+		{
+			MethodName: "ExportSessions",
+			Handler:    _Updater_ExportSessions_Handler,
+		},
+		{
+			MethodName: "ImportSessions",
+			Handler:    _Updater_ImportSessions_Handler,
+		},
+		{
+			MethodName: "GetTopTalkers",
+			Handler:    _Updater_GetTopTalkers_Handler,
+		},
+		{
+			MethodName: "ControlRouteAnnouncement",
+			Handler:    _Updater_ControlRouteAnnouncement_Handler,
+		},
+		{
+			MethodName: "DeleteSession",
+			Handler:    _Updater_DeleteSession_Handler,
+		},
+		{
+			MethodName: "ChangeBlackholeRule",
+			Handler:    _Updater_ChangeBlackholeRule_Handler,
+		},
+		{
+			MethodName: "GetBlackholeRules",
+			Handler:    _Updater_GetBlackholeRules_Handler,
+		},
+		{
+			MethodName: "GetApplications",
+			Handler:    _Updater_GetApplications_Handler,
+		},
+		{
+			MethodName: "GetCountries",
+			Handler:    _Updater_GetCountries_Handler,
+		},
+		{
+			MethodName: "SetPortTriggers",
+			Handler:    _Updater_SetPortTriggers_Handler,
+		},
+		{
+			MethodName: "GetPortTriggers",
+			Handler:    _Updater_GetPortTriggers_Handler,
+		},
+		{
+			MethodName: "ChangeSessionLogSampling",
+			Handler:    _Updater_ChangeSessionLogSampling_Handler,
+		},
+		{
+			MethodName: "GetRunningConfig",
+			Handler:    _Updater_GetRunningConfig_Handler,
+		},
+		{
+			MethodName: "CommitConfig",
+			Handler:    _Updater_CommitConfig_Handler,
+		},
+		{
+			MethodName: "ConfirmCommit",
+			Handler:    _Updater_ConfirmCommit_Handler,
+		},
+		{
+			MethodName: "RollbackCommit",
+			Handler:    _Updater_RollbackCommit_Handler,
+		},
+		{
+			MethodName: "FindSessions",
+			Handler:    _Updater_FindSessions_Handler,
+		},
+		{
+			MethodName: "GetExternalAddress",
+			Handler:    _Updater_GetExternalAddress_Handler,
+		},
+		{
+			MethodName: "GetFlowGraph",
+			Handler:    _Updater_GetFlowGraph_Handler,
+		},
+		{
+			MethodName: "SetMaintenance",
+			Handler:    _Updater_SetMaintenance_Handler,
+		},
+		{
+			MethodName: "GetMaintenance",
+			Handler:    _Updater_GetMaintenance_Handler,
+		},
+		{
+			MethodName: "GetNftablesRuleset",
+			Handler:    _Updater_GetNftablesRuleset_Handler,
+		},
+		{
+			MethodName: "TracePacket",
+			Handler:    _Updater_TracePacket_Handler,
+		},
+		{
+			MethodName: "GetForwardedHandshakes",
+			Handler:    _Updater_GetForwardedHandshakes_Handler,
+		},
+		{
+			MethodName: "SetCaptivePortalHost",
+			Handler:    _Updater_SetCaptivePortalHost_Handler,
+		},
+		{
+			MethodName: "GetCaptivePortalHosts",
+			Handler:    _Updater_GetCaptivePortalHosts_Handler,
+		},
+		{
+			MethodName: "GetHostIdentities",
+			Handler:    _Updater_GetHostIdentities_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamDump",
+			Handler:       _Updater_StreamDump_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "updatecfg.proto",
+}
+
+// SessionAuthorizerClient is the client API for SessionAuthorizer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type SessionAuthorizerClient interface {
+	AuthorizeSession(ctx context.Context, in *SessionAuthorizationRequest, opts ...grpc.CallOption) (*SessionAuthorizationReply, error)
+}
+
+type sessionAuthorizerClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewSessionAuthorizerClient(cc *grpc.ClientConn) SessionAuthorizerClient {
+	return &sessionAuthorizerClient{cc}
+}
+
+func (c *sessionAuthorizerClient) AuthorizeSession(ctx context.Context, in *SessionAuthorizationRequest, opts ...grpc.CallOption) (*SessionAuthorizationReply, error) {
+	out := new(SessionAuthorizationReply)
+	err := c.cc.Invoke(ctx, "/updatecfg.SessionAuthorizer/AuthorizeSession", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SessionAuthorizerServer is the server API for SessionAuthorizer service.
+type SessionAuthorizerServer interface {
+	AuthorizeSession(context.Context, *SessionAuthorizationRequest) (*SessionAuthorizationReply, error)
+}
+
+func RegisterSessionAuthorizerServer(s *grpc.Server, srv SessionAuthorizerServer) {
+	s.RegisterService(&_SessionAuthorizer_serviceDesc, srv)
+}
+
+func _SessionAuthorizer_AuthorizeSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionAuthorizationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionAuthorizerServer).AuthorizeSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/updatecfg.SessionAuthorizer/AuthorizeSession",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionAuthorizerServer).AuthorizeSession(ctx, req.(*SessionAuthorizationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _SessionAuthorizer_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "updatecfg.SessionAuthorizer",
+	HandlerType: (*SessionAuthorizerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AuthorizeSession",
+			Handler:    _SessionAuthorizer_AuthorizeSession_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "updatecfg.proto",
 }
 
-func init() { proto.RegisterFile("updatecfg.proto", fileDescriptor_updatecfg_fa7d68b5067fd899) }
-
-var fileDescriptor_updatecfg_fa7d68b5067fd899 = []byte{
-	// 610 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x53, 0x41, 0x6f, 0xd3, 0x4c,
-	0x10, 0xad, 0xd3, 0x7c, 0x4d, 0x32, 0x4e, 0xd2, 0xed, 0x7c, 0x15, 0x0a, 0xa0, 0x4a, 0xc5, 0x12,
-	0x28, 0x2a, 0x55, 0x91, 0x52, 0xa9, 0x17, 0x38, 0xd0, 0x26, 0x54, 0x8a, 0x0a, 0xae, 0xb5, 0x4d,
-	0xe0, 0x68, 0xd9, 0xf1, 0xc6, 0x44, 0x38, 0xb6, 0x59, 0xaf, 0x8b, 0x7a, 0xeb, 0x89, 0x3b, 0x67,
-	0x7e, 0x19, 0xbf, 0x84, 0x2b, 0xf2, 0xae, 0xed, 0x3a, 0xa4, 0xf4, 0x36, 0x9e, 0x79, 0x3b, 0x6f,
-	0xe6, 0xf9, 0x0d, 0x6c, 0xa7, 0xb1, 0xe7, 0x08, 0x36, 0x9b, 0xfb, 0x47, 0x31, 0x8f, 0x44, 0x84,
-	0xad, 0x32, 0x61, 0x04, 0x80, 0xa3, 0x74, 0x19, 0x0f, 0xa3, 0x50, 0xf0, 0x28, 0xa0, 0xec, 0x6b,
-	0xca, 0x12, 0x81, 0xcf, 0xa0, 0xcd, 0x42, 0xc7, 0x0d, 0x98, 0x2d, 0xb8, 0x33, 0x63, 0x3d, 0x6d,
-	0x5f, 0xeb, 0x37, 0xa9, 0xae, 0x72, 0x93, 0x2c, 0x85, 0xc7, 0x00, 0xb2, 0x66, 0x8b, 0x9b, 0x98,
-	0xf5, 0x6a, 0xfb, 0x5a, 0xbf, 0x3b, 0xd8, 0x3d, 0xba, 0x63, 0x92, 0xa8, 0xc9, 0x4d, 0xcc, 0x68,
-	0x4b, 0x14, 0xa1, 0xf1, 0x1c, 0x5a, 0x63, 0xeb, 0xd4, 0xf3, 0x38, 0x4b, 0x12, 0xec, 0x41, 0xc3,
-	0x51, 0xa1, 0xec, 0xdf, 0xa6, 0xc5, 0xa7, 0xe1, 0xc2, 0xd6, 0x55, 0xea, 0x86, 0x4c, 0xe0, 0xd1,
-	0x2a, 0x46, 0x5f, 0xa1, 0x28, 0x5b, 0x95, 0x2f, 0xb1, 0x0f, 0x64, 0xe9, 0x24, 0x5f, 0x6c, 0x77,
-	0x21, 0x12, 0x3b, 0x4c, 0x97, 0x2e, 0xe3, 0x72, 0xb6, 0x0e, 0xed, 0x66, 0xf9, 0xb3, 0x85, 0x48,
-	0x4c, 0x99, 0x35, 0xae, 0x61, 0x6f, 0x1c, 0x0a, 0xc6, 0xe7, 0xce, 0x8c, 0xe5, 0x6d, 0x86, 0x9f,
-	0x9d, 0xd0, 0x67, 0x15, 0x0d, 0x16, 0x05, 0xc0, 0x5e, 0x78, 0x92, 0xbf, 0x43, 0xf5, 0x32, 0x37,
-	0xf6, 0x70, 0x00, 0x7a, 0x1c, 0x71, 0x61, 0x27, 0x72, 0x58, 0x49, 0xa4, 0x0f, 0x76, 0x2a, 0x13,
-	0xaa, 0x2d, 0x28, 0x64, 0x28, 0x15, 0x1b, 0xbf, 0x34, 0xe8, 0x9c, 0x47, 0xfc, 0x9b, 0xc3, 0x3d,
-	0xe6, 0x59, 0x11, 0x17, 0x78, 0x08, 0x98, 0x44, 0x29, 0x9f, 0x31, 0x5b, 0x36, 0xcb, 0xa7, 0x56,
-	0x74, 0x44, 0x55, 0x32, 0x9c, 0x9a, 0x1b, 0x5f, 0x43, 0x57, 0x38, 0xdc, 0x67, 0xc2, 0x2e, 0x84,
-	0xa9, 0x3d, 0x20, 0x4c, 0x47, 0x61, 0x0b, 0xc9, 0x0f, 0x01, 0xf3, 0xc7, 0x55, 0xaa, 0x4d, 0x45,
-	0xa5, 0x2a, 0x15, 0xaa, 0x57, 0xd0, 0x94, 0x7e, 0x99, 0x45, 0x41, 0xaf, 0x2e, 0x7f, 0xf0, 0xff,
-	0x15, 0x12, 0x2b, 0x2f, 0xd1, 0x12, 0x64, 0xfc, 0xd4, 0xe0, 0x69, 0xf6, 0x3e, 0xdf, 0x6f, 0x11,
-	0xfa, 0xab, 0x92, 0xbe, 0x84, 0x9d, 0xdc, 0x56, 0xf3, 0x12, 0x91, 0x7b, 0x8b, 0xa8, 0xc2, 0xdd,
-	0xcb, 0x35, 0xfd, 0x6b, 0xeb, 0xfa, 0x1f, 0x42, 0x3d, 0xdb, 0x43, 0x2e, 0xa0, 0x0f, 0x7a, 0x95,
-	0xe1, 0x56, 0x14, 0xa6, 0x12, 0x65, 0x3c, 0x86, 0xff, 0x28, 0x8b, 0x83, 0x1b, 0x24, 0xb0, 0xb9,
-	0x4c, 0x7c, 0xd9, 0xb0, 0x45, 0xb3, 0xf0, 0xe0, 0x0d, 0xb4, 0x4a, 0xbf, 0x62, 0x07, 0x5a, 0xa3,
-	0xe9, 0x07, 0xcb, 0x1e, 0xd1, 0x4b, 0x8b, 0x6c, 0x20, 0x42, 0x57, 0x7e, 0x4e, 0xe8, 0xa9, 0x79,
-	0xf5, 0xfe, 0x74, 0xf2, 0x8e, 0x68, 0xd8, 0x86, 0xa6, 0xcc, 0x5d, 0x98, 0x63, 0x52, 0x3b, 0xa0,
-	0xd0, 0x2c, 0xc4, 0x40, 0x1d, 0x1a, 0x53, 0xf3, 0xc2, 0xbc, 0xfc, 0x64, 0x92, 0x0d, 0x6c, 0xc0,
-	0xe6, 0x64, 0x68, 0x91, 0xad, 0x2c, 0x98, 0x8e, 0x2c, 0xb2, 0x83, 0xdb, 0xd9, 0x01, 0x5c, 0x9f,
-	0xd8, 0xe7, 0x81, 0xe3, 0x93, 0xdb, 0xdb, 0x3a, 0x02, 0xd4, 0x27, 0x43, 0xeb, 0x84, 0x7c, 0x57,
-	0xf1, 0x74, 0x64, 0x9d, 0x90, 0x1f, 0xb7, 0xf5, 0xc1, 0x6f, 0x0d, 0x1a, 0x53, 0xb9, 0x0e, 0xc7,
-	0xb7, 0xa0, 0xe7, 0xf7, 0x99, 0x9d, 0x2a, 0xee, 0x55, 0xf6, 0x5c, 0xbf, 0xdd, 0x27, 0xa4, 0x52,
-	0x96, 0xfb, 0x1a, 0x1b, 0xf8, 0x11, 0x1e, 0xa9, 0x3f, 0xf1, 0xb7, 0xe5, 0xb1, 0x5f, 0xb5, 0xcd,
-	0x43, 0xf7, 0x70, 0x6f, 0x5f, 0x0a, 0xbb, 0x0a, 0xb4, 0xfa, 0xd7, 0xf1, 0x45, 0xd5, 0x27, 0xff,
-	0x36, 0xc4, 0x7d, 0x3d, 0xcf, 0xc8, 0x59, 0x5b, 0x2d, 0x6e, 0x3a, 0x62, 0x38, 0xf7, 0x2d, 0xcd,
-	0xdd, 0x92, 0x06, 0x3b, 0xfe, 0x13, 0x00, 0x00, 0xff, 0xff, 0xb8, 0xe4, 0x11, 0xa6, 0xc8, 0x04,
-	0x00, 0x00,
+func init() { proto.RegisterFile("updatecfg.proto", fileDescriptor_updatecfg_022d0bfb299e358c) }
+
+var fileDescriptor_updatecfg_022d0bfb299e358c = []byte{
+	// 5047 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x3c, 0x4d, 0x73, 0x23, 0x49,
+	0x56, 0x5d, 0xfa, 0xb2, 0xf4, 0xf4, 0x55, 0x4e, 0xbb, 0xdd, 0xb2, 0xdc, 0x1f, 0x9e, 0xea, 0x6d,
+	0xb6, 0xa7, 0x99, 0x6d, 0x06, 0x37, 0xd3, 0x3b, 0x3b, 0xcb, 0xc2, 0xba, 0x65, 0xb7, 0xdb, 0x8c,
+	0x5b, 0xed, 0x2d, 0xc9, 0x33, 0xb1, 0x4b, 0x0c, 0x15, 0x65, 0x29, 0xa5, 0x2e, 0x5c, 0xaa, 0x2a,
+	0xaa, 0x4a, 0xdd, 0xee, 0x09, 0x88, 0x18, 0x82, 0x60, 0x2f, 0x44, 0x00, 0x73, 0x02, 0x02, 0x2e,
+	0xfc, 0x01, 0x0e, 0x44, 0xc0, 0x91, 0x03, 0xb1, 0x01, 0x37, 0x0e, 0x7b, 0xe3, 0x40, 0x04, 0x47,
+	0xfe, 0x01, 0x47, 0x88, 0xfc, 0xa8, 0xaa, 0x4c, 0xa9, 0x4a, 0x96, 0x3c, 0xb0, 0x37, 0xe5, 0xcb,
+	0x97, 0x2f, 0x33, 0xdf, 0x7b, 0xf9, 0x5e, 0xbe, 0x97, 0xaf, 0x04, 0xcd, 0xa9, 0x37, 0x34, 0x43,
+	0x3c, 0x18, 0x8d, 0x1f, 0x7b, 0xbe, 0x1b, 0xba, 0xa8, 0x12, 0x03, 0x34, 0x1b, 0xd0, 0xc1, 0x74,
+	0xe2, 0x75, 0x5c, 0x27, 0xf4, 0x5d, 0x5b, 0xc7, 0xbf, 0x37, 0xc5, 0x41, 0x88, 0xde, 0x83, 0x1a,
+	0x76, 0xcc, 0x73, 0x1b, 0x1b, 0xa1, 0x6f, 0x0e, 0x70, 0x4b, 0xd9, 0x55, 0x1e, 0x96, 0xf5, 0x2a,
+	0x83, 0xf5, 0x09, 0x08, 0x3d, 0x01, 0xa0, 0x7d, 0x46, 0xf8, 0xce, 0xc3, 0xad, 0xdc, 0xae, 0xf2,
+	0xb0, 0xb1, 0xb7, 0xf9, 0x38, 0x99, 0x89, 0x62, 0xf5, 0xdf, 0x79, 0x58, 0xaf, 0x84, 0xd1, 0x4f,
+	0xcd, 0x85, 0x75, 0x32, 0x5b, 0x2f, 0xf4, 0xb1, 0x39, 0x89, 0x26, 0xfb, 0x08, 0xaa, 0x09, 0xa5,
+	0xa0, 0xa5, 0xec, 0xe6, 0x33, 0x49, 0x41, 0x4c, 0x2a, 0x40, 0xf7, 0xa1, 0x6e, 0x39, 0x21, 0xf6,
+	0x47, 0x64, 0xa8, 0x35, 0x0c, 0x5a, 0xb9, 0xdd, 0xfc, 0xc3, 0xba, 0x5e, 0x8b, 0x81, 0xc7, 0xc3,
+	0x40, 0xfb, 0x7b, 0x05, 0x6a, 0x64, 0x46, 0x3c, 0x3c, 0x35, 0x07, 0x17, 0x98, 0xee, 0x4c, 0x1c,
+	0x45, 0x77, 0x56, 0xd7, 0xab, 0xc2, 0xa0, 0x6b, 0xed, 0x0c, 0xdd, 0x86, 0x4a, 0x68, 0x4d, 0x70,
+	0x10, 0x9a, 0x13, 0xaf, 0x95, 0xdf, 0x55, 0x1e, 0xe6, 0xf5, 0x04, 0x80, 0x10, 0x14, 0x86, 0x66,
+	0x68, 0xb6, 0x0a, 0xbb, 0xca, 0xc3, 0x9a, 0x4e, 0x7f, 0xa3, 0x16, 0xac, 0x0d, 0x7d, 0xd7, 0xf3,
+	0xf0, 0xb0, 0x55, 0xdc, 0x55, 0x1e, 0x16, 0xf4, 0xa8, 0xa9, 0x7d, 0x95, 0x83, 0x2d, 0xca, 0x26,
+	0xcb, 0xb9, 0xe8, 0xb8, 0x8e, 0x83, 0x07, 0x61, 0xc4, 0xab, 0x16, 0xac, 0x99, 0xc3, 0xa1, 0x8f,
+	0x83, 0x80, 0xae, 0xbc, 0xa2, 0x47, 0x4d, 0x74, 0x0b, 0xd6, 0xa6, 0x01, 0x36, 0x42, 0x3b, 0xa0,
+	0x4b, 0x2e, 0xeb, 0xa5, 0x69, 0x80, 0xfb, 0x76, 0x80, 0x1e, 0x40, 0x63, 0x60, 0x1a, 0x03, 0xec,
+	0x87, 0xd6, 0xc8, 0x1a, 0x98, 0x21, 0xa6, 0xcb, 0xab, 0xe9, 0xf5, 0x81, 0xd9, 0x49, 0x80, 0xe8,
+	0x43, 0xd8, 0xb4, 0x9c, 0x00, 0x0f, 0xa6, 0x3e, 0x36, 0x82, 0x0b, 0xcb, 0x33, 0xde, 0x60, 0xdf,
+	0x1a, 0xbd, 0xa3, 0x4b, 0x2e, 0xeb, 0x28, 0xea, 0xeb, 0x5d, 0x58, 0xde, 0x67, 0xb4, 0x67, 0x56,
+	0x6e, 0xc5, 0xeb, 0xca, 0xad, 0x94, 0x22, 0xb7, 0x8f, 0x60, 0x3b, 0xe2, 0xc0, 0x81, 0x15, 0x0c,
+	0x96, 0x64, 0x82, 0xf6, 0x00, 0x2a, 0xc7, 0xa7, 0xfb, 0xac, 0x31, 0x8b, 0x56, 0x4b, 0xd0, 0xce,
+	0xa1, 0xd4, 0x9b, 0x9e, 0x3b, 0x38, 0x44, 0x8f, 0x65, 0x9c, 0xaa, 0xb4, 0xfe, 0x98, 0x54, 0xc2,
+	0xe5, 0x87, 0xa0, 0x4e, 0xcc, 0xe0, 0xc2, 0x38, 0xb7, 0xc2, 0xc0, 0x70, 0xa6, 0x93, 0x73, 0xec,
+	0x53, 0x76, 0xd7, 0xf5, 0x06, 0x81, 0x3f, 0xb3, 0xc2, 0xa0, 0x4b, 0xa1, 0xda, 0x5f, 0x2b, 0x70,
+	0xe7, 0x38, 0xda, 0x12, 0xa7, 0xd3, 0x79, 0x6d, 0x3a, 0x63, 0x2c, 0x1c, 0xb2, 0xab, 0x54, 0x71,
+	0x0f, 0xaa, 0x9e, 0xeb, 0x87, 0x46, 0x40, 0x57, 0x4b, 0x67, 0xaa, 0xee, 0xad, 0x0b, 0x4b, 0x64,
+	0xdb, 0xd0, 0x81, 0x60, 0xf1, 0x2d, 0xdd, 0x87, 0xfa, 0x05, 0xc6, 0x9e, 0x11, 0xe0, 0x20, 0xb0,
+	0x5c, 0x27, 0xa0, 0xe2, 0x2e, 0xeb, 0x35, 0x02, 0xec, 0x71, 0x98, 0xf6, 0xcf, 0x39, 0xa8, 0x3f,
+	0x77, 0xfd, 0xb7, 0xa6, 0x3f, 0xc4, 0xc3, 0x53, 0xd7, 0x0f, 0xd1, 0x07, 0x80, 0x02, 0x77, 0xea,
+	0x0f, 0xb0, 0x41, 0x67, 0xe4, 0x7b, 0x63, 0x6b, 0x52, 0x59, 0x0f, 0xc1, 0x63, 0xbb, 0x43, 0xdf,
+	0x87, 0x46, 0x68, 0xfa, 0x63, 0x1c, 0x1a, 0x11, 0xfb, 0x72, 0x0b, 0xd8, 0x57, 0x67, 0xb8, 0xbc,
+	0x49, 0xa6, 0xe2, 0x83, 0xc5, 0xa9, 0xf2, 0x6c, 0x2a, 0xd6, 0x23, 0x4c, 0xf5, 0x2b, 0x50, 0xa6,
+	0x56, 0x6b, 0xe0, 0xda, 0x54, 0x19, 0x1b, 0x7b, 0x1b, 0xc2, 0x24, 0xa7, 0xbc, 0x4b, 0x8f, 0x91,
+	0xd0, 0x3d, 0xa8, 0x72, 0xf2, 0x5f, 0xba, 0x0e, 0xa6, 0x87, 0xab, 0xa2, 0x03, 0x03, 0xfd, 0xc4,
+	0x75, 0x30, 0xfa, 0x35, 0x58, 0x63, 0x1b, 0x62, 0xba, 0x57, 0xdd, 0x6b, 0x0b, 0x04, 0x63, 0xae,
+	0xf4, 0x28, 0x8a, 0x1e, 0xa1, 0x22, 0x15, 0xf2, 0x17, 0x8e, 0xd5, 0x5a, 0xa3, 0xdc, 0x24, 0x3f,
+	0xb5, 0x7f, 0x50, 0xa0, 0x39, 0x83, 0x8e, 0xb6, 0xa0, 0xe4, 0xf9, 0x78, 0x64, 0x5d, 0x72, 0xd5,
+	0xe4, 0xad, 0x5f, 0x24, 0xc3, 0x66, 0xf6, 0x5f, 0x98, 0xdd, 0x3f, 0x51, 0xcd, 0x1d, 0x82, 0xcf,
+	0xd7, 0x6e, 0x39, 0x63, 0x59, 0x31, 0x7f, 0x19, 0xd6, 0xb9, 0xf5, 0x1f, 0xc5, 0x18, 0xdc, 0x05,
+	0xa8, 0xac, 0x23, 0x19, 0x39, 0xa7, 0xc5, 0xb9, 0x79, 0x2d, 0xfe, 0x00, 0x0a, 0x64, 0xdd, 0x74,
+	0xc1, 0xd5, 0xbd, 0x56, 0x1a, 0xb3, 0xc9, 0x72, 0x74, 0x8a, 0xa5, 0x05, 0x50, 0xee, 0x62, 0x6b,
+	0xfc, 0xfa, 0xdc, 0xf5, 0x57, 0x3e, 0x9e, 0xf7, 0xa0, 0x3a, 0x31, 0x07, 0x12, 0x8b, 0x6b, 0x3a,
+	0x4c, 0xcc, 0x41, 0xc4, 0xc9, 0x2d, 0x28, 0x05, 0xa1, 0x19, 0x5a, 0x03, 0x7e, 0x2a, 0x78, 0x4b,
+	0xfb, 0x08, 0xd4, 0x68, 0xd2, 0x60, 0xf9, 0xf3, 0xa9, 0xfd, 0x36, 0x34, 0x84, 0x61, 0x9e, 0xfd,
+	0x0e, 0xfd, 0x2a, 0x54, 0x9c, 0x08, 0x42, 0x5d, 0x59, 0x55, 0x52, 0xd7, 0x08, 0x5b, 0x4f, 0xb0,
+	0xc8, 0x9a, 0x42, 0xec, 0x98, 0x0e, 0x3b, 0xdf, 0x15, 0x9d, 0xb7, 0xb4, 0x3f, 0x51, 0xe0, 0x66,
+	0x84, 0xbf, 0xb2, 0xe5, 0x10, 0x38, 0x97, 0xbb, 0x06, 0xe7, 0xf2, 0xb3, 0x9c, 0xd3, 0xbe, 0x48,
+	0x16, 0x13, 0x3c, 0xb7, 0xa7, 0xc1, 0xeb, 0x15, 0x16, 0xf3, 0x1e, 0xd4, 0x46, 0x64, 0x88, 0xc1,
+	0x79, 0xcf, 0x1c, 0x54, 0x95, 0xc2, 0x7a, 0x4c, 0x00, 0xc7, 0xa0, 0x1e, 0xbc, 0xe8, 0x9c, 0x9e,
+	0x60, 0x33, 0x58, 0x65, 0x9b, 0x08, 0x0a, 0x96, 0xf7, 0xe6, 0x29, 0xa7, 0x48, 0x7f, 0x6b, 0x5f,
+	0x02, 0x22, 0xa4, 0xe6, 0xaf, 0x34, 0xd7, 0x20, 0x86, 0xbe, 0x03, 0x25, 0x73, 0x10, 0x5a, 0xae,
+	0x43, 0x59, 0xd2, 0xd8, 0xbb, 0x29, 0xb0, 0x91, 0xcc, 0xb2, 0x4f, 0x3b, 0x75, 0x8e, 0xa4, 0xfd,
+	0x6d, 0x1e, 0x1a, 0xc2, 0x3e, 0x88, 0x46, 0x5c, 0x73, 0xe2, 0x47, 0x50, 0x0c, 0xc2, 0xc8, 0x5b,
+	0xcb, 0x7e, 0x95, 0x4c, 0x40, 0xd8, 0x86, 0x75, 0x86, 0x82, 0xde, 0x87, 0x12, 0xf7, 0x10, 0x85,
+	0x2c, 0x0f, 0xc1, 0x11, 0xd0, 0x07, 0x50, 0x0a, 0xb0, 0xff, 0x06, 0xfb, 0xad, 0xe2, 0x02, 0xb5,
+	0xe0, 0x38, 0xc4, 0x97, 0xd8, 0x64, 0x27, 0x46, 0x80, 0x07, 0xae, 0x43, 0x7d, 0x35, 0x59, 0x7c,
+	0x8d, 0x02, 0x7b, 0x0c, 0x46, 0x90, 0x7c, 0xec, 0xe0, 0xb7, 0x31, 0xd2, 0x1a, 0x43, 0xa2, 0xc0,
+	0x08, 0xe9, 0x01, 0x34, 0x7c, 0x7c, 0x6e, 0x39, 0xc3, 0x18, 0xab, 0x4c, 0xb1, 0xea, 0x0c, 0x2a,
+	0xa0, 0xb1, 0x09, 0xdd, 0xf3, 0xd0, 0xb4, 0x1c, 0x3c, 0x6c, 0x55, 0xe8, 0x5d, 0x8a, 0x2d, 0xe3,
+	0x15, 0x07, 0x26, 0xeb, 0xc2, 0x97, 0x9e, 0xe5, 0xe3, 0xa0, 0x05, 0x14, 0x8b, 0xad, 0xeb, 0x90,
+	0xc1, 0x84, 0x73, 0x55, 0x95, 0xce, 0x95, 0x0f, 0xea, 0xe7, 0xe6, 0x05, 0x7e, 0xe5, 0x9c, 0xec,
+	0x77, 0x57, 0xd0, 0x8e, 0x2b, 0x6d, 0x4b, 0x1b, 0xca, 0x9e, 0x19, 0x04, 0x6f, 0x5d, 0x7f, 0xc8,
+	0xcf, 0x4f, 0xdc, 0xd6, 0x3e, 0x81, 0x9b, 0xc4, 0xc4, 0x51, 0x65, 0x0f, 0x42, 0x6b, 0xb0, 0x8a,
+	0x91, 0x79, 0x02, 0x6b, 0x1d, 0x77, 0x4a, 0x00, 0x44, 0x51, 0x1c, 0x73, 0x82, 0xb9, 0x6f, 0xa1,
+	0xbf, 0xd1, 0x26, 0x14, 0xdf, 0x98, 0xf6, 0x94, 0xdd, 0x54, 0x0b, 0x3a, 0x6b, 0x68, 0xff, 0xa4,
+	0xc0, 0xc6, 0xec, 0x8c, 0x4b, 0x6a, 0xe3, 0x47, 0x50, 0x73, 0xcc, 0xd0, 0x18, 0xb0, 0x39, 0xd9,
+	0xbd, 0xba, 0xba, 0x87, 0x04, 0x45, 0xe1, 0xcb, 0xd1, 0xab, 0x8e, 0x19, 0xf2, 0xdf, 0x01, 0x1d,
+	0x66, 0x0d, 0x92, 0x61, 0xf9, 0x05, 0xc3, 0xac, 0x41, 0x3c, 0x2c, 0x91, 0x52, 0x41, 0x92, 0xd2,
+	0x53, 0x58, 0x3f, 0xb1, 0x9c, 0x0b, 0xb2, 0xfe, 0xe9, 0x2a, 0xdc, 0xfa, 0x17, 0x05, 0x9a, 0xe2,
+	0xc0, 0x25, 0x37, 0xdd, 0x80, 0xdc, 0xd4, 0xe3, 0x07, 0x30, 0x37, 0xf5, 0xd0, 0x1d, 0x80, 0xc0,
+	0xc3, 0x78, 0x68, 0x4c, 0xce, 0xbd, 0x80, 0xbb, 0xda, 0x0a, 0x85, 0xbc, 0x3c, 0xf7, 0xa8, 0xb9,
+	0x1c, 0x4d, 0x6d, 0xdb, 0x18, 0x4e, 0x3d, 0x1b, 0x5f, 0xf2, 0x4b, 0x32, 0x10, 0xd0, 0x01, 0x85,
+	0xa0, 0x87, 0xd0, 0x34, 0xa7, 0xa1, 0xeb, 0xe0, 0xb1, 0x1b, 0x5a, 0x26, 0x35, 0x20, 0x45, 0x8a,
+	0x34, 0x0b, 0x16, 0x18, 0x50, 0x92, 0x18, 0x30, 0x81, 0xf5, 0xc3, 0x31, 0x51, 0xac, 0x53, 0xd7,
+	0xb6, 0x06, 0xd8, 0x7f, 0xe1, 0x06, 0xab, 0xdf, 0x57, 0x11, 0x14, 0x7c, 0x62, 0x44, 0x98, 0x6e,
+	0xd0, 0xdf, 0x44, 0x61, 0xce, 0xa7, 0x7e, 0xc0, 0xfc, 0x71, 0x41, 0x67, 0x0d, 0xed, 0xdf, 0x15,
+	0x68, 0x4b, 0xf3, 0xad, 0xec, 0x72, 0x96, 0x9e, 0x0b, 0xed, 0x40, 0xe5, 0xb5, 0x1b, 0x84, 0x06,
+	0x45, 0x2f, 0xd0, 0x9e, 0x32, 0x01, 0xe8, 0x64, 0xc8, 0x1d, 0x00, 0xda, 0xc9, 0xc6, 0xb1, 0xd0,
+	0x88, 0xa2, 0x3f, 0xa3, 0x63, 0xf7, 0xa0, 0x48, 0x1a, 0xd1, 0xd5, 0xed, 0xb6, 0xb0, 0xff, 0x39,
+	0x76, 0xe9, 0x0c, 0x55, 0xfb, 0x2e, 0xa0, 0xde, 0xf4, 0x3c, 0x18, 0xf8, 0xd6, 0x39, 0x5e, 0xc9,
+	0xbf, 0x5f, 0x42, 0x33, 0xe2, 0x46, 0xa4, 0xaf, 0xf7, 0xa1, 0x3e, 0x70, 0x9d, 0x91, 0xeb, 0x4f,
+	0x8c, 0xf3, 0x77, 0x21, 0x66, 0x72, 0x28, 0xe8, 0x35, 0x0e, 0x7c, 0x46, 0x60, 0x84, 0x34, 0xbe,
+	0x1c, 0x10, 0xf5, 0x61, 0x38, 0x8c, 0x25, 0x55, 0x06, 0x63, 0x28, 0x77, 0x00, 0x48, 0xbc, 0xc7,
+	0x11, 0x18, 0x7b, 0x2a, 0x04, 0x42, 0xbb, 0xb5, 0x7f, 0x55, 0x00, 0x92, 0x35, 0xaf, 0x2c, 0xf7,
+	0x3d, 0x28, 0xe1, 0xb1, 0xe0, 0xfd, 0xc5, 0x1b, 0xee, 0xcc, 0x8e, 0x74, 0x8e, 0x49, 0xae, 0xc5,
+	0x96, 0x33, 0x8e, 0xdd, 0xff, 0xe2, 0x41, 0x11, 0xea, 0xac, 0x59, 0x2c, 0xcc, 0x5d, 0x1c, 0x06,
+	0xa0, 0x4a, 0xcc, 0x27, 0x07, 0xf2, 0xbb, 0x50, 0x0d, 0x12, 0x18, 0xbf, 0x27, 0xdd, 0x94, 0xbd,
+	0x16, 0xef, 0xd5, 0x45, 0xcc, 0xcc, 0xbb, 0xd2, 0x2d, 0xb8, 0x19, 0xc5, 0x36, 0x87, 0x97, 0xe4,
+	0x1a, 0xc9, 0x85, 0xac, 0xfd, 0xbc, 0x08, 0x6b, 0xbc, 0x87, 0x28, 0xa8, 0x67, 0x5a, 0x51, 0x50,
+	0x43, 0x7f, 0xa7, 0xba, 0xde, 0xb6, 0x10, 0x71, 0xb0, 0x93, 0x1f, 0xb7, 0xc9, 0x3d, 0xde, 0x9b,
+	0x9e, 0xdb, 0x96, 0xbc, 0xe3, 0xcc, 0x7b, 0x3c, 0xc3, 0xdd, 0x4f, 0x2e, 0x59, 0x7c, 0x30, 0xbd,
+	0x0f, 0x17, 0x29, 0x6d, 0x60, 0x20, 0x1a, 0x84, 0xfd, 0x00, 0x9a, 0x9e, 0x6f, 0xbd, 0x31, 0x43,
+	0x1c, 0x93, 0x2f, 0x2d, 0x20, 0xdf, 0xe0, 0xc8, 0x11, 0xfd, 0xf7, 0xa0, 0x16, 0x0d, 0xa7, 0x13,
+	0x30, 0x47, 0x5c, 0xe5, 0x30, 0x3a, 0xc3, 0x0e, 0x54, 0x6c, 0x33, 0x08, 0x8d, 0x69, 0x80, 0x87,
+	0xd4, 0x05, 0xe7, 0xf5, 0x32, 0x01, 0x9c, 0x05, 0x78, 0x48, 0x3a, 0x47, 0x96, 0xc3, 0x4c, 0x38,
+	0x75, 0xbc, 0x75, 0xbd, 0x3c, 0xb2, 0x1c, 0x2a, 0x74, 0xf4, 0x04, 0x6e, 0x86, 0xd8, 0x9f, 0x58,
+	0x0e, 0x35, 0x5b, 0xc6, 0xd0, 0xf2, 0x31, 0xbb, 0x18, 0x01, 0x45, 0xdc, 0x14, 0x3a, 0x0f, 0xa2,
+	0xbe, 0x2c, 0x1f, 0x4c, 0x62, 0x73, 0x3a, 0x8b, 0xff, 0xae, 0x55, 0x63, 0x21, 0x3c, 0x6f, 0x12,
+	0x06, 0xfb, 0x78, 0xe2, 0x0a, 0x1c, 0xa8, 0x2f, 0x62, 0x30, 0xc3, 0x15, 0x18, 0xcc, 0x07, 0xd3,
+	0xfd, 0x37, 0x18, 0x83, 0x19, 0x88, 0x6e, 0x3f, 0xb9, 0xff, 0x37, 0xc5, 0xfb, 0x3f, 0x5d, 0x8f,
+	0x8f, 0xcd, 0x10, 0x0f, 0x5b, 0x2a, 0x65, 0x4a, 0xd4, 0x24, 0x3d, 0x1e, 0x4d, 0x1d, 0x05, 0xad,
+	0x75, 0x96, 0xa6, 0xe1, 0x4d, 0x6a, 0xdb, 0xe8, 0xe1, 0x45, 0xdc, 0xb6, 0x91, 0x06, 0xda, 0x83,
+	0x9b, 0x3e, 0x9e, 0x98, 0x96, 0x63, 0x39, 0x63, 0xc3, 0xb6, 0x46, 0x98, 0x64, 0x81, 0x8c, 0x49,
+	0xd0, 0xda, 0xa0, 0x8b, 0xd9, 0x88, 0x3b, 0x4f, 0x78, 0xdf, 0xcb, 0x00, 0x3d, 0x86, 0x8d, 0x48,
+	0x6e, 0xe2, 0x59, 0xda, 0xa4, 0x67, 0x69, 0x9d, 0x77, 0xbd, 0x4c, 0x8e, 0x94, 0x0f, 0x4d, 0xae,
+	0xd3, 0x3d, 0xc7, 0xf4, 0x82, 0xd7, 0x6e, 0x62, 0x52, 0x85, 0xeb, 0x01, 0x35, 0xa9, 0x5d, 0x72,
+	0x45, 0x40, 0x50, 0x20, 0x33, 0x51, 0x25, 0xcf, 0xeb, 0xf4, 0x37, 0x7a, 0x0c, 0x65, 0x21, 0x43,
+	0x30, 0xeb, 0xaa, 0x39, 0x79, 0x3d, 0xc6, 0xd1, 0x4e, 0x60, 0xbd, 0xef, 0x7a, 0x7d, 0xd3, 0xbe,
+	0x58, 0xc9, 0x84, 0x12, 0x2e, 0x31, 0x7d, 0x62, 0x81, 0x21, 0x6b, 0x10, 0x2f, 0xad, 0x46, 0xa1,
+	0x7b, 0x6c, 0x5a, 0xc5, 0x73, 0xa7, 0xcc, 0x9c, 0xbb, 0x07, 0xd0, 0x60, 0x66, 0xca, 0x88, 0xa4,
+	0xc1, 0x6c, 0x6a, 0x9d, 0x41, 0x4f, 0xb9, 0x4c, 0x88, 0xe1, 0x65, 0x68, 0xa2, 0x5d, 0xad, 0x32,
+	0x18, 0x33, 0xbc, 0xdf, 0x86, 0xa6, 0xe5, 0xc8, 0xa4, 0x98, 0x0b, 0x6a, 0x58, 0x8e, 0x44, 0x8b,
+	0x26, 0xaa, 0x44, 0x62, 0xcc, 0x17, 0xd5, 0x2c, 0x27, 0xa1, 0xa6, 0xfd, 0x9d, 0x02, 0x25, 0xc6,
+	0x94, 0x95, 0x6d, 0xb4, 0xa0, 0x59, 0xb9, 0x0c, 0xcd, 0xca, 0x8b, 0x9a, 0x75, 0x1f, 0xea, 0xd8,
+	0xf7, 0x5d, 0x7f, 0x66, 0xd9, 0x35, 0x0a, 0x8c, 0x16, 0x7d, 0x0f, 0xaa, 0x0c, 0x49, 0x5c, 0x32,
+	0x50, 0x10, 0x5b, 0xf0, 0xcf, 0x14, 0x68, 0x8a, 0x82, 0x24, 0xe6, 0xf8, 0x7b, 0x50, 0x89, 0x18,
+	0x1d, 0x19, 0xe3, 0x9d, 0x94, 0x1c, 0x4b, 0x6c, 0xfc, 0x13, 0x6c, 0xf4, 0xed, 0xc8, 0x1d, 0xb3,
+	0x5b, 0xa2, 0x18, 0x79, 0xb0, 0x29, 0xb8, 0x0f, 0x26, 0xd7, 0xc3, 0x21, 0x0e, 0x42, 0x6e, 0x21,
+	0x22, 0x9d, 0x4b, 0xc1, 0x97, 0xd0, 0x32, 0xaf, 0x87, 0x3f, 0x86, 0x96, 0xee, 0x4e, 0x43, 0xbc,
+	0xef, 0x38, 0xee, 0xd4, 0x19, 0xe0, 0x09, 0x76, 0xc2, 0x15, 0xb4, 0xb2, 0x0d, 0x65, 0x93, 0x8f,
+	0xe4, 0xa6, 0x3f, 0x6e, 0x6b, 0x7f, 0xa5, 0xc0, 0x26, 0xd7, 0xff, 0x03, 0x6c, 0xe3, 0x10, 0xaf,
+	0x46, 0x37, 0x56, 0xe1, 0xdc, 0x8c, 0x0a, 0x0b, 0xfa, 0x91, 0x5f, 0xf2, 0xee, 0x46, 0xad, 0x58,
+	0x81, 0xbb, 0x2b, 0x92, 0x1c, 0xf9, 0x0b, 0x05, 0xea, 0xcf, 0x6c, 0x73, 0x70, 0xf1, 0xda, 0xb5,
+	0xb1, 0x3e, 0xb5, 0x31, 0xda, 0x85, 0xaa, 0xc0, 0x30, 0x7e, 0xf4, 0x45, 0x10, 0x61, 0x21, 0x0f,
+	0x61, 0xb9, 0xcf, 0x64, 0x2d, 0x51, 0xff, 0xf2, 0xb2, 0xfe, 0xed, 0x41, 0x85, 0x2f, 0x02, 0x13,
+	0x2d, 0xcb, 0x67, 0xae, 0x35, 0x41, 0xd3, 0xfe, 0x58, 0x81, 0xb6, 0xb4, 0x32, 0xf9, 0xfe, 0xb8,
+	0x05, 0x25, 0x96, 0x3a, 0xe2, 0x89, 0x24, 0xde, 0x5a, 0x32, 0x7d, 0xe4, 0x4f, 0x6d, 0x9c, 0x92,
+	0x3e, 0x92, 0xe6, 0xd3, 0x29, 0x16, 0x89, 0xb4, 0x24, 0xf0, 0x2a, 0xd7, 0xbd, 0x2f, 0x60, 0x63,
+	0x76, 0x2c, 0x39, 0x1e, 0x8f, 0xa1, 0x48, 0x48, 0x47, 0x47, 0x23, 0x7b, 0x05, 0x0c, 0x2d, 0xf3,
+	0x92, 0xf2, 0x31, 0x6c, 0xec, 0x7b, 0x9e, 0x6d, 0x0d, 0x98, 0x6e, 0xaf, 0xb0, 0xb0, 0x9f, 0xe6,
+	0xa4, 0xa1, 0xb1, 0xc5, 0x4c, 0x8b, 0x07, 0xdb, 0x82, 0x61, 0x67, 0x76, 0x25, 0x6e, 0x13, 0xdb,
+	0x47, 0x84, 0xff, 0x06, 0xcb, 0xd9, 0xe1, 0xba, 0xde, 0x60, 0xe0, 0xe8, 0x0e, 0x95, 0x62, 0x6e,
+	0x0b, 0xcb, 0x98, 0xdb, 0xe2, 0x52, 0xe6, 0xb6, 0xb4, 0x9c, 0xb9, 0x5d, 0x4b, 0x31, 0xb7, 0x2e,
+	0xac, 0xcb, 0x2c, 0x24, 0xf2, 0x79, 0x06, 0x35, 0x53, 0x00, 0x72, 0x31, 0xdd, 0x15, 0xc4, 0x94,
+	0xc2, 0x3b, 0x5d, 0x1a, 0x93, 0x29, 0xb3, 0x8f, 0x40, 0xa5, 0x23, 0x7c, 0x0b, 0xaf, 0x28, 0xb0,
+	0x26, 0x1b, 0xf7, 0x2e, 0x16, 0x96, 0x70, 0xe7, 0x51, 0xe4, 0x3b, 0xcf, 0x22, 0x91, 0xcd, 0x4b,
+	0x22, 0xbf, 0x8c, 0x24, 0x0a, 0x4b, 0x49, 0xa2, 0xb8, 0x9c, 0x24, 0x4a, 0xf3, 0x92, 0x20, 0xeb,
+	0x1a, 0x62, 0xc7, 0xc2, 0xc3, 0x98, 0x18, 0x93, 0x57, 0x9d, 0x41, 0x39, 0x2d, 0xed, 0x1c, 0x1a,
+	0x02, 0xff, 0x88, 0xb4, 0x3e, 0x86, 0xca, 0x20, 0x82, 0x70, 0x51, 0xb5, 0x67, 0x93, 0x04, 0x09,
+	0xd7, 0xf4, 0x04, 0x39, 0x53, 0x46, 0x7f, 0xa4, 0x40, 0x95, 0xdc, 0xee, 0xfa, 0xbe, 0x35, 0x1e,
+	0x63, 0x7f, 0xee, 0x1e, 0x51, 0x11, 0x8c, 0xf0, 0x26, 0x14, 0x89, 0x21, 0x0d, 0x38, 0x09, 0xd6,
+	0x20, 0x3b, 0x76, 0x3d, 0xec, 0x18, 0xd2, 0xb5, 0xbf, 0xa2, 0xd7, 0x08, 0x30, 0xf2, 0x7e, 0x24,
+	0x62, 0x63, 0x48, 0x74, 0x3c, 0x31, 0x8b, 0x15, 0xbd, 0x42, 0x31, 0x08, 0x40, 0xf3, 0x61, 0x5b,
+	0x58, 0xc4, 0x75, 0xde, 0x7a, 0xca, 0x21, 0x1f, 0xcb, 0x9d, 0xe9, 0x96, 0x14, 0x7f, 0xc5, 0xa4,
+	0xf5, 0x18, 0x8f, 0x58, 0x14, 0x71, 0xce, 0x15, 0x14, 0xf4, 0x0f, 0xa0, 0xce, 0x47, 0xf1, 0xf7,
+	0x9f, 0x28, 0x10, 0x52, 0x32, 0x02, 0xa1, 0x59, 0x6f, 0x86, 0x84, 0xa4, 0x3e, 0xf7, 0x4e, 0xe8,
+	0x21, 0x14, 0x88, 0xb3, 0x5f, 0x18, 0x12, 0x51, 0x0c, 0xed, 0x6b, 0x05, 0xd6, 0xe5, 0x95, 0x13,
+	0xd5, 0x10, 0x59, 0xa0, 0x2c, 0xc7, 0x02, 0xf4, 0x21, 0x94, 0x88, 0x0c, 0xf0, 0xb0, 0x95, 0x9b,
+	0xb3, 0xce, 0xd2, 0x0e, 0x75, 0x8e, 0x27, 0xa8, 0x51, 0x5e, 0x52, 0xa3, 0x9f, 0x29, 0xb0, 0xcd,
+	0x0d, 0xe0, 0x89, 0x3b, 0xee, 0x99, 0x13, 0xcf, 0xb6, 0x9c, 0xf1, 0x35, 0x13, 0x20, 0x75, 0x9e,
+	0x00, 0x79, 0x2a, 0x47, 0xba, 0xf9, 0x05, 0xce, 0x54, 0x44, 0x44, 0x1f, 0x43, 0x2b, 0x69, 0x8a,
+	0x51, 0x01, 0xf7, 0xc8, 0x35, 0x7d, 0x2b, 0xe9, 0x4f, 0x42, 0x03, 0x1c, 0x68, 0x5b, 0xb0, 0xa9,
+	0x4f, 0x1d, 0x12, 0x61, 0x74, 0x5c, 0x67, 0x64, 0x45, 0x1b, 0xd0, 0x3e, 0x00, 0x34, 0x03, 0x27,
+	0x2c, 0xdf, 0x82, 0xd2, 0x80, 0x36, 0xa3, 0xf7, 0x2a, 0xd6, 0xd2, 0x3e, 0x83, 0x8d, 0x8e, 0x3b,
+	0x99, 0x58, 0xa1, 0x44, 0x24, 0x0b, 0x9d, 0xd8, 0x16, 0xfa, 0xcb, 0x9f, 0x18, 0x24, 0xba, 0x70,
+	0xa7, 0xd1, 0x7d, 0xbf, 0xc1, 0xc1, 0x7d, 0x06, 0x25, 0xab, 0xeb, 0x30, 0x08, 0x23, 0x1f, 0xad,
+	0xee, 0x16, 0xdc, 0xd4, 0x5d, 0xdb, 0x3e, 0x37, 0x07, 0x17, 0x72, 0xc7, 0x36, 0x14, 0xd9, 0x4a,
+	0x55, 0xc8, 0x4f, 0x82, 0x31, 0x3f, 0xb7, 0xe4, 0xa7, 0xf6, 0x75, 0x01, 0xea, 0x5c, 0x60, 0xcf,
+	0x2d, 0x3b, 0x4c, 0x39, 0xf9, 0x8b, 0x23, 0xf7, 0xdc, 0xb5, 0x23, 0xf7, 0xfc, 0x32, 0x91, 0x7b,
+	0xe1, 0x1b, 0x44, 0xee, 0xc5, 0xf9, 0xc8, 0x7d, 0x3e, 0x30, 0x2e, 0x5d, 0x3b, 0x30, 0x5e, 0x9b,
+	0x0b, 0x8c, 0x6f, 0xc1, 0xda, 0xc4, 0x72, 0x0c, 0x73, 0x8c, 0x79, 0x62, 0xbe, 0x34, 0xb1, 0x9c,
+	0xfd, 0x31, 0xa6, 0x1d, 0xe6, 0x25, 0xed, 0xa8, 0xf0, 0x0e, 0xf3, 0x92, 0x74, 0xec, 0x40, 0x85,
+	0x8c, 0x60, 0x1e, 0x02, 0x98, 0xd7, 0x9a, 0x58, 0x0e, 0xf3, 0x0e, 0xa4, 0xd3, 0xbc, 0xe4, 0x9d,
+	0x55, 0xde, 0x69, 0x5e, 0xb2, 0xce, 0x47, 0x50, 0xb8, 0xb0, 0x9c, 0x21, 0x8d, 0xfc, 0x1b, 0xd2,
+	0x11, 0xe7, 0xd2, 0xfc, 0xd4, 0x72, 0x86, 0x3a, 0xc5, 0xc9, 0x0a, 0x8d, 0xeb, 0x59, 0xa1, 0xf1,
+	0x5f, 0x2a, 0xb0, 0xc1, 0xa9, 0x04, 0xcf, 0x09, 0x99, 0xe5, 0x8f, 0xef, 0x87, 0x50, 0x1a, 0x51,
+	0x35, 0xe2, 0x8a, 0xd1, 0x9a, 0x5f, 0x18, 0x53, 0x33, 0x9d, 0xe3, 0x11, 0x67, 0x62, 0x5b, 0x13,
+	0x2b, 0xd2, 0x07, 0xd6, 0xa0, 0x67, 0x64, 0xea, 0x07, 0xae, 0xcf, 0x9d, 0x30, 0x6f, 0x69, 0xbf,
+	0x0f, 0xeb, 0xf2, 0xca, 0xd8, 0xdd, 0x32, 0x71, 0xfd, 0xca, 0xd5, 0x61, 0x38, 0x11, 0xa4, 0x83,
+	0x2f, 0x43, 0x83, 0xcf, 0xc0, 0x6e, 0x0b, 0x40, 0x40, 0x1d, 0x0a, 0xc9, 0xb4, 0x6e, 0xdf, 0x87,
+	0xad, 0xc3, 0xcb, 0x10, 0xfb, 0x8e, 0x69, 0x47, 0x3a, 0xb2, 0xbc, 0xb7, 0xf8, 0x0f, 0x05, 0x36,
+	0xe7, 0x46, 0x2f, 0x99, 0x59, 0x5f, 0xf5, 0x25, 0x32, 0xcd, 0xb1, 0x24, 0xaf, 0x56, 0x85, 0x25,
+	0x5e, 0xad, 0x5a, 0xb0, 0x66, 0x63, 0xd3, 0x77, 0x78, 0x65, 0x4d, 0x5e, 0x8f, 0x9a, 0x99, 0xb9,
+	0xf6, 0x27, 0xa0, 0x3e, 0xb7, 0xdd, 0xb7, 0x47, 0xbe, 0xe9, 0xc5, 0xef, 0x9a, 0xf7, 0x80, 0x6d,
+	0xe3, 0x8d, 0x69, 0x93, 0xf4, 0x0d, 0xdb, 0x19, 0x44, 0xa0, 0x97, 0x81, 0xf6, 0x0e, 0xca, 0x64,
+	0x50, 0xd7, 0x1d, 0x62, 0xf2, 0x7c, 0xc0, 0x77, 0x5f, 0xd1, 0x73, 0x16, 0x75, 0x05, 0x54, 0xc5,
+	0x99, 0xb5, 0xa2, 0xbf, 0xe3, 0xcb, 0x7a, 0x5e, 0xb8, 0xac, 0x47, 0x29, 0xc9, 0x82, 0x90, 0x92,
+	0x9c, 0xe5, 0x69, 0x71, 0x5e, 0x1e, 0x7f, 0xae, 0xb0, 0xb9, 0x0f, 0x87, 0x63, 0x4a, 0x63, 0xe4,
+	0xbb, 0x93, 0x28, 0x08, 0x20, 0xbf, 0xc9, 0x7a, 0x42, 0x97, 0xcf, 0x9e, 0x0b, 0xdd, 0xf8, 0xee,
+	0x89, 0x87, 0xfc, 0xe1, 0x3b, 0x6a, 0x8a, 0x51, 0x60, 0x41, 0x8e, 0x02, 0x3f, 0x00, 0xc4, 0x7f,
+	0x1a, 0x1e, 0xf6, 0xf9, 0xbb, 0x1d, 0x5d, 0x8d, 0xa2, 0xab, 0xbc, 0xe7, 0x14, 0xfb, 0xec, 0xe9,
+	0x4e, 0x1b, 0x41, 0x43, 0x60, 0x21, 0xd1, 0x8d, 0xf7, 0xa1, 0xe8, 0xb8, 0x43, 0x9c, 0xf6, 0x0c,
+	0x1e, 0xf1, 0x4d, 0x67, 0x18, 0x04, 0x15, 0x0f, 0xc7, 0x38, 0xba, 0xf8, 0xcc, 0xa2, 0x92, 0x6d,
+	0xea, 0x0c, 0x43, 0xfb, 0x53, 0x05, 0xd0, 0x4b, 0x93, 0x30, 0xc3, 0x31, 0x9d, 0xc1, 0x2a, 0x17,
+	0xac, 0x24, 0x04, 0xcd, 0x49, 0x21, 0xe8, 0x03, 0x68, 0xf0, 0xd7, 0x69, 0xb9, 0x62, 0xa6, 0x4e,
+	0xa1, 0x71, 0x48, 0xb4, 0x05, 0x25, 0x1f, 0xff, 0x2e, 0x1e, 0x84, 0xfc, 0xb5, 0x87, 0xb7, 0xb4,
+	0x1f, 0x40, 0x4b, 0x58, 0xcf, 0xca, 0xef, 0x55, 0x7f, 0x93, 0x03, 0x55, 0xda, 0xcf, 0x92, 0xc7,
+	0x6a, 0x97, 0xe4, 0xdd, 0xe3, 0x61, 0xd1, 0x93, 0xba, 0x00, 0x12, 0x16, 0x9c, 0x17, 0x17, 0x4c,
+	0xac, 0x56, 0x60, 0x91, 0x31, 0x05, 0x7a, 0x38, 0x58, 0x03, 0xbd, 0x0f, 0x2a, 0xdd, 0x2f, 0x1e,
+	0x26, 0x7c, 0x60, 0xe1, 0x41, 0x93, 0xc3, 0x63, 0x4e, 0xbc, 0x0f, 0xaa, 0x8f, 0x47, 0xd3, 0x40,
+	0x44, 0x65, 0x21, 0x42, 0x93, 0xc3, 0x7b, 0x0b, 0x02, 0x4e, 0x16, 0x26, 0xcc, 0x06, 0x9c, 0xc9,
+	0xc9, 0x2c, 0x4b, 0x27, 0xb3, 0x05, 0x5b, 0xdd, 0x51, 0x48, 0xe4, 0x14, 0xd0, 0x88, 0x1c, 0xc7,
+	0x17, 0x83, 0x0f, 0x61, 0x73, 0xae, 0x87, 0xf0, 0xae, 0x05, 0x6b, 0x3e, 0x6b, 0x47, 0x61, 0x16,
+	0x6f, 0x6a, 0xff, 0x95, 0x03, 0xc4, 0xe2, 0x12, 0x5a, 0x99, 0xf6, 0x7f, 0x94, 0xd6, 0x21, 0xb6,
+	0x89, 0xd6, 0xfe, 0x2c, 0xcc, 0xea, 0x70, 0x1c, 0x62, 0x55, 0x84, 0x32, 0x2b, 0x7e, 0xee, 0x21,
+	0xa9, 0xaf, 0x22, 0x17, 0x46, 0x31, 0x9f, 0xb3, 0xe8, 0x95, 0x5e, 0x44, 0x24, 0x42, 0x11, 0x9a,
+	0x8c, 0x3a, 0x7b, 0xad, 0x6f, 0x0a, 0x70, 0x3a, 0xc5, 0x03, 0x68, 0x90, 0xe7, 0x7a, 0x5e, 0x55,
+	0x47, 0x66, 0x61, 0x45, 0x4d, 0x75, 0x07, 0xbf, 0xed, 0xc4, 0x40, 0x96, 0x52, 0xf6, 0x0c, 0xe6,
+	0xe1, 0xd8, 0xa5, 0xa0, 0xfc, 0xda, 0xf5, 0x4e, 0x48, 0x9b, 0x04, 0x43, 0x96, 0x67, 0xb8, 0x1e,
+	0x8b, 0xb8, 0x2b, 0x74, 0x7c, 0xc5, 0xf2, 0x5e, 0x31, 0x80, 0xf6, 0x3d, 0xa8, 0x50, 0x1e, 0xf7,
+	0x42, 0xec, 0x51, 0x85, 0x0b, 0xc9, 0x05, 0x82, 0xc9, 0x83, 0x35, 0x98, 0x7a, 0x06, 0x53, 0x3b,
+	0x8e, 0xe6, 0x58, 0x4b, 0xfb, 0x79, 0x0e, 0x54, 0x49, 0x4a, 0x44, 0xa8, 0xb4, 0x1a, 0x02, 0x7b,
+	0x91, 0x2d, 0x99, 0xab, 0x32, 0x24, 0xf3, 0xe8, 0x0c, 0x85, 0x28, 0xc0, 0x1b, 0xec, 0x0f, 0xad,
+	0x41, 0x44, 0x39, 0x6a, 0x92, 0xcb, 0x84, 0x3b, 0x0d, 0xbd, 0x69, 0x68, 0x48, 0x02, 0x67, 0x9e,
+	0x66, 0x9d, 0x75, 0x1d, 0x4b, 0x99, 0xa7, 0x48, 0xb4, 0x85, 0xd5, 0x45, 0x5b, 0xbc, 0x4a, 0xb4,
+	0xa5, 0x6f, 0x22, 0xda, 0xb5, 0x74, 0xd1, 0x66, 0x1d, 0xa3, 0xdf, 0x84, 0x76, 0x5c, 0x6b, 0xf5,
+	0xc2, 0x74, 0x86, 0xc1, 0x6b, 0xf3, 0x62, 0xa5, 0x84, 0xc6, 0x1f, 0x92, 0x5a, 0x37, 0xd3, 0xb2,
+	0x85, 0xe1, 0xd7, 0x79, 0x8c, 0xa6, 0x6b, 0xcf, 0x09, 0x9e, 0x3d, 0x7a, 0x9a, 0xc8, 0x0b, 0x4f,
+	0x13, 0x54, 0x33, 0xcc, 0xc0, 0x75, 0xa2, 0x9c, 0x2f, 0x6b, 0x69, 0xff, 0x98, 0x83, 0x8d, 0x94,
+	0x5d, 0xa4, 0x86, 0xae, 0x69, 0x73, 0x91, 0xa4, 0x6f, 0x18, 0xe2, 0x89, 0x17, 0x27, 0x51, 0xe2,
+	0x36, 0xa9, 0xdf, 0x1d, 0xb8, 0x13, 0xcf, 0xc6, 0xc4, 0x45, 0x32, 0x47, 0x98, 0x00, 0xa8, 0x93,
+	0xc4, 0x0e, 0xad, 0x83, 0xe3, 0xb5, 0xba, 0xbc, 0x89, 0xb6, 0xa1, 0xec, 0xb8, 0x86, 0x4f, 0x94,
+	0x94, 0xdb, 0xc0, 0x35, 0xc7, 0x4d, 0x0c, 0x11, 0x33, 0x87, 0xdc, 0xe6, 0x45, 0x4d, 0x74, 0x17,
+	0xc0, 0x72, 0x22, 0xea, 0x54, 0x52, 0x05, 0x5d, 0x80, 0x90, 0x85, 0xba, 0x6f, 0xb0, 0x3f, 0xb2,
+	0xdd, 0xb7, 0xf4, 0x68, 0x15, 0xf4, 0xb8, 0x4d, 0x5e, 0x76, 0x47, 0x54, 0x0e, 0x2d, 0x98, 0xaf,
+	0x5d, 0x94, 0x05, 0xa4, 0x73, 0x4c, 0xcd, 0x81, 0x56, 0xaa, 0xf4, 0xc9, 0x2a, 0x3f, 0x81, 0x32,
+	0xaf, 0xf2, 0x4b, 0x4b, 0x9c, 0xa5, 0x0d, 0x8b, 0xf1, 0x33, 0x13, 0x32, 0xff, 0x93, 0x83, 0x1d,
+	0x6e, 0xd9, 0xf7, 0xa7, 0xe1, 0x6b, 0xd7, 0xb7, 0xbe, 0xa4, 0x2a, 0x1a, 0xe9, 0x1b, 0x79, 0x13,
+	0xb2, 0xcd, 0xb8, 0x7c, 0x97, 0x35, 0x96, 0x49, 0x05, 0x67, 0x5c, 0x6e, 0x63, 0x0d, 0x28, 0x64,
+	0x24, 0x2f, 0x8a, 0x33, 0x36, 0xfb, 0xff, 0xff, 0x9d, 0x75, 0x3e, 0x5a, 0x2b, 0x5f, 0x3b, 0x5a,
+	0xab, 0xcc, 0x45, 0x6b, 0x33, 0xe1, 0x28, 0xcc, 0x86, 0xa3, 0xda, 0x10, 0xb6, 0xd3, 0x05, 0x40,
+	0x44, 0xbe, 0x09, 0x45, 0xd3, 0x26, 0xba, 0xc5, 0x0e, 0x0c, 0x6b, 0x10, 0x8b, 0x3e, 0x30, 0x07,
+	0xaf, 0xb1, 0x11, 0x3f, 0x15, 0xd6, 0xf5, 0x0a, 0x85, 0x90, 0xd8, 0x9d, 0xb0, 0x98, 0xe6, 0x76,
+	0xd8, 0x5d, 0x82, 0xfe, 0x26, 0x2f, 0x25, 0xeb, 0x1d, 0xd3, 0x23, 0x8e, 0x9c, 0xcc, 0x6a, 0xda,
+	0xd7, 0xaa, 0x51, 0xb9, 0xb2, 0xb0, 0xea, 0x89, 0x5c, 0x0a, 0x77, 0x47, 0xcc, 0x16, 0x8a, 0xb3,
+	0x8b, 0x35, 0x71, 0x9a, 0x0b, 0xad, 0xb9, 0xa5, 0xad, 0x14, 0x0c, 0xb2, 0xed, 0xb2, 0x90, 0xe5,
+	0x76, 0xd6, 0x94, 0x94, 0x2a, 0x63, 0xc6, 0x6f, 0xc0, 0xf6, 0x5c, 0xd7, 0x2a, 0x16, 0xf6, 0xbf,
+	0x15, 0xb8, 0x95, 0x46, 0x60, 0xc9, 0xfb, 0xe0, 0x33, 0xa8, 0x0f, 0xf1, 0xc8, 0x9c, 0xda, 0xa1,
+	0xc1, 0x98, 0x95, 0x5b, 0x86, 0x59, 0x35, 0x3e, 0x86, 0xb6, 0x92, 0xda, 0x9a, 0xfc, 0x5c, 0x6d,
+	0xcd, 0xfc, 0xae, 0x19, 0x2a, 0xbb, 0x0c, 0xb2, 0x62, 0x00, 0x3c, 0x34, 0x88, 0x89, 0x8a, 0x02,
+	0x89, 0x66, 0x02, 0x27, 0x97, 0x78, 0xd1, 0x5c, 0x14, 0x25, 0x73, 0xf1, 0x09, 0xdc, 0x24, 0x14,
+	0x8f, 0x87, 0xd8, 0x09, 0xad, 0x70, 0xb5, 0x44, 0xfb, 0x7f, 0xe6, 0xa0, 0x26, 0x0c, 0x7e, 0x37,
+	0xab, 0x4d, 0xca, 0x9c, 0x36, 0x49, 0x8f, 0x5b, 0xb9, 0xa5, 0x1e, 0xb7, 0xc8, 0xd9, 0x18, 0x59,
+	0x7e, 0x10, 0x1a, 0x01, 0xc6, 0x4e, 0xf4, 0x79, 0x07, 0x85, 0xf4, 0x30, 0x76, 0xe2, 0xa2, 0x0a,
+	0xda, 0x5b, 0x48, 0x8a, 0x2a, 0x68, 0x27, 0xb9, 0x02, 0x33, 0x42, 0xc6, 0x80, 0xe6, 0x84, 0xe3,
+	0xb4, 0xbb, 0x29, 0x7e, 0x15, 0x90, 0x96, 0xe9, 0x2f, 0x2d, 0x93, 0xe9, 0x5f, 0x5b, 0x2a, 0xd3,
+	0x5f, 0x5e, 0x2e, 0xd3, 0x5f, 0x49, 0x79, 0x73, 0xb9, 0x84, 0x8d, 0x59, 0xf1, 0x10, 0x9d, 0xfc,
+	0x4e, 0xa4, 0x2c, 0xcc, 0x6b, 0xdc, 0x12, 0x78, 0x28, 0x0a, 0x24, 0xd2, 0x13, 0xd1, 0xa7, 0xe5,
+	0x66, 0x7c, 0x5a, 0x86, 0x59, 0x7f, 0xf4, 0xeb, 0xfc, 0x16, 0x49, 0xbf, 0xb0, 0xa9, 0x43, 0xe5,
+	0xe0, 0xec, 0xe5, 0xa9, 0x71, 0xa0, 0xbf, 0x3a, 0x55, 0x6f, 0x20, 0x04, 0x0d, 0xda, 0xec, 0xeb,
+	0xfb, 0xdd, 0xde, 0xc9, 0x7e, 0xff, 0x50, 0x55, 0x50, 0x0d, 0xca, 0x14, 0xf6, 0x69, 0xf7, 0x58,
+	0xcd, 0x3d, 0xd2, 0xa1, 0x1c, 0xe7, 0xee, 0xab, 0xb0, 0x76, 0xd6, 0xfd, 0xb4, 0xfb, 0xea, 0xf3,
+	0xae, 0x7a, 0x03, 0xad, 0x41, 0xbe, 0xdf, 0x39, 0x55, 0x4b, 0xe4, 0xc7, 0xd9, 0xc1, 0xa9, 0xba,
+	0x8e, 0x9a, 0xe4, 0xbb, 0x91, 0x37, 0x4f, 0x8d, 0xe7, 0xb6, 0x39, 0x56, 0xbf, 0xfa, 0xaa, 0x80,
+	0x00, 0x0a, 0xfd, 0xce, 0xe9, 0x53, 0xf5, 0xa7, 0xec, 0xf7, 0xd9, 0xc1, 0xe9, 0x53, 0xf5, 0xeb,
+	0xaf, 0x0a, 0x8f, 0xfe, 0x4c, 0x81, 0x4a, 0x5c, 0x7e, 0x8b, 0x54, 0xa8, 0x91, 0x86, 0x91, 0x90,
+	0x6e, 0x42, 0x95, 0x42, 0x7a, 0xfd, 0xfd, 0xfe, 0x71, 0x47, 0x55, 0xd0, 0x26, 0xab, 0x6b, 0x36,
+	0x0e, 0x8e, 0x7b, 0x9d, 0x57, 0x9f, 0x1d, 0xea, 0xc7, 0xdd, 0x23, 0x35, 0x87, 0x36, 0xa0, 0x49,
+	0xa1, 0xfa, 0xe1, 0x8f, 0xce, 0x0e, 0x7b, 0x7d, 0x02, 0xcc, 0xa3, 0x06, 0x00, 0x05, 0x3e, 0x7b,
+	0x75, 0xd6, 0x3d, 0x50, 0x0b, 0x68, 0x1d, 0xea, 0x1c, 0xa9, 0x7b, 0xf8, 0x39, 0x41, 0x29, 0x0a,
+	0xa0, 0x93, 0xc3, 0xfd, 0xde, 0xe1, 0x81, 0x5a, 0x7a, 0xf4, 0x05, 0x40, 0x52, 0x87, 0x8c, 0x76,
+	0xe0, 0x16, 0x45, 0xd8, 0xef, 0xf4, 0x8f, 0x5f, 0x75, 0x8d, 0xb3, 0x6e, 0xef, 0xf4, 0xb0, 0x73,
+	0xfc, 0xfc, 0xf8, 0xf0, 0x40, 0xbd, 0x11, 0x4f, 0x40, 0x09, 0xaa, 0x4a, 0xbc, 0x7c, 0x4e, 0x4d,
+	0xcd, 0x09, 0x90, 0x5e, 0x7f, 0x5f, 0xef, 0xab, 0xf9, 0x47, 0xbf, 0x05, 0x55, 0x21, 0x29, 0x47,
+	0x10, 0x7a, 0x87, 0xbd, 0xde, 0xf1, 0xab, 0x6e, 0xcf, 0xd8, 0x3f, 0x39, 0x51, 0x6f, 0x90, 0x0d,
+	0xc6, 0x90, 0x83, 0x1f, 0x77, 0xf7, 0x5f, 0xd2, 0x6d, 0x6f, 0x40, 0x33, 0x86, 0x72, 0x5e, 0xe4,
+	0x1e, 0xfd, 0x0e, 0xa0, 0x79, 0x13, 0x44, 0x04, 0x79, 0xfa, 0x4a, 0xef, 0xef, 0x9f, 0x18, 0x07,
+	0x87, 0xcf, 0xf7, 0xcf, 0x4e, 0xfa, 0xea, 0x0d, 0xd4, 0x82, 0x4d, 0x0e, 0xdb, 0x3f, 0xeb, 0xbf,
+	0x38, 0xec, 0xf6, 0x8f, 0x3b, 0xfb, 0xfd, 0xc3, 0x03, 0x55, 0x41, 0x6d, 0xd8, 0xe2, 0x3d, 0x67,
+	0x5d, 0xb9, 0x2f, 0xb7, 0xf7, 0x6f, 0x3b, 0xb0, 0x76, 0x46, 0x95, 0xd0, 0x47, 0x3f, 0x84, 0x2a,
+	0x2f, 0x00, 0x27, 0xdf, 0x11, 0x21, 0xd1, 0x0c, 0xce, 0x7f, 0xef, 0xd6, 0x56, 0x85, 0x6e, 0xaa,
+	0xdd, 0xda, 0x0d, 0xf4, 0x19, 0x6c, 0xb1, 0x93, 0x39, 0xfb, 0x15, 0x0f, 0x7a, 0x28, 0x9a, 0x8b,
+	0x45, 0x9f, 0xf8, 0xa4, 0xd2, 0xd5, 0x61, 0x93, 0x21, 0xc9, 0x9f, 0x60, 0xa0, 0x5f, 0x9a, 0x79,
+	0xea, 0xc8, 0xf8, 0x3a, 0x23, 0x95, 0xe6, 0x0b, 0xa8, 0x1d, 0xe1, 0x30, 0xae, 0xcf, 0x47, 0x3b,
+	0x29, 0x9f, 0x1c, 0x44, 0x56, 0xb5, 0xbd, 0x9d, 0xde, 0xc9, 0x28, 0x1d, 0xc3, 0xfa, 0xfe, 0x70,
+	0xc8, 0x8a, 0xf2, 0xa3, 0x4e, 0xb4, 0x9b, 0x32, 0xe2, 0xea, 0x45, 0x3d, 0x87, 0x06, 0xab, 0x9f,
+	0xf8, 0xe6, 0x74, 0xe8, 0x07, 0x07, 0xc9, 0xf6, 0xd2, 0xe8, 0x48, 0x1f, 0x25, 0x2c, 0x60, 0x52,
+	0x5c, 0x9d, 0x2f, 0x31, 0x69, 0xf6, 0xdb, 0x83, 0xf6, 0x76, 0x7a, 0x67, 0xc4, 0xa4, 0x58, 0xb9,
+	0x5e, 0x74, 0x4e, 0x65, 0xe5, 0x9a, 0xfb, 0xf2, 0x60, 0x31, 0xa9, 0x23, 0x00, 0xf6, 0x35, 0x24,
+	0x55, 0xd3, 0xdb, 0x33, 0x6a, 0x2a, 0x7d, 0x28, 0xd9, 0xbe, 0x35, 0xd3, 0x1b, 0xbd, 0xb2, 0x6a,
+	0x37, 0x3e, 0x54, 0xd0, 0x0b, 0x68, 0xf2, 0xd8, 0x3d, 0xfa, 0x70, 0x0e, 0xbd, 0x37, 0x4b, 0x6d,
+	0xee, 0x7b, 0xc2, 0x54, 0x3e, 0x75, 0x01, 0x25, 0xdf, 0xdc, 0xc5, 0xc4, 0xbe, 0x95, 0x42, 0x6c,
+	0xee, 0xd3, 0xbc, 0x54, 0x7a, 0x3f, 0x24, 0xaf, 0x34, 0xce, 0x30, 0xae, 0xb9, 0x97, 0x18, 0x3f,
+	0x5b, 0x89, 0x9f, 0x4a, 0xe1, 0x73, 0x58, 0x3f, 0x62, 0x9f, 0x38, 0x25, 0xe5, 0xec, 0x92, 0x12,
+	0xa4, 0xd6, 0xd6, 0xb7, 0xef, 0x2e, 0xc0, 0x60, 0x84, 0x3f, 0x85, 0xfa, 0x11, 0x0e, 0x93, 0x72,
+	0x71, 0x49, 0x00, 0x73, 0xe5, 0xe7, 0xed, 0x76, 0x46, 0x2f, 0x23, 0x76, 0x0a, 0x1b, 0x4c, 0x99,
+	0xa5, 0x3a, 0x64, 0xf4, 0x20, 0xab, 0x42, 0xf9, 0x6a, 0xcd, 0xef, 0x42, 0xe3, 0x08, 0x87, 0x42,
+	0xf5, 0xac, 0xa4, 0x6a, 0xf3, 0x25, 0xcd, 0xed, 0x9d, 0xac, 0xee, 0x68, 0x85, 0x0d, 0x56, 0x1d,
+	0x1b, 0xa7, 0xdd, 0x76, 0xe7, 0x1f, 0x1b, 0xe4, 0x02, 0xda, 0x76, 0x7b, 0x1e, 0x23, 0x2a, 0x3a,
+	0xa4, 0xb2, 0x6d, 0x1c, 0x4f, 0x24, 0x8a, 0x0b, 0xf0, 0x53, 0xf7, 0xc8, 0x44, 0x90, 0x54, 0xa4,
+	0x49, 0x22, 0x98, 0xab, 0x38, 0x6c, 0xb7, 0x33, 0x7a, 0x19, 0xb1, 0x1e, 0xb4, 0xa2, 0xc3, 0x37,
+	0x5b, 0x1c, 0x86, 0xee, 0x8b, 0x93, 0x67, 0x94, 0x8e, 0xa5, 0xae, 0xf0, 0x00, 0xea, 0xcc, 0x8e,
+	0xf1, 0xed, 0xa0, 0x7b, 0xf3, 0x5b, 0x94, 0x0a, 0xc5, 0x52, 0xa9, 0xc4, 0xda, 0x21, 0x97, 0x6f,
+	0x3d, 0xc8, 0x2a, 0x26, 0xba, 0x5a, 0x3b, 0xd8, 0xa9, 0x90, 0x06, 0xc9, 0x02, 0x4d, 0xad, 0x83,
+	0x6a, 0xdf, 0x5d, 0x80, 0xc1, 0x08, 0xff, 0x08, 0x9a, 0x47, 0x38, 0x14, 0xeb, 0x6c, 0x50, 0x46,
+	0x31, 0x4d, 0x4c, 0xf4, 0x76, 0x66, 0xbf, 0x68, 0x7b, 0xe3, 0x4a, 0x10, 0xc9, 0x04, 0xcc, 0xd6,
+	0xd7, 0xb4, 0xb7, 0xd3, 0x3b, 0x23, 0x7d, 0x69, 0xf6, 0x98, 0x2d, 0x88, 0x6a, 0x07, 0x24, 0xd3,
+	0x94, 0x59, 0x82, 0x91, 0xca, 0x42, 0xb6, 0x53, 0x89, 0xd8, 0xdd, 0x0c, 0x62, 0x69, 0x3b, 0x9d,
+	0xab, 0x60, 0xd0, 0x6e, 0xa0, 0x3e, 0xb4, 0xd8, 0xbc, 0xf3, 0xa5, 0x04, 0xd2, 0x42, 0x33, 0x2b,
+	0x0d, 0x52, 0x17, 0xda, 0x07, 0xf5, 0x08, 0x87, 0xd2, 0xfb, 0xbd, 0xa4, 0x86, 0x69, 0x2f, 0xfe,
+	0xed, 0x3b, 0xd9, 0x08, 0x8c, 0xea, 0x33, 0xa8, 0x89, 0x8f, 0xfc, 0xd2, 0xde, 0x53, 0x5e, 0xff,
+	0xb3, 0x4e, 0x87, 0xf4, 0xa0, 0x2f, 0x2d, 0x2b, 0xed, 0xa9, 0x3f, 0xcb, 0xc7, 0xcb, 0xcf, 0xff,
+	0x92, 0x22, 0xa7, 0x56, 0x06, 0x64, 0x58, 0xcc, 0xda, 0x73, 0xfa, 0x39, 0x19, 0xb7, 0x46, 0x77,
+	0x53, 0xec, 0x9b, 0xf0, 0x2c, 0xdc, 0xbe, 0x9d, 0xd9, 0xcf, 0xe8, 0xfd, 0x04, 0xd0, 0x11, 0x0e,
+	0x67, 0x9e, 0x3e, 0x25, 0xc7, 0x9a, 0xfe, 0xa8, 0xda, 0xbe, 0xb7, 0x08, 0x45, 0x3c, 0x13, 0xf1,
+	0xa3, 0x99, 0x74, 0x26, 0x66, 0x5f, 0x23, 0xdb, 0xdb, 0xe9, 0x9d, 0xb1, 0x9f, 0xe8, 0xe1, 0x50,
+	0x78, 0x45, 0x92, 0xfc, 0xc4, 0xfc, 0x6b, 0x59, 0x7b, 0x27, 0xab, 0x3b, 0xd2, 0x36, 0xe2, 0x77,
+	0x44, 0x7a, 0xf7, 0xd3, 0x07, 0xc8, 0xee, 0xf1, 0x0a, 0xaa, 0x8c, 0x97, 0x33, 0x6f, 0x36, 0x12,
+	0x2f, 0xd3, 0x5f, 0x7a, 0xda, 0xf7, 0x16, 0xa1, 0x44, 0x56, 0xa1, 0x4a, 0x23, 0x45, 0xfe, 0x2f,
+	0x0f, 0xe2, 0xf6, 0xe7, 0x5f, 0x7c, 0xda, 0x3b, 0x59, 0xdd, 0x8c, 0xd8, 0x08, 0xb6, 0x8e, 0x70,
+	0x74, 0xff, 0x96, 0x32, 0xcd, 0x0f, 0xae, 0x48, 0x8d, 0x72, 0xfa, 0xf7, 0xaf, 0x42, 0x63, 0xf3,
+	0x98, 0xa4, 0xce, 0x38, 0x9c, 0x4f, 0xa0, 0xdd, 0x5f, 0x98, 0x77, 0xe1, 0x73, 0x68, 0x8b, 0x90,
+	0xe2, 0x29, 0x06, 0x70, 0xf3, 0x28, 0x65, 0x0a, 0xd9, 0x66, 0x66, 0xa6, 0xad, 0x96, 0x9c, 0x84,
+	0x39, 0x22, 0x39, 0x47, 0x20, 0x9d, 0xdf, 0xd4, 0xec, 0x4e, 0xfb, 0xee, 0x02, 0x0c, 0x4a, 0x78,
+	0xef, 0x2d, 0xac, 0xcf, 0x64, 0x31, 0xb1, 0x8f, 0xce, 0x41, 0x8d, 0x5b, 0xbc, 0x57, 0x8a, 0x9d,
+	0x16, 0x24, 0x9e, 0xdb, 0xdf, 0xba, 0x12, 0x8f, 0x4e, 0xfc, 0x4c, 0x7d, 0x56, 0x63, 0x81, 0x64,
+	0xd7, 0x0c, 0x3b, 0xa3, 0xf1, 0xa9, 0x72, 0x5e, 0xa2, 0xb9, 0xe3, 0x27, 0xff, 0x3b, 0x00, 0x3a,
+	0xa1, 0x46, 0x9a, 0x4c, 0x45, 0x00, 0x00,
 }